@@ -3,101 +3,341 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer"
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+	"github.com/wonderfulspam/gitlab-smith/pkg/renderer"
 )
 
 var analyzeCmd = &cobra.Command{
-	Use:   "analyze [file]",
+	Use:   "analyze [file... | -]",
 	Short: "Analyze GitLab CI configuration for issues and improvements",
 	Long: `Analyze GitLab CI configuration files to identify potential issues,
 optimization opportunities, and suggest improvements for better maintainability,
-performance, security, and reliability.`,
-	Args: cobra.ExactArgs(1),
+performance, security, and reliability.
+
+Use "-" to read the configuration from stdin, for example:
+  git show HEAD:.gitlab-ci.yml | gitlab-smith analyze -
+
+Several files can be given with -f; they are merged in order before analysis,
+and on conflicts the last file specified wins.
+
+With --output-dir, each file is analyzed on its own instead, and one report per
+file is written to the directory in --format, together with an index.json that
+summarizes the scores of all files:
+  gitlab-smith analyze --output-dir reports --format json ci/*.yml
+
+With --remote-project, the configuration is fetched from GitLab as merged by
+the server, with all includes resolved there, instead of from local files:
+  gitlab-smith analyze --remote-project group/project --ref main --gitlab-token $TOKEN
+
+With --fixable, only the issues with a mechanical fix are listed, each with a
+diff of the change that fixes it. Nothing is written:
+  gitlab-smith analyze --fixable .gitlab-ci.yml
+
+With --policy-dir, or policy_dir in the --config file, the policies of the YAML
+spec files in a directory run as additional checks, for conventions specific to
+your organization. See examples/policies for the format:
+  gitlab-smith analyze --policy-dir examples/policies .gitlab-ci.yml`,
+	Args: cobra.ArbitraryArgs,
 	RunE: runAnalyze,
 }
 
 var (
 	analyzeFormat            string
 	analyzeConfigFile        string
+	analyzePolicyDir         string
 	analyzeSeverityThreshold string
 	analyzeDisableChecks     []string
+	analyzeBaseline          string
+	analyzeMaxNewIssues      int
+	analyzeWatch             bool
+	analyzeFiles             []string
+	analyzeRemoteProject     string
+	analyzeRemoteRef         string
+	analyzeGitLabURL         string
+	analyzeGitLabToken       string
+	analyzePlan              bool
+	analyzeFixable           bool
+	analyzeFailOn            []string
+	analyzeFailOnSeverity    string
+	analyzeMinSeverity       string
+	analyzeOnlyTypes         []string
+	analyzeRepoDir           string
+	analyzeNoColor           bool
+	analyzeOutputDir         string
 )
 
 func init() {
 	analyzeCmd.Flags().StringVar(&analyzeFormat, "format", "table", "Output format: table, json")
 	analyzeCmd.Flags().StringVar(&analyzeConfigFile, "config", "", "Configuration file path")
+	analyzeCmd.Flags().StringVar(&analyzePolicyDir, "policy-dir", "", "Directory of policy spec files to run as additional checks")
 	analyzeCmd.Flags().StringVar(&analyzeSeverityThreshold, "severity-threshold", "", "Minimum severity to report (low, medium, high)")
 	analyzeCmd.Flags().StringSliceVar(&analyzeDisableChecks, "disable-check", []string{}, "Disable specific checks")
+	analyzeCmd.Flags().StringVar(&analyzeBaseline, "baseline", "", "Baseline configuration file; only report issues not present in it")
+	analyzeCmd.Flags().IntVar(&analyzeMaxNewIssues, "max-new-issues", 0, "Maximum number of new issues allowed relative to --baseline")
+	analyzeCmd.Flags().BoolVar(&analyzeWatch, "watch", false, "Re-run the analysis whenever the file or one of its local includes changes")
+	analyzeCmd.Flags().StringArrayVarP(&analyzeFiles, "file", "f", []string{}, "Configuration file to merge before analysis (repeatable; later files win)")
+	analyzeCmd.Flags().StringVar(&analyzeRemoteProject, "remote-project", "", "Analyze the server-merged configuration of this GitLab project (ID or path)")
+	analyzeCmd.Flags().StringVar(&analyzeRemoteRef, "ref", "main", "Branch, tag or commit to fetch with --remote-project")
+	analyzeCmd.Flags().StringVar(&analyzeGitLabURL, "gitlab-url", "https://gitlab.com", "GitLab URL for --remote-project")
+	analyzeCmd.Flags().StringVar(&analyzeGitLabToken, "gitlab-token", "", "GitLab token for --remote-project")
+	analyzeCmd.Flags().BoolVar(&analyzeFixable, "fixable", false, "List only the issues that can be fixed automatically, each with a preview of the fix; no file is written")
+	analyzeCmd.Flags().BoolVar(&analyzePlan, "plan", false, "Print a ranked refactoring plan that groups related issues instead of the issue list")
+	analyzeCmd.Flags().StringSliceVar(&analyzeFailOn, "fail-on", []string{}, "Issue types that fail the analysis with exit code 2; other issues exit 1 (performance, security, maintainability, reliability)")
+	analyzeCmd.Flags().StringVar(&analyzeFailOnSeverity, "fail-on-severity", "", "Minimum severity that fails the analysis with exit code 2 (low, medium, high)")
+	analyzeCmd.Flags().StringVar(&analyzeMinSeverity, "min-severity", "", "Only list issues of at least this severity; the summary still counts all issues (low, medium, high)")
+	analyzeCmd.Flags().StringSliceVar(&analyzeOnlyTypes, "only-types", []string{}, "Only list issues of these types; the summary still counts all issues (performance, security, maintainability, reliability)")
+	analyzeCmd.Flags().StringVar(&analyzeRepoDir, "repo-dir", "", "Repository checkout used to verify that files the configuration references, such as cache key files, exist")
+	analyzeCmd.Flags().BoolVar(&analyzeNoColor, "no-color", false, "Disable colored table output (also disabled by NO_COLOR or when not writing to a terminal)")
+	analyzeCmd.Flags().StringVar(&analyzeOutputDir, "output-dir", "", "Analyze each file on its own and write one report per file, plus index.json, into this directory")
 	rootCmd.AddCommand(analyzeCmd)
 }
 
 func runAnalyze(cmd *cobra.Command, args []string) error {
-	configFile := args[0]
+	sources := append(append([]string{}, args...), analyzeFiles...)
+	if analyzeRemoteProject != "" {
+		if len(sources) > 0 {
+			return fmt.Errorf("--remote-project cannot be combined with local configuration files")
+		}
+		if analyzeWatch {
+			return fmt.Errorf("--watch cannot be used with --remote-project")
+		}
+		return analyzeRemote(cmd)
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("no configuration given: pass a file, - for stdin, or -f")
+	}
+	if analyzeBaseline != "" && (analyzePlan || analyzeFixable) {
+		return fmt.Errorf("--baseline cannot be combined with --plan or --fixable")
+	}
 
-	// Make path absolute for cleaner display
-	absPath, err := filepath.Abs(configFile)
+	if analyzeOutputDir != "" {
+		if analyzeWatch || analyzeBaseline != "" || analyzePlan {
+			return fmt.Errorf("--output-dir cannot be combined with --watch, --baseline or --plan")
+		}
+		return analyzeToDir(cmd, sources, analyzeOutputDir)
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("accepts at most 1 file, received %d; use -f to merge files or --output-dir to analyze each", len(args))
+	}
+
+	if analyzeWatch {
+		for _, source := range sources {
+			if source == stdinSource {
+				return fmt.Errorf("--watch cannot be used when reading from stdin")
+			}
+		}
+		return watchAnalysis(cmd, sources)
+	}
+
+	_, err := analyzeSources(cmd, sources)
+	return err
+}
+
+// stdinSource is the source name that reads the configuration from stdin
+const stdinSource = "-"
+
+// analyzeSources runs and prints one analysis of the merged sources,
+// returning the files it read so watch mode knows what to monitor
+func analyzeSources(cmd *cobra.Command, sources []string) ([]string, error) {
+	config, files, err := loadSources(cmd, sources)
 	if err != nil {
-		absPath = configFile
+		return files, fmt.Errorf("failed to parse GitLab CI config: %w", err)
 	}
+	return files, analyzeConfig(cmd, config, displaySources(sources))
+}
 
-	// Parse the GitLab CI configuration with includes
-	config, err := parser.ParseFile(configFile)
+// analyzeRemote analyzes the configuration GitLab merges for --remote-project
+// at --ref, which includes templates that only the server can reach
+func analyzeRemote(cmd *cobra.Command) error {
+	client := renderer.NewGitLabClient(analyzeGitLabURL, analyzeGitLabToken, analyzeRemoteProject)
+	data, err := client.FetchMergedConfig(cmd.Context(), analyzeRemoteRef)
+	if err != nil {
+		return fmt.Errorf("failed to fetch merged config: %w", err)
+	}
+
+	config, err := parser.Parse(data)
 	if err != nil {
 		return fmt.Errorf("failed to parse GitLab CI config: %w", err)
 	}
+	return analyzeConfig(cmd, config, fmt.Sprintf("%s@%s (merged by GitLab)", analyzeRemoteProject, analyzeRemoteRef))
+}
 
-	// Create analyzer with configuration
+// newAnalyzer creates the analyzer from --config and applies the other CLI
+// overrides to its configuration
+func newAnalyzer() (*analyzer.Analyzer, error) {
 	var analyzerInstance *analyzer.Analyzer
 	if analyzeConfigFile != "" {
 		var err error
 		analyzerInstance, err = analyzer.NewFromConfigFile(analyzeConfigFile)
 		if err != nil {
-			return fmt.Errorf("failed to load configuration: %w", err)
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
 		}
 	} else {
 		analyzerInstance = analyzer.New()
 	}
 
 	// Apply CLI overrides
+	if analyzePolicyDir != "" {
+		if err := analyzerInstance.LoadPolicies(analyzePolicyDir); err != nil {
+			return nil, err
+		}
+	}
 	if analyzeSeverityThreshold != "" {
 		analyzerInstance.GetConfig().Analyzer.SeverityThreshold = types.Severity(analyzeSeverityThreshold)
 	}
 	for _, checkName := range analyzeDisableChecks {
 		analyzerInstance.DisableCheck(checkName)
 	}
+	if analyzeRepoDir != "" {
+		files, err := parser.RepositoryFiles(analyzeRepoDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repository files: %w", err)
+		}
+		analyzerInstance.GetConfig().RepositoryFiles = files
+	}
+	if err := applyExitPolicyFlags(&analyzerInstance.GetConfig().Analyzer.ExitPolicy); err != nil {
+		return nil, err
+	}
+	return analyzerInstance, nil
+}
+
+// analyzeConfig runs and prints the analysis of a loaded configuration
+func analyzeConfig(cmd *cobra.Command, config *parser.GitLabConfig, absPath string) error {
+	analyzerInstance, err := newAnalyzer()
+	if err != nil {
+		return err
+	}
+	policy := &analyzerInstance.GetConfig().Analyzer.ExitPolicy
+	filter, err := parseIssueFilter(analyzeMinSeverity, analyzeOnlyTypes)
+	if err != nil {
+		return err
+	}
 
 	// Run analysis
 	result := analyzerInstance.Analyze(config)
 
-	switch analyzeFormat {
-	case "json":
-		return outputAnalysisJSON(cmd, result, absPath)
-	case "table":
-		return outputAnalysisTable(cmd, result, absPath)
+	if analyzeBaseline != "" {
+		return runBaselineAnalysis(cmd, analyzerInstance, result, filter, policy, absPath)
+	}
+
+	// The exit policy judges every issue, not just the ones listed
+	shown := result.Filtered(filter)
+	switch {
+	case analyzeFixable:
+		err = outputFixable(cmd, shown, absPath)
+	case analyzePlan:
+		err = outputPlan(cmd, analyzer.BuildRefactoringPlan(shown), absPath)
+	case analyzeFormat == "json":
+		err = outputAnalysisJSON(cmd.OutOrStdout(), shown, absPath)
+	case analyzeFormat == "table":
+		err = outputAnalysisTable(cmd.OutOrStdout(), shown, absPath)
 	default:
-		return fmt.Errorf("unsupported format: %s (supported: table, json)", analyzeFormat)
+		err = fmt.Errorf("unsupported format: %s (supported: table, json)", analyzeFormat)
 	}
+	if err != nil {
+		return err
+	}
+	return analysisExitError(cmd, result, policy)
+}
+
+// applyExitPolicyFlags overrides the configured exit policy with --fail-on
+// and --fail-on-severity
+func applyExitPolicyFlags(policy *types.ExitPolicy) error {
+	if len(analyzeFailOn) > 0 {
+		issueTypes, err := parseIssueTypes("--fail-on", analyzeFailOn)
+		if err != nil {
+			return err
+		}
+		policy.FailOn = issueTypes
+	}
+	if analyzeFailOnSeverity != "" {
+		severity := types.Severity(analyzeFailOnSeverity)
+		if types.SeverityRank(severity) == 0 {
+			return fmt.Errorf("unknown severity for --fail-on-severity: %s (supported: low, medium, high)", analyzeFailOnSeverity)
+		}
+		policy.FailOnSeverity = severity
+	}
+	return nil
 }
 
-func outputAnalysisJSON(cmd *cobra.Command, result *types.AnalysisResult, filePath string) error {
+// parseIssueFilter builds the filter for --min-severity and --only-types
+func parseIssueFilter(minSeverity string, onlyTypes []string) (types.IssueFilter, error) {
+	var filter types.IssueFilter
+	if minSeverity != "" {
+		filter.MinSeverity = types.Severity(minSeverity)
+		if types.SeverityRank(filter.MinSeverity) == 0 {
+			return filter, fmt.Errorf("unknown severity for --min-severity: %s (supported: low, medium, high)", minSeverity)
+		}
+	}
+	issueTypes, err := parseIssueTypes("--only-types", onlyTypes)
+	if err != nil {
+		return filter, err
+	}
+	filter.Types = issueTypes
+	return filter, nil
+}
+
+// parseIssueTypes validates the issue type names given to flag
+func parseIssueTypes(flag string, names []string) ([]types.IssueType, error) {
+	var issueTypes []types.IssueType
+	for _, name := range names {
+		issueType := types.IssueType(strings.TrimSpace(name))
+		switch issueType {
+		case types.IssueTypePerformance, types.IssueTypeSecurity, types.IssueTypeMaintainability, types.IssueTypeReliability:
+			issueTypes = append(issueTypes, issueType)
+		default:
+			return nil, fmt.Errorf("unknown issue type for %s: %s (supported: performance, security, maintainability, reliability)", flag, name)
+		}
+	}
+	return issueTypes, nil
+}
+
+// analysisExitError returns the error that makes analyze exit 1 when only
+// warnings were found and 2 when an issue fails under the exit policy. Without
+// a policy, and in watch mode, analyze keeps exiting 0 whatever it finds.
+func analysisExitError(cmd *cobra.Command, result *types.AnalysisResult, policy *types.ExitPolicy) error {
+	if !policy.IsSet() || analyzeWatch {
+		return nil
+	}
+
+	code := result.ExitCode(policy)
+	if code == types.ExitClean {
+		return nil
+	}
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+
+	failing := 0
+	for _, issue := range result.Issues {
+		if policy.Fails(issue) {
+			failing++
+		}
+	}
+	if code == types.ExitFailures {
+		return &exitError{code: code, message: fmt.Sprintf("%d issue(s) fail the exit policy, %d warning(s)", failing, len(result.Issues)-failing)}
+	}
+	return &exitError{code: code, message: fmt.Sprintf("%d warning(s), none fail the exit policy", len(result.Issues))}
+}
+
+func outputAnalysisJSON(out io.Writer, result *types.AnalysisResult, filePath string) error {
 	output := map[string]interface{}{
 		"file":     filePath,
 		"analysis": result,
 	}
 
-	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder := json.NewEncoder(out)
 	encoder.SetIndent("", "  ")
 	return encoder.Encode(output)
 }
 
-func outputAnalysisTable(cmd *cobra.Command, result *types.AnalysisResult, filePath string) error {
-	out := cmd.OutOrStdout()
-
+func outputAnalysisTable(out io.Writer, result *types.AnalysisResult, filePath string) error {
 	fmt.Fprintf(out, "GitLab CI Analysis Report\n")
 	fmt.Fprintf(out, "========================\n")
 	fmt.Fprintf(out, "File: %s\n\n", filePath)
@@ -105,47 +345,27 @@ func outputAnalysisTable(cmd *cobra.Command, result *types.AnalysisResult, fileP
 	// Summary
 	fmt.Fprintf(out, "Summary\n")
 	fmt.Fprintf(out, "-------\n")
+	fmt.Fprintf(out, "Health Score: %d/100 (%s)\n", result.Health.Score, result.Health.Grade)
 	fmt.Fprintf(out, "Total Issues: %d\n", result.TotalIssues)
 	fmt.Fprintf(out, "  Performance: %d\n", result.Summary.Performance)
 	fmt.Fprintf(out, "  Security: %d\n", result.Summary.Security)
 	fmt.Fprintf(out, "  Maintainability: %d\n", result.Summary.Maintainability)
 	fmt.Fprintf(out, "  Reliability: %d\n", result.Summary.Reliability)
+	if result.HiddenIssues > 0 {
+		fmt.Fprintf(out, "  (%d hidden by --min-severity/--only-types)\n", result.HiddenIssues)
+	}
 	fmt.Fprintf(out, "\n")
 
 	if len(result.Issues) == 0 {
+		if result.HiddenIssues > 0 {
+			fmt.Fprintf(out, "No issues match the selected filters.\n")
+			return nil
+		}
 		fmt.Fprintf(out, "✅ No issues found! Your GitLab CI configuration looks good.\n")
 		return nil
 	}
 
-	// Group issues by severity
-	severityOrder := []types.Severity{types.SeverityHigh, types.SeverityMedium, types.SeverityLow}
-	severityLabels := map[types.Severity]string{
-		types.SeverityHigh:   "🔴 HIGH",
-		types.SeverityMedium: "🟡 MEDIUM",
-		types.SeverityLow:    "🟢 LOW",
-	}
-
-	for _, severity := range severityOrder {
-		issues := result.FilterBySeverity(severity)
-		if len(issues) == 0 {
-			continue
-		}
-
-		fmt.Fprintf(out, "%s SEVERITY (%d issues)\n", severityLabels[severity], len(issues))
-		fmt.Fprintf(out, "%s\n", getUnderline(len(severityLabels[severity])+12))
-
-		for _, issue := range issues {
-			fmt.Fprintf(out, "• [%s] %s\n", string(issue.Type), issue.Message)
-			fmt.Fprintf(out, "  Path: %s\n", issue.Path)
-			if issue.JobName != "" {
-				fmt.Fprintf(out, "  Job: %s\n", issue.JobName)
-			}
-			if issue.Suggestion != "" {
-				fmt.Fprintf(out, "  💡 %s\n", issue.Suggestion)
-			}
-			fmt.Fprintf(out, "\n")
-		}
-	}
+	fmt.Fprintf(out, "%s\n", result.FormatTable(useColor(out, analyzeNoColor)))
 
 	// Tips
 	fmt.Fprintf(out, "💡 Tips\n")
@@ -164,6 +384,208 @@ func outputAnalysisTable(cmd *cobra.Command, result *types.AnalysisResult, fileP
 	return nil
 }
 
+// useColor reports whether table output to out should be colored: only when
+// out is a terminal and neither --no-color nor NO_COLOR is set
+func useColor(out io.Writer, noColor bool) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	file, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// outputPlan prints the refactoring plan in the selected format
+func outputPlan(cmd *cobra.Command, plan *types.RefactoringPlan, filePath string) error {
+	switch analyzeFormat {
+	case "json":
+		output := map[string]interface{}{
+			"file": filePath,
+			"plan": plan,
+		}
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(output)
+	case "table":
+		return outputPlanTable(cmd, plan, filePath)
+	default:
+		return fmt.Errorf("unsupported format: %s (supported: table, json)", analyzeFormat)
+	}
+}
+
+func outputPlanTable(cmd *cobra.Command, plan *types.RefactoringPlan, filePath string) error {
+	out := cmd.OutOrStdout()
+
+	fmt.Fprintf(out, "GitLab CI Refactoring Plan\n")
+	fmt.Fprintf(out, "==========================\n")
+	fmt.Fprintf(out, "File: %s\n\n", filePath)
+
+	if len(plan.Recommendations) == 0 {
+		fmt.Fprintf(out, "✅ Nothing to refactor! Your GitLab CI configuration looks good.\n")
+		return nil
+	}
+
+	fmt.Fprintf(out, "Health Score: %d/100 -> %d/100 when all %d issues are resolved\n\n", plan.CurrentScore, plan.ProjectedScore, plan.TotalIssues)
+
+	for _, rec := range plan.Recommendations {
+		fmt.Fprintf(out, "%d. %s [%s]\n", rec.Rank, rec.Title, rec.Severity)
+		fmt.Fprintf(out, "   %s\n", rec.Action)
+		fmt.Fprintf(out, "   Impact: resolves %d issues, +%d health score", rec.IssuesResolved, rec.ScoreGain)
+		if rec.MinutesSaved > 0 {
+			fmt.Fprintf(out, ", ~%d min saved per pipeline", rec.MinutesSaved)
+		}
+		fmt.Fprintf(out, "\n")
+		if len(rec.Jobs) > 0 {
+			fmt.Fprintf(out, "   Jobs: %s\n", strings.Join(rec.Jobs, ", "))
+		}
+		for _, issue := range rec.Issues {
+			fmt.Fprintf(out, "   • %s\n", issue.Message)
+		}
+		fmt.Fprintf(out, "\n")
+	}
+
+	return nil
+}
+
+// runBaselineAnalysis reports only the issues introduced relative to the
+// baseline config and fails when they exceed --max-new-issues or fail the
+// exit policy
+func runBaselineAnalysis(cmd *cobra.Command, analyzerInstance *analyzer.Analyzer, result *types.AnalysisResult, filter types.IssueFilter, policy *types.ExitPolicy, filePath string) error {
+	baseConfig, err := parser.ParseFile(analyzeBaseline)
+	if err != nil {
+		return fmt.Errorf("failed to parse baseline config: %w", err)
+	}
+
+	delta := analyzer.CompareAnalyses(analyzerInstance.Analyze(baseConfig), result)
+
+	// --max-new-issues counts every new issue, not just the ones listed
+	shown := &types.AnalysisDelta{
+		Added:     filter.Apply(delta.Added),
+		Removed:   filter.Apply(delta.Removed),
+		Unchanged: filter.Apply(delta.Unchanged),
+	}
+	switch analyzeFormat {
+	case "json":
+		err = outputDeltaJSON(cmd, shown, filePath)
+	case "table":
+		err = outputDeltaTable(cmd, shown, filePath)
+	default:
+		return fmt.Errorf("unsupported format: %s (supported: table, json)", analyzeFormat)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Only new issues are judged by the exit policy; one that fails it
+	// takes precedence over --max-new-issues
+	exitErr := analysisExitError(cmd, &types.AnalysisResult{Issues: delta.Added}, policy)
+	if failed, ok := exitErr.(*exitError); ok && failed.code == types.ExitFailures {
+		return exitErr
+	}
+	if len(delta.Added) > analyzeMaxNewIssues {
+		return fmt.Errorf("%d new issues introduced (maximum allowed: %d)", len(delta.Added), analyzeMaxNewIssues)
+	}
+	return exitErr
+}
+
+func outputDeltaJSON(cmd *cobra.Command, delta *types.AnalysisDelta, filePath string) error {
+	output := map[string]interface{}{
+		"file":     filePath,
+		"baseline": analyzeBaseline,
+		"delta":    delta,
+	}
+
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+func outputDeltaTable(cmd *cobra.Command, delta *types.AnalysisDelta, filePath string) error {
+	out := cmd.OutOrStdout()
+
+	fmt.Fprintf(out, "GitLab CI Baseline Comparison\n")
+	fmt.Fprintf(out, "=============================\n")
+	fmt.Fprintf(out, "File: %s\n", filePath)
+	fmt.Fprintf(out, "Baseline: %s\n\n", analyzeBaseline)
+
+	fmt.Fprintf(out, "New Issues: %d\n", len(delta.Added))
+	fmt.Fprintf(out, "Resolved Issues: %d\n", len(delta.Removed))
+	fmt.Fprintf(out, "Existing Issues: %d\n\n", len(delta.Unchanged))
+
+	if len(delta.Added) == 0 {
+		fmt.Fprintf(out, "✅ No new issues introduced.\n")
+		return nil
+	}
+
+	fmt.Fprintf(out, "New Issues\n")
+	fmt.Fprintf(out, "----------\n")
+	for _, issue := range delta.Added {
+		fmt.Fprintf(out, "• [%s/%s] %s\n", string(issue.Type), string(issue.Severity), issue.Message)
+		fmt.Fprintf(out, "  Path: %s\n", issue.Path)
+		if issue.SourceFile != "" {
+			fmt.Fprintf(out, "  File: %s\n", issue.SourceFile)
+		}
+		if issue.Suggestion != "" {
+			fmt.Fprintf(out, "  💡 %s\n", issue.Suggestion)
+		}
+		fmt.Fprintf(out, "\n")
+	}
+
+	return nil
+}
+
+// loadSources parses each source with its includes and merges them in order.
+// Local includes of stdin are resolved relative to the working directory.
+func loadSources(cmd *cobra.Command, sources []string) (*parser.GitLabConfig, []string, error) {
+	var configs []*parser.GitLabConfig
+	var files []string
+
+	for _, source := range sources {
+		resolver := parser.NewIncludeResolver("", "")
+		var config *parser.GitLabConfig
+		var err error
+		if source == stdinSource {
+			config, err = parser.ParseReader(cmd.InOrStdin())
+			if err == nil {
+				err = parser.ResolveIncludesWithResolver(config, ".", resolver)
+			}
+		} else {
+			files = append(files, source)
+			config, err = parser.ParseFileWithResolver(source, resolver)
+		}
+		files = append(files, resolver.LocalFiles()...)
+		if err != nil {
+			return nil, files, err
+		}
+		configs = append(configs, config)
+	}
+
+	if len(configs) == 1 {
+		return configs[0], files, nil
+	}
+	return parser.MergeConfigs(configs...), files, nil
+}
+
+// displaySources returns the sources as absolute paths for cleaner display
+func displaySources(sources []string) string {
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		if source == stdinSource {
+			names[i] = "<stdin>"
+			continue
+		}
+		absPath, err := filepath.Abs(source)
+		if err != nil {
+			absPath = source
+		}
+		names[i] = absPath
+	}
+	return strings.Join(names, ", ")
+}
+
 func getUnderline(length int) string {
 	underline := ""
 	for i := 0; i < length; i++ {
@@ -2,14 +2,19 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
 )
 
 func TestAnalyzeCommand(t *testing.T) {
@@ -336,3 +341,626 @@ performance, security, and reliability.`,
 		t.Error("Expected substantial help output")
 	}
 }
+
+func TestOutputAnalysisJSONIncludesHealth(t *testing.T) {
+	result := &types.AnalysisResult{
+		Issues: []types.Issue{
+			{Type: types.IssueTypeSecurity, Severity: types.SeverityHigh, Path: "jobs.deploy", Message: "Secret in script"},
+		},
+		TotalIssues: 1,
+		JobCount:    4,
+	}
+	result.Health = result.CalculateHealth()
+
+	var buf bytes.Buffer
+	if err := outputAnalysisJSON(&buf, result, "ci.yml"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var output struct {
+		Analysis struct {
+			Health types.HealthScore `json:"health"`
+		} `json:"analysis"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	if output.Analysis.Health != result.Health {
+		t.Errorf("Expected health %+v, got %+v", result.Health, output.Analysis.Health)
+	}
+	if output.Analysis.Health.Breakdown.Security == 100 {
+		t.Error("Expected security breakdown to reflect the high severity issue")
+	}
+}
+
+func TestRunAnalyzeWithBaseline(t *testing.T) {
+	tempDir := t.TempDir()
+	baseFile := filepath.Join(tempDir, "base.yml")
+	headFile := filepath.Join(tempDir, "head.yml")
+
+	base := `
+stages: [build]
+build:
+  stage: build
+  image: node:18
+  script:
+    - npm run build
+`
+	head := `
+stages: [build]
+build:
+  stage: build
+  image: node
+  script:
+    - npm run build
+`
+	if err := os.WriteFile(baseFile, []byte(base), 0644); err != nil {
+		t.Fatalf("Failed to write baseline: %v", err)
+	}
+	if err := os.WriteFile(headFile, []byte(head), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	defer func() {
+		analyzeFormat, analyzeBaseline, analyzeMaxNewIssues = "table", "", 0
+	}()
+
+	run := func(maxNew int, format string) (string, error) {
+		analyzeFormat, analyzeBaseline, analyzeMaxNewIssues = format, baseFile, maxNew
+		cmd := &cobra.Command{}
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		err := runAnalyze(cmd, []string{headFile})
+		return buf.String(), err
+	}
+
+	output, err := run(0, "table")
+	if err == nil || !strings.Contains(err.Error(), "new issues introduced") {
+		t.Errorf("Expected threshold error, got %v", err)
+	}
+	if !strings.Contains(output, "New Issues: 1") || !strings.Contains(output, "without explicit tag") {
+		t.Errorf("Expected the untagged image to be reported as new, got: %s", output)
+	}
+	if !strings.Contains(output, "  File: "+headFile+"\n") {
+		t.Errorf("Expected the new issue's source file to be shown, got: %s", output)
+	}
+
+	output, err = run(1, "json")
+	if err != nil {
+		t.Fatalf("Expected no error within threshold, got %v", err)
+	}
+	var result struct {
+		Delta types.AnalysisDelta `json:"delta"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if len(result.Delta.Added) != 1 {
+		t.Errorf("Expected 1 added issue in JSON delta, got %d", len(result.Delta.Added))
+	}
+}
+
+func TestRunAnalyzeFromStdinAndMultipleFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	baseFile := filepath.Join(tempDir, "base.yml")
+	overrideFile := filepath.Join(tempDir, "override.yml")
+
+	base := `
+stages: [build]
+build:
+  stage: build
+  image: node
+  script:
+    - npm run build
+`
+	override := `
+build:
+  stage: build
+  image: node:20.11.0
+  script:
+    - npm run build
+`
+	if err := os.WriteFile(baseFile, []byte(base), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.WriteFile(overrideFile, []byte(override), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	defer func() {
+		analyzeFormat, analyzeFiles = "table", []string{}
+	}()
+	analyzeFormat = "json"
+
+	run := func(args []string, files []string, stdin string) (*types.AnalysisResult, error) {
+		analyzeFiles = files
+		cmd := &cobra.Command{}
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetIn(strings.NewReader(stdin))
+		if err := runAnalyze(cmd, args); err != nil {
+			return nil, err
+		}
+		var output struct {
+			Analysis types.AnalysisResult `json:"analysis"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+			return nil, fmt.Errorf("invalid JSON output: %w", err)
+		}
+		return &output.Analysis, nil
+	}
+
+	result, err := run([]string{"-"}, nil, base)
+	if err != nil {
+		t.Fatalf("Unexpected error reading stdin: %v", err)
+	}
+	if len(result.FilterByType(types.IssueTypeSecurity)) == 0 {
+		t.Error("Expected the untagged image read from stdin to be reported")
+	}
+
+	result, err = run(nil, []string{baseFile, overrideFile}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error merging files: %v", err)
+	}
+	if len(result.FilterByType(types.IssueTypeSecurity)) != 0 {
+		t.Errorf("Expected the last file's tagged image to win, got %+v", result.Issues)
+	}
+
+	if _, err := run(nil, nil, ""); err == nil {
+		t.Error("Expected an error when no configuration is given")
+	}
+}
+
+func TestRunAnalyzeRemoteProject(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"valid":       true,
+			"merged_yaml": "build:\n  image: node\n  script: [npm run build]\n",
+		})
+	}))
+	defer server.Close()
+
+	defer func() {
+		analyzeFormat, analyzeRemoteProject, analyzeGitLabURL = "table", "", "https://gitlab.com"
+	}()
+	analyzeFormat = "json"
+	analyzeRemoteProject = "123"
+	analyzeGitLabURL = server.URL
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	if err := runAnalyze(cmd, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var output struct {
+		File     string               `json:"file"`
+		Analysis types.AnalysisResult `json:"analysis"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if !strings.Contains(output.File, "123@main") {
+		t.Errorf("Expected the remote project and ref as the file, got %q", output.File)
+	}
+	if len(output.Analysis.FilterByType(types.IssueTypeSecurity)) == 0 {
+		t.Error("Expected the untagged image in the merged config to be reported")
+	}
+
+	if err := runAnalyze(cmd, []string{"local.yml"}); err == nil {
+		t.Error("Expected an error when combining --remote-project with local files")
+	}
+}
+
+func TestRunAnalyzeWithPlan(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "plan.yml")
+
+	content := `
+stages: [build, test]
+build:
+  stage: build
+  image: node
+  cache:
+    paths: [node_modules/]
+  script:
+    - npm ci
+    - npm run build
+test:
+  stage: test
+  image: node
+  cache:
+    paths: [node_modules/]
+  script:
+    - npm ci
+    - npm test
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	defer func() {
+		analyzeFormat, analyzePlan = "table", false
+	}()
+	analyzePlan = true
+
+	run := func(format string) string {
+		analyzeFormat = format
+		cmd := &cobra.Command{}
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		if err := runAnalyze(cmd, []string{testFile}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return buf.String()
+	}
+
+	output := run("table")
+	if !strings.Contains(output, "Refactoring Plan") || !strings.Contains(output, "1. ") || !strings.Contains(output, "Impact: resolves") {
+		t.Errorf("Expected a ranked plan, got: %s", output)
+	}
+
+	var result struct {
+		Plan types.RefactoringPlan `json:"plan"`
+	}
+	if err := json.Unmarshal([]byte(run("json")), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if len(result.Plan.Recommendations) == 0 || result.Plan.Recommendations[0].Rank != 1 {
+		t.Fatalf("Expected ranked recommendations, got %+v", result.Plan)
+	}
+	resolved := 0
+	for _, rec := range result.Plan.Recommendations {
+		resolved += rec.IssuesResolved
+	}
+	if resolved != result.Plan.TotalIssues {
+		t.Errorf("Expected every issue in exactly one recommendation, got %d of %d", resolved, result.Plan.TotalIssues)
+	}
+}
+
+func TestRunAnalyzeFixable(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), ".gitlab-ci.yml")
+	content := `stages: [build, build]
+build:
+  stage: build
+  image: node:latest
+  script: [npm run build]
+  artifacts:
+    paths: [dist/]
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	defer func() {
+		analyzeFormat, analyzeFixable = "table", false
+	}()
+	analyzeFixable = true
+
+	run := func(format string) string {
+		analyzeFormat = format
+		cmd := &cobra.Command{}
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		if err := runAnalyze(cmd, []string{testFile}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return buf.String()
+	}
+
+	output := run("table")
+	if !strings.Contains(output, "(duplicate_stages)") || !strings.Contains(output, "+    expire_in: 30 days") {
+		t.Errorf("Expected fixable issues with patches, got: %s", output)
+	}
+	if strings.Contains(output, "image_tags") {
+		t.Errorf("Expected issues without a fixer to be left out, got: %s", output)
+	}
+
+	var result struct {
+		Fixable []FixableIssue `json:"fixable"`
+	}
+	if err := json.Unmarshal([]byte(run("json")), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if len(result.Fixable) != 2 {
+		t.Fatalf("Expected 2 fixable issues, got %+v", result.Fixable)
+	}
+	for _, issue := range result.Fixable {
+		if !issue.Fixable || issue.Patch == "" {
+			t.Errorf("Expected a fixable issue with a patch, got %+v", issue)
+		}
+	}
+
+	after, _ := os.ReadFile(testFile)
+	if string(after) != content {
+		t.Error("Expected --fixable not to write the file")
+	}
+}
+
+func TestRunAnalyzeExitPolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "gate.yml")
+	// An untagged image is a medium security issue; the missing stages
+	// declaration a maintainability one
+	content := `
+build:
+  image: node
+  script: [npm ci]
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	defer func() {
+		analyzeFailOn, analyzeFailOnSeverity = []string{}, ""
+	}()
+
+	run := func(failOn []string, severity string) error {
+		analyzeFailOn, analyzeFailOnSeverity = failOn, severity
+		cmd := &cobra.Command{}
+		cmd.SetOut(&bytes.Buffer{})
+		return runAnalyze(cmd, []string{testFile})
+	}
+	exitCode := func(err error) int {
+		if err == nil {
+			return 0
+		}
+		exitErr, ok := err.(*exitError)
+		if !ok {
+			t.Fatalf("Expected an exit error, got %v", err)
+		}
+		return exitErr.code
+	}
+
+	if err := run(nil, ""); err != nil {
+		t.Errorf("Expected analyze to exit 0 without an exit policy, got %v", err)
+	}
+	if code := exitCode(run([]string{"security", "reliability"}, "")); code != 2 {
+		t.Errorf("Expected exit code 2 for a security issue, got %d", code)
+	}
+	if code := exitCode(run([]string{"reliability"}, "")); code != 1 {
+		t.Errorf("Expected exit code 1 with only warnings, got %d", code)
+	}
+	if code := exitCode(run([]string{"security"}, "high")); code != 1 {
+		t.Errorf("Expected exit code 1 when the security issue is below the severity, got %d", code)
+	}
+	if err := run([]string{"style"}, ""); err == nil || !strings.Contains(err.Error(), "unknown issue type") {
+		t.Errorf("Expected an error for an unknown issue type, got %v", err)
+	}
+}
+
+func TestRunAnalyzeBaselineExitPolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	baseFile := filepath.Join(tempDir, "base.yml")
+	headFile := filepath.Join(tempDir, "head.yml")
+
+	base := `
+stages: [build]
+build:
+  stage: build
+  image: node
+  script: [npm run build]
+`
+	// A hardcoded password is a new high severity security issue; the
+	// untagged image is already in the baseline
+	head := `
+stages: [build]
+variables:
+  DB_PASSWORD: hunter2secret
+build:
+  stage: build
+  image: node
+  script: [npm run build]
+`
+	if err := os.WriteFile(baseFile, []byte(base), 0644); err != nil {
+		t.Fatalf("Failed to write baseline: %v", err)
+	}
+	if err := os.WriteFile(headFile, []byte(head), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	defer func() {
+		analyzeBaseline, analyzeMaxNewIssues, analyzeFailOnSeverity = "", 0, ""
+		analyzePlan, analyzeFixable = false, false
+	}()
+
+	run := func(config, severity string) error {
+		analyzeBaseline, analyzeMaxNewIssues, analyzeFailOnSeverity = baseFile, 10, severity
+		cmd := &cobra.Command{}
+		cmd.SetOut(&bytes.Buffer{})
+		return runAnalyze(cmd, []string{config})
+	}
+
+	err := run(headFile, "high")
+	exitErr, ok := err.(*exitError)
+	if !ok || exitErr.code != types.ExitFailures {
+		t.Fatalf("Expected exit code 2 for a new high severity issue, got %v", err)
+	}
+	if err := run(baseFile, "medium"); err != nil {
+		t.Errorf("Expected issues already in the baseline not to fail, got %v", err)
+	}
+
+	analyzePlan = true
+	if err := run(headFile, ""); err == nil || !strings.Contains(err.Error(), "--baseline cannot be combined") {
+		t.Errorf("Expected --plan to be rejected with --baseline, got %v", err)
+	}
+	analyzePlan, analyzeFixable = false, true
+	if err := run(headFile, ""); err == nil || !strings.Contains(err.Error(), "--baseline cannot be combined") {
+		t.Errorf("Expected --fixable to be rejected with --baseline, got %v", err)
+	}
+}
+
+func TestRunAnalyzeIssueFilter(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "filter.yml")
+	// An untagged image is a medium security issue; the missing stages
+	// declaration a maintainability one
+	content := `
+build:
+  image: node
+  script: [npm ci]
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	defer func() {
+		analyzeMinSeverity, analyzeOnlyTypes, analyzeFormat = "", []string{}, "table"
+	}()
+
+	run := func(format, minSeverity string, onlyTypes []string) (string, error) {
+		analyzeFormat, analyzeMinSeverity, analyzeOnlyTypes = format, minSeverity, onlyTypes
+		var out bytes.Buffer
+		cmd := &cobra.Command{}
+		cmd.SetOut(&out)
+		err := runAnalyze(cmd, []string{testFile})
+		return out.String(), err
+	}
+
+	output, err := run("json", "", []string{"security"})
+	if err != nil {
+		t.Fatalf("analyze failed: %v", err)
+	}
+	var parsed struct {
+		Analysis types.AnalysisResult `json:"analysis"`
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if len(parsed.Analysis.Issues) == 0 || parsed.Analysis.HiddenIssues == 0 {
+		t.Fatalf("Expected security issues shown and others hidden, got %+v", parsed.Analysis)
+	}
+	for _, issue := range parsed.Analysis.Issues {
+		if issue.Type != types.IssueTypeSecurity {
+			t.Errorf("Expected only security issues, got %+v", issue)
+		}
+	}
+	if parsed.Analysis.TotalIssues != len(parsed.Analysis.Issues)+parsed.Analysis.HiddenIssues {
+		t.Errorf("Expected the total to count hidden issues, got %d", parsed.Analysis.TotalIssues)
+	}
+
+	output, err = run("table", "high", []string{"security"})
+	if err != nil {
+		t.Fatalf("analyze failed: %v", err)
+	}
+	if !strings.Contains(output, "hidden by --min-severity/--only-types") || !strings.Contains(output, "No issues match the selected filters") {
+		t.Errorf("Expected the table to note hidden issues, got:\n%s", output)
+	}
+
+	if _, err := run("table", "critical", nil); err == nil || !strings.Contains(err.Error(), "unknown severity") {
+		t.Errorf("Expected an error for an unknown severity, got %v", err)
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	if useColor(&bytes.Buffer{}, false) {
+		t.Error("Expected no color when not writing to a terminal")
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer file.Close()
+	if useColor(file, false) {
+		t.Error("Expected no color when writing to a regular file")
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip("no terminal available")
+	}
+	defer tty.Close()
+	if !useColor(tty, false) {
+		t.Error("Expected color on a terminal")
+	}
+	if useColor(tty, true) {
+		t.Error("Expected --no-color to disable color")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if useColor(tty, false) {
+		t.Error("Expected NO_COLOR to disable color")
+	}
+}
+
+func TestAnalyzeOutputDir(t *testing.T) {
+	dir := t.TempDir()
+	outDir := filepath.Join(dir, "reports")
+	config := "build:\n  stage: build\n  image: node\n  script: [npm ci]\n"
+	for _, name := range []string{"a.yml", "ci/a.yml", "ci_a.yml"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+
+	defer func() {
+		analyzeFormat, analyzeOutputDir = "table", ""
+	}()
+	analyzeFormat, analyzeOutputDir = "json", outDir
+
+	// Relative sources keep the report names short
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+
+	cmd := &cobra.Command{}
+	cmd.SetOut(&bytes.Buffer{})
+	err := runAnalyze(cmd, []string{"ci_a.yml", "missing.yml", "ci/a.yml", "a.yml"})
+	if err == nil || !strings.Contains(err.Error(), "1 of 4") {
+		t.Errorf("Expected an error for the missing file, got %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "index.json"))
+	if err != nil {
+		t.Fatalf("Expected an index: %v", err)
+	}
+	var index ReportIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("Invalid index: %v", err)
+	}
+
+	reports := make(map[string]string)
+	for _, entry := range index.Files {
+		reports[entry.Source] = entry.Report
+		if entry.Source == "missing.yml" && !strings.Contains(entry.Error, "no such file") {
+			t.Errorf("Expected the load error in the index, got %+v", entry)
+		}
+	}
+	expected := map[string]string{
+		"a.yml":       "a.yml.json",
+		"ci/a.yml":    "ci_a.yml.json",
+		"ci_a.yml":    "ci_a.yml-2.json",
+		"missing.yml": "",
+	}
+	if !reflect.DeepEqual(reports, expected) {
+		t.Errorf("Expected reports %v, got %v", expected, reports)
+	}
+	if index.Failed != 1 || index.TotalIssues == 0 || index.AverageScore == 0 {
+		t.Errorf("Unexpected index totals %+v", index)
+	}
+
+	var report struct {
+		File     string               `json:"file"`
+		Analysis types.AnalysisResult `json:"analysis"`
+	}
+	data, err = os.ReadFile(filepath.Join(outDir, "ci_a.yml-2.json"))
+	if err != nil {
+		t.Fatalf("Expected a report: %v", err)
+	}
+	if err := json.Unmarshal(data, &report); err != nil || report.File != "ci_a.yml" || report.Analysis.TotalIssues == 0 {
+		t.Errorf("Unexpected report %+v (%v)", report, err)
+	}
+
+	if err := runAnalyze(cmd, []string{"a.yml", "ci/a.yml"}); err != nil {
+		t.Errorf("Expected several files to be accepted with --output-dir, got %v", err)
+	}
+	analyzeOutputDir = ""
+	if err := runAnalyze(cmd, []string{"a.yml", "ci/a.yml"}); err == nil {
+		t.Error("Expected several files to be rejected without --output-dir")
+	}
+}
@@ -81,6 +81,11 @@ analyzer:
       # - "*-experimental"
       # - "sandbox-*"
 
+  # Exit policy for using analyze as a merge gate. When set, analyze exits 0
+  # when clean, 2 if any issue matches the policy and 1 for other issues
+  # fail_on: [security, reliability]
+  # fail_on_severity: medium
+
 # Configure individual checks
 # Each check can be enabled/disabled, have severity overridden, and have custom exclusions
 checks:
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wonderfulspam/gitlab-smith/pkg/differ"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old-file> <new-file> | --git <old-ref> <new-ref>",
+	Short: "Show the semantic differences between two GitLab CI configurations",
+	Long: `Compare two GitLab CI configurations and print the semantic, dependency and
+performance changes between them:
+  gitlab-smith diff old.yml .gitlab-ci.yml
+
+With --git, the arguments are git revisions and the configuration is read from
+each with git show, without checking anything out. --path selects the file,
+relative to the repository root. A file missing at a revision counts as an
+empty configuration, so every job shows up as added or removed:
+  gitlab-smith diff --git HEAD~1 HEAD
+  gitlab-smith diff --git origin/main HEAD --path ci/pipeline.yml
+
+Includes aren't resolved; only the given file is compared.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+var (
+	diffGit    bool
+	diffPath   string
+	diffFormat string
+	diffIgnore []string
+)
+
+func init() {
+	diffCmd.Flags().BoolVar(&diffGit, "git", false, "Compare the configuration at two git revisions")
+	diffCmd.Flags().StringVar(&diffPath, "path", ".gitlab-ci.yml", "Configuration file to read at each revision with --git")
+	diffCmd.Flags().StringVar(&diffFormat, "format", "table", "Output format: table, json")
+	diffCmd.Flags().StringArrayVar(&diffIgnore, "ignore", nil, "Drop diffs whose path matches this glob, e.g. 'variables.*' (repeatable)")
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	if diffFormat != "table" && diffFormat != "json" {
+		return fmt.Errorf("unsupported format: %s (supported: table, json)", diffFormat)
+	}
+
+	var configs [2]*parser.GitLabConfig
+	var files FileInfo
+	for i, arg := range args {
+		var data []byte
+		var err error
+		name := arg
+		if diffGit {
+			name = arg + ":" + diffPath
+			data, err = readFileAtRevision(arg, diffPath)
+		} else {
+			data, err = os.ReadFile(arg)
+		}
+		if err != nil {
+			return fmt.Errorf("reading '%s': %w", name, err)
+		}
+
+		configs[i], err = parser.Parse(data)
+		if err != nil {
+			return fmt.Errorf("parsing GitLab CI config '%s': %w", name, err)
+		}
+		if i == 0 {
+			files.Old = name
+		} else {
+			files.New = name
+		}
+	}
+
+	opts := differ.DefaultDifferOptions()
+	opts.IgnorePaths = diffIgnore
+	result := RefactorResult{
+		Comparison: differ.CompareWithOptions(configs[0], configs[1], opts),
+		Files:      files,
+	}
+
+	if diffFormat == "json" {
+		output, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling result to JSON: %w", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(output))
+		return nil
+	}
+	fmt.Fprint(cmd.OutOrStdout(), formatAsTable(&result))
+	return nil
+}
+
+// readFileAtRevision returns the content of path at a git revision, or no
+// content when the file doesn't exist there. An unknown revision is an error.
+func readFileAtRevision(revision, path string) ([]byte, error) {
+	if _, err := runGit("rev-parse", "--git-dir"); err != nil {
+		return nil, err
+	}
+	if _, err := runGit("rev-parse", "--verify", "--quiet", revision+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("unknown git revision %q", revision)
+	}
+	object := revision + ":" + strings.TrimPrefix(path, "./")
+	if _, err := runGit("cat-file", "-e", object); err != nil {
+		return nil, nil
+	}
+	return runGit("show", object)
+}
+
+// runGit runs git in the working directory, including its error output in
+// the returned error
+func runGit(args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	command := exec.Command("git", args...)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func runDiffForTest(t *testing.T, args []string, setup func()) (string, error) {
+	t.Helper()
+	defer func() {
+		diffGit, diffPath, diffFormat, diffIgnore = false, ".gitlab-ci.yml", "table", nil
+	}()
+	setup()
+
+	cmd := &cobra.Command{}
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	err := runDiff(cmd, args)
+	return buf.String(), err
+}
+
+func TestRunDiffFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	oldFile := filepath.Join(tempDir, "old.yml")
+	newFile := filepath.Join(tempDir, "new.yml")
+	os.WriteFile(oldFile, []byte("build:\n  script: [make]\n"), 0644)
+	os.WriteFile(newFile, []byte("build:\n  script: [make]\ntest:\n  script: [make test]\n"), 0644)
+
+	output, err := runDiffForTest(t, []string{oldFile, newFile}, func() {})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Old: "+oldFile) || !strings.Contains(output, "jobs.test") {
+		t.Errorf("Expected the added job in the table, got:\n%s", output)
+	}
+
+	if _, err := runDiffForTest(t, []string{oldFile, newFile}, func() { diffFormat = "yaml" }); err == nil {
+		t.Error("Expected an unsupported format to be rejected")
+	}
+}
+
+func TestRunDiffGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		command := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := command.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	commit := func(content, message string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repo, ".gitlab-ci.yml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", ".gitlab-ci.yml")
+		git("commit", "-q", "-m", message)
+	}
+
+	git("init", "-q")
+	os.WriteFile(filepath.Join(repo, "README.md"), []byte("readme\n"), 0644)
+	git("add", "README.md")
+	git("commit", "-q", "-m", "initial")
+	commit("build:\n  script: [make]\n", "add ci")
+	commit("build:\n  script: [make all]\ndeploy:\n  script: [./deploy.sh]\n", "add deploy")
+	t.Chdir(repo)
+
+	output, err := runDiffForTest(t, []string{"HEAD~1", "HEAD"}, func() {
+		diffGit = true
+		diffFormat = "json"
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result RefactorResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, output)
+	}
+	if result.Files.Old != "HEAD~1:.gitlab-ci.yml" || result.Files.New != "HEAD:.gitlab-ci.yml" {
+		t.Errorf("Expected revisions in the file names, got %+v", result.Files)
+	}
+	if !result.Comparison.HasChanges || !strings.Contains(output, "jobs.deploy") {
+		t.Errorf("Expected the added deploy job, got:\n%s", output)
+	}
+
+	// The configuration didn't exist in the first commit, so every job is new
+	output, err = runDiffForTest(t, []string{"HEAD~2", "HEAD"}, func() { diffGit = true })
+	if err != nil {
+		t.Fatalf("Unexpected error for a missing file: %v", err)
+	}
+	for _, job := range []string{"jobs.build", "jobs.deploy"} {
+		if !strings.Contains(output, job) {
+			t.Errorf("Expected %s to be added, got:\n%s", job, output)
+		}
+	}
+
+	if _, err := runDiffForTest(t, []string{"no-such-ref", "HEAD"}, func() { diffGit = true }); err == nil || !strings.Contains(err.Error(), "unknown git revision") {
+		t.Errorf("Expected an unknown revision error, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <check-name>",
+	Short: "Explain what a check looks for and how to fix its findings",
+	Long: `Print a check's description, why its findings matter, an example
+configuration before and after the fix, and related checks:
+  gitlab-smith explain image_tags
+
+List every check with --list.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if explainList {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: runExplain,
+}
+
+var explainList bool
+
+func init() {
+	explainCmd.Flags().BoolVar(&explainList, "list", false, "List all checks")
+	rootCmd.AddCommand(explainCmd)
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	a := analyzer.New()
+	out := cmd.OutOrStdout()
+
+	if explainList {
+		listExplainableChecks(out, a)
+		return nil
+	}
+
+	name := args[0]
+	registry := a.GetRegistry()
+	check, exists := registry.GetCheck(name)
+	if !exists {
+		return fmt.Errorf("unknown check %q; run 'gitlab-smith explain --list' to see all checks", name)
+	}
+
+	fmt.Fprintf(out, "%s (%s)\n", check.Name(), check.Type())
+	fmt.Fprintf(out, "%s\n", getUnderline(len(check.Name())+len(check.Type())+3))
+	if description := a.GetConfig().Checks[name].Description; description != "" {
+		fmt.Fprintf(out, "%s\n", description)
+	}
+
+	doc, documented := registry.GetDoc(name)
+	if !documented {
+		return nil
+	}
+
+	fmt.Fprintf(out, "\nWhy it matters:\n  %s\n", doc.Rationale)
+	fmt.Fprintf(out, "\nBefore:\n%s\n", indentBlock(doc.Example.Before))
+	fmt.Fprintf(out, "\nAfter:\n%s\n", indentBlock(doc.Example.After))
+	if len(doc.Related) > 0 {
+		fmt.Fprintf(out, "\nRelated checks: %s\n", strings.Join(doc.Related, ", "))
+	}
+	return nil
+}
+
+// listExplainableChecks prints every registered check with its type and
+// description, ordered by name
+func listExplainableChecks(out io.Writer, a *analyzer.Analyzer) {
+	checks := a.GetRegistry().GetChecks()
+
+	width := 0
+	for _, check := range checks {
+		if len(check.Name()) > width {
+			width = len(check.Name())
+		}
+	}
+
+	for _, check := range checks {
+		fmt.Fprintf(out, "%-*s  %-15s  %s\n", width, check.Name(), check.Type(), a.GetConfig().Checks[check.Name()].Description)
+	}
+}
+
+// indentBlock indents each line of an example snippet for display
+func indentBlock(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+	return strings.Join(lines, "\n")
+}
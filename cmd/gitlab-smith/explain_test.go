@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer"
+)
+
+func TestRunExplain(t *testing.T) {
+	run := func(list bool, args ...string) (string, error) {
+		defer func() { explainList = false }()
+		explainList = list
+		cmd := &cobra.Command{}
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		err := runExplain(cmd, args)
+		return buf.String(), err
+	}
+
+	output, err := run(false, "image_tags")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"image_tags (security)",
+		"Ensures Docker images and services use specific, non-floating tags",
+		"Why it matters:",
+		"Before:\n  test:\n    image: node:latest",
+		"After:",
+		"Related checks: duplicated_image_config",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	if _, err := run(false, "no_such_check"); err == nil || !strings.Contains(err.Error(), "explain --list") {
+		t.Errorf("Expected an unknown check error pointing at --list, got %v", err)
+	}
+
+	output, err = run(true)
+	if err != nil {
+		t.Fatalf("Unexpected error listing checks: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if want := len(analyzer.New().GetRegistry().GetChecks()); len(lines) != want {
+		t.Errorf("Expected %d checks listed, got %d", want, len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "allow_failure_critical") || !strings.Contains(lines[0], "reliability") {
+		t.Errorf("Expected checks ordered by name with their type, got %q", lines[0])
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer"
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/differ"
+	"github.com/wonderfulspam/gitlab-smith/pkg/fixer"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+var fixCmd = &cobra.Command{
+	Use:   "fix <file>",
+	Short: "Apply the mechanical fixes that analyze --fixable lists",
+	Long: `Analyze a GitLab CI configuration and fix the issues that have a mechanical
+fix, in the file itself and in the local includes the issues come from.
+Fixers exist for artifact_expiration, duplicate_stages and
+missing_interruptible; other issues are left for you to resolve.
+
+Fixed files are rewritten in the YAML encoder's layout, with comments kept.
+Use --dry-run to print the changes as a diff without writing anything:
+  gitlab-smith fix --dry-run .gitlab-ci.yml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFix,
+}
+
+var (
+	fixConfigFile string
+	fixDryRun     bool
+)
+
+func init() {
+	fixCmd.Flags().StringVar(&fixConfigFile, "config", "", "Configuration file path")
+	fixCmd.Flags().BoolVar(&fixDryRun, "dry-run", false, "Print the changes as a diff instead of writing them")
+	rootCmd.AddCommand(fixCmd)
+}
+
+func runFix(cmd *cobra.Command, args []string) error {
+	if args[0] == stdinSource {
+		return fmt.Errorf("fix writes the files it fixes and cannot read from stdin; use analyze --fixable - to preview")
+	}
+
+	analyzerInstance := analyzer.New()
+	if fixConfigFile != "" {
+		var err error
+		analyzerInstance, err = analyzer.NewFromConfigFile(fixConfigFile)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	config, err := parser.ParseFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse GitLab CI config: %w", err)
+	}
+	result := analyzerInstance.Analyze(config)
+
+	issuesByFile := make(map[string][]types.Issue)
+	for _, issue := range result.Issues {
+		if issue.Fixable && issue.SourceFile != "" {
+			issuesByFile[issue.SourceFile] = append(issuesByFile[issue.SourceFile], issue)
+		}
+	}
+	files := make([]string, 0, len(issuesByFile))
+	for file := range issuesByFile {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	out := cmd.OutOrStdout()
+	total := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			// Remote includes have no file to fix
+			continue
+		}
+		fixedData, fixed, err := fixer.Apply(data, issuesByFile[file])
+		if err != nil {
+			return fmt.Errorf("fixing %s: %w", file, err)
+		}
+		if len(fixed) == 0 {
+			continue
+		}
+		total += len(fixed)
+
+		if fixDryRun {
+			before, _, err := fixer.Apply(data, nil)
+			if err != nil {
+				return fmt.Errorf("fixing %s: %w", file, err)
+			}
+			fmt.Fprint(out, differ.UnifiedFileDiff(file, before, fixedData))
+			continue
+		}
+		if err := os.WriteFile(file, fixedData, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", file, err)
+		}
+		for _, issue := range fixed {
+			fmt.Fprintf(out, "Fixed %s: %s (%s)\n", file, issue.Message, issue.Check)
+		}
+	}
+
+	switch {
+	case total == 0:
+		fmt.Fprintf(out, "No issues can be fixed automatically.\n")
+	case fixDryRun:
+		fmt.Fprintf(out, "%d issue(s) would be fixed; no file was written.\n", total)
+	default:
+		fmt.Fprintf(out, "%d issue(s) fixed.\n", total)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRunFix(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), ".gitlab-ci.yml")
+	content := `stages: [build, build]
+build:
+  stage: build
+  image: node:latest
+  script: [npm run build]
+  artifacts:
+    paths: [dist/]
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	defer func() { fixDryRun = false }()
+	run := func(dryRun bool) string {
+		fixDryRun = dryRun
+		cmd := &cobra.Command{}
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		if err := runFix(cmd, []string{testFile}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return buf.String()
+	}
+
+	output := run(true)
+	if !strings.Contains(output, "+    expire_in: 30 days") || !strings.Contains(output, "2 issue(s) would be fixed") {
+		t.Errorf("Expected a diff of the fixes, got: %s", output)
+	}
+	if after, _ := os.ReadFile(testFile); string(after) != content {
+		t.Error("Expected --dry-run not to write the file")
+	}
+
+	output = run(false)
+	if !strings.Contains(output, "(duplicate_stages)") || !strings.Contains(output, "2 issue(s) fixed") {
+		t.Errorf("Expected the fixed issues to be listed, got: %s", output)
+	}
+	after, _ := os.ReadFile(testFile)
+	if !strings.Contains(string(after), "expire_in: 30 days") || !strings.Contains(string(after), "stages: [build]\n") {
+		t.Errorf("Expected the file to be fixed, got:\n%s", after)
+	}
+	if !strings.Contains(string(after), "image: node:latest") {
+		t.Errorf("Expected issues without a fixer to be left alone, got:\n%s", after)
+	}
+
+	if output := run(false); !strings.Contains(output, "No issues can be fixed automatically.") {
+		t.Errorf("Expected nothing left to fix, got: %s", output)
+	}
+}
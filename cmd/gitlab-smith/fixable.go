@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/fixer"
+)
+
+// FixableIssue is an issue with the patch that would fix it
+type FixableIssue struct {
+	types.Issue
+	Patch string `json:"patch"`
+}
+
+// fixableIssues returns the issues a fixer resolves, with a preview of the
+// change to their source file. Issues whose file can't be read, such as
+// configurations from stdin, or that the fixer can't apply to it are left out.
+func fixableIssues(issues []types.Issue) ([]FixableIssue, error) {
+	files := make(map[string][]byte)
+	var fixable []FixableIssue
+	for _, issue := range issues {
+		if !issue.Fixable || issue.SourceFile == "" {
+			continue
+		}
+		data, read := files[issue.SourceFile]
+		if !read {
+			data, _ = os.ReadFile(issue.SourceFile)
+			files[issue.SourceFile] = data
+		}
+		if data == nil {
+			continue
+		}
+
+		patch, err := fixer.Preview(issue.SourceFile, data, issue)
+		if err != nil {
+			return nil, fmt.Errorf("previewing the fix for %s: %w", issue.Path, err)
+		}
+		if patch != "" {
+			fixable = append(fixable, FixableIssue{Issue: issue, Patch: patch})
+		}
+	}
+	return fixable, nil
+}
+
+// outputFixable prints the issues --fixable lists with their patches,
+// without writing any file
+func outputFixable(cmd *cobra.Command, result *types.AnalysisResult, filePath string) error {
+	fixable, err := fixableIssues(result.Issues)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	switch analyzeFormat {
+	case "json":
+		output := map[string]interface{}{
+			"file":    filePath,
+			"fixable": fixable,
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(output)
+	case "table":
+		fmt.Fprintf(out, "Fixable Issues\n")
+		fmt.Fprintf(out, "==============\n")
+		fmt.Fprintf(out, "File: %s\n\n", filePath)
+		if len(fixable) == 0 {
+			fmt.Fprintf(out, "No issues can be fixed automatically.\n")
+			return nil
+		}
+		for i, issue := range fixable {
+			fmt.Fprintf(out, "%d. [%s] %s (%s)\n", i+1, issue.Severity, issue.Message, issue.Check)
+			fmt.Fprintf(out, "%s\n", issue.Patch)
+		}
+		fmt.Fprintf(out, "%d of %d issues can be fixed automatically.\n", len(fixable), len(result.Issues))
+		return nil
+	default:
+		return fmt.Errorf("unsupported format: %s (supported: table, json)", analyzeFormat)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer"
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+	"github.com/wonderfulspam/gitlab-smith/pkg/renderer"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint <file | ->",
+	Short: "Validate a GitLab CI configuration, optionally cross-checked with GitLab",
+	Long: `Parse and analyze a GitLab CI configuration locally. With --remote, the
+configuration is also sent to the project's CI lint API, and any disagreement
+between GitLab and gitlab-smith is reported, for example jobs that GitLab
+resolves from includes that couldn't be fetched locally:
+  gitlab-smith lint .gitlab-ci.yml --remote --project group/project --gitlab-token $TOKEN`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLint,
+}
+
+var (
+	lintFormat      string
+	lintRemote      bool
+	lintProject     string
+	lintRef         string
+	lintGitLabURL   string
+	lintGitLabToken string
+	lintShowMerged  bool
+	lintMinSeverity string
+	lintOnlyTypes   []string
+)
+
+func init() {
+	lintCmd.Flags().StringVar(&lintFormat, "format", "table", "Output format: table, json")
+	lintCmd.Flags().BoolVar(&lintRemote, "remote", false, "Also validate with GitLab's CI lint API")
+	lintCmd.Flags().StringVar(&lintProject, "project", "", "GitLab project (ID or path) to lint in the context of, required with --remote")
+	lintCmd.Flags().StringVar(&lintRef, "ref", "main", "Ref that project-relative includes resolve against")
+	lintCmd.Flags().StringVar(&lintGitLabURL, "gitlab-url", "https://gitlab.com", "GitLab URL for --remote")
+	lintCmd.Flags().StringVar(&lintGitLabToken, "gitlab-token", "", "GitLab token for --remote")
+	lintCmd.Flags().BoolVar(&lintShowMerged, "show-merged", false, "Print the merged YAML returned by GitLab (table format)")
+	lintCmd.Flags().StringVar(&lintMinSeverity, "min-severity", "", "Only list issues of at least this severity; totals still count all issues (low, medium, high)")
+	lintCmd.Flags().StringSliceVar(&lintOnlyTypes, "only-types", []string{}, "Only list issues of these types; totals still count all issues (performance, security, maintainability, reliability)")
+	rootCmd.AddCommand(lintCmd)
+}
+
+// lintReport combines the local analysis with GitLab's lint result
+type lintReport struct {
+	File          string                `json:"file"`
+	LocalError    string                `json:"local_error,omitempty"`
+	Analysis      *types.AnalysisResult `json:"analysis,omitempty"`
+	Remote        *renderer.LintResult  `json:"remote,omitempty"`
+	Discrepancies []string              `json:"discrepancies,omitempty"`
+
+	// highIssues counts the high severity issues, including hidden ones
+	highIssues int
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	if lintRemote && lintProject == "" {
+		return fmt.Errorf("--project is required with --remote")
+	}
+	filter, err := parseIssueFilter(lintMinSeverity, lintOnlyTypes)
+	if err != nil {
+		return err
+	}
+
+	source := args[0]
+	var data []byte
+	baseDir := "."
+	if source == stdinSource {
+		data, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		data, err = os.ReadFile(source)
+		baseDir = filepath.Dir(source)
+	}
+	if err != nil {
+		return fmt.Errorf("reading configuration: %w", err)
+	}
+
+	report := &lintReport{File: displaySources([]string{source})}
+
+	config, localErr := parser.Parse(data)
+	if localErr == nil {
+		localErr = parser.ResolveIncludes(config, baseDir)
+	}
+	if localErr != nil {
+		report.LocalError = localErr.Error()
+	} else {
+		analysis := analyzer.New().Analyze(config)
+		report.highIssues = len(analysis.FilterBySeverity(types.SeverityHigh))
+		report.Analysis = analysis.Filtered(filter)
+	}
+
+	if lintRemote {
+		client := renderer.NewGitLabClient(lintGitLabURL, lintGitLabToken, lintProject)
+		report.Remote, err = client.Lint(cmd.Context(), string(data), lintRef)
+		if err != nil {
+			return fmt.Errorf("GitLab lint request failed: %w", err)
+		}
+		report.Discrepancies = reconcileLint(config, localErr, report.Remote)
+	}
+
+	switch lintFormat {
+	case "json":
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	case "table":
+		outputLintTable(cmd, report)
+	default:
+		return fmt.Errorf("unsupported format: %s (supported: table, json)", lintFormat)
+	}
+	if err != nil {
+		return err
+	}
+
+	if localErr != nil {
+		return fmt.Errorf("configuration is invalid: %w", localErr)
+	}
+	if report.Remote != nil && !report.Remote.Valid {
+		return fmt.Errorf("GitLab reports the configuration as invalid")
+	}
+	return nil
+}
+
+// reconcileLint describes where GitLab's view of the configuration differs
+// from the local one: opposite validity verdicts, and jobs present in only
+// one of GitLab's merged YAML and the locally resolved config
+func reconcileLint(config *parser.GitLabConfig, localErr error, remote *renderer.LintResult) []string {
+	var discrepancies []string
+
+	switch {
+	case localErr == nil && !remote.Valid:
+		discrepancies = append(discrepancies, "GitLab rejects the configuration although gitlab-smith parsed it")
+	case localErr != nil && remote.Valid:
+		discrepancies = append(discrepancies, fmt.Sprintf("GitLab accepts the configuration although gitlab-smith failed to parse it: %v", localErr))
+	}
+	if localErr != nil || !remote.Valid || remote.MergedYAML == "" {
+		return discrepancies
+	}
+
+	merged, err := parser.Parse([]byte(remote.MergedYAML))
+	if err != nil {
+		return append(discrepancies, fmt.Sprintf("GitLab's merged YAML could not be parsed: %v", err))
+	}
+
+	local, server := visibleJobNames(config), visibleJobNames(merged)
+	if missing := subtractNames(server, local); len(missing) > 0 {
+		discrepancies = append(discrepancies, fmt.Sprintf("Jobs only GitLab resolved (likely from includes unreachable locally): %s", strings.Join(missing, ", ")))
+	}
+	if extra := subtractNames(local, server); len(extra) > 0 {
+		discrepancies = append(discrepancies, fmt.Sprintf("Jobs GitLab doesn't define: %s", strings.Join(extra, ", ")))
+	}
+	return discrepancies
+}
+
+func visibleJobNames(config *parser.GitLabConfig) map[string]bool {
+	names := make(map[string]bool)
+	for jobName := range config.Jobs {
+		if !parser.IsTemplateJob(jobName) {
+			names[jobName] = true
+		}
+	}
+	return names
+}
+
+// subtractNames returns the sorted names in a that are not in b
+func subtractNames(a, b map[string]bool) []string {
+	var names []string
+	for name := range a {
+		if !b[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func outputLintTable(cmd *cobra.Command, report *lintReport) {
+	out := cmd.OutOrStdout()
+
+	fmt.Fprintf(out, "GitLab CI Lint Report\n")
+	fmt.Fprintf(out, "=====================\n")
+	fmt.Fprintf(out, "File: %s\n\n", report.File)
+
+	if report.LocalError != "" {
+		fmt.Fprintf(out, "Local:  ❌ %s\n", report.LocalError)
+	} else {
+		fmt.Fprintf(out, "Local:  ✅ valid, %d issues (%d high)",
+			report.Analysis.TotalIssues, report.highIssues)
+		if report.Analysis.HiddenIssues > 0 {
+			fmt.Fprintf(out, ", %d hidden by --min-severity/--only-types", report.Analysis.HiddenIssues)
+		}
+		fmt.Fprintf(out, "\n")
+	}
+
+	if report.Remote == nil {
+		return
+	}
+	if report.Remote.Valid {
+		fmt.Fprintf(out, "GitLab: ✅ valid\n")
+	} else {
+		fmt.Fprintf(out, "GitLab: ❌ invalid\n")
+	}
+	for _, message := range report.Remote.Errors {
+		fmt.Fprintf(out, "  Error: %s\n", message)
+	}
+	for _, message := range report.Remote.Warnings {
+		fmt.Fprintf(out, "  Warning: %s\n", message)
+	}
+
+	if len(report.Discrepancies) > 0 {
+		fmt.Fprintf(out, "\nDiscrepancies\n")
+		fmt.Fprintf(out, "-------------\n")
+		for _, discrepancy := range report.Discrepancies {
+			fmt.Fprintf(out, "• %s\n", discrepancy)
+		}
+	}
+
+	if lintShowMerged && report.Remote.MergedYAML != "" {
+		fmt.Fprintf(out, "\nMerged YAML\n")
+		fmt.Fprintf(out, "-----------\n")
+		fmt.Fprint(out, report.Remote.MergedYAML)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRunLintRemote(t *testing.T) {
+	// GitLab resolves a job from an include that isn't available locally
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Content string `json:"content"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"valid":       true,
+			"warnings":    []string{},
+			"merged_yaml": body.Content + "security:scan:\n  script: [scan]\n",
+		})
+	}))
+	defer server.Close()
+
+	defer func() {
+		lintFormat, lintRemote, lintProject, lintGitLabURL = "table", false, "", "https://gitlab.com"
+	}()
+	lintGitLabURL = server.URL
+
+	run := func(stdin string) (string, error) {
+		cmd := &cobra.Command{}
+		cmd.SetContext(context.Background())
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetIn(strings.NewReader(stdin))
+		err := runLint(cmd, []string{"-"})
+		return buf.String(), err
+	}
+
+	config := "build:\n  image: node:20\n  script: [make]\n"
+
+	output, err := run(config)
+	if err != nil {
+		t.Fatalf("Unexpected error linting locally: %v", err)
+	}
+	if !strings.Contains(output, "Local:  ✅") || strings.Contains(output, "GitLab:") {
+		t.Errorf("Expected only the local verdict without --remote, got:\n%s", output)
+	}
+
+	lintRemote = true
+	if _, err := run(config); err == nil || !strings.Contains(err.Error(), "--project") {
+		t.Errorf("Expected --remote to require --project, got %v", err)
+	}
+
+	lintProject = "123"
+	output, err = run(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "GitLab: ✅ valid") {
+		t.Errorf("Expected GitLab's verdict, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Jobs only GitLab resolved") || !strings.Contains(output, "security:scan") {
+		t.Errorf("Expected the job resolved only by GitLab to be reported, got:\n%s", output)
+	}
+
+	lintFormat = "json"
+	output, err = run(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var report lintReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if report.Remote == nil || !strings.Contains(report.Remote.MergedYAML, "security:scan") {
+		t.Errorf("Expected the merged YAML in the JSON report, got %+v", report.Remote)
+	}
+	if len(report.Discrepancies) != 1 {
+		t.Errorf("Expected 1 discrepancy, got %v", report.Discrepancies)
+	}
+}
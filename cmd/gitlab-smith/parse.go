@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 
 	"github.com/spf13/cobra"
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
 )
 
+var parseDiffIncludes bool
+
 var parseCmd = &cobra.Command{
 	Use:   "parse <file>",
 	Short: "Parse and display a GitLab CI configuration file",
@@ -16,6 +19,15 @@ var parseCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		filename := args[0]
 
+		if parseDiffIncludes {
+			config, err := parser.ParseFile(filename)
+			if err != nil {
+				return fmt.Errorf("parsing GitLab CI config: %w", err)
+			}
+			printJobSources(cmd, config, filename)
+			return nil
+		}
+
 		data, err := os.ReadFile(filename)
 		if err != nil {
 			return fmt.Errorf("reading file: %w", err)
@@ -36,6 +48,31 @@ var parseCmd = &cobra.Command{
 	},
 }
 
+// printJobSources prints a table of each job and the file or include it was
+// defined in
+func printJobSources(cmd *cobra.Command, config *parser.GitLabConfig, mainFile string) {
+	jobNames := make([]string, 0, len(config.Jobs))
+	width := len("JOB")
+	for jobName := range config.Jobs {
+		jobNames = append(jobNames, jobName)
+		if len(jobName) > width {
+			width = len(jobName)
+		}
+	}
+	sort.Strings(jobNames)
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "%-*s  %s\n", width, "JOB", "SOURCE")
+	for _, jobName := range jobNames {
+		source := config.JobSource(jobName)
+		if source == "" {
+			source = mainFile
+		}
+		fmt.Fprintf(out, "%-*s  %s\n", width, jobName, source)
+	}
+}
+
 func init() {
+	parseCmd.Flags().BoolVar(&parseDiffIncludes, "diff-includes", false, "Resolve includes and print which file or include each job comes from")
 	rootCmd.AddCommand(parseCmd)
 }
@@ -210,3 +210,57 @@ func TestParseCommandHelp(t *testing.T) {
 		t.Error("Expected help text not found in output")
 	}
 }
+
+func TestParseCommandDiffIncludes(t *testing.T) {
+	tempDir := t.TempDir()
+	mainFile := filepath.Join(tempDir, ".gitlab-ci.yml")
+	files := map[string]string{
+		mainFile: `
+include:
+  - local: ci/build.yml
+
+test:
+  script: [make test]
+`,
+		filepath.Join(tempDir, "ci", "build.yml"): `
+build:
+  script: [make]
+`,
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parseDiffIncludes = true
+	defer func() { parseDiffIncludes = false }()
+
+	cmd := &cobra.Command{
+		Use:  "parse <file>",
+		Args: cobra.ExactArgs(1),
+		RunE: parseCmd.RunE,
+	}
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{mainFile})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and two jobs, got:\n%s", buf.String())
+	}
+	if fields := strings.Fields(lines[1]); len(fields) != 2 || fields[0] != "build" || fields[1] != "ci/build.yml" {
+		t.Errorf("Expected build to come from ci/build.yml, got %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); len(fields) != 2 || fields[0] != "test" || fields[1] != mainFile {
+		t.Errorf("Expected test to come from the main file, got %q", lines[2])
+	}
+}
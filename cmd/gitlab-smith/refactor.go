@@ -33,6 +33,7 @@ var (
 	pipelineCompare bool
 	gitlabURL       string
 	gitlabToken     string
+	ignorePaths     []string
 )
 
 func init() {
@@ -41,10 +42,11 @@ func init() {
 	refactorCmd.Flags().StringVar(&outputFile, "output", "", "Output file for results (default: stdout)")
 	refactorCmd.Flags().BoolVar(&analyze, "analyze", true, "Perform static analysis on both configurations")
 	refactorCmd.Flags().BoolVar(&fullTest, "full-test", false, "Enable full testing mode with GitLab API")
-	refactorCmd.Flags().StringVar(&format, "format", "json", "Output format (json, table, dot, mermaid)")
+	refactorCmd.Flags().StringVar(&format, "format", "json", "Output format (json, table, dot, mermaid, plantuml)")
 	refactorCmd.Flags().BoolVar(&pipelineCompare, "pipeline-compare", false, "Enable pipeline execution comparison simulation")
 	refactorCmd.Flags().StringVar(&gitlabURL, "gitlab-url", "", "GitLab URL for full testing mode")
 	refactorCmd.Flags().StringVar(&gitlabToken, "gitlab-token", "", "GitLab token for API access")
+	refactorCmd.Flags().StringArrayVar(&ignorePaths, "ignore", []string{}, "Drop diffs whose path matches this glob, e.g. 'variables.*' (repeatable)")
 
 	refactorCmd.MarkFlagRequired("old")
 	refactorCmd.MarkFlagRequired("new")
@@ -80,7 +82,7 @@ func runRefactor(cmd *cobra.Command, args []string) error {
 	}
 
 	// Perform comparison
-	diffResult := differ.Compare(oldConfig, newConfig)
+	diffResult := differ.CompareWithOptions(oldConfig, newConfig, refactorDifferOptions())
 
 	// Prepare result structure
 	result := RefactorResult{
@@ -123,7 +125,7 @@ func runRefactor(cmd *cobra.Command, args []string) error {
 		}
 	case "table":
 		output = []byte(formatAsTable(&result))
-	case "dot", "mermaid":
+	case "dot", "mermaid", "plantuml":
 		// For visual formats, we need to generate the appropriate diagram
 		r := renderer.New(nil)
 		var visualOutput string
@@ -170,12 +172,20 @@ func runRefactor(cmd *cobra.Command, args []string) error {
 			}
 		}
 	} else {
-		fmt.Fprintln(os.Stderr, "\n✓ No semantic differences found")
+		fmt.Fprintf(os.Stderr, "\n✓ %s\n", diffResult.Summary)
 	}
 
 	return nil
 }
 
+// refactorDifferOptions returns the default differ options with the
+// --ignore patterns applied
+func refactorDifferOptions() differ.DifferOptions {
+	opts := differ.DefaultDifferOptions()
+	opts.IgnorePaths = ignorePaths
+	return opts
+}
+
 type RefactorResult struct {
 	Comparison         *differ.DiffResult           `json:"comparison"`
 	Analysis           *AnalysisComparison          `json:"analysis,omitempty"`
@@ -310,7 +320,7 @@ func runFullTestMode() error {
 
 	// Perform static comparison first
 	fmt.Println("🔍 Performing semantic comparison...")
-	diffResult := differ.Compare(oldConfig, newConfig)
+	diffResult := differ.CompareWithOptions(oldConfig, newConfig, refactorDifferOptions())
 
 	// Prepare full test result
 	result := FullTestResult{
@@ -384,7 +394,7 @@ func runFullTestMode() error {
 		}
 	case "table":
 		output = []byte(formatFullTestAsTable(&result))
-	case "dot", "mermaid":
+	case "dot", "mermaid", "plantuml":
 		// For visual formats in full test mode, always show comparison since we have pipeline comparison
 		r := renderer.New(nil)
 		visualOutput, err := r.RenderVisualComparison(oldConfig, newConfig, result.PipelineComparison, format)
@@ -477,3 +477,39 @@ func TestFormatAsTable(t *testing.T) {
 		}
 	}
 }
+
+func TestRunRefactorIgnore(t *testing.T) {
+	tempDir := t.TempDir()
+	oldPath := filepath.Join(tempDir, "old.yml")
+	newPath := filepath.Join(tempDir, "new.yml")
+	resultPath := filepath.Join(tempDir, "result.json")
+	os.WriteFile(oldPath, []byte("variables:\n  NODE_VERSION: \"16\"\nbuild:\n  script: [make]\n"), 0644)
+	os.WriteFile(newPath, []byte("variables:\n  NODE_VERSION: \"20\"\nbuild:\n  script: [make all]\n"), 0644)
+
+	defer func() {
+		oldFile, newFile, outputFile, format, analyze, ignorePaths = "", "", "", "json", true, []string{}
+	}()
+	oldFile, newFile, outputFile, format, analyze = oldPath, newPath, resultPath, "json", false
+	ignorePaths = []string{"variables.*"}
+
+	if err := runRefactor(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(resultPath)
+	if err != nil {
+		t.Fatalf("Expected the result file to be written: %v", err)
+	}
+
+	var result RefactorResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if result.Comparison.IgnoredDiffs != 1 || !strings.Contains(result.Comparison.Summary, "(1 ignored)") {
+		t.Errorf("Expected one ignored diff in the summary, got %+v", result.Comparison)
+	}
+	for _, diff := range result.Comparison.Semantic {
+		if strings.HasPrefix(diff.Path, "variables.") {
+			t.Errorf("Expected variable diffs to be ignored, got %+v", diff)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer"
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+)
+
+// reportIndexFile is the file --output-dir writes next to the reports
+const reportIndexFile = "index.json"
+
+// ReportIndex summarizes the reports written by --output-dir, so dashboards
+// can ingest one file instead of one per configuration
+type ReportIndex struct {
+	Files        []ReportIndexEntry `json:"files"`
+	AverageScore int                `json:"average_score"`
+	TotalIssues  int                `json:"total_issues"`
+	Failed       int                `json:"failed"`
+}
+
+// ReportIndexEntry is one analyzed configuration in the report index. Report
+// is empty and Error set when the configuration couldn't be loaded.
+type ReportIndexEntry struct {
+	Source      string        `json:"source"`
+	Report      string        `json:"report,omitempty"`
+	Score       int           `json:"score"`
+	Grade       string        `json:"grade,omitempty"`
+	TotalIssues int           `json:"total_issues"`
+	Summary     types.Summary `json:"summary"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// analyzeToDir analyzes each source on its own and writes one report per
+// source into dir, in --format, together with an index of all reports.
+// Sources that fail to load are recorded in the index and make the command
+// fail once every report is written.
+func analyzeToDir(cmd *cobra.Command, sources []string, dir string) error {
+	for _, source := range sources {
+		if source == stdinSource {
+			return fmt.Errorf("--output-dir cannot be used when reading from stdin")
+		}
+	}
+	extension, write, err := reportWriter(analyzeFormat)
+	if err != nil {
+		return err
+	}
+
+	analyzerInstance, err := newAnalyzer()
+	if err != nil {
+		return err
+	}
+	filter, err := parseIssueFilter(analyzeMinSeverity, analyzeOnlyTypes)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Sorting makes report names, which are numbered on collisions,
+	// independent of the order the sources were given in
+	sources = uniqueSorted(sources)
+	results, loadErr := analyzerInstance.AnalyzeAll(sources)
+
+	fileErrors := make(map[string]error)
+	if joined, ok := loadErr.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			var fileErr *analyzer.FileError
+			if errors.As(err, &fileErr) {
+				fileErrors[fileErr.Path] = fileErr.Err
+			}
+		}
+	}
+
+	index := &ReportIndex{Files: []ReportIndexEntry{}}
+	combined := &types.AnalysisResult{}
+	names := reportNames(sources, extension)
+	scoreSum := 0
+	for _, source := range sources {
+		entry := ReportIndexEntry{Source: source}
+		result, ok := results[source]
+		if !ok {
+			entry.Error = "failed to load configuration"
+			if err := fileErrors[source]; err != nil {
+				entry.Error = err.Error()
+			}
+			index.Failed++
+			index.Files = append(index.Files, entry)
+			continue
+		}
+
+		entry.Report = names[source]
+		if err := writeReport(filepath.Join(dir, entry.Report), result.Filtered(filter), source, write); err != nil {
+			return err
+		}
+		entry.Score = result.Health.Score
+		entry.Grade = result.Health.Grade
+		entry.TotalIssues = result.TotalIssues
+		entry.Summary = result.Summary
+		index.Files = append(index.Files, entry)
+
+		scoreSum += result.Health.Score
+		index.TotalIssues += result.TotalIssues
+		combined.Issues = append(combined.Issues, result.Issues...)
+	}
+	if analyzed := len(sources) - index.Failed; analyzed > 0 {
+		index.AverageScore = scoreSum / analyzed
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, reportIndexFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write report index: %w", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Wrote %d report(s) and %s to %s\n", len(sources)-index.Failed, reportIndexFile, dir)
+
+	if loadErr != nil {
+		return fmt.Errorf("failed to analyze %d of %d configuration(s): %w", index.Failed, len(sources), loadErr)
+	}
+	return analysisExitError(cmd, combined, &analyzerInstance.GetConfig().Analyzer.ExitPolicy)
+}
+
+// reportWriter returns the file extension and writer of a report format
+func reportWriter(format string) (string, func(io.Writer, *types.AnalysisResult, string) error, error) {
+	switch format {
+	case "json":
+		return ".json", outputAnalysisJSON, nil
+	case "table":
+		return ".txt", outputAnalysisTable, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported format: %s (supported: table, json)", format)
+	}
+}
+
+func writeReport(path string, result *types.AnalysisResult, source string, write func(io.Writer, *types.AnalysisResult, string) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if err := write(file, result, source); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write report %s: %w", path, err)
+	}
+	return file.Close()
+}
+
+// reportNames names each source's report after its path, with separators
+// replaced by underscores and without leading dots, e.g. ci/build.yml
+// becomes ci_build.yml.json and .gitlab-ci.yml gitlab-ci.yml.json.
+// Sources whose names collide get -2, -3, ... in the order given, and no
+// report is named like the index.
+func reportNames(sources []string, extension string) map[string]string {
+	names := make(map[string]string, len(sources))
+	taken := map[string]bool{reportIndexFile: true}
+	for _, source := range sources {
+		base := filepath.ToSlash(filepath.Clean(source))
+		for strings.HasPrefix(base, "../") {
+			base = strings.TrimPrefix(base, "../")
+		}
+		base = strings.TrimLeft(strings.ReplaceAll(strings.TrimLeft(base, "/"), "/", "_"), ".")
+
+		name := base + extension
+		for n := 2; taken[name]; n++ {
+			name = base + "-" + strconv.Itoa(n) + extension
+		}
+		taken[name] = true
+		names[source] = name
+	}
+	return names
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
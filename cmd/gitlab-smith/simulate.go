@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+	"github.com/wonderfulspam/gitlab-smith/pkg/renderer"
+)
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate <config-file>",
+	Short: "Show which jobs run in a given pipeline",
+	Long: `Evaluate workflow:rules and each job's rules or only/except for one pipeline
+and print the jobs that run, grouped by stage, with the rule that decided it,
+followed by the jobs that don't run and why:
+  gitlab-smith simulate .gitlab-ci.yml --event merge_request_event --branch feature/x
+
+--changed-files lists the files the pipeline changes, for rules:changes; without
+it, changes conditions of job rules are assumed to match. --var sets a pipeline
+variable or overrides a predefined one such as CI_DEFAULT_BRANCH:
+  gitlab-smith simulate .gitlab-ci.yml --tag v1.2.0 --var CI_DEFAULT_BRANCH=master
+
+--diff-context compares the pipeline with a second one side by side. It takes
+event, branch and tag settings; the rest is taken from the first pipeline:
+  gitlab-smith simulate .gitlab-ci.yml --diff-context event=merge_request_event,branch=feature/x`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSimulate,
+}
+
+var (
+	simulateEvent        string
+	simulateBranch       string
+	simulateTag          string
+	simulateChangedFiles []string
+	simulateVars         []string
+	simulateDiffContext  string
+)
+
+func init() {
+	simulateCmd.Flags().StringVar(&simulateEvent, "event", "push", "Pipeline source (CI_PIPELINE_SOURCE): push, merge_request_event, external_pull_request_event, schedule, web, api, trigger, pipeline, parent_pipeline, ...")
+	simulateCmd.Flags().StringVar(&simulateBranch, "branch", "main", "Branch the pipeline runs for (the source branch of a merge request)")
+	simulateCmd.Flags().StringVar(&simulateTag, "tag", "", "Tag the pipeline runs for, instead of a branch")
+	simulateCmd.Flags().StringSliceVar(&simulateChangedFiles, "changed-files", nil, "Comma-separated files changed by the pipeline, for rules:changes")
+	simulateCmd.Flags().StringArrayVar(&simulateVars, "var", nil, "Variable as KEY=VALUE; predefined CI_* variables are overridden (repeatable)")
+	simulateCmd.Flags().StringVar(&simulateDiffContext, "diff-context", "", "Compare with a second pipeline, e.g. event=merge_request_event,branch=feature/x")
+	rootCmd.AddCommand(simulateCmd)
+}
+
+// simulationContext is a pipeline to simulate, as given on the command line
+type simulationContext struct {
+	event, branch, tag string
+}
+
+func (s simulationContext) String() string {
+	if s.tag != "" {
+		return fmt.Sprintf("%s pipeline for tag %s", s.event, s.tag)
+	}
+	return fmt.Sprintf("%s pipeline on %s", s.event, s.branch)
+}
+
+// pipelineContext builds the parser context, applying the variables and
+// changed files given on the command line
+func (s simulationContext) pipelineContext() (*parser.PipelineContext, error) {
+	if !parser.IsPipelineSource(s.event) {
+		return nil, fmt.Errorf("unknown pipeline source %q (supported: %s)", s.event, strings.Join(parser.PipelineSources, ", "))
+	}
+	ctx := &parser.PipelineContext{
+		Event:        s.event,
+		Tag:          s.tag,
+		Variables:    map[string]string{},
+		IsMR:         s.event == "merge_request_event",
+		ChangedFiles: simulateChangedFiles,
+	}
+	if s.tag == "" {
+		ctx.Branch = s.branch
+	}
+	for _, assignment := range simulateVars {
+		if err := ctx.SetVariable(assignment); err != nil {
+			return nil, err
+		}
+	}
+
+	defaultBranch := "main"
+	if branch, ok := ctx.Predefined["CI_DEFAULT_BRANCH"]; ok {
+		defaultBranch = branch
+	}
+	ctx.IsMainBranch = !ctx.IsMR && ctx.Branch != "" && ctx.Branch == defaultBranch
+	return ctx, nil
+}
+
+// parseSimulationContext applies a --diff-context spec such as
+// "event=merge_request_event,branch=feature/x" to base
+func parseSimulationContext(spec string, base simulationContext) (simulationContext, error) {
+	result := base
+	for _, setting := range strings.Split(spec, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(setting), "=")
+		if !found || value == "" {
+			return result, fmt.Errorf("invalid --diff-context setting %q, expected key=value", setting)
+		}
+		switch key {
+		case "event":
+			result.event = value
+		case "branch":
+			result.branch, result.tag = value, ""
+		case "tag":
+			result.tag = value
+		default:
+			return result, fmt.Errorf("unknown --diff-context setting %q (supported: event, branch, tag)", key)
+		}
+	}
+	return result, nil
+}
+
+// simulation is the outcome of simulating one pipeline
+type simulation struct {
+	created   bool
+	decisions map[string]parser.JobDecision
+}
+
+func simulate(config *parser.GitLabConfig, ctx *parser.PipelineContext) simulation {
+	created, workflowVars := config.EvaluateWorkflow(ctx)
+	result := simulation{created: created, decisions: make(map[string]parser.JobDecision)}
+	if !created {
+		return result
+	}
+	for jobName, job := range config.ConcreteJobs() {
+		result.decisions[jobName] = config.ExplainJob(job, ctx, workflowVars)
+	}
+	return result
+}
+
+func runSimulate(cmd *cobra.Command, args []string) error {
+	config, err := parser.ParseFile(args[0])
+	if err != nil {
+		return fmt.Errorf("parsing GitLab CI config '%s': %w", args[0], err)
+	}
+
+	first := simulationContext{event: simulateEvent, branch: simulateBranch, tag: simulateTag}
+	firstCtx, err := first.pipelineContext()
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if simulateDiffContext == "" {
+		writeSimulation(out, config, first, simulate(config, firstCtx))
+		return nil
+	}
+
+	second, err := parseSimulationContext(simulateDiffContext, first)
+	if err != nil {
+		return err
+	}
+	secondCtx, err := second.pipelineContext()
+	if err != nil {
+		return err
+	}
+	writeSimulationDiff(out, config, first, second, simulate(config, firstCtx), simulate(config, secondCtx))
+	return nil
+}
+
+// writeSimulation prints the jobs that run, grouped by stage, and then the
+// jobs that don't
+func writeSimulation(out io.Writer, config *parser.GitLabConfig, label simulationContext, result simulation) {
+	fmt.Fprintln(out, label)
+	if !result.created {
+		fmt.Fprintln(out, "No pipeline is created: workflow:rules don't match")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nJobs that run:")
+	for _, stage := range renderer.PipelineStages(config) {
+		var lines []string
+		for _, jobName := range config.ConcreteJobNames() {
+			decision := result.decisions[jobName]
+			if !decision.Runs || config.JobStage(jobName) != stage {
+				continue
+			}
+			name := jobName
+			if decision.When != "on_success" {
+				name += " (" + decision.When + ")"
+			}
+			lines = append(lines, fmt.Sprintf("    %s\t%s", name, decision.Reason))
+		}
+		if len(lines) > 0 {
+			fmt.Fprintf(w, "  %s:\n%s\n", stage, strings.Join(lines, "\n"))
+		}
+	}
+
+	var skipped []string
+	for _, jobName := range config.ConcreteJobNames() {
+		if decision := result.decisions[jobName]; !decision.Runs {
+			skipped = append(skipped, fmt.Sprintf("  %s\t%s", jobName, decision.Reason))
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(w, "\nJobs that don't run:\n%s\n", strings.Join(skipped, "\n"))
+	}
+	w.Flush()
+}
+
+// writeSimulationDiff prints both pipelines side by side, marking the jobs
+// whose outcome differs with "*", and then the reasons for each difference
+func writeSimulationDiff(out io.Writer, config *parser.GitLabConfig, firstLabel, secondLabel simulationContext, first, second simulation) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  Job\tStage\t%s\t%s\n", firstLabel, secondLabel)
+
+	outcome := func(result simulation, jobName string) string {
+		decision := result.decisions[jobName]
+		switch {
+		case !result.created:
+			return "no pipeline"
+		case !decision.Runs:
+			return "-"
+		case decision.When != "on_success":
+			return "runs (" + decision.When + ")"
+		default:
+			return "runs"
+		}
+	}
+
+	jobNames := config.ConcreteJobNames()
+	stageIndex := make(map[string]int)
+	for i, stage := range renderer.PipelineStages(config) {
+		stageIndex[stage] = i
+	}
+	sort.SliceStable(jobNames, func(i, j int) bool {
+		return stageIndex[config.JobStage(jobNames[i])] < stageIndex[config.JobStage(jobNames[j])]
+	})
+
+	var differences []string
+	for _, jobName := range jobNames {
+		a, b := outcome(first, jobName), outcome(second, jobName)
+		marker := " "
+		if a != b {
+			marker = "*"
+			differences = append(differences, fmt.Sprintf("  %s:\t%s\t| %s", jobName,
+				reasonOrDefault(first, jobName), reasonOrDefault(second, jobName)))
+		}
+		fmt.Fprintf(w, "%s %s\t%s\t%s\t%s\n", marker, jobName, config.JobStage(jobName), a, b)
+	}
+	w.Flush()
+
+	if len(differences) == 0 {
+		fmt.Fprintln(out, "\nBoth pipelines run the same jobs")
+		return
+	}
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\n%d job(s) differ:\n%s\n", len(differences), strings.Join(differences, "\n"))
+	w.Flush()
+}
+
+func reasonOrDefault(result simulation, jobName string) string {
+	if !result.created {
+		return "workflow:rules don't match"
+	}
+	return result.decisions[jobName].Reason
+}
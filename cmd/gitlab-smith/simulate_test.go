@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const simulateTestConfig = `stages: [build, test, deploy]
+build:
+  stage: build
+  script: [make]
+unit:
+  stage: test
+  script: [make test]
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+    - changes: [src/**/*]
+release:
+  stage: deploy
+  script: [./release.sh]
+  rules:
+    - if: $CI_COMMIT_REF_NAME =~ /^release\/.*/
+      when: manual
+deploy:
+  stage: deploy
+  script: [./deploy.sh]
+  rules:
+    - if: $CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH
+`
+
+func runSimulateForTest(t *testing.T, setup func()) (string, error) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), ".gitlab-ci.yml")
+	if err := os.WriteFile(file, []byte(simulateTestConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		simulateEvent, simulateBranch, simulateTag = "push", "main", ""
+		simulateChangedFiles, simulateVars, simulateDiffContext = nil, nil, ""
+	}()
+	setup()
+
+	cmd := &cobra.Command{}
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	err := runSimulate(cmd, []string{file})
+	return buf.String(), err
+}
+
+func TestRunSimulate(t *testing.T) {
+	output, err := runSimulateForTest(t, func() {
+		simulateBranch = "release/1.0"
+		simulateChangedFiles = []string{"README.md"}
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	running, skipped, found := strings.Cut(output, "Jobs that don't run:")
+	if !found {
+		t.Fatalf("Expected skipped jobs to be listed, got:\n%s", output)
+	}
+	for _, want := range []string{"push pipeline on release/1.0", "  build:\n", "release (manual)", `rules[0] matched (if: $CI_COMMIT_REF_NAME =~ /^release\/.*/)`} {
+		if !strings.Contains(running, want) {
+			t.Errorf("Expected %q among the running jobs, got:\n%s", want, output)
+		}
+	}
+	for _, want := range []string{"deploy", "unit"} {
+		if !strings.Contains(skipped, want) || strings.Contains(running, "    "+want+" ") {
+			t.Errorf("Expected %s not to run, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestRunSimulateVariables(t *testing.T) {
+	output, err := runSimulateForTest(t, func() {
+		simulateBranch = "master"
+		simulateVars = []string{"CI_DEFAULT_BRANCH=master"}
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if running, _, _ := strings.Cut(output, "Jobs that don't run:"); !strings.Contains(running, "deploy  rules[0] matched") {
+		t.Errorf("Expected deploy to run on the overridden default branch, got:\n%s", output)
+	}
+
+	if _, err := runSimulateForTest(t, func() { simulateVars = []string{"not-a-variable"} }); err == nil {
+		t.Error("Expected an invalid --var to be rejected")
+	}
+	if _, err := runSimulateForTest(t, func() { simulateEvent = "merge_request" }); err == nil {
+		t.Error("Expected an unknown --event to be rejected")
+	}
+}
+
+func TestRunSimulateDiffContext(t *testing.T) {
+	output, err := runSimulateForTest(t, func() {
+		simulateDiffContext = "event=merge_request_event,branch=feature/x"
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, want := range []string{"push pipeline on main", "merge_request_event pipeline on feature/x", "1 job(s) differ", "deploy:"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in the comparison, got:\n%s", want, output)
+		}
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "* ") && !strings.HasPrefix(line, "* deploy ") {
+			t.Errorf("Expected only deploy to differ, got %q", line)
+		}
+	}
+
+	if _, err := runSimulateForTest(t, func() { simulateDiffContext = "ref=main" }); err == nil {
+		t.Error("Expected an unknown --diff-context setting to be rejected")
+	}
+}
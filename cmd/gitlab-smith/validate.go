@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/wonderfulspam/gitlab-smith/pkg/validator"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate <before.yml> <after.yml> | --dir <before-dir> <after-dir>",
+	Short: "Check that a refactored GitLab CI configuration keeps its behavior",
+	Long: `Compare a GitLab CI configuration before and after a refactoring and print
+the result as JSON: the change in analyzer issues, whether behavior is
+maintained, the improvements detected, a pipeline comparison summary, and the
+issues resolved or newly introduced. Exits non-zero when the refactoring
+changes behavior or introduces issues, so it can gate CI:
+  gitlab-smith validate old/.gitlab-ci.yml .gitlab-ci.yml
+
+With --dir, the arguments are directories holding a main CI file whose local
+includes are resolved relative to each directory, for configurations split
+across many files:
+  gitlab-smith validate --dir before/ after/
+
+With --format markdown, the result is printed as a GitLab-flavored Markdown
+summary ready to post as a merge request comment, for example through the
+notes API:
+  gitlab-smith validate --format markdown old.yml .gitlab-ci.yml > comment.md`,
+	Args: cobra.ExactArgs(2),
+	RunE: runValidate,
+}
+
+var (
+	validateDirs   bool
+	validateFormat string
+)
+
+func init() {
+	validateCmd.Flags().BoolVar(&validateDirs, "dir", false, "Compare directories containing a main CI file and its includes")
+	validateCmd.Flags().StringVar(&validateFormat, "format", "json", "Output format: json, markdown")
+	rootCmd.AddCommand(validateCmd)
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	if validateFormat != "json" && validateFormat != "markdown" {
+		return fmt.Errorf("unsupported format: %s (supported: json, markdown)", validateFormat)
+	}
+
+	var result *validator.SimpleRefactoringResult
+	var err error
+	if validateDirs {
+		result, err = validator.CompareDirectories(args[0], args[1])
+	} else {
+		result, err = validator.ValidateSimpleRefactoring(&validator.SimpleRefactoringCase{
+			Name:       "validate",
+			BeforeFile: args[0],
+			AfterFile:  args[1],
+			Expectations: validator.SimpleRefactoringExpectations{
+				ShouldMaintainBehavior:      true,
+				ShouldImproveOrMaintainPerf: true,
+			},
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("validating refactoring: %w", err)
+	}
+
+	if validateFormat == "markdown" {
+		fmt.Fprint(cmd.OutOrStdout(), result.ToMarkdownComment())
+	} else {
+		output, err := result.ToJSON()
+		if err != nil {
+			return fmt.Errorf("encoding result: %w", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(output))
+	}
+
+	if !result.Success {
+		return fmt.Errorf("refactoring validation failed: %d problem(s)", len(result.Issues))
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRunValidate(t *testing.T) {
+	casesDir := "../../test/simple-refactoring-cases"
+
+	cmd := &cobra.Command{}
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	err := runValidate(cmd, []string{
+		filepath.Join(casesDir, "duplicate-before-scripts-before.yml"),
+		filepath.Join(casesDir, "duplicate-before-scripts-after.yml"),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\n%s", err, buf.String())
+	}
+
+	var report struct {
+		Success             bool                     `json:"success"`
+		AnalysisImprovement int                      `json:"analysis_improvement"`
+		BehaviorMaintained  bool                     `json:"behavior_maintained"`
+		ImprovementTags     []string                 `json:"improvement_tags"`
+		PipelineComparison  map[string]interface{}   `json:"pipeline_comparison"`
+		ResolvedIssues      []map[string]interface{} `json:"resolved_issues"`
+		IntroducedIssues    []map[string]interface{} `json:"introduced_issues"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if !report.Success || !report.BehaviorMaintained {
+		t.Errorf("Expected a successful, behavior-preserving refactoring, got %+v", report)
+	}
+	if report.AnalysisImprovement <= 0 || len(report.ResolvedIssues) == 0 {
+		t.Errorf("Expected resolved issues, got improvement %d and %d resolved",
+			report.AnalysisImprovement, len(report.ResolvedIssues))
+	}
+	if len(report.IntroducedIssues) != 0 {
+		t.Errorf("Expected no introduced issues, got %v", report.IntroducedIssues)
+	}
+	if len(report.ImprovementTags) == 0 || report.PipelineComparison == nil {
+		t.Errorf("Expected improvement tags and a pipeline comparison, got %+v", report)
+	}
+}
+
+func TestRunValidateIntroducedIssues(t *testing.T) {
+	dir := t.TempDir()
+	before := filepath.Join(dir, "before.yml")
+	after := filepath.Join(dir, "after.yml")
+	os.WriteFile(before, []byte("stages: [build]\nbuild:\n  stage: build\n  image: node:20.11.0\n  script: [make]\n"), 0644)
+	os.WriteFile(after, []byte("stages: [build]\nbuild:\n  stage: build\n  image: node:latest\n  script: [make]\n"), 0644)
+
+	cmd := &cobra.Command{}
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	if err := runValidate(cmd, []string{before, after}); err == nil {
+		t.Fatalf("Expected an error when issues are introduced, got output:\n%s", buf.String())
+	}
+
+	var report struct {
+		Success          bool          `json:"success"`
+		IntroducedIssues []interface{} `json:"introduced_issues"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if report.Success || len(report.IntroducedIssues) == 0 {
+		t.Errorf("Expected failure with introduced issues, got %s", buf.String())
+	}
+}
+
+func TestRunValidateDirectories(t *testing.T) {
+	before := t.TempDir()
+	after := t.TempDir()
+	job := "  stage: test\n  image: golang:1.24\n  script: [go test ./...]\n"
+	os.WriteFile(filepath.Join(before, ".gitlab-ci.yml"), []byte("stages: [test]\nunit:\n"+job), 0644)
+	os.MkdirAll(filepath.Join(after, "ci"), 0755)
+	os.WriteFile(filepath.Join(after, ".gitlab-ci.yml"), []byte("stages: [test]\ninclude:\n  - local: ci/test.yml\n"), 0644)
+	os.WriteFile(filepath.Join(after, "ci", "test.yml"), []byte("unit:\n"+job), 0644)
+
+	defer func() { validateDirs = false }()
+	validateDirs = true
+
+	cmd := &cobra.Command{}
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	if err := runValidate(cmd, []string{before, after}); err != nil {
+		t.Fatalf("Unexpected error: %v\n%s", err, buf.String())
+	}
+
+	var report struct {
+		Success            bool `json:"success"`
+		PipelineComparison struct {
+			AddedJobs   int `json:"added_jobs"`
+			RemovedJobs int `json:"removed_jobs"`
+		} `json:"pipeline_comparison"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if !report.Success || report.PipelineComparison.RemovedJobs != 0 || report.PipelineComparison.AddedJobs != 0 {
+		t.Errorf("Expected moving the job into an include to be a no-op, got %s", buf.String())
+	}
+
+	if err := runValidate(cmd, []string{before, t.TempDir()}); err == nil || !strings.Contains(err.Error(), "no GitLab CI main file") {
+		t.Errorf("Expected an error for a directory without a main file, got %v", err)
+	}
+}
+
+func TestRunValidateMarkdown(t *testing.T) {
+	casesDir := "../../test/simple-refactoring-cases"
+
+	defer func() { validateFormat = "json" }()
+	validateFormat = "markdown"
+
+	cmd := &cobra.Command{}
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	err := runValidate(cmd, []string{
+		filepath.Join(casesDir, "duplicate-before-scripts-before.yml"),
+		filepath.Join(casesDir, "duplicate-before-scripts-after.yml"),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\n%s", err, buf.String())
+	}
+
+	output := buf.String()
+	for _, want := range []string{"### :white_check_mark:", "#### Pipeline comparison", "<details>"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in the Markdown output, got:\n%s", want, output)
+		}
+	}
+
+	validateFormat = "html"
+	if err := runValidate(cmd, []string{"a.yml", "b.yml"}); err == nil || !strings.Contains(err.Error(), "unsupported format") {
+		t.Errorf("Expected an unsupported format error, got %v", err)
+	}
+}
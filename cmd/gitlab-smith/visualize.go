@@ -13,7 +13,7 @@ var visualizeCmd = &cobra.Command{
 	Use:   "visualize <config-file>",
 	Short: "Generate a visual representation of a GitLab CI pipeline",
 	Long: `Creates a visual diagram of the GitLab CI pipeline structure showing jobs, stages, 
-and dependencies. Supports DOT graph and Mermaid diagram formats.`,
+and dependencies. Supports DOT graph, Mermaid and PlantUML diagram formats.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runVisualize,
 }
@@ -24,7 +24,7 @@ var (
 )
 
 func init() {
-	visualizeCmd.Flags().StringVar(&visualFormat, "format", "mermaid", "Visual format (dot, mermaid)")
+	visualizeCmd.Flags().StringVar(&visualFormat, "format", "mermaid", "Visual format (dot, mermaid, plantuml)")
 	visualizeCmd.Flags().StringVar(&visualOutputFile, "output", "", "Output file for the diagram (default: stdout)")
 
 	rootCmd.AddCommand(visualizeCmd)
@@ -66,6 +66,9 @@ func runVisualize(cmd *cobra.Command, args []string) error {
 		case "mermaid":
 			fmt.Printf("Mermaid diagram written to %s\n", visualOutputFile)
 			fmt.Println("💡 View online at: https://mermaid.live/")
+		case "plantuml":
+			fmt.Printf("PlantUML diagram written to %s\n", visualOutputFile)
+			fmt.Println("💡 To generate an image: plantuml -tpng " + visualOutputFile)
 		}
 	} else {
 		fmt.Print(visualOutput)
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// watchPollInterval is how often files are checked when file system
+	// notifications aren't available
+	watchPollInterval = 300 * time.Millisecond
+	watchDebounce     = 200 * time.Millisecond
+	clearScreen       = "\033[H\033[2J"
+)
+
+// watchAnalysis re-runs the analysis whenever a config file or one of its
+// local includes changes, until interrupted. Includes are re-resolved on every
+// run so newly added ones are watched too.
+func watchAnalysis(cmd *cobra.Command, sources []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	out := cmd.OutOrStdout()
+	for {
+		fmt.Fprint(out, clearScreen)
+		files, err := analyzeSources(cmd, sources)
+		if err != nil {
+			fmt.Fprintf(out, "❌ %v\n", err)
+		}
+		fmt.Fprintf(out, "\nWatching %d file(s) for changes. Press Ctrl+C to stop.\n", len(files))
+
+		watcher := newFileWatcher(files, watchPollInterval, watchDebounce)
+		if err := watcher.Wait(ctx); err != nil {
+			return nil
+		}
+	}
+}
+
+// fileWatcher detects changes to a set of files. It listens for file system
+// notifications on the files' directories, so editors that save by replacing
+// a file are seen too, and falls back to polling modification times and
+// sizes where notifications aren't available. Missing files are watched for
+// creation. A fileWatcher serves a single Wait.
+type fileWatcher struct {
+	files    []string
+	interval time.Duration
+	debounce time.Duration
+	state    map[string]fileState
+	// notifier is nil when the watcher polls
+	notifier *fsnotify.Watcher
+}
+
+type fileState struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+func newFileWatcher(files []string, interval, debounce time.Duration) *fileWatcher {
+	w := newPollingWatcher(files, interval, debounce)
+	notifier, err := fsnotify.NewWatcher()
+	if err != nil {
+		return w
+	}
+	for _, dir := range watchDirs(files) {
+		if err := notifier.Add(dir); err != nil {
+			notifier.Close()
+			return w
+		}
+	}
+	w.notifier = notifier
+	return w
+}
+
+func newPollingWatcher(files []string, interval, debounce time.Duration) *fileWatcher {
+	w := &fileWatcher{files: files, interval: interval, debounce: debounce}
+	w.state = w.snapshot()
+	return w
+}
+
+// watchDirs returns the directories to listen on for changes to files: each
+// file's directory, or its closest existing ancestor when the directory
+// hasn't been created yet
+func watchDirs(files []string) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, file := range files {
+		dir := filepath.Dir(filepath.Clean(file))
+		for {
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// Wait blocks until a watched file changes and then stays unchanged for the
+// debounce period, so a burst of saves triggers a single re-run. It returns
+// the context's error if the context is cancelled first.
+func (w *fileWatcher) Wait(ctx context.Context) error {
+	if w.notifier == nil {
+		return w.poll(ctx)
+	}
+	defer w.notifier.Close()
+
+	// A debounce timer that hasn't been started yet never fires
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-w.notifier.Events:
+			if !ok {
+				return w.poll(ctx)
+			}
+			if w.affects(event.Name) {
+				debounce.Reset(w.debounce)
+			}
+		case <-w.notifier.Errors:
+			// Events may have been lost, so check the files themselves
+			return w.poll(ctx)
+		case <-debounce.C:
+			return nil
+		}
+	}
+}
+
+// affects reports whether a change to path may change a watched file: it's
+// either one of the files or a directory on the way to one
+func (w *fileWatcher) affects(path string) bool {
+	path = filepath.Clean(path)
+	for _, file := range w.files {
+		file = filepath.Clean(file)
+		if file == path || strings.HasPrefix(file, path+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// poll waits like Wait by comparing the files' state every interval. A change
+// made before polling started is caught against the state taken when the
+// watcher was created.
+func (w *fileWatcher) poll(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	changed := false
+	var lastChange time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			current := w.snapshot()
+			if !statesEqual(current, w.state) {
+				w.state = current
+				changed = true
+				lastChange = now
+				continue
+			}
+			if changed && now.Sub(lastChange) >= w.debounce {
+				return nil
+			}
+		}
+	}
+}
+
+func (w *fileWatcher) snapshot() map[string]fileState {
+	state := make(map[string]fileState, len(w.files))
+	for _, file := range w.files {
+		info, err := os.Stat(file)
+		if err != nil {
+			state[file] = fileState{}
+			continue
+		}
+		state[file] = fileState{exists: true, modTime: info.ModTime(), size: info.Size()}
+	}
+	return state
+}
+
+func statesEqual(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, state := range a {
+		other, exists := b[file]
+		if !exists || !state.modTime.Equal(other.modTime) || state.exists != other.exists || state.size != other.size {
+			return false
+		}
+	}
+	return true
+}
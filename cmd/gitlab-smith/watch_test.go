@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestFileWatcherDetectsChanges(t *testing.T) {
+	tempDir := t.TempDir()
+	existing := filepath.Join(tempDir, ".gitlab-ci.yml")
+	missing := filepath.Join(tempDir, "ci/jobs.yml")
+	if err := os.WriteFile(existing, []byte("stages: [build]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func() error
+	}{
+		{"modified file", func() error {
+			return os.WriteFile(existing, []byte("stages: [build, test]\n"), 0644)
+		}},
+		{"created include", func() error {
+			if err := os.MkdirAll(filepath.Dir(missing), 0755); err != nil {
+				return err
+			}
+			return os.WriteFile(missing, []byte("lint:\n  script: [make lint]\n"), 0644)
+		}},
+	}
+
+	watchers := []struct {
+		name string
+		new  func(files []string, interval, debounce time.Duration) *fileWatcher
+	}{
+		{"notify", newFileWatcher},
+		{"poll", newPollingWatcher},
+	}
+
+	for _, w := range watchers {
+		for _, tt := range tests {
+			t.Run(w.name+"/"+tt.name, func(t *testing.T) {
+				if err := os.RemoveAll(filepath.Dir(missing)); err != nil {
+					t.Fatalf("Failed to reset files: %v", err)
+				}
+				watcher := w.new([]string{existing, missing}, 10*time.Millisecond, 20*time.Millisecond)
+				if err := tt.change(); err != nil {
+					t.Fatalf("Failed to change files: %v", err)
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				defer cancel()
+				if err := watcher.Wait(ctx); err != nil {
+					t.Errorf("Expected change to be detected, got %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestFileWatcherDebouncesBursts(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".gitlab-ci.yml")
+	watcher := newFileWatcher([]string{file}, 10*time.Millisecond, 100*time.Millisecond)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(file, []byte(fmt.Sprintf("stages: [s%d]\n", i)), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		time.Sleep(30 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := watcher.Wait(ctx); err != nil {
+		t.Fatalf("Expected change to be detected, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 160*time.Millisecond {
+		t.Errorf("Expected Wait to hold off until the burst settled, returned after %v", elapsed)
+	}
+}
+
+func TestFileWatcherStopsOnCancel(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".gitlab-ci.yml")
+	watcher := newFileWatcher([]string{file}, 10*time.Millisecond, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := watcher.Wait(ctx); err == nil {
+		t.Error("Expected context error when nothing changes")
+	}
+}
+
+func TestAnalyzeFileReturnsLocalIncludes(t *testing.T) {
+	tempDir := t.TempDir()
+	mainFile := filepath.Join(tempDir, ".gitlab-ci.yml")
+	config := `
+include:
+  - local: ci/build.yml
+stages: [build]
+`
+	if err := os.WriteFile(mainFile, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cmd := &cobra.Command{}
+	cmd.SetOut(&bytes.Buffer{})
+	files, err := analyzeSources(cmd, []string{mainFile})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{mainFile, filepath.Join(tempDir, "ci/build.yml")}
+	if len(files) != len(expected) || files[0] != expected[0] || files[1] != expected[1] {
+		t.Errorf("Expected watched files %v, got %v", expected, files)
+	}
+}
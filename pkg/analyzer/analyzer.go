@@ -1,15 +1,24 @@
 package analyzer
 
 import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/maintainability"
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/performance"
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/policy"
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/reliability"
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/security"
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/fixer"
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
 )
 
-// Analyzer manages the analysis process with configurable checks
+// Analyzer manages the analysis process with configurable checks. Once
+// configured, Analyze may be called from several goroutines at once.
 type Analyzer struct {
 	registry *CheckRegistry
 	config   *Config
@@ -26,10 +35,13 @@ func New() *Analyzer {
 	maintainability.RegisterChecks(registry)
 	reliability.RegisterChecks(registry)
 
-	return &Analyzer{
+	analyzer := &Analyzer{
 		registry: registry,
 		config:   config,
 	}
+	// Checks that are off by default stay off
+	analyzer.applyConfig()
+	return analyzer
 }
 
 // NewWithConfig creates a new analyzer with custom configuration
@@ -43,14 +55,53 @@ func NewWithConfig(config *Config) *Analyzer {
 	return analyzer
 }
 
-// NewFromConfigFile creates a new analyzer loading config from file
+// NewFromConfigFile creates a new analyzer loading config from file, along
+// with the policies of its policy_dir
 func NewFromConfigFile(configFile string) (*Analyzer, error) {
 	config, err := LoadOrCreateConfig(configFile)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewWithConfig(config), nil
+	analyzer := NewWithConfig(config)
+	if dir := config.Analyzer.PolicyDir; dir != "" {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(configFile), dir)
+		}
+		if err := analyzer.LoadPolicies(dir); err != nil {
+			return nil, err
+		}
+	}
+	return analyzer, nil
+}
+
+// LoadPolicies registers the policies of the spec files in dir as checks.
+// They're enabled unless the configuration already lists them, and can't
+// reuse the name of another check.
+func (a *Analyzer) LoadPolicies(dir string) error {
+	policies, err := policy.LoadDir(dir)
+	if err != nil {
+		return fmt.Errorf("loading policies: %w", err)
+	}
+	for _, p := range policies {
+		if _, exists := a.registry.GetCheck(p.Name); exists {
+			return fmt.Errorf("loading policies: %s: policy %q has the name of an existing check", p.Source, p.Name)
+		}
+	}
+
+	policy.RegisterChecks(a.registry, policies)
+	for _, p := range policies {
+		if _, configured := a.config.Checks[p.Name]; !configured {
+			a.config.Checks[p.Name] = types.CheckConfig{
+				Name:        p.Name,
+				Type:        p.Type,
+				Enabled:     true,
+				Description: p.Description,
+			}
+		}
+	}
+	a.applyConfig()
+	return nil
 }
 
 // applyConfig applies configuration settings to the registry
@@ -77,13 +128,15 @@ func (a *Analyzer) Analyze(config *parser.GitLabConfig) *types.AnalysisResult {
 	// Run all enabled checks
 	for _, checker := range a.registry.GetChecks() {
 		if checker.Enabled() {
-			issues := checker.Check(config)
-			result.Issues = append(result.Issues, issues...)
+			result.Issues = append(result.Issues, a.runCheck(checker, config)...)
 		}
 	}
 
+	types.SortIssues(result.Issues)
 	result.TotalIssues = len(result.Issues)
 	result.Summary = types.CalculateSummary(result.Issues)
+	result.JobCount = countJobs(config)
+	result.Health = result.CalculateHealth()
 
 	return result
 }
@@ -103,17 +156,43 @@ func (a *Analyzer) AnalyzeWithFilter(config *parser.GitLabConfig, issueTypes ...
 	// Run filtered checks
 	for _, checker := range a.registry.GetChecks() {
 		if checker.Enabled() && (len(typeFilter) == 0 || typeFilter[checker.Type()]) {
-			issues := checker.Check(config)
-			result.Issues = append(result.Issues, issues...)
+			result.Issues = append(result.Issues, a.runCheck(checker, config)...)
 		}
 	}
 
+	types.SortIssues(result.Issues)
 	result.TotalIssues = len(result.Issues)
 	result.Summary = types.CalculateSummary(result.Issues)
+	result.JobCount = countJobs(config)
+	result.Health = result.CalculateHealth()
 
 	return result
 }
 
+// runCheck runs a check, dropping the issues on jobs from GitLab's security
+// templates that the check skips. Issues are attributed to the file defining
+// their job, and others to the main file, and tagged with the check's name
+// and whether a fixer can resolve them.
+func (a *Analyzer) runCheck(checker Checker, config *parser.GitLabConfig) []types.Issue {
+	issues := checker.Check(config)
+	kept := issues[:0]
+	for _, issue := range issues {
+		if issue.JobName != "" && a.config.ShouldSkipSecurityTemplateJob(checker.Name(), config, issue.JobName) {
+			continue
+		}
+		issue.Check = checker.Name()
+		issue.Fixable = fixer.CanFix(issue.Check)
+		if issue.SourceFile == "" {
+			issue.SourceFile = config.FilePath
+			if issue.JobName != "" {
+				issue.SourceFile = config.JobSourceFile(issue.JobName)
+			}
+		}
+		kept = append(kept, issue)
+	}
+	return kept
+}
+
 // EnableCheck enables a specific check
 func (a *Analyzer) EnableCheck(checkName string) {
 	a.config.EnableCheck(checkName)
@@ -147,8 +226,102 @@ func (a *Analyzer) ListChecks() []types.CheckConfig {
 	return checks
 }
 
+// countJobs returns the number of runnable (non-template) jobs
+func countJobs(config *parser.GitLabConfig) int {
+	if config == nil {
+		return 0
+	}
+	return len(config.ConcreteJobs())
+}
+
 // Convenience function for backward compatibility
 func Analyze(config *parser.GitLabConfig) *types.AnalysisResult {
 	analyzer := New()
 	return analyzer.Analyze(config)
 }
+
+// AnalyzeFile parses the GitLab CI file at path, resolves its includes and
+// analyzes the result with cfg, or DefaultConfig when cfg is nil. Includes
+// are resolved as parser.ParseFile does: local files relative to the file's
+// directory, remote and template includes over HTTP, while project includes,
+// which need the GitLab API, and includes that fail to load are skipped.
+// extends is left in place, since every check follows it itself.
+func AnalyzeFile(path string, cfg *Config) (*types.AnalysisResult, error) {
+	config, err := parser.ParseFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return analyzerFor(cfg).Analyze(config), nil
+}
+
+// AnalyzeAll analyzes the GitLab CI files at paths as AnalyzeFile does, but
+// concurrently, with at most GOMAXPROCS files in flight. The results are
+// keyed by path. Files that fail to load are left out of the results, and
+// their FileErrors are joined, in the order of paths, into the returned error.
+func AnalyzeAll(paths []string, cfg *Config) (map[string]*types.AnalysisResult, error) {
+	return analyzerFor(cfg).AnalyzeAll(paths)
+}
+
+// AnalyzeAll analyzes the GitLab CI files at paths like the AnalyzeAll
+// function, with this analyzer's checks, including loaded policies
+func (a *Analyzer) AnalyzeAll(paths []string) (map[string]*types.AnalysisResult, error) {
+	// Checks only read the analyzer's registry and configuration, so one
+	// analyzer serves every worker. Parsing gets a resolver per file, since
+	// resolvers cache includes without locking.
+	results := make([]*types.AnalysisResult, len(paths))
+	errs := make([]error, len(paths))
+	indexes := make(chan int)
+
+	workers := min(runtime.GOMAXPROCS(0), len(paths))
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				config, err := parser.ParseFile(paths[i])
+				if err != nil {
+					errs[i] = &FileError{Path: paths[i], Err: err}
+					continue
+				}
+				results[i] = a.Analyze(config)
+			}
+		}()
+	}
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	byPath := make(map[string]*types.AnalysisResult, len(paths))
+	for i, path := range paths {
+		if results[i] != nil {
+			byPath[path] = results[i]
+		}
+	}
+	return byPath, errors.Join(errs...)
+}
+
+// FileError is the error AnalyzeAll returns for a file it couldn't load
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("parsing %s: %v", e.Path, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// analyzerFor returns an analyzer using cfg, or DefaultConfig when cfg is nil
+func analyzerFor(cfg *Config) *Analyzer {
+	if cfg == nil {
+		return New()
+	}
+	return NewWithConfig(cfg)
+}
@@ -1,6 +1,11 @@
 package analyzer
 
 import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -142,6 +147,32 @@ func TestAnalyzerEnableDisableCheck(t *testing.T) {
 	}
 }
 
+func TestAnalyzerOptInChecks(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Workflow: &parser.Workflow{Rules: []parser.Rule{{If: `$CI_PIPELINE_SOURCE == "merge_request_event"`}}},
+		Jobs: map[string]*parser.JobConfig{
+			"test": {Stage: "test", Script: []string{"make test"}},
+		},
+	}
+	reported := func(result *types.AnalysisResult) bool {
+		for _, issue := range result.Issues {
+			if issue.Check == "missing_interruptible" {
+				return true
+			}
+		}
+		return false
+	}
+
+	analyzer := New()
+	if reported(analyzer.Analyze(config)) {
+		t.Error("Expected missing_interruptible to be off by default")
+	}
+	analyzer.EnableCheck("missing_interruptible")
+	if !reported(analyzer.Analyze(config)) {
+		t.Error("Expected missing_interruptible to report once enabled")
+	}
+}
+
 func TestAnalyzeWithFilter(t *testing.T) {
 	analyzer := New()
 
@@ -319,7 +350,7 @@ func TestAnalyze_ComprehensiveConfig(t *testing.T) {
 		Jobs: map[string]*parser.JobConfig{
 			"build project": { // Should trigger naming issue
 				Stage: "build",
-				Image: "node", // Should trigger image tag issue
+				Image: &parser.Image{Name: "node"}, // Should trigger image tag issue
 				Script: []string{
 					"npm install",
 					"npm run build",
@@ -368,6 +399,24 @@ func TestAnalyze_ComprehensiveConfig(t *testing.T) {
 	}
 }
 
+func TestAnalyze_DeterministicOrder(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"job a": {Image: &parser.Image{Name: "node"}, Artifacts: &parser.Artifacts{Paths: []string{"dist/"}}},
+			"job b": {Image: &parser.Image{Name: "python"}, Retry: &parser.Retry{Max: 5}},
+			"job c": {Image: &parser.Image{Name: "golang"}, Stage: "missing"},
+		},
+	}
+
+	first := Analyze(config)
+	for i := 0; i < 10; i++ {
+		again := Analyze(config)
+		if !reflect.DeepEqual(first.Issues, again.Issues) {
+			t.Fatalf("Analysis issue order differs between runs")
+		}
+	}
+}
+
 func TestRegistryOperations(t *testing.T) {
 	registry := NewCheckRegistry()
 
@@ -523,3 +572,291 @@ func TestConfigOperations(t *testing.T) {
 		t.Error("Expected security checks")
 	}
 }
+
+func TestAnalyze_SecurityTemplateJobs(t *testing.T) {
+	config, err := parser.Parse([]byte(`
+include:
+  - template: Jobs/SAST.gitlab-ci.yml
+stages: [test]
+sast:
+  stage: test
+  allow_failure: true
+  variables:
+    SAST_EXCLUDED_PATHS: vendor
+unit-test:
+  stage: test
+  image: golang:1.22
+  allow_failure: true
+  script: [go test ./...]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	flagged := func(result *types.AnalysisResult, jobName string) []string {
+		var paths []string
+		for _, issue := range result.Issues {
+			if issue.JobName == jobName {
+				paths = append(paths, issue.Path)
+			}
+		}
+		return paths
+	}
+
+	cfg := DefaultConfig()
+	cfg.EnableCheck("allow_failure_critical")
+	result := NewWithConfig(cfg).Analyze(config)
+	if paths := flagged(result, "sast"); len(paths) != 0 {
+		t.Errorf("Expected the template's sast job to be skipped, got %v", paths)
+	}
+	if paths := flagged(result, "unit-test"); len(paths) == 0 {
+		t.Error("Expected the user's own jobs to be analyzed")
+	}
+
+	cfg = DefaultConfig()
+	cfg.EnableCheck("allow_failure_critical")
+	cfg.Analyzer.SecurityTemplates.Analyze = true
+	if paths := flagged(NewWithConfig(cfg).Analyze(config), "sast"); len(paths) == 0 {
+		t.Error("Expected template jobs to be analyzed when configured")
+	}
+
+	// skipped_checks replaces the default list
+	cfg = DefaultConfig()
+	cfg.EnableCheck("allow_failure_critical")
+	cfg.Analyzer.SecurityTemplates.SkippedChecks = []string{"image_tags"}
+	paths := flagged(NewWithConfig(cfg).Analyze(config), "sast")
+	if !reflect.DeepEqual(paths, []string{"jobs.sast.allow_failure"}) {
+		t.Errorf("Expected allow_failure_critical to report on the sast job once left out, got %v", paths)
+	}
+}
+
+func TestAnalyzeFile(t *testing.T) {
+	dir := t.TempDir()
+	mainFile := filepath.Join(dir, ".gitlab-ci.yml")
+	os.MkdirAll(filepath.Join(dir, "ci"), 0755)
+	os.WriteFile(mainFile, []byte("include:\n  - local: ci/build.yml\nstages: [build]\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "ci", "build.yml"), []byte("build:\n  stage: build\n  image: node:latest\n  script: [npm run build]\n"), 0644)
+
+	hasImageTagIssue := func(result *types.AnalysisResult) bool {
+		for _, issue := range result.Issues {
+			if issue.Path == "jobs.build.image" {
+				return true
+			}
+		}
+		return false
+	}
+
+	result, err := AnalyzeFile(mainFile, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !hasImageTagIssue(result) {
+		t.Errorf("Expected the included job to be analyzed, got %+v", result.Issues)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Checks["image_tags"] = types.CheckConfig{Name: "image_tags", Type: types.IssueTypeSecurity, Enabled: false}
+	result, err = AnalyzeFile(mainFile, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if hasImageTagIssue(result) {
+		t.Error("Expected the given config to disable image_tags")
+	}
+
+	if _, err := AnalyzeFile(filepath.Join(dir, "missing.yml"), nil); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestAnalyzeFileSourceFiles(t *testing.T) {
+	dir := t.TempDir()
+	mainFile := filepath.Join(dir, ".gitlab-ci.yml")
+	os.MkdirAll(filepath.Join(dir, "ci"), 0755)
+	os.WriteFile(mainFile, []byte("include:\n  - local: /ci/build.yml\nstages: [build, test]\ntest:\n  stage: test\n  image: node:latest\n  script: [npm test]\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "ci", "build.yml"), []byte("build:\n  stage: build\n  image: node:latest\n  script: [npm run build]\n"), 0644)
+
+	result, err := AnalyzeFile(mainFile, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"jobs.build.image": filepath.Join(dir, "ci", "build.yml"),
+		"jobs.test.image":  mainFile,
+	}
+	for _, issue := range result.Issues {
+		if issue.SourceFile == "" {
+			t.Errorf("Expected every issue to have a source file, got %+v", issue)
+		}
+		if expected, ok := want[issue.Path]; ok {
+			if issue.SourceFile != expected {
+				t.Errorf("Expected %s to come from %s, got %s", issue.Path, expected, issue.SourceFile)
+			}
+			delete(want, issue.Path)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("Expected image tag issues for %v", want)
+	}
+}
+
+func TestLoadPolicies(t *testing.T) {
+	dir := t.TempDir()
+	policyDir := filepath.Join(dir, "policies")
+	os.MkdirAll(policyDir, 0755)
+	os.WriteFile(filepath.Join(policyDir, "deploy.yml"), []byte(`
+policies:
+  - name: deploy_environment
+    description: Deploy jobs must name their environment
+    type: reliability
+    match:
+      - field: stage
+        equals: deploy
+    require:
+      - field: environment.name
+        exists: true
+`), 0644)
+	config, err := parser.Parse([]byte("stages: [deploy]\ndeploy:\n  stage: deploy\n  script: [./deploy.sh]\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	a := New()
+	if err := a.LoadPolicies(policyDir); err != nil {
+		t.Fatalf("LoadPolicies failed: %v", err)
+	}
+	if _, ok := a.GetRegistry().GetDoc("deploy_environment"); !ok {
+		t.Error("Expected the policy to be documented")
+	}
+	found := false
+	for _, issue := range a.Analyze(config).Issues {
+		if issue.Path == "jobs.deploy.environment.name" && issue.Type == types.IssueTypeReliability {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected the policy to report the deploy job")
+	}
+
+	ciFile := filepath.Join(dir, ".gitlab-ci.yml")
+	os.WriteFile(ciFile, []byte("stages: [deploy]\ndeploy:\n  stage: deploy\n  script: [./deploy.sh]\n"), 0644)
+	results, err := a.AnalyzeAll([]string{ciFile})
+	if err != nil {
+		t.Fatalf("AnalyzeAll failed: %v", err)
+	}
+	found = false
+	for _, issue := range results[ciFile].Issues {
+		found = found || issue.Check == "deploy_environment"
+	}
+	if !found {
+		t.Error("Expected AnalyzeAll to run the loaded policies")
+	}
+
+	// A policy can't replace a built-in check
+	os.WriteFile(filepath.Join(policyDir, "clash.yml"), []byte("policies:\n  - name: image_tags\n    require:\n      - field: image.name\n        exists: true\n"), 0644)
+	if err := New().LoadPolicies(policyDir); err == nil || !strings.Contains(err.Error(), "existing check") {
+		t.Errorf("Expected an error for a policy named after a check, got %v", err)
+	}
+	os.Remove(filepath.Join(policyDir, "clash.yml"))
+
+	// policy_dir is relative to the config file, which can disable policies
+	configFile := filepath.Join(dir, "gitlab-smith.yml")
+	os.WriteFile(configFile, []byte("analyzer:\n  policy_dir: policies\nchecks:\n  deploy_environment:\n    name: deploy_environment\n    type: reliability\n    enabled: false\n"), 0644)
+	a, err = NewFromConfigFile(configFile)
+	if err != nil {
+		t.Fatalf("NewFromConfigFile failed: %v", err)
+	}
+	if a.GetConfig().IsCheckEnabled("deploy_environment") {
+		t.Error("Expected the configured policy to stay disabled")
+	}
+	for _, issue := range a.Analyze(config).Issues {
+		if issue.Path == "jobs.deploy.environment.name" {
+			t.Errorf("Expected the disabled policy not to run, got %+v", issue)
+		}
+	}
+}
+
+func TestAnalyzeAll(t *testing.T) {
+	files := scenarioFiles(t)
+	missing := filepath.Join(t.TempDir(), "missing.yml")
+
+	results, err := AnalyzeAll(append([]string{missing}, files...), nil)
+	if err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("Expected an error naming the missing file, got %v", err)
+	}
+	if _, exists := results[missing]; exists {
+		t.Error("Expected no result for the missing file")
+	}
+	if len(results) != len(files) {
+		t.Fatalf("Expected %d results, got %d", len(files), len(results))
+	}
+
+	for _, file := range files {
+		expected, err := AnalyzeFile(file, nil)
+		if err != nil {
+			t.Fatalf("AnalyzeFile(%s) failed: %v", file, err)
+		}
+		// Some messages list jobs in map order, so only the counts and
+		// scores are compared
+		got := results[file]
+		if got.TotalIssues != expected.TotalIssues || got.Summary != expected.Summary || got.Health.Score != expected.Health.Score {
+			t.Errorf("Expected the concurrent result for %s to match AnalyzeFile, got %+v and %+v", file, got.Summary, expected.Summary)
+		}
+	}
+
+	if results, err := AnalyzeAll(nil, nil); err != nil || len(results) != 0 {
+		t.Errorf("Expected no results and no error without paths, got %v, %v", results, err)
+	}
+}
+
+func BenchmarkAnalyzeAll(b *testing.B) {
+	files := scenarioFiles(b)
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, file := range files {
+				if _, err := AnalyzeFile(file, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("concurrent", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := AnalyzeAll(files, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// scenarioFiles returns the main CI files of the refactoring scenarios
+func scenarioFiles(tb testing.TB) []string {
+	files, err := filepath.Glob("../../test/refactoring-scenarios/*/*/.gitlab-ci.yml")
+	if err != nil || len(files) == 0 {
+		tb.Fatalf("Failed to find scenario files: %v", err)
+	}
+	return files
+}
+
+func TestAnalyzeFileIsDeterministic(t *testing.T) {
+	path := "../../test/realistic-app-scenarios/flask-microservice/before/.gitlab-ci.yml"
+
+	var first []byte
+	for i := 0; i < 10; i++ {
+		result, err := AnalyzeFile(path, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		output, err := json.Marshal(result)
+		if err != nil {
+			t.Fatalf("Failed to marshal result: %v", err)
+		}
+		if first == nil {
+			first = output
+		} else if !bytes.Equal(output, first) {
+			t.Fatalf("Expected run %d to produce the same output as the first", i+1)
+		}
+	}
+}
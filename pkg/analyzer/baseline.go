@@ -0,0 +1,47 @@
+package analyzer
+
+import (
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+)
+
+// CompareAnalyses diffs two analysis results by issue fingerprint so that
+// only issues introduced by head are reported as added. Repeated fingerprints
+// are matched one-to-one, so a second copy of an existing issue still counts
+// as new.
+func CompareAnalyses(base, head *types.AnalysisResult) *types.AnalysisDelta {
+	delta := &types.AnalysisDelta{
+		Added:     []types.Issue{},
+		Removed:   []types.Issue{},
+		Unchanged: []types.Issue{},
+	}
+
+	remaining := make(map[string][]types.Issue)
+	if base != nil {
+		for _, issue := range base.Issues {
+			fingerprint := issue.Fingerprint()
+			remaining[fingerprint] = append(remaining[fingerprint], issue)
+		}
+	}
+
+	if head != nil {
+		for _, issue := range head.Issues {
+			fingerprint := issue.Fingerprint()
+			if matches := remaining[fingerprint]; len(matches) > 0 {
+				delta.Unchanged = append(delta.Unchanged, issue)
+				remaining[fingerprint] = matches[1:]
+				continue
+			}
+			delta.Added = append(delta.Added, issue)
+		}
+	}
+
+	for _, issues := range remaining {
+		delta.Removed = append(delta.Removed, issues...)
+	}
+
+	types.SortIssues(delta.Added)
+	types.SortIssues(delta.Removed)
+	types.SortIssues(delta.Unchanged)
+
+	return delta
+}
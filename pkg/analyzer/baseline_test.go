@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+)
+
+func TestCompareAnalyses(t *testing.T) {
+	existing := types.Issue{Type: types.IssueTypeSecurity, Severity: types.SeverityMedium, Path: "jobs.build.image", Message: "Docker image without explicit tag"}
+	fixed := types.Issue{Type: types.IssueTypePerformance, Severity: types.SeverityLow, Path: "jobs.test.cache", Message: "Cache configured without key"}
+	introduced := types.Issue{Type: types.IssueTypeReliability, Severity: types.SeverityHigh, Path: "jobs.deploy.retry", Message: "Deploy job without retry"}
+
+	base := &types.AnalysisResult{Issues: []types.Issue{existing, fixed}}
+	// Severity changes don't affect the fingerprint
+	moved := existing
+	moved.Severity = types.SeverityHigh
+	head := &types.AnalysisResult{Issues: []types.Issue{moved, introduced}}
+
+	delta := CompareAnalyses(base, head)
+
+	if len(delta.Added) != 1 || delta.Added[0].Fingerprint() != introduced.Fingerprint() {
+		t.Errorf("Expected only the introduced issue to be added, got %v", delta.Added)
+	}
+	if len(delta.Removed) != 1 || delta.Removed[0].Fingerprint() != fixed.Fingerprint() {
+		t.Errorf("Expected only the fixed issue to be removed, got %v", delta.Removed)
+	}
+	if len(delta.Unchanged) != 1 || delta.Unchanged[0].Fingerprint() != existing.Fingerprint() {
+		t.Errorf("Expected the existing issue to be unchanged, got %v", delta.Unchanged)
+	}
+}
+
+func TestCompareAnalyses_DuplicateFingerprints(t *testing.T) {
+	issue := types.Issue{Type: types.IssueTypeMaintainability, Severity: types.SeverityLow, Path: "jobs", Message: "Duplicate script block"}
+
+	base := &types.AnalysisResult{Issues: []types.Issue{issue}}
+	head := &types.AnalysisResult{Issues: []types.Issue{issue, issue}}
+
+	delta := CompareAnalyses(base, head)
+
+	if len(delta.Added) != 1 || len(delta.Unchanged) != 1 || len(delta.Removed) != 0 {
+		t.Errorf("Expected one added and one unchanged issue, got %+v", delta)
+	}
+}
+
+func TestCompareAnalyses_NilBaseline(t *testing.T) {
+	head := &types.AnalysisResult{Issues: []types.Issue{{Type: types.IssueTypeSecurity, Path: "variables", Message: "Secret"}}}
+
+	delta := CompareAnalyses(nil, head)
+
+	if len(delta.Added) != 1 || len(delta.Removed) != 0 || len(delta.Unchanged) != 0 {
+		t.Errorf("Expected every issue to be new without a baseline, got %+v", delta)
+	}
+}
+
+func TestCompareAnalyses_SameFile(t *testing.T) {
+	path := "../../test/realistic-app-scenarios/flask-microservice/before/.gitlab-ci.yml"
+
+	base, err := AnalyzeFile(path, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	head, err := AnalyzeFile(path, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	delta := CompareAnalyses(base, head)
+	if len(delta.Added) != 0 || len(delta.Removed) != 0 {
+		t.Errorf("Expected comparing a file with itself to add and resolve nothing, got %d added and %d resolved", len(delta.Added), len(delta.Removed))
+	}
+	if len(delta.Unchanged) != len(base.Issues) {
+		t.Errorf("Expected all %d issues to be unchanged, got %d", len(base.Issues), len(delta.Unchanged))
+	}
+}
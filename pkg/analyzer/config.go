@@ -5,10 +5,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"regexp"
-	"strings"
 
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
 	"gopkg.in/yaml.v2"
 )
 
@@ -19,12 +18,27 @@ type Config struct {
 	Checks   map[string]types.CheckConfig `yaml:"checks" json:"checks"`
 	Differ   DifferConfig                 `yaml:"differ,omitempty" json:"differ,omitempty"`
 	Output   OutputConfig                 `yaml:"output,omitempty" json:"output,omitempty"`
+	// RepositoryFiles lists the files of the analyzed repository, as
+	// returned by parser.RepositoryFiles. When set, it's passed to every
+	// check as the "repository_files" param, so checks can verify that the
+	// files a configuration references exist.
+	RepositoryFiles []string `yaml:"-" json:"-"`
 }
 
 // AnalyzerConfig holds analyzer-specific configuration
 type AnalyzerConfig struct {
 	SeverityThreshold types.Severity   `yaml:"severity_threshold,omitempty" json:"severity_threshold,omitempty"`
 	GlobalExclusions  GlobalExclusions `yaml:"global_exclusions,omitempty" json:"global_exclusions,omitempty"`
+	// SecurityTemplates decides which checks skip the jobs of GitLab's
+	// security templates
+	SecurityTemplates SecurityTemplates `yaml:"security_templates,omitempty" json:"security_templates,omitempty"`
+	// ExitPolicy decides which issues make `analyze` exit with a failure
+	// rather than a warning code
+	types.ExitPolicy `yaml:",inline"`
+	// PolicyDir is a directory of policy spec files whose policies are run
+	// as additional checks, see package policy. A relative path is resolved
+	// against the configuration file's directory.
+	PolicyDir string `yaml:"policy_dir,omitempty" json:"policy_dir,omitempty"`
 }
 
 // GlobalExclusions defines global exclusion patterns
@@ -33,6 +47,43 @@ type GlobalExclusions struct {
 	Jobs  []string `yaml:"jobs,omitempty" json:"jobs,omitempty"`
 }
 
+// DefaultSecurityTemplateChecks are the checks that skip jobs from GitLab's
+// security templates, such as sast or dependency_scanning, unless
+// configured otherwise. The templates set allow_failure, images and rules on
+// purpose and define jobs that users only override in part.
+var DefaultSecurityTemplateChecks = []string{
+	"allow_failure_critical",
+	"artifact_expiration",
+	"cache_usage",
+	"empty_needs",
+	"git_clone_strategy",
+	"image_tags",
+	"job_naming",
+	"job_without_script",
+	"missing_interruptible",
+	"missing_needs",
+	"retry_configuration",
+	"runner_tags",
+	"unknown_extends",
+}
+
+// OptInChecks are the checks DefaultConfig leaves disabled. They suggest
+// practices that only pay off for some pipelines, so they're enabled where
+// they do.
+var OptInChecks = []string{
+	"allow_failure_critical",
+	"missing_interruptible",
+}
+
+// SecurityTemplates configures how jobs from GitLab's security templates are
+// analyzed
+type SecurityTemplates struct {
+	// Analyze reports issues on security template jobs like on any other job
+	Analyze bool `yaml:"analyze,omitempty" json:"analyze,omitempty"`
+	// SkippedChecks replaces DefaultSecurityTemplateChecks
+	SkippedChecks []string `yaml:"skipped_checks,omitempty" json:"skipped_checks,omitempty"`
+}
+
 // DifferConfig holds differ-specific configuration
 type DifferConfig struct {
 	IgnoreChanges       []string `yaml:"ignore_changes,omitempty" json:"ignore_changes,omitempty"`
@@ -102,13 +153,91 @@ func DefaultConfig() *Config {
 				Enabled:     true,
 				Description: "Identifies workflow optimization opportunities",
 			},
+			"parallel_resource_group": {
+				Name:        "parallel_resource_group",
+				Type:        types.IssueTypePerformance,
+				Enabled:     true,
+				Description: "Detects parallel jobs serialized by a single resource_group",
+			},
+			"parallel_without_matrix": {
+				Name:        "parallel_without_matrix",
+				Type:        types.IssueTypePerformance,
+				Enabled:     true,
+				Description: "Detects high parallel counts without parallel:matrix",
+			},
+			"large_artifact_paths": {
+				Name:        "large_artifact_paths",
+				Type:        types.IssueTypePerformance,
+				Enabled:     true,
+				Description: "Detects artifact paths that match the whole project directory",
+			},
+			"cache_artifact_overlap": {
+				Name:        "cache_artifact_overlap",
+				Type:        types.IssueTypePerformance,
+				Enabled:     true,
+				Description: "Detects directories that are both cached and uploaded as artifacts",
+			},
+			"cache_key_conflicts": {
+				Name:        "cache_key_conflicts",
+				Type:        types.IssueTypePerformance,
+				Enabled:     true,
+				Description: "Detects jobs caching the same paths under different keys, or different paths under the same key",
+			},
+			"needs_artifacts": {
+				Name:        "needs_artifacts",
+				Type:        types.IssueTypePerformance,
+				Enabled:     true,
+				Description: "Suggests 'artifacts: false' for needs whose artifacts are never used",
+			},
+			"missing_interruptible": {
+				Name:        "missing_interruptible",
+				Type:        types.IssueTypePerformance,
+				Enabled:     false,
+				Description: "Suggests interruptible jobs for merge request pipelines (opt-in)",
+			},
+			"auto_cancel_on_new_commit": {
+				Name:        "auto_cancel_on_new_commit",
+				Type:        types.IssueTypePerformance,
+				Enabled:     true,
+				Description: "Suggests workflow:auto_cancel:on_new_commit: interruptible when long merge request jobs aren't interruptible",
+			},
+			"cache_pull_policy": {
+				Name:        "cache_pull_policy",
+				Type:        types.IssueTypePerformance,
+				Enabled:     true,
+				Description: "Suggests 'policy: pull' for cache consumers that never write the cache",
+			},
+			"cache_key_files": {
+				Name:        "cache_key_files",
+				Type:        types.IssueTypePerformance,
+				Enabled:     true,
+				Description: "Detects cache keys on missing files and fixed keys on dependency directories",
+			},
+			"redundant_needs": {
+				Name:        "redundant_needs",
+				Type:        types.IssueTypePerformance,
+				Enabled:     true,
+				Description: "Detects needs already implied through another need",
+			},
+			"empty_needs": {
+				Name:        "empty_needs",
+				Type:        types.IssueTypePerformance,
+				Enabled:     true,
+				Description: "Suggests 'needs: []' for jobs that use nothing from earlier stages but wait for them",
+			},
+			"git_clone_strategy": {
+				Name:        "git_clone_strategy",
+				Type:        types.IssueTypePerformance,
+				Enabled:     true,
+				Description: "Suggests a shallow GIT_DEPTH for full clones that don't use the history, and GIT_STRATEGY: none for jobs that don't use the repository",
+			},
 
 			// Security checks
 			"image_tags": {
 				Name:        "image_tags",
 				Type:        types.IssueTypeSecurity,
 				Enabled:     true,
-				Description: "Ensures Docker images use specific tags",
+				Description: "Ensures Docker images and services use specific, non-floating tags",
 			},
 			"environment_variables": {
 				Name:        "environment_variables",
@@ -116,6 +245,24 @@ func DefaultConfig() *Config {
 				Enabled:     true,
 				Description: "Detects potential secrets in variable names",
 			},
+			"id_token_audience": {
+				Name:        "id_token_audience",
+				Type:        types.IssueTypeSecurity,
+				Enabled:     true,
+				Description: "Detects OIDC id_tokens without a scoped audience or issued to every job",
+			},
+			"secrets_configuration": {
+				Name:        "secrets_configuration",
+				Type:        types.IssueTypeSecurity,
+				Enabled:     true,
+				Description: "Detects secrets without a matching ID token, incomplete Vault references and secrets in default",
+			},
+			"script_shell_injection": {
+				Name:        "script_shell_injection",
+				Type:        types.IssueTypeSecurity,
+				Enabled:     true,
+				Description: "Detects eval, sh -c and curl | sh on variable input, and unquoted commit or merge request metadata in scripts",
+			},
 
 			// Maintainability checks
 			"job_naming": {
@@ -130,6 +277,12 @@ func DefaultConfig() *Config {
 				Enabled:     true,
 				Description: "Detects overly complex job scripts",
 			},
+			"deprecated_commands": {
+				Name:        "deprecated_commands",
+				Type:        types.IssueTypeMaintainability,
+				Enabled:     true,
+				Description: "Detects deprecated commands in scripts such as apt-key, docker-compose v1 and Python 2",
+			},
 			"duplicated_code": {
 				Name:        "duplicated_code",
 				Type:        types.IssueTypeMaintainability,
@@ -142,6 +295,13 @@ func DefaultConfig() *Config {
 				Enabled:     true,
 				Description: "Detects duplicate before_script configurations",
 			},
+			"common_script_prefix": {
+				Name:        "common_script_prefix",
+				Type:        types.IssueTypeMaintainability,
+				Enabled:     true,
+				Description: "Detects leading before_script or after_script commands shared by many jobs",
+			},
+
 			"duplicated_cache_config": {
 				Name:        "duplicated_cache_config",
 				Type:        types.IssueTypeMaintainability,
@@ -154,6 +314,12 @@ func DefaultConfig() *Config {
 				Enabled:     true,
 				Description: "Detects duplicate job setup patterns",
 			},
+			"duplicated_changes_rules": {
+				Name:        "duplicated_changes_rules",
+				Type:        types.IssueTypeMaintainability,
+				Enabled:     true,
+				Description: "Detects rules:changes globs repeated across many jobs",
+			},
 			"duplicated_variables": {
 				Name:        "duplicated_variables",
 				Type:        types.IssueTypeMaintainability,
@@ -166,6 +332,18 @@ func DefaultConfig() *Config {
 				Enabled:     true,
 				Description: "Detects overly complex rules configurations",
 			},
+			"rules_complexity": {
+				Name:        "rules_complexity",
+				Type:        types.IssueTypeMaintainability,
+				Enabled:     true,
+				Description: "Scores rules by conditions, variables and file checks and flags complex ones",
+			},
+			"rules_with_legacy_keywords": {
+				Name:        "rules_with_legacy_keywords",
+				Type:        types.IssueTypeMaintainability,
+				Enabled:     true,
+				Description: "Detects top-level when, only or except that rules silently override",
+			},
 			"template_complexity": {
 				Name:        "template_complexity",
 				Type:        types.IssueTypeMaintainability,
@@ -184,6 +362,24 @@ func DefaultConfig() *Config {
 				Enabled:     true,
 				Description: "Suggests include optimization opportunities",
 			},
+			"included_job_overrides": {
+				Name:        "included_job_overrides",
+				Type:        types.IssueTypeMaintainability,
+				Enabled:     true,
+				Description: "Detects jobs defined in several sources where one definition silently replaces another",
+			},
+			"duplicate_stages": {
+				Name:        "duplicate_stages",
+				Type:        types.IssueTypeMaintainability,
+				Enabled:     true,
+				Description: "Detects stages listed more than once",
+			},
+			"unused_stages": {
+				Name:        "unused_stages",
+				Type:        types.IssueTypeMaintainability,
+				Enabled:     true,
+				Description: "Detects declared stages that no job uses",
+			},
 			"external_include_duplication": {
 				Name:        "external_include_duplication",
 				Type:        types.IssueTypeMaintainability,
@@ -208,6 +404,30 @@ func DefaultConfig() *Config {
 				Enabled:     true,
 				Description: "Ensures stages are explicitly defined",
 			},
+			"workflow_skipped_jobs": {
+				Name:        "workflow_skipped_jobs",
+				Type:        types.IssueTypeMaintainability,
+				Enabled:     true,
+				Description: "Detects jobs whose rules never match a pipeline allowed by workflow:rules",
+			},
+			"redundant_parallel": {
+				Name:        "redundant_parallel",
+				Type:        types.IssueTypeMaintainability,
+				Enabled:     true,
+				Description: "Detects 'parallel: 1', which has no effect",
+			},
+			"shadowed_rules": {
+				Name:        "shadowed_rules",
+				Type:        types.IssueTypeMaintainability,
+				Enabled:     true,
+				Description: "Detects rules that can never apply because an earlier rule always matches first",
+			},
+			"manual_job_blocking": {
+				Name:        "manual_job_blocking",
+				Type:        types.IssueTypeMaintainability,
+				Enabled:     true,
+				Description: "Reports whether manual jobs block the pipeline when allow_failure is left to GitLab's defaults",
+			},
 
 			// Reliability checks
 			"retry_configuration": {
@@ -222,6 +442,115 @@ func DefaultConfig() *Config {
 				Enabled:     true,
 				Description: "Detects jobs referencing undefined stages",
 			},
+			"job_without_script": {
+				Name:        "job_without_script",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects jobs that have no script after resolving extends",
+			},
+			"unknown_job_keywords": {
+				Name:        "unknown_job_keywords",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects misspelled or unknown job keywords",
+			},
+			"deploy_resource_group": {
+				Name:        "deploy_resource_group",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects jobs deploying to the same environment without a shared resource_group",
+			},
+			"environment_stop_jobs": {
+				Name:        "environment_stop_jobs",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects on_stop references to missing jobs or jobs that don't stop the environment",
+			},
+			"production_deploy_gate": {
+				Name:        "production_deploy_gate",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects production deploys without a resource_group or a manual or tag gate",
+			},
+			"environment_urls": {
+				Name:        "environment_urls",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Validates environment URLs and flags production or staging deployments without one",
+			},
+			"needs_limit": {
+				Name:        "needs_limit",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects jobs with more needs, and pipelines with more jobs, than GitLab allows",
+			},
+			"cross_project_needs": {
+				Name:        "cross_project_needs",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects needs:project entries missing a ref or job",
+			},
+
+			"unknown_extends": {
+				Name:        "unknown_extends",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects extends targets that aren't defined after includes are merged",
+			},
+			"allow_failure_critical": {
+				Name:        "allow_failure_critical",
+				Type:        types.IssueTypeReliability,
+				Enabled:     false,
+				Description: "Detects allow_failure: true on test, lint and security jobs (opt-in)",
+			},
+			"coverage_regex": {
+				Name:        "coverage_regex",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects invalid coverage patterns and coverage set on several jobs",
+			},
+			"runner_tags": {
+				Name:        "runner_tags",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects untagged jobs among tagged ones and runner tags used by a single job",
+			},
+			"report_only_dependencies": {
+				Name:        "report_only_dependencies",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects jobs taking artifacts from a job whose only artifacts are reports",
+			},
+			"dependencies_outside_needs": {
+				Name:        "dependencies_outside_needs",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects dependencies on jobs missing from the job's needs",
+			},
+			"global_keywords_as_jobs": {
+				Name:        "global_keywords_as_jobs",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects jobs named after global keywords such as image or cache, which GitLab doesn't create",
+			},
+			"rules_variables_scope": {
+				Name:        "rules_variables_scope",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects script variables set by only some of a job's rules, leaving them empty in other pipelines",
+			},
+			"pages_artifacts": {
+				Name:        "pages_artifacts",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects Pages jobs that don't upload their publish directory as an artifact",
+			},
+			"job_timeouts": {
+				Name:        "job_timeouts",
+				Type:        types.IssueTypeReliability,
+				Enabled:     true,
+				Description: "Detects invalid timeouts and timeouts longer than a configured maximum, including retries",
+			},
 		},
 	}
 }
@@ -341,7 +670,7 @@ func (c *Config) ShouldSkipJob(checkName, jobName string) bool {
 	// Check global exclusions first
 	if c.Analyzer.GlobalExclusions.Jobs != nil {
 		for _, pattern := range c.Analyzer.GlobalExclusions.Jobs {
-			if matched, _ := matchPattern(pattern, jobName); matched {
+			if parser.MatchPattern(pattern, jobName) {
 				return true
 			}
 		}
@@ -351,7 +680,7 @@ func (c *Config) ShouldSkipJob(checkName, jobName string) bool {
 	if check, exists := c.Checks[checkName]; exists {
 		// Check ignore patterns
 		for _, pattern := range check.IgnorePatterns {
-			if matched, _ := matchPattern(pattern, jobName); matched {
+			if parser.MatchPattern(pattern, jobName) {
 				return true
 			}
 		}
@@ -367,12 +696,30 @@ func (c *Config) ShouldSkipJob(checkName, jobName string) bool {
 	return false
 }
 
+// ShouldSkipSecurityTemplateJob reports whether a check skips a job because
+// it comes from one of GitLab's security templates
+func (c *Config) ShouldSkipSecurityTemplateJob(checkName string, gitlabConfig *parser.GitLabConfig, jobName string) bool {
+	if c.Analyzer.SecurityTemplates.Analyze {
+		return false
+	}
+	skipped := c.Analyzer.SecurityTemplates.SkippedChecks
+	if skipped == nil {
+		skipped = DefaultSecurityTemplateChecks
+	}
+	for _, name := range skipped {
+		if name == checkName {
+			return gitlabConfig.IsSecurityTemplateJob(jobName)
+		}
+	}
+	return false
+}
+
 // ShouldSkipPath checks if a path should be excluded based on configuration
 func (c *Config) ShouldSkipPath(checkName, path string) bool {
 	// Check global exclusions first
 	if c.Analyzer.GlobalExclusions.Paths != nil {
 		for _, pattern := range c.Analyzer.GlobalExclusions.Paths {
-			if matched, _ := matchPattern(pattern, path); matched {
+			if parser.MatchPattern(pattern, path) {
 				return true
 			}
 		}
@@ -381,7 +728,7 @@ func (c *Config) ShouldSkipPath(checkName, path string) bool {
 	// Check check-specific exclusions
 	if check, exists := c.Checks[checkName]; exists {
 		for _, excludedPath := range check.Exclusions.Paths {
-			if matched, _ := matchPattern(excludedPath, path); matched {
+			if parser.MatchPattern(excludedPath, path) {
 				return true
 			}
 		}
@@ -430,15 +777,3 @@ func severityLevel(s types.Severity) int {
 		return 0
 	}
 }
-
-// Helper function for simple glob pattern matching
-func matchPattern(pattern, str string) (bool, error) {
-	// Simple implementation - can be enhanced with proper glob library
-	if strings.Contains(pattern, "*") {
-		// Convert simple glob to regex
-		regexPattern := strings.ReplaceAll(pattern, "*", ".*")
-		regexPattern = "^" + regexPattern + "$"
-		return regexp.MatchString(regexPattern, str)
-	}
-	return pattern == str, nil
-}
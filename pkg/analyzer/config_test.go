@@ -35,10 +35,10 @@ func TestDefaultConfigStructure(t *testing.T) {
 		}
 	}
 
-	// Verify all checks are enabled by default
+	// Verify all checks but the opt-in ones are enabled by default
 	for checkName, check := range config.Checks {
-		if !check.Enabled {
-			t.Errorf("Check '%s' should be enabled by default", checkName)
+		if optIn := types.ContainsString(OptInChecks, checkName); check.Enabled == optIn {
+			t.Errorf("Check '%s' should be enabled by default unless it's opt-in, got enabled: %v", checkName, check.Enabled)
 		}
 		if check.Name != checkName {
 			t.Errorf("Check name mismatch: map key '%s' vs check.Name '%s'", checkName, check.Name)
@@ -629,37 +629,3 @@ func TestConfigGetCustomParam(t *testing.T) {
 		t.Errorf("Expected default value, got %v", value)
 	}
 }
-
-func TestMatchPattern(t *testing.T) {
-	tests := []struct {
-		pattern string
-		str     string
-		match   bool
-	}{
-		{"exact", "exact", true},
-		{"exact", "not-exact", false},
-		{"prefix-*", "prefix-test", true},
-		{"prefix-*", "prefix-", true},
-		{"prefix-*", "other-test", false},
-		{"*-suffix", "test-suffix", true},
-		{"*-suffix", "-suffix", true},
-		{"*-suffix", "test-other", false},
-		{"*-middle-*", "test-middle-part", true},
-		{"*-middle-*", "-middle-", true},
-		{"*-middle-*", "test-other-part", false},
-		{"*", "anything", true},
-		{"*", "", true},
-	}
-
-	for _, tt := range tests {
-		result, err := matchPattern(tt.pattern, tt.str)
-		if err != nil {
-			t.Errorf("matchPattern(%s, %s) returned error: %v", tt.pattern, tt.str, err)
-			continue
-		}
-		if result != tt.match {
-			t.Errorf("matchPattern(%s, %s) = %v, want %v",
-				tt.pattern, tt.str, result, tt.match)
-		}
-	}
-}
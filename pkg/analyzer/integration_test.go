@@ -22,13 +22,13 @@ func TestIntegrationAdvancedChecks(t *testing.T) {
 			Jobs: map[string]*parser.JobConfig{
 				// Template job
 				".base": {
-					Image:        "node:16",
+					Image:        &parser.Image{Name: "node:16"},
 					BeforeScript: []string{"npm install", "npm run setup"},
 				},
 				// Multiple similar jobs that could use matrix
 				"test_node14": {
 					Stage: "test",
-					Image: "node:14",
+					Image: &parser.Image{Name: "node:14"},
 					Variables: map[string]interface{}{
 						"NODE_VERSION": "14",
 					},
@@ -36,7 +36,7 @@ func TestIntegrationAdvancedChecks(t *testing.T) {
 				},
 				"test_node16": {
 					Stage: "test",
-					Image: "node:16",
+					Image: &parser.Image{Name: "node:16"},
 					Variables: map[string]interface{}{
 						"NODE_VERSION": "16",
 					},
@@ -44,7 +44,7 @@ func TestIntegrationAdvancedChecks(t *testing.T) {
 				},
 				"test_node18": {
 					Stage: "test",
-					Image: "node:18",
+					Image: &parser.Image{Name: "node:18"},
 					Variables: map[string]interface{}{
 						"NODE_VERSION": "18",
 					},
@@ -149,7 +149,7 @@ func TestIntegrationAdvancedChecks(t *testing.T) {
 		config := &parser.GitLabConfig{
 			Jobs: map[string]*parser.JobConfig{
 				".base": {
-					Image:        "alpine",
+					Image:        &parser.Image{Name: "alpine"},
 					BeforeScript: []string{"echo base"},
 				},
 				".extended": {
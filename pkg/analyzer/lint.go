@@ -0,0 +1,129 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// DiagnosticSource identifies which stage of linting produced a diagnostic
+type DiagnosticSource string
+
+const (
+	DiagnosticSourceSchema   DiagnosticSource = "schema"
+	DiagnosticSourceParser   DiagnosticSource = "parser"
+	DiagnosticSourceAnalyzer DiagnosticSource = "analyzer"
+)
+
+// Diagnostic is a single lint finding from any source
+type Diagnostic struct {
+	Source     DiagnosticSource `json:"source"`
+	Severity   types.Severity   `json:"severity"`
+	Type       types.IssueType  `json:"type,omitempty"`
+	Path       string           `json:"path,omitempty"`
+	Line       int              `json:"line,omitempty"`
+	Message    string           `json:"message"`
+	Suggestion string           `json:"suggestion,omitempty"`
+}
+
+// LintReport combines schema errors, parse warnings and analyzer issues
+type LintReport struct {
+	Diagnostics []Diagnostic          `json:"diagnostics"`
+	Analysis    *types.AnalysisResult `json:"analysis,omitempty"`
+}
+
+// HasSchemaErrors reports whether GitLab would reject the configuration
+func (r *LintReport) HasSchemaErrors() bool {
+	for _, diagnostic := range r.Diagnostics {
+		if diagnostic.Source == DiagnosticSourceSchema {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint validates, parses and analyzes a configuration in one pass. Schema
+// errors are reported as high severity and parse warnings as medium, so all
+// diagnostics share the analyzer's severity ordering. An error is returned
+// only when the data isn't valid YAML. A nil cfg uses the default config.
+func Lint(data []byte, cfg *Config) (*LintReport, error) {
+	schemaErrors, schemaWarnings, err := parser.ValidateSchema(data)
+	if err != nil {
+		return nil, err
+	}
+
+	config, parseWarnings, err := parser.ParseWithWarnings(data, parser.ParseOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	report := &LintReport{Diagnostics: []Diagnostic{}}
+	for _, schemaError := range schemaErrors {
+		report.Diagnostics = append(report.Diagnostics, Diagnostic{
+			Source:   DiagnosticSourceSchema,
+			Severity: types.SeverityHigh,
+			Path:     schemaError.Path,
+			Line:     schemaError.Line,
+			Message:  schemaError.Message,
+		})
+	}
+	for _, warning := range append(parseWarnings, schemaWarnings...) {
+		report.Diagnostics = append(report.Diagnostics, Diagnostic{
+			Source:   DiagnosticSourceParser,
+			Severity: types.SeverityMedium,
+			Line:     warning.Line,
+			Message:  warning.Message,
+		})
+	}
+
+	a := New()
+	if cfg != nil {
+		a = NewWithConfig(cfg)
+	}
+	report.Analysis = a.Analyze(config)
+	for _, issue := range report.Analysis.Issues {
+		report.Diagnostics = append(report.Diagnostics, Diagnostic{
+			Source:     DiagnosticSourceAnalyzer,
+			Severity:   issue.Severity,
+			Type:       issue.Type,
+			Path:       issue.Path,
+			Message:    issue.Message,
+			Suggestion: issue.Suggestion,
+		})
+	}
+
+	sortDiagnostics(report.Diagnostics)
+	return report, nil
+}
+
+var diagnosticSourceOrder = map[DiagnosticSource]int{
+	DiagnosticSourceSchema:   0,
+	DiagnosticSourceParser:   1,
+	DiagnosticSourceAnalyzer: 2,
+}
+
+// sortDiagnostics orders by severity (highest first), then source, line,
+// type, path and message
+func sortDiagnostics(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.Severity != b.Severity {
+			return types.SeverityRank(a.Severity) > types.SeverityRank(b.Severity)
+		}
+		if a.Source != b.Source {
+			return diagnosticSourceOrder[a.Source] < diagnosticSourceOrder[b.Source]
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Message < b.Message
+	})
+}
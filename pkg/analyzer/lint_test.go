@@ -0,0 +1,55 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+)
+
+func TestLint(t *testing.T) {
+	data := []byte(`
+stages: [build]
+build:
+  stage: build
+  image: node:latest
+  script: [make]
+  when: sometimes
+  scripts: [make test]
+lint:
+  script: [make lint]
+lint:
+  script: [make lint]
+`)
+
+	report, err := Lint(data, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !report.HasSchemaErrors() {
+		t.Error("Expected schema errors for invalid when value")
+	}
+
+	sources := make(map[DiagnosticSource]int)
+	for _, diagnostic := range report.Diagnostics {
+		sources[diagnostic.Source]++
+	}
+	for _, source := range []DiagnosticSource{DiagnosticSourceSchema, DiagnosticSourceParser, DiagnosticSourceAnalyzer} {
+		if sources[source] == 0 {
+			t.Errorf("Expected diagnostics from %s, got %+v", source, report.Diagnostics)
+		}
+	}
+
+	for i := 1; i < len(report.Diagnostics); i++ {
+		prev, curr := report.Diagnostics[i-1], report.Diagnostics[i]
+		if types.SeverityRank(prev.Severity) < types.SeverityRank(curr.Severity) {
+			t.Errorf("Diagnostics not ordered by severity: %+v before %+v", prev, curr)
+		}
+	}
+}
+
+func TestLintInvalidYAML(t *testing.T) {
+	if _, err := Lint([]byte("build: [unclosed"), nil); err == nil {
+		t.Error("Expected error for invalid YAML")
+	}
+}
@@ -0,0 +1,92 @@
+package maintainability
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckDuplicatedChangesRules clusters jobs by the rules:changes globs they
+// repeat in their own rules. When at least min_jobs jobs (3 by default,
+// configurable through custom_params) list the same globs, any matching
+// change runs all of them, and the condition is reported as a candidate for
+// a shared rules template. Jobs inheriting the rule through extends already
+// share it and aren't counted.
+func CheckDuplicatedChangesRules(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	minJobs := types.IntParam(params, "min_jobs", 3)
+
+	clusters := make(map[string][]string)
+	for _, jobName := range config.ConcreteJobNames() {
+		seen := make(map[string]bool)
+		for _, rule := range config.Jobs[jobName].Rules {
+			key := changesKey(rule.Changes)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			clusters[key] = append(clusters[key], jobName)
+		}
+	}
+
+	keys := make([]string, 0, len(clusters))
+	for key := range clusters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		jobNames := clusters[key]
+		if len(jobNames) < minJobs {
+			continue
+		}
+		globs := strings.Split(key, "\n")
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeMaintainability,
+			Severity:   types.SeverityLow,
+			Path:       "jobs.*.rules",
+			Message:    fmt.Sprintf("%d jobs repeat rules:changes [%s], so one matching change runs all of them: %s", len(jobNames), strings.Join(globs, ", "), strings.Join(jobNames, ", ")),
+			Suggestion: fmt.Sprintf("Move the rule into a shared template such as '.%s-changes' and extend it, or gate the pipeline with workflow:rules if every job depends on it", changesTemplateName(globs)),
+		})
+	}
+
+	return issues
+}
+
+// changesKey identifies a set of changes globs regardless of their order.
+// It's empty for rules without changes.
+func changesKey(changes []string) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	globs := make([]string, 0, len(changes))
+	seen := make(map[string]bool)
+	for _, glob := range changes {
+		glob = strings.TrimPrefix(strings.TrimSpace(glob), "./")
+		if glob != "" && !seen[glob] {
+			seen[glob] = true
+			globs = append(globs, glob)
+		}
+	}
+	sort.Strings(globs)
+	return strings.Join(globs, "\n")
+}
+
+// changesTemplateName derives a template name from the leading directory of
+// the first glob that has one, e.g. "src" for src/**/*.go
+func changesTemplateName(globs []string) string {
+	for _, glob := range globs {
+		dir, _, found := strings.Cut(glob, "/")
+		if !found {
+			continue
+		}
+		if name := strings.Trim(dir, "*?[]{}.-_"); name != "" && !strings.ContainsAny(name, "*?[]{}") {
+			return name
+		}
+	}
+	return "shared"
+}
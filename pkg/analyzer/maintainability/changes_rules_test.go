@@ -0,0 +1,71 @@
+package maintainability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckDuplicatedChangesRules(t *testing.T) {
+	yamlContent := `
+.src-changes:
+  rules:
+    - changes: [src/**/*]
+
+build:
+  script: [make]
+  rules:
+    - changes: [src/**/*, go.mod]
+test:
+  script: [make test]
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+      changes: [go.mod, ./src/**/*]
+lint:
+  script: [make lint]
+  rules:
+    - changes: [src/**/*, go.mod]
+    - changes: [src/**/*, go.mod]
+      when: manual
+
+docs:
+  script: [make docs]
+  rules:
+    - changes: [docs/**/*]
+site:
+  script: [make site]
+  rules:
+    - changes: [docs/**/*]
+
+inherited_1:
+  extends: .src-changes
+  script: [make one]
+inherited_2:
+  extends: .src-changes
+  script: [make two]
+inherited_3:
+  extends: .src-changes
+  script: [make three]
+`
+	config, err := parser.Parse([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	issues := CheckDuplicatedChangesRules(config, nil)
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d: %v", len(issues), issues)
+	}
+	message := issues[0].Message
+	if !strings.Contains(message, "3 jobs repeat rules:changes [go.mod, src/**/*]") || !strings.HasSuffix(message, "build, lint, test") {
+		t.Errorf("Expected the globs and jobs in the message, got %q", message)
+	}
+	if !strings.Contains(issues[0].Suggestion, "'.src-changes'") {
+		t.Errorf("Expected a template name derived from the glob, got %q", issues[0].Suggestion)
+	}
+
+	if issues := CheckDuplicatedChangesRules(config, map[string]interface{}{"min_jobs": 2}); len(issues) != 2 {
+		t.Errorf("Expected min_jobs to lower the threshold, got %v", issues)
+	}
+}
@@ -1,6 +1,7 @@
 package maintainability
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
@@ -42,6 +43,35 @@ func CheckScriptComplexity(config *parser.GitLabConfig) []types.Issue {
 	return issues
 }
 
+// CheckRulesComplexity flags jobs whose rules score above max_score (24 by
+// default, configurable through custom_params) on parser.RuleComplexity. It
+// catches a few rules packed with && and || conditions over many variables,
+// which the rule count of CheckVerboseRules misses.
+func CheckRulesComplexity(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	maxScore := types.IntParam(params, "max_score", 24)
+
+	for _, jobName := range config.ConcreteJobNames() {
+		complexity := parser.ExplainRuleComplexity(config.Jobs[jobName])
+		if complexity.Score <= maxScore {
+			continue
+		}
+		issues = append(issues, types.Issue{
+			Type:     types.IssueTypeMaintainability,
+			Severity: types.SeverityMedium,
+			Path:     "jobs." + jobName + ".rules",
+			Message: fmt.Sprintf("Rules complexity %d exceeds %d: %d conditions × %d variables × %d for changes/exists",
+				complexity.Score, maxScore, complexity.Conditions, max(complexity.Variables, 1), complexity.FileChecks),
+			Suggestion: "Split the conditions into separate rules, move shared ones to workflow:rules or a rules template, or compute a single variable in workflow:rules",
+			JobName:    jobName,
+			Score:      complexity.Score,
+		})
+	}
+
+	return issues
+}
+
 func CheckVerboseRules(config *parser.GitLabConfig) []types.Issue {
 	var issues []types.Issue
 
@@ -87,3 +117,92 @@ func CheckVerboseRules(config *parser.GitLabConfig) []types.Issue {
 
 	return issues
 }
+
+// CheckRulesWithLegacyKeywords flags jobs that end up with rules alongside a
+// top-level when, only or except, set directly or through extends. Once rules
+// are present they alone decide whether and how the job runs, so a top-level
+// `when: manual` no longer gates the job.
+func CheckRulesWithLegacyKeywords(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for _, jobName := range config.ConcreteJobNames() {
+		// Later entries override earlier ones, as with extends
+		chain := append(config.ExtendsChain(jobName), jobName)
+		var hasRules bool
+		var when string
+		var only, except interface{}
+		for _, name := range chain {
+			job := config.Jobs[name]
+			if job == nil {
+				continue
+			}
+			if job.Rules != nil {
+				hasRules = true
+			}
+			if job.When != "" {
+				when = job.When
+			}
+			if job.Only != nil {
+				only = job.Only
+			}
+			if job.Except != nil {
+				except = job.Except
+			}
+		}
+		if !hasRules {
+			continue
+		}
+
+		var legacy []string
+		if when != "" && when != "on_success" {
+			legacy = append(legacy, "when: "+when)
+		}
+		if only != nil {
+			legacy = append(legacy, "only")
+		}
+		if except != nil {
+			legacy = append(legacy, "except")
+		}
+		if len(legacy) == 0 {
+			continue
+		}
+
+		issues = append(issues, types.Issue{
+			Type:     types.IssueTypeMaintainability,
+			Severity: types.SeverityMedium,
+			Path:     "jobs." + jobName + ".rules",
+			Message: fmt.Sprintf("Job '%s' sets %s alongside rules, which take precedence; the top-level keywords are ignored",
+				jobName, strings.Join(legacy, ", ")),
+			Suggestion: "Express the condition in rules instead, e.g. set 'when: manual' on the matching rule",
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
+
+// CheckRedundantParallel flags `parallel: 1`, which runs the single instance
+// the job would run anyway. Templates are included, since the setting is
+// just as redundant in the jobs that extend them.
+func CheckRedundantParallel(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	// Templates are checked too, where the setting is written
+	for _, jobName := range config.GetAllJobNames() {
+		job := config.Jobs[jobName]
+		if job == nil || job.Parallel == nil || len(job.Parallel.Matrix) > 0 || job.Parallel.Count != 1 {
+			continue
+		}
+
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeMaintainability,
+			Severity:   types.SeverityLow,
+			Path:       "jobs." + jobName + ".parallel",
+			Message:    fmt.Sprintf("Job '%s' sets 'parallel: 1', which has no effect", jobName),
+			Suggestion: "Remove parallel, or set the number of copies the job should run",
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
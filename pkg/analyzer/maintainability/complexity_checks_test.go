@@ -184,3 +184,134 @@ func TestCheckVerboseRules(t *testing.T) {
 		}
 	})
 }
+
+func TestCheckRulesComplexity(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			// Three rules, but each nests conditions over several variables
+			"deploy": {
+				Stage: "deploy",
+				Rules: []parser.Rule{
+					{If: `($CI_COMMIT_BRANCH == "main" || $CI_COMMIT_TAG) && $DEPLOY_ENABLED == "true"`, Changes: []string{"deploy/**/*"}},
+					{If: `$CI_PIPELINE_SOURCE == "schedule" && $NIGHTLY == "true"`},
+					{If: `$CI_PIPELINE_SOURCE == "web"`, When: "manual"},
+				},
+			},
+			// More rules than verbose_rules allows, but each is simple
+			"test": {
+				Stage: "test",
+				Rules: []parser.Rule{
+					{If: `$CI_COMMIT_BRANCH == "main"`},
+					{If: `$CI_COMMIT_BRANCH == "develop"`},
+					{If: `$CI_COMMIT_BRANCH =~ /^release/`},
+					{If: `$CI_COMMIT_BRANCH =~ /^hotfix/`},
+				},
+			},
+			".template": {
+				Rules: []parser.Rule{
+					{If: `$A && $B && $C && $D && $E && $F`, Exists: []string{"Dockerfile"}},
+				},
+			},
+		},
+	}
+
+	issues := CheckRulesComplexity(config, nil)
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d: %v", len(issues), issues)
+	}
+	issue := issues[0]
+	// (3 + 2 + 1) conditions × 5 variables × 2 for changes
+	if issue.JobName != "deploy" || issue.Score != 60 {
+		t.Errorf("Expected deploy to score 60, got %s with %d", issue.JobName, issue.Score)
+	}
+	if !strings.Contains(issue.Message, "6 conditions × 5 variables × 2 for changes/exists") {
+		t.Errorf("Expected a breakdown of the score, got %q", issue.Message)
+	}
+
+	if issues := CheckRulesComplexity(config, map[string]interface{}{"max_score": 60}); len(issues) != 0 {
+		t.Errorf("Expected no issues with max_score 60, got %d", len(issues))
+	}
+}
+
+func TestCheckRulesWithLegacyKeywords(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			".manual": {When: "manual"},
+			"deploy": {
+				Script: []string{"./deploy.sh"},
+				When:   "manual",
+				Rules:  []parser.Rule{{If: "$CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH"}},
+			},
+			"release": {
+				Script:  []string{"./release.sh"},
+				Extends: ".manual",
+				Rules:   []parser.Rule{{If: "$CI_COMMIT_TAG"}},
+			},
+			"legacy": {
+				Script: []string{"make"},
+				Only:   []interface{}{"main"},
+				Rules:  []parser.Rule{{When: "always"}},
+			},
+			"gated": {
+				Script: []string{"./gate.sh"},
+				Rules:  []parser.Rule{{If: "$CI_COMMIT_TAG", When: "manual"}},
+			},
+			"plain": {
+				Script: []string{"make"},
+				When:   "manual",
+			},
+			"explicit_default": {
+				Script: []string{"make"},
+				When:   "on_success",
+				Rules:  []parser.Rule{{If: "$CI_COMMIT_TAG"}},
+			},
+		},
+	}
+
+	issues := CheckRulesWithLegacyKeywords(config)
+
+	var flagged []string
+	for _, issue := range issues {
+		flagged = append(flagged, issue.JobName)
+		if issue.Type != types.IssueTypeMaintainability {
+			t.Errorf("Expected maintainability issue, got %s", issue.Type)
+		}
+	}
+	if strings.Join(flagged, ",") != "deploy,legacy,release" {
+		t.Errorf("Expected deploy, legacy and release to be flagged, got %v", flagged)
+	}
+	for _, issue := range issues {
+		if issue.JobName == "release" && !strings.Contains(issue.Message, "when: manual") {
+			t.Errorf("Expected the inherited when to be named, got %q", issue.Message)
+		}
+		if issue.JobName == "legacy" && !strings.Contains(issue.Message, "only") {
+			t.Errorf("Expected only to be named, got %q", issue.Message)
+		}
+	}
+}
+
+func TestCheckRedundantParallel(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			".template": {Parallel: &parser.Parallel{Count: 1}},
+			"single":    {Script: []string{"make test"}, Parallel: &parser.Parallel{Count: 1}},
+			"split":     {Script: []string{"make test"}, Parallel: &parser.Parallel{Count: 4}},
+			"matrix": {
+				Script:   []string{"make test"},
+				Parallel: &parser.Parallel{Matrix: []map[string]interface{}{{"GO": "1.22"}}},
+			},
+			"plain": {Script: []string{"make test"}},
+		},
+	}
+
+	var flagged []string
+	for _, issue := range CheckRedundantParallel(config) {
+		flagged = append(flagged, issue.JobName)
+		if issue.Path != "jobs."+issue.JobName+".parallel" {
+			t.Errorf("Unexpected path %s", issue.Path)
+		}
+	}
+	if strings.Join(flagged, ",") != ".template,single" {
+		t.Errorf("Expected .template and single to be flagged, got %v", flagged)
+	}
+}
@@ -0,0 +1,130 @@
+package maintainability
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// deprecatedCommand is a script invocation that should be replaced
+type deprecatedCommand struct {
+	pattern     string
+	re          *regexp.Regexp
+	replacement string
+}
+
+// defaultDeprecatedCommands are checked unless custom_params sets
+// "use_default_rules" to false. Teams add their own through
+// "deprecated_commands", a map of regular expression to replacement; an entry
+// with the same expression as a default replaces it.
+var defaultDeprecatedCommands = []deprecatedCommand{
+	newDeprecatedCommand(`(^|[;&|\s])(echo\s+["']?)?::set-env\b`, "write KEY=value lines to a dotenv file and publish it with artifacts:reports:dotenv"),
+	newDeprecatedCommand(`(^|[;&|(]|\bsudo)\s*apt-key\s+(add|adv)\b`, "store the key under /etc/apt/keyrings and reference it with signed-by in the sources list"),
+	newDeprecatedCommand(`(^|[;&|(]|\bsudo)\s*docker-compose(\s|$)`, "use the Compose v2 plugin: docker compose"),
+	newDeprecatedCommand(`\b(python2(\.\d+)?|pip2)\b`, "use python3 and pip3; Python 2 is end of life"),
+}
+
+func newDeprecatedCommand(pattern, replacement string) deprecatedCommand {
+	return deprecatedCommand{pattern: pattern, re: regexp.MustCompile(pattern), replacement: replacement}
+}
+
+// CheckScriptUsesDeprecatedCommands flags script lines that invoke deprecated
+// tooling, reporting each rule once per job with the first matching line and
+// the suggested replacement
+func CheckScriptUsesDeprecatedCommands(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	rules := deprecatedCommandRules(params)
+	if len(rules) == 0 {
+		return issues
+	}
+
+	checkJob := func(job *parser.JobConfig, path, jobName string) {
+		sections := []struct {
+			name  string
+			lines []string
+		}{
+			{"before_script", job.BeforeScript},
+			{"script", job.Script},
+			{"after_script", job.AfterScript},
+		}
+
+		for _, rule := range rules {
+		sectionLoop:
+			for _, section := range sections {
+				for _, entry := range section.lines {
+					// Multi-line entries are reported by the offending line
+					for _, line := range strings.Split(entry, "\n") {
+						if !rule.re.MatchString(line) {
+							continue
+						}
+						issues = append(issues, types.Issue{
+							Type:       types.IssueTypeMaintainability,
+							Severity:   types.SeverityLow,
+							Path:       path + "." + section.name,
+							Message:    fmt.Sprintf("Deprecated command in script: %s", strings.TrimSpace(line)),
+							Suggestion: "Replace it: " + rule.replacement,
+							JobName:    jobName,
+						})
+						break sectionLoop
+					}
+				}
+			}
+		}
+	}
+
+	if config.Default != nil {
+		checkJob(config.Default, "default", "")
+	}
+
+	jobNames := make([]string, 0, len(config.Jobs))
+	for jobName := range config.Jobs {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	for _, jobName := range jobNames {
+		if job := config.Jobs[jobName]; job != nil {
+			checkJob(job, "jobs."+jobName, jobName)
+		}
+	}
+
+	return issues
+}
+
+// deprecatedCommandRules combines the default ruleset with the rules from
+// custom_params. Custom rules whose expression doesn't compile are skipped.
+func deprecatedCommandRules(params map[string]interface{}) []deprecatedCommand {
+	custom := make(map[string]string)
+	if entries, ok := params["deprecated_commands"].(map[string]interface{}); ok {
+		for pattern, replacement := range entries {
+			custom[pattern] = fmt.Sprint(replacement)
+		}
+	}
+
+	var rules []deprecatedCommand
+	if useDefaults, ok := params["use_default_rules"].(bool); !ok || useDefaults {
+		for _, rule := range defaultDeprecatedCommands {
+			if _, overridden := custom[rule.pattern]; !overridden {
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	patterns := make([]string, 0, len(custom))
+	for pattern := range custom {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		if re, err := regexp.Compile(pattern); err == nil {
+			rules = append(rules, deprecatedCommand{pattern: pattern, re: re, replacement: custom[pattern]})
+		}
+	}
+	return rules
+}
@@ -0,0 +1,89 @@
+package maintainability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckScriptUsesDeprecatedCommands(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Default: &parser.JobConfig{
+			BeforeScript: []string{"apt-key add /tmp/repo.gpg && apt-get update"},
+		},
+		Jobs: map[string]*parser.JobConfig{
+			"integration": {
+				Script: []string{
+					"docker-compose up -d",
+					"docker compose ps",
+					"docker-compose down",
+				},
+			},
+			"legacy": {
+				Script: []string{"pip install -r requirements.txt\npython2 manage.py test"},
+			},
+			"modern": {
+				BeforeScript: []string{"apk add --no-cache docker-compose"},
+				Script:       []string{"docker compose up -d", "python3 -m pytest", "echo docker-compose.yml"},
+			},
+		},
+	}
+
+	issues := CheckScriptUsesDeprecatedCommands(config, nil)
+
+	if len(issues) != 3 {
+		t.Fatalf("Expected 3 issues, got %d: %+v", len(issues), issues)
+	}
+	expected := map[string]string{
+		"default.before_script":   "keyrings",
+		"jobs.integration.script": "docker compose",
+		"jobs.legacy.script":      "python3",
+	}
+	for _, issue := range issues {
+		if issue.Severity != types.SeverityLow || issue.Type != types.IssueTypeMaintainability {
+			t.Errorf("Expected a low maintainability issue, got %+v", issue)
+		}
+		want, exists := expected[issue.Path]
+		if !exists {
+			t.Errorf("Unexpected issue at %s: %s", issue.Path, issue.Message)
+			continue
+		}
+		if !strings.Contains(issue.Suggestion, want) {
+			t.Errorf("Expected suggestion for %s to mention %q, got %q", issue.Path, want, issue.Suggestion)
+		}
+	}
+	for _, issue := range issues {
+		if issue.Path == "jobs.legacy.script" && !strings.HasSuffix(issue.Message, "python2 manage.py test") {
+			t.Errorf("Expected the offending line of a multi-line entry, got %q", issue.Message)
+		}
+	}
+}
+
+func TestCheckScriptUsesDeprecatedCommands_CustomRules(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"build": {
+				Script: []string{"docker-compose build", "npm install --no-save"},
+			},
+		},
+	}
+
+	params := map[string]interface{}{
+		"deprecated_commands": map[string]interface{}{
+			`\bnpm install\b`: "use npm ci",
+			`(`:               "invalid expressions are skipped",
+		},
+	}
+	issues := CheckScriptUsesDeprecatedCommands(config, params)
+	if len(issues) != 2 {
+		t.Fatalf("Expected the default and custom rule to match, got %+v", issues)
+	}
+
+	params["use_default_rules"] = false
+	issues = CheckScriptUsesDeprecatedCommands(config, params)
+	if len(issues) != 1 || issues[0].Suggestion != "Replace it: use npm ci" {
+		t.Errorf("Expected only the custom rule to match, got %+v", issues)
+	}
+}
@@ -0,0 +1,338 @@
+package maintainability
+
+import "github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+
+// checkDocs documents each maintainability check for `gitlab-smith explain`
+var checkDocs = map[string]types.CheckDoc{
+	"job_naming": {
+		Rationale: "Job names show up in the pipeline graph, in needs and in the API. Names with spaces have to be quoted wherever they are referenced, and very long names are truncated in the UI.",
+		Example: types.CheckExample{
+			Before: `"run unit tests":
+  script: make test`,
+			After: `unit_tests:
+  script: make test`,
+		},
+	},
+	"script_complexity": {
+		Rationale: "Long inline scripts are hard to review, can't be run locally and aren't covered by shell linters. Hardcoded URLs have to be found and changed in every script when an endpoint moves.",
+		Example: types.CheckExample{
+			Before: `deploy:
+  script:
+    - curl -sf https://deploy.example.com/hook
+    - ...  # a dozen more lines`,
+			After: `deploy:
+  variables:
+    DEPLOY_HOOK_URL: https://deploy.example.com/hook
+  script:
+    - ./ci/deploy.sh`,
+		},
+		Related: []string{"deprecated_commands", "script_shell_injection"},
+	},
+	"deprecated_commands": {
+		Rationale: "Deprecated tools such as apt-key, docker-compose v1 and Python 2 stop receiving fixes and disappear from base images. Jobs using them break without warning when an image is updated.",
+		Example: types.CheckExample{
+			Before: `test:
+  script:
+    - docker-compose up -d`,
+			After: `test:
+  script:
+    - docker compose up -d`,
+		},
+		Related: []string{"script_complexity"},
+	},
+	"verbose_rules": {
+		Rationale: "Many rules on one job, or rules whose when values contradict each other, make it hard to tell when the job runs. Most can be collapsed into fewer conditions or moved to workflow:rules.",
+		Example: types.CheckExample{
+			Before: `test:
+  rules:
+    - if: $CI_COMMIT_BRANCH == "main"
+    - if: $CI_COMMIT_BRANCH == "develop"
+    - if: $CI_COMMIT_BRANCH =~ /^release/
+    - if: $CI_COMMIT_TAG`,
+			After: `test:
+  rules:
+    - if: $CI_COMMIT_BRANCH =~ /^(main|develop|release.*)$/ || $CI_COMMIT_TAG`,
+		},
+		Related: []string{"rules_with_legacy_keywords", "workflow_optimization", "rules_complexity"},
+	},
+	"rules_complexity": {
+		Rationale: "A few rules can still be hard to follow when each combines several && and || conditions over many variables, and changes or exists make the outcome depend on the repository too. The score multiplies the conditions, the distinct variables and the file checks, so conditions shared by many jobs are better decided once in workflow:rules.",
+		Example: types.CheckExample{
+			Before: `deploy:
+  rules:
+    - if: ($CI_COMMIT_BRANCH == "main" || $CI_COMMIT_TAG) && $CI_PIPELINE_SOURCE != "merge_request_event" && $DEPLOY_ENABLED == "true"
+      changes: [deploy/**/*]
+    - if: $NIGHTLY_DEPLOY == "true"`,
+			After: `workflow:
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+      when: never
+    - if: $CI_COMMIT_BRANCH == "main" || $CI_COMMIT_TAG
+      variables:
+        RELEASE: "true"
+    - when: always
+
+deploy:
+  rules:
+    - if: $RELEASE && $DEPLOY_ENABLED == "true"
+      changes: [deploy/**/*]
+    - if: $NIGHTLY_DEPLOY == "true"`,
+		},
+		Related: []string{"verbose_rules", "workflow_optimization"},
+	},
+	"rules_with_legacy_keywords": {
+		Rationale: "When a job has rules, GitLab ignores its top-level when, only and except. The configuration looks like it restricts the job, but the restriction silently has no effect.",
+		Example: types.CheckExample{
+			Before: `deploy:
+  when: manual
+  rules:
+    - if: $CI_COMMIT_BRANCH == "main"`,
+			After: `deploy:
+  rules:
+    - if: $CI_COMMIT_BRANCH == "main"
+      when: manual`,
+		},
+		Related: []string{"verbose_rules", "unknown_job_keywords"},
+	},
+	"redundant_parallel": {
+		Rationale: "parallel: 1 runs the one instance the job runs anyway. It suggests the job is split across runners when it isn't, and is usually a leftover from lowering the count.",
+		Example: types.CheckExample{
+			Before: `test:
+  parallel: 1
+  script: make test`,
+			After: `test:
+  script: make test`,
+		},
+		Related: []string{"parallel_without_matrix"},
+	},
+	"duplicated_code": {
+		Rationale: "Jobs that repeat the same script have to be changed together. Sooner or later one copy is missed and the jobs drift apart; a template or default keeps a single definition.",
+		Example: types.CheckExample{
+			Before: `test_unit:
+  script: [npm ci, npm test]
+test_lint:
+  script: [npm ci, npm run lint]`,
+			After: `.node:
+  before_script: [npm ci]
+
+test_unit:
+  extends: .node
+  script: [npm test]
+test_lint:
+  extends: .node
+  script: [npm run lint]`,
+		},
+		Related: []string{"duplicated_before_scripts", "common_script_prefix", "duplicated_setup"},
+	},
+	"duplicated_before_scripts": {
+		Rationale: "Identical or nearly identical before_script blocks across jobs are setup that belongs in one place. Moving them to default or a template means a new setup step is added once.",
+		Example: types.CheckExample{
+			Before: `build:
+  before_script: [apt-get update, apt-get install -y make]
+test:
+  before_script: [apt-get update, apt-get install -y make]`,
+			After: `default:
+  before_script: [apt-get update, apt-get install -y make]`,
+		},
+		Related: []string{"common_script_prefix", "duplicated_code"},
+	},
+	"common_script_prefix": {
+		Rationale: "When many jobs start their before_script or after_script with the same commands, those commands are shared setup in disguise. Keeping them in a template, referenced with !reference, leaves each job with only what is specific to it.",
+		Example: types.CheckExample{
+			Before: `build:
+  before_script: [source ci/env.sh, make deps]
+test:
+  before_script: [source ci/env.sh, make test-deps]`,
+			After: `.env:
+  before_script: [source ci/env.sh]
+
+build:
+  before_script: [!reference [.env, before_script], make deps]
+test:
+  before_script: [!reference [.env, before_script], make test-deps]`,
+		},
+		Related: []string{"duplicated_before_scripts", "duplicated_code"},
+	},
+	"duplicated_cache_config": {
+		Rationale: "The same cache block copied into several jobs has to be kept identical by hand. A change to the key or paths in one copy splits the cache between jobs.",
+		Example: types.CheckExample{
+			Before: `build:
+  cache: {key: deps, paths: [node_modules/]}
+test:
+  cache: {key: deps, paths: [node_modules/]}`,
+			After: `default:
+  cache: {key: deps, paths: [node_modules/]}`,
+		},
+		Related: []string{"cache_key_conflicts", "duplicated_image_config"},
+	},
+	"duplicated_image_config": {
+		Rationale: "Repeating the same image in many jobs means an upgrade touches every one of them, and a job that is missed keeps running the old version.",
+		Example: types.CheckExample{
+			Before: `build:
+  image: golang:1.22
+test:
+  image: golang:1.22`,
+			After: `default:
+  image: golang:1.22`,
+		},
+		Related: []string{"image_tags", "duplicated_cache_config"},
+	},
+	"duplicated_setup": {
+		Rationale: "Jobs repeating the same setup commands, such as installing packages, pay for them in every job and every copy has to be maintained. A template or default before_script keeps them in one place.",
+		Example: types.CheckExample{
+			Before: `build:
+  script: [pip install -r requirements.txt, make build]
+test:
+  script: [pip install -r requirements.txt, make test]`,
+			After: `.python:
+  before_script: [pip install -r requirements.txt]
+
+build:
+  extends: .python
+  script: [make build]
+test:
+  extends: .python
+  script: [make test]`,
+		},
+		Related: []string{"duplicated_code", "duplicated_before_scripts"},
+	},
+	"duplicated_changes_rules": {
+		Rationale: "Several jobs listing the same rules:changes globs all run whenever a matching file changes, and the globs have to be kept in sync by hand. A shared rules template states the condition once; if every job depends on it, workflow:rules can decide for the whole pipeline.",
+		Example: types.CheckExample{
+			Before: `build:
+  rules:
+    - changes: [src/**/*]
+test:
+  rules:
+    - changes: [src/**/*]
+lint:
+  rules:
+    - changes: [src/**/*]`,
+			After: `.src-changes:
+  rules:
+    - changes: [src/**/*]
+
+build:
+  extends: .src-changes
+test:
+  extends: .src-changes
+lint:
+  extends: .src-changes`,
+		},
+		Related: []string{"verbose_rules", "workflow_optimization"},
+	},
+	"stages_definition": {
+		Rationale: "Without a stages list GitLab falls back to build, test and deploy. Declaring the stages documents the pipeline's order and catches jobs assigned to a stage that doesn't exist.",
+		Example: types.CheckExample{
+			Before: `build:
+  stage: build
+test:
+  stage: test`,
+			After: `stages: [build, test]
+
+build:
+  stage: build
+test:
+  stage: test`,
+		},
+		Related: []string{"missing_stages", "unused_stages", "duplicate_stages"},
+	},
+	"include_optimization": {
+		Rationale: "Configuration split over many small includes is hard to follow, and each include is another file GitLab has to fetch and merge. Grouping related includes keeps the pipeline readable.",
+		Example: types.CheckExample{
+			Before: `include:
+  - local: ci/build.yml
+  - local: ci/build-docker.yml
+  - local: ci/build-docs.yml`,
+			After: `include:
+  - local: ci/build.yml`,
+		},
+		Related: []string{"included_job_overrides", "unknown_extends"},
+	},
+	"included_job_overrides": {
+		Rationale: "When a job is defined both in an include and locally, GitLab merges the definitions and the last one wins key by key. The result is easy to misread, and a change to the included file can be silently overridden.",
+		Example: types.CheckExample{
+			Before: `include:
+  - local: ci/test.yml  # defines unit_tests
+
+unit_tests:
+  script: make test-fast`,
+			After: `include:
+  - local: ci/test.yml
+
+unit_tests_fast:
+  extends: unit_tests
+  script: make test-fast`,
+		},
+		Related: []string{"include_optimization"},
+	},
+	"duplicate_stages": {
+		Rationale: "A stage listed twice is ambiguous about where its jobs run in the order, and is usually the leftover of a merge or a copy-paste.",
+		Example: types.CheckExample{
+			Before: `stages: [build, test, build, deploy]`,
+			After:  `stages: [build, test, deploy]`,
+		},
+		Related: []string{"unused_stages", "stages_definition"},
+	},
+	"unused_stages": {
+		Rationale: "Stages that no job uses clutter the pipeline definition and suggest jobs that were removed or renamed without updating the list.",
+		Example: types.CheckExample{
+			Before: `stages: [build, test, package, deploy]
+# no job uses package`,
+			After: `stages: [build, test, deploy]`,
+		},
+		Related: []string{"duplicate_stages", "missing_stages"},
+	},
+	"workflow_skipped_jobs": {
+		Rationale: "A job whose rules only match pipelines that workflow:rules never creates can't run. It is dead configuration, or a job that someone expects to run and doesn't.",
+		Example: types.CheckExample{
+			Before: `workflow:
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+
+nightly:
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "schedule"`,
+			After: `workflow:
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+    - if: $CI_PIPELINE_SOURCE == "schedule"
+
+nightly:
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "schedule"`,
+		},
+		Related: []string{"workflow_optimization", "verbose_rules"},
+	},
+	"shadowed_rules": {
+		Rationale: "GitLab applies the first rule that matches and ignores the rest. A broad rule placed before a narrower one, such as a bare when: on_success or a branch check before a branch-and-source check, makes the later rule dead, so its when, allow_failure or variables never take effect.",
+		Example: types.CheckExample{
+			Before: `deploy:
+  rules:
+    - if: $CI_COMMIT_BRANCH == "main"
+    - if: $CI_COMMIT_BRANCH == "main" && $CI_PIPELINE_SOURCE == "schedule"
+      when: never`,
+			After: `deploy:
+  rules:
+    - if: $CI_COMMIT_BRANCH == "main" && $CI_PIPELINE_SOURCE == "schedule"
+      when: never
+    - if: $CI_COMMIT_BRANCH == "main"`,
+		},
+		Related: []string{"verbose_rules", "workflow_skipped_jobs"},
+	},
+	"manual_job_blocking": {
+		Rationale: "GitLab's default for allow_failure depends on where when: manual is written. A job-level manual job is optional and the pipeline passes without it, while when: manual in rules blocks the pipeline until someone runs the job. Setting allow_failure explicitly makes the choice between an optional job and a required gate visible to reviewers.",
+		Example: types.CheckExample{
+			Before: `deploy:
+  rules:
+    - if: $CI_COMMIT_BRANCH == "main"
+      when: manual`,
+			After: `deploy:
+  rules:
+    - if: $CI_COMMIT_BRANCH == "main"
+      when: manual
+      allow_failure: true`,
+		},
+		Related: []string{"rules_with_legacy_keywords", "allow_failure_critical"},
+	},
+}
@@ -2,6 +2,7 @@ package maintainability
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
@@ -9,19 +10,23 @@ import (
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
 )
 
+// CheckDuplicatedCode flags jobs that do the same thing: the same script,
+// image, stage, rules and variables, compared through their fingerprints
 func CheckDuplicatedCode(config *parser.GitLabConfig) []types.Issue {
 	var issues []types.Issue
-	scriptSets := make(map[string][]string)
+	jobSets := make(map[string][]string)
 
 	for jobName, job := range config.Jobs {
-		scriptKey := strings.Join(job.Script, "\n")
-		if scriptKey != "" {
-			scriptSets[scriptKey] = append(scriptSets[scriptKey], jobName)
+		if job == nil || len(job.Script) == 0 || config.IsPagesJob(jobName) {
+			continue
 		}
+		fingerprint := config.JobFingerprint(jobName)
+		jobSets[fingerprint] = append(jobSets[fingerprint], jobName)
 	}
 
-	for _, jobNames := range scriptSets {
+	for _, jobNames := range jobSets {
 		if len(jobNames) > 1 {
+			sort.Strings(jobNames)
 			issues = append(issues, types.Issue{
 				Type:       types.IssueTypeMaintainability,
 				Severity:   types.SeverityMedium,
@@ -35,16 +40,25 @@ func CheckDuplicatedCode(config *parser.GitLabConfig) []types.Issue {
 	return issues
 }
 
+// duplicationCandidates returns the concrete jobs compared for duplication.
+// Pages jobs are left out: their name or pages keyword is what makes GitLab
+// deploy them, so one mirroring another job can't be folded into it.
+func duplicationCandidates(config *parser.GitLabConfig) map[string]*parser.JobConfig {
+	jobs := config.ConcreteJobs()
+	for jobName := range jobs {
+		if config.IsPagesJob(jobName) {
+			delete(jobs, jobName)
+		}
+	}
+	return jobs
+}
+
 func CheckDuplicatedBeforeScripts(config *parser.GitLabConfig) []types.Issue {
 	var issues []types.Issue
 	beforeScriptSets := make(map[string][]string)
 	beforeScriptJobs := make(map[string][]string) // Map job name to before_script lines
 
-	for jobName, job := range config.Jobs {
-		// Skip template jobs (starting with .) from duplication analysis
-		if strings.HasPrefix(jobName, ".") {
-			continue
-		}
+	for jobName, job := range duplicationCandidates(config) {
 		if len(job.BeforeScript) > 0 {
 			scriptKey := strings.Join(job.BeforeScript, "\n")
 			beforeScriptSets[scriptKey] = append(beforeScriptSets[scriptKey], jobName)
@@ -55,6 +69,7 @@ func CheckDuplicatedBeforeScripts(config *parser.GitLabConfig) []types.Issue {
 	// Report exact duplicates
 	for _, jobNames := range beforeScriptSets {
 		if len(jobNames) > 1 {
+			sort.Strings(jobNames)
 			issues = append(issues, types.Issue{
 				Type:       types.IssueTypeMaintainability,
 				Severity:   types.SeverityHigh,
@@ -66,18 +81,24 @@ func CheckDuplicatedBeforeScripts(config *parser.GitLabConfig) []types.Issue {
 	}
 
 	// Check for similar before_script blocks with high overlap
+	// Jobs are visited in name order, so the same groups form on every run
+	jobOrder := make([]string, 0, len(beforeScriptJobs))
+	for jobName := range beforeScriptJobs {
+		jobOrder = append(jobOrder, jobName)
+	}
+	sort.Strings(jobOrder)
 	processed := make(map[string]bool)
-	for job1, script1 := range beforeScriptJobs {
+	for _, job1 := range jobOrder {
 		if processed[job1] {
 			continue
 		}
 		similarJobs := []string{job1}
-		for job2, script2 := range beforeScriptJobs {
+		for _, job2 := range jobOrder {
 			if job1 == job2 || processed[job2] {
 				continue
 			}
 			// Calculate overlap between scripts
-			overlap := calculateScriptOverlap(script1, script2)
+			overlap := calculateScriptOverlap(beforeScriptJobs[job1], beforeScriptJobs[job2])
 			if overlap > 0.7 { // More than 70% overlap
 				similarJobs = append(similarJobs, job2)
 				processed[job2] = true
@@ -128,18 +149,14 @@ func CheckDuplicatedCacheConfig(config *parser.GitLabConfig) []types.Issue {
 	cacheSets := make(map[string][]string)
 	expander := varexpand.New(config)
 
-	for jobName, job := range config.Jobs {
-		// Skip template jobs (starting with .) from duplication analysis
-		if strings.HasPrefix(jobName, ".") {
-			continue
-		}
+	for jobName, job := range duplicationCandidates(config) {
 		if job.Cache != nil {
 			// Expand variables in cache key and paths
 			var expandedKey string
-			if keyStr, ok := job.Cache.Key.(string); ok {
-				expandedKey = expander.ExpandString(keyStr, job.Variables)
+			if object := job.Cache.KeyObject(); object != nil {
+				expandedKey = fmt.Sprintf("%v", *object)
 			} else {
-				expandedKey = fmt.Sprintf("%v", job.Cache.Key)
+				expandedKey = expander.ExpandString(job.Cache.KeyString(), job.Variables)
 			}
 
 			expandedPaths := make([]string, len(job.Cache.Paths))
@@ -168,6 +185,7 @@ func CheckDuplicatedCacheConfig(config *parser.GitLabConfig) []types.Issue {
 	// Report duplicate cache configurations
 	for _, jobNames := range cacheSets {
 		if len(jobNames) > 1 {
+			sort.Strings(jobNames)
 			issues = append(issues, types.Issue{
 				Type:       types.IssueTypeMaintainability,
 				Severity:   types.SeverityMedium,
@@ -186,14 +204,10 @@ func CheckDuplicatedImageConfig(config *parser.GitLabConfig) []types.Issue {
 	imageSets := make(map[string][]string)
 	expander := varexpand.New(config)
 
-	for jobName, job := range config.Jobs {
-		// Skip template jobs (starting with .) from duplication analysis
-		if strings.HasPrefix(jobName, ".") {
-			continue
-		}
-		if job.Image != "" {
+	for jobName, job := range duplicationCandidates(config) {
+		if imageName := job.Image.GetName(); imageName != "" {
 			// Expand variables in image names for accurate duplication detection
-			expandedImage := expander.ExpandString(job.Image, job.Variables)
+			expandedImage := expander.ExpandJobString(imageName, job)
 
 			// Only process fully resolved images to avoid false positives
 			if !expander.HasUnresolvedVariables(expandedImage) {
@@ -205,6 +219,7 @@ func CheckDuplicatedImageConfig(config *parser.GitLabConfig) []types.Issue {
 	// Report duplicate image configurations
 	for image, jobNames := range imageSets {
 		if len(jobNames) > 2 {
+			sort.Strings(jobNames)
 			issues = append(issues, types.Issue{
 				Type:       types.IssueTypeMaintainability,
 				Severity:   types.SeverityLow,
@@ -225,9 +240,10 @@ func CheckDuplicatedSetup(config *parser.GitLabConfig) []types.Issue {
 	// Also check for overall duplication patterns across before_script and script
 	overallSetupPatterns := make(map[string][]string)
 
-	for jobName, job := range config.Jobs {
-		// Skip template jobs (starting with .) from duplication analysis
-		if strings.HasPrefix(jobName, ".") {
+	for jobName, job := range duplicationCandidates(config) {
+		// Matrix jobs set up a toolchain per combination, which no shared
+		// job or default can provide.
+		if len(job.ExpandMatrix(jobName)) > 0 {
 			continue
 		}
 
@@ -267,6 +283,7 @@ func CheckDuplicatedSetup(config *parser.GitLabConfig) []types.Issue {
 	// Report duplicate setup patterns
 	for pattern, jobNames := range setupPatterns {
 		if len(jobNames) > 1 {
+			sort.Strings(jobNames)
 			issues = append(issues, types.Issue{
 				Type:       types.IssueTypeMaintainability,
 				Severity:   types.SeverityMedium,
@@ -280,6 +297,7 @@ func CheckDuplicatedSetup(config *parser.GitLabConfig) []types.Issue {
 	// Report jobs with similar overall setup configuration
 	for _, jobNames := range overallSetupPatterns {
 		if len(jobNames) > 1 {
+			sort.Strings(jobNames)
 			issues = append(issues, types.Issue{
 				Type:       types.IssueTypeMaintainability,
 				Severity:   types.SeverityMedium,
@@ -379,3 +397,111 @@ func normalizeSetupCommand(cmd string) string {
 
 	return ""
 }
+
+// CheckBeforeAfterScriptInDefault looks across all jobs for before_script
+// and after_script blocks that start with the same commands, which the
+// pairwise duplication checks miss when the rest of the blocks differ. When
+// at least min_jobs jobs share at least min_lines leading commands (3 and 2
+// by default, configurable through custom_params), the shared lines are
+// reported as a candidate for default or a shared template.
+func CheckBeforeAfterScriptInDefault(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	minJobs := types.IntParam(params, "min_jobs", 3)
+	minLines := types.IntParam(params, "min_lines", 2)
+
+	sections := []struct {
+		name  string
+		lines func(job *parser.JobConfig) []string
+	}{
+		{"before_script", func(job *parser.JobConfig) []string { return job.BeforeScript }},
+		{"after_script", func(job *parser.JobConfig) []string { return job.AfterScript }},
+	}
+
+	for _, section := range sections {
+		scripts := make(map[string][]string)
+		for jobName, job := range duplicationCandidates(config) {
+			if !job.InheritsDefault(section.name) {
+				continue
+			}
+			if lines := section.lines(job); len(lines) >= minLines {
+				scripts[jobName] = lines
+			}
+		}
+
+		jobNames, prefix := commonScriptPrefix(scripts, minLines, minJobs)
+		if jobNames == nil {
+			continue
+		}
+		// Identical before_script blocks are already reported by
+		// CheckDuplicatedBeforeScripts
+		if section.name == "before_script" && allEqualLength(scripts, jobNames, len(prefix)) {
+			continue
+		}
+
+		issues = append(issues, types.Issue{
+			Type:     types.IssueTypeMaintainability,
+			Severity: types.SeverityMedium,
+			Path:     "default." + section.name,
+			Message: fmt.Sprintf("%d jobs (%s) start their %s with the same %d commands: %s",
+				len(jobNames), strings.Join(jobNames, ", "), section.name, len(prefix), strings.Join(prefix, "; ")),
+			Suggestion: fmt.Sprintf("Move these commands to a template and pull them in with !reference [.template, %s], or to default.%s if the jobs' remaining lines can move into their script", section.name, section.name),
+		})
+	}
+
+	return issues
+}
+
+// commonScriptPrefix finds the leading commands, at least minLines long,
+// shared by the most jobs, and returns those jobs along with the longest
+// prefix they all share. It returns nil when fewer than minJobs jobs share
+// such a prefix.
+func commonScriptPrefix(scripts map[string][]string, minLines, minJobs int) ([]string, []string) {
+	jobNames := make([]string, 0, len(scripts))
+	for jobName := range scripts {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	// Jobs sharing each prefix of minLines commands
+	groups := make(map[string][]string)
+	var keys []string
+	for _, jobName := range jobNames {
+		key := strings.Join(scripts[jobName][:minLines], "\n")
+		if groups[key] == nil {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], jobName)
+	}
+
+	var best []string
+	for _, key := range keys {
+		if len(groups[key]) > len(best) {
+			best = groups[key]
+		}
+	}
+	if len(best) < minJobs {
+		return nil, nil
+	}
+
+	prefix := scripts[best[0]]
+	for _, jobName := range best[1:] {
+		lines := scripts[jobName]
+		n := 0
+		for n < len(prefix) && n < len(lines) && prefix[n] == lines[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return best, prefix
+}
+
+// allEqualLength reports whether each of the jobs' scripts has exactly n lines
+func allEqualLength(scripts map[string][]string, jobNames []string, n int) bool {
+	for _, jobName := range jobNames {
+		if len(scripts[jobName]) != n {
+			return false
+		}
+	}
+	return true
+}
@@ -42,6 +42,44 @@ func TestCheckDuplicatedCode(t *testing.T) {
 			t.Errorf("Expected message to contain both job names, got '%s'", issue.Message)
 		}
 	})
+
+	t.Run("Same script with different behavior", func(t *testing.T) {
+		config := &parser.GitLabConfig{
+			Jobs: map[string]*parser.JobConfig{
+				"test_node16": {
+					Image:  &parser.Image{Name: "node:16"},
+					Script: []string{"npm test"},
+				},
+				"test_node18": {
+					Image:  &parser.Image{Name: "node:18"},
+					Script: []string{"npm test"},
+				},
+			},
+		}
+
+		if issues := CheckDuplicatedCode(config); len(issues) != 0 {
+			t.Errorf("Expected jobs with different images not to be duplicates, got %+v", issues)
+		}
+	})
+
+	t.Run("Pages job mirroring a build job", func(t *testing.T) {
+		config := &parser.GitLabConfig{
+			Jobs: map[string]*parser.JobConfig{
+				"docs": {
+					Stage:  "build",
+					Script: []string{"mkdocs build --site-dir public"},
+				},
+				"pages": {
+					Stage:  "build",
+					Script: []string{"mkdocs build --site-dir public"},
+				},
+			},
+		}
+
+		if issues := CheckDuplicatedCode(config); len(issues) != 0 {
+			t.Errorf("Expected the pages job to be left out of duplication checks, got %+v", issues)
+		}
+	})
 }
 
 func TestCheckDuplicatedBeforeScripts(t *testing.T) {
@@ -117,21 +155,21 @@ func TestCheckDuplicatedCacheConfig(t *testing.T) {
 				"build": {
 					Stage: "build",
 					Cache: &parser.Cache{
-						Key:   "$CI_COMMIT_REF_SLUG",
+						Key:   &parser.CacheKey{Value: "$CI_COMMIT_REF_SLUG"},
 						Paths: []string{"node_modules/", ".npm/"},
 					},
 				},
 				"test": {
 					Stage: "test",
 					Cache: &parser.Cache{
-						Key:   "$CI_COMMIT_REF_SLUG",
+						Key:   &parser.CacheKey{Value: "$CI_COMMIT_REF_SLUG"},
 						Paths: []string{"node_modules/", ".npm/"},
 					},
 				},
 				"deploy": {
 					Stage: "deploy",
 					Cache: &parser.Cache{
-						Key:   "$CI_COMMIT_REF_SLUG",
+						Key:   &parser.CacheKey{Value: "$CI_COMMIT_REF_SLUG"},
 						Paths: []string{"node_modules/", ".npm/"},
 					},
 				},
@@ -177,15 +215,15 @@ func TestCheckDuplicatedImageConfig(t *testing.T) {
 			Jobs: map[string]*parser.JobConfig{
 				"build": {
 					Stage: "build",
-					Image: "node:16",
+					Image: &parser.Image{Name: "node:16"},
 				},
 				"test": {
 					Stage: "test",
-					Image: "node:16",
+					Image: &parser.Image{Name: "node:16"},
 				},
 				"lint": {
 					Stage: "test",
-					Image: "node:16",
+					Image: &parser.Image{Name: "node:16"},
 				},
 			},
 		}
@@ -207,9 +245,9 @@ func TestCheckDuplicatedImageConfig(t *testing.T) {
 				"PYTHON_IMAGE": "python:3.11",
 			},
 			Jobs: map[string]*parser.JobConfig{
-				"test1": {Image: "${PYTHON_IMAGE}"},
-				"test2": {Image: "${PYTHON_IMAGE}"},
-				"test3": {Image: "${PYTHON_IMAGE}"},
+				"test1": {Image: &parser.Image{Name: "${PYTHON_IMAGE}"}},
+				"test2": {Image: &parser.Image{Name: "${PYTHON_IMAGE}"}},
+				"test3": {Image: &parser.Image{Name: "${PYTHON_IMAGE}"}},
 			},
 		}
 
@@ -230,9 +268,9 @@ func TestCheckDuplicatedImageConfig(t *testing.T) {
 				"NODE_IMAGE": "node:18",
 			},
 			Jobs: map[string]*parser.JobConfig{
-				"job1": {Image: "${NODE_IMAGE}"},
-				"job2": {Image: "node:18"}, // Same as expanded variable
-				"job3": {Image: "${NODE_IMAGE}"},
+				"job1": {Image: &parser.Image{Name: "${NODE_IMAGE}"}},
+				"job2": {Image: &parser.Image{Name: "node:18"}}, // Same as expanded variable
+				"job3": {Image: &parser.Image{Name: "${NODE_IMAGE}"}},
 			},
 		}
 
@@ -287,6 +325,26 @@ func TestCheckDuplicatedSetup(t *testing.T) {
 			t.Error("Expected duplicate setup configuration issue")
 		}
 	})
+
+	t.Run("Matrix jobs set up their own toolchain", func(t *testing.T) {
+		config := &parser.GitLabConfig{
+			Jobs: map[string]*parser.JobConfig{
+				"deps": {
+					Stage:  "prepare",
+					Script: []string{"pip install -r requirements.txt"},
+				},
+				"test": {
+					Stage:    "test",
+					Parallel: &parser.Parallel{Matrix: []map[string]interface{}{{"PYTHON": []interface{}{"3.11", "3.12"}}}},
+					Script:   []string{"pip install -r requirements.txt", "pytest"},
+				},
+			},
+		}
+
+		if issues := CheckDuplicatedSetup(config); len(issues) != 0 {
+			t.Errorf("Expected matrix jobs to be skipped, got %+v", issues)
+		}
+	})
 }
 
 func TestNormalizeSetupCommand(t *testing.T) {
@@ -456,3 +514,47 @@ func TestCreateSetupFingerprint(t *testing.T) {
 		t.Logf("Fingerprint for empty script: '%s'", fingerprint)
 	})
 }
+
+func TestCheckBeforeAfterScriptInDefault(t *testing.T) {
+	config, err := parser.Parse([]byte(`
+.template:
+  before_script: [apk add git, git fetch, make deps]
+build:
+  before_script: [apk add git, git fetch, make deps, make generate]
+  script: [make]
+test:
+  before_script: [apk add git, git fetch, make deps]
+  script: [make test]
+  after_script: [./report.sh]
+lint:
+  before_script: [apk add git, git fetch, npm ci]
+  script: [npm run lint]
+docs:
+  inherit:
+    default: false
+  before_script: [apk add git, git fetch, make deps]
+  script: [make docs]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	issues := CheckBeforeAfterScriptInDefault(config, nil)
+	if len(issues) != 1 {
+		t.Fatalf("Expected one before_script issue, got %+v", issues)
+	}
+	issue := issues[0]
+	if issue.Path != "default.before_script" {
+		t.Errorf("Expected the issue at default.before_script, got %s", issue.Path)
+	}
+	if !strings.Contains(issue.Message, "3 jobs (build, lint, test)") || !strings.Contains(issue.Message, "the same 2 commands: apk add git; git fetch") {
+		t.Errorf("Expected the shared lines of build, lint and test, got %q", issue.Message)
+	}
+
+	if issues := CheckBeforeAfterScriptInDefault(config, map[string]interface{}{"min_jobs": 4}); len(issues) != 0 {
+		t.Errorf("Expected no issues with min_jobs above the number of sharing jobs, got %+v", issues)
+	}
+	if issues := CheckBeforeAfterScriptInDefault(config, map[string]interface{}{"min_lines": 3, "min_jobs": 2}); len(issues) != 1 || !strings.Contains(issues[0].Message, "2 jobs (build, test)") {
+		t.Errorf("Expected build and test to share 3 lines, got %+v", issues)
+	}
+}
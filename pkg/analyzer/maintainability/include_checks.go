@@ -0,0 +1,74 @@
+package maintainability
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckIncludedJobOverrides flags jobs defined in more than one source, such
+// as the main file and an include, where the definition that wins differs
+// from one it overrode. Intentional overrides can be listed in custom_params
+// ("allowlist").
+func CheckIncludedJobOverrides(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	allowed := make(map[string]bool)
+	for _, jobName := range types.StringListParam(params, "allowlist", nil) {
+		allowed[jobName] = true
+	}
+
+	jobNames := make([]string, 0, len(config.ShadowedJobs))
+	for jobName := range config.ShadowedJobs {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	for _, jobName := range jobNames {
+		job := config.Jobs[jobName]
+		if job == nil || allowed[jobName] {
+			continue
+		}
+
+		var overridden []string
+		for _, definition := range config.ShadowedJobs[jobName] {
+			if definition.Job != nil && !reflect.DeepEqual(*definition.Job, *job) {
+				overridden = append(overridden, sourceName(definition.Source))
+			}
+		}
+		if len(overridden) == 0 {
+			continue
+		}
+
+		issues = append(issues, types.Issue{
+			Type:     types.IssueTypeMaintainability,
+			Severity: types.SeverityMedium,
+			Path:     "jobs." + jobName,
+			Message: fmt.Sprintf("Job '%s' in %s overrides a different definition from %s",
+				jobName, sourceName(config.JobSource(jobName)), joinSources(overridden)),
+			Suggestion: "Rename one of the jobs, or use extends to build on the included job; add the job to this check's allowlist if the override is intended",
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
+
+// sourceName describes a job source for messages
+func sourceName(source string) string {
+	if source == "" {
+		return "the main configuration"
+	}
+	return source
+}
+
+func joinSources(sources []string) string {
+	if len(sources) == 1 {
+		return sources[0]
+	}
+	return strings.Join(sources[:len(sources)-1], ", ") + " and " + sources[len(sources)-1]
+}
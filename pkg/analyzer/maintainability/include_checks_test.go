@@ -0,0 +1,70 @@
+package maintainability
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckIncludedJobOverrides(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".gitlab-ci.yml": `
+include:
+  - local: ci/jobs.yml
+
+test:
+  script: [make test]
+
+lint:
+  script: [make lint]
+`,
+		"ci/jobs.yml": `
+test:
+  script: [npm test]
+
+lint:
+  script: [make lint]
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, err := parser.ParseFile(filepath.Join(dir, ".gitlab-ci.yml"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	issues := CheckIncludedJobOverrides(config, nil)
+	if len(issues) != 1 {
+		t.Fatalf("Expected only the differing test job to be flagged, got %+v", issues)
+	}
+	if issues[0].JobName != "test" || !strings.Contains(issues[0].Message, "in the main configuration overrides a different definition from ci/jobs.yml") {
+		t.Errorf("Expected the main file's test to override the included one, got %q", issues[0].Message)
+	}
+
+	if issues := CheckIncludedJobOverrides(config, map[string]interface{}{"allowlist": []interface{}{"test"}}); len(issues) != 0 {
+		t.Errorf("Expected allowlisted overrides to be ignored, got %+v", issues)
+	}
+}
+
+func TestCheckIncludedJobOverrides_MergeConfigs(t *testing.T) {
+	first := &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{"test": {Script: []string{"make test"}}}}
+	second := &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{"test": {Script: []string{"npm test"}}}}
+
+	// Merging files explicitly, where the last one wins by design, isn't an
+	// include override
+	if issues := CheckIncludedJobOverrides(parser.MergeConfigs(first, second), nil); len(issues) != 0 {
+		t.Errorf("Expected no issues for explicitly merged configs, got %+v", issues)
+	}
+}
@@ -7,6 +7,8 @@ import (
 // CheckRegistry interface to avoid import cycles
 type CheckRegistry interface {
 	Register(name string, issueType types.IssueType, checkFunc types.CheckFunc)
+	RegisterWithParams(name string, issueType types.IssueType, checkFunc types.ParamCheckFunc)
+	Describe(name string, doc types.CheckDoc)
 }
 
 // RegisterChecks registers all maintainability-related checks
@@ -16,16 +18,34 @@ func RegisterChecks(registry CheckRegistry) {
 
 	// Complexity checks
 	registry.Register("script_complexity", types.IssueTypeMaintainability, CheckScriptComplexity)
+	registry.RegisterWithParams("deprecated_commands", types.IssueTypeMaintainability, CheckScriptUsesDeprecatedCommands)
 	registry.Register("verbose_rules", types.IssueTypeMaintainability, CheckVerboseRules)
+	registry.RegisterWithParams("rules_complexity", types.IssueTypeMaintainability, CheckRulesComplexity)
+	registry.Register("rules_with_legacy_keywords", types.IssueTypeMaintainability, CheckRulesWithLegacyKeywords)
+	registry.Register("redundant_parallel", types.IssueTypeMaintainability, CheckRedundantParallel)
+	registry.Register("shadowed_rules", types.IssueTypeMaintainability, CheckShadowedRules)
 
 	// Duplication checks
 	registry.Register("duplicated_code", types.IssueTypeMaintainability, CheckDuplicatedCode)
 	registry.Register("duplicated_before_scripts", types.IssueTypeMaintainability, CheckDuplicatedBeforeScripts)
+	registry.RegisterWithParams("common_script_prefix", types.IssueTypeMaintainability, CheckBeforeAfterScriptInDefault)
 	registry.Register("duplicated_cache_config", types.IssueTypeMaintainability, CheckDuplicatedCacheConfig)
 	registry.Register("duplicated_image_config", types.IssueTypeMaintainability, CheckDuplicatedImageConfig)
 	registry.Register("duplicated_setup", types.IssueTypeMaintainability, CheckDuplicatedSetup)
+	registry.RegisterWithParams("duplicated_changes_rules", types.IssueTypeMaintainability, CheckDuplicatedChangesRules)
 
 	// Structure checks
 	registry.Register("stages_definition", types.IssueTypeMaintainability, CheckStagesDefinition)
 	registry.Register("include_optimization", types.IssueTypeMaintainability, CheckIncludeOptimization)
+	registry.RegisterWithParams("included_job_overrides", types.IssueTypeMaintainability, CheckIncludedJobOverrides)
+	registry.Register("duplicate_stages", types.IssueTypeMaintainability, CheckDuplicateStages)
+	registry.Register("unused_stages", types.IssueTypeMaintainability, CheckUnusedStages)
+
+	// Workflow checks
+	registry.Register("workflow_skipped_jobs", types.IssueTypeMaintainability, CheckWorkflowSkippedJobs)
+	registry.Register("manual_job_blocking", types.IssueTypeMaintainability, CheckManualJobBlocking)
+
+	for name, doc := range checkDocs {
+		registry.Describe(name, doc)
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
 )
 
 // Mock registry for testing
@@ -21,6 +22,14 @@ func (r *mockRegistry) Register(name string, issueType types.IssueType, checkFun
 	r.checks[name] = checkFunc
 }
 
+func (r *mockRegistry) RegisterWithParams(name string, issueType types.IssueType, checkFunc types.ParamCheckFunc) {
+	r.checks[name] = func(config *parser.GitLabConfig) []types.Issue {
+		return checkFunc(config, nil)
+	}
+}
+
+func (r *mockRegistry) Describe(name string, doc types.CheckDoc) {}
+
 func TestRegisterChecks(t *testing.T) {
 	t.Run("registers all checks", func(t *testing.T) {
 		registry := newMockRegistry()
@@ -36,14 +45,26 @@ func TestRegisterChecks(t *testing.T) {
 		expectedChecks := []string{
 			"job_naming",
 			"script_complexity",
+			"deprecated_commands",
 			"verbose_rules",
+			"rules_complexity",
+			"rules_with_legacy_keywords",
+			"redundant_parallel",
+			"shadowed_rules",
 			"duplicated_code",
 			"duplicated_before_scripts",
+			"common_script_prefix",
 			"duplicated_cache_config",
 			"duplicated_image_config",
 			"duplicated_setup",
+			"duplicated_changes_rules",
 			"stages_definition",
 			"include_optimization",
+			"included_job_overrides",
+			"duplicate_stages",
+			"unused_stages",
+			"workflow_skipped_jobs",
+			"manual_job_blocking",
 		}
 
 		for _, expectedName := range expectedChecks {
@@ -0,0 +1,74 @@
+package maintainability
+
+import (
+	"fmt"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckManualJobBlocking flags manual jobs that leave allow_failure to
+// GitLab's defaults, which differ by where `when: manual` is set: a job-level
+// manual job is optional (allow_failure: true), while a manual rule blocks
+// the pipeline until someone runs the job (allow_failure: false). Jobs that
+// set allow_failure themselves or through extends are not flagged.
+func CheckManualJobBlocking(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for _, jobName := range config.ConcreteJobNames() {
+		// Later entries override earlier ones, as with extends
+		chain := append(config.ExtendsChain(jobName), jobName)
+		var rules []parser.Rule
+		var when string
+		var allowFailureSet bool
+		for _, name := range chain {
+			job := config.Jobs[name]
+			if job == nil {
+				continue
+			}
+			if job.Rules != nil {
+				rules = job.Rules
+			}
+			if job.When != "" {
+				when = job.When
+			}
+			if job.AllowFailure != nil {
+				allowFailureSet = true
+			}
+		}
+		if allowFailureSet {
+			continue
+		}
+
+		// Once rules are present the top-level when no longer applies
+		if rules == nil {
+			if when == "manual" {
+				issues = append(issues, types.Issue{
+					Type:       types.IssueTypeMaintainability,
+					Severity:   types.SeverityLow,
+					Path:       fmt.Sprintf("jobs.%s.when", jobName),
+					Message:    fmt.Sprintf("Manual job '%s' doesn't block the pipeline: job-level 'when: manual' defaults to 'allow_failure: true'", jobName),
+					Suggestion: "Set 'allow_failure: true' to document an optional job, or 'allow_failure: false' to make it a required gate",
+					JobName:    jobName,
+				})
+			}
+			continue
+		}
+
+		for i, rule := range rules {
+			if rule.When != "manual" || rule.AllowFailure != nil {
+				continue
+			}
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeMaintainability,
+				Severity:   types.SeverityLow,
+				Path:       fmt.Sprintf("jobs.%s.rules[%d]", jobName, i),
+				Message:    fmt.Sprintf("Manual job '%s' blocks the pipeline until someone runs it: 'when: manual' in rules defaults to 'allow_failure: false'", jobName),
+				Suggestion: "Add 'allow_failure: true' to the rule if the job is optional, or 'allow_failure: false' to document the blocking gate",
+				JobName:    jobName,
+			})
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,69 @@
+package maintainability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckManualJobBlocking(t *testing.T) {
+	allowed := true
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			".manual":  {When: "manual"},
+			".gate":    {AllowFailure: &parser.AllowFailure{Allowed: false}},
+			"optional": {Script: []string{"./smoke.sh"}, When: "manual"},
+			"inherited": {
+				Script:  []string{"./smoke.sh"},
+				Extends: ".manual",
+			},
+			"explicit": {
+				Script:       []string{"./smoke.sh"},
+				When:         "manual",
+				AllowFailure: &parser.AllowFailure{Allowed: true},
+			},
+			"template_gate": {
+				Script:  []string{"./deploy.sh"},
+				Extends: ".gate",
+				Rules:   []parser.Rule{{If: "$CI_COMMIT_TAG", When: "manual"}},
+			},
+			"deploy": {
+				Script: []string{"./deploy.sh"},
+				Rules: []parser.Rule{
+					{If: "$CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH", When: "manual"},
+					{If: "$CI_COMMIT_TAG", When: "manual", AllowFailure: &allowed},
+				},
+			},
+			"rules_override": {
+				Script:  []string{"make"},
+				Extends: ".manual",
+				Rules:   []parser.Rule{{If: "$CI_COMMIT_TAG"}},
+			},
+			"automatic": {Script: []string{"make"}},
+		},
+	}
+
+	issues := CheckManualJobBlocking(config)
+
+	var flagged []string
+	for _, issue := range issues {
+		flagged = append(flagged, issue.Path)
+		if issue.Type != types.IssueTypeMaintainability || issue.Severity != types.SeverityLow {
+			t.Errorf("Expected a low maintainability issue, got %s %s", issue.Severity, issue.Type)
+		}
+	}
+	if strings.Join(flagged, ",") != "jobs.deploy.rules[0],jobs.inherited.when,jobs.optional.when" {
+		t.Errorf("Unexpected issues: %v", flagged)
+	}
+
+	for _, issue := range issues {
+		if issue.JobName == "deploy" && !strings.Contains(issue.Message, "blocks the pipeline") {
+			t.Errorf("Expected the rule-level manual job to be reported as blocking, got %q", issue.Message)
+		}
+		if issue.JobName == "optional" && !strings.Contains(issue.Message, "doesn't block the pipeline") {
+			t.Errorf("Expected the job-level manual job to be reported as optional, got %q", issue.Message)
+		}
+	}
+}
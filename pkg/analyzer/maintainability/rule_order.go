@@ -0,0 +1,126 @@
+package maintainability
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckShadowedRules flags rules that can never apply because an earlier
+// rule in the same list matches first in every pipeline they would match.
+// Rule lists of jobs, templates and workflow:rules are probed across
+// representative pipeline contexts. An earlier rule with changes or exists
+// may not match, so it never counts as shadowing a later one.
+func CheckShadowedRules(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	if config.Workflow != nil {
+		variables := func(ctx *parser.PipelineContext) map[string]string {
+			return config.RuleVariables(ctx, nil, nil)
+		}
+		for _, pair := range shadowedRules(config.Workflow.Rules, variables) {
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeMaintainability,
+				Severity:   types.SeverityMedium,
+				Path:       fmt.Sprintf("workflow.rules[%d]", pair.shadowed),
+				Message:    fmt.Sprintf("workflow:rules rule %d can never apply: rule %d matches first in every pipeline it would match", pair.shadowed, pair.shadowing),
+				Suggestion: "Move the more specific rule before the broader one, or remove the unreachable rule",
+			})
+		}
+	}
+
+	jobNames := make([]string, 0, len(config.Jobs))
+	for jobName := range config.Jobs {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	for _, jobName := range jobNames {
+		job := config.Jobs[jobName]
+		if job == nil || len(job.Rules) < 2 {
+			continue
+		}
+		variables := func(ctx *parser.PipelineContext) map[string]string {
+			return config.RuleVariables(ctx, nil, job)
+		}
+		for _, pair := range shadowedRules(job.Rules, variables) {
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeMaintainability,
+				Severity:   types.SeverityMedium,
+				Path:       fmt.Sprintf("jobs.%s.rules[%d]", jobName, pair.shadowed),
+				Message:    fmt.Sprintf("Rule %d of job '%s' can never apply: rule %d matches first in every pipeline it would match", pair.shadowed, jobName, pair.shadowing),
+				Suggestion: "Move the more specific rule before the broader one, or remove the unreachable rule",
+				JobName:    jobName,
+			})
+		}
+	}
+
+	return issues
+}
+
+// rulePair names a rule and the earlier rule that shadows it
+type rulePair struct {
+	shadowing, shadowed int
+}
+
+// shadowedRules returns, for each rule that is only ever matched in contexts
+// where one earlier rule also matches, that earlier rule. Nothing is
+// returned when an if expression can't be evaluated.
+func shadowedRules(rules []parser.Rule, variables func(ctx *parser.PipelineContext) map[string]string) []rulePair {
+	if len(rules) < 2 {
+		return nil
+	}
+
+	// matches[c][i] tells whether rule i matches in context c. changes and
+	// exists are assumed to match, since the files aren't known.
+	contexts := parser.RuleProbeContexts(rules)
+	matches := make([][]bool, len(contexts))
+	for c, ctx := range contexts {
+		vars := variables(ctx)
+		matches[c] = make([]bool, len(rules))
+		for i, rule := range rules {
+			matches[c][i] = true
+			if rule.If == "" {
+				continue
+			}
+			matched, err := parser.EvaluateExpression(rule.If, vars)
+			if err != nil {
+				return nil
+			}
+			matches[c][i] = matched
+		}
+	}
+
+	var pairs []rulePair
+	for i := 1; i < len(rules); i++ {
+		reachable := false
+		for c := range contexts {
+			reachable = reachable || matches[c][i]
+		}
+		// A rule matching none of the contexts depends on something we
+		// don't probe, so it can't be shown to be shadowed
+		if !reachable {
+			continue
+		}
+
+		for j := 0; j < i; j++ {
+			if len(rules[j].Changes) > 0 || len(rules[j].Exists) > 0 {
+				continue
+			}
+			covers := true
+			for c := range contexts {
+				if matches[c][i] && !matches[c][j] {
+					covers = false
+					break
+				}
+			}
+			if covers {
+				pairs = append(pairs, rulePair{shadowing: j, shadowed: i})
+				break
+			}
+		}
+	}
+	return pairs
+}
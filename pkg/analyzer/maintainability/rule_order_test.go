@@ -0,0 +1,90 @@
+package maintainability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckShadowedRules(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Workflow: &parser.Workflow{
+			Rules: []parser.Rule{
+				{If: `$CI_PIPELINE_SOURCE == "merge_request_event"`},
+				{If: "$CI_COMMIT_BRANCH"},
+				{If: "$CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH"},
+			},
+		},
+		Jobs: map[string]*parser.JobConfig{
+			"deploy": {
+				Script: []string{"./deploy.sh"},
+				Rules: []parser.Rule{
+					{If: `$CI_COMMIT_BRANCH == "main"`},
+					{If: `$CI_COMMIT_BRANCH == "main" && $CI_PIPELINE_SOURCE == "schedule"`, When: "never"},
+					{If: "$FORCE_DEPLOY"},
+				},
+			},
+			".catch_all": {
+				Rules: []parser.Rule{
+					{When: "on_success"},
+					{If: "$CI_COMMIT_TAG", When: "manual"},
+				},
+			},
+			"ordered": {
+				Script: []string{"make"},
+				Rules: []parser.Rule{
+					{If: `$CI_PIPELINE_SOURCE == "schedule"`, When: "never"},
+					{If: `$CI_COMMIT_BRANCH == "main" || $FORCE_BUILD`},
+					{When: "on_success"},
+				},
+			},
+			"changes": {
+				Script: []string{"make"},
+				Rules: []parser.Rule{
+					{Changes: []string{"src/**/*"}},
+					{If: "$CI_COMMIT_BRANCH"},
+				},
+			},
+			"unparseable": {
+				Script: []string{"make"},
+				Rules: []parser.Rule{
+					{When: "always"},
+					{If: "$CI_COMMIT_BRANCH =="},
+				},
+			},
+		},
+	}
+
+	var flagged []string
+	for _, issue := range CheckShadowedRules(config) {
+		flagged = append(flagged, issue.Path)
+	}
+	expected := "workflow.rules[2],jobs..catch_all.rules[1],jobs.deploy.rules[1]"
+	if strings.Join(flagged, ",") != expected {
+		t.Errorf("Expected %s to be flagged, got %v", expected, flagged)
+	}
+}
+
+func TestShadowedRulesReportsBothIndexes(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"test": {
+				Script: []string{"make test"},
+				Rules: []parser.Rule{
+					{If: `$CI_PIPELINE_SOURCE == "merge_request_event"`},
+					{If: "$CI_COMMIT_TAG"},
+					{If: `$CI_PIPELINE_SOURCE == "merge_request_event" && $CI_MERGE_REQUEST_TARGET_BRANCH_NAME == "main"`, When: "manual"},
+				},
+			},
+		},
+	}
+
+	issues := CheckShadowedRules(config)
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %+v", issues)
+	}
+	if issues[0].Path != "jobs.test.rules[2]" || !strings.Contains(issues[0].Message, "Rule 2") || !strings.Contains(issues[0].Message, "rule 0 matches first") {
+		t.Errorf("Expected rule 2 to be reported as shadowed by rule 0, got %s: %s", issues[0].Path, issues[0].Message)
+	}
+}
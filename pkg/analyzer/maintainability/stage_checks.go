@@ -0,0 +1,63 @@
+package maintainability
+
+import (
+	"fmt"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckDuplicateStages flags stages listed more than once in `stages`
+func CheckDuplicateStages(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	counts := make(map[string]int)
+	for _, stage := range config.Stages {
+		counts[stage]++
+	}
+
+	reported := make(map[string]bool)
+	for _, stage := range config.Stages {
+		if counts[stage] < 2 || reported[stage] {
+			continue
+		}
+		reported[stage] = true
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeMaintainability,
+			Severity:   types.SeverityLow,
+			Path:       "stages",
+			Message:    fmt.Sprintf("Stage '%s' is listed %d times", stage, counts[stage]),
+			Suggestion: fmt.Sprintf("Remove the duplicate '%s' entries from stages", stage),
+		})
+	}
+
+	return issues
+}
+
+// CheckUnusedStages flags declared stages that no job runs in, taking stages
+// set through extends into account
+func CheckUnusedStages(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	used := make(map[string]bool)
+	for jobName := range config.ConcreteJobs() {
+		used[config.JobStage(jobName)] = true
+	}
+
+	reported := make(map[string]bool)
+	for _, stage := range config.Stages {
+		if used[stage] || reported[stage] || stage == ".pre" || stage == ".post" {
+			continue
+		}
+		reported[stage] = true
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeMaintainability,
+			Severity:   types.SeverityLow,
+			Path:       "stages",
+			Message:    fmt.Sprintf("Stage '%s' is declared but no job uses it", stage),
+			Suggestion: fmt.Sprintf("Remove '%s' from stages or assign jobs to it", stage),
+		})
+	}
+
+	return issues
+}
@@ -0,0 +1,77 @@
+package maintainability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckDuplicateStages(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Stages: []string{"build", "test", "build", "deploy", "build", "test"},
+	}
+
+	issues := CheckDuplicateStages(config)
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %d: %+v", len(issues), issues)
+	}
+	if issues[0].Path != "stages" || !strings.Contains(issues[0].Message, "'build' is listed 3 times") {
+		t.Errorf("Unexpected first issue: %+v", issues[0])
+	}
+	if !strings.Contains(issues[1].Message, "'test' is listed 2 times") {
+		t.Errorf("Unexpected second issue: %+v", issues[1])
+	}
+}
+
+func TestCheckUnusedStages(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Stages: []string{".pre", "build", "test", "lint", "deploy"},
+		Jobs: map[string]*parser.JobConfig{
+			".deploy": {Stage: "deploy"},
+			".lint":   {Stage: "lint"},
+			"build":   {Stage: "build"},
+			"unit":    {},
+			"promote": {Extends: ".deploy"},
+		},
+	}
+
+	issues := CheckUnusedStages(config)
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d: %+v", len(issues), issues)
+	}
+	if issues[0].Path != "stages" || !strings.Contains(issues[0].Message, "'lint'") {
+		t.Errorf("Expected the unused lint stage to be flagged, got %+v", issues[0])
+	}
+}
+
+func TestCheckUnusedStages_MatrixAndReferenceJobs(t *testing.T) {
+	config, err := parser.Parse([]byte(`
+stages: [build, test, deploy]
+.setup:
+  before_script: [source env.sh]
+build:
+  stage: build
+  parallel:
+    matrix:
+      - TARGET: [a, b]
+  script: [make]
+test:
+  parallel:
+    matrix:
+      - NODE: ["18", "20"]
+  script: [npm test]
+deploy:
+  stage: deploy
+  before_script:
+    - !reference [.setup, before_script]
+  script: [make deploy]
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	if issues := CheckUnusedStages(config); len(issues) != 0 {
+		t.Errorf("expected stages of matrix and !reference jobs to count as used, got %+v", issues)
+	}
+}
@@ -0,0 +1,67 @@
+package maintainability
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckWorkflowSkippedJobs flags jobs that workflow:rules always skip
+func CheckWorkflowSkippedJobs(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	if config.Workflow == nil || len(config.Workflow.Rules) == 0 {
+		return issues
+	}
+
+	scenarios := parser.RepresentativeScenarios()
+
+	var createdLabels []string
+	workflowVars := make([]map[string]string, len(scenarios))
+	created := make([]bool, len(scenarios))
+	for i, scenario := range scenarios {
+		created[i], workflowVars[i] = config.EvaluateWorkflow(scenario.Context)
+		if created[i] {
+			createdLabels = append(createdLabels, scenario.Label)
+		}
+	}
+
+	// If the workflow doesn't create a pipeline in any scenario we model,
+	// we can't reason about which jobs are reachable
+	if len(createdLabels) == 0 {
+		return issues
+	}
+
+	for jobName, job := range config.ConcreteJobs() {
+		var jobLabels []string
+		reachable := false
+		for i, scenario := range scenarios {
+			if !config.JobRuns(job, scenario.Context, nil) {
+				continue
+			}
+			jobLabels = append(jobLabels, scenario.Label)
+			if created[i] && config.JobRuns(job, scenario.Context, workflowVars[i]) {
+				reachable = true
+				break
+			}
+		}
+
+		if reachable || len(jobLabels) == 0 {
+			continue
+		}
+
+		issues = append(issues, types.Issue{
+			Type:     types.IssueTypeMaintainability,
+			Severity: types.SeverityMedium,
+			Path:     "jobs." + jobName + ".rules",
+			Message: fmt.Sprintf("Job will never run: its rules only match %s pipelines, but workflow:rules only create %s pipelines",
+				strings.Join(jobLabels, ", "), strings.Join(createdLabels, ", ")),
+			Suggestion: "Align the job's rules with workflow:rules, or remove the job if it is no longer needed",
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
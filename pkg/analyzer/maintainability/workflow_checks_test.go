@@ -0,0 +1,108 @@
+package maintainability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckWorkflowSkippedJobs(t *testing.T) {
+	t.Run("Tag job with MR-only workflow", func(t *testing.T) {
+		yamlContent := `
+workflow:
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+
+test:
+  script:
+    - make test
+
+release:
+  script:
+    - make release
+  rules:
+    - if: $CI_COMMIT_TAG
+`
+		config, err := parser.Parse([]byte(yamlContent))
+		if err != nil {
+			t.Fatalf("Failed to parse config: %v", err)
+		}
+
+		issues := CheckWorkflowSkippedJobs(config)
+		if len(issues) != 1 {
+			t.Fatalf("Expected 1 issue, got %d: %v", len(issues), issues)
+		}
+
+		issue := issues[0]
+		if issue.JobName != "release" {
+			t.Errorf("Expected issue for release job, got %s", issue.JobName)
+		}
+		if issue.Type != types.IssueTypeMaintainability {
+			t.Errorf("Expected maintainability issue, got %s", issue.Type)
+		}
+		if !strings.Contains(issue.Message, "tag") || !strings.Contains(issue.Message, "merge request") {
+			t.Errorf("Expected message to explain the contradiction, got: %s", issue.Message)
+		}
+	})
+
+	t.Run("Job reachable through workflow", func(t *testing.T) {
+		yamlContent := `
+workflow:
+  rules:
+    - if: $CI_COMMIT_TAG
+    - if: $CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH
+
+release:
+  script:
+    - make release
+  rules:
+    - if: $CI_COMMIT_TAG
+`
+		config, err := parser.Parse([]byte(yamlContent))
+		if err != nil {
+			t.Fatalf("Failed to parse config: %v", err)
+		}
+
+		if issues := CheckWorkflowSkippedJobs(config); len(issues) != 0 {
+			t.Errorf("Expected no issues, got %v", issues)
+		}
+	})
+
+	t.Run("Job relying on workflow variables", func(t *testing.T) {
+		yamlContent := `
+workflow:
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+      variables:
+        RUN_REVIEW: "true"
+
+review:
+  script:
+    - deploy review
+  rules:
+    - if: $RUN_REVIEW == "true"
+`
+		config, err := parser.Parse([]byte(yamlContent))
+		if err != nil {
+			t.Fatalf("Failed to parse config: %v", err)
+		}
+
+		if issues := CheckWorkflowSkippedJobs(config); len(issues) != 0 {
+			t.Errorf("Expected no issues, got %v", issues)
+		}
+	})
+
+	t.Run("No workflow", func(t *testing.T) {
+		config := &parser.GitLabConfig{
+			Jobs: map[string]*parser.JobConfig{
+				"release": {Rules: []parser.Rule{{If: "$CI_COMMIT_TAG"}}},
+			},
+		}
+
+		if issues := CheckWorkflowSkippedJobs(config); len(issues) != 0 {
+			t.Errorf("Expected no issues without workflow, got %v", issues)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package performance
+
+import (
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckLargeArtifactPaths flags artifact paths such as `.`, `*` or `**/*`
+// that match the whole project directory, which uploads far more data than
+// later jobs need
+func CheckLargeArtifactPaths(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for jobName, job := range config.ConcreteJobs() {
+		if job.Artifacts == nil {
+			continue
+		}
+		for _, path := range job.Artifacts.Paths {
+			if !isBroadArtifactPath(path) {
+				continue
+			}
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypePerformance,
+				Severity:   types.SeverityMedium,
+				Path:       "jobs." + jobName + ".artifacts.paths",
+				Message:    "Artifact path '" + path + "' uploads the entire project directory",
+				Suggestion: "List only the directories or file patterns later jobs need, such as dist/ or **/*.xml",
+				JobName:    jobName,
+			})
+		}
+	}
+
+	return issues
+}
+
+// CheckCacheArtifactOverlap flags jobs that both cache and upload the same
+// directory as artifacts, transferring it twice
+func CheckCacheArtifactOverlap(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for jobName, job := range config.ConcreteJobs() {
+		if job.Artifacts == nil {
+			continue
+		}
+		cache := effectiveCache(config, job)
+		if cache == nil {
+			continue
+		}
+
+		cached := make(map[string]bool)
+		for _, path := range cache.Paths {
+			cached[normalizeArtifactPath(path)] = true
+		}
+		for _, path := range job.Artifacts.Paths {
+			normalized := normalizeArtifactPath(path)
+			if normalized == "" || !cached[normalized] {
+				continue
+			}
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypePerformance,
+				Severity:   types.SeverityLow,
+				Path:       "jobs." + jobName + ".artifacts.paths",
+				Message:    "Job '" + jobName + "' both caches and uploads '" + path + "' as an artifact",
+				Suggestion: "Use cache for reusable dependencies and artifacts for build outputs; drop one of the two",
+				JobName:    jobName,
+			})
+		}
+	}
+
+	return issues
+}
+
+// isBroadArtifactPath reports whether a path has no segment that narrows it
+// down, i.e. it consists only of `.`, `*` and `**` segments
+func isBroadArtifactPath(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		switch segment {
+		case "", ".", "*", "**":
+			continue
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeArtifactPath strips leading `./` and trailing slashes or globs so
+// that `./node_modules/`, `node_modules` and `node_modules/**` compare equal
+func normalizeArtifactPath(path string) string {
+	path = strings.TrimPrefix(path, "./")
+	for {
+		trimmed := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(path, "/"), "/**"), "/*")
+		if trimmed == path {
+			return path
+		}
+		path = trimmed
+	}
+}
+
+// effectiveCache returns the job's cache, falling back to the inherited
+// default or global cache
+func effectiveCache(config *parser.GitLabConfig, job *parser.JobConfig) *parser.Cache {
+	if job.Cache != nil {
+		return job.Cache
+	}
+	if !job.InheritsDefault("cache") {
+		return nil
+	}
+	if config.Default != nil && config.Default.Cache != nil {
+		return config.Default.Cache
+	}
+	return config.Cache
+}
@@ -0,0 +1,123 @@
+package performance
+
+import (
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestIsBroadArtifactPath(t *testing.T) {
+	tests := map[string]bool{
+		".":            true,
+		"./":           true,
+		"*":            true,
+		"**/*":         true,
+		"./**":         true,
+		"dist/":        false,
+		"**/*.xml":     false,
+		"coverage/**":  false,
+		"build/*.jar":  false,
+		"./reports/**": false,
+	}
+
+	for path, expected := range tests {
+		if broad := isBroadArtifactPath(path); broad != expected {
+			t.Errorf("isBroadArtifactPath(%q) = %v, expected %v", path, broad, expected)
+		}
+	}
+}
+
+func TestCheckLargeArtifactPaths(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"build":   {Artifacts: &parser.Artifacts{Paths: []string{"dist/", "."}}},
+			"test":    {Artifacts: &parser.Artifacts{Paths: []string{"**/*.xml"}}},
+			"package": {Artifacts: &parser.Artifacts{Paths: []string{"**/*"}}},
+			"lint":    {},
+		},
+	}
+
+	issues := CheckLargeArtifactPaths(config)
+
+	jobs := make(map[string]bool)
+	for _, issue := range issues {
+		jobs[issue.JobName] = true
+	}
+	if len(issues) != 2 || !jobs["build"] || !jobs["package"] {
+		t.Errorf("Expected issues for build and package, got %+v", issues)
+	}
+}
+
+func TestCheckCacheArtifactOverlap(t *testing.T) {
+	globalCache := &parser.Cache{Paths: []string{".npm/"}}
+
+	tests := []struct {
+		name        string
+		config      *parser.GitLabConfig
+		expectIssue bool
+	}{
+		{
+			name: "job caches and uploads the same directory",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"build": {
+					Cache:     &parser.Cache{Paths: []string{"./node_modules/"}},
+					Artifacts: &parser.Artifacts{Paths: []string{"node_modules/**", "dist/"}},
+				},
+			}},
+			expectIssue: true,
+		},
+		{
+			name: "inherited global cache overlaps",
+			config: &parser.GitLabConfig{Cache: globalCache, Jobs: map[string]*parser.JobConfig{
+				"build": {Artifacts: &parser.Artifacts{Paths: []string{".npm"}}},
+			}},
+			expectIssue: true,
+		},
+		{
+			name: "global cache not inherited",
+			config: &parser.GitLabConfig{Cache: globalCache, Jobs: map[string]*parser.JobConfig{
+				"build": {
+					Artifacts: &parser.Artifacts{Paths: []string{".npm"}},
+					Inherit:   &parser.Inherit{Default: false},
+				},
+			}},
+		},
+		{
+			name: "distinct cache and artifact paths",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"build": {
+					Cache:     &parser.Cache{Paths: []string{"node_modules/"}},
+					Artifacts: &parser.Artifacts{Paths: []string{"dist/"}},
+				},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := CheckCacheArtifactOverlap(tt.config)
+			if (len(issues) > 0) != tt.expectIssue {
+				t.Errorf("Expected issue: %v, got %+v", tt.expectIssue, issues)
+			}
+		})
+	}
+}
+
+func TestArtifactChecksSkipNilAndHiddenJobs(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Cache: &parser.Cache{Paths: []string{"dist/"}},
+		Jobs: map[string]*parser.JobConfig{
+			"empty": nil,
+			".template": {
+				Artifacts: &parser.Artifacts{Paths: []string{".", "dist/"}},
+			},
+		},
+	}
+
+	if issues := CheckLargeArtifactPaths(config); len(issues) != 0 {
+		t.Errorf("Expected no large artifact issues, got %+v", issues)
+	}
+	if issues := CheckCacheArtifactOverlap(config); len(issues) != 0 {
+		t.Errorf("Expected no overlap issues, got %+v", issues)
+	}
+}
@@ -0,0 +1,180 @@
+package performance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// dependencyLockFiles maps the directories package managers install into to
+// the lock files that decide their content, in order of preference. Paths can
+// be extended through the check's custom_params ("dependency_paths").
+var dependencyLockFiles = map[string][]string{
+	"node_modules":   {"package-lock.json", "yarn.lock", "pnpm-lock.yaml"},
+	".npm":           {"package-lock.json"},
+	".yarn":          {"yarn.lock"},
+	".pnpm-store":    {"pnpm-lock.yaml"},
+	"vendor":         {"composer.lock", "Gemfile.lock", "go.sum"},
+	".bundle":        {"Gemfile.lock"},
+	".go/pkg/mod":    {"go.sum"},
+	".m2/repository": {"pom.xml"},
+	".m2":            {"pom.xml"},
+	".gradle":        {"gradle.lockfile", "build.gradle", "build.gradle.kts"},
+	".venv":          {"poetry.lock", "Pipfile.lock", "requirements.txt"},
+	"venv":           {"poetry.lock", "Pipfile.lock", "requirements.txt"},
+	".cache/pip":     {"requirements.txt", "poetry.lock", "Pipfile.lock"},
+	"target":         {"Cargo.lock"},
+}
+
+// CheckCacheKeyFiles flags caches keyed on files that don't exist in the
+// repository, which GitLab silently replaces with the key "default", and
+// caches of dependency directories under a fixed string key, which are never
+// invalidated when the dependencies change.
+//
+// Missing files can only be detected when the repository's files are known,
+// through the "repository_files" param; without it only fixed keys are
+// reported.
+func CheckCacheKeyFiles(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	repositoryFiles := types.StringListParam(params, "repository_files", nil)
+	dependencyPaths := types.StringListParam(params, "dependency_paths", nil)
+
+	type cacheSite struct {
+		path    string
+		jobName string
+		cache   *parser.Cache
+	}
+	var sites []cacheSite
+	if config.Cache != nil {
+		sites = append(sites, cacheSite{path: "cache", cache: config.Cache})
+	}
+	if config.Default != nil && config.Default.Cache != nil {
+		sites = append(sites, cacheSite{path: "default.cache", cache: config.Default.Cache})
+	}
+	jobNames := make([]string, 0, len(config.Jobs))
+	for jobName, job := range config.Jobs {
+		if job != nil && job.Cache != nil {
+			jobNames = append(jobNames, jobName)
+		}
+	}
+	sort.Strings(jobNames)
+	for _, jobName := range jobNames {
+		sites = append(sites, cacheSite{path: "jobs." + jobName + ".cache", jobName: jobName, cache: config.Jobs[jobName].Cache})
+	}
+
+	for _, site := range sites {
+		owner := "The global cache"
+		if site.path == "default.cache" {
+			owner = "The default cache"
+		} else if site.jobName != "" {
+			owner = fmt.Sprintf("Job '%s'", site.jobName)
+		}
+
+		if object := site.cache.KeyObject(); object != nil {
+			if repositoryFiles == nil || len(object.Files) == 0 {
+				continue
+			}
+			var missing []string
+			for _, file := range object.Files {
+				if !anyRepositoryFile(repositoryFiles, file) {
+					missing = append(missing, file)
+				}
+			}
+			if len(missing) == 0 {
+				continue
+			}
+			severity := types.SeverityLow
+			message := fmt.Sprintf("%s keys its cache on %s, which doesn't exist in the repository", owner, quoteNames(missing))
+			if len(missing) == len(object.Files) {
+				severity = types.SeverityMedium
+				message += ", so GitLab falls back to the key 'default' and never invalidates the cache"
+			}
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypePerformance,
+				Severity:   severity,
+				Path:       site.path + ".key.files",
+				Message:    message,
+				Suggestion: "Point key.files at the lock files the cached dependencies are installed from",
+				JobName:    site.jobName,
+			})
+			continue
+		}
+
+		key := site.cache.KeyString()
+		if key == "" || strings.Contains(key, "$") {
+			continue
+		}
+		dirs, lockFiles := cachedDependencyDirs(site.cache.Paths, dependencyPaths, repositoryFiles)
+		if len(dirs) == 0 {
+			continue
+		}
+		suggestion := "Use 'key: files:' on the lock file, so the cache is rebuilt when the dependencies change"
+		if len(lockFiles) > 0 {
+			suggestion = fmt.Sprintf("Use 'key: files: [%s]', so the cache is rebuilt when the dependencies change", strings.Join(lockFiles, ", "))
+		}
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypePerformance,
+			Severity:   types.SeverityLow,
+			Path:       site.path + ".key",
+			Message:    fmt.Sprintf("%s caches %s under the fixed key '%s', which isn't invalidated when the dependencies change", owner, strings.Join(dirs, ", "), key),
+			Suggestion: suggestion,
+			JobName:    site.jobName,
+		})
+	}
+
+	return issues
+}
+
+// cachedDependencyDirs returns the cached paths that are dependency
+// directories, and up to two lock files to key them on. When the repository's
+// files are known, only lock files that exist are suggested.
+func cachedDependencyDirs(paths, extraDirs, repositoryFiles []string) ([]string, []string) {
+	var dirs, lockFiles []string
+	extraDirs = normalizedCachePaths(extraDirs)
+	seen := make(map[string]bool)
+	for _, path := range normalizedCachePaths(paths) {
+		candidates, known := dependencyLockFiles[path]
+		if !known && !types.ContainsString(extraDirs, path) {
+			continue
+		}
+		dirs = append(dirs, path)
+		for _, lockFile := range candidates {
+			if seen[lockFile] || len(lockFiles) == 2 {
+				continue
+			}
+			if repositoryFiles != nil && !anyRepositoryFile(repositoryFiles, lockFile) {
+				continue
+			}
+			seen[lockFile] = true
+			lockFiles = append(lockFiles, lockFile)
+			if repositoryFiles == nil {
+				// Without the repository's files, only the most likely lock
+				// file of each directory is suggested
+				break
+			}
+		}
+	}
+	return dirs, lockFiles
+}
+
+// anyRepositoryFile reports whether a repository file matches the pattern
+func anyRepositoryFile(files []string, pattern string) bool {
+	for _, file := range files {
+		if parser.MatchGlob(pattern, file) {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "'" + name + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,125 @@
+package performance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckCacheKeyFiles(t *testing.T) {
+	repository := []string{"package-lock.json", "src/index.js", "backend/go.sum"}
+
+	tests := []struct {
+		name       string
+		config     *parser.GitLabConfig
+		params     map[string]interface{}
+		expectPath []string
+		severity   types.Severity
+		contains   string
+	}{
+		{
+			name: "key files missing from the repository",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"build": {Cache: &parser.Cache{Key: &parser.CacheKey{Files: []string{"yarn.lock"}}, Paths: []string{"node_modules/"}}},
+			}},
+			params:     map[string]interface{}{"repository_files": repository},
+			expectPath: []string{"jobs.build.cache.key.files"},
+			severity:   types.SeverityMedium,
+			contains:   "falls back to the key 'default'",
+		},
+		{
+			name: "one of two key files missing",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"build": {Cache: &parser.Cache{Key: &parser.CacheKey{Files: []string{"package-lock.json", ".nvmrc"}}, Paths: []string{"node_modules/"}}},
+			}},
+			params:     map[string]interface{}{"repository_files": repository},
+			expectPath: []string{"jobs.build.cache.key.files"},
+			severity:   types.SeverityLow,
+			contains:   "'.nvmrc'",
+		},
+		{
+			name: "key files present, including through a glob",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"build": {Cache: &parser.Cache{Key: &parser.CacheKey{Files: []string{"package-lock.json", "**/go.sum"}}, Paths: []string{"node_modules/"}}},
+			}},
+			params: map[string]interface{}{"repository_files": repository},
+		},
+		{
+			name: "key files aren't verified without the repository files",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"build": {Cache: &parser.Cache{Key: &parser.CacheKey{Files: []string{"yarn.lock"}}, Paths: []string{"node_modules/"}}},
+			}},
+		},
+		{
+			name: "fixed key on a dependency directory suggests the existing lock file",
+			config: &parser.GitLabConfig{Default: &parser.JobConfig{
+				Cache: &parser.Cache{Key: &parser.CacheKey{Value: "node"}, Paths: []string{"./node_modules/"}},
+			}},
+			params:     map[string]interface{}{"repository_files": repository},
+			expectPath: []string{"default.cache.key"},
+			severity:   types.SeverityLow,
+			contains:   "fixed key 'node'",
+		},
+		{
+			name: "key with variables changes on its own",
+			config: &parser.GitLabConfig{Cache: &parser.Cache{
+				Key: &parser.CacheKey{Value: "$CI_COMMIT_REF_SLUG"}, Paths: []string{"node_modules/"},
+			}},
+		},
+		{
+			name: "fixed key on build output is fine",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"build": {Cache: &parser.Cache{Key: &parser.CacheKey{Value: "build"}, Paths: []string{"dist/"}}},
+			}},
+		},
+		{
+			name: "custom dependency paths",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"build": {Cache: &parser.Cache{Key: &parser.CacheKey{Value: "deps"}, Paths: []string{"third_party/"}}},
+			}},
+			params:     map[string]interface{}{"dependency_paths": []interface{}{"third_party/"}},
+			expectPath: []string{"jobs.build.cache.key"},
+			severity:   types.SeverityLow,
+			contains:   "third_party",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := CheckCacheKeyFiles(tt.config, tt.params)
+
+			if len(issues) != len(tt.expectPath) {
+				t.Fatalf("Expected %d issues, got %d: %v", len(tt.expectPath), len(issues), issues)
+			}
+			for i, path := range tt.expectPath {
+				if issues[i].Path != path {
+					t.Errorf("Expected path %s, got %s", path, issues[i].Path)
+				}
+				if issues[i].Severity != tt.severity {
+					t.Errorf("Expected severity %s, got %s", tt.severity, issues[i].Severity)
+				}
+				if !strings.Contains(issues[i].Message, tt.contains) {
+					t.Errorf("Expected message to contain %q, got %q", tt.contains, issues[i].Message)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckCacheKeyFilesSuggestsLockFiles(t *testing.T) {
+	config := &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+		"build": {Cache: &parser.Cache{Key: &parser.CacheKey{Value: "deps"}, Paths: []string{"node_modules/"}}},
+	}}
+
+	issues := CheckCacheKeyFiles(config, map[string]interface{}{"repository_files": []string{"yarn.lock"}})
+	if len(issues) != 1 || !strings.Contains(issues[0].Suggestion, "[yarn.lock]") {
+		t.Errorf("Expected the suggestion to name the existing yarn.lock, got %v", issues)
+	}
+
+	issues = CheckCacheKeyFiles(config, nil)
+	if len(issues) != 1 || !strings.Contains(issues[0].Suggestion, "[package-lock.json]") {
+		t.Errorf("Expected the suggestion to name the most likely lock file, got %v", issues)
+	}
+}
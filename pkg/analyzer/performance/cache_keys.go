@@ -0,0 +1,185 @@
+package performance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// jobCacheUsage is a job's cache reduced to what decides whether jobs share it
+type jobCacheUsage struct {
+	job    string
+	key    string
+	paths  []string
+	pushes bool
+	// perJob is set when the key includes the job's name or stage, which
+	// separates the jobs' caches on purpose
+	perJob bool
+}
+
+// CheckCacheKeyConflicts flags jobs that cache the same paths under
+// different keys, which stores the same files several times without ever
+// sharing them, and jobs that upload different paths under the same key,
+// which replace each other's cache
+func CheckCacheKeyConflicts(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	byPaths := make(map[string][]jobCacheUsage)
+	byKey := make(map[string][]jobCacheUsage)
+	var pathGroups, keyGroups []string
+	for _, jobName := range config.ConcreteJobNames() {
+		job := config.Jobs[jobName]
+		cache := jobCache(config, jobName, job)
+		if cache == nil || len(cache.Paths) == 0 {
+			continue
+		}
+
+		key := cacheKeyIdentity(cache, jobVariables(config, jobName))
+		usage := jobCacheUsage{
+			job:    jobName,
+			key:    key,
+			paths:  normalizedCachePaths(cache.Paths),
+			pushes: cache.Policy != "pull",
+			perJob: key != cacheKeyIdentity(cache, strings.NewReplacer()),
+		}
+		if !usage.perJob {
+			pathsID := strings.Join(usage.paths, "\n")
+			if byPaths[pathsID] == nil {
+				pathGroups = append(pathGroups, pathsID)
+			}
+			byPaths[pathsID] = append(byPaths[pathsID], usage)
+		}
+		if byKey[usage.key] == nil {
+			keyGroups = append(keyGroups, usage.key)
+		}
+		byKey[usage.key] = append(byKey[usage.key], usage)
+	}
+
+	for _, pathsID := range pathGroups {
+		group := byPaths[pathsID]
+		keys := make(map[string]bool)
+		var jobs []string
+		for _, usage := range group {
+			keys[usage.key] = true
+			jobs = append(jobs, usage.job)
+		}
+		if len(keys) < 2 {
+			continue
+		}
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypePerformance,
+			Severity:   types.SeverityLow,
+			Path:       "jobs." + group[0].job + ".cache.key",
+			Message:    fmt.Sprintf("Jobs %s cache the same paths (%s) under %d different keys, so they never share a cache", quoteJobs(jobs), strings.Join(group[0].paths, ", "), len(keys)),
+			Suggestion: "Use the same key in these jobs, e.g. key.files on the lock file, so they share one cache",
+			JobName:    group[0].job,
+		})
+	}
+
+	for _, key := range keyGroups {
+		group := byKey[key]
+		for i := 0; i < len(group); i++ {
+			conflict := -1
+			for j := i + 1; j < len(group) && conflict < 0; j++ {
+				a, b := group[i], group[j]
+				if a.pushes && b.pushes && !pathSubset(a.paths, b.paths) && !pathSubset(b.paths, a.paths) {
+					conflict = j
+				}
+			}
+			if conflict < 0 {
+				continue
+			}
+			a, b := group[i], group[conflict]
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypePerformance,
+				Severity:   types.SeverityMedium,
+				Path:       "jobs." + b.job + ".cache.key",
+				Message:    fmt.Sprintf("Jobs '%s' and '%s' upload different paths under the same cache key, so each replaces the other's cache", a.job, b.job),
+				Suggestion: "Give each set of paths its own key, e.g. with key.prefix, or cache the same paths in both jobs",
+				JobName:    b.job,
+			})
+			break
+		}
+	}
+
+	return issues
+}
+
+// jobCache returns the cache a job uses: its own, the nearest template's, or
+// the inherited default or global cache
+func jobCache(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) *parser.Cache {
+	if job.Cache != nil {
+		return job.Cache
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.Cache != nil {
+			return template.Cache
+		}
+	}
+	return effectiveCache(config, job)
+}
+
+// cacheKeyIdentity returns a string that is equal for two jobs exactly when
+// their cache keys are, after expanding job-specific variables. Unset keys
+// get GitLab's default key.
+func cacheKeyIdentity(cache *parser.Cache, expand *strings.Replacer) string {
+	if object := cache.KeyObject(); object != nil {
+		files := append([]string(nil), object.Files...)
+		sort.Strings(files)
+		return "files:" + strings.Join(files, ",") + " prefix:" + expand.Replace(object.Prefix)
+	}
+	if key := cache.KeyString(); key != "" {
+		return expand.Replace(key)
+	}
+	return "default"
+}
+
+// jobVariables expands the predefined variables that differ between jobs of
+// the same pipeline
+func jobVariables(config *parser.GitLabConfig, jobName string) *strings.Replacer {
+	stage := config.JobStage(jobName)
+	return strings.NewReplacer(
+		"${CI_JOB_NAME}", jobName, "$CI_JOB_NAME", jobName,
+		"${CI_JOB_STAGE}", stage, "$CI_JOB_STAGE", stage,
+	)
+}
+
+func normalizedCachePaths(paths []string) []string {
+	normalized := make([]string, 0, len(paths))
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		path = strings.TrimSuffix(strings.TrimPrefix(path, "./"), "/")
+		if !seen[path] {
+			seen[path] = true
+			normalized = append(normalized, path)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// pathSubset reports whether every path in a is also in b
+func pathSubset(a, b []string) bool {
+	inB := make(map[string]bool, len(b))
+	for _, path := range b {
+		inB[path] = true
+	}
+	for _, path := range a {
+		if !inB[path] {
+			return false
+		}
+	}
+	return true
+}
+
+func quoteJobs(jobs []string) string {
+	quoted := make([]string, len(jobs))
+	for i, job := range jobs {
+		quoted[i] = "'" + job + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
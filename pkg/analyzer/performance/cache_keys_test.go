@@ -0,0 +1,118 @@
+package performance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckCacheKeyConflicts(t *testing.T) {
+	tests := []struct {
+		name         string
+		yaml         string
+		wantSeverity []types.Severity
+		wantJob      string
+	}{
+		{
+			name: "same paths under different keys",
+			yaml: `
+test:
+  script: [npm test]
+  cache:
+    key: test-deps
+    paths: [node_modules/]
+lint:
+  script: [npm run lint]
+  cache:
+    key: lint-deps
+    paths: [./node_modules]
+`,
+			wantSeverity: []types.Severity{types.SeverityLow},
+			wantJob:      "lint",
+		},
+		{
+			name: "same key uploading different paths",
+			yaml: `
+build:
+  script: [make]
+  cache:
+    key:
+      files: [go.sum]
+    paths: [.go/]
+test:
+  script: [make test]
+  cache:
+    key:
+      files: [go.sum]
+    paths: [.cache/]
+`,
+			wantSeverity: []types.Severity{types.SeverityMedium},
+			wantJob:      "test",
+		},
+		{
+			name: "shared key and paths",
+			yaml: `
+cache:
+  key: deps
+  paths: [node_modules/]
+test:
+  script: [npm test]
+lint:
+  script: [npm run lint]
+`,
+		},
+		{
+			name: "pull job with fewer paths",
+			yaml: `
+install:
+  script: [npm ci]
+  cache:
+    key: deps
+    paths: [node_modules/, .npm/]
+test:
+  script: [npm test]
+  cache:
+    key: deps
+    paths: [node_modules/]
+    policy: pull
+`,
+		},
+		{
+			name: "per-job keys",
+			yaml: `
+default:
+  cache:
+    key: $CI_JOB_NAME-$CI_COMMIT_REF_SLUG
+    paths: [.pip-cache/]
+test:api:
+  script: [pytest]
+test:workers:
+  script: [pytest]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parser.Parse([]byte(tt.yaml))
+			if err != nil {
+				t.Fatalf("Failed to parse config: %v", err)
+			}
+
+			issues := CheckCacheKeyConflicts(config)
+			if len(issues) != len(tt.wantSeverity) {
+				t.Fatalf("Expected %d issues, got %d: %+v", len(tt.wantSeverity), len(issues), issues)
+			}
+			for i, issue := range issues {
+				if issue.Severity != tt.wantSeverity[i] || issue.JobName != tt.wantJob {
+					t.Errorf("Expected a %s issue for %s, got %s for %s", tt.wantSeverity[i], tt.wantJob, issue.Severity, issue.JobName)
+				}
+				if !strings.HasSuffix(issue.Path, ".cache.key") {
+					t.Errorf("Expected the issue to point at the cache key, got %s", issue.Path)
+				}
+			}
+		})
+	}
+}
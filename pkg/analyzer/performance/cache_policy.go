@@ -0,0 +1,86 @@
+package performance
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// Default heuristics for CheckCachePullPolicy. Both can be overridden through
+// the check's custom_params ("job_patterns" and "cache_write_commands").
+var (
+	defaultCacheConsumerPatterns = []string{"deploy", "test", "lint", "release"}
+	defaultCacheWriteCommands    = []string{
+		"npm install", "npm ci", "yarn install", "yarn --frozen-lockfile", "pnpm install",
+		"pip install", "poetry install", "bundle install", "composer install",
+		"go mod download", "go build", "cargo build", "mvn", "gradle", "make", "build",
+	}
+)
+
+// CheckCachePullPolicy flags jobs that only consume a cache but keep the
+// default pull-push policy, which re-uploads an unchanged cache every run
+func CheckCachePullPolicy(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	jobPatterns := types.StringListParam(params, "job_patterns", defaultCacheConsumerPatterns)
+	writeCommands := types.StringListParam(params, "cache_write_commands", defaultCacheWriteCommands)
+
+	for jobName, job := range config.ConcreteJobs() {
+
+		// Inherited global/default caches are shared with the jobs that
+		// populate them, so only job-level cache declarations are considered
+		cache := job.Cache
+		if cache == nil || len(cache.Paths) == 0 {
+			continue
+		}
+		if cache.Policy != "" && cache.Policy != "pull-push" {
+			continue
+		}
+
+		if !matchesAnyPattern(jobName, job.Stage, jobPatterns) {
+			continue
+		}
+		if scriptWritesCache(job, writeCommands) {
+			continue
+		}
+
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypePerformance,
+			Severity:   types.SeverityLow,
+			Path:       "jobs." + jobName + ".cache.policy",
+			Message:    fmt.Sprintf("Job '%s' only reads its cache but uses the pull-push policy", jobName),
+			Suggestion: "Set 'cache: policy: pull' to skip re-uploading an unchanged cache",
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
+
+func matchesAnyPattern(jobName, stage string, patterns []string) bool {
+	name := strings.ToLower(jobName)
+	stage = strings.ToLower(stage)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if strings.Contains(name, pattern) || strings.Contains(stage, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func scriptWritesCache(job *parser.JobConfig, writeCommands []string) bool {
+	for _, lines := range [][]string{job.BeforeScript, job.Script} {
+		for _, line := range lines {
+			line = strings.ToLower(line)
+			for _, command := range writeCommands {
+				if strings.Contains(line, strings.ToLower(command)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package performance
+
+import (
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckCachePullPolicy(t *testing.T) {
+	nodeCache := func(policy string) *parser.Cache {
+		return &parser.Cache{Key: &parser.CacheKey{Value: "node"}, Paths: []string{"node_modules/"}, Policy: policy}
+	}
+
+	tests := []struct {
+		name         string
+		jobs         map[string]*parser.JobConfig
+		params       map[string]interface{}
+		expectIssues []string
+	}{
+		{
+			name: "deploy job with default policy that only reads",
+			jobs: map[string]*parser.JobConfig{
+				"deploy": {Stage: "deploy", Cache: nodeCache(""), Script: []string{"./deploy.sh"}},
+			},
+			expectIssues: []string{"deploy"},
+		},
+		{
+			name: "explicit pull-push on test job",
+			jobs: map[string]*parser.JobConfig{
+				"unit": {Stage: "test", Cache: nodeCache("pull-push"), Script: []string{"npx jest"}},
+			},
+			expectIssues: []string{"unit"},
+		},
+		{
+			name: "job that installs dependencies writes the cache",
+			jobs: map[string]*parser.JobConfig{
+				"test": {Stage: "test", Cache: nodeCache(""), Script: []string{"npm ci", "npm test"}},
+			},
+		},
+		{
+			name: "pull policy is already optimal",
+			jobs: map[string]*parser.JobConfig{
+				"deploy": {Stage: "deploy", Cache: nodeCache("pull"), Script: []string{"./deploy.sh"}},
+			},
+		},
+		{
+			name: "non-consumer job name is ignored",
+			jobs: map[string]*parser.JobConfig{
+				"package": {Stage: "package", Cache: nodeCache(""), Script: []string{"tar czf app.tgz dist"}},
+			},
+		},
+		{
+			name: "templates are ignored",
+			jobs: map[string]*parser.JobConfig{
+				".deploy": {Cache: nodeCache(""), Script: []string{"./deploy.sh"}},
+			},
+		},
+		{
+			name: "custom job patterns",
+			jobs: map[string]*parser.JobConfig{
+				"package": {Stage: "package", Cache: nodeCache(""), Script: []string{"tar czf app.tgz dist"}},
+				"deploy":  {Stage: "deploy", Cache: nodeCache(""), Script: []string{"./deploy.sh"}},
+			},
+			params:       map[string]interface{}{"job_patterns": []interface{}{"package"}},
+			expectIssues: []string{"package"},
+		},
+		{
+			name: "custom write commands",
+			jobs: map[string]*parser.JobConfig{
+				"deploy": {Stage: "deploy", Cache: nodeCache(""), Script: []string{"./warm-cache.sh"}},
+			},
+			params: map[string]interface{}{"cache_write_commands": []interface{}{"warm-cache"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := CheckCachePullPolicy(&parser.GitLabConfig{Jobs: tt.jobs}, tt.params)
+
+			if len(issues) != len(tt.expectIssues) {
+				t.Fatalf("Expected %d issues, got %d: %v", len(tt.expectIssues), len(issues), issues)
+			}
+			for i, jobName := range tt.expectIssues {
+				if issues[i].JobName != jobName {
+					t.Errorf("Expected issue for job %s, got %s", jobName, issues[i].JobName)
+				}
+				if issues[i].Path != "jobs."+jobName+".cache.policy" {
+					t.Errorf("Unexpected path %s", issues[i].Path)
+				}
+			}
+		})
+	}
+}
@@ -19,7 +19,7 @@ func TestCheckCacheUsage_GlobalCache(t *testing.T) {
 			name: "global cache covers all jobs",
 			config: &parser.GitLabConfig{
 				Cache: &parser.Cache{
-					Key:   "global-cache",
+					Key:   &parser.CacheKey{Value: "global-cache"},
 					Paths: []string{".cache/"},
 				},
 				Jobs: map[string]*parser.JobConfig{
@@ -34,8 +34,8 @@ func TestCheckCacheUsage_GlobalCache(t *testing.T) {
 			name: "complex global cache key",
 			config: &parser.GitLabConfig{
 				Cache: &parser.Cache{
-					Key: map[string]interface{}{
-						"files": []interface{}{"go.mod", "go.sum"},
+					Key: &parser.CacheKey{
+						Files: []string{"go.mod", "go.sum"},
 					},
 					Paths:  []string{".go/pkg/mod/"},
 					Policy: "pull-push",
@@ -54,7 +54,7 @@ func TestCheckCacheUsage_GlobalCache(t *testing.T) {
 			config: &parser.GitLabConfig{
 				Default: &parser.JobConfig{
 					Cache: &parser.Cache{
-						Key:   "default-cache",
+						Key:   &parser.CacheKey{Value: "default-cache"},
 						Paths: []string{".cache/"},
 					},
 				},
@@ -82,14 +82,14 @@ func TestCheckCacheUsage_GlobalCache(t *testing.T) {
 			name: "mixed cache configuration",
 			config: &parser.GitLabConfig{
 				Cache: &parser.Cache{
-					Key:   "global-cache",
+					Key:   &parser.CacheKey{Value: "global-cache"},
 					Paths: []string{".cache/"},
 				},
 				Jobs: map[string]*parser.JobConfig{
 					"job1": {
 						Stage: "test",
 						Cache: &parser.Cache{
-							Key:   "job-specific-cache",
+							Key:   &parser.CacheKey{Value: "job-specific-cache"},
 							Paths: []string{".job-cache/"},
 						},
 					},
@@ -99,6 +99,24 @@ func TestCheckCacheUsage_GlobalCache(t *testing.T) {
 			expectIssues:   0,
 			expectMessages: []string{},
 		},
+		{
+			name: "jobs opting out of default cache",
+			config: &parser.GitLabConfig{
+				Default: &parser.JobConfig{
+					Cache: &parser.Cache{
+						Key:   &parser.CacheKey{Value: "default-cache"},
+						Paths: []string{".cache/"},
+					},
+				},
+				Jobs: map[string]*parser.JobConfig{
+					"job1": {Stage: "test", Inherit: &parser.Inherit{Default: false}},
+					"job2": {Stage: "build", Inherit: &parser.Inherit{Default: []interface{}{"image"}}},
+					"job3": {Stage: "deploy"},
+				},
+			},
+			expectIssues:   1,
+			expectMessages: []string{"More than half of jobs don't use caching"},
+		},
 		{
 			name: "job cache without key",
 			config: &parser.GitLabConfig{
@@ -121,7 +139,7 @@ func TestCheckCacheUsage_GlobalCache(t *testing.T) {
 					"job1": {
 						Stage: "test",
 						Cache: &parser.Cache{
-							Key: "test-key",
+							Key: &parser.CacheKey{Value: "test-key"},
 							// No paths specified
 						},
 					},
@@ -137,8 +155,8 @@ func TestCheckCacheUsage_GlobalCache(t *testing.T) {
 					"job1": {
 						Stage: "test",
 						Cache: &parser.Cache{
-							Key: map[string]interface{}{
-								"files": []interface{}{"package.json", "yarn.lock"},
+							Key: &parser.CacheKey{
+								Files: []string{"package.json", "yarn.lock"},
 							},
 							Paths: []string{"node_modules/"},
 						},
@@ -187,19 +205,18 @@ func TestCheckCacheUsage_GlobalCache(t *testing.T) {
 func TestCacheKeyValidation(t *testing.T) {
 	tests := []struct {
 		name  string
-		key   interface{}
+		key   *parser.CacheKey
 		valid bool
 	}{
-		{"string key", "valid-key", true},
-		{"empty string key", "", false},
+		{"string key", &parser.CacheKey{Value: "valid-key"}, true},
+		{"empty string key", &parser.CacheKey{}, false},
 		{"nil key", nil, false},
-		{"complex key with files", map[string]interface{}{
-			"files": []interface{}{"go.mod", "go.sum"},
+		{"complex key with files", &parser.CacheKey{
+			Files: []string{"go.mod", "go.sum"},
 		}, true},
-		{"complex key with prefix", map[string]interface{}{
-			"prefix": "my-prefix",
+		{"complex key with prefix", &parser.CacheKey{
+			Prefix: "my-prefix",
 		}, true},
-		{"invalid type", 123, false},
 	}
 
 	for _, tt := range tests {
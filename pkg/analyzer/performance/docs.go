@@ -0,0 +1,306 @@
+package performance
+
+import "github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+
+// checkDocs documents each performance check for `gitlab-smith explain`
+var checkDocs = map[string]types.CheckDoc{
+	"cache_usage": {
+		Rationale: "Without a cache every job downloads its dependencies from scratch. A cache without a key is shared by every job and branch, so unrelated jobs overwrite each other's files, and a cache without paths stores nothing.",
+		Example: types.CheckExample{
+			Before: `test:
+  cache: {}
+  script: npm ci && npm test`,
+			After: `test:
+  cache:
+    key:
+      files: [package-lock.json]
+    paths: [.npm/]
+  script: npm ci --cache .npm && npm test`,
+		},
+		Related: []string{"cache_key_conflicts", "cache_pull_policy", "duplicated_cache_config"},
+	},
+	"artifact_expiration": {
+		Rationale: "Artifacts without expire_in are kept until the project's default expiry, often forever. They use up storage quota and slow down artifact listing for every pipeline that follows.",
+		Example: types.CheckExample{
+			Before: `build:
+  artifacts:
+    paths: [dist/]`,
+			After: `build:
+  artifacts:
+    paths: [dist/]
+    expire_in: 1 week`,
+		},
+		Related: []string{"large_artifact_paths", "needs_artifacts"},
+	},
+	"dependency_chains": {
+		Rationale: "A job that depends on many others can't start until the slowest of them finishes, and downloads all of their artifacts. Long chains serialize the pipeline and stretch its critical path.",
+		Example: types.CheckExample{
+			Before: `deploy:
+  dependencies: [build_a, build_b, build_c, lint, test_a, test_b]`,
+			After: `deploy:
+  needs: [build_a, build_b, build_c]`,
+		},
+		Related: []string{"unnecessary_dependencies", "missing_needs"},
+	},
+	"unnecessary_dependencies": {
+		Rationale: "Jobs download the artifacts of every job in earlier stages by default. Listing dependencies that would be inferred anyway adds configuration to maintain without changing what the job receives.",
+		Example: types.CheckExample{
+			Before: `test:
+  stage: test
+  dependencies: [build]
+  script: make test`,
+			After: `test:
+  stage: test
+  script: make test`,
+		},
+		Related: []string{"dependency_chains", "missing_needs"},
+	},
+	"matrix_opportunities": {
+		Rationale: "Jobs that differ only in a version or platform repeat the same configuration. A parallel:matrix generates them from one definition, so a change is made once and applies to every variant.",
+		Example: types.CheckExample{
+			Before: `test_node18:
+  image: node:18
+  script: npm test
+test_node20:
+  image: node:20
+  script: npm test`,
+			After: `test:
+  image: node:$NODE_VERSION
+  script: npm test
+  parallel:
+    matrix:
+      - NODE_VERSION: ["18", "20"]`,
+		},
+		Related: []string{"duplicated_code", "parallel_resource_group"},
+	},
+	"missing_needs": {
+		Rationale: "Without needs a job waits for every job in earlier stages to finish. Declaring needs lets it start as soon as the jobs it actually uses are done, which shortens the pipeline.",
+		Example: types.CheckExample{
+			Before: `test:
+  stage: test
+  dependencies: [build]`,
+			After: `test:
+  stage: test
+  needs: [build]`,
+		},
+		Related: []string{"redundant_needs", "needs_artifacts", "needs_limit"},
+	},
+	"redundant_needs": {
+		Rationale: "A need that is already implied through another need doesn't change when the job starts. It makes the dependency graph harder to read and has to be kept in sync when the pipeline changes.",
+		Example: types.CheckExample{
+			Before: `deploy:
+  needs: [build, test]  # test already needs build`,
+			After: `deploy:
+  needs: [test]`,
+		},
+		Related: []string{"missing_needs", "dependency_chains"},
+	},
+	"workflow_optimization": {
+		Rationale: "When most jobs repeat the same branch or merge request rules, the pipeline decision is spread over every job. workflow:rules decides once whether a pipeline runs, and keeps job rules for what is specific to the job.",
+		Example: types.CheckExample{
+			Before: `build:
+  rules:
+    - if: $CI_COMMIT_BRANCH == "main"
+test:
+  rules:
+    - if: $CI_COMMIT_BRANCH == "main"`,
+			After: `workflow:
+  rules:
+    - if: $CI_COMMIT_BRANCH == "main"
+
+build:
+  script: make
+test:
+  script: make test`,
+		},
+		Related: []string{"verbose_rules", "workflow_skipped_jobs"},
+	},
+	"missing_interruptible": {
+		Rationale: "When a merge request receives a new commit, the pipeline for the old commit is obsolete. Jobs that aren't interruptible keep running and hold runners that the new pipeline is waiting for. The check is off by default, since short pipelines gain little from cancellation; enable it where merge request pipelines tie up runners.",
+		Example: types.CheckExample{
+			Before: `test:
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+  script: make test`,
+			After: `test:
+  interruptible: true
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+  script: make test`,
+		},
+		Related: []string{"auto_cancel_on_new_commit"},
+	},
+	"auto_cancel_on_new_commit": {
+		Rationale: "By default GitLab only cancels a redundant pipeline while all its running jobs are interruptible. Setting on_new_commit to interruptible cancels the interruptible jobs even when others are running, so long merge request jobs stop using runners sooner.",
+		Example: types.CheckExample{
+			Before: `workflow:
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"`,
+			After: `workflow:
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+  auto_cancel:
+    on_new_commit: interruptible`,
+		},
+		Related: []string{"missing_interruptible"},
+	},
+	"parallel_resource_group": {
+		Rationale: "A resource_group lets only one job in the group run at a time. Parallel instances that share one are run one after another, so parallel brings no speedup while still multiplying the job.",
+		Example: types.CheckExample{
+			Before: `test:
+  parallel: 4
+  resource_group: test-db`,
+			After: `test:
+  parallel: 4
+  resource_group: test-db-$CI_NODE_INDEX`,
+		},
+		Related: []string{"deploy_resource_group", "matrix_opportunities"},
+	},
+	"parallel_without_matrix": {
+		Rationale: "parallel without a matrix starts that many identical copies of the job. Unless the script splits its work by CI_NODE_INDEX they all do the same thing, and a high count can take every runner the project has. GitLab rejects counts above 200.",
+		Example: types.CheckExample{
+			Before: `test:
+  parallel: 50
+  script: make test`,
+			After: `test:
+  parallel: 5
+  script: ./split-tests.sh $CI_NODE_INDEX $CI_NODE_TOTAL`,
+		},
+		Related: []string{"parallel_resource_group", "matrix_opportunities", "redundant_parallel"},
+	},
+	"large_artifact_paths": {
+		Rationale: "An artifact path that matches the whole project uploads the source tree, dependencies and build output on every run. Uploads and downloads slow every job that uses the artifacts, and storage fills up.",
+		Example: types.CheckExample{
+			Before: `build:
+  artifacts:
+    paths: [./]`,
+			After: `build:
+  artifacts:
+    paths: [dist/]
+    expire_in: 1 week`,
+		},
+		Related: []string{"artifact_expiration", "cache_artifact_overlap"},
+	},
+	"cache_artifact_overlap": {
+		Rationale: "A directory that is both cached and uploaded as an artifact is transferred twice. Caches are for speeding up later runs and artifacts for passing results between jobs; one directory rarely needs both.",
+		Example: types.CheckExample{
+			Before: `build:
+  cache:
+    paths: [node_modules/]
+  artifacts:
+    paths: [node_modules/, dist/]`,
+			After: `build:
+  cache:
+    paths: [node_modules/]
+  artifacts:
+    paths: [dist/]`,
+		},
+		Related: []string{"cache_usage", "large_artifact_paths"},
+	},
+	"cache_key_conflicts": {
+		Rationale: "Jobs that cache different paths under the same key overwrite each other's cache, so each run restores the wrong files. Jobs that cache the same paths under different keys store the same content twice and never share it.",
+		Example: types.CheckExample{
+			Before: `build:
+  cache: {key: deps, paths: [node_modules/]}
+lint:
+  cache: {key: deps, paths: [.eslintcache]}`,
+			After: `build:
+  cache: {key: node-modules, paths: [node_modules/]}
+lint:
+  cache: {key: eslint, paths: [.eslintcache]}`,
+		},
+		Related: []string{"cache_usage", "duplicated_cache_config"},
+	},
+	"needs_artifacts": {
+		Rationale: "By default a job downloads the artifacts of every job it needs. When none of those files are used, the download only adds time to the start of the job.",
+		Example: types.CheckExample{
+			Before: `deploy:
+  needs: [test]
+  script: ./deploy.sh`,
+			After: `deploy:
+  needs:
+    - job: test
+      artifacts: false
+  script: ./deploy.sh`,
+		},
+		Related: []string{"missing_needs", "artifact_expiration"},
+	},
+	"cache_pull_policy": {
+		Rationale: "With the default pull-push policy every job uploads its cache when it finishes, even when it never changes it. Jobs that only read the cache should use policy: pull to skip the upload.",
+		Example: types.CheckExample{
+			Before: `test:
+  cache:
+    key: deps
+    paths: [node_modules/]`,
+			After: `test:
+  cache:
+    key: deps
+    paths: [node_modules/]
+    policy: pull`,
+		},
+		Related: []string{"cache_usage", "cache_key_conflicts"},
+	},
+	"cache_key_files": {
+		Rationale: "A cache under a fixed key is restored even after the lock file changes, so jobs install on top of stale dependencies until someone bumps the key. key.files derives the key from the lock file instead, but when none of its files exist GitLab quietly uses the key 'default'.",
+		Example: types.CheckExample{
+			Before: `build:
+  cache:
+    key: node
+    paths: [node_modules/]`,
+			After: `build:
+  cache:
+    key:
+      files: [package-lock.json]
+      prefix: node
+    paths: [node_modules/]`,
+		},
+		Related: []string{"cache_usage", "cache_key_conflicts"},
+	},
+	"git_clone_strategy": {
+		Rationale: "Every job fetches the repository before its script runs. GIT_DEPTH: 0 fetches the whole history, which takes longest on old repositories, and jobs that only deploy artifacts or call remote APIs don't need the sources at all.",
+		Example: types.CheckExample{
+			Before: `variables:
+  GIT_DEPTH: 0
+
+deploy:
+  environment: production
+  needs: [build]
+  script: aws s3 sync dist/ s3://bucket`,
+			After: `variables:
+  GIT_DEPTH: 10
+
+deploy:
+  environment: production
+  needs: [build]
+  variables:
+    GIT_STRATEGY: none
+  script: aws s3 sync dist/ s3://bucket`,
+		},
+		Related: []string{"needs_artifacts", "cache_usage"},
+	},
+	"empty_needs": {
+		Rationale: "A job without needs waits until every job of the earlier stages has finished, even when it uses none of their artifacts. Linters, unit tests and other jobs that only need the repository can start as soon as the pipeline is created with needs: [], which shortens the pipeline when they sit on its critical path.",
+		Example: types.CheckExample{
+			Before: `stages: [build, test]
+
+build:
+  stage: build
+  script: make build
+
+lint:
+  stage: test
+  script: make lint`,
+			After: `stages: [build, test]
+
+build:
+  stage: build
+  script: make build
+
+lint:
+  stage: test
+  needs: []
+  script: make lint`,
+		},
+		Related: []string{"missing_needs", "redundant_needs", "dependency_chains"},
+	},
+}
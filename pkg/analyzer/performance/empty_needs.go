@@ -0,0 +1,177 @@
+package performance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+	"github.com/wonderfulspam/gitlab-smith/pkg/renderer"
+)
+
+// defaultGateKeywords mark jobs that rely on the stage order to run only
+// after everything before them passed, such as deployments. They can be
+// overridden through the check's custom_params ("gate_keywords").
+var defaultGateKeywords = []string{"deploy", "release", "publish", "promote", "rollout"}
+
+// CheckEmptyNeeds suggests `needs: []` for jobs in later stages that don't
+// use anything from the jobs before them: they set neither needs nor
+// dependencies and no earlier job passes artifacts, or they opt out of
+// artifacts with `dependencies: []`, and they don't restore a cache that an
+// earlier job writes. Such jobs still wait for every earlier stage.
+// Deployments, triggers, jobs named like gate_keywords and jobs that run on
+// failure, manually or delayed are left alone, since the stage order is what
+// keeps them behind the jobs that verify the pipeline.
+func CheckEmptyNeeds(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	gateKeywords := types.StringListParam(params, "gate_keywords", defaultGateKeywords)
+
+	stageIndex := make(map[string]int)
+	for i, stage := range renderer.PipelineStages(config) {
+		stageIndex[stage] = i
+	}
+
+	jobNames := config.ConcreteJobNames()
+
+	baseline := renderer.PipelineCriticalPath(config).Duration
+
+	for _, jobName := range jobNames {
+		job := config.Jobs[jobName]
+		stage := config.JobStage(jobName)
+		if stage == ".post" || job.Environment != nil || job.Trigger != nil || !runsOnSuccess(job) {
+			continue
+		}
+		if gateKeyword(jobName, gateKeywords) || gateKeyword(stage, gateKeywords) {
+			continue
+		}
+		if jobNeeds(config, jobName, job) != nil {
+			continue
+		}
+		dependencies := jobDependencies(config, jobName, job)
+		if len(dependencies) > 0 {
+			continue
+		}
+
+		readsCache := ""
+		if cache := jobCache(config, jobName, job); cache != nil && cache.Policy != "push" {
+			readsCache = cacheKeyIdentity(cache, jobVariables(config, jobName))
+		}
+
+		var earlierStages []string
+		passesArtifacts, warmsCache := false, false
+		for _, other := range jobNames {
+			otherStage := config.JobStage(other)
+			if stageIndex[otherStage] >= stageIndex[stage] {
+				continue
+			}
+			if !types.ContainsString(earlierStages, otherStage) {
+				earlierStages = append(earlierStages, otherStage)
+			}
+			if hasArtifacts(config, other) {
+				passesArtifacts = true
+			}
+			if readsCache != "" && writesCache(config, other, readsCache) {
+				warmsCache = true
+			}
+		}
+		if len(earlierStages) == 0 || warmsCache {
+			continue
+		}
+		// Without dependencies the job downloads the artifacts of every
+		// earlier job, which it may rely on
+		if dependencies == nil && passesArtifacts {
+			continue
+		}
+		sort.Slice(earlierStages, func(i, j int) bool {
+			return stageIndex[earlierStages[i]] < stageIndex[earlierStages[j]]
+		})
+
+		suggestion := fmt.Sprintf("Add 'needs: []' to '%s' so it starts as soon as the pipeline is created", jobName)
+		saved := baseline - renderer.PipelineCriticalPath(withEmptyNeeds(config, jobName)).Duration
+		if saved >= 1 {
+			suggestion += fmt.Sprintf(" (~%.0fs faster pipeline)", saved)
+		}
+
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypePerformance,
+			Severity:   types.SeverityLow,
+			Path:       "jobs." + jobName + ".needs",
+			Message:    fmt.Sprintf("Job '%s' uses nothing from earlier jobs but waits for the %s stage(s)", jobName, quoteNames(earlierStages)),
+			Suggestion: suggestion,
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
+
+// runsOnSuccess reports whether the job only ever runs automatically once
+// the jobs before it succeed, the only case in which needs: [] doesn't
+// change when it runs
+func runsOnSuccess(job *parser.JobConfig) bool {
+	gated := func(when string) bool {
+		return when == "manual" || when == "delayed" || when == "on_failure" || when == "always"
+	}
+	if gated(job.When) {
+		return false
+	}
+	for _, rule := range job.Rules {
+		if gated(rule.When) {
+			return false
+		}
+	}
+	return true
+}
+
+// writesCache reports whether a job uploads the cache with the given key
+// identity
+func writesCache(config *parser.GitLabConfig, jobName, identity string) bool {
+	cache := jobCache(config, jobName, config.Jobs[jobName])
+	return cache != nil && cache.Policy != "pull" && cacheKeyIdentity(cache, jobVariables(config, jobName)) == identity
+}
+
+// gateKeyword reports whether a word of name starts with one of keywords
+func gateKeyword(name string, keywords []string) bool {
+	for _, word := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !('a' <= r && r <= 'z') && !('0' <= r && r <= '9')
+	}) {
+		for _, keyword := range keywords {
+			if strings.HasPrefix(word, strings.ToLower(keyword)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jobDependencies returns the job's dependencies, or those it inherits from
+// the nearest template it extends. An empty, non-nil list means the job
+// downloads no artifacts.
+func jobDependencies(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) []string {
+	if job.Dependencies != nil {
+		return job.Dependencies
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.Dependencies != nil {
+			return template.Dependencies
+		}
+	}
+	return nil
+}
+
+// withEmptyNeeds returns a copy of the configuration in which the job has
+// `needs: []`, leaving the original untouched
+func withEmptyNeeds(config *parser.GitLabConfig, jobName string) *parser.GitLabConfig {
+	copied := *config
+	copied.Jobs = make(map[string]*parser.JobConfig, len(config.Jobs))
+	for name, job := range config.Jobs {
+		copied.Jobs[name] = job
+	}
+	job := *config.Jobs[jobName]
+	job.Needs = []parser.Need{}
+	copied.Jobs[jobName] = &job
+	return &copied
+}
@@ -0,0 +1,139 @@
+package performance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckEmptyNeeds(t *testing.T) {
+	config, err := parser.Parse([]byte(`
+stages: [build, test, deploy]
+
+build:
+  stage: build
+  script: [make build, sleep 60]
+
+lint:
+  stage: test
+  script: [make lint]
+
+# Opts out of artifacts altogether
+docs:
+  stage: test
+  dependencies: []
+  script: [make docs]
+
+unit:
+  stage: test
+  needs: []
+  script: [make test]
+
+integration:
+  stage: test
+  dependencies: [build]
+  script: [make integration]
+
+nightly:
+  stage: test
+  script: [make nightly]
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "schedule"
+      when: manual
+
+cleanup:
+  stage: deploy
+  script: [./cleanup.sh]
+  dependencies: []
+
+production:
+  stage: deploy
+  script: [./deploy.sh]
+  dependencies: []
+  environment: production
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	issues := CheckEmptyNeeds(config, nil)
+
+	var flagged []string
+	for _, issue := range issues {
+		flagged = append(flagged, issue.JobName)
+	}
+	// cleanup runs in a deploy stage, which gates on everything before it
+	if strings.Join(flagged, ",") != "docs,lint" {
+		t.Fatalf("Expected docs and lint to be flagged, got %v", flagged)
+	}
+
+	lint := issues[1]
+	if lint.Path != "jobs.lint.needs" || !strings.Contains(lint.Message, "'build' stage") {
+		t.Errorf("Unexpected issue %+v", lint)
+	}
+	if !strings.Contains(lint.Suggestion, "needs: []") {
+		t.Errorf("Expected needs: [] to be suggested, got %s", lint.Suggestion)
+	}
+
+	// Custom gate keywords replace the defaults
+	issues = CheckEmptyNeeds(config, map[string]interface{}{"gate_keywords": []interface{}{"lint"}})
+	flagged = nil
+	for _, issue := range issues {
+		flagged = append(flagged, issue.JobName)
+	}
+	if strings.Join(flagged, ",") != "cleanup,docs" {
+		t.Errorf("Expected cleanup and docs to be flagged with custom keywords, got %v", flagged)
+	}
+}
+
+func TestCheckEmptyNeeds_EarlierInputs(t *testing.T) {
+	t.Run("artifacts of earlier jobs", func(t *testing.T) {
+		config := &parser.GitLabConfig{
+			Stages: []string{"build", "test"},
+			Jobs: map[string]*parser.JobConfig{
+				"build": {Stage: "build", Script: []string{"make"}, Artifacts: &parser.Artifacts{Paths: []string{"bin/"}}},
+				"test":  {Stage: "test", Script: []string{"./bin/app --test"}},
+			},
+		}
+		if issues := CheckEmptyNeeds(config, nil); len(issues) != 0 {
+			t.Errorf("Expected no issues for a job that downloads earlier artifacts, got %v", issues)
+		}
+	})
+
+	t.Run("cache warmed by an earlier job", func(t *testing.T) {
+		config := &parser.GitLabConfig{
+			Stages: []string{"prepare", "test"},
+			Cache:  &parser.Cache{Key: &parser.CacheKey{Files: []string{"go.sum"}}, Paths: []string{".go/"}},
+			Jobs: map[string]*parser.JobConfig{
+				"prepare": {Stage: "prepare", Script: []string{"go mod download"}},
+				"test":    {Stage: "test", Script: []string{"go test ./..."}},
+			},
+		}
+		if issues := CheckEmptyNeeds(config, nil); len(issues) != 0 {
+			t.Errorf("Expected no issues for a job that restores an earlier job's cache, got %v", issues)
+		}
+
+		config.Jobs["test"].Cache = &parser.Cache{Key: &parser.CacheKey{Value: "tools"}, Paths: []string{".tools/"}}
+		if issues := CheckEmptyNeeds(config, nil); len(issues) != 1 {
+			t.Errorf("Expected an issue once the job uses its own cache, got %v", issues)
+		}
+	})
+
+	t.Run("estimated savings", func(t *testing.T) {
+		config := &parser.GitLabConfig{
+			Stages: []string{"build", "test"},
+			Jobs: map[string]*parser.JobConfig{
+				"build": {Stage: "build", Script: []string{"make", "sleep 120"}},
+				"lint":  {Stage: "test", Script: []string{"make lint", "sleep 300"}},
+			},
+		}
+		issues := CheckEmptyNeeds(config, nil)
+		if len(issues) != 1 || !strings.Contains(issues[0].Suggestion, "faster pipeline") {
+			t.Errorf("Expected the saving on the critical path to be estimated, got %v", issues)
+		}
+		if config.Jobs["lint"].Needs != nil {
+			t.Error("Expected the configuration to be left untouched")
+		}
+	})
+}
@@ -0,0 +1,222 @@
+package performance
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// Default heuristics for CheckGitCloneStrategy. Both can be overridden
+// through the check's custom_params ("history_commands" and
+// "repo_free_commands").
+var (
+	defaultHistoryCommands = []string{
+		"git log", "git describe", "git rev-list", "git merge-base", "git blame",
+		"git shortlog", "git diff", "git tag", "git fetch", "git checkout",
+		"git rebase", "git merge", "git cherry", "semantic-release", "gitversion",
+		"git-cliff", "standard-version", "conventional-changelog", "commitlint",
+		"lerna", "nx affected", "sonar-scanner", "gitleaks", "trufflehog",
+	}
+	defaultRepoFreeCommands = []string{
+		"echo", "printf", "sleep", "export", "true", "curl", "wget", "aws",
+		"gsutil", "az", "gcloud", "kubectl", "helm", "rsync", "scp", "ssh",
+		"release-cli",
+	}
+)
+
+// CheckGitCloneStrategy flags jobs that fetch more of the repository than
+// they use: full clones (GIT_DEPTH: 0) for jobs whose scripts don't look at
+// the history, and deploy jobs that only run remote commands or use
+// downloaded artifacts but still fetch the sources instead of setting
+// GIT_STRATEGY: none
+func CheckGitCloneStrategy(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	historyCommands := types.StringListParam(params, "history_commands", defaultHistoryCommands)
+	repoFreeCommands := types.StringListParam(params, "repo_free_commands", defaultRepoFreeCommands)
+
+	var inheritingFullClone []string
+
+	for _, jobName := range config.ConcreteJobNames() {
+		job := config.Jobs[jobName]
+		if job.Trigger != nil {
+			continue
+		}
+		scripts := jobScripts(config, jobName, job)
+		if len(scripts) == 0 {
+			continue
+		}
+		if config.GitStrategy(jobName) == "none" {
+			continue
+		}
+
+		if depth, ok := config.GitDepth(jobName); ok && depth == 0 && !scriptsContainAny(scripts, historyCommands) {
+			if setsVariable(config, jobName, "GIT_DEPTH") {
+				issues = append(issues, types.Issue{
+					Type:       types.IssueTypePerformance,
+					Severity:   types.SeverityMedium,
+					Path:       "jobs." + jobName + ".variables.GIT_DEPTH",
+					Message:    fmt.Sprintf("Job '%s' clones the full history with GIT_DEPTH: 0 but its scripts don't use it", jobName),
+					Suggestion: "Remove GIT_DEPTH: 0 or set a shallow depth such as GIT_DEPTH: 10",
+					JobName:    jobName,
+				})
+			} else {
+				inheritingFullClone = append(inheritingFullClone, jobName)
+			}
+		}
+
+		if needsRepository(config, jobName, job, scripts, repoFreeCommands) {
+			continue
+		}
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypePerformance,
+			Severity:   types.SeverityLow,
+			Path:       "jobs." + jobName + ".variables.GIT_STRATEGY",
+			Message:    fmt.Sprintf("Job '%s' fetches the repository but only runs remote commands or uses downloaded artifacts", jobName),
+			Suggestion: "Set 'GIT_STRATEGY: none' to skip fetching the sources",
+			JobName:    jobName,
+		})
+	}
+
+	if len(inheritingFullClone) > 0 {
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypePerformance,
+			Severity:   types.SeverityMedium,
+			Path:       "variables.GIT_DEPTH",
+			Message:    fmt.Sprintf("Global GIT_DEPTH: 0 clones the full history for %d job(s) whose scripts don't use it: %s", len(inheritingFullClone), quoteJobs(inheritingFullClone)),
+			Suggestion: "Set a shallow GIT_DEPTH globally and GIT_DEPTH: 0 only on the jobs that need the history",
+		})
+	}
+
+	return issues
+}
+
+// setsVariable reports whether the job or a template it extends sets the
+// variable itself, rather than inheriting it from the global variables
+func setsVariable(config *parser.GitLabConfig, jobName, name string) bool {
+	if _, exists := config.Jobs[jobName].Variables[name]; exists {
+		return true
+	}
+	for _, template := range config.ExtendsChain(jobName) {
+		if _, exists := config.Jobs[template].Variables[name]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+// needsRepository reports whether a job may use the repository's files. Only
+// deploy jobs and jobs downloading artifacts are considered repository-free,
+// and only when every command they run is a known remote command whose file
+// arguments, if any, are downloaded artifacts. Jobs with a
+// pre_get_sources_script hook customize the fetch and are left alone.
+func needsRepository(config *parser.GitLabConfig, jobName string, job *parser.JobConfig, scripts, repoFreeCommands []string) bool {
+	if hooks := config.JobHooks(jobName); hooks != nil && len(hooks.PreGetSourcesScript) > 0 {
+		return true
+	}
+
+	artifactPaths := downloadedArtifactPaths(config, jobName, job)
+	if job.Environment == nil && len(artifactPaths) == 0 {
+		return true
+	}
+
+	if config.Default != nil {
+		if job.InheritsDefault("before_script") {
+			scripts = append(scripts, config.Default.BeforeScript...)
+		}
+		if job.InheritsDefault("after_script") {
+			scripts = append(scripts, config.Default.AfterScript...)
+		}
+	}
+
+	for _, line := range scripts {
+		if strings.Contains(line, "$(") || strings.Contains(line, "`") {
+			return true
+		}
+		for _, command := range splitCommands(line) {
+			fields := strings.Fields(command)
+			if len(fields) == 0 {
+				continue
+			}
+			if !types.ContainsString(repoFreeCommands, fields[0]) && !strings.Contains(fields[0], "=") {
+				return true
+			}
+			for _, argument := range fields[1:] {
+				if referencesLocalFile(argument) && !underAnyPath(argument, artifactPaths) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// downloadedArtifactPaths returns the artifact paths of the upstream jobs
+// the job downloads artifacts from through needs or dependencies
+func downloadedArtifactPaths(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) []string {
+	var upstreams []string
+	for _, need := range jobNeeds(config, jobName, job) {
+		if need.Job != "" && need.DownloadsArtifacts() && !need.IsExternal() {
+			upstreams = append(upstreams, need.Job)
+		}
+	}
+	upstreams = append(upstreams, job.Dependencies...)
+
+	var paths []string
+	for _, upstream := range upstreams {
+		upstreamJob := config.Jobs[upstream]
+		if upstreamJob == nil {
+			continue
+		}
+		if artifacts := resolvedArtifacts(config, upstream, upstreamJob); artifacts != nil {
+			paths = append(paths, artifacts.Paths...)
+		}
+	}
+	return paths
+}
+
+// splitCommands splits a script line on the shell's command separators
+func splitCommands(line string) []string {
+	return strings.FieldsFunc(line, func(r rune) bool {
+		return r == ';' || r == '|' || r == '&'
+	})
+}
+
+// referencesLocalFile reports whether a command argument looks like a
+// relative path. URLs, absolute paths, flags and variables aren't.
+func referencesLocalFile(argument string) bool {
+	argument = strings.Trim(argument, `"'`)
+	if argument == "" || strings.Contains(argument, "://") {
+		return false
+	}
+	switch argument[0] {
+	case '-', '$', '/', '~':
+		return false
+	}
+	// host:path arguments of scp and rsync
+	if colon := strings.Index(argument, ":"); colon >= 0 && colon < strings.Index(argument, "/") {
+		return false
+	}
+	return strings.HasPrefix(argument, ".") || strings.Contains(argument, "/")
+}
+
+// underAnyPath reports whether a relative path lies within one of the
+// artifact paths
+func underAnyPath(argument string, paths []string) bool {
+	argument = normalizeArtifactPath(strings.Trim(argument, `"'`))
+	for _, p := range paths {
+		p = strings.TrimSuffix(normalizeArtifactPath(p), "/")
+		if wildcard := strings.IndexAny(p, "*?["); wildcard >= 0 {
+			p = strings.TrimSuffix(p[:wildcard], "/")
+		}
+		if p == "" || p == "." {
+			continue
+		}
+		if argument == p || strings.HasPrefix(argument, p+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package performance
+
+import (
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckGitCloneStrategy(t *testing.T) {
+	build := &parser.JobConfig{
+		Stage:     "build",
+		Script:    []string{"make dist"},
+		Artifacts: &parser.Artifacts{Paths: []string{"dist/"}},
+	}
+	production := &parser.Environment{Name: "production"}
+
+	tests := []struct {
+		name       string
+		config     *parser.GitLabConfig
+		params     map[string]interface{}
+		expectPath []string
+	}{
+		{
+			name: "deploy job syncing artifacts fetches the repository",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"build": build,
+				"deploy": {
+					Environment: production,
+					Needs:       []parser.Need{{Job: "build"}},
+					Script:      []string{"aws s3 sync ./dist/ s3://bucket --delete"},
+				},
+			}},
+			expectPath: []string{"jobs.deploy.variables.GIT_STRATEGY"},
+		},
+		{
+			name: "deploy job already skips the fetch",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"build": build,
+				"deploy": {
+					Environment: production,
+					Needs:       []parser.Need{{Job: "build"}},
+					Variables:   map[string]interface{}{"GIT_STRATEGY": "none"},
+					Script:      []string{"aws s3 sync dist/ s3://bucket"},
+				},
+			}},
+		},
+		{
+			name: "deploy job running a repository script",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"deploy": {Environment: production, Script: []string{"./deploy.sh"}},
+			}},
+		},
+		{
+			name: "deploy job applying repository manifests",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"build": build,
+				"deploy": {
+					Environment: production,
+					Needs:       []parser.Need{{Job: "build"}},
+					Script:      []string{"kubectl apply -f k8s/"},
+				},
+			}},
+		},
+		{
+			name: "job with a pre_get_sources_script hook",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"notify": {
+					Environment: production,
+					Hooks:       &parser.Hooks{PreGetSourcesScript: []string{"git config --global core.longpaths true"}},
+					Script:      []string{"curl -X POST $WEBHOOK_URL"},
+				},
+			}},
+		},
+		{
+			name: "non-deploy job without artifacts needs the repository",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"ping": {Script: []string{"curl -sf https://example.com/health"}},
+			}},
+		},
+		{
+			name: "job-level full clone without history commands",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"test": {Variables: map[string]interface{}{"GIT_DEPTH": 0}, Script: []string{"make test"}},
+			}},
+			expectPath: []string{"jobs.test.variables.GIT_DEPTH"},
+		},
+		{
+			name: "full clone used for versioning",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"release": {Variables: map[string]interface{}{"GIT_DEPTH": "0"}, Script: []string{"npx semantic-release"}},
+			}},
+		},
+		{
+			name: "global full clone is reported once",
+			config: &parser.GitLabConfig{
+				Variables: map[string]interface{}{"GIT_DEPTH": "0"},
+				Jobs: map[string]*parser.JobConfig{
+					"lint":      {Script: []string{"make lint"}},
+					"test":      {Script: []string{"make test"}},
+					"changelog": {Script: []string{"git log --oneline > CHANGELOG"}},
+				},
+			},
+			expectPath: []string{"variables.GIT_DEPTH"},
+		},
+		{
+			name: "custom history commands",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"version": {Variables: map[string]interface{}{"GIT_DEPTH": "0"}, Script: []string{"./ci/version.sh"}},
+			}},
+			params: map[string]interface{}{"history_commands": []interface{}{"version.sh"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := CheckGitCloneStrategy(tt.config, tt.params)
+
+			if len(issues) != len(tt.expectPath) {
+				t.Fatalf("Expected %d issues, got %d: %v", len(tt.expectPath), len(issues), issues)
+			}
+			for i, path := range tt.expectPath {
+				if issues[i].Path != path {
+					t.Errorf("Expected issue at %s, got %s", path, issues[i].Path)
+				}
+			}
+		})
+	}
+}
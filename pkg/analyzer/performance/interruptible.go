@@ -0,0 +1,164 @@
+package performance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+	"github.com/wonderfulspam/gitlab-smith/pkg/renderer"
+)
+
+// CheckMissingInterruptible flags configs that run merge request pipelines
+// without marking any job interruptible, so superseded pipelines keep
+// occupying runners after new commits are pushed. Deploy and release jobs
+// aren't counted.
+func CheckMissingInterruptible(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	if config.Workflow != nil && config.Workflow.AutoCancel != nil {
+		return issues
+	}
+	if config.Default != nil && config.Default.Interruptible != nil {
+		return issues
+	}
+	for _, job := range config.Jobs {
+		if job != nil && job.Interruptible != nil {
+			return issues
+		}
+	}
+
+	if !createsMergeRequestPipelines(config) {
+		return issues
+	}
+
+	ctx := parser.MergeRequestPipelineContext("feature")
+	created, workflowVars := config.EvaluateWorkflow(ctx)
+	if !created {
+		return issues
+	}
+
+	mrJobs := 0
+	for jobName, job := range config.ConcreteJobs() {
+		if !isDeployJob(config, jobName) && config.JobRuns(job, ctx, workflowVars) {
+			mrJobs++
+		}
+	}
+	if mrJobs == 0 {
+		return issues
+	}
+
+	issues = append(issues, types.Issue{
+		Type:       types.IssueTypePerformance,
+		Severity:   types.SeverityMedium,
+		Path:       "default.interruptible",
+		Message:    fmt.Sprintf("%d jobs run in merge request pipelines but none are interruptible", mrJobs),
+		Suggestion: "Set 'interruptible: true' under 'default:' (and 'false' on deploy jobs) so outdated pipelines are cancelled on new commits",
+	})
+
+	return issues
+}
+
+// isDeployJob reports whether a job deploys or releases, which interruptible
+// doesn't apply to: cancelling it halfway can leave an environment broken
+func isDeployJob(config *parser.GitLabConfig, jobName string) bool {
+	if config.JobEnvironment(jobName) != nil {
+		return true
+	}
+	stage := config.JobStage(jobName)
+	return stage == "deploy" || stage == "release"
+}
+
+// defaultLongJobSeconds is the estimated duration, on the pipeline
+// simulator's scale, from which a job counts as long. Overridable through
+// custom_params ("min_duration_seconds").
+const defaultLongJobSeconds = 60
+
+// CheckAutoCancelOnNewCommit suggests workflow:auto_cancel:on_new_commit:
+// interruptible when long jobs of merge request pipelines aren't
+// interruptible, so outdated pipelines keep running them after a new push.
+// Configs without any interruptible setting are left to
+// CheckMissingInterruptible.
+func CheckAutoCancelOnNewCommit(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	if config.Workflow != nil && config.Workflow.AutoCancel != nil {
+		switch config.Workflow.AutoCancel.OnNewCommit {
+		case "interruptible", "none":
+			return issues
+		}
+	}
+	if len(CheckMissingInterruptible(config)) > 0 || !createsMergeRequestPipelines(config) {
+		return issues
+	}
+
+	ctx := parser.MergeRequestPipelineContext("feature")
+	created, workflowVars := config.EvaluateWorkflow(ctx)
+	if !created {
+		return issues
+	}
+
+	minDuration := float64(types.IntParam(params, "min_duration_seconds", defaultLongJobSeconds))
+	var longJobs []string
+	for jobName, job := range config.ConcreteJobs() {
+		if jobInterruptible(config, jobName, job) {
+			continue
+		}
+		if config.JobRuns(job, ctx, workflowVars) && renderer.EstimateJobDuration(config, jobName) >= minDuration {
+			longJobs = append(longJobs, jobName)
+		}
+	}
+	if len(longJobs) == 0 {
+		return issues
+	}
+	sort.Strings(longJobs)
+
+	issues = append(issues, types.Issue{
+		Type:       types.IssueTypePerformance,
+		Severity:   types.SeverityLow,
+		Path:       "workflow.auto_cancel.on_new_commit",
+		Message:    fmt.Sprintf("Long merge request jobs aren't interruptible, so new commits don't cancel them: %s", strings.Join(longJobs, ", ")),
+		Suggestion: "Mark these jobs 'interruptible: true' and set 'workflow:auto_cancel:on_new_commit: interruptible' so outdated pipelines stop on new commits",
+	})
+
+	return issues
+}
+
+// jobInterruptible resolves a job's interruptible setting from the job, the
+// templates it extends and the default block, in that order
+func jobInterruptible(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) bool {
+	if job.Interruptible != nil {
+		return *job.Interruptible
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.Interruptible != nil {
+			return *template.Interruptible
+		}
+	}
+	if config.Default != nil && config.Default.Interruptible != nil && job.InheritsDefault("interruptible") {
+		return *config.Default.Interruptible
+	}
+	return false
+}
+
+// createsMergeRequestPipelines reports whether the workflow or any job
+// explicitly opts into merge request pipelines
+func createsMergeRequestPipelines(config *parser.GitLabConfig) bool {
+	if config.Workflow != nil {
+		for _, rule := range config.Workflow.Rules {
+			if strings.Contains(rule.If, "merge_request_event") || strings.Contains(rule.If, "$CI_MERGE_REQUEST_") {
+				return true
+			}
+		}
+	}
+
+	for _, job := range config.Jobs {
+		if job != nil && hasMRSpecificRules(job) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,198 @@
+package performance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckMissingInterruptible(t *testing.T) {
+	enabled := true
+	mrWorkflow := &parser.Workflow{
+		Rules: []parser.Rule{
+			{If: `$CI_PIPELINE_SOURCE == "merge_request_event"`},
+			{If: `$CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH`},
+		},
+	}
+	jobs := func() map[string]*parser.JobConfig {
+		return map[string]*parser.JobConfig{
+			"test":  {Stage: "test", Script: []string{"make test"}},
+			"lint":  {Stage: "test", Script: []string{"make lint"}},
+			".base": {Script: []string{"echo template"}},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		config      *parser.GitLabConfig
+		expectIssue bool
+	}{
+		{
+			name:        "MR pipelines without interruptible",
+			config:      &parser.GitLabConfig{Workflow: mrWorkflow, Jobs: jobs()},
+			expectIssue: true,
+		},
+		{
+			name: "MR-specific job rules without workflow",
+			config: &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{
+				"review": {Rules: []parser.Rule{{If: `$CI_PIPELINE_SOURCE == "merge_request_event"`}}},
+			}},
+			expectIssue: true,
+		},
+		{
+			name: "default interruptible set",
+			config: &parser.GitLabConfig{
+				Workflow: mrWorkflow,
+				Default:  &parser.JobConfig{Interruptible: &enabled},
+				Jobs:     jobs(),
+			},
+		},
+		{
+			name: "job-level interruptible set",
+			config: &parser.GitLabConfig{
+				Workflow: mrWorkflow,
+				Jobs: map[string]*parser.JobConfig{
+					"test": {Script: []string{"make test"}, Interruptible: &enabled},
+				},
+			},
+		},
+		{
+			name: "workflow auto_cancel configured",
+			config: &parser.GitLabConfig{
+				Workflow: &parser.Workflow{
+					Rules:      mrWorkflow.Rules,
+					AutoCancel: &parser.AutoCancel{OnNewCommit: "interruptible"},
+				},
+				Jobs: jobs(),
+			},
+		},
+		{
+			name:   "no merge request pipelines",
+			config: &parser.GitLabConfig{Jobs: jobs()},
+		},
+		{
+			name: "only deploy jobs in merge request pipelines",
+			config: &parser.GitLabConfig{Workflow: mrWorkflow, Jobs: map[string]*parser.JobConfig{
+				"review":  {Stage: "review", Environment: &parser.Environment{Name: "review/$CI_COMMIT_REF_SLUG"}},
+				"publish": {Stage: "release", Script: []string{"make publish"}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := CheckMissingInterruptible(tt.config)
+
+			if tt.expectIssue {
+				if len(issues) != 1 {
+					t.Fatalf("Expected 1 issue, got %d: %v", len(issues), issues)
+				}
+				if issues[0].Path != "default.interruptible" {
+					t.Errorf("Expected path default.interruptible, got %s", issues[0].Path)
+				}
+			} else if len(issues) != 0 {
+				t.Errorf("Expected no issues, got %v", issues)
+			}
+		})
+	}
+}
+
+func TestCheckAutoCancelOnNewCommit(t *testing.T) {
+	enabled, disabled := true, false
+	mrRules := []parser.Rule{
+		{If: `$CI_PIPELINE_SOURCE == "merge_request_event"`},
+		{If: `$CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH`},
+	}
+	longScript := make([]string, 20)
+	for i := range longScript {
+		longScript[i] = "make step"
+	}
+	jobs := func() map[string]*parser.JobConfig {
+		return map[string]*parser.JobConfig{
+			"e2e":  {Stage: "test", Script: longScript, Interruptible: &disabled},
+			"lint": {Stage: "test", Script: []string{"make lint"}, Interruptible: &disabled},
+			"unit": {Stage: "test", Script: longScript},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		config      *parser.GitLabConfig
+		params      map[string]interface{}
+		expectIssue bool
+	}{
+		{
+			name: "long jobs not interruptible",
+			config: &parser.GitLabConfig{
+				Workflow: &parser.Workflow{Rules: mrRules},
+				Default:  &parser.JobConfig{Interruptible: &disabled},
+				Jobs:     jobs(),
+			},
+			expectIssue: true,
+		},
+		{
+			name: "conservative auto_cancel",
+			config: &parser.GitLabConfig{
+				Workflow: &parser.Workflow{Rules: mrRules, AutoCancel: &parser.AutoCancel{OnNewCommit: "conservative"}},
+				Jobs:     jobs(),
+			},
+			expectIssue: true,
+		},
+		{
+			name: "already cancels interruptible jobs",
+			config: &parser.GitLabConfig{
+				Workflow: &parser.Workflow{Rules: mrRules, AutoCancel: &parser.AutoCancel{OnNewCommit: "interruptible"}},
+				Jobs:     jobs(),
+			},
+		},
+		{
+			name: "long jobs interruptible through default",
+			config: &parser.GitLabConfig{
+				Workflow: &parser.Workflow{Rules: mrRules},
+				Default:  &parser.JobConfig{Interruptible: &enabled},
+				Jobs: map[string]*parser.JobConfig{
+					"unit": {Stage: "test", Script: longScript},
+				},
+			},
+		},
+		{
+			name: "threshold above every job",
+			config: &parser.GitLabConfig{
+				Workflow: &parser.Workflow{Rules: mrRules},
+				Default:  &parser.JobConfig{Interruptible: &disabled},
+				Jobs:     jobs(),
+			},
+			params: map[string]interface{}{"min_duration_seconds": 600},
+		},
+		{
+			name: "no merge request pipelines",
+			config: &parser.GitLabConfig{
+				Default: &parser.JobConfig{Interruptible: &disabled},
+				Jobs:    jobs(),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := CheckAutoCancelOnNewCommit(tt.config, tt.params)
+
+			if !tt.expectIssue {
+				if len(issues) != 0 {
+					t.Errorf("Expected no issues, got %v", issues)
+				}
+				return
+			}
+			if len(issues) != 1 {
+				t.Fatalf("Expected 1 issue, got %d: %v", len(issues), issues)
+			}
+			if issues[0].Path != "workflow.auto_cancel.on_new_commit" {
+				t.Errorf("Expected path workflow.auto_cancel.on_new_commit, got %s", issues[0].Path)
+			}
+			if !strings.HasSuffix(issues[0].Message, ": e2e, unit") {
+				t.Errorf("Expected only the long jobs to be listed, got %q", issues[0].Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package performance
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// defaultArtifactConsumerCommands are commands that use the whole working
+// directory, and with it any downloaded artifacts, without naming them. They
+// can be overridden through the check's custom_params ("consumer_commands").
+var defaultArtifactConsumerCommands = []string{
+	"docker build", "docker buildx", "podman build", "buildah", "kaniko",
+	"npm publish", "yarn publish", "pnpm publish", "semantic-release", "twine upload",
+	"gem push", "cargo publish", "rsync", "scp", "aws s3", "gsutil", "az storage",
+	"firebase deploy", "netlify deploy", "vercel", "release-cli",
+}
+
+// defaultReportExtensions are the extensions of report files, such as scan
+// results, that are too small for their transfer to matter. They can be
+// overridden through the check's custom_params ("report_extensions").
+var defaultReportExtensions = []string{".json", ".xml", ".sarif", ".html", ".txt", ".csv", ".log"}
+
+// defaultReportDirectories are directories that hold test and coverage
+// output, which downstream jobs don't build on. They can be overridden
+// through the check's custom_params ("report_directories").
+var defaultReportDirectories = []string{"coverage", "htmlcov", "test-results", "reports"}
+
+// CheckNeedsArtifacts flags needs that download an upstream job's artifacts
+// although the downstream job's scripts never refer to them. Such needs only
+// express ordering and can set `artifacts: false` to skip the transfer.
+// Report output, such as scan results or the coverage directory of a test
+// job, doesn't count as an artifact the downstream job could use.
+func CheckNeedsArtifacts(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	consumerCommands := types.StringListParam(params, "consumer_commands", defaultArtifactConsumerCommands)
+	reportExtensions := types.StringListParam(params, "report_extensions", defaultReportExtensions)
+	reportDirectories := types.StringListParam(params, "report_directories", defaultReportDirectories)
+
+	for jobName, job := range config.ConcreteJobs() {
+		scripts := jobScripts(config, jobName, job)
+		if len(scripts) == 0 || scriptsContainAny(scripts, consumerCommands) {
+			continue
+		}
+
+		for i, need := range job.Needs {
+			if !need.DownloadsArtifacts() || need.IsExternal() {
+				continue
+			}
+			// An explicit dependencies list already limits which artifacts
+			// are downloaded
+			if job.Dependencies != nil && !types.ContainsString(job.Dependencies, need.Job) {
+				continue
+			}
+			upstream, exists := config.Jobs[need.Job]
+			if !exists || upstream == nil {
+				continue
+			}
+			artifacts := resolvedArtifacts(config, need.Job, upstream)
+			if artifacts == nil || len(artifacts.Paths) == 0 || artifacts.Reports.Has("dotenv") {
+				continue
+			}
+			var usable []string
+			for _, artifactPath := range artifacts.Paths {
+				if !isReportOutput(artifactPath, artifacts.Reports, reportExtensions, reportDirectories) {
+					usable = append(usable, artifactPath)
+				}
+			}
+			if len(usable) == 0 || scriptsReferenceArtifacts(scripts, artifacts.Paths) {
+				continue
+			}
+
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypePerformance,
+				Severity:   types.SeverityLow,
+				Path:       fmt.Sprintf("jobs.%s.needs[%d]", jobName, i),
+				Message:    fmt.Sprintf("Job '%s' downloads the artifacts of '%s' but its scripts don't reference them", jobName, need.Job),
+				Suggestion: fmt.Sprintf("Use '{job: %s, artifacts: false}' to keep the ordering without transferring artifacts", need.Job),
+				JobName:    jobName,
+			})
+		}
+	}
+
+	return issues
+}
+
+// jobScripts returns the job's before_script, script and after_script lines,
+// each taken from the job or, when unset, from the nearest template it extends
+func jobScripts(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) []string {
+	chain := config.ExtendsChain(jobName)
+	resolve := func(field func(*parser.JobConfig) []string) []string {
+		if lines := field(job); len(lines) > 0 {
+			return lines
+		}
+		for i := len(chain) - 1; i >= 0; i-- {
+			if lines := field(config.Jobs[chain[i]]); len(lines) > 0 {
+				return lines
+			}
+		}
+		return nil
+	}
+
+	var lines []string
+	lines = append(lines, resolve(func(j *parser.JobConfig) []string { return j.BeforeScript })...)
+	lines = append(lines, resolve(func(j *parser.JobConfig) []string { return j.Script })...)
+	lines = append(lines, resolve(func(j *parser.JobConfig) []string { return j.AfterScript })...)
+	return lines
+}
+
+func resolvedArtifacts(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) *parser.Artifacts {
+	if job.Artifacts != nil {
+		return job.Artifacts
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template.Artifacts != nil {
+			return template.Artifacts
+		}
+	}
+	return nil
+}
+
+// scriptsReferenceArtifacts reports whether any script line mentions one of
+// the artifact paths, either in full or by its final path segment. Paths too
+// broad to recognize are assumed to be referenced.
+func scriptsReferenceArtifacts(scripts, artifactPaths []string) bool {
+	for _, artifactPath := range artifactPaths {
+		reference := normalizeArtifactPath(artifactPath)
+		if wildcard := strings.IndexAny(reference, "*?["); wildcard >= 0 {
+			reference = strings.TrimSuffix(reference[:wildcard], "/")
+		}
+		if reference == "" || reference == "." {
+			return true
+		}
+
+		base := path.Base(reference)
+		for _, line := range scripts {
+			if strings.Contains(line, reference) || strings.Contains(line, base) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isReportOutput reports whether an artifact path holds report output: a
+// file with one of the report extensions, one of the report directories, or
+// a directory holding one of the job's artifacts:reports files
+func isReportOutput(artifactPath string, reports *parser.Reports, extensions, directories []string) bool {
+	if types.ContainsString(extensions, strings.ToLower(path.Ext(artifactPath))) {
+		return true
+	}
+	normalized := normalizeArtifactPath(artifactPath)
+	if types.ContainsString(directories, normalized) {
+		return true
+	}
+	for _, reportPath := range reports.Paths() {
+		if strings.HasPrefix(normalizeArtifactPath(reportPath), normalized+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func scriptsContainAny(scripts, commands []string) bool {
+	for _, line := range scripts {
+		for _, command := range commands {
+			if strings.Contains(line, command) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package performance
+
+import (
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckNeedsArtifacts(t *testing.T) {
+	yaml := `
+build:
+  script: [make]
+  artifacts:
+    paths: [dist/, reports/*.xml]
+.uses-dist:
+  script: [ls dist]
+unused:
+  script: [./notify.sh]
+  needs: [build]
+opted_out:
+  script: [./notify.sh]
+  needs:
+    - job: build
+      artifacts: false
+by_path:
+  script: [tar czf app.tgz dist]
+  needs: [build]
+by_extends:
+  extends: .uses-dist
+  needs: [build]
+by_glob:
+  script: [junit-merge reports]
+  needs: [build]
+filtered:
+  script: [./notify.sh]
+  needs: [build]
+  dependencies: []
+container:
+  script: [docker build -t app .]
+  needs: [build]
+`
+	config, err := parser.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	issues := CheckNeedsArtifacts(config, nil)
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d: %+v", len(issues), issues)
+	}
+	if issues[0].JobName != "unused" || issues[0].Path != "jobs.unused.needs[0]" {
+		t.Errorf("Expected issue on unused's first need, got %+v", issues[0])
+	}
+
+	// Overriding the consumer commands drops the docker build exemption
+	issues = CheckNeedsArtifacts(config, map[string]interface{}{"consumer_commands": []interface{}{"rsync"}})
+	if len(issues) != 2 {
+		t.Errorf("Expected 2 issues with custom consumer commands, got %d: %+v", len(issues), issues)
+	}
+}
+
+func TestCheckNeedsArtifacts_DotenvReports(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"version": {
+				Script: []string{"echo VERSION=1 > build.env"},
+				Artifacts: &parser.Artifacts{
+					Paths:   []string{"dist/"},
+					Reports: &parser.Reports{Dotenv: []string{"build.env"}},
+				},
+			},
+			"deploy": {
+				Script: []string{"deploy $VERSION"},
+				Needs:  []parser.Need{{Job: "version"}},
+			},
+		},
+	}
+
+	if issues := CheckNeedsArtifacts(config, nil); len(issues) != 0 {
+		t.Errorf("Expected dotenv artifacts to count as used, got %+v", issues)
+	}
+}
+
+func TestCheckNeedsArtifacts_ReportFiles(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"scan": {
+				Script:    []string{"trivy image -o trivy-report.json app"},
+				Artifacts: &parser.Artifacts{Paths: []string{"trivy-report.json", "results/junit.XML"}},
+			},
+			"deploy": {
+				Script: []string{"./deploy.sh"},
+				Needs:  []parser.Need{{Job: "scan"}},
+			},
+		},
+	}
+
+	if issues := CheckNeedsArtifacts(config, nil); len(issues) != 0 {
+		t.Errorf("Expected artifacts made up of report files to be left alone, got %+v", issues)
+	}
+	params := map[string]interface{}{"report_extensions": []interface{}{".xml"}}
+	if issues := CheckNeedsArtifacts(config, params); len(issues) != 1 {
+		t.Errorf("Expected 1 issue with custom report extensions, got %+v", issues)
+	}
+}
+
+func TestCheckNeedsArtifacts_ReportDirectories(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"test": {
+				Script:   []string{"npm test"},
+				Parallel: &parser.Parallel{Matrix: []map[string]interface{}{{"NODE_VERSION": []interface{}{"18", "20"}}}},
+				Artifacts: &parser.Artifacts{
+					Paths: []string{"./coverage/"},
+					Reports: &parser.Reports{
+						CoverageReport: &parser.CoverageReport{CoverageFormat: "cobertura", Path: "coverage/cobertura-coverage.xml"},
+					},
+				},
+			},
+			"python-test": {
+				Script:    []string{"pytest --cov --cov-report=html"},
+				Artifacts: &parser.Artifacts{Paths: []string{"htmlcov/"}},
+			},
+			"build": {
+				Script: []string{"npm run build"},
+				Needs:  []parser.Need{{Job: "test"}, {Job: "python-test"}},
+			},
+		},
+	}
+
+	if issues := CheckNeedsArtifacts(config, nil); len(issues) != 0 {
+		t.Errorf("Expected coverage output to be left alone, got %+v", issues)
+	}
+
+	config.Jobs["test"].Artifacts.Paths = append(config.Jobs["test"].Artifacts.Paths, "dist/")
+	issues := CheckNeedsArtifacts(config, nil)
+	if len(issues) != 1 || issues[0].Path != "jobs.build.needs[0]" {
+		t.Errorf("Expected the unused dist/ artifacts of test to be flagged, got %+v", issues)
+	}
+}
@@ -0,0 +1,69 @@
+package performance
+
+import (
+	"fmt"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// maxParallelCount is the highest parallel count GitLab accepts
+const maxParallelCount = 200
+
+// defaultParallelThreshold is the parallel count above which
+// CheckParallelWithoutMatrix flags a job. It can be overridden through the
+// check's custom_params ("max_parallel").
+const defaultParallelThreshold = 20
+
+// CheckParallelWithoutMatrix flags jobs with a high `parallel` count and no
+// matrix. Every instance runs the same script, so unless the job splits its
+// work by CI_NODE_INDEX the copies only duplicate work, and either way a
+// high count can take up every available runner.
+func CheckParallelWithoutMatrix(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	threshold := types.IntParam(params, "max_parallel", defaultParallelThreshold)
+
+	for _, jobName := range config.ConcreteJobNames() {
+		job := config.Jobs[jobName]
+		parallel := jobParallel(config, jobName, job)
+		if parallel == nil || len(parallel.Matrix) > 0 || parallel.Count <= threshold {
+			continue
+		}
+
+		severity := types.SeverityMedium
+		message := fmt.Sprintf("Job '%s' runs %d parallel copies without a matrix", jobName, parallel.Count)
+		if parallel.Count > maxParallelCount {
+			severity = types.SeverityHigh
+			message += fmt.Sprintf(", above GitLab's limit of %d", maxParallelCount)
+		} else if !scriptsContainAny(jobScripts(config, jobName, job), []string{"CI_NODE_INDEX", "CI_NODE_TOTAL"}) {
+			message += ", and its scripts don't split the work by CI_NODE_INDEX"
+		}
+
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypePerformance,
+			Severity:   severity,
+			Path:       "jobs." + jobName + ".parallel",
+			Message:    message,
+			Suggestion: fmt.Sprintf("Lower parallel to at most %d, or use parallel:matrix if the copies should differ", threshold),
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
+
+// jobParallel returns the job's parallel setting, or the one it inherits from
+// the nearest template it extends
+func jobParallel(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) *parser.Parallel {
+	if job.Parallel != nil {
+		return job.Parallel
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.Parallel != nil {
+			return template.Parallel
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package performance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckParallelWithoutMatrix(t *testing.T) {
+	tests := []struct {
+		name           string
+		jobs           map[string]*parser.JobConfig
+		params         map[string]interface{}
+		expectJobs     []string
+		expectSeverity types.Severity
+		expectMessage  string
+	}{
+		{
+			name: "identical copies",
+			jobs: map[string]*parser.JobConfig{
+				"test": {Script: []string{"make test"}, Parallel: &parser.Parallel{Count: 50}},
+			},
+			expectJobs:     []string{"test"},
+			expectSeverity: types.SeverityMedium,
+			expectMessage:  "don't split the work",
+		},
+		{
+			name: "copies splitting the work are still flagged",
+			jobs: map[string]*parser.JobConfig{
+				"test": {Script: []string{"./split.sh $CI_NODE_INDEX"}, Parallel: &parser.Parallel{Count: 50}},
+			},
+			expectJobs:     []string{"test"},
+			expectSeverity: types.SeverityMedium,
+		},
+		{
+			name: "count above GitLab's limit",
+			jobs: map[string]*parser.JobConfig{
+				"test": {Script: []string{"make test"}, Parallel: &parser.Parallel{Count: 500}},
+			},
+			expectJobs:     []string{"test"},
+			expectSeverity: types.SeverityHigh,
+			expectMessage:  "limit of 200",
+		},
+		{
+			name: "count inherited from a template",
+			jobs: map[string]*parser.JobConfig{
+				".sharded": {Parallel: &parser.Parallel{Count: 30}},
+				"test":     {Extends: ".sharded", Script: []string{"make test"}},
+			},
+			expectJobs:     []string{"test"},
+			expectSeverity: types.SeverityMedium,
+		},
+		{
+			name: "count within the threshold",
+			jobs: map[string]*parser.JobConfig{
+				"test": {Script: []string{"make test"}, Parallel: &parser.Parallel{Count: 8}},
+			},
+		},
+		{
+			name: "matrix jobs are left alone",
+			jobs: map[string]*parser.JobConfig{
+				"test": {
+					Script:   []string{"make test"},
+					Parallel: &parser.Parallel{Matrix: []map[string]interface{}{{"GO": []interface{}{"1.21", "1.22"}}}},
+				},
+			},
+		},
+		{
+			name: "custom threshold",
+			jobs: map[string]*parser.JobConfig{
+				"test": {Script: []string{"make test"}, Parallel: &parser.Parallel{Count: 8}},
+			},
+			params:         map[string]interface{}{"max_parallel": 4},
+			expectJobs:     []string{"test"},
+			expectSeverity: types.SeverityMedium,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := CheckParallelWithoutMatrix(&parser.GitLabConfig{Jobs: tt.jobs}, tt.params)
+
+			if len(issues) != len(tt.expectJobs) {
+				t.Fatalf("Expected %d issues, got %d: %v", len(tt.expectJobs), len(issues), issues)
+			}
+			for i, jobName := range tt.expectJobs {
+				issue := issues[i]
+				if issue.JobName != jobName || issue.Path != "jobs."+jobName+".parallel" {
+					t.Errorf("Expected issue for %s, got %+v", jobName, issue)
+				}
+				if issue.Severity != tt.expectSeverity {
+					t.Errorf("Expected severity %s, got %s", tt.expectSeverity, issue.Severity)
+				}
+				if !strings.Contains(issue.Message, tt.expectMessage) {
+					t.Errorf("Expected message to contain %q, got %q", tt.expectMessage, issue.Message)
+				}
+			}
+		})
+	}
+}
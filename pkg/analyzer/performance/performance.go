@@ -2,16 +2,20 @@ package performance
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/varexpand"
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+	"github.com/wonderfulspam/gitlab-smith/pkg/renderer"
 )
 
 // CheckRegistry interface to avoid import cycles
 type CheckRegistry interface {
 	Register(name string, issueType types.IssueType, checkFunc types.CheckFunc)
+	RegisterWithParams(name string, issueType types.IssueType, checkFunc types.ParamCheckFunc)
+	Describe(name string, doc types.CheckDoc)
 }
 
 // RegisterChecks registers all performance-related checks
@@ -22,7 +26,24 @@ func RegisterChecks(registry CheckRegistry) {
 	registry.Register("unnecessary_dependencies", types.IssueTypePerformance, CheckUnnecessaryDependencies)
 	registry.Register("matrix_opportunities", types.IssueTypePerformance, CheckMatrixOpportunities)
 	registry.Register("missing_needs", types.IssueTypePerformance, CheckMissingNeeds)
+	registry.Register("redundant_needs", types.IssueTypePerformance, CheckRedundantNeeds)
+	registry.RegisterWithParams("empty_needs", types.IssueTypePerformance, CheckEmptyNeeds)
 	registry.Register("workflow_optimization", types.IssueTypePerformance, CheckWorkflowOptimization)
+	registry.Register("missing_interruptible", types.IssueTypePerformance, CheckMissingInterruptible)
+	registry.RegisterWithParams("auto_cancel_on_new_commit", types.IssueTypePerformance, CheckAutoCancelOnNewCommit)
+	registry.Register("parallel_resource_group", types.IssueTypePerformance, CheckParallelResourceGroup)
+	registry.RegisterWithParams("parallel_without_matrix", types.IssueTypePerformance, CheckParallelWithoutMatrix)
+	registry.Register("large_artifact_paths", types.IssueTypePerformance, CheckLargeArtifactPaths)
+	registry.Register("cache_artifact_overlap", types.IssueTypePerformance, CheckCacheArtifactOverlap)
+	registry.Register("cache_key_conflicts", types.IssueTypePerformance, CheckCacheKeyConflicts)
+	registry.RegisterWithParams("needs_artifacts", types.IssueTypePerformance, CheckNeedsArtifacts)
+	registry.RegisterWithParams("cache_pull_policy", types.IssueTypePerformance, CheckCachePullPolicy)
+	registry.RegisterWithParams("cache_key_files", types.IssueTypePerformance, CheckCacheKeyFiles)
+	registry.RegisterWithParams("git_clone_strategy", types.IssueTypePerformance, CheckGitCloneStrategy)
+
+	for name, doc := range checkDocs {
+		registry.Describe(name, doc)
+	}
 }
 
 func CheckCacheUsage(config *parser.GitLabConfig) []types.Issue {
@@ -35,9 +56,11 @@ func CheckCacheUsage(config *parser.GitLabConfig) []types.Issue {
 		// 1. It has its own cache configuration, OR
 		// 2. There's a global cache configuration, OR
 		// 3. There's a default cache configuration
+		// and the job doesn't opt out of it via inherit:default
+		inheritsCache := job.InheritsDefault("cache")
 		hasCache := job.Cache != nil ||
-			config.Cache != nil ||
-			(config.Default != nil && config.Default.Cache != nil)
+			(inheritsCache && config.Cache != nil) ||
+			(inheritsCache && config.Default != nil && config.Default.Cache != nil)
 
 		if !hasCache {
 			jobsWithoutCache++
@@ -46,18 +69,8 @@ func CheckCacheUsage(config *parser.GitLabConfig) []types.Issue {
 		// Check for inefficient cache configuration
 		if job.Cache != nil {
 			// Check if cache key is missing or empty
-			hasKey := false
-			if job.Cache.Key != nil {
-				switch key := job.Cache.Key.(type) {
-				case string:
-					hasKey = key != ""
-				case map[string]interface{}:
-					// Complex key with files or prefix
-					hasKey = true
-				default:
-					hasKey = false
-				}
-			}
+			// A complex key with files or prefix, or a non-empty string
+			hasKey := job.Cache.Key.IsObject() || job.Cache.KeyString() != ""
 
 			if !hasKey {
 				issues = append(issues, types.Issue{
@@ -179,25 +192,23 @@ func CheckUnnecessaryDependencies(config *parser.GitLabConfig) []types.Issue {
 func CheckMatrixOpportunities(config *parser.GitLabConfig) []types.Issue {
 	var issues []types.Issue
 
-	// Group jobs by stage (potential matrix candidates)
-	stageGroups := make(map[string][]string)
+	// Group jobs that differ at most in image and variables (matrix candidates)
+	matrixGroups := make(map[string][]string)
 
-	for jobName, job := range config.Jobs {
-		// Skip templates
-		if strings.HasPrefix(jobName, ".") {
-			continue
-		}
+	for jobName := range config.ConcreteJobs() {
 
-		stage := job.Stage
-		if stage == "" {
-			stage = "test" // Default stage
-		}
-		stageGroups[stage] = append(stageGroups[stage], jobName)
+		fingerprint := config.JobMatrixFingerprint(jobName)
+		matrixGroups[fingerprint] = append(matrixGroups[fingerprint], jobName)
 	}
 
-	// Look for stages with multiple similar jobs
-	for stage, jobNames := range stageGroups {
+	// Look for groups of similar jobs whose variations a matrix could express
+	for _, jobNames := range matrixGroups {
+		sort.Strings(jobNames)
 		if len(jobNames) >= 3 && canUseMatrix(jobNames, config.Jobs, config) {
+			stage := config.Jobs[jobNames[0]].Stage
+			if stage == "" {
+				stage = "test" // Default stage
+			}
 			issues = append(issues, types.Issue{
 				Type:       types.IssueTypePerformance,
 				Severity:   types.SeverityMedium,
@@ -224,12 +235,21 @@ func CheckMissingNeeds(config *parser.GitLabConfig) []types.Issue {
 	}
 
 	if needsOpportunities > 2 {
+		suggestion := "Consider using 'needs' instead of 'dependencies' for more granular job control and better parallelization"
+
+		// Estimate the gain from the critical path of the stage-gated
+		// pipeline versus one where those jobs only wait for their dependencies
+		saved := renderer.PipelineCriticalPath(config).Duration - renderer.NeedsCriticalPath(config).Duration
+		if saved >= 1 {
+			suggestion += fmt.Sprintf(" (~%.0fs faster)", saved)
+		}
+
 		issues = append(issues, types.Issue{
 			Type:       types.IssueTypePerformance,
 			Severity:   types.SeverityLow,
 			Path:       "jobs.*.dependencies",
 			Message:    fmt.Sprintf("Found %d jobs using dependencies that could benefit from 'needs' for better parallelization", needsOpportunities),
-			Suggestion: "Consider using 'needs' instead of 'dependencies' for more granular job control and better parallelization",
+			Suggestion: suggestion,
 		})
 	}
 
@@ -312,10 +332,10 @@ func canUseMatrix(jobNames []string, jobs map[string]*parser.JobConfig, config *
 		commonStage++
 
 		// Different images often indicate matrix opportunity - expand variables first
-		firstImage := expander.ExpandString(firstJob.Image, firstJob.Variables)
-		currentImage := expander.ExpandString(job.Image, job.Variables)
+		firstImage := expander.ExpandJobString(firstJob.Image.GetName(), firstJob)
+		currentImage := expander.ExpandJobString(job.Image.GetName(), job)
 
-		if currentImage != firstImage && job.Image != "" && firstJob.Image != "" {
+		if currentImage != firstImage && job.Image.GetName() != "" && firstJob.Image.GetName() != "" {
 			// Only count as different if both images are fully resolved (no unresolved variables)
 			if !expander.HasUnresolvedVariables(firstImage) && !expander.HasUnresolvedVariables(currentImage) {
 				differentImages++
@@ -131,21 +131,21 @@ func TestCheckMatrixOpportunities(t *testing.T) {
 			Jobs: map[string]*parser.JobConfig{
 				"test_node14": {
 					Stage: "test",
-					Image: "node:14",
+					Image: &parser.Image{Name: "node:14"},
 					Variables: map[string]interface{}{
 						"NODE_VERSION": "14",
 					},
 				},
 				"test_node16": {
 					Stage: "test",
-					Image: "node:16",
+					Image: &parser.Image{Name: "node:16"},
 					Variables: map[string]interface{}{
 						"NODE_VERSION": "16",
 					},
 				},
 				"test_node18": {
 					Stage: "test",
-					Image: "node:18",
+					Image: &parser.Image{Name: "node:18"},
 					Variables: map[string]interface{}{
 						"NODE_VERSION": "18",
 					},
@@ -270,6 +270,34 @@ func TestCheckMissingNeeds(t *testing.T) {
 			t.Errorf("Expected message to mention 3 jobs, got: %s", issue.Message)
 		}
 	})
+
+	t.Run("estimates time saved by switching to needs", func(t *testing.T) {
+		slowScript := make([]string, 20)
+		for i := range slowScript {
+			slowScript[i] = "make"
+		}
+		config := &parser.GitLabConfig{
+			Stages: []string{"build", "test"},
+			Jobs: map[string]*parser.JobConfig{
+				"build:fast": {Stage: "build", Script: []string{"make"}},
+				"build:slow": {Stage: "build", Script: slowScript},
+				"test1":      {Stage: "test", Script: []string{"make test"}, Dependencies: []string{"build:fast"}},
+				"test2":      {Stage: "test", Script: []string{"make test"}, Dependencies: []string{"build:fast"}},
+				"test3":      {Stage: "test", Script: []string{"make test"}, Dependencies: []string{"build:fast"}},
+			},
+		}
+
+		issues := CheckMissingNeeds(config)
+		if len(issues) != 1 {
+			t.Fatalf("Expected 1 issue, got %d", len(issues))
+		}
+
+		// Stage-gated: 70s slow build + 32s tests; with needs the tests
+		// finish at 64s, leaving the 70s slow build as the critical path
+		if !strings.Contains(issues[0].Suggestion, "(~32s faster)") {
+			t.Errorf("Expected suggestion to quantify the saving, got: %s", issues[0].Suggestion)
+		}
+	})
 }
 
 func TestCheckWorkflowOptimization(t *testing.T) {
@@ -461,8 +489,8 @@ func TestCanUseMatrix(t *testing.T) {
 			name:     "same stage different images",
 			jobNames: []string{"job1", "job2"},
 			jobs: map[string]*parser.JobConfig{
-				"job1": {Stage: "test", Image: "node:14"},
-				"job2": {Stage: "test", Image: "node:16"},
+				"job1": {Stage: "test", Image: &parser.Image{Name: "node:14"}},
+				"job2": {Stage: "test", Image: &parser.Image{Name: "node:16"}},
 			},
 			config:   &parser.GitLabConfig{Jobs: make(map[string]*parser.JobConfig)},
 			expected: true,
@@ -513,7 +541,20 @@ func TestRegisterChecks(t *testing.T) {
 		"unnecessary_dependencies",
 		"matrix_opportunities",
 		"missing_needs",
+		"redundant_needs",
+		"empty_needs",
 		"workflow_optimization",
+		"missing_interruptible",
+		"auto_cancel_on_new_commit",
+		"parallel_resource_group",
+		"parallel_without_matrix",
+		"large_artifact_paths",
+		"cache_artifact_overlap",
+		"cache_key_conflicts",
+		"needs_artifacts",
+		"cache_pull_policy",
+		"cache_key_files",
+		"git_clone_strategy",
 	}
 
 	if len(registry.checks) != len(expectedChecks) {
@@ -537,7 +578,7 @@ func TestCheckCacheUsage_EdgeCases(t *testing.T) {
 				"build": {
 					Stage: "build",
 					Cache: &parser.Cache{
-						Key:   "my-key",
+						Key:   &parser.CacheKey{Value: "my-key"},
 						Paths: []string{},
 					},
 				},
@@ -576,6 +617,18 @@ func (r *mockRegistry) Register(name string, issueType types.IssueType, checkFun
 	}
 }
 
+func (r *mockRegistry) RegisterWithParams(name string, issueType types.IssueType, checkFunc types.ParamCheckFunc) {
+	r.checks[name] = registeredCheck{
+		name:      name,
+		issueType: issueType,
+		checkFunc: func(config *parser.GitLabConfig) []types.Issue {
+			return checkFunc(config, nil)
+		},
+	}
+}
+
+func (r *mockRegistry) Describe(name string, doc types.CheckDoc) {}
+
 // Helper function
 func contains(s, substr string) bool {
 	for i := 0; i <= len(s)-len(substr); i++ {
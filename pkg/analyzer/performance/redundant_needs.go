@@ -0,0 +1,121 @@
+package performance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckRedundantNeeds flags needs entries that are already implied by
+// another need, such as C needing A and B when B already needs A. Only
+// entries whose removal keeps the job's behavior are reported: optional,
+// cross-project and matrix-specific needs neither count as guarantees nor
+// get flagged, and needs that download artifacts are kept, since a job only
+// receives artifacts from the jobs it lists.
+func CheckRedundantNeeds(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	graph := make(map[string][]string)
+	for jobName, job := range config.Jobs {
+		if job == nil {
+			continue
+		}
+		for _, need := range jobNeeds(config, jobName, job) {
+			if guaranteesOrder(config, need) {
+				graph[jobName] = append(graph[jobName], need.Job)
+			}
+		}
+		sort.Strings(graph[jobName])
+	}
+
+	for _, jobName := range config.ConcreteJobNames() {
+		job := config.Jobs[jobName]
+		for i, need := range jobNeeds(config, jobName, job) {
+			if !guaranteesOrder(config, need) {
+				continue
+			}
+			if need.DownloadsArtifacts() && hasArtifacts(config, need.Job) {
+				continue
+			}
+
+			for _, via := range graph[jobName] {
+				if via == need.Job {
+					continue
+				}
+				path := needsPath(graph, via, need.Job, map[string]bool{jobName: true})
+				if path == nil {
+					continue
+				}
+				route := strings.Join(append([]string{jobName}, path...), " → ")
+				issues = append(issues, types.Issue{
+					Type:       types.IssueTypePerformance,
+					Severity:   types.SeverityLow,
+					Path:       fmt.Sprintf("jobs.%s.needs[%d]", jobName, i),
+					Message:    fmt.Sprintf("Job '%s' needs '%s', which is already implied by %s", jobName, need.Job, route),
+					Suggestion: fmt.Sprintf("Remove '%s' from the needs of '%s' to simplify the pipeline graph", need.Job, jobName),
+					JobName:    jobName,
+				})
+				break
+			}
+		}
+	}
+
+	return issues
+}
+
+// jobNeeds returns the job's needs, or those it inherits from the nearest
+// template it extends
+func jobNeeds(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) []parser.Need {
+	if job.Needs != nil {
+		return job.Needs
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.Needs != nil {
+			return template.Needs
+		}
+	}
+	return nil
+}
+
+// guaranteesOrder reports whether a need always makes the job wait for
+// another job of this pipeline
+func guaranteesOrder(config *parser.GitLabConfig, need parser.Need) bool {
+	if need.Job == "" || need.Optional || need.IsExternal() || need.Parallel != nil {
+		return false
+	}
+	_, exists := config.Jobs[need.Job]
+	return exists
+}
+
+// hasArtifacts reports whether a job passes files or dotenv variables to the
+// jobs that need it
+func hasArtifacts(config *parser.GitLabConfig, jobName string) bool {
+	job := config.Jobs[jobName]
+	if job == nil {
+		return false
+	}
+	artifacts := resolvedArtifacts(config, jobName, job)
+	return artifacts != nil && (len(artifacts.Paths) > 0 || artifacts.Untracked || artifacts.Reports.Has("dotenv"))
+}
+
+// needsPath returns the jobs on a needs path from one job to another,
+// including both ends, or nil when there is none
+func needsPath(graph map[string][]string, from, to string, visited map[string]bool) []string {
+	if from == to {
+		return []string{to}
+	}
+	if visited[from] {
+		return nil
+	}
+	visited[from] = true
+	for _, next := range graph[from] {
+		if path := needsPath(graph, next, to, visited); path != nil {
+			return append([]string{from}, path...)
+		}
+	}
+	return nil
+}
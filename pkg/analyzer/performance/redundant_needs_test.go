@@ -0,0 +1,77 @@
+package performance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckRedundantNeeds(t *testing.T) {
+	noArtifacts := false
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"lint":  {Script: []string{"make lint"}},
+			"build": {Script: []string{"make"}, Needs: []parser.Need{{Job: "lint"}}},
+			"test":  {Script: []string{"make test"}, Needs: []parser.Need{{Job: "build"}}},
+			// lint is implied through test → build → lint
+			"deploy": {
+				Script: []string{"./deploy.sh"},
+				Needs:  []parser.Need{{Job: "test"}, {Job: "lint"}},
+			},
+			// build produces artifacts, so needing it directly isn't redundant
+			"package": {
+				Script: []string{"make package"},
+				Needs:  []parser.Need{{Job: "test"}, {Job: "compile"}},
+			},
+			"compile": {Script: []string{"make"}, Artifacts: &parser.Artifacts{Paths: []string{"bin/"}}},
+			"verify":  {Script: []string{"make verify"}, Needs: []parser.Need{{Job: "compile"}}},
+			"release": {
+				Script: []string{"make release"},
+				Needs:  []parser.Need{{Job: "verify"}, {Job: "compile"}},
+			},
+			// ...unless its artifacts are skipped
+			"notify": {
+				Script: []string{"./notify.sh"},
+				Needs:  []parser.Need{{Job: "verify"}, {Job: "compile", Artifacts: &noArtifacts}},
+			},
+			// An optional need doesn't guarantee the ordering
+			"audit": {Script: []string{"make audit"}, Needs: []parser.Need{{Job: "lint", Optional: true}}},
+			"report": {
+				Script: []string{"./report.sh"},
+				Needs:  []parser.Need{{Job: "audit"}, {Job: "lint"}},
+			},
+		},
+	}
+
+	issues := CheckRedundantNeeds(config)
+
+	var flagged []string
+	for _, issue := range issues {
+		flagged = append(flagged, issue.JobName)
+	}
+	if strings.Join(flagged, ",") != "deploy,notify" {
+		t.Fatalf("Expected deploy and notify to be flagged, got %v", flagged)
+	}
+
+	deploy := issues[0]
+	if deploy.Path != "jobs.deploy.needs[1]" {
+		t.Errorf("Expected the redundant edge's path, got %s", deploy.Path)
+	}
+	if !strings.Contains(deploy.Message, "'lint'") || !strings.Contains(deploy.Message, "deploy → test → build → lint") {
+		t.Errorf("Expected the redundant edge and the implying path, got %q", deploy.Message)
+	}
+}
+
+func TestCheckRedundantNeeds_Cycle(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"a": {Script: []string{"a"}, Needs: []parser.Need{{Job: "b"}}},
+			"b": {Script: []string{"b"}, Needs: []parser.Need{{Job: "a"}}},
+			"c": {Script: []string{"c"}, Needs: []parser.Need{{Job: "a"}, {Job: "b"}}},
+		},
+	}
+
+	// GitLab rejects cyclic needs; the check only has to terminate
+	CheckRedundantNeeds(config)
+}
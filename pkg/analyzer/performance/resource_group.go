@@ -0,0 +1,47 @@
+package performance
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckParallelResourceGroup flags parallel jobs that set a single
+// resource_group. Every parallel instance shares the group, so GitLab runs
+// them one at a time and the parallelism is lost.
+func CheckParallelResourceGroup(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for jobName, job := range config.Jobs {
+		if job == nil || job.Parallel.Instances() < 2 || job.ResourceGroup == "" {
+			continue
+		}
+		if isPerInstanceResourceGroup(job.ResourceGroup) {
+			continue
+		}
+
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypePerformance,
+			Severity:   types.SeverityMedium,
+			Path:       "jobs." + jobName + ".resource_group",
+			Message:    fmt.Sprintf("Job '%s' runs %d parallel instances in resource_group '%s', which serializes them", jobName, job.Parallel.Instances(), job.ResourceGroup),
+			Suggestion: "Remove resource_group or include $CI_NODE_INDEX in it so each instance gets its own group",
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
+
+// isPerInstanceResourceGroup reports whether the group name differs between
+// parallel instances of the same job
+func isPerInstanceResourceGroup(group string) bool {
+	for _, variable := range []string{"CI_NODE_INDEX", "CI_JOB_NAME", "CI_JOB_ID"} {
+		if strings.Contains(group, variable) {
+			return true
+		}
+	}
+	return false
+}
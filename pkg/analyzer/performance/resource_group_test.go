@@ -0,0 +1,27 @@
+package performance
+
+import (
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckParallelResourceGroup(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"test":        {Script: []string{"make test"}, Parallel: &parser.Parallel{Count: 5}, ResourceGroup: "test-db"},
+			"test_shards": {Script: []string{"make test"}, Parallel: &parser.Parallel{Count: 5}, ResourceGroup: "test-db-$CI_NODE_INDEX"},
+			"deploy":      {Script: []string{"./deploy.sh"}, ResourceGroup: "production"},
+			"lint":        {Script: []string{"make lint"}, Parallel: &parser.Parallel{Count: 3}},
+		},
+	}
+
+	issues := CheckParallelResourceGroup(config)
+
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d: %+v", len(issues), issues)
+	}
+	if issues[0].Path != "jobs.test.resource_group" {
+		t.Errorf("Expected issue for jobs.test.resource_group, got %s", issues[0].Path)
+	}
+}
@@ -0,0 +1,193 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+)
+
+// planTheme is a group of related issues that one refactoring addresses
+type planTheme struct {
+	name     string
+	title    string
+	action   string
+	keywords []string
+}
+
+// planThemes are matched in order against each issue's lowercased message;
+// issues matching none fall back to a theme per issue type
+var planThemes = []planTheme{
+	{
+		name:     "caching",
+		title:    "Consolidate cache configuration",
+		action:   "Give jobs that share dependencies one cache key and paths, and set pull or push policies per job",
+		keywords: []string{"cache", "caching"},
+	},
+	{
+		name:     "duplication",
+		title:    "Remove duplicated job configuration",
+		action:   "Move repeated settings into default, hidden templates with extends, or parallel:matrix",
+		keywords: []string{"duplicat", "same", "similar", "matrix", "extends", "consolidated"},
+	},
+	{
+		name:     "images",
+		title:    "Pin container images",
+		action:   "Use explicit, versioned image tags so runs are reproducible",
+		keywords: []string{"image", "'latest' tag"},
+	},
+	{
+		name:     "dependencies",
+		title:    "Tighten job dependencies",
+		action:   "Use needs for parallelism and only depend on jobs whose artifacts are used",
+		keywords: []string{"needs", "dependencies", "artifact"},
+	},
+	{
+		name:     "rules",
+		title:    "Simplify rules and workflow",
+		action:   "Align job rules with workflow:rules and drop conflicting or unreachable conditions",
+		keywords: []string{"rules", "workflow", "merge request", "never run"},
+	},
+	{
+		name:     "secrets",
+		title:    "Harden secrets handling",
+		action:   "Scope secrets and ID tokens to the jobs that need them and keep credentials out of variables",
+		keywords: []string{"secret", "id token"},
+	},
+}
+
+// typeThemes are the fallback themes for issues that match no keywords
+var typeThemes = map[types.IssueType]planTheme{
+	types.IssueTypePerformance: {
+		name:   "performance",
+		title:  "Address remaining performance issues",
+		action: "Apply the suggestions below to cut pipeline time",
+	},
+	types.IssueTypeSecurity: {
+		name:   "security",
+		title:  "Address remaining security issues",
+		action: "Apply the suggestions below to reduce pipeline exposure",
+	},
+	types.IssueTypeMaintainability: {
+		name:   "maintainability",
+		title:  "Address remaining maintainability issues",
+		action: "Apply the suggestions below to keep the configuration readable",
+	},
+	types.IssueTypeReliability: {
+		name:   "reliability",
+		title:  "Address remaining reliability issues",
+		action: "Apply the suggestions below so pipelines fail less often",
+	},
+}
+
+// minutesPerIssue is a rough estimate of the pipeline minutes a performance
+// issue costs per run, by severity
+var minutesPerIssue = map[types.Severity]int{
+	types.SeverityHigh:   5,
+	types.SeverityMedium: 2,
+	types.SeverityLow:    1,
+}
+
+// BuildRefactoringPlan clusters the issues of an analysis into themed
+// recommendations, ranked by the health score gained when each is resolved,
+// then by the number of issues and the estimated minutes saved
+func BuildRefactoringPlan(result *types.AnalysisResult) *types.RefactoringPlan {
+	plan := &types.RefactoringPlan{Recommendations: []types.Recommendation{}}
+	if result == nil {
+		return plan
+	}
+
+	plan.CurrentScore = result.Score()
+	plan.ProjectedScore = (&types.AnalysisResult{JobCount: result.JobCount}).Score()
+	plan.TotalIssues = len(result.Issues)
+
+	clusters := make(map[string][]types.Issue)
+	themes := make(map[string]planTheme)
+	for _, issue := range result.Issues {
+		theme := issueTheme(issue)
+		clusters[theme.name] = append(clusters[theme.name], issue)
+		themes[theme.name] = theme
+	}
+
+	for name, issues := range clusters {
+		theme := themes[name]
+		remaining := &types.AnalysisResult{JobCount: result.JobCount}
+		for _, issue := range result.Issues {
+			if issueTheme(issue).name != name {
+				remaining.Issues = append(remaining.Issues, issue)
+			}
+		}
+
+		types.SortIssues(issues)
+		recommendation := types.Recommendation{
+			Theme:          theme.name,
+			Title:          theme.title,
+			Action:         theme.action,
+			Severity:       issues[0].Severity,
+			IssuesResolved: len(issues),
+			ScoreGain:      remaining.Score() - plan.CurrentScore,
+			Jobs:           issueJobs(issues),
+			Issues:         issues,
+		}
+		for _, issue := range issues {
+			if issue.Type == types.IssueTypePerformance {
+				recommendation.MinutesSaved += minutesPerIssue[issue.Severity]
+			}
+		}
+		plan.Recommendations = append(plan.Recommendations, recommendation)
+	}
+
+	sort.Slice(plan.Recommendations, func(i, j int) bool {
+		a, b := plan.Recommendations[i], plan.Recommendations[j]
+		if a.ScoreGain != b.ScoreGain {
+			return a.ScoreGain > b.ScoreGain
+		}
+		if a.IssuesResolved != b.IssuesResolved {
+			return a.IssuesResolved > b.IssuesResolved
+		}
+		if a.MinutesSaved != b.MinutesSaved {
+			return a.MinutesSaved > b.MinutesSaved
+		}
+		return a.Theme < b.Theme
+	})
+	for i := range plan.Recommendations {
+		plan.Recommendations[i].Rank = i + 1
+	}
+
+	return plan
+}
+
+// issueTheme returns the first theme whose keywords appear in the issue's
+// message, or the fallback theme for its type
+func issueTheme(issue types.Issue) planTheme {
+	message := strings.ToLower(issue.Message)
+	for _, theme := range planThemes {
+		for _, keyword := range theme.keywords {
+			if strings.Contains(message, keyword) {
+				return theme
+			}
+		}
+	}
+	if theme, exists := typeThemes[issue.Type]; exists {
+		return theme
+	}
+	return planTheme{
+		name:   string(issue.Type),
+		title:  "Address remaining " + string(issue.Type) + " issues",
+		action: "Apply the suggestions below",
+	}
+}
+
+// issueJobs returns the sorted, distinct jobs named by the issues
+func issueJobs(issues []types.Issue) []string {
+	seen := make(map[string]bool)
+	var jobs []string
+	for _, issue := range issues {
+		if issue.JobName != "" && !seen[issue.JobName] {
+			seen[issue.JobName] = true
+			jobs = append(jobs, issue.JobName)
+		}
+	}
+	sort.Strings(jobs)
+	return jobs
+}
@@ -0,0 +1,70 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+)
+
+func TestBuildRefactoringPlan(t *testing.T) {
+	result := &types.AnalysisResult{
+		JobCount: 4,
+		Issues: []types.Issue{
+			{Type: types.IssueTypePerformance, Severity: types.SeverityMedium, Path: "jobs.build.cache", Message: "Cache configured without key - may lead to cache conflicts", JobName: "build"},
+			{Type: types.IssueTypePerformance, Severity: types.SeverityLow, Path: "jobs.test.cache", Message: "Cache configured without paths", JobName: "test"},
+			{Type: types.IssueTypeMaintainability, Severity: types.SeverityLow, Path: "jobs", Message: "Duplicate before_script blocks in jobs: build, test"},
+			{Type: types.IssueTypeSecurity, Severity: types.SeverityHigh, Path: "jobs.deploy.image", Message: "Docker image without explicit tag: alpine", JobName: "deploy"},
+			{Type: types.IssueTypeReliability, Severity: types.SeverityLow, Path: "jobs.lint.retry", Message: "High retry count may mask underlying issues", JobName: "lint"},
+		},
+	}
+
+	plan := BuildRefactoringPlan(result)
+
+	if plan.TotalIssues != 5 || plan.CurrentScore != result.Score() || plan.ProjectedScore != 100 {
+		t.Errorf("Unexpected plan totals: %+v", plan)
+	}
+	if len(plan.Recommendations) != 4 {
+		t.Fatalf("Expected 4 recommendations, got %+v", plan.Recommendations)
+	}
+
+	// The untagged image costs the most score, so it ranks first
+	first := plan.Recommendations[0]
+	if first.Rank != 1 || first.Theme != "images" || first.Severity != types.SeverityHigh {
+		t.Errorf("Expected the image recommendation first, got %+v", first)
+	}
+
+	var caching *types.Recommendation
+	for i, rec := range plan.Recommendations {
+		if rec.Rank != i+1 {
+			t.Errorf("Expected rank %d, got %d", i+1, rec.Rank)
+		}
+		if i > 0 && rec.ScoreGain > plan.Recommendations[i-1].ScoreGain {
+			t.Errorf("Recommendations aren't ordered by score gain: %+v", plan.Recommendations)
+		}
+		if rec.Theme == "caching" {
+			caching = &plan.Recommendations[i]
+		}
+	}
+	if caching == nil {
+		t.Fatal("Expected the cache issues to be grouped into one recommendation")
+	}
+	if caching.IssuesResolved != 2 || caching.MinutesSaved != 3 {
+		t.Errorf("Expected 2 cache issues saving 3 minutes, got %+v", caching)
+	}
+	if len(caching.Jobs) != 2 || caching.Jobs[0] != "build" || caching.Jobs[1] != "test" {
+		t.Errorf("Expected the cache recommendation to name build and test, got %v", caching.Jobs)
+	}
+	if caching.ScoreGain <= 0 {
+		t.Errorf("Expected resolving the cache issues to raise the score, got %d", caching.ScoreGain)
+	}
+}
+
+func TestBuildRefactoringPlan_NoIssues(t *testing.T) {
+	plan := BuildRefactoringPlan(&types.AnalysisResult{JobCount: 2})
+	if len(plan.Recommendations) != 0 || plan.CurrentScore != 100 {
+		t.Errorf("Expected an empty plan, got %+v", plan)
+	}
+	if plan := BuildRefactoringPlan(nil); plan == nil || plan.Recommendations == nil {
+		t.Error("Expected an empty plan for a nil result")
+	}
+}
@@ -0,0 +1,409 @@
+// Package policy runs organization-specific checks written as YAML rule
+// specs, so teams can enforce their own conventions without changing
+// gitlab-smith. A spec file lists policies:
+//
+//	policies:
+//	  - name: deploy_environment
+//	    description: Deploy jobs must name the environment they deploy to
+//	    type: reliability
+//	    severity: high
+//	    match:
+//	      - field: stage
+//	        equals: deploy
+//	    require:
+//	      - field: environment.name
+//	        exists: true
+//	    message: Deploy job '{job}' doesn't set environment:name
+//	    suggestion: Add environment:name so deployments are tracked
+//
+// A policy applies to every job that satisfies all its match conditions, or
+// to every job without them, and reports the jobs that fail any of its
+// require conditions. Fields are dotted paths into the job as JSON, as
+// written on the job, the templates it extends and the default block, e.g.
+// image.name, artifacts.paths or retry.max.
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// CheckRegistry interface to avoid import cycles
+type CheckRegistry interface {
+	Register(name string, issueType types.IssueType, checkFunc types.CheckFunc)
+	Describe(name string, doc types.CheckDoc)
+}
+
+// File is the content of a policy spec file
+type File struct {
+	Policies []Policy `yaml:"policies"`
+}
+
+// Policy is a check defined by a rule spec
+type Policy struct {
+	Name        string          `yaml:"name"`
+	Description string          `yaml:"description,omitempty"`
+	Type        types.IssueType `yaml:"type,omitempty"`
+	Severity    types.Severity  `yaml:"severity,omitempty"`
+	// Match selects the jobs the policy applies to; all must hold
+	Match []Condition `yaml:"match,omitempty"`
+	// Require lists what every selected job must satisfy
+	Require []Condition `yaml:"require"`
+	// Message and Suggestion describe a violation; {job} is replaced with
+	// the job's name
+	Message    string             `yaml:"message,omitempty"`
+	Suggestion string             `yaml:"suggestion,omitempty"`
+	Example    types.CheckExample `yaml:"example,omitempty"`
+	// Source is the spec file the policy was loaded from
+	Source string `yaml:"-"`
+}
+
+// Condition tests one job field with exactly one operator
+type Condition struct {
+	Field string `yaml:"field"`
+	// Exists requires the field to be set to a non-empty value, or with
+	// false, to be unset or empty
+	Exists *bool `yaml:"exists,omitempty"`
+	// Equals and NotEquals compare the field's value; for lists, Equals
+	// holds when any element equals the value and NotEquals when none does
+	Equals    *string `yaml:"equals,omitempty"`
+	NotEquals *string `yaml:"not_equals,omitempty"`
+	// Matches is a regular expression the value, or any element of a list,
+	// must match
+	Matches string `yaml:"matches,omitempty"`
+	// OneOf lists the values allowed for the field
+	OneOf []string `yaml:"one_of,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// LoadDir loads the policies of every .yml and .yaml file in dir, in file
+// name order
+func LoadDir(dir string) ([]Policy, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy directory: %w", err)
+	}
+
+	var policies []Policy
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		loaded, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range loaded {
+			if previous, exists := seen[policy.Name]; exists {
+				return nil, fmt.Errorf("%s: policy %q is already defined in %s", path, policy.Name, previous)
+			}
+			seen[policy.Name] = path
+			policies = append(policies, policy)
+		}
+	}
+	return policies, nil
+}
+
+// LoadFile loads and validates the policies of one spec file
+func LoadFile(path string) ([]Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy file: %w", err)
+	}
+
+	var file File
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for i := range file.Policies {
+		policy := &file.Policies[i]
+		policy.Source = path
+		if err := policy.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return file.Policies, nil
+}
+
+// validate checks the spec and fills in the defaults: maintainability
+// issues of medium severity
+func (p *Policy) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("policy without a name")
+	}
+	switch p.Type {
+	case "":
+		p.Type = types.IssueTypeMaintainability
+	case types.IssueTypePerformance, types.IssueTypeSecurity, types.IssueTypeMaintainability, types.IssueTypeReliability:
+	default:
+		return fmt.Errorf("policy %q: unknown type %q", p.Name, p.Type)
+	}
+	switch p.Severity {
+	case "":
+		p.Severity = types.SeverityMedium
+	case types.SeverityLow, types.SeverityMedium, types.SeverityHigh:
+	default:
+		return fmt.Errorf("policy %q: unknown severity %q", p.Name, p.Severity)
+	}
+	if len(p.Require) == 0 {
+		return fmt.Errorf("policy %q: require lists no conditions", p.Name)
+	}
+
+	for _, conditions := range [][]Condition{p.Match, p.Require} {
+		for i := range conditions {
+			if err := conditions[i].compile(); err != nil {
+				return fmt.Errorf("policy %q: %w", p.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Condition) compile() error {
+	if c.Field == "" {
+		return fmt.Errorf("condition without a field")
+	}
+	operators := 0
+	for _, set := range []bool{c.Exists != nil, c.Equals != nil, c.NotEquals != nil, c.Matches != "", c.OneOf != nil} {
+		if set {
+			operators++
+		}
+	}
+	if operators != 1 {
+		return fmt.Errorf("condition on %s needs exactly one of exists, equals, not_equals, matches and one_of", c.Field)
+	}
+	if c.Matches != "" {
+		pattern, err := regexp.Compile(c.Matches)
+		if err != nil {
+			return fmt.Errorf("condition on %s: invalid pattern: %w", c.Field, err)
+		}
+		c.pattern = pattern
+	}
+	return nil
+}
+
+// RegisterChecks registers each policy as a check named after it
+func RegisterChecks(registry CheckRegistry, policies []Policy) {
+	for _, policy := range policies {
+		registry.Register(policy.Name, policy.Type, policy.Check)
+		registry.Describe(policy.Name, types.CheckDoc{
+			Rationale: policy.Description,
+			Example:   policy.Example,
+		})
+	}
+}
+
+// Check reports the jobs the policy applies to that fail a require
+// condition
+func (p Policy) Check(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for _, jobName := range config.ConcreteJobNames() {
+		fields := jobFields(config, jobName)
+		if !allHold(p.Match, fields) {
+			continue
+		}
+		for _, condition := range p.Require {
+			if condition.holds(fields) {
+				continue
+			}
+			message := p.Message
+			if message == "" {
+				message = fmt.Sprintf("Job '{job}' violates policy %s: %s", p.Name, condition)
+			}
+			issues = append(issues, types.Issue{
+				Type:       p.Type,
+				Severity:   p.Severity,
+				Path:       "jobs." + jobName + "." + condition.Field,
+				Message:    strings.ReplaceAll(message, "{job}", jobName),
+				Suggestion: strings.ReplaceAll(p.Suggestion, "{job}", jobName),
+				JobName:    jobName,
+			})
+			break
+		}
+	}
+
+	return issues
+}
+
+func allHold(conditions []Condition, fields map[string]interface{}) bool {
+	for _, condition := range conditions {
+		if !condition.holds(fields) {
+			return false
+		}
+	}
+	return true
+}
+
+// holds evaluates the condition against a job's fields
+func (c Condition) holds(fields map[string]interface{}) bool {
+	value, found := lookup(fields, c.Field)
+	values := scalarValues(value)
+
+	switch {
+	case c.Exists != nil:
+		return *c.Exists == (found && !isEmpty(value))
+	case c.Equals != nil:
+		return contains(values, *c.Equals)
+	case c.NotEquals != nil:
+		return !contains(values, *c.NotEquals)
+	case c.pattern != nil:
+		for _, v := range values {
+			if c.pattern.MatchString(v) {
+				return true
+			}
+		}
+		return false
+	default:
+		if len(values) == 0 {
+			return false
+		}
+		for _, v := range values {
+			if !contains(c.OneOf, v) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// String describes the condition for default messages
+func (c Condition) String() string {
+	switch {
+	case c.Exists != nil && *c.Exists:
+		return c.Field + " must be set"
+	case c.Exists != nil:
+		return c.Field + " must not be set"
+	case c.Equals != nil:
+		return fmt.Sprintf("%s must be %q", c.Field, *c.Equals)
+	case c.NotEquals != nil:
+		return fmt.Sprintf("%s must not be %q", c.Field, *c.NotEquals)
+	case c.Matches != "":
+		return fmt.Sprintf("%s must match /%s/", c.Field, c.Matches)
+	default:
+		return fmt.Sprintf("%s must be one of %s", c.Field, strings.Join(c.OneOf, ", "))
+	}
+}
+
+// jobFields returns a job as a JSON object, merged over the templates it
+// extends the way GitLab merges extends: nested objects are combined and
+// other values replaced. The keywords of the default block the job inherits
+// come first, so anything the job or its templates set overrides them.
+func jobFields(config *parser.GitLabConfig, jobName string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if job := config.Jobs[jobName]; job != nil && config.Default != nil {
+		for key, value := range jsonObject(config.Default) {
+			if job.InheritsDefault(key) {
+				fields[key] = value
+			}
+		}
+	}
+	for _, name := range append(config.ExtendsChain(jobName), jobName) {
+		if job := config.Jobs[name]; job != nil {
+			mergeFields(fields, jsonObject(job))
+		}
+	}
+	delete(fields, "extends")
+	return fields
+}
+
+// jsonObject returns a job as a JSON object, or nil if it can't be encoded
+func jsonObject(job *parser.JobConfig) map[string]interface{} {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil
+	}
+	var object map[string]interface{}
+	if json.Unmarshal(data, &object) != nil {
+		return nil
+	}
+	return object
+}
+
+func mergeFields(dst, src map[string]interface{}) {
+	for key, value := range src {
+		if srcMap, ok := value.(map[string]interface{}); ok {
+			if dstMap, ok := dst[key].(map[string]interface{}); ok {
+				mergeFields(dstMap, srcMap)
+				continue
+			}
+		}
+		dst[key] = value
+	}
+}
+
+// lookup resolves a dotted path such as artifacts.paths
+func lookup(fields map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = fields
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// scalarValues returns a value, or the scalar elements of a list, as
+// strings
+func scalarValues(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		var values []string
+		for _, element := range v {
+			values = append(values, scalarValues(element)...)
+		}
+		return values
+	case map[string]interface{}:
+		return nil
+	case string:
+		return []string{v}
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func writePolicyFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	writePolicyFile(t, dir, "b.yaml", `
+policies:
+  - name: second
+    require:
+      - field: tags
+        exists: true
+`)
+	writePolicyFile(t, dir, "a.yml", `
+policies:
+  - name: first
+    type: security
+    severity: high
+    require:
+      - field: image.name
+        matches: ^registry\.example\.com/
+`)
+	writePolicyFile(t, dir, "notes.txt", "not a policy")
+
+	policies, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+	if len(policies) != 2 || policies[0].Name != "first" || policies[1].Name != "second" {
+		t.Fatalf("Expected policies first and second in file order, got %+v", policies)
+	}
+	if policies[0].Type != types.IssueTypeSecurity || policies[0].Severity != types.SeverityHigh {
+		t.Errorf("Expected the configured type and severity, got %s/%s", policies[0].Type, policies[0].Severity)
+	}
+	if policies[1].Type != types.IssueTypeMaintainability || policies[1].Severity != types.SeverityMedium {
+		t.Errorf("Expected the default type and severity, got %s/%s", policies[1].Type, policies[1].Severity)
+	}
+	if policies[1].Source != filepath.Join(dir, "b.yaml") {
+		t.Errorf("Expected the source file to be recorded, got %q", policies[1].Source)
+	}
+
+	writePolicyFile(t, dir, "c.yml", `
+policies:
+  - name: first
+    require:
+      - field: tags
+        exists: true
+`)
+	if _, err := LoadDir(dir); err == nil || !strings.Contains(err.Error(), "already defined") {
+		t.Errorf("Expected an error for a duplicate policy name, got %v", err)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", "policies:\n  - name: p\n    requires: []\n", "field requires not found"},
+		{"missing name", "policies:\n  - require:\n      - field: tags\n        exists: true\n", "without a name"},
+		{"unknown type", "policies:\n  - name: p\n    type: style\n    require:\n      - field: tags\n        exists: true\n", "unknown type"},
+		{"unknown severity", "policies:\n  - name: p\n    severity: critical\n    require:\n      - field: tags\n        exists: true\n", "unknown severity"},
+		{"no require", "policies:\n  - name: p\n", "require lists no conditions"},
+		{"no operator", "policies:\n  - name: p\n    require:\n      - field: tags\n", "exactly one of"},
+		{"two operators", "policies:\n  - name: p\n    require:\n      - field: stage\n        equals: deploy\n        one_of: [deploy]\n", "exactly one of"},
+		{"invalid pattern", "policies:\n  - name: p\n    require:\n      - field: stage\n        matches: \"(\"\n", "invalid pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writePolicyFile(t, t.TempDir(), "policy.yml", tt.content)
+			_, err := LoadFile(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	config, err := parser.Parse([]byte(`
+.deploy:
+  stage: deploy
+  image: registry.example.com/deployer:1
+  environment:
+    name: staging
+build:
+  stage: build
+  image: node:20
+  script: [npm ci]
+deploy_staging:
+  extends: .deploy
+  script: [./deploy.sh staging]
+deploy_production:
+  stage: deploy
+  image: registry.example.com/deployer:1
+  script: [./deploy.sh production]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	yes := true
+	deploy := "deploy"
+	tests := []struct {
+		name   string
+		policy Policy
+		want   []string
+	}{
+		{
+			name: "exists through extends",
+			policy: Policy{
+				Match:   []Condition{{Field: "stage", Equals: &deploy}},
+				Require: []Condition{{Field: "environment.name", Exists: &yes}},
+			},
+			want: []string{"deploy_production"},
+		},
+		{
+			name:   "matches",
+			policy: Policy{Require: []Condition{{Field: "image.name", Matches: `^registry\.example\.com/`}}},
+			want:   []string{"build"},
+		},
+		{
+			name:   "not equals",
+			policy: Policy{Require: []Condition{{Field: "stage", NotEquals: &deploy}}},
+			want:   []string{"deploy_production", "deploy_staging"},
+		},
+		{
+			name:   "one of a list",
+			policy: Policy{Require: []Condition{{Field: "script", OneOf: []string{"npm ci", "./deploy.sh staging"}}}},
+			want:   []string{"deploy_production"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.policy.Name = "test_policy"
+			if err := tt.policy.validate(); err != nil {
+				t.Fatalf("Invalid policy: %v", err)
+			}
+			issues := tt.policy.Check(config)
+			var jobs []string
+			for _, issue := range issues {
+				jobs = append(jobs, issue.JobName)
+			}
+			if strings.Join(jobs, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected issues for %v, got %v", tt.want, jobs)
+			}
+		})
+	}
+}
+
+func TestPolicyCheckDefault(t *testing.T) {
+	config, err := parser.Parse([]byte(`
+default:
+  image: registry.example.com/base:1
+build:
+  script: [make]
+lint:
+  inherit:
+    default: false
+  script: [make lint]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	yes := true
+	policy := Policy{Name: "image_set", Require: []Condition{{Field: "image.name", Exists: &yes}}}
+	if err := policy.validate(); err != nil {
+		t.Fatalf("Invalid policy: %v", err)
+	}
+
+	issues := policy.Check(config)
+	if len(issues) != 1 || issues[0].JobName != "lint" {
+		t.Errorf("Expected only the job not inheriting the default image to be reported, got %+v", issues)
+	}
+}
+
+func TestPolicyCheckMessage(t *testing.T) {
+	config, err := parser.Parse([]byte(`
+deploy:
+  stage: deploy
+  script: [./deploy.sh]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	yes := true
+	policy := Policy{
+		Name:       "deploy_environment",
+		Require:    []Condition{{Field: "environment.name", Exists: &yes}},
+		Suggestion: "Add environment:name to '{job}'",
+	}
+	if err := policy.validate(); err != nil {
+		t.Fatalf("Invalid policy: %v", err)
+	}
+
+	issues := policy.Check(config)
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d", len(issues))
+	}
+	issue := issues[0]
+	if issue.Path != "jobs.deploy.environment.name" {
+		t.Errorf("Expected the path of the failed field, got %s", issue.Path)
+	}
+	if issue.Message != "Job 'deploy' violates policy deploy_environment: environment.name must be set" {
+		t.Errorf("Unexpected default message: %s", issue.Message)
+	}
+	if issue.Suggestion != "Add environment:name to 'deploy'" {
+		t.Errorf("Expected {job} to be replaced, got %s", issue.Suggestion)
+	}
+}
+
+func TestExamplePolicies(t *testing.T) {
+	policies, err := LoadDir(filepath.Join("..", "..", "..", "examples", "policies"))
+	if err != nil {
+		t.Fatalf("Failed to load the example policies: %v", err)
+	}
+	if len(policies) == 0 {
+		t.Error("Expected example policies")
+	}
+}
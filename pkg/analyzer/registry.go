@@ -1,6 +1,8 @@
 package analyzer
 
 import (
+	"sort"
+
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
 )
@@ -16,11 +18,13 @@ type Checker interface {
 // CheckRegistry manages all available checks
 type CheckRegistry struct {
 	checks map[string]Checker
+	docs   map[string]types.CheckDoc
 }
 
 func NewCheckRegistry() *CheckRegistry {
 	return &CheckRegistry{
 		checks: make(map[string]Checker),
+		docs:   make(map[string]types.CheckDoc),
 	}
 }
 
@@ -29,11 +33,39 @@ func (r *CheckRegistry) Register(name string, issueType types.IssueType, checkFu
 	r.checks[name] = checker
 }
 
+// RegisterWithParams registers a check that receives its configured custom_params
+func (r *CheckRegistry) RegisterWithParams(name string, issueType types.IssueType, checkFunc types.ParamCheckFunc) {
+	checker := NewBaseChecker(name, issueType, nil)
+	checker.paramCheckFunc = checkFunc
+	r.checks[name] = checker
+}
+
+// Describe attaches the documentation shown by `gitlab-smith explain` to a check
+func (r *CheckRegistry) Describe(name string, doc types.CheckDoc) {
+	r.docs[name] = doc
+}
+
+// GetDoc returns a check's documentation, if it was described
+func (r *CheckRegistry) GetDoc(name string) (types.CheckDoc, bool) {
+	doc, exists := r.docs[name]
+	return doc, exists
+}
+
+// GetCheck returns the check registered under name
+func (r *CheckRegistry) GetCheck(name string) (Checker, bool) {
+	check, exists := r.checks[name]
+	return check, exists
+}
+
+// GetChecks returns all registered checks ordered by name
 func (r *CheckRegistry) GetChecks() []Checker {
 	checks := make([]Checker, 0, len(r.checks))
 	for _, check := range r.checks {
 		checks = append(checks, check)
 	}
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].Name() < checks[j].Name()
+	})
 	return checks
 }
 
@@ -49,12 +81,13 @@ func (r *CheckRegistry) GetChecksByType(issueType types.IssueType) []Checker {
 
 // BaseChecker provides common functionality for all checkers
 type BaseChecker struct {
-	name        string
-	issueType   types.IssueType
-	enabled     bool
-	checkFunc   types.CheckFunc
-	description string
-	config      *Config // Reference to global config for filtering
+	name           string
+	issueType      types.IssueType
+	enabled        bool
+	checkFunc      types.CheckFunc
+	paramCheckFunc types.ParamCheckFunc
+	description    string
+	config         *Config // Reference to global config for filtering
 }
 
 func NewBaseChecker(name string, issueType types.IssueType, checkFunc types.CheckFunc) *BaseChecker {
@@ -77,7 +110,12 @@ func (c *BaseChecker) Check(gitlabConfig *parser.GitLabConfig) []types.Issue {
 	}
 
 	// Run the check function
-	issues := c.checkFunc(gitlabConfig)
+	var issues []types.Issue
+	if c.paramCheckFunc != nil {
+		issues = c.paramCheckFunc(gitlabConfig, c.customParams())
+	} else {
+		issues = c.checkFunc(gitlabConfig)
+	}
 
 	// Filter issues based on configuration
 	if c.config != nil {
@@ -109,6 +147,23 @@ func (c *BaseChecker) Check(gitlabConfig *parser.GitLabConfig) []types.Issue {
 	return issues
 }
 
+// customParams returns the check's configured custom_params, if any
+func (c *BaseChecker) customParams() map[string]interface{} {
+	if c.config == nil {
+		return nil
+	}
+	params := c.config.Checks[c.name].CustomParams
+	if c.config.RepositoryFiles == nil {
+		return params
+	}
+	withFiles := make(map[string]interface{}, len(params)+1)
+	for name, value := range params {
+		withFiles[name] = value
+	}
+	withFiles["repository_files"] = c.config.RepositoryFiles
+	return withFiles
+}
+
 func (c *BaseChecker) Name() string {
 	return c.name
 }
@@ -60,6 +60,46 @@ func TestCheckRegistryRegister(t *testing.T) {
 	}
 }
 
+func TestCheckRegistryRegisterWithParams(t *testing.T) {
+	registry := NewCheckRegistry()
+
+	var received map[string]interface{}
+	registry.RegisterWithParams("param_check", types.IssueTypePerformance,
+		func(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+			received = params
+			return nil
+		})
+
+	config := DefaultConfig()
+	config.Checks["param_check"] = types.CheckConfig{
+		Name:         "param_check",
+		Type:         types.IssueTypePerformance,
+		Enabled:      true,
+		CustomParams: map[string]interface{}{"threshold": 3},
+	}
+
+	checker := registry.GetChecks()[0].(*BaseChecker)
+	checker.Check(&parser.GitLabConfig{})
+	if received != nil {
+		t.Errorf("Expected nil params without a config, got %v", received)
+	}
+
+	checker.SetConfig(config)
+	checker.Check(&parser.GitLabConfig{})
+	if received["threshold"] != 3 {
+		t.Errorf("Expected custom params to be passed to the check, got %v", received)
+	}
+
+	config.RepositoryFiles = []string{"go.mod"}
+	checker.Check(&parser.GitLabConfig{})
+	if files, ok := received["repository_files"].([]string); !ok || len(files) != 1 || received["threshold"] != 3 {
+		t.Errorf("Expected repository files alongside the custom params, got %v", received)
+	}
+	if _, leaked := config.Checks["param_check"].CustomParams["repository_files"]; leaked {
+		t.Errorf("Repository files should not be written into the check's configuration")
+	}
+}
+
 func TestCheckRegistryRegisterMultiple(t *testing.T) {
 	registry := NewCheckRegistry()
 
@@ -256,3 +296,48 @@ func TestBaseCheckerEnableDisable(t *testing.T) {
 		t.Error("Checker should be enabled after SetEnabled(true)")
 	}
 }
+
+func TestCheckRegistryDescribe(t *testing.T) {
+	registry := NewCheckRegistry()
+	registry.Register("test_check", types.IssueTypePerformance, func(config *parser.GitLabConfig) []types.Issue { return nil })
+
+	if _, exists := registry.GetDoc("test_check"); exists {
+		t.Error("Expected no doc before Describe")
+	}
+
+	registry.Describe("test_check", types.CheckDoc{
+		Rationale: "Because",
+		Example:   types.CheckExample{Before: "a: 1", After: "a: 2"},
+	})
+
+	doc, exists := registry.GetDoc("test_check")
+	if !exists || doc.Rationale != "Because" || doc.Example.After != "a: 2" {
+		t.Errorf("Expected the described doc, got %+v (exists=%v)", doc, exists)
+	}
+}
+
+func TestAllChecksDocumented(t *testing.T) {
+	registry := New().GetRegistry()
+
+	for _, check := range registry.GetChecks() {
+		doc, exists := registry.GetDoc(check.Name())
+		if !exists {
+			t.Errorf("Check %s has no documentation", check.Name())
+			continue
+		}
+		if doc.Rationale == "" || doc.Example.Before == "" || doc.Example.After == "" {
+			t.Errorf("Check %s documentation is missing a rationale or example", check.Name())
+		}
+		for _, related := range doc.Related {
+			if _, ok := registry.GetCheck(related); !ok || related == check.Name() {
+				t.Errorf("Check %s lists unknown related check %s", check.Name(), related)
+			}
+		}
+	}
+
+	for name := range registry.docs {
+		if _, ok := registry.GetCheck(name); !ok {
+			t.Errorf("Documentation for unregistered check %s", name)
+		}
+	}
+}
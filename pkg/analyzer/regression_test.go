@@ -31,7 +31,7 @@ func TestNoRegressions(t *testing.T) {
 				// Job with naming issue
 				"build project": {
 					Stage:        "build",
-					Image:        "node", // Should trigger image tag issue
+					Image:        &parser.Image{Name: "node"}, // Should trigger image tag issue
 					BeforeScript: []string{"npm install", "npm ci"},
 					Script:       []string{"npm run build", "npm run test", "echo done"},
 					Cache: &parser.Cache{
@@ -0,0 +1,114 @@
+package reliability
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// defaultCriticalKeywords mark a job as critical when a word of its name or
+// stage starts with one of them. Overridable through the check's
+// custom_params ("critical_keywords").
+var defaultCriticalKeywords = []string{"test", "security", "lint", "sast", "scan"}
+
+// defaultCriticalReports are artifacts:reports whose results are lost when
+// the job producing them fails. Overridable through custom_params
+// ("critical_reports").
+var defaultCriticalReports = []string{
+	"junit", "sast", "dependency_scanning", "container_scanning", "secret_detection",
+	"dast", "api_fuzzing", "coverage_fuzzing",
+}
+
+// CheckAllowFailureOnCriticalJobs flags a blanket `allow_failure: true` on
+// jobs that look critical by name or stage, or that produce test or security
+// reports, since their failures then go unnoticed. allow_failure limited to
+// exit_codes is not flagged.
+func CheckAllowFailureOnCriticalJobs(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	keywords := types.StringListParam(params, "critical_keywords", defaultCriticalKeywords)
+	reports := types.StringListParam(params, "critical_reports", defaultCriticalReports)
+
+	for _, jobName := range config.ConcreteJobNames() {
+		job := config.Jobs[jobName]
+		if !resolvedAllowFailure(config, jobName, job).IsBlanket() {
+			continue
+		}
+
+		reason := criticalJobReason(config, jobName, keywords, reports)
+		if reason == "" {
+			continue
+		}
+
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeReliability,
+			Severity:   types.SeverityMedium,
+			Path:       fmt.Sprintf("jobs.%s.allow_failure", jobName),
+			Message:    fmt.Sprintf("Job '%s' allows failure although it %s, so its failures go unnoticed", jobName, reason),
+			Suggestion: "Remove allow_failure, or limit it to the expected exit codes with 'allow_failure: {exit_codes: [...]}'",
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
+
+// resolvedAllowFailure returns the job's allow_failure, or the one it inherits
+// from the nearest template it extends
+func resolvedAllowFailure(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) *parser.AllowFailure {
+	if job.AllowFailure != nil {
+		return job.AllowFailure
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.AllowFailure != nil {
+			return template.AllowFailure
+		}
+	}
+	return nil
+}
+
+// criticalJobReason explains why a job counts as critical, or returns an
+// empty string when it doesn't
+func criticalJobReason(config *parser.GitLabConfig, jobName string, keywords, reports []string) string {
+	if keyword := matchingKeyword(jobName, keywords); keyword != "" {
+		return fmt.Sprintf("looks like a %s job", keyword)
+	}
+	stage := config.JobStage(jobName)
+	if keyword := matchingKeyword(stage, keywords); keyword != "" {
+		return fmt.Sprintf("runs in the '%s' stage", stage)
+	}
+
+	chain := append(config.ExtendsChain(jobName), jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		job := config.Jobs[chain[i]]
+		if job == nil || job.Artifacts == nil {
+			continue
+		}
+		for _, report := range reports {
+			if job.Artifacts.Reports.Has(report) {
+				return fmt.Sprintf("produces a %s report", report)
+			}
+		}
+		break
+	}
+	return ""
+}
+
+// matchingKeyword returns the first keyword that starts a word of name
+func matchingKeyword(name string, keywords []string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, keyword := range keywords {
+		for _, word := range words {
+			if strings.HasPrefix(word, strings.ToLower(keyword)) {
+				return keyword
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package reliability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckAllowFailureOnCriticalJobs(t *testing.T) {
+	config, err := parser.Parse([]byte(`
+stages: [build, test, security, deploy]
+.scanner:
+  stage: security
+  allow_failure: true
+unit-tests:
+  stage: build
+  script: [make test]
+  allow_failure: true
+dependency-check:
+  extends: .scanner
+  script: [check]
+container:
+  stage: build
+  script: [scan]
+  allow_failure: true
+  artifacts:
+    reports:
+      container_scanning: report.json
+scoped-lint:
+  stage: build
+  script: [lint]
+  allow_failure:
+    exit_codes: [1]
+experimental:
+  stage: deploy
+  script: [deploy]
+  allow_failure: true
+latest:
+  stage: build
+  script: [make]
+  allow_failure: true
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	issues := CheckAllowFailureOnCriticalJobs(config, nil)
+
+	flagged := make(map[string]string)
+	for _, issue := range issues {
+		flagged[issue.JobName] = issue.Message
+	}
+	if len(issues) != 3 {
+		t.Errorf("Expected 3 issues, got %d: %+v", len(issues), issues)
+	}
+	if !strings.Contains(flagged["unit-tests"], "test job") {
+		t.Errorf("Expected unit-tests to be flagged by name, got %q", flagged["unit-tests"])
+	}
+	if !strings.Contains(flagged["dependency-check"], "'security' stage") {
+		t.Errorf("Expected dependency-check to be flagged by its inherited stage, got %q", flagged["dependency-check"])
+	}
+	if !strings.Contains(flagged["container"], "container_scanning report") {
+		t.Errorf("Expected container to be flagged by its report, got %q", flagged["container"])
+	}
+
+	t.Run("custom heuristic", func(t *testing.T) {
+		params := map[string]interface{}{
+			"critical_keywords": []interface{}{"deploy"},
+			"critical_reports":  []interface{}{},
+		}
+		issues := CheckAllowFailureOnCriticalJobs(config, params)
+		if len(issues) != 1 || issues[0].JobName != "experimental" {
+			t.Errorf("Expected only the deploy job to be flagged, got %+v", issues)
+		}
+	})
+}
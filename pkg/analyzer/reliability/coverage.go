@@ -0,0 +1,75 @@
+package reliability
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckCoverageRegex flags coverage patterns that aren't valid regular
+// expressions, which GitLab silently ignores, and coverage set on more than
+// one job, since the pipeline reports a single coverage value
+func CheckCoverageRegex(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	var coverageJobs []string
+	for _, jobName := range config.ConcreteJobNames() {
+		job := config.Jobs[jobName]
+		coverage := resolvedCoverage(config, jobName, job)
+		if coverage == "" {
+			continue
+		}
+		coverageJobs = append(coverageJobs, jobName)
+
+		if err := validateCoveragePattern(coverage); err != nil {
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeReliability,
+				Severity:   types.SeverityMedium,
+				Path:       fmt.Sprintf("jobs.%s.coverage", jobName),
+				Message:    fmt.Sprintf("Job '%s' has an invalid coverage pattern %s: %v", jobName, coverage, err),
+				Suggestion: "Fix the pattern; GitLab ignores invalid coverage patterns and reports no coverage",
+				JobName:    jobName,
+			})
+		}
+	}
+
+	if len(coverageJobs) > 1 {
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeReliability,
+			Severity:   types.SeverityLow,
+			Path:       "jobs.*.coverage",
+			Message:    fmt.Sprintf("Coverage is extracted in %d jobs: %s", len(coverageJobs), strings.Join(coverageJobs, ", ")),
+			Suggestion: "Set coverage on the one job whose result should represent the pipeline",
+		})
+	}
+
+	return issues
+}
+
+// resolvedCoverage returns the job's coverage pattern, or the one it inherits
+// from the nearest template it extends
+func resolvedCoverage(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) string {
+	if job.Coverage != "" {
+		return job.Coverage
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.Coverage != "" {
+			return template.Coverage
+		}
+	}
+	return ""
+}
+
+// validateCoveragePattern checks that a coverage pattern is enclosed in
+// slashes and compiles. GitLab evaluates it with RE2, as Go does.
+func validateCoveragePattern(coverage string) error {
+	if len(coverage) < 2 || !strings.HasPrefix(coverage, "/") || !strings.HasSuffix(coverage, "/") {
+		return fmt.Errorf("pattern must be enclosed in slashes")
+	}
+	_, err := regexp.Compile(coverage[1 : len(coverage)-1])
+	return err
+}
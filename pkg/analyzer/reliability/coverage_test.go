@@ -0,0 +1,58 @@
+package reliability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckCoverageRegex(t *testing.T) {
+	t.Run("invalid patterns", func(t *testing.T) {
+		config := &parser.GitLabConfig{
+			Jobs: map[string]*parser.JobConfig{
+				"valid":      {Script: []string{"go test"}, Coverage: `/coverage: \d+\.\d+% of statements/`},
+				".template":  {Coverage: `/(\d+%/`},
+				"unbalanced": {Script: []string{"pytest"}, Extends: ".template"},
+				"bare":       {Script: []string{"jest"}, Coverage: `All files\s+(\d+)`},
+			},
+		}
+
+		issues := CheckCoverageRegex(config)
+
+		var invalid []string
+		for _, issue := range issues {
+			if issue.JobName != "" {
+				invalid = append(invalid, issue.JobName)
+			}
+		}
+		if strings.Join(invalid, ",") != "bare,unbalanced" {
+			t.Errorf("Expected bare and unbalanced to be flagged, got %v", invalid)
+		}
+		for _, issue := range issues {
+			if issue.JobName == "unbalanced" && !strings.Contains(issue.Message, "missing closing )") {
+				t.Errorf("Expected the compile error in the message, got %q", issue.Message)
+			}
+		}
+	})
+
+	t.Run("coverage on several jobs", func(t *testing.T) {
+		config := &parser.GitLabConfig{
+			Jobs: map[string]*parser.JobConfig{
+				"unit":        {Script: []string{"go test"}, Coverage: `/total: (\d+)%/`},
+				"integration": {Script: []string{"go test -tags it"}, Coverage: `/total: (\d+)%/`},
+				"lint":        {Script: []string{"golangci-lint run"}},
+			},
+		}
+
+		issues := CheckCoverageRegex(config)
+		if len(issues) != 1 || !strings.Contains(issues[0].Message, "integration, unit") {
+			t.Errorf("Expected one issue naming both coverage jobs, got %+v", issues)
+		}
+
+		delete(config.Jobs, "integration")
+		if issues := CheckCoverageRegex(config); len(issues) != 0 {
+			t.Errorf("Expected no issues for a single valid coverage job, got %+v", issues)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package reliability
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckDependenciesOutsideNeeds flags dependencies entries missing from the
+// job's needs. A job with needs only receives artifacts from the jobs it
+// needs, and GitLab refuses to create a pipeline in which a job depends on a
+// job it doesn't need.
+func CheckDependenciesOutsideNeeds(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for _, jobName := range config.ConcreteJobNames() {
+		job := config.Jobs[jobName]
+		needs := resolvedNeeds(config, jobName, job)
+		if needs == nil {
+			continue
+		}
+		dependencies := resolvedDependencies(config, jobName, job)
+		if len(dependencies) == 0 {
+			continue
+		}
+
+		needed := make(map[string]bool, len(needs))
+		var neededNames []string
+		for _, need := range needs {
+			if need.Job == "" || need.IsExternal() {
+				continue
+			}
+			needed[need.Job] = true
+			neededNames = append(neededNames, need.Job)
+		}
+
+		var missing []string
+		var firstIndex int
+		for i, dependency := range dependencies {
+			if needed[dependency] || needed[matrixJobBase(dependency)] {
+				continue
+			}
+			if len(missing) == 0 {
+				firstIndex = i
+			}
+			missing = append(missing, dependency)
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		neededList := "[]"
+		if len(neededNames) > 0 {
+			neededList = strings.Join(neededNames, ", ")
+		}
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeReliability,
+			Severity:   types.SeverityHigh,
+			Path:       fmt.Sprintf("jobs.%s.dependencies[%d]", jobName, firstIndex),
+			Message:    fmt.Sprintf("Job '%s' lists %s in dependencies but not in needs (%s); dependencies must be a subset of needs, so GitLab rejects the pipeline", jobName, quoteNames(missing), neededList),
+			Suggestion: fmt.Sprintf("Add the missing jobs to the needs of '%s', or remove them from its dependencies", jobName),
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
+
+// matrixJobBase returns the job name of a parallel:matrix job reference such
+// as "build: [linux, amd64]", or the reference itself otherwise
+func matrixJobBase(reference string) string {
+	if i := strings.Index(reference, ": ["); i > 0 {
+		return reference[:i]
+	}
+	return reference
+}
+
+func quoteNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "'" + name + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
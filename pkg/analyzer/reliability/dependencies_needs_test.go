@@ -0,0 +1,60 @@
+package reliability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckDependenciesOutsideNeeds(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"build": {Script: []string{"make"}},
+			"test":  {Script: []string{"make test"}},
+			"lint":  {Script: []string{"make lint"}},
+
+			"deploy":    {Script: []string{"./deploy.sh"}, Needs: []parser.Need{{Job: "test"}}, Dependencies: []string{"build", "test", "lint"}},
+			"subset":    {Script: []string{"./deploy.sh"}, Needs: []parser.Need{{Job: "build"}, {Job: "test"}}, Dependencies: []string{"build"}},
+			"no_needs":  {Script: []string{"./deploy.sh"}, Dependencies: []string{"build"}},
+			"empty":     {Script: []string{"./deploy.sh"}, Needs: []parser.Need{}, Dependencies: []string{"build"}},
+			"none":      {Script: []string{"./deploy.sh"}, Needs: []parser.Need{{Job: "build"}}, Dependencies: []string{}},
+			"matrix":    {Script: []string{"./deploy.sh"}, Needs: []parser.Need{{Job: "build"}}, Dependencies: []string{"build: [linux, amd64]"}},
+			".template": {Needs: []parser.Need{{Job: "test"}}},
+			"inherited": {Script: []string{"./deploy.sh"}, Extends: ".template", Dependencies: []string{"build"}},
+		},
+	}
+
+	issues := CheckDependenciesOutsideNeeds(config)
+
+	flagged := make(map[string]string)
+	for _, issue := range issues {
+		flagged[issue.JobName] = issue.Path
+	}
+	expected := map[string]string{
+		"deploy":    "jobs.deploy.dependencies[0]",
+		"empty":     "jobs.empty.dependencies[0]",
+		"inherited": "jobs.inherited.dependencies[0]",
+	}
+	for jobName, path := range expected {
+		if flagged[jobName] != path {
+			t.Errorf("Expected %s to be flagged at %s, got %q", jobName, path, flagged[jobName])
+		}
+	}
+	if len(flagged) != len(expected) {
+		t.Errorf("Expected only %d jobs flagged, got %v", len(expected), flagged)
+	}
+
+	for _, issue := range issues {
+		switch issue.JobName {
+		case "deploy":
+			if !strings.Contains(issue.Message, "'build', 'lint'") || !strings.Contains(issue.Message, "(test)") {
+				t.Errorf("Expected the conflicting jobs in the message, got %q", issue.Message)
+			}
+		case "empty":
+			if !strings.Contains(issue.Message, "not in needs ([])") {
+				t.Errorf("Expected an empty needs list in the message, got %q", issue.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package reliability
+
+import "github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+
+// checkDocs documents each reliability check for `gitlab-smith explain`
+var checkDocs = map[string]types.CheckDoc{
+	"retry_configuration": {
+		Rationale: "Retries hide flaky jobs instead of fixing them, and GitLab rejects more than 2. Retrying on every failure also reruns jobs that failed for a real reason; retry:when limits retries to infrastructure failures.",
+		Example: types.CheckExample{
+			Before: `test:
+  retry: 5`,
+			After: `test:
+  retry:
+    max: 2
+    when: [runner_system_failure, stuck_or_timeout_failure]`,
+		},
+		Related: []string{"allow_failure_critical", "job_timeouts"},
+	},
+	"missing_stages": {
+		Rationale: "GitLab refuses to create a pipeline when a job uses a stage that isn't in the stages list. The error only shows up when the pipeline runs, after the change is merged.",
+		Example: types.CheckExample{
+			Before: `stages: [build, test]
+
+deploy:
+  stage: deploy`,
+			After: `stages: [build, test, deploy]
+
+deploy:
+  stage: deploy`,
+		},
+		Related: []string{"stages_definition", "unused_stages"},
+	},
+	"job_without_script": {
+		Rationale: "Every job needs a script, its own or one inherited through extends, unless it only triggers a downstream pipeline. GitLab rejects the configuration otherwise, often because a template name was mistyped.",
+		Example: types.CheckExample{
+			Before: `.test_template:
+  script: make test
+
+unit_tests:
+  extends: .test-template`,
+			After: `.test_template:
+  script: make test
+
+unit_tests:
+  extends: .test_template`,
+		},
+		Related: []string{"unknown_extends", "unknown_job_keywords"},
+	},
+	"unknown_job_keywords": {
+		Rationale: "A misspelled keyword such as scripts or artefacts is a configuration error in GitLab, or, in a template, is silently carried along and never takes effect.",
+		Example: types.CheckExample{
+			Before: `build:
+  scripts: make
+  artefacts:
+    paths: [dist/]`,
+			After: `build:
+  script: make
+  artifacts:
+    paths: [dist/]`,
+		},
+		Related: []string{"job_without_script", "rules_with_legacy_keywords"},
+	},
+	"deploy_resource_group": {
+		Rationale: "Two jobs deploying to the same environment at once can interleave and leave it half updated. A shared resource_group makes GitLab run the deployments one at a time.",
+		Example: types.CheckExample{
+			Before: `deploy_app:
+  environment: production
+deploy_worker:
+  environment: production`,
+			After: `deploy_app:
+  environment: production
+  resource_group: production
+deploy_worker:
+  environment: production
+  resource_group: production`,
+		},
+		Related: []string{"production_deploy_gate", "environment_stop_jobs", "parallel_resource_group"},
+	},
+	"environment_stop_jobs": {
+		Rationale: "Review environments are torn down by the job named in on_stop. If that job is missing or doesn't set environment:action: stop, the environments are never stopped and their resources keep running.",
+		Example: types.CheckExample{
+			Before: `review:
+  environment:
+    name: review/$CI_COMMIT_REF_SLUG
+    on_stop: stop_review
+stop_review:
+  script: ./teardown.sh`,
+			After: `review:
+  environment:
+    name: review/$CI_COMMIT_REF_SLUG
+    on_stop: stop_review
+stop_review:
+  script: ./teardown.sh
+  when: manual
+  environment:
+    name: review/$CI_COMMIT_REF_SLUG
+    action: stop`,
+		},
+		Related: []string{"deploy_resource_group"},
+	},
+	"production_deploy_gate": {
+		Rationale: "A production deploy that runs automatically and concurrently ships whatever reaches the branch, possibly twice at once. A manual gate and a resource_group give a person the final say and serialize releases. Deploying only from tags also counts as a gate, since a person creates the tag.",
+		Example: types.CheckExample{
+			Before: `deploy_production:
+  environment: production
+  script: ./deploy.sh`,
+			After: `deploy_production:
+  environment: production
+  resource_group: production
+  when: manual
+  script: ./deploy.sh`,
+		},
+		Related: []string{"deploy_resource_group"},
+	},
+	"environment_urls": {
+		Rationale: "The environment URL links each deployment from merge requests and the environments page. A URL without a scheme or host is a broken link, a production or staging environment without a URL can't be opened from GitLab, and jobs giving one environment different URLs make the link depend on which job deployed last.",
+		Example: types.CheckExample{
+			Before: `deploy_staging:
+  environment:
+    name: staging
+    url: $CI_ENVIRONMENT_SLUG.example.com
+  script: ./deploy.sh`,
+			After: `deploy_staging:
+  environment:
+    name: staging
+    url: https://$CI_ENVIRONMENT_SLUG.example.com
+  script: ./deploy.sh`,
+		},
+		Related: []string{"environment_stop_jobs", "deploy_resource_group"},
+	},
+	"needs_limit": {
+		Rationale: "GitLab limits how many jobs a job can need and how many jobs a pipeline can have. A configuration over either limit is rejected when the pipeline is created.",
+		Example: types.CheckExample{
+			Before: `report:
+  needs: [test_1, test_2, ..., test_60]`,
+			After: `collect:
+  needs: [test_1, ..., test_30]
+report:
+  needs: [collect, test_31, ..., test_60]`,
+		},
+		Related: []string{"missing_needs", "cross_project_needs"},
+	},
+	"cross_project_needs": {
+		Rationale: "needs:project fetches artifacts from a job in another project. Without a ref GitLab can't tell which pipeline to take them from, and without a job it can't tell which artifacts, so the job fails.",
+		Example: types.CheckExample{
+			Before: `deploy:
+  needs:
+    - project: group/library`,
+			After: `deploy:
+  needs:
+    - project: group/library
+      job: build
+      ref: main
+      artifacts: true`,
+		},
+		Related: []string{"needs_limit"},
+	},
+	"unknown_extends": {
+		Rationale: "extends naming a template that doesn't exist after includes are merged is a configuration error. It usually means a typo or an include that was removed.",
+		Example: types.CheckExample{
+			Before: `test:
+  extends: .node-base  # defined as .node_base`,
+			After: `test:
+  extends: .node_base`,
+		},
+		Related: []string{"job_without_script", "include_optimization"},
+	},
+	"allow_failure_critical": {
+		Rationale: "allow_failure: true on a test, lint or security job lets the pipeline pass when the job fails. The check stops protecting anything, and failures go unnoticed until they are in production. The check is off by default, since advisory scans and benchmarks are often allowed to fail on purpose; enable it where such jobs are meant to gate merges.",
+		Example: types.CheckExample{
+			Before: `sast:
+  allow_failure: true`,
+			After: `sast:
+  allow_failure:
+    exit_codes: [3]  # only the known "no files to scan" exit`,
+		},
+		Related: []string{"retry_configuration"},
+	},
+	"coverage_regex": {
+		Rationale: "The coverage keyword takes a regular expression between slashes. An invalid pattern never matches, so coverage silently stops being reported, and setting it on several jobs makes the merge request figure an average of them.",
+		Example: types.CheckExample{
+			Before: `test:
+  coverage: 'Total: (\d+)%'`,
+			After: `test:
+  coverage: '/Total: (\d+\.?\d*)%/'`,
+		},
+	},
+	"runner_tags": {
+		Rationale: "When most jobs pick runners by tag, an untagged job runs on whichever shared runner is free, which may lack the tools or network access it needs. A tag used by a single job is often a typo of a common one.",
+		Example: types.CheckExample{
+			Before: `default:
+  tags: [docker]
+
+build:
+  tags: [dokcer]`,
+			After: `default:
+  tags: [docker]
+
+build:
+  script: make`,
+		},
+	},
+	"report_only_dependencies": {
+		Rationale: "Report files such as JUnit XML are uploaded for GitLab to process and aren't downloaded by later jobs. A job that depends on another only to read its reports finds nothing and fails, or silently works with missing data.",
+		Example: types.CheckExample{
+			Before: `test:
+  artifacts:
+    reports:
+      junit: report.xml
+publish_results:
+  dependencies: [test]
+  script: ./upload.sh report.xml`,
+			After: `test:
+  artifacts:
+    paths: [report.xml]
+    reports:
+      junit: report.xml
+publish_results:
+  dependencies: [test]
+  script: ./upload.sh report.xml`,
+		},
+		Related: []string{"needs_artifacts", "allow_failure_critical"},
+	},
+	"dependencies_outside_needs": {
+		Rationale: "A job with needs only receives artifacts from the jobs it needs. GitLab requires dependencies to be a subset of needs and rejects the pipeline when a job depends on a job it doesn't need.",
+		Example: types.CheckExample{
+			Before: `deploy:
+  needs: [test]
+  dependencies: [build]
+  script: ./deploy.sh dist/`,
+			After: `deploy:
+  needs: [build, test]
+  dependencies: [build]
+  script: ./deploy.sh dist/`,
+		},
+		Related: []string{"report_only_dependencies", "unnecessary_dependencies"},
+	},
+	"global_keywords_as_jobs": {
+		Rationale: "Top-level keys such as image, cache, services or variables are global keywords. A job given one of these names is read as global configuration instead: the job never runs, and its settings may change every other job.",
+		Example: types.CheckExample{
+			Before: `image:
+  stage: build
+  script: docker build -t app .`,
+			After: `build_image:
+  stage: build
+  script: docker build -t app .`,
+		},
+		Related: []string{"job_without_script", "unknown_job_keywords"},
+	},
+	"rules_variables_scope": {
+		Rationale: "Variables under rules:variables are only set when that rule is the one that matches. A script reading a variable that some rules set and others don't works on the default branch and gets an empty value in merge requests, which usually surfaces as a confusing failure far from the rules.",
+		Example: types.CheckExample{
+			Before: `deploy:
+  script: ./deploy.sh --env "$DEPLOY_ENV"
+  rules:
+    - if: $CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH
+      variables:
+        DEPLOY_ENV: production
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"`,
+			After: `deploy:
+  script: ./deploy.sh --env "$DEPLOY_ENV"
+  variables:
+    DEPLOY_ENV: review
+  rules:
+    - if: $CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH
+      variables:
+        DEPLOY_ENV: production
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"`,
+		},
+		Related: []string{"shadowed_rules"},
+	},
+	"pages_artifacts": {
+		Rationale: "GitLab Pages deploys the publish directory (public unless pages:publish names another) from the Pages job's artifacts. When artifacts:paths leaves it out, the job passes and the site is never updated, with no error pointing at the cause.",
+		Example: types.CheckExample{
+			Before: `pages:
+  script: mkdocs build --site-dir public
+  artifacts:
+    paths: [site/]`,
+			After: `pages:
+  script: mkdocs build --site-dir public
+  artifacts:
+    paths: [public/]`,
+		},
+		Related: []string{"job_without_script"},
+	},
+	"job_timeouts": {
+		Rationale: "A timeout GitLab can't parse fails the pipeline. One longer than the project's or runner's maximum timeout is silently capped, so the job is cancelled earlier than the configuration says, and retries multiply how long a hanging job holds a runner.",
+		Example: types.CheckExample{
+			Before: `integration:
+  timeout: 5 hrs 30
+  retry: 2`,
+			After: `integration:
+  timeout: 1h
+  retry:
+    max: 2
+    when: runner_system_failure`,
+		},
+		Related: []string{"retry_configuration"},
+	},
+}
@@ -0,0 +1,143 @@
+package reliability
+
+import (
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckEnvironmentStopJobs flags environment.on_stop references to jobs that
+// don't exist or that don't stop the environment, which leaves review apps
+// and other dynamic environments running forever
+func CheckEnvironmentStopJobs(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for jobName := range config.ConcreteJobs() {
+		environment := config.JobEnvironment(jobName)
+		if environment == nil || environment.OnStop == "" {
+			continue
+		}
+
+		stopJobName := environment.OnStop
+		stopJob, exists := config.Jobs[stopJobName]
+		if !exists || stopJob == nil {
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeReliability,
+				Severity:   types.SeverityHigh,
+				Path:       "jobs." + jobName + ".environment.on_stop",
+				Message:    "Job '" + jobName + "' references stop job '" + stopJobName + "' which does not exist",
+				Suggestion: "Define '" + stopJobName + "' with environment.action: stop or fix the on_stop reference",
+				JobName:    jobName,
+			})
+			continue
+		}
+
+		stopEnvironment := config.JobEnvironment(stopJobName)
+		if stopEnvironment == nil || stopEnvironment.Action != "stop" {
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeReliability,
+				Severity:   types.SeverityMedium,
+				Path:       "jobs." + stopJobName + ".environment.action",
+				Message:    "Stop job '" + stopJobName + "' for '" + jobName + "' does not set environment.action: stop",
+				Suggestion: "Set environment.action: stop (and the same environment name) on '" + stopJobName + "'",
+				JobName:    stopJobName,
+			})
+		}
+	}
+
+	return issues
+}
+
+// CheckProductionDeployGate flags production deployments that can start
+// automatically and concurrently, having neither a resource_group nor a
+// gate: a manual start, or rules that only run the job for tags, which a
+// person creates to cut a release
+func CheckProductionDeployGate(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for jobName, job := range config.ConcreteJobs() {
+		environment := config.JobEnvironment(jobName)
+		if !environment.IsProduction() || !isDeployAction(environment.Action) {
+			continue
+		}
+		if resolvedResourceGroup(config, jobName, job) != "" || hasDeployGate(config, jobName, job) {
+			continue
+		}
+
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeReliability,
+			Severity:   types.SeverityMedium,
+			Path:       "jobs." + jobName + ".environment",
+			Message:    "Production deploy job '" + jobName + "' has no resource_group and no manual or tag gate",
+			Suggestion: "Add resource_group: production to serialize deployments, or require approval with when: manual",
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
+
+// Variables of a push pipeline for the default branch and of a tag pipeline,
+// for telling rules that only match tags apart
+var (
+	branchPipelineVariables = map[string]string{
+		"CI_PIPELINE_SOURCE": "push",
+		"CI_COMMIT_BRANCH":   "main",
+		"CI_COMMIT_REF_NAME": "main",
+		"CI_DEFAULT_BRANCH":  "main",
+	}
+	tagPipelineVariables = map[string]string{
+		"CI_PIPELINE_SOURCE": "push",
+		"CI_COMMIT_TAG":      "v1.0.0",
+		"CI_COMMIT_REF_NAME": "v1.0.0",
+		"CI_DEFAULT_BRANCH":  "main",
+	}
+)
+
+// hasDeployGate reports whether the job, or the template it extends, can
+// only start manually in at least one context, or only runs for tags
+func hasDeployGate(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) bool {
+	candidates := []*parser.JobConfig{job}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		candidates = append(candidates, config.Jobs[chain[i]])
+	}
+
+	for _, candidate := range candidates {
+		if candidate.When == "manual" {
+			return true
+		}
+		for _, rule := range candidate.Rules {
+			if rule.When == "manual" {
+				return true
+			}
+		}
+	}
+	for _, candidate := range candidates {
+		if len(candidate.Rules) > 0 {
+			return onlyRunsForTags(candidate.Rules)
+		}
+	}
+	return false
+}
+
+// onlyRunsForTags reports whether every rule that can add the job needs a
+// tag: its if is false in a default branch pipeline but true in a tag one
+func onlyRunsForTags(rules []parser.Rule) bool {
+	for _, rule := range rules {
+		if rule.When == "never" {
+			continue
+		}
+		if rule.If == "" {
+			return false
+		}
+		onBranch, err := parser.EvaluateExpression(rule.If, branchPipelineVariables)
+		if err != nil || onBranch {
+			return false
+		}
+		onTag, err := parser.EvaluateExpression(rule.If, tagPipelineVariables)
+		if err != nil || !onTag {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package reliability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckEnvironmentStopJobs(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"deploy_review":  {Environment: &parser.Environment{Name: "review/$CI_COMMIT_REF_SLUG", OnStop: "stop_review"}},
+			"stop_review":    {Environment: &parser.Environment{Name: "review/$CI_COMMIT_REF_SLUG", Action: "stop"}},
+			"deploy_preview": {Environment: &parser.Environment{Name: "preview", OnStop: "teardown_preview"}},
+			"deploy_staging": {Environment: &parser.Environment{Name: "staging", OnStop: "stop_staging"}},
+			"stop_staging":   {Environment: &parser.Environment{Name: "staging"}},
+		},
+	}
+
+	issues := CheckEnvironmentStopJobs(config)
+
+	expected := map[string]string{
+		"jobs.deploy_preview.environment.on_stop": "teardown_preview",
+		"jobs.stop_staging.environment.action":    "stop_staging",
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %d: %+v", len(expected), len(issues), issues)
+	}
+	for _, issue := range issues {
+		jobName, exists := expected[issue.Path]
+		if !exists {
+			t.Errorf("Unexpected issue at %s", issue.Path)
+			continue
+		}
+		if !strings.Contains(issue.Message, jobName) {
+			t.Errorf("Expected message to name %s, got: %s", jobName, issue.Message)
+		}
+	}
+}
+
+func TestCheckProductionDeployGate(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"deploy_prod":     {Environment: &parser.Environment{Name: "production"}},
+			"deploy_tiered":   {Environment: &parser.Environment{Name: "live", DeploymentTier: "production"}},
+			"deploy_grouped":  {Environment: &parser.Environment{Name: "production"}, ResourceGroup: "production"},
+			"deploy_manual":   {Environment: &parser.Environment{Name: "production"}, Rules: []parser.Rule{{When: "manual"}}},
+			"deploy_staging":  {Environment: &parser.Environment{Name: "staging"}},
+			"stop_production": {Environment: &parser.Environment{Name: "production", Action: "stop"}},
+			"deploy_tagged": {
+				Environment: &parser.Environment{Name: "production"},
+				Rules:       []parser.Rule{{If: "$CI_COMMIT_TAG =~ /^v/"}, {If: "$CI_PIPELINE_SOURCE == \"schedule\"", When: "never"}},
+			},
+			"deploy_main_or_tag": {
+				Environment: &parser.Environment{Name: "production"},
+				Rules:       []parser.Rule{{If: "$CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH || $CI_COMMIT_TAG"}},
+			},
+			// The production name comes from the template, the URL from the job
+			".production":    {Environment: &parser.Environment{Name: "production"}},
+			"deploy_extends": {Extends: ".production", Environment: &parser.Environment{URL: "https://example.com"}},
+		},
+	}
+
+	issues := CheckProductionDeployGate(config)
+
+	paths := make(map[string]bool)
+	for _, issue := range issues {
+		paths[issue.Path] = true
+	}
+	expected := []string{"deploy_prod", "deploy_tiered", "deploy_main_or_tag", "deploy_extends"}
+	if len(issues) != len(expected) {
+		t.Errorf("Expected issues for %v, got %+v", expected, issues)
+	}
+	for _, jobName := range expected {
+		if !paths["jobs."+jobName+".environment"] {
+			t.Errorf("Expected an issue for %s, got %+v", jobName, issues)
+		}
+	}
+}
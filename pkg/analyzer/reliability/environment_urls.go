@@ -0,0 +1,143 @@
+package reliability
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/varexpand"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// runtimeVariablePattern matches the variables left in a string after
+// expansion, which are only known when the job runs
+var runtimeVariablePattern = regexp.MustCompile(`\$\{?[A-Za-z_][A-Za-z0-9_]*\}?`)
+
+// CheckEnvironmentURLs flags environment URLs that aren't valid http(s) URLs
+// once their variables are expanded, production and staging deployments
+// without a URL, and environments whose deploy jobs give different URLs.
+// Variables only known at runtime, such as CI_ENVIRONMENT_SLUG, are assumed
+// to expand to a valid host name segment.
+func CheckEnvironmentURLs(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+	expander := varexpand.New(config)
+
+	type deployment struct {
+		jobName  string
+		url      string
+		expanded string
+	}
+	deployments := make(map[string][]deployment)
+	var environments []string
+
+	for _, jobName := range config.ConcreteJobNames() {
+		job := config.Jobs[jobName]
+		environment := config.JobEnvironment(jobName)
+		if environment == nil || environment.Name == "" || !isDeployAction(environment.Action) {
+			continue
+		}
+		name := expander.ExpandJobString(environment.Name, job)
+
+		if environment.URL == "" {
+			if tier := environmentTier(environment); tier == "production" || tier == "staging" {
+				issues = append(issues, types.Issue{
+					Type:       types.IssueTypeReliability,
+					Severity:   types.SeverityLow,
+					Path:       "jobs." + jobName + ".environment.url",
+					Message:    fmt.Sprintf("Job '%s' deploys to %s environment '%s' without a URL", jobName, tier, name),
+					Suggestion: "Set environment.url so the deployment is linked from merge requests and the environments page",
+					JobName:    jobName,
+				})
+			}
+			continue
+		}
+
+		expanded := expander.ExpandJobString(environment.URL, job)
+		if problem := environmentURLProblem(expanded); problem != "" {
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeReliability,
+				Severity:   types.SeverityMedium,
+				Path:       "jobs." + jobName + ".environment.url",
+				Message:    fmt.Sprintf("Job '%s' sets environment URL '%s', which %s", jobName, environment.URL, problem),
+				Suggestion: "Use a full http:// or https:// URL, e.g. https://$CI_ENVIRONMENT_SLUG.example.com",
+				JobName:    jobName,
+			})
+			continue
+		}
+
+		if deployments[name] == nil {
+			environments = append(environments, name)
+		}
+		deployments[name] = append(deployments[name], deployment{jobName: jobName, url: environment.URL, expanded: expanded})
+	}
+
+	for _, name := range environments {
+		group := deployments[name]
+		for _, other := range group[1:] {
+			if other.expanded == group[0].expanded {
+				continue
+			}
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeReliability,
+				Severity:   types.SeverityLow,
+				Path:       "jobs." + other.jobName + ".environment.url",
+				Message:    fmt.Sprintf("Jobs '%s' and '%s' deploy to environment '%s' with different URLs (%s and %s)", group[0].jobName, other.jobName, name, group[0].url, other.url),
+				Suggestion: "Use the same environment.url in every job deploying to '" + name + "', since the environment shows the URL of its latest deployment",
+				JobName:    other.jobName,
+			})
+			break
+		}
+	}
+
+	return issues
+}
+
+// environmentURLProblem describes why an expanded environment URL is
+// invalid, or returns "" when it is valid or is only known at runtime
+func environmentURLProblem(expanded string) string {
+	// A URL that starts with a variable of its own, e.g. one set through a
+	// dotenv report, or a predefined URL variable gets its scheme from it and
+	// can't be checked. Other predefined variables never contain a scheme.
+	if loc := runtimeVariablePattern.FindStringIndex(expanded); loc != nil && loc[0] == 0 {
+		name := strings.Trim(expanded[loc[0]:loc[1]], "${}")
+		if !strings.HasPrefix(name, "CI_") || strings.HasSuffix(name, "_URL") {
+			return ""
+		}
+	}
+	candidate := runtimeVariablePattern.ReplaceAllString(expanded, "x")
+
+	if strings.ContainsAny(candidate, " \t") {
+		return "contains whitespace"
+	}
+	parsed, err := url.Parse(candidate)
+	if err != nil {
+		return "can't be parsed"
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "doesn't start with http:// or https://"
+	}
+	if parsed.Host == "" {
+		return "has no host"
+	}
+	return ""
+}
+
+// environmentTier returns the environment's deployment_tier or, when unset,
+// the tier GitLab infers from its name
+func environmentTier(environment *parser.Environment) string {
+	if environment.DeploymentTier != "" {
+		return environment.DeploymentTier
+	}
+	if environment.IsProduction() {
+		return "production"
+	}
+	name := strings.ToLower(environment.Name)
+	for _, prefix := range []string{"staging", "stage", "pre-prod", "preprod", "model", "demo"} {
+		if name == prefix || strings.HasPrefix(name, prefix+"/") || strings.HasPrefix(name, prefix+"-") {
+			return "staging"
+		}
+	}
+	return "other"
+}
@@ -0,0 +1,56 @@
+package reliability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckEnvironmentURLs(t *testing.T) {
+	deploy := func(name, url string) *parser.JobConfig {
+		return &parser.JobConfig{Script: []string{"./deploy.sh"}, Environment: &parser.Environment{Name: name, URL: url}}
+	}
+
+	config := &parser.GitLabConfig{
+		Variables: map[string]interface{}{"DOMAIN": "example.com"},
+		Jobs: map[string]*parser.JobConfig{
+			"review":         deploy("review/$CI_COMMIT_REF_SLUG", "https://$CI_ENVIRONMENT_SLUG.$DOMAIN"),
+			"review_no_url":  deploy("review/$CI_COMMIT_REF_SLUG", ""),
+			"no_scheme":      deploy("qa", "$CI_ENVIRONMENT_SLUG.example.com"),
+			"no_host":        deploy("qa2", "https://"),
+			"dynamic":        deploy("dynamic", "$DYNAMIC_ENVIRONMENT_URL"),
+			"staging_no_url": deploy("staging", ""),
+			"tier_no_url": {Script: []string{"./deploy.sh"}, Environment: &parser.Environment{
+				Name: "eu-live", DeploymentTier: "production",
+			}},
+			"prod_a":    deploy("production", "https://$DOMAIN"),
+			"prod_b":    deploy("production", "https://www.$DOMAIN"),
+			"prod_same": deploy("production", "https://example.com"),
+			"stop_prod": {Script: []string{"./stop.sh"}, Environment: &parser.Environment{Name: "production", Action: "stop"}},
+			".template": deploy("staging", ""),
+		},
+	}
+
+	issues := CheckEnvironmentURLs(config)
+
+	flagged := make(map[string]string)
+	for _, issue := range issues {
+		flagged[issue.JobName] = issue.Message
+	}
+	expected := map[string]string{
+		"no_scheme":      "doesn't start with http:// or https://",
+		"no_host":        "has no host",
+		"staging_no_url": "staging environment 'staging' without a URL",
+		"tier_no_url":    "production environment 'eu-live' without a URL",
+		"prod_b":         "Jobs 'prod_a' and 'prod_b' deploy to environment 'production' with different URLs (https://$DOMAIN and https://www.$DOMAIN)",
+	}
+	for jobName, message := range expected {
+		if !strings.Contains(flagged[jobName], message) {
+			t.Errorf("Expected %s to be flagged with %q, got %q", jobName, message, flagged[jobName])
+		}
+	}
+	if len(flagged) != len(expected) {
+		t.Errorf("Expected only %d jobs flagged, got %v", len(expected), flagged)
+	}
+}
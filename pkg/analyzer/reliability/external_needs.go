@@ -0,0 +1,47 @@
+package reliability
+
+import (
+	"fmt"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckCrossProjectNeeds flags needs:project entries without a ref or job.
+// GitLab needs both to know which pipeline's artifacts to download.
+func CheckCrossProjectNeeds(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for _, jobName := range config.ConcreteJobNames() {
+		job := config.Jobs[jobName]
+		for i, need := range resolvedNeeds(config, jobName, job) {
+			if need.Project == "" {
+				continue
+			}
+			path := fmt.Sprintf("jobs.%s.needs[%d]", jobName, i)
+
+			if need.Job == "" {
+				issues = append(issues, types.Issue{
+					Type:       types.IssueTypeReliability,
+					Severity:   types.SeverityHigh,
+					Path:       path,
+					Message:    fmt.Sprintf("Job '%s' needs project '%s' without naming the job to download artifacts from", jobName, need.Project),
+					Suggestion: "Add job: to the needs entry",
+					JobName:    jobName,
+				})
+			}
+			if need.Ref == "" {
+				issues = append(issues, types.Issue{
+					Type:       types.IssueTypeReliability,
+					Severity:   types.SeverityMedium,
+					Path:       path,
+					Message:    fmt.Sprintf("Job '%s' needs project '%s' without a ref, so GitLab can't tell which pipeline to take artifacts from", jobName, need.Project),
+					Suggestion: "Add ref: with the branch or tag whose latest pipeline provides the artifacts, e.g. ref: main",
+					JobName:    jobName,
+				})
+			}
+		}
+	}
+
+	return issues
+}
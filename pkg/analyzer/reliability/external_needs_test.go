@@ -0,0 +1,59 @@
+package reliability
+
+import (
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckCrossProjectNeeds(t *testing.T) {
+	config, err := parser.Parse([]byte(`
+stages: [build, test]
+build:
+  stage: build
+  script: [make]
+test:
+  stage: test
+  script: [make test]
+  needs:
+    - build
+    - project: group/library
+      job: build-lib
+      ref: main
+      artifacts: true
+    - project: group/assets
+      job: bundle
+    - pipeline: $PARENT_PIPELINE_ID
+      job: generate
+.downstream:
+  needs:
+    - project: group/tools
+deploy:
+  extends: .downstream
+  stage: test
+  script: [deploy]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	issues := CheckCrossProjectNeeds(config)
+
+	expected := []struct {
+		path     string
+		severity types.Severity
+	}{
+		{"jobs.deploy.needs[0]", types.SeverityHigh},
+		{"jobs.deploy.needs[0]", types.SeverityMedium},
+		{"jobs.test.needs[2]", types.SeverityMedium},
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %d: %+v", len(expected), len(issues), issues)
+	}
+	for i, want := range expected {
+		if issues[i].Path != want.path || issues[i].Severity != want.severity {
+			t.Errorf("Issue %d: expected %s at %s, got %s at %s", i, want.severity, want.path, issues[i].Severity, issues[i].Path)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package reliability
+
+import (
+	"fmt"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// Default limits for CheckNeedsLimit. Both can be overridden through the
+// check's custom_params ("max_needs" and "max_jobs") to match a plan or a
+// self-managed instance's settings.
+const (
+	defaultMaxNeeds = 50
+	defaultMaxJobs  = 500
+)
+
+// CheckNeedsLimit flags jobs whose needs expand to more jobs than GitLab
+// accepts, and pipelines that define more jobs than typical instance limits.
+// Needs on parallel or matrix jobs count once per generated job.
+func CheckNeedsLimit(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	maxNeeds := types.IntParam(params, "max_needs", defaultMaxNeeds)
+	maxJobs := types.IntParam(params, "max_jobs", defaultMaxJobs)
+
+	totalJobs := 0
+	for _, jobName := range config.ConcreteJobNames() {
+		totalJobs += jobInstances(config, jobName)
+
+		job := config.Jobs[jobName]
+		count := 0
+		for _, need := range resolvedNeeds(config, jobName, job) {
+			count += needInstances(config, need)
+		}
+		if count > maxNeeds {
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeReliability,
+				Severity:   types.SeverityHigh,
+				Path:       "jobs." + jobName + ".needs",
+				Message:    fmt.Sprintf("Job '%s' needs %d jobs, %d over the limit of %d", jobName, count, count-maxNeeds, maxNeeds),
+				Suggestion: "Depend on fewer jobs, for example by collecting upstream results in an aggregating job, or fall back to stage ordering",
+				JobName:    jobName,
+			})
+		}
+	}
+
+	if totalJobs > maxJobs {
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeReliability,
+			Severity:   types.SeverityMedium,
+			Path:       "jobs",
+			Message:    fmt.Sprintf("Pipeline defines %d jobs, %d over the limit of %d", totalJobs, totalJobs-maxJobs, maxJobs),
+			Suggestion: "Split the pipeline with rules or child pipelines so a single pipeline stays within the instance's job limit",
+		})
+	}
+
+	return issues
+}
+
+// needInstances returns how many generated jobs a needs entry refers to
+func needInstances(config *parser.GitLabConfig, need parser.Need) int {
+	if need.IsExternal() {
+		return 1
+	}
+	if need.Parallel != nil {
+		return need.Parallel.Instances()
+	}
+	return jobInstances(config, need.Job)
+}
+
+func resolvedNeeds(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) []parser.Need {
+	if job.Needs != nil {
+		return job.Needs
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template.Needs != nil {
+			return template.Needs
+		}
+	}
+	return nil
+}
+
+// jobInstances returns how many jobs GitLab generates for a job definition,
+// taking parallel and parallel:matrix into account
+func jobInstances(config *parser.GitLabConfig, jobName string) int {
+	job := config.Jobs[jobName]
+	if job == nil {
+		return 1
+	}
+	if job.Parallel != nil {
+		return job.Parallel.Instances()
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.Parallel != nil {
+			return template.Parallel.Instances()
+		}
+	}
+	return 1
+}
@@ -0,0 +1,86 @@
+package reliability
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckNeedsLimit(t *testing.T) {
+	yaml := `
+stages: [build, test]
+build:
+  stage: build
+  script: [make]
+  parallel: 3
+compile:
+  stage: build
+  script: [make]
+  parallel:
+    matrix:
+      - OS: [linux, macos]
+        ARCH: [amd64, arm64]
+.needs-all:
+  needs: [build, compile]
+aggregate:
+  extends: .needs-all
+  stage: test
+  script: [report]
+pick:
+  stage: test
+  script: [report]
+  needs:
+    - job: compile
+      parallel:
+        matrix:
+          - OS: [linux]
+            ARCH: [amd64]
+`
+	config, err := parser.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// aggregate needs 3 build jobs and 4 compile jobs; pick needs 1
+	issues := CheckNeedsLimit(config, map[string]interface{}{"max_needs": 5})
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d: %+v", len(issues), issues)
+	}
+	if issues[0].JobName != "aggregate" || issues[0].Path != "jobs.aggregate.needs" {
+		t.Errorf("Expected issue on aggregate's needs, got %+v", issues[0])
+	}
+	if !strings.Contains(issues[0].Message, "needs 7 jobs, 2 over the limit of 5") {
+		t.Errorf("Expected counts in message, got %q", issues[0].Message)
+	}
+
+	// 3 + 4 + 1 + 1 generated jobs
+	issues = CheckNeedsLimit(config, map[string]interface{}{"max_jobs": 8})
+	if len(issues) != 1 || issues[0].Path != "jobs" {
+		t.Fatalf("Expected a single pipeline size issue, got %+v", issues)
+	}
+	if !strings.Contains(issues[0].Message, "Pipeline defines 9 jobs, 1 over the limit of 8") {
+		t.Errorf("Expected counts in message, got %q", issues[0].Message)
+	}
+
+	if issues := CheckNeedsLimit(config, nil); len(issues) != 0 {
+		t.Errorf("Expected no issues with default limits, got %+v", issues)
+	}
+}
+
+func TestCheckNeedsLimit_DefaultLimit(t *testing.T) {
+	var needs []parser.Need
+	config := &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{}}
+	for i := 0; i < defaultMaxNeeds+1; i++ {
+		name := fmt.Sprintf("job%d", i)
+		config.Jobs[name] = &parser.JobConfig{Stage: "build"}
+		needs = append(needs, parser.Need{Job: name})
+	}
+	config.Jobs["final"] = &parser.JobConfig{Stage: "test", Needs: needs}
+
+	issues := CheckNeedsLimit(config, nil)
+	if len(issues) != 1 || issues[0].JobName != "final" {
+		t.Fatalf("Expected a needs limit issue for final, got %+v", issues)
+	}
+}
@@ -0,0 +1,71 @@
+package reliability
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckPagesArtifacts flags Pages jobs whose artifacts:paths don't include
+// the directory they publish (public unless pages:publish or publish says
+// otherwise). The job succeeds but the Pages deployment has nothing to
+// serve.
+func CheckPagesArtifacts(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for _, jobName := range config.ConcreteJobNames() {
+		if !config.IsPagesJob(jobName) {
+			continue
+		}
+		publishDir := config.PagesPublishDir(jobName)
+
+		// The job's own artifacts override those of the templates it extends
+		var paths []string
+		for _, name := range append(config.ExtendsChain(jobName), jobName) {
+			if definition := config.Jobs[name]; definition != nil && definition.Artifacts != nil {
+				paths = definition.Artifacts.Paths
+			}
+		}
+		if artifactsInclude(paths, publishDir) {
+			continue
+		}
+
+		message := fmt.Sprintf("Pages job '%s' publishes '%s' but doesn't upload it as an artifact", jobName, publishDir)
+		if len(paths) == 0 {
+			message = fmt.Sprintf("Pages job '%s' publishes '%s' but has no artifacts:paths", jobName, publishDir)
+		}
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeReliability,
+			Severity:   types.SeverityHigh,
+			Path:       "jobs." + jobName + ".artifacts.paths",
+			Message:    message,
+			Suggestion: fmt.Sprintf("Add '%s' to artifacts:paths so the deployment has files to serve", publishDir),
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
+
+// artifactsInclude reports whether one of the artifact paths uploads dir,
+// either naming it or one of its parents
+func artifactsInclude(paths []string, dir string) bool {
+	dir = cleanArtifactDir(dir)
+	for _, path := range paths {
+		path = cleanArtifactDir(path)
+		if path == "" || path == dir || strings.HasPrefix(dir, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func cleanArtifactDir(path string) string {
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "./"), "/")
+	if path == "." {
+		return ""
+	}
+	return path
+}
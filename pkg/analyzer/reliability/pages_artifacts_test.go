@@ -0,0 +1,73 @@
+package reliability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckPagesArtifacts(t *testing.T) {
+	yamlContent := `
+pages:
+  script:
+    - mkdocs build --site-dir public
+  artifacts:
+    paths: [site/]
+
+docs:
+  script:
+    - hugo --destination site
+  pages:
+    publish: site
+  artifacts:
+    paths: [./site/]
+
+everything:
+  script:
+    - make docs
+  publish: build/html
+  pages: true
+  artifacts:
+    paths: [build]
+
+no_artifacts:
+  script:
+    - make docs
+  pages: true
+
+.uploads-public:
+  artifacts:
+    paths: [public]
+
+inherited:
+  extends: .uploads-public
+  script:
+    - make docs
+  pages: true
+
+build:
+  script:
+    - make
+`
+	config, err := parser.Parse([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	issues := CheckPagesArtifacts(config)
+
+	flagged := make(map[string]string)
+	for _, issue := range issues {
+		flagged[issue.JobName] = issue.Message
+	}
+	if len(flagged) != 2 {
+		t.Errorf("Expected pages and no_artifacts to be flagged, got %v", flagged)
+	}
+	if !strings.Contains(flagged["pages"], "publishes 'public' but doesn't upload it") {
+		t.Errorf("Expected the publish directory in the message, got %q", flagged["pages"])
+	}
+	if !strings.Contains(flagged["no_artifacts"], "has no artifacts:paths") {
+		t.Errorf("Expected missing artifacts to be reported, got %q", flagged["no_artifacts"])
+	}
+}
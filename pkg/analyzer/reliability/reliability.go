@@ -1,6 +1,10 @@
 package reliability
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
 )
@@ -8,25 +12,75 @@ import (
 // CheckRegistry interface to avoid import cycles
 type CheckRegistry interface {
 	Register(name string, issueType types.IssueType, checkFunc types.CheckFunc)
+	RegisterWithParams(name string, issueType types.IssueType, checkFunc types.ParamCheckFunc)
+	Describe(name string, doc types.CheckDoc)
 }
 
 // RegisterChecks registers all reliability-related checks
 func RegisterChecks(registry CheckRegistry) {
 	registry.Register("retry_configuration", types.IssueTypeReliability, CheckRetryConfiguration)
 	registry.Register("missing_stages", types.IssueTypeReliability, CheckMissingStages)
+	registry.Register("job_without_script", types.IssueTypeReliability, CheckJobWithoutScript)
+	registry.Register("unknown_job_keywords", types.IssueTypeReliability, CheckUnknownJobKeywords)
+	registry.Register("deploy_resource_group", types.IssueTypeReliability, CheckDeployResourceGroups)
+	registry.Register("environment_stop_jobs", types.IssueTypeReliability, CheckEnvironmentStopJobs)
+	registry.Register("production_deploy_gate", types.IssueTypeReliability, CheckProductionDeployGate)
+	registry.Register("environment_urls", types.IssueTypeReliability, CheckEnvironmentURLs)
+	registry.RegisterWithParams("needs_limit", types.IssueTypeReliability, CheckNeedsLimit)
+	registry.Register("cross_project_needs", types.IssueTypeReliability, CheckCrossProjectNeeds)
+	registry.Register("unknown_extends", types.IssueTypeReliability, CheckUnknownExtends)
+	registry.RegisterWithParams("allow_failure_critical", types.IssueTypeReliability, CheckAllowFailureOnCriticalJobs)
+	registry.Register("coverage_regex", types.IssueTypeReliability, CheckCoverageRegex)
+	registry.RegisterWithParams("runner_tags", types.IssueTypeReliability, CheckRunnerTags)
+	registry.Register("report_only_dependencies", types.IssueTypeReliability, CheckReportOnlyDependencies)
+	registry.Register("dependencies_outside_needs", types.IssueTypeReliability, CheckDependenciesOutsideNeeds)
+	registry.Register("global_keywords_as_jobs", types.IssueTypeReliability, CheckGlobalKeywordsAsJobs)
+	registry.Register("rules_variables_scope", types.IssueTypeReliability, CheckRulesVariablesScope)
+	registry.Register("pages_artifacts", types.IssueTypeReliability, CheckPagesArtifacts)
+	registry.RegisterWithParams("job_timeouts", types.IssueTypeReliability, CheckJobTimeouts)
+
+	for name, doc := range checkDocs {
+		registry.Describe(name, doc)
+	}
 }
 
+// CheckRetryConfiguration flags retry counts above GitLab's maximum of 2 and
+// retry:when entries that aren't known failure reasons
 func CheckRetryConfiguration(config *parser.GitLabConfig) []types.Issue {
 	var issues []types.Issue
 
-	for jobName, job := range config.Jobs {
-		if job.Retry != nil && job.Retry.Max > 3 {
+	// Templates are checked too, where the setting is written
+	for _, jobName := range config.GetAllJobNames() {
+		job := config.Jobs[jobName]
+		if job == nil || job.Retry == nil {
+			continue
+		}
+
+		if job.Retry.Max > 2 {
 			issues = append(issues, types.Issue{
 				Type:       types.IssueTypeReliability,
 				Severity:   types.SeverityLow,
 				Path:       "jobs." + jobName + ".retry.max",
 				Message:    "High retry count may mask underlying issues",
-				Suggestion: "Consider investigating root cause instead of increasing retries",
+				Suggestion: "GitLab allows at most 2 retries; consider investigating root cause instead of increasing retries",
+				JobName:    jobName,
+			})
+		}
+
+		for _, reason := range job.Retry.When {
+			if isRetryReason(reason) {
+				continue
+			}
+			suggestion := "Use one of: " + strings.Join(parser.RetryReasons, ", ")
+			if closest := closestRetryReason(reason); closest != "" {
+				suggestion = "Did you mean '" + closest + "'?"
+			}
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeReliability,
+				Severity:   types.SeverityMedium,
+				Path:       "jobs." + jobName + ".retry.when",
+				Message:    fmt.Sprintf("Unknown retry reason '%s' in job '%s'", reason, jobName),
+				Suggestion: suggestion,
 				JobName:    jobName,
 			})
 		}
@@ -35,23 +89,254 @@ func CheckRetryConfiguration(config *parser.GitLabConfig) []types.Issue {
 	return issues
 }
 
+func isRetryReason(reason string) bool {
+	for _, known := range parser.RetryReasons {
+		if reason == known {
+			return true
+		}
+	}
+	return false
+}
+
+// closestRetryReason returns the retry reason nearest to reason by edit
+// distance, or "" when none is close enough to be a plausible typo
+func closestRetryReason(reason string) string {
+	closest, best := "", maxKeywordSuggestionDistance+1
+	for _, known := range parser.RetryReasons {
+		if distance := levenshtein(reason, known); distance < best && distance < len(known) {
+			closest, best = known, distance
+		}
+	}
+	return closest
+}
+
+// CheckMissingStages flags jobs whose stage, set directly or through
+// extends, isn't available in the pipeline
 func CheckMissingStages(config *parser.GitLabConfig) []types.Issue {
 	var issues []types.Issue
 
-	// Check if jobs reference non-existent stages
-	definedStages := make(map[string]bool)
-	for _, stage := range config.Stages {
-		definedStages[stage] = true
+	available := config.AvailableStages()
+	for jobName := range config.ConcreteJobs() {
+		stage := config.JobStage(jobName)
+		if available[stage] {
+			continue
+		}
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeReliability,
+			Severity:   types.SeverityHigh,
+			Path:       "jobs." + jobName + ".stage",
+			Message:    "Job references undefined stage: " + stage,
+			Suggestion: "Add '" + stage + "' to the stages list or use an existing stage",
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
+
+// CheckJobWithoutScript flags runnable jobs that end up with no script, which
+// GitLab rejects at runtime. Trigger jobs and scripts supplied through the
+// extends chain are taken into account. Jobs named like those of GitLab's
+// security templates are overrides of jobs whose script comes with the
+// template, and are skipped.
+func CheckJobWithoutScript(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	// Jobs may be partial overrides of jobs defined in remote, template or
+	// project includes, which are not always resolvable
+	if hasExternalIncludes(config) {
+		return issues
+	}
+
+	for jobName, job := range config.ConcreteJobs() {
+		if job.Trigger != nil || parser.IsSecurityTemplateJobName(jobName) {
+			continue
+		}
+		if hasResolvedScript(config, jobName, job) {
+			continue
+		}
+
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeReliability,
+			Severity:   types.SeverityHigh,
+			Path:       "jobs." + jobName + ".script",
+			Message:    "Job '" + jobName + "' has no script and will fail with \"script is empty\"",
+			Suggestion: "Add a script, extend a template that provides one, or use trigger for downstream pipelines",
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
+
+// CheckUnknownExtends flags extends targets that are still undefined once
+// includes are merged, which GitLab rejects when creating the pipeline.
+// Targets that may live in an unresolvable remote, template or project
+// include are reported at a lower severity.
+func CheckUnknownExtends(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	severity := types.SeverityHigh
+	location := "this configuration or its includes"
+	if hasExternalIncludes(config) {
+		severity = types.SeverityMedium
+		location = "any resolvable include; it may come from a remote, template or project include"
+	}
+
+	unknown := config.UnknownExtends()
+	jobNames := make([]string, 0, len(unknown))
+	for jobName := range unknown {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	for _, jobName := range jobNames {
+		for _, parent := range unknown[jobName] {
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeReliability,
+				Severity:   severity,
+				Path:       "jobs." + jobName + ".extends",
+				Message:    fmt.Sprintf("Job '%s' extends '%s', which isn't defined in %s", jobName, parent, location),
+				Suggestion: "Define the template, fix the name, or include the file that defines it",
+				JobName:    jobName,
+			})
+		}
+	}
+
+	return issues
+}
+
+func hasResolvedScript(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) bool {
+	if len(job.Script) > 0 {
+		return true
+	}
+	for _, parent := range config.ExtendsChain(jobName) {
+		if template := config.Jobs[parent]; len(template.Script) > 0 || template.Trigger != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func hasExternalIncludes(config *parser.GitLabConfig) bool {
+	for _, include := range config.Include {
+		if include.Remote != "" || include.Template != "" || include.Project != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckDeployResourceGroups flags environments deployed by several jobs that
+// don't share a resource_group, since those deployments can run concurrently
+// and overwrite each other
+func CheckDeployResourceGroups(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	deployers := make(map[string][]string)
+	for jobName := range config.ConcreteJobs() {
+		environment := config.JobEnvironment(jobName)
+		if environment == nil || environment.Name == "" || !isDeployAction(environment.Action) {
+			continue
+		}
+		deployers[environment.Name] = append(deployers[environment.Name], jobName)
+	}
+
+	environments := make([]string, 0, len(deployers))
+	for name := range deployers {
+		environments = append(environments, name)
+	}
+	sort.Strings(environments)
+
+	for _, environment := range environments {
+		jobNames := deployers[environment]
+		if len(jobNames) < 2 {
+			continue
+		}
+		sort.Strings(jobNames)
+
+		groups := make(map[string]bool)
+		for _, jobName := range jobNames {
+			groups[resolvedResourceGroup(config, jobName, config.Jobs[jobName])] = true
+		}
+		if len(groups) == 1 && !groups[""] {
+			continue
+		}
+
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeReliability,
+			Severity:   types.SeverityMedium,
+			Path:       "jobs." + jobNames[0] + ".resource_group",
+			Message:    "Jobs " + strings.Join(jobNames, ", ") + " deploy to environment '" + environment + "' without a shared resource_group",
+			Suggestion: "Set the same resource_group on every job deploying to '" + environment + "' so deployments run one at a time",
+			JobName:    jobNames[0],
+		})
+	}
+
+	return issues
+}
+
+// isDeployAction reports whether an environment action deploys, as opposed
+// to stopping, preparing, verifying or accessing the environment
+func isDeployAction(action string) bool {
+	return action == "" || action == "start"
+}
+
+func resolvedResourceGroup(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) string {
+	if job.ResourceGroup != "" {
+		return job.ResourceGroup
 	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template.ResourceGroup != "" {
+			return template.ResourceGroup
+		}
+	}
+	return ""
+}
+
+// maxKeywordSuggestionDistance bounds how far a typo may be from a keyword
+// before no suggestion is offered
+const maxKeywordSuggestionDistance = 2
+
+// CheckUnknownJobKeywords flags job keys that GitLab doesn't recognize, such
+// as `scripts:` for `script:`. It reads the raw YAML because a misspelled
+// keyword can keep a mapping from being recognized as a job at all. Hidden
+// jobs (starting with ".") are skipped since they often hold custom anchor
+// data.
+func CheckUnknownJobKeywords(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for jobName, value := range config.RawData {
+		if parser.IsTemplateJob(jobName) || parser.IsGlobalKeyword(jobName) {
+			continue
+		}
+		job, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		keys := make([]string, 0, len(job))
+		for key := range job {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if parser.IsJobKeyword(key) {
+				continue
+			}
 
-	for jobName, job := range config.Jobs {
-		if job.Stage != "" && !definedStages[job.Stage] {
+			suggestion := "Remove the key or check the GitLab CI keyword reference"
+			if closest := closestJobKeyword(key); closest != "" {
+				suggestion = "Did you mean '" + closest + "'?"
+			}
 			issues = append(issues, types.Issue{
 				Type:       types.IssueTypeReliability,
-				Severity:   types.SeverityHigh,
-				Path:       "jobs." + jobName + ".stage",
-				Message:    "Job references undefined stage: " + job.Stage,
-				Suggestion: "Add '" + job.Stage + "' to the stages list or use an existing stage",
+				Severity:   types.SeverityMedium,
+				Path:       "jobs." + jobName + "." + key,
+				Message:    "Unknown keyword '" + key + "' in job '" + jobName + "'",
+				Suggestion: suggestion,
 				JobName:    jobName,
 			})
 		}
@@ -59,3 +344,37 @@ func CheckMissingStages(config *parser.GitLabConfig) []types.Issue {
 
 	return issues
 }
+
+// closestJobKeyword returns the job keyword nearest to key by edit distance,
+// or "" when none is close enough to be a plausible typo
+func closestJobKeyword(key string) string {
+	closest, best := "", maxKeywordSuggestionDistance+1
+	for _, keyword := range parser.JobKeywords() {
+		if distance := levenshtein(key, keyword); distance < best && distance < len(keyword) {
+			closest, best = keyword, distance
+		}
+	}
+	return closest
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
@@ -95,6 +95,27 @@ func TestCheckRetryConfiguration(t *testing.T) {
 	}
 }
 
+func TestCheckRetryConfiguration_When(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"valid": {Retry: &parser.Retry{Max: 2, When: []string{"runner_system_failure", "api_failure"}}},
+			"typo":  {Retry: &parser.Retry{Max: 1, When: []string{"script_failur"}}},
+			"bogus": {Retry: &parser.Retry{Max: 1, When: []string{"flaky"}}},
+		},
+	}
+
+	issues := CheckRetryConfiguration(config)
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %d: %+v", len(issues), issues)
+	}
+	if issues[0].JobName != "bogus" || !strings.HasPrefix(issues[0].Suggestion, "Use one of:") {
+		t.Errorf("Expected the list of reasons for an unrecognizable value, got %+v", issues[0])
+	}
+	if issues[1].JobName != "typo" || issues[1].Suggestion != "Did you mean 'script_failure'?" {
+		t.Errorf("Expected a suggestion for the typo, got %+v", issues[1])
+	}
+}
+
 func TestCheckMissingStages(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -188,9 +209,9 @@ func TestRegisterChecks(t *testing.T) {
 
 	RegisterChecks(registry)
 
-	// Check that both checks were registered
-	if len(registry.checks) != 2 {
-		t.Errorf("Expected 2 checks to be registered, got %d", len(registry.checks))
+	// Check that all checks were registered
+	if len(registry.checks) != 20 {
+		t.Errorf("Expected 20 checks to be registered, got %d", len(registry.checks))
 	}
 
 	// Check specific registrations
@@ -207,6 +228,101 @@ func TestRegisterChecks(t *testing.T) {
 	}
 }
 
+func TestCheckJobWithoutScript(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       *parser.GitLabConfig
+		expectIssues []string
+	}{
+		{
+			name: "job without script",
+			config: &parser.GitLabConfig{
+				Jobs: map[string]*parser.JobConfig{
+					"build": {Stage: "build"},
+					"test":  {Stage: "test", Script: []string{"make test"}},
+				},
+			},
+			expectIssues: []string{"build"},
+		},
+		{
+			name: "templates and trigger jobs are excluded",
+			config: &parser.GitLabConfig{
+				Jobs: map[string]*parser.JobConfig{
+					".base":      {Stage: "build"},
+					"downstream": {Stage: "deploy", Trigger: "group/project"},
+				},
+			},
+		},
+		{
+			name: "script supplied through a multi-level extends chain",
+			config: &parser.GitLabConfig{
+				Jobs: map[string]*parser.JobConfig{
+					".script": {Script: []string{"make"}},
+					".build":  {Stage: "build", Extends: ".script"},
+					"build":   {Extends: []interface{}{".build"}},
+				},
+			},
+		},
+		{
+			name: "security template job overrides are excluded",
+			config: &parser.GitLabConfig{
+				Jobs: map[string]*parser.JobConfig{
+					"dependency_scanning": {Stage: "test", Variables: map[string]interface{}{"DS_EXCLUDED_PATHS": "vendor"}},
+					"semgrep-sast":        {Stage: "test"},
+				},
+			},
+		},
+		{
+			name: "extends chain without script",
+			config: &parser.GitLabConfig{
+				Jobs: map[string]*parser.JobConfig{
+					".base": {Stage: "build", Extends: ".missing"},
+					"build": {Extends: ".base"},
+				},
+			},
+			expectIssues: []string{"build"},
+		},
+		{
+			name: "circular extends does not loop",
+			config: &parser.GitLabConfig{
+				Jobs: map[string]*parser.JobConfig{
+					".a":    {Extends: ".b"},
+					".b":    {Extends: ".a"},
+					"build": {Extends: ".a"},
+				},
+			},
+			expectIssues: []string{"build"},
+		},
+		{
+			name: "external includes may define the job",
+			config: &parser.GitLabConfig{
+				Include: []parser.Include{{Template: "Jobs/SAST.gitlab-ci.yml"}},
+				Jobs: map[string]*parser.JobConfig{
+					"sast": {Stage: "test"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := CheckJobWithoutScript(tt.config)
+
+			if len(issues) != len(tt.expectIssues) {
+				t.Fatalf("Expected %d issues, got %d: %v", len(tt.expectIssues), len(issues), issues)
+			}
+			for i, jobName := range tt.expectIssues {
+				if issues[i].JobName != jobName || issues[i].Path != "jobs."+jobName+".script" {
+					t.Errorf("Unexpected issue %+v", issues[i])
+				}
+				if issues[i].Severity != types.SeverityHigh {
+					t.Errorf("Expected high severity, got %s", issues[i].Severity)
+				}
+			}
+		})
+	}
+}
+
 // Mock registry for testing
 type registeredCheck struct {
 	name      string
@@ -225,3 +341,152 @@ func (r *mockRegistry) Register(name string, issueType types.IssueType, checkFun
 		checkFunc: checkFunc,
 	}
 }
+
+func (r *mockRegistry) RegisterWithParams(name string, issueType types.IssueType, checkFunc types.ParamCheckFunc) {
+	r.checks[name] = registeredCheck{
+		name:      name,
+		issueType: issueType,
+		checkFunc: func(config *parser.GitLabConfig) []types.Issue {
+			return checkFunc(config, nil)
+		},
+	}
+}
+
+func (r *mockRegistry) Describe(name string, doc types.CheckDoc) {}
+
+func TestCheckUnknownJobKeywords(t *testing.T) {
+	config := &parser.GitLabConfig{
+		RawData: map[string]interface{}{
+			"stages":    []interface{}{"build"},
+			"variables": map[string]interface{}{"FOO": "bar"},
+			".template": map[string]interface{}{"custom_data": "ok"},
+			"build": map[string]interface{}{
+				"stage":   "build",
+				"scripts": []interface{}{"make"},
+			},
+			"deploy": map[string]interface{}{
+				"script":      []interface{}{"./deploy.sh"},
+				"enviroment":  "production",
+				"owner_notes": "ask ops",
+			},
+		},
+	}
+
+	issues := CheckUnknownJobKeywords(config)
+
+	expected := map[string]string{
+		"jobs.build.scripts":      "Did you mean 'script'?",
+		"jobs.deploy.enviroment":  "Did you mean 'environment'?",
+		"jobs.deploy.owner_notes": "Remove the key or check the GitLab CI keyword reference",
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %d: %+v", len(expected), len(issues), issues)
+	}
+	for _, issue := range issues {
+		suggestion, exists := expected[issue.Path]
+		if !exists {
+			t.Errorf("Unexpected issue at %s", issue.Path)
+			continue
+		}
+		if issue.Suggestion != suggestion {
+			t.Errorf("Expected suggestion %q for %s, got %q", suggestion, issue.Path, issue.Suggestion)
+		}
+	}
+}
+
+func TestCheckUnknownExtends(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			".template": {Script: []string{"make"}},
+			"ok":        {Extends: ".template"},
+			"broken":    {Extends: []interface{}{".template", ".missing"}},
+		},
+	}
+
+	issues := CheckUnknownExtends(config)
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d: %+v", len(issues), issues)
+	}
+	if issues[0].Path != "jobs.broken.extends" || issues[0].Severity != types.SeverityHigh {
+		t.Errorf("Expected high severity issue on broken's extends, got %+v", issues[0])
+	}
+	if !strings.Contains(issues[0].Message, "'.missing'") {
+		t.Errorf("Expected message to name the missing template, got %q", issues[0].Message)
+	}
+
+	// The template may come from an include that couldn't be fetched
+	config.Include = []parser.Include{{Remote: "https://example.com/ci.yml"}}
+	issues = CheckUnknownExtends(config)
+	if len(issues) != 1 || issues[0].Severity != types.SeverityMedium {
+		t.Errorf("Expected a medium severity issue with remote includes, got %+v", issues)
+	}
+}
+
+func TestCheckMissingStages_ResolvedStages(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			".template": {Stage: "release"},
+			"setup":     {Stage: ".pre"},
+			"build":     {Stage: "build"},
+			"publish":   {Extends: ".template"},
+		},
+	}
+
+	// Without declared stages the defaults and .pre/.post are available, and
+	// publish inherits an undefined stage from its template
+	issues := CheckMissingStages(config)
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d: %+v", len(issues), issues)
+	}
+	if issues[0].JobName != "publish" || !strings.Contains(issues[0].Message, "release") {
+		t.Errorf("Expected publish's inherited stage to be flagged, got %+v", issues[0])
+	}
+}
+
+func TestCheckDeployResourceGroups(t *testing.T) {
+	tests := []struct {
+		name         string
+		jobs         map[string]*parser.JobConfig
+		expectIssues []string
+	}{
+		{
+			name: "deploys to the same environment without resource_group",
+			jobs: map[string]*parser.JobConfig{
+				"deploy_app":    {Environment: &parser.Environment{Name: "production"}},
+				"deploy_worker": {Environment: &parser.Environment{Name: "production"}, ResourceGroup: "production"},
+				"deploy_review": {Environment: &parser.Environment{Name: "review"}},
+			},
+			expectIssues: []string{"jobs.deploy_app.resource_group"},
+		},
+		{
+			name: "shared resource_group through extends",
+			jobs: map[string]*parser.JobConfig{
+				".deploy":       {ResourceGroup: "production"},
+				"deploy_app":    {Extends: ".deploy", Environment: &parser.Environment{Name: "production"}},
+				"deploy_worker": {Extends: ".deploy", Environment: &parser.Environment{Name: "production"}},
+			},
+		},
+		{
+			name: "stop jobs are not deployments",
+			jobs: map[string]*parser.JobConfig{
+				"deploy_review": {Environment: &parser.Environment{Name: "review", OnStop: "stop_review"}},
+				"stop_review":   {Environment: &parser.Environment{Name: "review", Action: "stop"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := CheckDeployResourceGroups(&parser.GitLabConfig{Jobs: tt.jobs})
+
+			if len(issues) != len(tt.expectIssues) {
+				t.Fatalf("Expected %d issues, got %d: %+v", len(tt.expectIssues), len(issues), issues)
+			}
+			for i, path := range tt.expectIssues {
+				if issues[i].Path != path {
+					t.Errorf("Expected issue at %s, got %s", path, issues[i].Path)
+				}
+			}
+		})
+	}
+}
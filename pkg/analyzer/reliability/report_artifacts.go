@@ -0,0 +1,156 @@
+package reliability
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckReportOnlyDependencies flags jobs that take artifacts from an
+// upstream job whose only artifacts are reports. Report files aren't
+// downloaded by later jobs, so a job listing the upstream job in
+// dependencies, needing it with `artifacts: true`, or reading one of its
+// report files finds nothing. dotenv reports are passed on as variables and
+// aren't flagged.
+func CheckReportOnlyDependencies(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for _, jobName := range config.ConcreteJobNames() {
+		job := config.Jobs[jobName]
+		scripts := resolvedScripts(config, jobName, job)
+		reported := make(map[string]bool)
+
+		flag := func(upstreamName, path, how string) {
+			if reported[upstreamName] {
+				return
+			}
+			upstream := config.Jobs[upstreamName]
+			if upstream == nil {
+				return
+			}
+			reports := reportOnlyArtifacts(config, upstreamName, upstream)
+			if reports == nil {
+				return
+			}
+			if how == "" {
+				if !scriptsMention(scripts, reports.Paths()) {
+					return
+				}
+				how = "reads its report files"
+			}
+
+			reported[upstreamName] = true
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeReliability,
+				Severity:   types.SeverityMedium,
+				Path:       path,
+				Message:    fmt.Sprintf("Job '%s' %s from '%s', whose only artifacts are reports (%s), which later jobs don't download", jobName, how, upstreamName, strings.Join(reports.Types(), ", ")),
+				Suggestion: fmt.Sprintf("Also list the files the job uses under artifacts:paths in '%s'", upstreamName),
+				JobName:    jobName,
+			})
+		}
+
+		for i, dependency := range resolvedDependencies(config, jobName, job) {
+			flag(dependency, fmt.Sprintf("jobs.%s.dependencies[%d]", jobName, i), "takes artifacts")
+		}
+		for i, need := range resolvedNeeds(config, jobName, job) {
+			if need.Job == "" || need.IsExternal() || !need.DownloadsArtifacts() {
+				continue
+			}
+			how := ""
+			if need.Artifacts != nil {
+				how = "needs artifacts"
+			}
+			flag(need.Job, fmt.Sprintf("jobs.%s.needs[%d]", jobName, i), how)
+		}
+	}
+
+	return issues
+}
+
+// reportOnlyArtifacts returns the job's reports when they are all it
+// uploads, and nil when it has artifact paths, untracked files, a dotenv
+// report or no reports at all
+func reportOnlyArtifacts(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) *parser.Reports {
+	artifacts := job.Artifacts
+	if artifacts == nil {
+		chain := config.ExtendsChain(jobName)
+		for i := len(chain) - 1; i >= 0; i-- {
+			if template := config.Jobs[chain[i]]; template != nil && template.Artifacts != nil {
+				artifacts = template.Artifacts
+				break
+			}
+		}
+	}
+	if artifacts == nil || len(artifacts.Paths) > 0 || artifacts.Untracked {
+		return nil
+	}
+	if len(artifacts.Reports.Types()) == 0 || artifacts.Reports.Has("dotenv") {
+		return nil
+	}
+	return artifacts.Reports
+}
+
+// resolvedDependencies returns the job's dependencies, or the ones it
+// inherits from the nearest template it extends
+func resolvedDependencies(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) []string {
+	if job.Dependencies != nil {
+		return job.Dependencies
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.Dependencies != nil {
+			return template.Dependencies
+		}
+	}
+	return nil
+}
+
+// resolvedScripts returns the job's before_script, script and after_script
+// lines, each taken from the job or, when unset, from the nearest template
+// it extends
+func resolvedScripts(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) []string {
+	chain := config.ExtendsChain(jobName)
+	resolve := func(field func(*parser.JobConfig) []string) []string {
+		if lines := field(job); len(lines) > 0 {
+			return lines
+		}
+		for i := len(chain) - 1; i >= 0; i-- {
+			if template := config.Jobs[chain[i]]; template != nil && len(field(template)) > 0 {
+				return field(template)
+			}
+		}
+		return nil
+	}
+
+	var lines []string
+	lines = append(lines, resolve(func(j *parser.JobConfig) []string { return j.BeforeScript })...)
+	lines = append(lines, resolve(func(j *parser.JobConfig) []string { return j.Script })...)
+	lines = append(lines, resolve(func(j *parser.JobConfig) []string { return j.AfterScript })...)
+	return lines
+}
+
+// scriptsMention reports whether a script line names one of the files,
+// by its path or its file name. Wildcard paths are matched by the part
+// before the first wildcard.
+func scriptsMention(scripts, files []string) bool {
+	for _, file := range files {
+		file = strings.TrimPrefix(file, "./")
+		if wildcard := strings.IndexAny(file, "*?["); wildcard >= 0 {
+			file = strings.TrimSuffix(file[:wildcard], "/")
+		}
+		if file == "" || file == "." {
+			continue
+		}
+		base := path.Base(file)
+		for _, line := range scripts {
+			if strings.Contains(line, file) || strings.Contains(line, base) {
+				return true
+			}
+		}
+	}
+	return false
+}
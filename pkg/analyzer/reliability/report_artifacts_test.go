@@ -0,0 +1,64 @@
+package reliability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckReportOnlyDependencies(t *testing.T) {
+	yes := true
+	junitOnly := &parser.Artifacts{Reports: &parser.Reports{JUnit: []string{"report.xml"}}}
+
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"test":    {Script: []string{"make test"}, Artifacts: junitOnly},
+			".report": {Artifacts: junitOnly},
+			"lint":    {Script: []string{"make lint"}, Extends: ".report"},
+			"build": {Script: []string{"make"}, Artifacts: &parser.Artifacts{
+				Paths:   []string{"dist/"},
+				Reports: &parser.Reports{JUnit: []string{"build.xml"}},
+			}},
+			"env": {Script: []string{"./version.sh"}, Artifacts: &parser.Artifacts{
+				Reports: &parser.Reports{Dotenv: []string{"build.env"}},
+			}},
+
+			"publish":  {Script: []string{"./upload.sh"}, Dependencies: []string{"test", "build", "env"}},
+			"explicit": {Script: []string{"./notify.sh"}, Needs: []parser.Need{{Job: "lint", Artifacts: &yes}}},
+			"reader":   {Script: []string{"cat report.xml"}, Needs: []parser.Need{{Job: "test"}}},
+			"ordering": {Script: []string{"./deploy.sh"}, Needs: []parser.Need{{Job: "test"}, {Job: "lint"}}},
+			"both":     {Script: []string{"cat report.xml"}, Needs: []parser.Need{{Job: "test"}}, Dependencies: []string{"test"}},
+		},
+	}
+
+	issues := CheckReportOnlyDependencies(config)
+
+	flagged := make(map[string]string)
+	for _, issue := range issues {
+		flagged[issue.JobName] += issue.Path + ";"
+	}
+	expected := map[string]string{
+		"publish":  "jobs.publish.dependencies[0];",
+		"explicit": "jobs.explicit.needs[0];",
+		"reader":   "jobs.reader.needs[0];",
+		"both":     "jobs.both.dependencies[0];",
+	}
+	for jobName, path := range expected {
+		if flagged[jobName] != path {
+			t.Errorf("Expected %s to be flagged at %s, got %q", jobName, path, flagged[jobName])
+		}
+	}
+	if len(flagged) != len(expected) {
+		t.Errorf("Expected only %d jobs flagged, got %v", len(expected), flagged)
+	}
+
+	for _, issue := range issues {
+		if issue.JobName == "publish" && (!strings.Contains(issue.Message, "'test'") || !strings.Contains(issue.Message, "junit")) {
+			t.Errorf("Expected the upstream job and report type in the message, got %q", issue.Message)
+		}
+		if issue.JobName == "explicit" && !strings.Contains(issue.Suggestion, "'lint'") {
+			t.Errorf("Expected the suggestion to name the upstream job, got %q", issue.Suggestion)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package reliability
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// jobOnlyKeywords are keywords that only make sense in a job, so a top-level
+// mapping setting one of them was meant to be a job
+var jobOnlyKeywords = []string{"script", "stage", "rules", "needs", "extends", "trigger", "run", "environment", "dependencies"}
+
+// globalKeywordSyntax lists the job keywords that are also part of a global
+// keyword's own syntax
+var globalKeywordSyntax = map[string][]string{
+	"workflow": {"rules"},
+	"include":  {"rules"},
+}
+
+// CheckGlobalKeywordsAsJobs flags jobs named after a global keyword such as
+// image or cache. GitLab reads such a key as global configuration, so the
+// job silently disappears from the pipeline.
+func CheckGlobalKeywordsAsJobs(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	keys := make([]string, 0, len(config.RawData))
+	for key := range config.RawData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	flagged := make(map[string]bool)
+	for _, key := range keys {
+		if !parser.IsGlobalKeyword(key) {
+			continue
+		}
+		value, ok := config.RawData[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, keyword := range jobOnlyKeywords {
+			if _, exists := value[keyword]; !exists || types.ContainsString(globalKeywordSyntax[key], keyword) {
+				continue
+			}
+			flagged[key] = true
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeReliability,
+				Severity:   types.SeverityHigh,
+				Path:       key,
+				Message:    fmt.Sprintf("'%s' sets the job keyword '%s', but '%s' is a global keyword, so GitLab doesn't create a job from it", key, keyword, key),
+				Suggestion: fmt.Sprintf("Rename the job, for example to '%s_job'", key),
+			})
+			break
+		}
+	}
+
+	// Jobs merged in from other sources aren't in RawData
+	jobNames := make([]string, 0, len(config.Jobs))
+	for jobName := range config.Jobs {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	for _, jobName := range jobNames {
+		if !parser.IsGlobalKeyword(jobName) || flagged[jobName] {
+			continue
+		}
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeReliability,
+			Severity:   types.SeverityHigh,
+			Path:       "jobs." + jobName,
+			Message:    fmt.Sprintf("Job '%s' is named after a global keyword, so GitLab doesn't create it", jobName),
+			Suggestion: fmt.Sprintf("Rename the job, for example to '%s_job'", jobName),
+			JobName:    jobName,
+		})
+	}
+
+	return issues
+}
@@ -0,0 +1,50 @@
+package reliability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckGlobalKeywordsAsJobs(t *testing.T) {
+	config, err := parser.Parse([]byte(`
+workflow:
+  rules:
+    - if: $CI_COMMIT_BRANCH
+include:
+  local: ci/common.yml
+  rules:
+    - if: $CI_COMMIT_BRANCH
+image:
+  stage: build
+  script: [docker build -t app .]
+cache:
+  key: deps
+  paths: [node_modules/]
+pages:
+  script: [make docs]
+  artifacts:
+    paths: [public]
+build:
+  script: [make]
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	issues := CheckGlobalKeywordsAsJobs(config)
+	if len(issues) != 1 || issues[0].Path != "image" || !strings.Contains(issues[0].Message, "'script'") {
+		t.Fatalf("Expected only the image job to be flagged, got %+v", issues)
+	}
+	if _, exists := config.Jobs["pages"]; !exists {
+		t.Error("Expected pages to be parsed as a job")
+	}
+
+	// A job merged in under a reserved name is flagged too
+	config.Jobs["services"] = &parser.JobConfig{Script: []string{"make"}}
+	issues = CheckGlobalKeywordsAsJobs(config)
+	if len(issues) != 2 || issues[1].JobName != "services" {
+		t.Errorf("Expected the services job to be flagged, got %+v", issues)
+	}
+}
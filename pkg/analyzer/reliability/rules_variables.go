@@ -0,0 +1,129 @@
+package reliability
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+var (
+	// scriptVariablePattern finds $VAR and ${VAR} references in script lines
+	scriptVariablePattern = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)`)
+	// shellAssignmentPattern finds variables a script assigns itself, such
+	// as `export VAR=...` or `VAR=$(...)`
+	shellAssignmentPattern = regexp.MustCompile(`(?:^|[\s;&|(])(?:export\s+|local\s+|readonly\s+)?([A-Za-z_][A-Za-z0-9_]*)=`)
+)
+
+// CheckRulesVariablesScope flags variables a job's scripts use that are only
+// set by some of its rules, so they are empty in pipelines where another
+// rule runs the job. Variables defined on the job, its templates or globally
+// are always set, as are those the matching workflow rule injects. Each
+// representative pipeline is evaluated; pipelines whose matching rule can't
+// be decided are skipped.
+func CheckRulesVariablesScope(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	for _, jobName := range config.ConcreteJobNames() {
+		job := config.Jobs[jobName]
+		rules := resolvedRules(config, jobName, job)
+
+		ruleVars := make(map[string]bool)
+		for _, rule := range rules {
+			for name := range rule.Variables {
+				ruleVars[name] = true
+			}
+		}
+		if len(ruleVars) == 0 {
+			continue
+		}
+
+		var candidates []string
+		for _, name := range scriptVariables(resolvedScripts(config, jobName, job)) {
+			if _, defined := config.JobVariable(jobName, name); ruleVars[name] && !defined {
+				candidates = append(candidates, name)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		undefined := make(map[string][]string)
+		for _, scenario := range parser.RepresentativeScenarios() {
+			created, workflowVars := config.EvaluateWorkflow(scenario.Context)
+			if !created {
+				continue
+			}
+			outcome, err := parser.EvaluateRules(rules, config.RuleVariables(scenario.Context, workflowVars, job))
+			if err != nil || !outcome.Runs {
+				continue
+			}
+			for _, name := range candidates {
+				_, fromRule := outcome.Variables[name]
+				_, fromWorkflow := workflowVars[name]
+				if !fromRule && !fromWorkflow {
+					undefined[name] = append(undefined[name], scenario.Label)
+				}
+			}
+		}
+
+		for _, name := range candidates {
+			contexts := undefined[name]
+			if len(contexts) == 0 {
+				continue
+			}
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeReliability,
+				Severity:   types.SeverityMedium,
+				Path:       "jobs." + jobName + ".rules",
+				Message:    fmt.Sprintf("Variable '%s' used by job '%s' is only set by some of its rules and is undefined in: %s", name, jobName, strings.Join(contexts, ", ")),
+				Suggestion: fmt.Sprintf("Give '%s' a default in the job's variables, or set it in every rule that runs the job", name),
+				JobName:    jobName,
+			})
+		}
+	}
+
+	return issues
+}
+
+// resolvedRules returns the job's rules or, when unset, those of the
+// nearest template it extends
+func resolvedRules(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) []parser.Rule {
+	if len(job.Rules) > 0 {
+		return job.Rules
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && len(template.Rules) > 0 {
+			return template.Rules
+		}
+	}
+	return nil
+}
+
+// scriptVariables returns the variables the script lines reference, sorted,
+// leaving out those the scripts assign themselves
+func scriptVariables(scripts []string) []string {
+	assigned := make(map[string]bool)
+	for _, line := range scripts {
+		for _, match := range shellAssignmentPattern.FindAllStringSubmatch(line, -1) {
+			assigned[match[1]] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, line := range scripts {
+		for _, match := range scriptVariablePattern.FindAllStringSubmatch(line, -1) {
+			if name := match[1]; !seen[name] && !assigned[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,71 @@
+package reliability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckRulesVariablesScope(t *testing.T) {
+	yamlContent := `
+variables:
+  REGION: eu-west-1
+
+.deploy:
+  rules:
+    - if: $CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH
+      variables:
+        DEPLOY_ENV: production
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+
+deploy:
+  extends: .deploy
+  script:
+    - ./deploy.sh --env "$DEPLOY_ENV" --region $REGION
+
+with_default:
+  extends: .deploy
+  variables:
+    DEPLOY_ENV: review
+  script:
+    - ./deploy.sh --env "$DEPLOY_ENV"
+
+every_rule:
+  script:
+    - ./deploy.sh --env "${DEPLOY_ENV}"
+  rules:
+    - if: $CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH
+      variables:
+        DEPLOY_ENV: production
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+      variables:
+        DEPLOY_ENV: review
+
+assigned:
+  extends: .deploy
+  script:
+    - export DEPLOY_ENV=${DEPLOY_ENV:-review}
+    - ./deploy.sh --env "$DEPLOY_ENV"
+`
+	config, err := parser.Parse([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	issues := CheckRulesVariablesScope(config)
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d: %v", len(issues), issues)
+	}
+
+	issue := issues[0]
+	if issue.JobName != "deploy" || issue.Path != "jobs.deploy.rules" {
+		t.Errorf("Expected the deploy job's rules to be flagged, got %s at %s", issue.JobName, issue.Path)
+	}
+	if !strings.Contains(issue.Message, "'DEPLOY_ENV'") || !strings.Contains(issue.Message, "undefined in: merge request") {
+		t.Errorf("Expected the variable and the merge request pipeline in the message, got %q", issue.Message)
+	}
+	if strings.Contains(issue.Message, "default branch") {
+		t.Errorf("Expected pipelines setting the variable to be left out, got %q", issue.Message)
+	}
+}
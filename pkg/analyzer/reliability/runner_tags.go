@@ -0,0 +1,134 @@
+package reliability
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// defaultMinTagUsage is how many jobs must use a runner tag before it stops
+// looking like a typo. Overridable through the check's custom_params
+// ("min_tag_usage"); jobs that may run untagged are listed in
+// "untagged_allowlist".
+const defaultMinTagUsage = 2
+
+// CheckRunnerTags flags jobs without runner tags in pipelines where other
+// jobs select runners by tag, since they can land on shared runners
+// unexpectedly, and tags used by too few jobs, which are often misspelled.
+// Tags set in default: or inherited through extends count for the job.
+func CheckRunnerTags(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	allowlist := make(map[string]bool)
+	for _, jobName := range types.StringListParam(params, "untagged_allowlist", nil) {
+		allowlist[jobName] = true
+	}
+	minUsage := types.IntParam(params, "min_tag_usage", defaultMinTagUsage)
+
+	jobNames := make([]string, 0, len(config.Jobs))
+	for jobName, job := range config.ConcreteJobs() {
+		// Trigger jobs don't run on a runner
+		if job.Trigger != nil {
+			continue
+		}
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	var untagged []string
+	tagUsers := make(map[string][]string)
+	for _, jobName := range jobNames {
+		tags := resolvedTags(config, jobName, config.Jobs[jobName])
+		if len(tags) == 0 {
+			untagged = append(untagged, jobName)
+			continue
+		}
+		for _, tag := range tags {
+			tagUsers[tag] = append(tagUsers[tag], jobName)
+		}
+	}
+	if len(tagUsers) == 0 {
+		return issues
+	}
+
+	for _, jobName := range untagged {
+		if allowlist[jobName] {
+			continue
+		}
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeReliability,
+			Severity:   types.SeverityMedium,
+			Path:       "jobs." + jobName + ".tags",
+			Message:    fmt.Sprintf("Job '%s' has no runner tags while other jobs select runners by tag", jobName),
+			Suggestion: "Add tags to the job or to default:, or add it to untagged_allowlist if any runner will do",
+			JobName:    jobName,
+		})
+	}
+
+	if len(jobNames)-len(untagged) < minUsage {
+		return issues
+	}
+
+	tags := make([]string, 0, len(tagUsers))
+	for tag := range tagUsers {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		users := tagUsers[tag]
+		if len(users) >= minUsage {
+			continue
+		}
+		suggestion := "Check the tag for typos; jobs with a tag no runner has stay pending"
+		if closest := closestTag(tag, tags, tagUsers, minUsage); closest != "" {
+			suggestion = "Did you mean '" + closest + "'?"
+		}
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeReliability,
+			Severity:   types.SeverityLow,
+			Path:       "jobs." + users[0] + ".tags",
+			Message:    fmt.Sprintf("Runner tag '%s' is only used by %s", tag, strings.Join(users, ", ")),
+			Suggestion: suggestion,
+			JobName:    users[0],
+		})
+	}
+
+	return issues
+}
+
+// resolvedTags returns the job's tags, or those it inherits from the nearest
+// template it extends or from default:
+func resolvedTags(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) []string {
+	if job.Tags != nil {
+		return job.Tags
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.Tags != nil {
+			return template.Tags
+		}
+	}
+	if config.Default != nil && job.InheritsDefault("tags") {
+		return config.Default.Tags
+	}
+	return nil
+}
+
+// closestTag returns the commonly used tag nearest to tag by edit distance,
+// or "" when none is close enough to be a plausible typo
+func closestTag(tag string, tags []string, tagUsers map[string][]string, minUsage int) string {
+	closest, best := "", maxKeywordSuggestionDistance+1
+	for _, candidate := range tags {
+		if candidate == tag || len(tagUsers[candidate]) < minUsage {
+			continue
+		}
+		if distance := levenshtein(tag, candidate); distance < best && distance < len(candidate) {
+			closest, best = candidate, distance
+		}
+	}
+	return closest
+}
@@ -0,0 +1,99 @@
+package reliability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckRunnerTags(t *testing.T) {
+	t.Run("untagged jobs among tagged ones", func(t *testing.T) {
+		config := &parser.GitLabConfig{
+			Jobs: map[string]*parser.JobConfig{
+				".gpu":     {Tags: []string{"gpu"}},
+				"build":    {Script: []string{"make"}, Tags: []string{"docker"}},
+				"test":     {Script: []string{"make test"}, Tags: []string{"docker"}},
+				"train":    {Script: []string{"python train.py"}, Extends: ".gpu"},
+				"evaluate": {Script: []string{"python eval.py"}, Extends: ".gpu"},
+				"notify":   {Script: []string{"./notify.sh"}},
+				"report":   {Script: []string{"./report.sh"}},
+				"deploy":   {Trigger: "group/deployments"},
+			},
+		}
+
+		issues := CheckRunnerTags(config, nil)
+		var flagged []string
+		for _, issue := range issues {
+			flagged = append(flagged, issue.JobName)
+			if issue.Severity != types.SeverityMedium {
+				t.Errorf("Expected medium severity for untagged jobs, got %s", issue.Severity)
+			}
+		}
+		if strings.Join(flagged, ",") != "notify,report" {
+			t.Errorf("Expected notify and report to be flagged, got %v", flagged)
+		}
+
+		issues = CheckRunnerTags(config, map[string]interface{}{
+			"untagged_allowlist": []interface{}{"notify", "report"},
+		})
+		if len(issues) != 0 {
+			t.Errorf("Expected allowlisted jobs not to be flagged, got %+v", issues)
+		}
+	})
+
+	t.Run("default tags", func(t *testing.T) {
+		config := &parser.GitLabConfig{
+			Default: &parser.JobConfig{Tags: []string{"docker"}},
+			Jobs: map[string]*parser.JobConfig{
+				"build": {Script: []string{"make"}},
+				"test":  {Script: []string{"make test"}},
+				"lint":  {Script: []string{"make lint"}, Inherit: &parser.Inherit{Default: false}},
+			},
+		}
+
+		issues := CheckRunnerTags(config, nil)
+		if len(issues) != 1 || issues[0].JobName != "lint" {
+			t.Errorf("Expected only lint, which doesn't inherit default tags, to be flagged, got %+v", issues)
+		}
+	})
+
+	t.Run("tag used by a single job", func(t *testing.T) {
+		config := &parser.GitLabConfig{
+			Jobs: map[string]*parser.JobConfig{
+				"build":   {Script: []string{"make"}, Tags: []string{"docker"}},
+				"test":    {Script: []string{"make test"}, Tags: []string{"docker"}},
+				"package": {Script: []string{"make package"}, Tags: []string{"dokcer"}},
+			},
+		}
+
+		issues := CheckRunnerTags(config, nil)
+		if len(issues) != 1 {
+			t.Fatalf("Expected 1 issue, got %+v", issues)
+		}
+		issue := issues[0]
+		if issue.JobName != "package" || issue.Severity != types.SeverityLow {
+			t.Errorf("Expected a low severity issue for package, got %+v", issue)
+		}
+		if !strings.Contains(issue.Message, "'dokcer'") || issue.Suggestion != "Did you mean 'docker'?" {
+			t.Errorf("Expected the misspelled tag with a suggestion, got %q / %q", issue.Message, issue.Suggestion)
+		}
+
+		if issues := CheckRunnerTags(config, map[string]interface{}{"min_tag_usage": 1}); len(issues) != 0 {
+			t.Errorf("Expected no issues with min_tag_usage 1, got %+v", issues)
+		}
+	})
+
+	t.Run("no tags anywhere", func(t *testing.T) {
+		config := &parser.GitLabConfig{
+			Jobs: map[string]*parser.JobConfig{
+				"build": {Script: []string{"make"}},
+				"test":  {Script: []string{"make test"}},
+			},
+		}
+		if issues := CheckRunnerTags(config, nil); len(issues) != 0 {
+			t.Errorf("Expected no issues when no job uses tags, got %+v", issues)
+		}
+	})
+}
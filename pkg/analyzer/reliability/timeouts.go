@@ -0,0 +1,154 @@
+package reliability
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// defaultMaxTimeout is the longest timeout CheckJobTimeouts accepts unless
+// custom_params sets "max_timeout". It matches the runner timeout of
+// GitLab.com's hosted runners; projects default to 1h.
+const defaultMaxTimeout = 3 * time.Hour
+
+// CheckJobTimeouts validates timeout values. Values GitLab can't parse fail
+// the pipeline, so they're reported wherever they're written, including
+// templates and default. For each job, an effective timeout above
+// max_timeout is flagged, since the project or runner limit silently caps
+// it, as is a timeout within the limit that retries can stretch beyond it.
+func CheckJobTimeouts(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	maxTimeout := durationParam(params, "max_timeout", defaultMaxTimeout)
+
+	if config.Default != nil && config.Default.Timeout != "" {
+		if _, err := parser.ParseDuration(config.Default.Timeout); err != nil {
+			issues = append(issues, invalidTimeoutIssue("default.timeout", "", config.Default.Timeout))
+		}
+	}
+	for _, jobName := range config.GetAllJobNames() {
+		job := config.Jobs[jobName]
+		if job == nil || job.Timeout == "" {
+			continue
+		}
+		if _, err := parser.ParseDuration(job.Timeout); err != nil {
+			issues = append(issues, invalidTimeoutIssue("jobs."+jobName+".timeout", jobName, job.Timeout))
+		}
+	}
+
+	for _, jobName := range config.ConcreteJobNames() {
+		value := effectiveTimeout(config, jobName)
+		timeout, err := parser.ParseDuration(value)
+		if value == "" || err != nil {
+			continue
+		}
+
+		if timeout > maxTimeout {
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeReliability,
+				Severity:   types.SeverityMedium,
+				Path:       "jobs." + jobName + ".timeout",
+				Message:    fmt.Sprintf("Job '%s' has a timeout of %s, longer than %s", jobName, value, formatDuration(maxTimeout)),
+				Suggestion: "GitLab caps job timeouts at the project's and the runner's maximum, so the job is cancelled earlier than configured; shorten it or split the job",
+				JobName:    jobName,
+			})
+			continue
+		}
+
+		retries := effectiveRetries(config, jobName)
+		if total := timeout * time.Duration(retries+1); retries > 0 && total > maxTimeout {
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeReliability,
+				Severity:   types.SeverityLow,
+				Path:       "jobs." + jobName + ".retry",
+				Message:    fmt.Sprintf("Job '%s' can run for %s: a timeout of %s over %d attempts, longer than %s", jobName, formatDuration(total), value, retries+1, formatDuration(maxTimeout)),
+				Suggestion: "Lower the timeout or the retry count so a job that keeps failing or hanging gives up within the limit",
+				JobName:    jobName,
+			})
+		}
+	}
+
+	return issues
+}
+
+func invalidTimeoutIssue(path, jobName, value string) types.Issue {
+	return types.Issue{
+		Type:       types.IssueTypeReliability,
+		Severity:   types.SeverityHigh,
+		Path:       path,
+		Message:    fmt.Sprintf("Timeout '%s' isn't a valid duration", value),
+		Suggestion: "Use a duration such as '90m', '1h 30m' or '2 hours'",
+		JobName:    jobName,
+	}
+}
+
+// effectiveTimeout returns the timeout a job runs with: its own, the nearest
+// template's, or the default one it inherits
+func effectiveTimeout(config *parser.GitLabConfig, jobName string) string {
+	job := config.Jobs[jobName]
+	if job.Timeout != "" {
+		return job.Timeout
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.Timeout != "" {
+			return template.Timeout
+		}
+	}
+	if config.Default != nil && job.InheritsDefault("timeout") {
+		return config.Default.Timeout
+	}
+	return ""
+}
+
+// effectiveRetries returns how many times a job can be retried, resolved
+// like effectiveTimeout and capped at GitLab's maximum of 2
+func effectiveRetries(config *parser.GitLabConfig, jobName string) int {
+	job := config.Jobs[jobName]
+	retry := job.Retry
+	if retry == nil {
+		chain := config.ExtendsChain(jobName)
+		for i := len(chain) - 1; i >= 0 && retry == nil; i-- {
+			if template := config.Jobs[chain[i]]; template != nil {
+				retry = template.Retry
+			}
+		}
+	}
+	if retry == nil && config.Default != nil && job.InheritsDefault("retry") {
+		retry = config.Default.Retry
+	}
+	if retry == nil {
+		return 0
+	}
+	return min(retry.Max, 2)
+}
+
+// durationParam reads a duration custom param written in GitLab's format,
+// e.g. "2h", falling back to defaultValue when it's missing or invalid
+func durationParam(params map[string]interface{}, name string, defaultValue time.Duration) time.Duration {
+	value, ok := params[name].(string)
+	if !ok {
+		return defaultValue
+	}
+	duration, err := parser.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return defaultValue
+	}
+	return duration
+}
+
+// formatDuration writes a duration the way it would appear in a
+// configuration, e.g. 1h30m rather than 1h30m0s
+func formatDuration(d time.Duration) string {
+	text := d.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
@@ -0,0 +1,59 @@
+package reliability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckJobTimeouts(t *testing.T) {
+	config, err := parser.Parse([]byte(`
+default:
+  timeout: 30m
+  retry: 1
+.slow:
+  timeout: 2 hours
+  retry: 2
+build:
+  script: [make]
+integration:
+  extends: .slow
+  script: [make integration]
+nightly:
+  timeout: 5h
+  script: [make soak]
+broken:
+  timeout: 1 fortnight
+  script: [make]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	issues := CheckJobTimeouts(config, nil)
+	byPath := make(map[string]string)
+	for _, issue := range issues {
+		byPath[issue.Path] = issue.Message
+	}
+	if len(issues) != 3 {
+		t.Errorf("Expected 3 issues, got %d: %v", len(issues), byPath)
+	}
+
+	if !strings.Contains(byPath["jobs.broken.timeout"], "isn't a valid duration") {
+		t.Errorf("Expected the unparseable timeout to be flagged, got %v", byPath)
+	}
+	if !strings.Contains(byPath["jobs.nightly.timeout"], "timeout of 5h, longer than 3h") {
+		t.Errorf("Expected the 5h timeout to be flagged, got %v", byPath)
+	}
+	// 2 hours from the template over 3 attempts
+	if !strings.Contains(byPath["jobs.integration.retry"], "can run for 6h") {
+		t.Errorf("Expected retries to be flagged for integration, got %v", byPath)
+	}
+
+	// Within 6h, nightly's 5h is accepted, but not with the default retry
+	issues = CheckJobTimeouts(config, map[string]interface{}{"max_timeout": "6h"})
+	if len(issues) != 2 || issues[1].Path != "jobs.nightly.retry" {
+		t.Errorf("Expected the invalid timeout and nightly's retries with max_timeout 6h, got %v", issues)
+	}
+}
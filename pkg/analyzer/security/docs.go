@@ -0,0 +1,76 @@
+package security
+
+import "github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+
+// checkDocs documents each security check for `gitlab-smith explain`
+var checkDocs = map[string]types.CheckDoc{
+	"image_tags": {
+		Rationale: "An image or service without a tag, tagged latest, or on a floating tag such as lts or bitnami/kubectl:1 can change between two runs of the same pipeline. Builds stop being reproducible, and a compromised or broken upstream image is pulled in without any change to the configuration. Digest-pinned images are never flagged, and neither are major-version tags such as node:20 of official Docker Hub images, which upstream maintains with patch releases.",
+		Example: types.CheckExample{
+			Before: `test:
+  image: node:latest
+  services:
+    - bitnami/redis:7`,
+			After: `test:
+  image: node:20.11-alpine
+  services:
+    - bitnami/redis:7.2.4`,
+		},
+		Related: []string{"duplicated_image_config"},
+	},
+	"environment_variables": {
+		Rationale: "Variables defined in .gitlab-ci.yml are visible to anyone who can read the repository and are printed in job logs. Passwords, tokens and secrets belong in masked, protected CI/CD variables or an external secrets manager.",
+		Example: types.CheckExample{
+			Before: `variables:
+  DEPLOY_TOKEN: "glpat-abc123"`,
+			After: `# DEPLOY_TOKEN is set as a masked, protected
+# variable in the project's CI/CD settings`,
+		},
+		Related: []string{"secrets_configuration", "id_token_audience"},
+	},
+	"id_token_audience": {
+		Rationale: "An ID token is accepted by any service that trusts GitLab and matches its audience. Without a scoped aud, a token meant for one service can be replayed against another, and tokens defined in default are handed to every job.",
+		Example: types.CheckExample{
+			Before: `default:
+  id_tokens:
+    VAULT_ID_TOKEN: {}`,
+			After: `deploy:
+  id_tokens:
+    VAULT_ID_TOKEN:
+      aud: https://vault.example.com`,
+		},
+		Related: []string{"secrets_configuration"},
+	},
+	"secrets_configuration": {
+		Rationale: "Secrets are fetched with an ID token. A job with secrets but no matching token fails at runtime, an incomplete Vault reference fetches nothing, and secrets in default are exposed to every job, including ones running untrusted code.",
+		Example: types.CheckExample{
+			Before: `deploy:
+  secrets:
+    DB_PASSWORD:
+      vault: production/db`,
+			After: `deploy:
+  id_tokens:
+    VAULT_ID_TOKEN:
+      aud: https://vault.example.com
+  secrets:
+    DB_PASSWORD:
+      vault: production/db/password@ops
+      token: $VAULT_ID_TOKEN`,
+		},
+		Related: []string{"id_token_audience", "environment_variables"},
+	},
+	"script_shell_injection": {
+		Rationale: "Branch names, commit messages and merge request titles are chosen by whoever pushes. Passing them to eval or sh -c, or unquoted into a command, lets that person run arbitrary commands with the job's credentials.",
+		Example: types.CheckExample{
+			Before: `notify:
+  script:
+    - eval "echo $CI_COMMIT_MESSAGE"
+    - ./notify.sh $CI_MERGE_REQUEST_TITLE`,
+			After: `notify:
+  script:
+    - echo "$CI_COMMIT_MESSAGE"
+    - ./notify.sh "$CI_MERGE_REQUEST_TITLE"`,
+		},
+		Related: []string{"script_complexity"},
+	},
+}
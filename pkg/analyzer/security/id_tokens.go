@@ -0,0 +1,100 @@
+package security
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckIDTokens flags OIDC id_tokens whose aud claim is missing or a
+// wildcard, since any service trusting GitLab's issuer would accept them,
+// and id_tokens defined in default, which are issued to every job
+func CheckIDTokens(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	checkTokens := func(tokens map[string]parser.IDToken, path, jobName string) {
+		names := make([]string, 0, len(tokens))
+		for name := range tokens {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			audiences := tokens[name].Aud
+			tokenPath := path + "." + name + ".aud"
+			switch {
+			case !hasAudience(audiences):
+				issues = append(issues, types.Issue{
+					Type:       types.IssueTypeSecurity,
+					Severity:   types.SeverityHigh,
+					Path:       tokenPath,
+					Message:    fmt.Sprintf("ID token '%s' has no audience", name),
+					Suggestion: "Set 'aud' to the URL of the service that consumes the token",
+					JobName:    jobName,
+				})
+			case hasWildcardAudience(audiences):
+				issues = append(issues, types.Issue{
+					Type:       types.IssueTypeSecurity,
+					Severity:   types.SeverityHigh,
+					Path:       tokenPath,
+					Message:    fmt.Sprintf("ID token '%s' uses a wildcard audience: %s", name, strings.Join(audiences, ", ")),
+					Suggestion: "Scope 'aud' to the exact service that consumes the token",
+					JobName:    jobName,
+				})
+			}
+		}
+	}
+
+	if config.Default != nil && len(config.Default.IDTokens) > 0 {
+		checkTokens(config.Default.IDTokens, "default.id_tokens", "")
+		if defaultTokensInherited(config) {
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeSecurity,
+				Severity:   types.SeverityMedium,
+				Path:       "default.id_tokens",
+				Message:    "ID tokens defined in default are issued to every job, including ones that run untrusted code",
+				Suggestion: "Define id_tokens only on the jobs that authenticate with them",
+			})
+		}
+	}
+
+	for _, jobName := range config.GetAllJobNames() {
+		if job := config.Jobs[jobName]; job != nil {
+			checkTokens(job.IDTokens, "jobs."+jobName+".id_tokens", jobName)
+		}
+	}
+
+	return issues
+}
+
+func hasAudience(audiences []string) bool {
+	for _, audience := range audiences {
+		if strings.TrimSpace(audience) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasWildcardAudience(audiences []string) bool {
+	for _, audience := range audiences {
+		if strings.Contains(audience, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultTokensInherited reports whether some job receives the default
+// id_tokens without overriding them or opting out through inherit
+func defaultTokensInherited(config *parser.GitLabConfig) bool {
+	for _, job := range config.ConcreteJobs() {
+		if job.IDTokens == nil && job.InheritsDefault("id_tokens") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckIDTokens(t *testing.T) {
+	yaml := `
+default:
+  id_tokens:
+    SHARED_TOKEN:
+      aud: https://vault.example.com
+vault:
+  script: [vault login]
+  id_tokens:
+    VAULT_TOKEN:
+      aud: https://vault.example.com
+    ANY_TOKEN:
+      aud: "*"
+    NO_AUD:
+      other: value
+    LIST_TOKEN:
+      aud: [https://a.example.com, "https://*.example.com"]
+build:
+  script: [make]
+`
+	config, err := parser.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if aud := config.Jobs["vault"].IDTokens["LIST_TOKEN"].Aud; len(aud) != 2 {
+		t.Fatalf("Expected list audience to be parsed, got %v", aud)
+	}
+
+	issues := CheckIDTokens(config)
+	expected := map[string]bool{
+		"default.id_tokens":                   true,
+		"jobs.vault.id_tokens.ANY_TOKEN.aud":  true,
+		"jobs.vault.id_tokens.LIST_TOKEN.aud": true,
+		"jobs.vault.id_tokens.NO_AUD.aud":     true,
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %d: %+v", len(expected), len(issues), issues)
+	}
+	for _, issue := range issues {
+		if !expected[issue.Path] {
+			t.Errorf("Unexpected issue: %+v", issue)
+		}
+	}
+}
+
+func TestCheckIDTokens_DefaultNotInherited(t *testing.T) {
+	noDefault := false
+	config := &parser.GitLabConfig{
+		Default: &parser.JobConfig{
+			IDTokens: map[string]parser.IDToken{"TOKEN": {Aud: []string{"https://vault.example.com"}}},
+		},
+		Jobs: map[string]*parser.JobConfig{
+			"vault": {Script: []string{"vault login"}},
+			"build": {
+				Script:  []string{"make"},
+				Inherit: &parser.Inherit{Default: noDefault},
+			},
+		},
+	}
+
+	if issues := CheckIDTokens(config); len(issues) != 1 {
+		t.Errorf("Expected the default id_tokens issue while vault inherits them, got %+v", issues)
+	}
+
+	config.Jobs["vault"].IDTokens = map[string]parser.IDToken{"VAULT": {Aud: []string{"https://vault.example.com"}}}
+	if issues := CheckIDTokens(config); len(issues) != 0 {
+		t.Errorf("Expected no issues once no job inherits the default id_tokens, got %+v", issues)
+	}
+}
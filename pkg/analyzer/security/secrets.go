@@ -0,0 +1,142 @@
+package security
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CheckSecrets flags jobs that fetch external secrets without an ID token to
+// authenticate with, secrets whose token refers to an ID token the job
+// doesn't have, incomplete Vault references, and secrets defined in default,
+// which every job then fetches
+func CheckSecrets(config *parser.GitLabConfig) []types.Issue {
+	var issues []types.Issue
+
+	if config.Default != nil && len(config.Default.Secrets) > 0 && defaultSecretsInherited(config) {
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeSecurity,
+			Severity:   types.SeverityMedium,
+			Path:       "default.secrets",
+			Message:    "Secrets defined in default are fetched by every job, including ones that run untrusted code",
+			Suggestion: "Define secrets only on the jobs that use them",
+		})
+	}
+
+	for _, jobName := range config.ConcreteJobNames() {
+		job := config.Jobs[jobName]
+		secrets := resolvedSecrets(config, jobName, job)
+		if len(secrets) == 0 {
+			continue
+		}
+		tokens := resolvedIDTokens(config, jobName, job)
+
+		if len(tokens) == 0 {
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeSecurity,
+				Severity:   types.SeverityHigh,
+				Path:       "jobs." + jobName + ".secrets",
+				Message:    fmt.Sprintf("Job '%s' fetches secrets but has no id_tokens to authenticate with", jobName),
+				Suggestion: "Add an ID token whose aud matches the secrets provider, e.g. id_tokens: {VAULT_ID_TOKEN: {aud: https://vault.example.com}}",
+				JobName:    jobName,
+			})
+		}
+
+		names := make([]string, 0, len(secrets))
+		for name := range secrets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			secret := secrets[name]
+			path := "jobs." + jobName + ".secrets." + name
+
+			if token := strings.Trim(strings.TrimPrefix(secret.Token, "$"), "{}"); token != "" && len(tokens) > 0 {
+				if _, exists := tokens[token]; !exists {
+					issues = append(issues, types.Issue{
+						Type:       types.IssueTypeSecurity,
+						Severity:   types.SeverityMedium,
+						Path:       path + ".token",
+						Message:    fmt.Sprintf("Secret '%s' authenticates with ID token '%s', which job '%s' doesn't define", name, token, jobName),
+						Suggestion: "Reference one of the job's id_tokens",
+						JobName:    jobName,
+					})
+				}
+			}
+
+			if secret.Vault != nil && (secret.Vault.Path == "" || secret.Vault.Field == "") {
+				issues = append(issues, types.Issue{
+					Type:       types.IssueTypeSecurity,
+					Severity:   types.SeverityMedium,
+					Path:       path + ".vault",
+					Message:    fmt.Sprintf("Secret '%s' doesn't name both a Vault path and field", name),
+					Suggestion: "Write the reference as path/to/secret/field@engine-path, or set path and field explicitly",
+					JobName:    jobName,
+				})
+			}
+		}
+	}
+
+	return issues
+}
+
+// resolvedSecrets returns the job's secrets, or those it inherits from the
+// nearest template it extends or from default
+func resolvedSecrets(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) map[string]parser.Secret {
+	if secrets := extendedSecrets(config, jobName, job); secrets != nil {
+		return secrets
+	}
+	if config.Default != nil && job.InheritsDefault("secrets") {
+		return config.Default.Secrets
+	}
+	return nil
+}
+
+// extendedSecrets returns the secrets set on the job or the nearest template
+// it extends
+func extendedSecrets(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) map[string]parser.Secret {
+	if job.Secrets != nil {
+		return job.Secrets
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.Secrets != nil {
+			return template.Secrets
+		}
+	}
+	return nil
+}
+
+// resolvedIDTokens returns the job's id_tokens, or those it inherits from the
+// nearest template it extends or from default
+func resolvedIDTokens(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) map[string]parser.IDToken {
+	if job.IDTokens != nil {
+		return job.IDTokens
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.IDTokens != nil {
+			return template.IDTokens
+		}
+	}
+	if config.Default != nil && job.InheritsDefault("id_tokens") {
+		return config.Default.IDTokens
+	}
+	return nil
+}
+
+// defaultSecretsInherited reports whether some job receives the default
+// secrets without overriding them, directly or through extends, or opting
+// out through inherit
+func defaultSecretsInherited(config *parser.GitLabConfig) bool {
+	for jobName, job := range config.ConcreteJobs() {
+		if extendedSecrets(config, jobName, job) == nil && job.InheritsDefault("secrets") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckSecrets(t *testing.T) {
+	vault := func(path, field string) *parser.VaultSecret {
+		return &parser.VaultSecret{Engine: parser.VaultEngine{Name: "kv-v2", Path: "secret"}, Path: path, Field: field}
+	}
+	vaultToken := map[string]parser.IDToken{"VAULT_ID_TOKEN": {Aud: []string{"https://vault.example.com"}}}
+
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			".vault": {IDTokens: vaultToken},
+			"deploy": {
+				Script:   []string{"./deploy.sh"},
+				Extends:  ".vault",
+				Secrets:  map[string]parser.Secret{"DB_PASSWORD": {Vault: vault("production/db", "password"), Token: "$VAULT_ID_TOKEN"}},
+				IDTokens: nil,
+			},
+			"migrate": {
+				Script:  []string{"./migrate.sh"},
+				Secrets: map[string]parser.Secret{"DB_PASSWORD": {Vault: vault("production/db", "password")}},
+			},
+			"release": {
+				Script:   []string{"./release.sh"},
+				IDTokens: vaultToken,
+				Secrets: map[string]parser.Secret{
+					"SIGNING_KEY": {Vault: vault("release/signing", "key"), Token: "$RELEASE_TOKEN"},
+					"API_KEY":     {Vault: vault("api", "")},
+				},
+			},
+		},
+	}
+
+	issues := CheckSecrets(config)
+
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.Path)
+		if issue.Type != types.IssueTypeSecurity {
+			t.Errorf("Expected security issue, got %s", issue.Type)
+		}
+	}
+	want := []string{
+		"jobs.migrate.secrets",
+		"jobs.release.secrets.API_KEY.vault",
+		"jobs.release.secrets.SIGNING_KEY.token",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected issues at %v, got %v", want, got)
+	}
+	if issues[0].Severity != types.SeverityHigh || !strings.Contains(issues[0].Message, "no id_tokens") {
+		t.Errorf("Expected a high severity issue for secrets without ID tokens, got %+v", issues[0])
+	}
+}
+
+func TestCheckSecrets_Default(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Default: &parser.JobConfig{
+			IDTokens: map[string]parser.IDToken{"VAULT_ID_TOKEN": {Aud: []string{"https://vault.example.com"}}},
+			Secrets:  map[string]parser.Secret{"DB_PASSWORD": {Vault: &parser.VaultSecret{Path: "db", Field: "password"}}},
+		},
+		Jobs: map[string]*parser.JobConfig{
+			"test": {Script: []string{"make test"}},
+		},
+	}
+
+	issues := CheckSecrets(config)
+	if len(issues) != 1 || issues[0].Path != "default.secrets" {
+		t.Fatalf("Expected only the default secrets to be flagged, got %+v", issues)
+	}
+
+	config.Jobs["test"].Inherit = &parser.Inherit{Default: []interface{}{"id_tokens"}}
+	if issues := CheckSecrets(config); len(issues) != 0 {
+		t.Errorf("Expected no issues once no job inherits the default secrets, got %+v", issues)
+	}
+}
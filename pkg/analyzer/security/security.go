@@ -12,33 +12,55 @@ import (
 // CheckRegistry interface to avoid import cycles
 type CheckRegistry interface {
 	Register(name string, issueType types.IssueType, checkFunc types.CheckFunc)
+	RegisterWithParams(name string, issueType types.IssueType, checkFunc types.ParamCheckFunc)
+	Describe(name string, doc types.CheckDoc)
 }
 
 // RegisterChecks registers all security-related checks
 func RegisterChecks(registry CheckRegistry) {
 	registry.Register("image_tags", types.IssueTypeSecurity, CheckImageTags)
 	registry.Register("environment_variables", types.IssueTypeSecurity, CheckEnvironmentVariables)
+	registry.Register("id_token_audience", types.IssueTypeSecurity, CheckIDTokens)
+	registry.Register("secrets_configuration", types.IssueTypeSecurity, CheckSecrets)
+	registry.RegisterWithParams("script_shell_injection", types.IssueTypeSecurity, CheckScriptShellInjection)
+
+	for name, doc := range checkDocs {
+		registry.Describe(name, doc)
+	}
+}
+
+// floatingTags are tags that move to a new image whenever upstream
+// publishes one, alone or with a variant suffix such as lts-alpine
+var floatingTags = map[string]bool{
+	"lts": true, "stable": true, "edge": true, "current": true, "rolling": true,
+	"nightly": true, "mainline": true, "main": true, "master": true, "develop": true,
 }
 
+// CheckImageTags flags images and services of the default block and of jobs
+// that aren't pinned: without a tag, on latest, or on a floating tag such as
+// lts or a bare major version. Images pinned by digest are never flagged, and
+// neither are major-version tags of official images, which upstream maintains
+// with patch releases for each supported major version.
 func CheckImageTags(config *parser.GitLabConfig) []types.Issue {
 	var issues []types.Issue
 	expander := varexpand.New(config)
 
-	checkImage := func(image, path, jobName string, jobVars map[string]interface{}) {
+	checkImage := func(job *parser.JobConfig, image, path, jobName string) {
 		if image == "" {
 			return
 		}
 
 		// Expand variables first
-		expandedImage := expander.ExpandString(image, jobVars)
+		expandedImage := expander.ExpandJobString(image, job)
 
 		// If expansion didn't resolve all variables, skip tag checking
-		if strings.Contains(expandedImage, "$") {
+		if strings.Contains(expandedImage, "$") || strings.Contains(expandedImage, "@sha256:") {
 			return
 		}
 
-		// Check for missing tag after expansion
-		if !strings.Contains(expandedImage, ":") {
+		tag := imageTag(expandedImage)
+		switch {
+		case tag == "":
 			issues = append(issues, types.Issue{
 				Type:       types.IssueTypeSecurity,
 				Severity:   types.SeverityMedium,
@@ -47,7 +69,7 @@ func CheckImageTags(config *parser.GitLabConfig) []types.Issue {
 				Suggestion: "Use specific tags instead of 'latest' for reproducible builds",
 				JobName:    jobName,
 			})
-		} else if strings.HasSuffix(expandedImage, ":latest") {
+		case tag == "latest":
 			issues = append(issues, types.Issue{
 				Type:       types.IssueTypeSecurity,
 				Severity:   types.SeverityLow,
@@ -56,22 +78,82 @@ func CheckImageTags(config *parser.GitLabConfig) []types.Issue {
 				Suggestion: "Pin to specific version for reproducible builds",
 				JobName:    jobName,
 			})
+		case isFloatingTag(tag) && !(isMajorVersionTag(tag) && isOfficialImage(expandedImage)):
+			issues = append(issues, types.Issue{
+				Type:       types.IssueTypeSecurity,
+				Severity:   types.SeverityMedium,
+				Path:       path,
+				Message:    fmt.Sprintf("Using floating tag '%s', which moves to new releases: %s (expands to: %s)", tag, image, expandedImage),
+				Suggestion: "Pin to a full version tag or to a digest (image@sha256:...) for reproducible builds",
+				JobName:    jobName,
+			})
 		}
 	}
 
-	// Check default image
-	if config.Default != nil {
-		checkImage(config.Default.Image, "default.image", "", config.Default.Variables)
+	checkJob := func(job *parser.JobConfig, path, jobName string) {
+		if job == nil {
+			return
+		}
+		checkImage(job, job.Image.GetName(), path+".image", jobName)
+		for i, service := range job.Services {
+			checkImage(job, service.Name, fmt.Sprintf("%s.services[%d]", path, i), jobName)
+		}
 	}
 
-	// Check job-specific images
+	// Check default image and services
+	checkJob(config.Default, "default", "")
+
+	// Check job-specific images and services
 	for jobName, job := range config.Jobs {
-		checkImage(job.Image, "jobs."+jobName+".image", jobName, job.Variables)
+		checkJob(job, "jobs."+jobName, jobName)
 	}
 
 	return issues
 }
 
+// imageTag returns the tag of an image reference, or an empty string when it
+// has none. A registry port such as registry:5000/app isn't a tag.
+func imageTag(image string) string {
+	name := image
+	if slash := strings.LastIndex(name, "/"); slash >= 0 {
+		name = name[slash+1:]
+	}
+	if colon := strings.LastIndex(name, ":"); colon >= 0 {
+		return name[colon+1:]
+	}
+	return ""
+}
+
+// isFloatingTag reports whether a tag is a bare major version, such as
+// python:3, or a moving release channel such as lts, either of them with a
+// variant suffix such as node:20-alpine or stable-slim
+func isFloatingTag(tag string) bool {
+	return floatingTags[strings.SplitN(tag, "-", 2)[0]] || isMajorVersionTag(tag)
+}
+
+// isMajorVersionTag reports whether a tag is a bare major version, with or
+// without a variant suffix
+func isMajorVersionTag(tag string) bool {
+	version := strings.SplitN(tag, "-", 2)[0]
+	if version == "" {
+		return false
+	}
+	for _, r := range version {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isOfficialImage reports whether an image is one of Docker Hub's official
+// images, which have no namespace, such as node or docker.io/library/node
+func isOfficialImage(image string) bool {
+	name := strings.TrimPrefix(image, "docker.io/")
+	name = strings.TrimPrefix(name, "library/")
+	return !strings.Contains(name, "/")
+}
+
 func CheckEnvironmentVariables(config *parser.GitLabConfig) []types.Issue {
 	var issues []types.Issue
 
@@ -24,9 +24,9 @@ func TestCheckImageTags(t *testing.T) {
 		{
 			name: "images with proper tags",
 			config: &parser.GitLabConfig{
-				Default: &parser.JobConfig{Image: "alpine:3.14"},
+				Default: &parser.JobConfig{Image: &parser.Image{Name: "alpine:3.14"}},
 				Jobs: map[string]*parser.JobConfig{
-					"test": {Image: "node:16.14.0"},
+					"test": {Image: &parser.Image{Name: "node:16.14.0"}},
 				},
 			},
 			expected: 0,
@@ -34,7 +34,16 @@ func TestCheckImageTags(t *testing.T) {
 		{
 			name: "image without tag",
 			config: &parser.GitLabConfig{
-				Default: &parser.JobConfig{Image: "alpine"},
+				Default: &parser.JobConfig{Image: &parser.Image{Name: "alpine"}},
+			},
+			expected: 1,
+		},
+		{
+			name: "object-form image without tag",
+			config: &parser.GitLabConfig{
+				Jobs: map[string]*parser.JobConfig{
+					"test": {Image: &parser.Image{Name: "alpine", Entrypoint: []string{""}}},
+				},
 			},
 			expected: 1,
 		},
@@ -42,7 +51,7 @@ func TestCheckImageTags(t *testing.T) {
 			name: "image with latest tag",
 			config: &parser.GitLabConfig{
 				Jobs: map[string]*parser.JobConfig{
-					"test": {Image: "node:latest"},
+					"test": {Image: &parser.Image{Name: "node:latest"}},
 				},
 			},
 			expected: 1,
@@ -50,11 +59,11 @@ func TestCheckImageTags(t *testing.T) {
 		{
 			name: "mixed image configurations",
 			config: &parser.GitLabConfig{
-				Default: &parser.JobConfig{Image: "alpine"},
+				Default: &parser.JobConfig{Image: &parser.Image{Name: "alpine"}},
 				Jobs: map[string]*parser.JobConfig{
-					"test1": {Image: "node:latest"},
-					"test2": {Image: "python:3.9"},
-					"test3": {Image: "ubuntu"},
+					"test1": {Image: &parser.Image{Name: "node:latest"}},
+					"test2": {Image: &parser.Image{Name: "python:3.9"}},
+					"test3": {Image: &parser.Image{Name: "ubuntu"}},
 				},
 			},
 			expected: 3,
@@ -62,9 +71,9 @@ func TestCheckImageTags(t *testing.T) {
 		{
 			name: "empty image field",
 			config: &parser.GitLabConfig{
-				Default: &parser.JobConfig{Image: ""},
+				Default: &parser.JobConfig{Image: nil},
 				Jobs: map[string]*parser.JobConfig{
-					"test": {Image: ""},
+					"test": {Image: nil},
 				},
 			},
 			expected: 0,
@@ -77,12 +86,12 @@ func TestCheckImageTags(t *testing.T) {
 					"REGISTRY":   "registry.example.com",
 					"PROJECT":    "my-project",
 				},
-				Default: &parser.JobConfig{Image: "$DOCKER_IMAGE"}, // Unknown variable, should be skipped
+				Default: &parser.JobConfig{Image: &parser.Image{Name: "$DOCKER_IMAGE"}}, // Unknown variable, should be skipped
 				Jobs: map[string]*parser.JobConfig{
-					"test1": {Image: "${CI_REGISTRY_IMAGE}:v1.0"}, // Predefined var with tag
-					"test2": {Image: "$BASE_IMAGE:latest"},        // Custom var with :latest tag
-					"test3": {Image: "${REGISTRY}/${PROJECT}"},    // Custom vars, no tag - should trigger
-					"test4": {Image: "${CI_REGISTRY_IMAGE}"},      // Predefined var, no tag - should trigger
+					"test1": {Image: &parser.Image{Name: "${CI_REGISTRY_IMAGE}:v1.0"}}, // Predefined var with tag
+					"test2": {Image: &parser.Image{Name: "$BASE_IMAGE:latest"}},        // Custom var with :latest tag
+					"test3": {Image: &parser.Image{Name: "${REGISTRY}/${PROJECT}"}},    // Custom vars, no tag - should trigger
+					"test4": {Image: &parser.Image{Name: "${CI_REGISTRY_IMAGE}"}},      // Predefined var, no tag - should trigger
 				},
 			},
 			expected: 3, // test2 (:latest), test3 (no tag), test4 (no tag)
@@ -95,27 +104,27 @@ func TestCheckImageTags(t *testing.T) {
 				},
 				Jobs: map[string]*parser.JobConfig{
 					"test": {
-						Image: "${JOB_IMAGE}:16",
+						Image: &parser.Image{Name: "${JOB_IMAGE}:16.14.0"},
 						Variables: map[string]interface{}{
 							"JOB_IMAGE": "node",
 						},
 					},
 				},
 			},
-			expected: 0, // Should resolve to node:16
+			expected: 0, // Should resolve to node:16.14.0
 		},
 		{
 			name: "custom variables with proper tags should pass",
 			config: &parser.GitLabConfig{
 				Variables: map[string]interface{}{
-					"NODE_IMAGE":   "node:22",
+					"NODE_IMAGE":   "node:22.11.0",
 					"PYTHON_IMAGE": "python:3.11-slim",
 					"BASE_IMAGE":   "ubuntu:20.04",
 				},
 				Jobs: map[string]*parser.JobConfig{
-					"node_job":   {Image: "${NODE_IMAGE}"},
-					"python_job": {Image: "$PYTHON_IMAGE"},
-					"base_job":   {Image: "${BASE_IMAGE}"},
+					"node_job":   {Image: &parser.Image{Name: "${NODE_IMAGE}"}},
+					"python_job": {Image: &parser.Image{Name: "$PYTHON_IMAGE"}},
+					"base_job":   {Image: &parser.Image{Name: "${BASE_IMAGE}"}},
 				},
 			},
 			expected: 0, // All should pass as they expand to properly tagged images
@@ -129,9 +138,9 @@ func TestCheckImageTags(t *testing.T) {
 					"GOOD_IMAGE":   "alpine:3.18",
 				},
 				Jobs: map[string]*parser.JobConfig{
-					"node_job":   {Image: "${NODE_IMAGE}"},
-					"python_job": {Image: "$PYTHON_IMAGE"},
-					"good_job":   {Image: "${GOOD_IMAGE}"},
+					"node_job":   {Image: &parser.Image{Name: "${NODE_IMAGE}"}},
+					"python_job": {Image: &parser.Image{Name: "$PYTHON_IMAGE"}},
+					"good_job":   {Image: &parser.Image{Name: "${GOOD_IMAGE}"}},
 				},
 			},
 			expected: 2, // node_job and python_job should fail due to :latest
@@ -145,9 +154,9 @@ func TestCheckImageTags(t *testing.T) {
 					"GOOD_IMAGE":   "alpine:3.18",
 				},
 				Jobs: map[string]*parser.JobConfig{
-					"node_job":   {Image: "${NODE_IMAGE}"},
-					"python_job": {Image: "$PYTHON_IMAGE"},
-					"good_job":   {Image: "${GOOD_IMAGE}"},
+					"node_job":   {Image: &parser.Image{Name: "${NODE_IMAGE}"}},
+					"python_job": {Image: &parser.Image{Name: "$PYTHON_IMAGE"}},
+					"good_job":   {Image: &parser.Image{Name: "${GOOD_IMAGE}"}},
 				},
 			},
 			expected: 2, // node_job and python_job should fail due to missing tags
@@ -156,14 +165,14 @@ func TestCheckImageTags(t *testing.T) {
 			name: "non-string variables should be converted",
 			config: &parser.GitLabConfig{
 				Variables: map[string]interface{}{
-					"VERSION": 22,
+					"VERSION": 22.11,
 					"DEBUG":   true,
 				},
 				Jobs: map[string]*parser.JobConfig{
-					"test": {Image: "node:${VERSION}"}, // Should expand to node:22
+					"test": {Image: &parser.Image{Name: "node:${VERSION}"}}, // Should expand to node:22.11
 				},
 			},
-			expected: 0, // Should pass as it expands to node:22
+			expected: 0, // Should pass as it expands to node:22.11
 		},
 	}
 
@@ -190,6 +199,107 @@ func TestCheckImageTags(t *testing.T) {
 	}
 }
 
+func TestCheckImageTagsFloatingAndServices(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Default: &parser.JobConfig{
+			Image:    &parser.Image{Name: "node:20"},
+			Services: []parser.Service{{Name: "redis:7.2.4"}, {Name: "postgres", Alias: "db"}},
+		},
+		Jobs: map[string]*parser.JobConfig{
+			"lts":      {Image: &parser.Image{Name: "node:lts-alpine"}},
+			"variant":  {Image: &parser.Image{Name: "node:18-alpine"}},
+			"library":  {Image: &parser.Image{Name: "docker.io/library/python:3"}},
+			"vendor":   {Image: &parser.Image{Name: "bitnami/kubectl:1"}},
+			"mirror":   {Image: &parser.Image{Name: "registry.example.com/node:20-alpine"}},
+			"digest":   {Image: &parser.Image{Name: "python:3@sha256:0123456789abcdef"}},
+			"registry": {Image: &parser.Image{Name: "registry.example.com:5000/tools/app"}},
+			"services": {
+				Image:    &parser.Image{Name: "docker:24-dind"},
+				Services: []parser.Service{{Name: "docker:stable-dind"}, {Name: "mysql:latest"}},
+			},
+		},
+	}
+
+	issues := CheckImageTags(config)
+
+	want := map[string]types.Severity{
+		"default.services[1]":       types.SeverityMedium,
+		"jobs.lts.image":            types.SeverityMedium,
+		"jobs.vendor.image":         types.SeverityMedium,
+		"jobs.mirror.image":         types.SeverityMedium,
+		"jobs.registry.image":       types.SeverityMedium,
+		"jobs.services.services[0]": types.SeverityMedium,
+		"jobs.services.services[1]": types.SeverityLow,
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("Expected %d issues, got %d: %+v", len(want), len(issues), issues)
+	}
+	for _, issue := range issues {
+		severity, ok := want[issue.Path]
+		if !ok {
+			t.Errorf("Unexpected issue at %s: %s", issue.Path, issue.Message)
+			continue
+		}
+		if issue.Severity != severity {
+			t.Errorf("Expected %s severity at %s, got %s", severity, issue.Path, issue.Severity)
+		}
+	}
+
+	for _, issue := range issues {
+		if issue.Path == "jobs.lts.image" && !strings.Contains(issue.Message, "floating tag 'lts-alpine'") {
+			t.Errorf("Expected floating tag message, got: %s", issue.Message)
+		}
+	}
+}
+
+func TestIsOfficialImage(t *testing.T) {
+	tests := []struct {
+		image    string
+		official bool
+	}{
+		{"node:20", true},
+		{"library/node:20", true},
+		{"docker.io/library/node:20", true},
+		{"bitnami/kubectl:1", false},
+		{"docker.io/bitnami/kubectl:1", false},
+		{"registry.example.com/node:20", false},
+		{"registry.example.com:5000/tools/app:1", false},
+	}
+
+	for _, tt := range tests {
+		if got := isOfficialImage(tt.image); got != tt.official {
+			t.Errorf("isOfficialImage(%q) = %v, want %v", tt.image, got, tt.official)
+		}
+	}
+}
+
+func TestIsFloatingTag(t *testing.T) {
+	tests := []struct {
+		tag      string
+		floating bool
+	}{
+		{"3", true},
+		{"20-alpine", true},
+		{"3-slim", true},
+		{"3-slim-bookworm", true},
+		{"lts", true},
+		{"lts-alpine", true},
+		{"stable-slim", true},
+		{"20.11", false},
+		{"20.11-alpine", false},
+		{"3.12-slim", false},
+		{"1.2.3", false},
+		{"alpine", false},
+		{"-alpine", false},
+	}
+
+	for _, tt := range tests {
+		if got := isFloatingTag(tt.tag); got != tt.floating {
+			t.Errorf("isFloatingTag(%q) = %v, want %v", tt.tag, got, tt.floating)
+		}
+	}
+}
+
 func TestCheckEnvironmentVariables(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -311,9 +421,9 @@ func TestRegisterChecks(t *testing.T) {
 
 	RegisterChecks(registry)
 
-	// Check that both checks were registered
-	if len(registry.checks) != 2 {
-		t.Errorf("Expected 2 checks to be registered, got %d", len(registry.checks))
+	// Check that all checks were registered
+	if len(registry.checks) != 5 {
+		t.Errorf("Expected 5 checks to be registered, got %d", len(registry.checks))
 	}
 
 	// Check specific registrations
@@ -348,3 +458,15 @@ func (r *mockRegistry) Register(name string, issueType types.IssueType, checkFun
 		checkFunc: checkFunc,
 	}
 }
+
+func (r *mockRegistry) RegisterWithParams(name string, issueType types.IssueType, checkFunc types.ParamCheckFunc) {
+	r.checks[name] = registeredCheck{
+		name:      name,
+		issueType: issueType,
+		checkFunc: func(config *parser.GitLabConfig) []types.Issue {
+			return checkFunc(config, nil)
+		},
+	}
+}
+
+func (r *mockRegistry) Describe(name string, doc types.CheckDoc) {}
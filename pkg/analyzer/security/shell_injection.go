@@ -0,0 +1,173 @@
+package security
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// shellPattern is a script construct that lets data end up executed as code
+type shellPattern struct {
+	description string
+	re          *regexp.Regexp
+}
+
+// defaultShellPatterns are always checked. Teams can add their own regular
+// expressions through the check's custom_params ("patterns").
+var defaultShellPatterns = []shellPattern{
+	{"eval of a variable", regexp.MustCompile(`(^|[;&|({\s])eval\s[^#]*\$`)},
+	{"unquoted variable passed to a shell", regexp.MustCompile(`\b(sh|bash|zsh|dash)\s+-c\s+\$`)},
+	{"download piped into a shell", regexp.MustCompile(`\b(curl|wget)\b[^|#]*\|\s*(sudo\s+)?(ba|z|da)?sh\b`)},
+}
+
+// defaultUntrustedVariables hold text chosen by whoever pushes a commit or
+// opens a merge request. Tags are left out since creating them takes push
+// access to the project. Overridable through custom_params
+// ("untrusted_variables").
+var defaultUntrustedVariables = []string{
+	"CI_COMMIT_MESSAGE", "CI_COMMIT_TITLE", "CI_COMMIT_DESCRIPTION", "CI_COMMIT_AUTHOR",
+	"CI_COMMIT_BRANCH", "CI_COMMIT_REF_NAME",
+	"CI_MERGE_REQUEST_TITLE", "CI_MERGE_REQUEST_DESCRIPTION", "CI_MERGE_REQUEST_SOURCE_BRANCH_NAME",
+	"CI_EXTERNAL_PULL_REQUEST_SOURCE_BRANCH_NAME",
+}
+
+// CheckScriptShellInjection flags script lines that may execute attacker
+// controlled text: eval of variables, variables passed unquoted to sh -c,
+// downloads piped into a shell, and commit or merge request metadata used
+// outside double quotes. Each job is reported once, with the first offending
+// line.
+func CheckScriptShellInjection(config *parser.GitLabConfig, params map[string]interface{}) []types.Issue {
+	var issues []types.Issue
+
+	patterns := append([]shellPattern{}, defaultShellPatterns...)
+	for _, expr := range types.StringListParam(params, "patterns", nil) {
+		if re, err := regexp.Compile(expr); err == nil {
+			patterns = append(patterns, shellPattern{"matches pattern " + expr, re})
+		}
+	}
+	untrusted := make(map[string]bool)
+	for _, name := range types.StringListParam(params, "untrusted_variables", defaultUntrustedVariables) {
+		untrusted[name] = true
+	}
+
+	checkJob := func(job *parser.JobConfig, path, jobName string) {
+		sections := []struct {
+			name  string
+			lines []string
+		}{
+			{"before_script", job.BeforeScript},
+			{"script", job.Script},
+			{"after_script", job.AfterScript},
+		}
+
+		var findings []string
+		var firstPath string
+		for _, section := range sections {
+			for _, entry := range section.lines {
+				// Multi-line entries are reported by the offending line
+				for _, line := range strings.Split(entry, "\n") {
+					reason := shellInjectionReason(line, patterns, untrusted)
+					if reason == "" {
+						continue
+					}
+					if firstPath == "" {
+						firstPath = path + "." + section.name
+					}
+					findings = append(findings, fmt.Sprintf("%s: %s", reason, strings.TrimSpace(line)))
+				}
+			}
+		}
+		if len(findings) == 0 {
+			return
+		}
+
+		message := fmt.Sprintf("Possible shell injection (%s)", findings[0])
+		if len(findings) > 1 {
+			message += fmt.Sprintf(" and %d more", len(findings)-1)
+		}
+		issues = append(issues, types.Issue{
+			Type:       types.IssueTypeSecurity,
+			Severity:   types.SeverityHigh,
+			Path:       firstPath,
+			Message:    message,
+			Suggestion: "Quote variables (\"$VAR\"), avoid eval and sh -c on variable input, and download scripts to a file and verify them before running",
+			JobName:    jobName,
+		})
+	}
+
+	if config.Default != nil {
+		checkJob(config.Default, "default", "")
+	}
+
+	jobNames := make([]string, 0, len(config.Jobs))
+	for jobName := range config.Jobs {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	for _, jobName := range jobNames {
+		if job := config.Jobs[jobName]; job != nil {
+			checkJob(job, "jobs."+jobName, jobName)
+		}
+	}
+
+	return issues
+}
+
+// shellInjectionReason describes why a script line is dangerous, or returns
+// an empty string when it isn't
+func shellInjectionReason(line string, patterns []shellPattern, untrusted map[string]bool) string {
+	for _, pattern := range patterns {
+		if pattern.re.MatchString(line) {
+			return pattern.description
+		}
+	}
+	for _, name := range unquotedVariables(line) {
+		if untrusted[name] {
+			return "unquoted $" + name
+		}
+	}
+	return ""
+}
+
+// unquotedVariables returns the names of variables referenced outside quotes
+// in a shell line. Variables in single quotes aren't expanded and those in
+// double quotes aren't split or globbed, so both are skipped.
+func unquotedVariables(line string) []string {
+	var names []string
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\\':
+			i++
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '$':
+			name := variableName(line[i+1:])
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+var variableNamePattern = regexp.MustCompile(`^\{?([A-Za-z_][A-Za-z0-9_]*)`)
+
+func variableName(rest string) string {
+	if match := variableNamePattern.FindStringSubmatch(rest); match != nil {
+		return match[1]
+	}
+	return ""
+}
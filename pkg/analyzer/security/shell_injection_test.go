@@ -0,0 +1,105 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestCheckScriptShellInjection(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    []string
+		wantMatch string
+	}{
+		{"eval of a variable", []string{`eval "$CI_COMMIT_MESSAGE"`}, "eval of a variable"},
+		{"sh -c with unquoted variable", []string{"sh -c $DEPLOY_COMMAND"}, "unquoted variable passed to a shell"},
+		{"curl piped into bash", []string{"curl -fsSL https://example.com/install.sh | bash"}, "download piped into a shell"},
+		{"wget piped into sudo sh", []string{"wget -qO- https://example.com/i.sh | sudo sh"}, "download piped into a shell"},
+		{"unquoted merge request title", []string{"./notify --title ${CI_MERGE_REQUEST_TITLE}"}, "unquoted $CI_MERGE_REQUEST_TITLE"},
+		{"quoted merge request title", []string{`./notify --title "$CI_MERGE_REQUEST_TITLE"`}, ""},
+		{"single quoted variable", []string{`echo '$CI_COMMIT_MESSAGE'`}, ""},
+		{"unquoted trusted variable", []string{"wget -O tool $TOOL_URL"}, ""},
+		{"curl to a file", []string{"curl -fsSL -o install.sh https://example.com/install.sh"}, ""},
+		{"evaluate in a word", []string{"./evaluate $MODEL"}, ""},
+		{"unquoted tag", []string{"docker push $CI_REGISTRY_IMAGE:$CI_COMMIT_TAG"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &parser.GitLabConfig{
+				Jobs: map[string]*parser.JobConfig{"job": {Script: tt.script}},
+			}
+
+			issues := CheckScriptShellInjection(config, nil)
+			if tt.wantMatch == "" {
+				if len(issues) != 0 {
+					t.Errorf("Expected no issues, got %+v", issues)
+				}
+				return
+			}
+			if len(issues) != 1 {
+				t.Fatalf("Expected 1 issue, got %+v", issues)
+			}
+			issue := issues[0]
+			if issue.Type != types.IssueTypeSecurity || issue.Path != "jobs.job.script" {
+				t.Errorf("Unexpected issue %+v", issue)
+			}
+			if !strings.Contains(issue.Message, tt.wantMatch) || !strings.Contains(issue.Message, tt.script[0]) {
+				t.Errorf("Expected %q and the offending line in the message, got %q", tt.wantMatch, issue.Message)
+			}
+		})
+	}
+}
+
+func TestCheckScriptShellInjection_OnePerJob(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Default: &parser.JobConfig{BeforeScript: []string{"curl https://get.example.com | sh"}},
+		Jobs: map[string]*parser.JobConfig{
+			"release": {
+				BeforeScript: []string{"eval $SETUP"},
+				Script:       []string{"echo building", "sh -c $RELEASE_CMD"},
+				AfterScript:  []string{"if true; then\n  ./post --msg $CI_COMMIT_TITLE\nfi"},
+			},
+		},
+	}
+
+	issues := CheckScriptShellInjection(config, nil)
+	if len(issues) != 2 {
+		t.Fatalf("Expected one issue for default and one for release, got %+v", issues)
+	}
+	if issues[0].Path != "default.before_script" {
+		t.Errorf("Expected the default block first, got %s", issues[0].Path)
+	}
+	release := issues[1]
+	if release.JobName != "release" || release.Path != "jobs.release.before_script" {
+		t.Errorf("Expected the first finding to locate the issue, got %+v", release)
+	}
+	if !strings.Contains(release.Message, "eval $SETUP") || !strings.Contains(release.Message, "and 2 more") {
+		t.Errorf("Expected the first line and a count of the rest, got %q", release.Message)
+	}
+	if strings.Contains(release.Message, "fi") {
+		t.Errorf("Expected only offending lines of multi-line entries, got %q", release.Message)
+	}
+}
+
+func TestCheckScriptShellInjection_Params(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"upload": {Script: []string{"scp build.tar $TARGET_HOST:/srv", "./notify $CI_COMMIT_TITLE"}},
+		},
+	}
+
+	issues := CheckScriptShellInjection(config, map[string]interface{}{
+		"patterns":            []interface{}{`\bscp\b.*\$`},
+		"untrusted_variables": []interface{}{"SOMETHING_ELSE"},
+	})
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, `matches pattern \bscp\b.*\$`) {
+		t.Fatalf("Expected the custom pattern to match, got %+v", issues)
+	}
+	if strings.Contains(issues[0].Message, "more") {
+		t.Errorf("Expected CI_COMMIT_TITLE not to count once untrusted_variables is overridden, got %q", issues[0].Message)
+	}
+}
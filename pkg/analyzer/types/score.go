@@ -0,0 +1,106 @@
+package types
+
+import "math"
+
+// severityWeights and typeWeights determine how much a single issue costs
+var severityWeights = map[Severity]float64{
+	SeverityHigh:   5,
+	SeverityMedium: 2,
+	SeverityLow:    1,
+}
+
+var typeWeights = map[IssueType]float64{
+	IssueTypeSecurity:        1.5,
+	IssueTypeReliability:     1.25,
+	IssueTypePerformance:     1.0,
+	IssueTypeMaintainability: 0.75,
+}
+
+// scorePenaltyScale converts the weighted issue cost per job into score points
+const scorePenaltyScale = 10
+
+// HealthScore is a bounded 0-100 pipeline health score with a per-category breakdown
+type HealthScore struct {
+	Score     int           `json:"score"`
+	Grade     string        `json:"grade"`
+	JobCount  int           `json:"job_count"`
+	Breakdown CategoryScore `json:"breakdown"`
+}
+
+// CategoryScore holds the 0-100 score each issue type would get on its own
+type CategoryScore struct {
+	Performance     int `json:"performance"`
+	Security        int `json:"security"`
+	Maintainability int `json:"maintainability"`
+	Reliability     int `json:"reliability"`
+}
+
+// Score returns the severity-weighted pipeline health score (0-100)
+func (r *AnalysisResult) Score() int {
+	return scoreIssues(r.Issues, r.JobCount)
+}
+
+// Grade returns the letter grade for the result's score
+func (r *AnalysisResult) Grade() string {
+	return GradeForScore(r.Score())
+}
+
+// CalculateHealth computes the overall score, grade and per-category breakdown
+func (r *AnalysisResult) CalculateHealth() HealthScore {
+	byType := make(map[IssueType][]Issue)
+	for _, issue := range r.Issues {
+		byType[issue.Type] = append(byType[issue.Type], issue)
+	}
+
+	score := r.Score()
+	return HealthScore{
+		Score:    score,
+		Grade:    GradeForScore(score),
+		JobCount: r.JobCount,
+		Breakdown: CategoryScore{
+			Performance:     scoreIssues(byType[IssueTypePerformance], r.JobCount),
+			Security:        scoreIssues(byType[IssueTypeSecurity], r.JobCount),
+			Maintainability: scoreIssues(byType[IssueTypeMaintainability], r.JobCount),
+			Reliability:     scoreIssues(byType[IssueTypeReliability], r.JobCount),
+		},
+	}
+}
+
+// GradeForScore maps a 0-100 score to a letter grade
+func GradeForScore(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	default:
+		return "F"
+	}
+}
+
+// scoreIssues subtracts the weighted issue cost per job from 100, so larger
+// pipelines are not punished for having proportionally more findings
+func scoreIssues(issues []Issue, jobCount int) int {
+	if jobCount < 1 {
+		jobCount = 1
+	}
+
+	penalty := 0.0
+	for _, issue := range issues {
+		typeWeight, exists := typeWeights[issue.Type]
+		if !exists {
+			typeWeight = 1.0
+		}
+		penalty += severityWeights[issue.Severity] * typeWeight
+	}
+
+	score := 100 - int(math.Round(penalty/float64(jobCount)*scorePenaltyScale))
+	if score < 0 {
+		return 0
+	}
+	return score
+}
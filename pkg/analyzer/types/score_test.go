@@ -0,0 +1,67 @@
+package types
+
+import "testing"
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		issues   []Issue
+		jobCount int
+		expected int
+	}{
+		{"no issues", nil, 5, 100},
+		{"single low maintainability issue", []Issue{{Type: IssueTypeMaintainability, Severity: SeverityLow}}, 10, 99},
+		{"high security issue in one job", []Issue{{Type: IssueTypeSecurity, Severity: SeverityHigh}}, 1, 25},
+		{"high security issue across ten jobs", []Issue{{Type: IssueTypeSecurity, Severity: SeverityHigh}}, 10, 92},
+		{"zero jobs treated as one", []Issue{{Type: IssueTypePerformance, Severity: SeverityMedium}}, 0, 80},
+		{"bounded at zero", []Issue{
+			{Type: IssueTypeSecurity, Severity: SeverityHigh},
+			{Type: IssueTypeSecurity, Severity: SeverityHigh},
+			{Type: IssueTypeReliability, Severity: SeverityHigh},
+		}, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &AnalysisResult{Issues: tt.issues, JobCount: tt.jobCount}
+			if score := result.Score(); score != tt.expected {
+				t.Errorf("Expected score %d, got %d", tt.expected, score)
+			}
+		})
+	}
+}
+
+func TestGradeForScore(t *testing.T) {
+	tests := map[int]string{100: "A", 90: "A", 89: "B", 75: "C", 60: "D", 59: "F", 0: "F"}
+	for score, expected := range tests {
+		if grade := GradeForScore(score); grade != expected {
+			t.Errorf("Score %d: expected grade %s, got %s", score, expected, grade)
+		}
+	}
+}
+
+func TestCalculateHealth(t *testing.T) {
+	result := &AnalysisResult{
+		Issues: []Issue{
+			{Type: IssueTypeSecurity, Severity: SeverityHigh},
+			{Type: IssueTypeMaintainability, Severity: SeverityLow},
+		},
+		JobCount: 5,
+	}
+
+	health := result.CalculateHealth()
+
+	if health.Score != result.Score() {
+		t.Errorf("Expected health score %d to match Score(), got %d", result.Score(), health.Score)
+	}
+	if health.Grade != GradeForScore(health.Score) {
+		t.Errorf("Expected grade %s, got %s", GradeForScore(health.Score), health.Grade)
+	}
+	if health.Breakdown.Security >= health.Breakdown.Maintainability {
+		t.Errorf("Expected security (%d) to drag more than maintainability (%d)",
+			health.Breakdown.Security, health.Breakdown.Maintainability)
+	}
+	if health.Breakdown.Performance != 100 || health.Breakdown.Reliability != 100 {
+		t.Errorf("Expected untouched categories to score 100, got %+v", health.Breakdown)
+	}
+}
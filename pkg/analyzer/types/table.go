@@ -0,0 +1,157 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ANSI escape sequences used by FormatTable
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiRed    = "\033[31m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+	ansiDim    = "\033[2m"
+)
+
+// tableTypeOrder is the order in which FormatTable lists issue types
+var tableTypeOrder = []IssueType{
+	IssueTypeSecurity,
+	IssueTypeReliability,
+	IssueTypePerformance,
+	IssueTypeMaintainability,
+}
+
+// tableMessageWidth caps the message column, so long paths don't push
+// messages off narrow terminals; longer messages wrap within the column
+const tableMessageWidth = 80
+
+// FormatTable renders the issues as aligned tables, one per issue type, with
+// columns for severity, path and message and each issue's suggestion on the
+// line below. When the issues come from more than one file, such as a main
+// file and its includes, each issue's source file is shown under its message.
+// Issues are listed most severe first. With useColor, severities are colored
+// with ANSI escapes (high red, medium yellow, low cyan).
+func (r *AnalysisResult) FormatTable(useColor bool) string {
+	var buf strings.Builder
+
+	paint := func(code, text string) string {
+		if !useColor {
+			return text
+		}
+		return code + text + ansiReset
+	}
+
+	byType := make(map[IssueType][]Issue)
+	var unknown []IssueType
+	sourceFiles := make(map[string]bool)
+	for _, issue := range r.Issues {
+		sourceFiles[issue.SourceFile] = true
+		if byType[issue.Type] == nil && !isTableType(issue.Type) {
+			unknown = append(unknown, issue.Type)
+		}
+		byType[issue.Type] = append(byType[issue.Type], issue)
+	}
+
+	first := true
+	for _, issueType := range append(append([]IssueType{}, tableTypeOrder...), unknown...) {
+		issues := append([]Issue(nil), byType[issueType]...)
+		if len(issues) == 0 {
+			continue
+		}
+		SortIssues(issues)
+
+		if !first {
+			buf.WriteString("\n")
+		}
+		first = false
+
+		title := fmt.Sprintf("%s (%d)", tableTitle(issueType), len(issues))
+		buf.WriteString(paint(ansiBold, title) + "\n")
+
+		severityWidth, pathWidth := len("SEVERITY"), len("PATH")
+		for _, issue := range issues {
+			severityWidth = max(severityWidth, len(issue.Severity))
+			pathWidth = max(pathWidth, len(issue.Path))
+		}
+		indent := strings.Repeat(" ", 2+severityWidth+2+pathWidth+2)
+
+		header := fmt.Sprintf("  %-*s  %-*s  %s", severityWidth, "SEVERITY", pathWidth, "PATH", "MESSAGE")
+		buf.WriteString(paint(ansiDim, header) + "\n")
+
+		for _, issue := range issues {
+			// Pad before coloring, so escape codes don't count towards the width
+			severity := fmt.Sprintf("%-*s", severityWidth, strings.ToUpper(string(issue.Severity)))
+			lines := wrapText(issue.Message, tableMessageWidth)
+			buf.WriteString(fmt.Sprintf("  %s  %-*s  %s\n", paint(severityColor(issue.Severity), severity), pathWidth, issue.Path, lines[0]))
+			for _, line := range lines[1:] {
+				buf.WriteString(indent + line + "\n")
+			}
+			if len(sourceFiles) > 1 && issue.SourceFile != "" {
+				buf.WriteString(indent + paint(ansiDim, "in "+issue.SourceFile) + "\n")
+			}
+			if issue.Suggestion != "" {
+				for i, line := range wrapText(issue.Suggestion, tableMessageWidth-2) {
+					prefix := "  "
+					if i == 0 {
+						prefix = "→ "
+					}
+					buf.WriteString(indent + paint(ansiDim, prefix+line) + "\n")
+				}
+			}
+		}
+	}
+
+	return buf.String()
+}
+
+func isTableType(issueType IssueType) bool {
+	for _, known := range tableTypeOrder {
+		if known == issueType {
+			return true
+		}
+	}
+	return false
+}
+
+// tableTitle capitalizes an issue type for a table heading
+func tableTitle(issueType IssueType) string {
+	name := string(issueType)
+	if name == "" {
+		return "Other"
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func severityColor(severity Severity) string {
+	switch severity {
+	case SeverityHigh:
+		return ansiRed
+	case SeverityMedium:
+		return ansiYellow
+	default:
+		return ansiCyan
+	}
+}
+
+// wrapText breaks text into lines of at most width characters at spaces.
+// Words longer than width get a line of their own.
+func wrapText(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
@@ -0,0 +1,102 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalysisResult_FormatTable(t *testing.T) {
+	result := &AnalysisResult{
+		Issues: []Issue{
+			{Type: IssueTypePerformance, Severity: SeverityLow, Path: "jobs.build.cache", Message: "Cache without key", Suggestion: "Add a key"},
+			{Type: IssueTypeSecurity, Severity: SeverityMedium, Path: "image", Message: "Floating tag"},
+			{Type: IssueTypePerformance, Severity: SeverityHigh, Path: "jobs.test", Message: "Long chain"},
+		},
+	}
+
+	output := result.FormatTable(false)
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	expected := []string{
+		"Security (1)",
+		"  SEVERITY  PATH   MESSAGE",
+		"  MEDIUM    image  Floating tag",
+		"",
+		"Performance (2)",
+		"  SEVERITY  PATH              MESSAGE",
+		"  HIGH      jobs.test         Long chain",
+		"  LOW       jobs.build.cache  Cache without key",
+		"                              → Add a key",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d:\n%s", len(expected), len(lines), output)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, expected[i], lines[i])
+		}
+	}
+	if strings.Contains(output, "\033[") {
+		t.Errorf("Expected no escape codes without color, got %q", output)
+	}
+
+	colored := result.FormatTable(true)
+	if !strings.Contains(colored, ansiRed+"HIGH") || !strings.Contains(colored, ansiYellow+"MEDIUM") {
+		t.Errorf("Expected severities to be colored, got %q", colored)
+	}
+	if !strings.Contains(colored, ansiRed+"HIGH    "+ansiReset+"  jobs.test ") {
+		t.Errorf("Expected padding inside the colored severity, got %q", colored)
+	}
+}
+
+func TestAnalysisResult_FormatTableWrapsMessages(t *testing.T) {
+	result := &AnalysisResult{
+		Issues: []Issue{
+			{Type: IssueTypeReliability, Severity: SeverityLow, Path: "p", Message: strings.Repeat("word ", 30)},
+		},
+	}
+
+	lines := strings.Split(strings.TrimRight(result.FormatTable(false), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected the message to wrap onto a second line, got %q", lines)
+	}
+	indent := strings.Repeat(" ", len("  SEVERITY  PATH  "))
+	if !strings.HasPrefix(lines[3], indent+"word") || len(lines[2]) > len(indent)+tableMessageWidth {
+		t.Errorf("Expected the wrapped line to align with the message column, got %q", lines)
+	}
+}
+
+func TestAnalysisResult_FormatTableSourceFiles(t *testing.T) {
+	single := &AnalysisResult{
+		Issues: []Issue{
+			{Type: IssueTypeSecurity, Severity: SeverityMedium, Path: "image", Message: "Floating tag", SourceFile: ".gitlab-ci.yml"},
+		},
+	}
+	if output := single.FormatTable(false); strings.Contains(output, "in .gitlab-ci.yml") {
+		t.Errorf("Expected no source file when every issue comes from one file, got:\n%s", output)
+	}
+
+	included := &AnalysisResult{
+		Issues: []Issue{
+			{Type: IssueTypeSecurity, Severity: SeverityMedium, Path: "image", Message: "Floating tag", SourceFile: ".gitlab-ci.yml"},
+			{Type: IssueTypeSecurity, Severity: SeverityLow, Path: "jobs.lint.image", Message: "Latest tag", Suggestion: "Pin it", SourceFile: "ci/lint.yml"},
+		},
+	}
+	lines := strings.Split(strings.TrimRight(included.FormatTable(false), "\n"), "\n")
+	expected := []string{
+		"Security (2)",
+		"  SEVERITY  PATH             MESSAGE",
+		"  MEDIUM    image            Floating tag",
+		"                             in .gitlab-ci.yml",
+		"  LOW       jobs.lint.image  Latest tag",
+		"                             in ci/lint.yml",
+		"                             → Pin it",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d:\n%s", len(expected), len(lines), strings.Join(lines, "\n"))
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, expected[i], lines[i])
+		}
+	}
+}
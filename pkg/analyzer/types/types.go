@@ -1,6 +1,8 @@
 package types
 
 import (
+	"sort"
+
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
 )
 
@@ -28,12 +30,63 @@ type Issue struct {
 	Message    string    `json:"message"`
 	Suggestion string    `json:"suggestion,omitempty"`
 	JobName    string    `json:"job_name,omitempty"`
+	// SourceFile is the file to edit to fix the issue: the include that
+	// defines the job, or the main file
+	SourceFile string `json:"source_file,omitempty"`
+	// Score is the measurement behind the issue, for checks that flag
+	// values above a threshold
+	Score int `json:"score,omitempty"`
+	// Check is the name of the check that reported the issue
+	Check string `json:"check,omitempty"`
+	// Fixable marks issues the fixer package can resolve mechanically
+	Fixable bool `json:"fixable,omitempty"`
+}
+
+// Fingerprint identifies an issue across analysis runs
+func (i Issue) Fingerprint() string {
+	return string(i.Type) + "|" + i.Path + "|" + i.Message
 }
 
 type AnalysisResult struct {
-	Issues      []Issue `json:"issues"`
-	TotalIssues int     `json:"total_issues"`
-	Summary     Summary `json:"summary"`
+	Issues      []Issue     `json:"issues"`
+	TotalIssues int         `json:"total_issues"`
+	Summary     Summary     `json:"summary"`
+	JobCount    int         `json:"job_count"`
+	Health      HealthScore `json:"health"`
+	// HiddenIssues counts the issues left out of Issues by Filtered
+	HiddenIssues int `json:"hidden_issues,omitempty"`
+}
+
+// AnalysisDelta holds the issues added, removed and unchanged between a
+// baseline analysis and a new one
+type AnalysisDelta struct {
+	Added     []Issue `json:"added"`
+	Removed   []Issue `json:"removed"`
+	Unchanged []Issue `json:"unchanged"`
+}
+
+// RefactoringPlan groups an analysis' issues into recommendations ranked by
+// how much fixing each group would improve the pipeline
+type RefactoringPlan struct {
+	CurrentScore    int              `json:"current_score"`
+	ProjectedScore  int              `json:"projected_score"`
+	TotalIssues     int              `json:"total_issues"`
+	Recommendations []Recommendation `json:"recommendations"`
+}
+
+// Recommendation is one action in a refactoring plan together with the issues
+// it resolves and its estimated impact
+type Recommendation struct {
+	Rank           int      `json:"rank"`
+	Theme          string   `json:"theme"`
+	Title          string   `json:"title"`
+	Action         string   `json:"action"`
+	Severity       Severity `json:"severity"`
+	IssuesResolved int      `json:"issues_resolved"`
+	ScoreGain      int      `json:"score_gain"`
+	MinutesSaved   int      `json:"estimated_minutes_saved"`
+	Jobs           []string `json:"jobs,omitempty"`
+	Issues         []Issue  `json:"issues"`
 }
 
 type Summary struct {
@@ -46,6 +99,55 @@ type Summary struct {
 // CheckFunc is a function type for check functions
 type CheckFunc func(config *parser.GitLabConfig) []Issue
 
+// ParamCheckFunc is a check function that also receives the check's custom_params
+type ParamCheckFunc func(config *parser.GitLabConfig, params map[string]interface{}) []Issue
+
+// StringListParam reads a list of strings from custom_params, accepting the
+// []interface{} form produced by YAML decoding and a single string
+func StringListParam(params map[string]interface{}, name string, defaultValue []string) []string {
+	switch list := params[name].(type) {
+	case []string:
+		return list
+	case []interface{}:
+		result := make([]string, 0, len(list))
+		for _, item := range list {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	case string:
+		return []string{list}
+	default:
+		return defaultValue
+	}
+}
+
+// IntParam reads an integer from custom_params, accepting the int64 and
+// float64 forms produced by YAML and JSON decoding
+func IntParam(params map[string]interface{}, name string, defaultValue int) int {
+	switch value := params[name].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	default:
+		return defaultValue
+	}
+}
+
+// ContainsString reports whether list contains value
+func ContainsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
 // CheckConfig holds configuration for individual checks
 type CheckConfig struct {
 	Name           string                 `yaml:"name" json:"name"`
@@ -64,6 +166,21 @@ type CheckExclusions struct {
 	Paths []string `yaml:"paths,omitempty" json:"paths,omitempty"`
 }
 
+// CheckDoc explains a check: why its findings matter, an example finding and
+// its fix, and the checks that cover related problems
+type CheckDoc struct {
+	Rationale string       `json:"rationale"`
+	Example   CheckExample `json:"example"`
+	Related   []string     `json:"related,omitempty"`
+}
+
+// CheckExample is a configuration snippet the check flags and the same
+// snippet fixed
+type CheckExample struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
 func (r *AnalysisResult) FilterBySeverity(severity Severity) []Issue {
 	var filtered []Issue
 	for _, issue := range r.Issues {
@@ -84,6 +201,147 @@ func (r *AnalysisResult) FilterByType(issueType IssueType) []Issue {
 	return filtered
 }
 
+// IssueFilter narrows the issues shown to a user. An issue matches when its
+// severity is at least MinSeverity, or MinSeverity is empty, and its type is
+// in Types, or Types is empty.
+type IssueFilter struct {
+	MinSeverity Severity
+	Types       []IssueType
+}
+
+// IsSet reports whether the filter leaves out any issues
+func (f IssueFilter) IsSet() bool {
+	return f.MinSeverity != "" || len(f.Types) > 0
+}
+
+// Matches reports whether the issue passes the filter
+func (f IssueFilter) Matches(issue Issue) bool {
+	if f.MinSeverity != "" && SeverityRank(issue.Severity) < SeverityRank(f.MinSeverity) {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, issueType := range f.Types {
+		if issue.Type == issueType {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the issues that pass the filter
+func (f IssueFilter) Apply(issues []Issue) []Issue {
+	if !f.IsSet() {
+		return issues
+	}
+	filtered := []Issue{}
+	for _, issue := range issues {
+		if f.Matches(issue) {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered
+}
+
+// Filtered returns a copy of the result listing only the issues that pass
+// the filter. TotalIssues, Summary and Health still describe every issue,
+// and HiddenIssues counts the ones left out, so that a filtered report
+// doesn't hide problems without saying so.
+func (r *AnalysisResult) Filtered(f IssueFilter) *AnalysisResult {
+	filtered := *r
+	filtered.Issues = f.Apply(r.Issues)
+	filtered.HiddenIssues = r.HiddenIssues + len(r.Issues) - len(filtered.Issues)
+	return &filtered
+}
+
+// Exit codes returned by AnalysisResult.ExitCode
+const (
+	ExitClean    = 0
+	ExitWarnings = 1
+	ExitFailures = 2
+)
+
+// ExitPolicy decides which issues fail an analysis used as a merge gate. An
+// issue fails it when its type is in FailOn, or FailOn is empty, and its
+// severity is at least FailOnSeverity, or FailOnSeverity is empty. All other
+// issues are warnings.
+type ExitPolicy struct {
+	FailOn         []IssueType `yaml:"fail_on,omitempty" json:"fail_on,omitempty"`
+	FailOnSeverity Severity    `yaml:"fail_on_severity,omitempty" json:"fail_on_severity,omitempty"`
+}
+
+// IsSet reports whether the policy restricts the issues that fail
+func (p *ExitPolicy) IsSet() bool {
+	return p != nil && (len(p.FailOn) > 0 || p.FailOnSeverity != "")
+}
+
+// Fails reports whether the issue fails the analysis under the policy
+func (p *ExitPolicy) Fails(issue Issue) bool {
+	if p == nil {
+		return true
+	}
+	if p.FailOnSeverity != "" && SeverityRank(issue.Severity) < SeverityRank(p.FailOnSeverity) {
+		return false
+	}
+	if len(p.FailOn) == 0 {
+		return true
+	}
+	for _, issueType := range p.FailOn {
+		if issue.Type == issueType {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitCode returns ExitClean when the analysis found no issues, ExitFailures
+// when an issue fails under the policy and ExitWarnings otherwise. A nil or
+// empty policy fails on every issue.
+func (r *AnalysisResult) ExitCode(policy *ExitPolicy) int {
+	if len(r.Issues) == 0 {
+		return ExitClean
+	}
+	for _, issue := range r.Issues {
+		if policy.Fails(issue) {
+			return ExitFailures
+		}
+	}
+	return ExitWarnings
+}
+
+// SortIssues orders issues by severity (highest first), then type, path and
+// message so that analysis output is stable across runs
+func SortIssues(issues []Issue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Severity != b.Severity {
+			return SeverityRank(a.Severity) > SeverityRank(b.Severity)
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Message < b.Message
+	})
+}
+
+// SeverityRank orders severities from low (1) to high (3); unknown severities rank 0
+func SeverityRank(s Severity) int {
+	switch s {
+	case SeverityLow:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityHigh:
+		return 3
+	default:
+		return 0
+	}
+}
+
 func CalculateSummary(issues []Issue) Summary {
 	summary := Summary{}
 
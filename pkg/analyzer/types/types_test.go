@@ -157,6 +157,41 @@ func TestCalculateSummary_EmptyIssues(t *testing.T) {
 	}
 }
 
+func TestIssueFingerprint(t *testing.T) {
+	a := Issue{Type: IssueTypeSecurity, Severity: SeverityLow, Path: "jobs.a.image", Message: "untagged", Suggestion: "pin it"}
+	b := a
+	b.Severity = SeverityHigh
+	b.Suggestion = "different"
+
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Error("Expected fingerprint to ignore severity and suggestion")
+	}
+
+	b.Path = "jobs.b.image"
+	if a.Fingerprint() == b.Fingerprint() {
+		t.Error("Expected fingerprint to depend on path")
+	}
+}
+
+func TestSortIssues(t *testing.T) {
+	issues := []Issue{
+		{Type: IssueTypeSecurity, Severity: SeverityLow, Path: "b", Message: "low security"},
+		{Type: IssueTypePerformance, Severity: SeverityMedium, Path: "z", Message: "medium perf"},
+		{Type: IssueTypeMaintainability, Severity: SeverityHigh, Path: "a", Message: "high maint"},
+		{Type: IssueTypePerformance, Severity: SeverityMedium, Path: "a", Message: "second"},
+		{Type: IssueTypePerformance, Severity: SeverityMedium, Path: "a", Message: "first"},
+	}
+
+	SortIssues(issues)
+
+	expected := []string{"high maint", "first", "second", "medium perf", "low security"}
+	for i, message := range expected {
+		if issues[i].Message != message {
+			t.Errorf("Position %d: expected %q, got %q", i, message, issues[i].Message)
+		}
+	}
+}
+
 func TestCheckFunc(t *testing.T) {
 	// Test that CheckFunc type works as expected
 	var checkFunc CheckFunc = func(config *parser.GitLabConfig) []Issue {
@@ -181,3 +216,111 @@ func TestCheckFunc(t *testing.T) {
 		t.Errorf("Expected issue message to be 'Test issue', got %s", issues[0].Message)
 	}
 }
+
+func TestAnalysisResultExitCode(t *testing.T) {
+	result := &AnalysisResult{Issues: []Issue{
+		{Type: IssueTypePerformance, Severity: SeverityHigh},
+		{Type: IssueTypeSecurity, Severity: SeverityLow},
+	}}
+
+	tests := []struct {
+		name     string
+		result   *AnalysisResult
+		policy   *ExitPolicy
+		expected int
+	}{
+		{"clean", &AnalysisResult{}, &ExitPolicy{FailOn: []IssueType{IssueTypeSecurity}}, ExitClean},
+		{"no policy fails on everything", result, nil, ExitFailures},
+		{"failing type", result, &ExitPolicy{FailOn: []IssueType{IssueTypeSecurity, IssueTypeReliability}}, ExitFailures},
+		{"warnings only", result, &ExitPolicy{FailOn: []IssueType{IssueTypeReliability}}, ExitWarnings},
+		{"severity below threshold", result, &ExitPolicy{FailOn: []IssueType{IssueTypeSecurity}, FailOnSeverity: SeverityMedium}, ExitWarnings},
+		{"severity alone", result, &ExitPolicy{FailOnSeverity: SeverityHigh}, ExitFailures},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := tt.result.ExitCode(tt.policy); code != tt.expected {
+				t.Errorf("Expected exit code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+
+	if (&ExitPolicy{}).IsSet() || !(&ExitPolicy{FailOnSeverity: SeverityHigh}).IsSet() {
+		t.Error("Expected IsSet to report only policies that restrict failing issues")
+	}
+}
+
+func TestAnalysisResultFiltered(t *testing.T) {
+	result := &AnalysisResult{
+		Issues: []Issue{
+			{Type: IssueTypePerformance, Severity: SeverityHigh},
+			{Type: IssueTypeSecurity, Severity: SeverityLow},
+			{Type: IssueTypeSecurity, Severity: SeverityHigh},
+			{Type: IssueTypeReliability, Severity: SeverityMedium},
+		},
+		TotalIssues: 4,
+		Summary:     Summary{Performance: 1, Security: 2, Reliability: 1},
+	}
+
+	tests := []struct {
+		name   string
+		filter IssueFilter
+		shown  int
+	}{
+		{"no filter", IssueFilter{}, 4},
+		{"minimum severity", IssueFilter{MinSeverity: SeverityMedium}, 3},
+		{"types", IssueFilter{Types: []IssueType{IssueTypeSecurity, IssueTypeReliability}}, 3},
+		{"intersection", IssueFilter{MinSeverity: SeverityHigh, Types: []IssueType{IssueTypeSecurity}}, 1},
+		{"nothing matches", IssueFilter{Types: []IssueType{IssueTypeMaintainability}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered := result.Filtered(tt.filter)
+			if len(filtered.Issues) != tt.shown {
+				t.Errorf("Expected %d issues shown, got %d", tt.shown, len(filtered.Issues))
+			}
+			if filtered.HiddenIssues != 4-tt.shown {
+				t.Errorf("Expected %d hidden issues, got %d", 4-tt.shown, filtered.HiddenIssues)
+			}
+			if filtered.TotalIssues != 4 || filtered.Summary != result.Summary {
+				t.Errorf("Expected the unfiltered totals, got %d and %+v", filtered.TotalIssues, filtered.Summary)
+			}
+		})
+	}
+
+	if len(result.Issues) != 4 {
+		t.Errorf("Expected the original result to be unchanged, got %d issues", len(result.Issues))
+	}
+}
+
+func TestParams(t *testing.T) {
+	params := map[string]interface{}{
+		"list":   []interface{}{"a", 1, "b"},
+		"single": "c",
+		"int":    3,
+		"float":  float64(4),
+	}
+
+	if got := StringListParam(params, "list", nil); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("StringListParam(list) = %v, want [a b]", got)
+	}
+	if got := StringListParam(params, "single", nil); len(got) != 1 || got[0] != "c" {
+		t.Errorf("StringListParam(single) = %v, want [c]", got)
+	}
+	if got := StringListParam(params, "missing", []string{"d"}); len(got) != 1 || got[0] != "d" {
+		t.Errorf("StringListParam(missing) = %v, want default [d]", got)
+	}
+	if got := IntParam(params, "int", 0); got != 3 {
+		t.Errorf("IntParam(int) = %d, want 3", got)
+	}
+	if got := IntParam(params, "float", 0); got != 4 {
+		t.Errorf("IntParam(float) = %d, want 4", got)
+	}
+	if got := IntParam(params, "single", 5); got != 5 {
+		t.Errorf("IntParam(single) = %d, want default 5", got)
+	}
+	if !ContainsString([]string{"a", "b"}, "b") || ContainsString([]string{"a"}, "b") {
+		t.Error("ContainsString gave the wrong answer")
+	}
+}
@@ -45,6 +45,20 @@ func New(config *parser.GitLabConfig) *Expander {
 
 // ExpandString expands variables in the given string with optional job-level variables
 func (e *Expander) ExpandString(str string, jobVars map[string]interface{}) string {
+	return e.expand(str, jobVars, nil)
+}
+
+// ExpandJobString expands variables as the given job sees them, applying only
+// the global variables the job inherits
+func (e *Expander) ExpandJobString(str string, job *parser.JobConfig) string {
+	if job == nil {
+		return e.expand(str, nil, nil)
+	}
+	return e.expand(str, job.Variables, job.InheritsVariable)
+}
+
+// expand substitutes variables; inherits filters global variables when non-nil
+func (e *Expander) expand(str string, jobVars map[string]interface{}, inherits func(string) bool) string {
 	if !strings.Contains(str, "$") {
 		return str
 	}
@@ -59,7 +73,9 @@ func (e *Expander) ExpandString(str string, jobVars map[string]interface{}) stri
 
 	// Add global vars (override common vars if defined)
 	for k, v := range e.globalVars {
-		jobVariables[k] = v
+		if inherits == nil || inherits(k) {
+			jobVariables[k] = v
+		}
 	}
 
 	// Add job-level vars (override global vars if defined)
@@ -230,3 +230,29 @@ func TestExpander_IntegrationWithRealWorldExamples(t *testing.T) {
 		})
 	}
 }
+
+func TestExpander_ExpandJobString(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Variables: map[string]interface{}{
+			"NODE_IMAGE":   "node:22",
+			"PYTHON_IMAGE": "python:3.12",
+		},
+	}
+	expander := New(config)
+
+	plain := &parser.JobConfig{}
+	if got := expander.ExpandJobString("$NODE_IMAGE", plain); got != "node:22" {
+		t.Errorf("Expected node:22, got %s", got)
+	}
+
+	restricted := &parser.JobConfig{
+		Inherit:   &parser.Inherit{Variables: []interface{}{"PYTHON_IMAGE"}},
+		Variables: map[string]interface{}{"LOCAL": "x"},
+	}
+	if got := expander.ExpandJobString("$NODE_IMAGE", restricted); got != "$NODE_IMAGE" {
+		t.Errorf("Expected NODE_IMAGE to stay unresolved, got %s", got)
+	}
+	if got := expander.ExpandJobString("$PYTHON_IMAGE-$LOCAL", restricted); got != "python:3.12-x" {
+		t.Errorf("Expected python:3.12-x, got %s", got)
+	}
+}
@@ -1,12 +1,39 @@
 package differ
 
 import (
-	"reflect"
+	"fmt"
+	"strings"
 
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
 )
 
+// Compare diffs two configurations, detecting improvement patterns with
+// DefaultDifferOptions
 func Compare(oldConfig, newConfig *parser.GitLabConfig) *DiffResult {
+	return CompareWithOptions(oldConfig, newConfig, DefaultDifferOptions())
+}
+
+// DiffFiles parses two GitLab CI files, resolving their includes as
+// parser.ParseFile does, and diffs them with DefaultDifferOptions. Local
+// includes are read relative to each file's directory; project includes,
+// which need the GitLab API, and includes that fail to load are skipped.
+// Jobs are compared as written, extends included, so that moving settings
+// into templates is reported as a refactoring improvement.
+func DiffFiles(oldPath, newPath string) (*DiffResult, error) {
+	oldConfig, err := parser.ParseFile(oldPath)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", oldPath, err)
+	}
+	newConfig, err := parser.ParseFile(newPath)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", newPath, err)
+	}
+	return Compare(oldConfig, newConfig), nil
+}
+
+// CompareWithOptions diffs two configurations, detecting only the improvement
+// patterns enabled in opts
+func CompareWithOptions(oldConfig, newConfig *parser.GitLabConfig, opts DifferOptions) *DiffResult {
 	result := &DiffResult{
 		Semantic:        []ConfigDiff{},
 		Dependencies:    []ConfigDiff{},
@@ -18,14 +45,28 @@ func Compare(oldConfig, newConfig *parser.GitLabConfig) *DiffResult {
 	// Compare global configuration
 	compareGlobalConfig(oldConfig, newConfig, result)
 
+	// Concrete jobs folded into an equivalent parallel:matrix job are
+	// reported as one improvement instead of removals and an addition
+	regroupedJobs := recordMatrixExpansions(matchMatrixExpansions(oldConfig, newConfig), result)
+
+	// So are jobs whose scripts were split among or merged from others
+	recordSplitsAndMerges(oldConfig, newConfig, matchSplitsAndMerges(oldConfig, newConfig, regroupedJobs), result, regroupedJobs)
+
+	// And jobs that do the same work under a new name
+	recordRenames(oldConfig, newConfig, matchRenames(oldConfig, newConfig, regroupedJobs), result, regroupedJobs)
+
 	// Compare jobs
-	compareJobs(oldConfig, newConfig, result)
+	compareJobs(oldConfig, newConfig, result, regroupedJobs)
 
 	// Compare dependency graphs
-	compareDependencies(oldConfig, newConfig, result)
+	compareDependencies(oldConfig, newConfig, result, regroupedJobs)
 
 	// Detect improvement patterns
-	detectImprovementPatterns(oldConfig, newConfig, result)
+	detectImprovementPatterns(oldConfig, newConfig, result, opts.withDefaults())
+
+	result.IgnoredDiffs = ignoreDiffs(result, opts.IgnorePaths)
+
+	sortResult(result)
 
 	result.HasChanges = len(result.Semantic) > 0 || len(result.Dependencies) > 0 || len(result.Performance) > 0 || len(result.Improvements) > 0
 	result.Summary = generateSummary(result)
@@ -52,8 +93,10 @@ func compareGlobalConfig(oldConfig, newConfig *parser.GitLabConfig, result *Diff
 	// Compare include statements
 	compareIncludes(oldConfig.Include, newConfig.Include, result)
 
+	compareWorkflow(oldConfig, newConfig, result)
+
 	// Compare default job configuration
-	if !reflect.DeepEqual(oldConfig.Default, newConfig.Default) {
+	if !semanticEqual(oldConfig.Default, newConfig.Default) {
 		result.Semantic = append(result.Semantic, ConfigDiff{
 			Type:        DiffTypeModified,
 			Path:        "default",
@@ -65,7 +108,61 @@ func compareGlobalConfig(oldConfig, newConfig *parser.GitLabConfig, result *Diff
 	}
 }
 
-func compareJobs(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult) {
+// compareWorkflow reports changes to the pipeline name, which only affects
+// how pipelines are displayed, to the rules, which decide whether a pipeline
+// is created at all, and to auto_cancel, which decides which pipelines and
+// jobs GitLab cancels
+func compareWorkflow(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult) {
+	oldWorkflow, newWorkflow := oldConfig.Workflow, newConfig.Workflow
+	if oldWorkflow == nil {
+		oldWorkflow = &parser.Workflow{}
+	}
+	if newWorkflow == nil {
+		newWorkflow = &parser.Workflow{}
+	}
+
+	if oldWorkflow.Name != newWorkflow.Name {
+		result.Semantic = append(result.Semantic, ConfigDiff{
+			Type:        DiffTypeModified,
+			Path:        "workflow.name",
+			Description: "Pipeline name has changed",
+			OldValue:    oldWorkflow.Name,
+			NewValue:    newWorkflow.Name,
+			Behavioral:  false,
+		})
+	}
+
+	if !semanticEqual(oldWorkflow.Rules, newWorkflow.Rules) {
+		behavioral, affectedJobs := workflowRulesBehaviorChanged(oldConfig, newConfig)
+		description := "Workflow rules changed which pipelines are created"
+		if !behavioral {
+			description = "Workflow rules rewritten without changing which pipelines are created"
+		} else if len(affectedJobs) > 0 {
+			description += fmt.Sprintf("; jobs %s run in different pipelines although their own rules didn't change", strings.Join(affectedJobs, ", "))
+		}
+		result.Semantic = append(result.Semantic, ConfigDiff{
+			Type:        DiffTypeModified,
+			Path:        "workflow.rules",
+			Description: description,
+			OldValue:    oldWorkflow.Rules,
+			NewValue:    newWorkflow.Rules,
+			Behavioral:  behavioral,
+		})
+	}
+
+	if !semanticEqual(oldWorkflow.AutoCancel, newWorkflow.AutoCancel) {
+		result.Semantic = append(result.Semantic, ConfigDiff{
+			Type:        DiffTypeModified,
+			Path:        "workflow.auto_cancel",
+			Description: "Pipeline auto-cancel behavior has changed",
+			OldValue:    oldWorkflow.AutoCancel,
+			NewValue:    newWorkflow.AutoCancel,
+			Behavioral:  true,
+		})
+	}
+}
+
+func compareJobs(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult, matrixJobs map[string]bool) {
 	oldJobs := make(map[string]*parser.JobConfig)
 	newJobs := make(map[string]*parser.JobConfig)
 
@@ -85,6 +182,9 @@ func compareJobs(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult)
 	}
 
 	for jobName := range allJobNames {
+		if matrixJobs[jobName] {
+			continue
+		}
 		oldJob, existsInOld := oldJobs[jobName]
 		newJob, existsInNew := newJobs[jobName]
 
@@ -105,14 +205,19 @@ func compareJobs(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult)
 				Behavioral:  true, // Job addition affects pipeline behavior
 			})
 		} else if existsInOld && existsInNew {
-			compareJob(jobName, oldJob, newJob, result)
+			compareJob(jobName, oldConfig, newConfig, oldJob, newJob, result)
 		}
 	}
 }
 
-func compareJob(jobName string, oldJob, newJob *parser.JobConfig, result *DiffResult) {
+func compareJob(jobName string, oldConfig, newConfig *parser.GitLabConfig, oldJob, newJob *parser.JobConfig, result *DiffResult) {
 	basePath := "jobs." + jobName
 
+	// Keywords that templates and default can provide compare by the value
+	// the job runs with, so moving them there isn't reported as a change
+	oldEffective := effectiveJob(oldConfig, jobName, oldJob)
+	newEffective := effectiveJob(newConfig, jobName, newJob)
+
 	// Compare critical job properties
 	if oldJob.Stage != newJob.Stage {
 		result.Semantic = append(result.Semantic, ConfigDiff{
@@ -136,18 +241,43 @@ func compareJob(jobName string, oldJob, newJob *parser.JobConfig, result *DiffRe
 		})
 	}
 
-	if oldJob.Image != newJob.Image {
+	if !semanticEqual(oldEffective.AfterScript, newEffective.AfterScript) {
+		result.Semantic = append(result.Semantic, ConfigDiff{
+			Type:        DiffTypeModified,
+			Path:        basePath + ".after_script",
+			Description: "Job after_script changed for " + jobName,
+			OldValue:    oldEffective.AfterScript,
+			NewValue:    newEffective.AfterScript,
+			Behavioral:  true,
+		})
+	}
+
+	if !semanticEqual(oldEffective.Image, newEffective.Image) {
 		result.Performance = append(result.Performance, ConfigDiff{
 			Type:        DiffTypeModified,
 			Path:        basePath + ".image",
 			Description: "Docker image changed for " + jobName,
-			OldValue:    oldJob.Image,
-			NewValue:    newJob.Image,
+			OldValue:    oldEffective.Image,
+			NewValue:    newEffective.Image,
 		})
 	}
 
-	// Compare dependencies and needs
-	if !equalStringSlices(oldJob.Dependencies, newJob.Dependencies) {
+	if !equalStringSlices(oldEffective.Tags, newEffective.Tags) {
+		result.Semantic = append(result.Semantic, ConfigDiff{
+			Type:        DiffTypeModified,
+			Path:        basePath + ".tags",
+			Description: "Runner tags changed for " + jobName,
+			OldValue:    oldEffective.Tags,
+			NewValue:    newEffective.Tags,
+			Behavioral:  true, // Tags decide which runners pick up the job
+		})
+	}
+
+	// Compare dependencies and needs. Unlike other lists, an empty one isn't
+	// the same as none: `dependencies: []` downloads no artifacts instead of
+	// all of them, and `needs: []` starts the job without waiting for the
+	// earlier stages.
+	if (oldJob.Dependencies == nil) != (newJob.Dependencies == nil) || !equalStringSlices(oldJob.Dependencies, newJob.Dependencies) {
 		result.Dependencies = append(result.Dependencies, ConfigDiff{
 			Type:        DiffTypeModified,
 			Path:        basePath + ".dependencies",
@@ -157,7 +287,7 @@ func compareJob(jobName string, oldJob, newJob *parser.JobConfig, result *DiffRe
 		})
 	}
 
-	if !reflect.DeepEqual(oldJob.Needs, newJob.Needs) {
+	if (oldJob.Needs == nil) != (newJob.Needs == nil) || !semanticEqual(oldJob.Needs, newJob.Needs) {
 		result.Dependencies = append(result.Dependencies, ConfigDiff{
 			Type:        DiffTypeModified,
 			Path:        basePath + ".needs",
@@ -168,17 +298,37 @@ func compareJob(jobName string, oldJob, newJob *parser.JobConfig, result *DiffRe
 	}
 
 	// Compare performance-related fields
-	if !reflect.DeepEqual(oldJob.Cache, newJob.Cache) {
+	if !semanticEqual(oldEffective.Cache, newEffective.Cache) {
 		result.Performance = append(result.Performance, ConfigDiff{
 			Type:        DiffTypeModified,
 			Path:        basePath + ".cache",
 			Description: "Cache configuration changed for " + jobName,
-			OldValue:    oldJob.Cache,
-			NewValue:    newJob.Cache,
+			OldValue:    oldEffective.Cache,
+			NewValue:    newEffective.Cache,
+		})
+	}
+
+	if oldEffective.Timeout != newEffective.Timeout {
+		result.Performance = append(result.Performance, ConfigDiff{
+			Type:        DiffTypeModified,
+			Path:        basePath + ".timeout",
+			Description: "Timeout changed for " + jobName,
+			OldValue:    oldEffective.Timeout,
+			NewValue:    newEffective.Timeout,
 		})
 	}
 
-	if !reflect.DeepEqual(oldJob.Artifacts, newJob.Artifacts) {
+	if !semanticEqual(oldEffective.Interruptible, newEffective.Interruptible) {
+		result.Performance = append(result.Performance, ConfigDiff{
+			Type:        DiffTypeModified,
+			Path:        basePath + ".interruptible",
+			Description: "Interruptible setting changed for " + jobName,
+			OldValue:    oldEffective.Interruptible,
+			NewValue:    newEffective.Interruptible,
+		})
+	}
+
+	if !semanticEqual(oldJob.Artifacts, newJob.Artifacts) {
 		result.Performance = append(result.Performance, ConfigDiff{
 			Type:        DiffTypeModified,
 			Path:        basePath + ".artifacts",
@@ -188,27 +338,60 @@ func compareJob(jobName string, oldJob, newJob *parser.JobConfig, result *DiffRe
 		})
 	}
 
+	// Retry compares as a whole: count, failure reasons and exit codes
+	if !semanticEqual(oldEffective.Retry, newEffective.Retry) {
+		result.Semantic = append(result.Semantic, ConfigDiff{
+			Type:        DiffTypeModified,
+			Path:        basePath + ".retry",
+			Description: "Retry configuration changed for " + jobName,
+			OldValue:    oldEffective.Retry,
+			NewValue:    newEffective.Retry,
+			Behavioral:  true, // Retries change how failures affect the pipeline
+		})
+	}
+
 	// Compare job variables
 	compareVariables(basePath+".variables", oldJob.Variables, newJob.Variables, result)
 
-	// Compare rules
-	if !reflect.DeepEqual(oldJob.Rules, newJob.Rules) {
+	// Inherit changes decide which defaults and globals reach the job
+	if !semanticEqual(oldJob.Inherit, newJob.Inherit) {
+		result.Semantic = append(result.Semantic, ConfigDiff{
+			Type:        DiffTypeModified,
+			Path:        basePath + ".inherit",
+			Description: "Default/variable inheritance changed for " + jobName,
+			OldValue:    oldJob.Inherit,
+			NewValue:    newJob.Inherit,
+			Behavioral:  true,
+		})
+	}
+
+	// Compare rules by their effective run decisions, not just structure
+	if !semanticEqual(oldJob.Rules, newJob.Rules) {
+		behavioral := rulesBehaviorChanged(oldConfig, newConfig, oldJob, newJob)
+		description := "Job rules changed for " + jobName
+		if !behavioral {
+			description = "Job rules rewritten without changing when " + jobName + " runs"
+		}
 		result.Semantic = append(result.Semantic, ConfigDiff{
 			Type:        DiffTypeModified,
 			Path:        basePath + ".rules",
-			Description: "Job rules changed for " + jobName,
+			Description: description,
 			OldValue:    oldJob.Rules,
 			NewValue:    newJob.Rules,
+			Behavioral:  behavioral,
 		})
 	}
 }
 
-func compareDependencies(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult) {
+func compareDependencies(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult, matrixJobs map[string]bool) {
 	oldGraph := oldConfig.GetDependencyGraph()
 	newGraph := newConfig.GetDependencyGraph()
 
 	// Check for dependency changes that could affect execution order
 	for jobName := range oldGraph {
+		if matrixJobs[jobName] {
+			continue
+		}
 		oldDeps := oldGraph[jobName]
 		newDeps := newGraph[jobName]
 
@@ -225,7 +408,7 @@ func compareDependencies(oldConfig, newConfig *parser.GitLabConfig, result *Diff
 
 	// Check for new jobs in dependency graph
 	for jobName := range newGraph {
-		if _, exists := oldGraph[jobName]; !exists {
+		if _, exists := oldGraph[jobName]; !exists && !matrixJobs[jobName] {
 			result.Dependencies = append(result.Dependencies, ConfigDiff{
 				Type:        DiffTypeAdded,
 				Path:        "dependency_graph." + jobName,
@@ -276,7 +459,7 @@ func compareVariables(path string, oldVars, newVars map[string]interface{}, resu
 				NewValue:    newVal,
 				Behavioral:  false, // Variable addition could be consolidation
 			})
-		} else if existsInOld && existsInNew && !reflect.DeepEqual(oldVal, newVal) {
+		} else if existsInOld && existsInNew && !semanticEqual(oldVal, newVal) {
 			result.Semantic = append(result.Semantic, ConfigDiff{
 				Type:        DiffTypeModified,
 				Path:        path + "." + key,
@@ -290,7 +473,7 @@ func compareVariables(path string, oldVars, newVars map[string]interface{}, resu
 }
 
 func compareIncludes(oldIncludes, newIncludes []parser.Include, result *DiffResult) {
-	if !reflect.DeepEqual(oldIncludes, newIncludes) {
+	if !semanticEqual(oldIncludes, newIncludes) {
 		result.Semantic = append(result.Semantic, ConfigDiff{
 			Type:        DiffTypeModified,
 			Path:        "include",
@@ -1,6 +1,10 @@
 package differ
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
@@ -13,7 +17,7 @@ func TestCompare_NoChanges(t *testing.T) {
 			"test": {
 				Stage:  "test",
 				Script: []string{"npm test"},
-				Image:  "node:16",
+				Image:  &parser.Image{Name: "node:16"},
 			},
 		},
 		Variables: map[string]interface{}{
@@ -203,13 +207,51 @@ func TestCompare_JobScriptChanged(t *testing.T) {
 	}
 }
 
+func TestCompare_ImageEntrypointChanged(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"test": {Image: &parser.Image{Name: "node:18"}},
+		},
+	}
+	newConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"test": {Image: &parser.Image{Name: "node:18", Entrypoint: []string{""}}},
+		},
+	}
+
+	result := Compare(oldConfig, newConfig)
+
+	if len(result.Performance) != 1 || result.Performance[0].Path != "jobs.test.image" {
+		t.Fatalf("Expected an image diff for an entrypoint-only change, got %+v", result.Performance)
+	}
+}
+
+func TestCompare_RetryWhenChanged(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"test": {Retry: &parser.Retry{Max: 2, When: []string{"runner_system_failure"}}},
+		},
+	}
+	newConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"test": {Retry: &parser.Retry{Max: 2, When: []string{"runner_system_failure", "script_failure"}}},
+		},
+	}
+
+	result := Compare(oldConfig, newConfig)
+
+	if len(result.Semantic) != 1 || result.Semantic[0].Path != "jobs.test.retry" || !result.Semantic[0].Behavioral {
+		t.Fatalf("Expected a behavioral retry diff for a when-only change, got %+v", result.Semantic)
+	}
+}
+
 func TestCompare_ImageChanged_PerformanceCategory(t *testing.T) {
 	oldConfig := &parser.GitLabConfig{
 		Jobs: map[string]*parser.JobConfig{
 			"test": {
 				Stage:  "test",
 				Script: []string{"npm test"},
-				Image:  "node:16",
+				Image:  &parser.Image{Name: "node:16"},
 			},
 		},
 	}
@@ -219,7 +261,7 @@ func TestCompare_ImageChanged_PerformanceCategory(t *testing.T) {
 			"test": {
 				Stage:  "test",
 				Script: []string{"npm test"},
-				Image:  "node:18",
+				Image:  &parser.Image{Name: "node:18"},
 			},
 		},
 	}
@@ -418,7 +460,7 @@ func TestCompare_CacheChanged_PerformanceCategory(t *testing.T) {
 				Stage:  "test",
 				Script: []string{"npm test"},
 				Cache: &parser.Cache{
-					Key:   "test-cache",
+					Key:   &parser.CacheKey{Value: "test-cache"},
 					Paths: []string{"node_modules/"},
 				},
 			},
@@ -431,7 +473,7 @@ func TestCompare_CacheChanged_PerformanceCategory(t *testing.T) {
 				Stage:  "test",
 				Script: []string{"npm test"},
 				Cache: &parser.Cache{
-					Key:   "test-cache-v2",
+					Key:   &parser.CacheKey{Value: "test-cache-v2"},
 					Paths: []string{"node_modules/", ".npm/"},
 				},
 			},
@@ -465,7 +507,7 @@ func TestCompare_ComplexChanges(t *testing.T) {
 			"build": {
 				Stage:  "build",
 				Script: []string{"make build"},
-				Image:  "golang:1.24",
+				Image:  &parser.Image{Name: "golang:1.24"},
 			},
 			"test": {
 				Stage:        "test",
@@ -484,7 +526,7 @@ func TestCompare_ComplexChanges(t *testing.T) {
 			"build": {
 				Stage:  "build",
 				Script: []string{"make build", "make package"},
-				Image:  "golang:1.20",
+				Image:  &parser.Image{Name: "golang:1.20"},
 			},
 			"test": {
 				Stage:        "test",
@@ -526,3 +568,599 @@ func TestCompare_ComplexChanges(t *testing.T) {
 		t.Errorf("Summary should mention performance changes: %s", result.Summary)
 	}
 }
+
+func TestCompare_DeterministicOrder(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Variables: map[string]interface{}{"A": "1", "B": "2", "C": "3"},
+		Jobs: map[string]*parser.JobConfig{
+			"build": {Stage: "build", Script: []string{"make"}},
+			"test":  {Stage: "test", Script: []string{"make test"}},
+			"lint":  {Stage: "test", Script: []string{"make lint"}},
+		},
+	}
+	newConfig := &parser.GitLabConfig{
+		Variables: map[string]interface{}{"A": "9", "D": "4"},
+		Jobs: map[string]*parser.JobConfig{
+			"build":   {Stage: "compile", Script: []string{"make all"}},
+			"test":    {Stage: "test", Script: []string{"make check"}},
+			"package": {Stage: "deploy", Script: []string{"make dist"}},
+		},
+	}
+
+	first := Compare(oldConfig, newConfig)
+	for i := 1; i < len(first.Semantic); i++ {
+		prev, curr := first.Semantic[i-1], first.Semantic[i]
+		if prev.Path > curr.Path || (prev.Path == curr.Path && prev.Type > curr.Type) {
+			t.Errorf("Semantic diffs not sorted: %s (%s) before %s (%s)", prev.Path, prev.Type, curr.Path, curr.Type)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		again := Compare(oldConfig, newConfig)
+		if again.Summary != first.Summary || !reflect.DeepEqual(again.Semantic, first.Semantic) ||
+			!reflect.DeepEqual(again.Dependencies, first.Dependencies) ||
+			!reflect.DeepEqual(again.ImprovementTags, first.ImprovementTags) {
+			t.Fatalf("Compare output differs between runs")
+		}
+	}
+}
+
+func TestCompare_InheritChangedIsBehavioral(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"deploy": {Script: []string{"./deploy.sh"}},
+		},
+	}
+	newConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"deploy": {Script: []string{"./deploy.sh"}, Inherit: &parser.Inherit{Default: false}},
+		},
+	}
+
+	result := Compare(oldConfig, newConfig)
+
+	if len(result.Semantic) != 1 {
+		t.Fatalf("Expected 1 semantic change, got %d: %+v", len(result.Semantic), result.Semantic)
+	}
+	diff := result.Semantic[0]
+	if diff.Path != "jobs.deploy.inherit" || !diff.Behavioral {
+		t.Errorf("Expected behavioral diff at jobs.deploy.inherit, got %+v", diff)
+	}
+}
+
+func TestCompare_RulesBehavior(t *testing.T) {
+	mainPush := parser.Rule{If: `$CI_COMMIT_BRANCH == "main"`}
+	mergeRequest := parser.Rule{If: `$CI_PIPELINE_SOURCE == "merge_request_event"`}
+
+	tests := []struct {
+		name       string
+		oldRules   []parser.Rule
+		newRules   []parser.Rule
+		workflow   *parser.Workflow
+		behavioral bool
+	}{
+		{
+			name:       "independent rules reordered",
+			oldRules:   []parser.Rule{mainPush, mergeRequest},
+			newRules:   []parser.Rule{mergeRequest, mainPush},
+			behavioral: false,
+		},
+		{
+			name:       "equality rewritten as anchored regex",
+			oldRules:   []parser.Rule{mainPush},
+			newRules:   []parser.Rule{{If: `$CI_COMMIT_BRANCH =~ /^main$/`}},
+			behavioral: false,
+		},
+		{
+			name:       "source conditions moved to workflow",
+			oldRules:   []parser.Rule{mainPush, mergeRequest},
+			newRules:   nil,
+			workflow:   &parser.Workflow{Rules: []parser.Rule{mainPush, mergeRequest}},
+			behavioral: false,
+		},
+		{
+			name:       "merge request pipelines dropped",
+			oldRules:   []parser.Rule{mainPush, mergeRequest},
+			newRules:   []parser.Rule{mainPush},
+			behavioral: true,
+		},
+		{
+			name:       "manual instead of automatic",
+			oldRules:   []parser.Rule{mainPush},
+			newRules:   []parser.Rule{{If: mainPush.If, When: "manual"}},
+			behavioral: true,
+		},
+		{
+			name:       "changes cannot be evaluated",
+			oldRules:   []parser.Rule{mainPush},
+			newRules:   []parser.Rule{{If: mainPush.If, Changes: []string{"src/**"}}},
+			behavioral: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldConfig := &parser.GitLabConfig{
+				Jobs: map[string]*parser.JobConfig{
+					"build": {Script: []string{"make"}, Rules: tt.oldRules},
+				},
+			}
+			newConfig := &parser.GitLabConfig{
+				Workflow: tt.workflow,
+				Jobs: map[string]*parser.JobConfig{
+					"build": {Script: []string{"make"}, Rules: tt.newRules},
+				},
+			}
+
+			result := Compare(oldConfig, newConfig)
+
+			var found bool
+			for _, diff := range result.Semantic {
+				if diff.Path != "jobs.build.rules" {
+					continue
+				}
+				found = true
+				if diff.Behavioral != tt.behavioral {
+					t.Errorf("Expected behavioral=%v, got %v (%s)", tt.behavioral, diff.Behavioral, diff.Description)
+				}
+			}
+			if !found {
+				t.Fatal("Expected a rules diff for jobs.build.rules")
+			}
+		})
+	}
+}
+
+func TestCompare_JobsFoldedIntoMatrix(t *testing.T) {
+	versionJob := func(version string) *parser.JobConfig {
+		return &parser.JobConfig{
+			Stage:     "test",
+			Image:     &parser.Image{Name: "node:" + version},
+			Variables: map[string]interface{}{"NODE_VERSION": version},
+			Script:    []string{"npm ci", "npm test"},
+		}
+	}
+	oldConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"test:node14": versionJob("14"),
+			"test:node16": versionJob("16"),
+			"test:node18": versionJob("18"),
+		},
+	}
+	matrixJob := &parser.JobConfig{
+		Stage:    "test",
+		Image:    &parser.Image{Name: "node:${NODE_VERSION}"},
+		Parallel: &parser.Parallel{Matrix: []map[string]interface{}{{"NODE_VERSION": []interface{}{"14", "16", "18"}}}},
+		Script:   []string{"npm ci", "npm test"},
+	}
+	newConfig := &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{"test": matrixJob}}
+
+	result := Compare(oldConfig, newConfig)
+
+	if len(result.Semantic) != 0 || len(result.Dependencies) != 0 {
+		t.Errorf("Expected the matrix to line up with the removed jobs, got semantic %+v and dependencies %+v", result.Semantic, result.Dependencies)
+	}
+	found := false
+	for _, diff := range result.Improvements {
+		if diff.Path == "jobs.test.parallel.matrix" && !diff.Behavioral {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a matrix consolidation improvement, got %+v", result.Improvements)
+	}
+	tags := 0
+	for _, tag := range result.ImprovementTags {
+		if tag == "matrix" {
+			tags++
+		}
+	}
+	if tags != 1 {
+		t.Errorf("Expected the matrix tag once, got %v", result.ImprovementTags)
+	}
+
+	// A matrix that doesn't generate every removed job stays an add and remove
+	changed := *matrixJob
+	changed.Script = []string{"npm ci", "npm run test:ci"}
+	result = Compare(oldConfig, &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{"test": &changed}})
+	if len(result.Semantic) != 4 {
+		t.Errorf("Expected 3 removed jobs and 1 added job, got %+v", result.Semantic)
+	}
+}
+
+func TestCompare_SplitAndMergedJobs(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"build":  {Stage: "build", Script: []string{"make compile", "make package"}},
+			"lint":   {Script: []string{"golangci-lint run"}},
+			"vet":    {Script: []string{"go vet ./..."}},
+			"deploy": {Stage: "deploy", Script: []string{"./deploy.sh"}, Needs: []parser.Need{{Job: "build"}}},
+		},
+	}
+	newConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			".check":  {Script: []string{"golangci-lint run", "go vet ./..."}},
+			"compile": {Stage: "build", Script: []string{"make compile"}},
+			"package": {Stage: "build", Script: []string{"  make package  "}},
+			"check":   {Extends: ".check"},
+			"deploy":  {Stage: "deploy", Script: []string{"./deploy.sh"}, Needs: []parser.Need{{Job: "package"}}},
+		},
+	}
+
+	result := Compare(oldConfig, newConfig)
+
+	for _, diff := range result.Semantic {
+		if (diff.Type == DiffTypeAdded || diff.Type == DiffTypeRemoved) && diff.Path != "jobs..check" {
+			t.Errorf("Expected split and merged jobs not to be reported as added or removed, got %+v", diff)
+		}
+	}
+	descriptions := make(map[string]string)
+	for _, diff := range result.Improvements {
+		if diff.Type == DiffTypeModified && !diff.Behavioral {
+			descriptions[diff.Path] = diff.Description
+		}
+	}
+	if descriptions["jobs.build"] != "Job 'build' split into 'compile', 'package'" {
+		t.Errorf("Expected build to be split, got %v", descriptions)
+	}
+	if descriptions["jobs.check"] != "Jobs 'lint', 'vet' merged into 'check'" {
+		t.Errorf("Expected lint and vet to be merged, got %v", descriptions)
+	}
+	tags := strings.Join(result.ImprovementTags, ",")
+	if !strings.Contains(tags, "split") || !strings.Contains(tags, "merge") {
+		t.Errorf("Expected split and merge tags, got %v", result.ImprovementTags)
+	}
+
+	// Jobs that now need another job still show up in the dependency graph
+	found := false
+	for _, diff := range result.Dependencies {
+		if diff.Path == "dependency_graph.deploy" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected deploy's changed needs to be reported, got %+v", result.Dependencies)
+	}
+
+	// A renamed job or one that only keeps part of the script isn't a split
+	newConfig = &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"make":    {Stage: "build", Script: []string{"make compile", "make package"}},
+			"compile": {Stage: "build", Script: []string{"make compile"}},
+			"lint":    {Script: []string{"golangci-lint run"}},
+			"vet":     {Script: []string{"go vet ./..."}},
+			"deploy":  {Stage: "deploy", Script: []string{"./deploy.sh"}, Needs: []parser.Need{{Job: "build"}}},
+		},
+	}
+	result = Compare(oldConfig, newConfig)
+	if len(result.Improvements) != 0 {
+		t.Errorf("Expected no split or merge, got %+v", result.Improvements)
+	}
+}
+
+func TestCompare_SplitChangingSettings(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			".go":   {Image: &parser.Image{Name: "golang:1.22"}, Variables: map[string]interface{}{"CGO_ENABLED": "0"}},
+			"build": {Extends: ".go", Stage: "build", Script: []string{"make compile", "make package"}},
+		},
+	}
+	newConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			".go":     {Image: &parser.Image{Name: "golang:1.22"}, Variables: map[string]interface{}{"CGO_ENABLED": "0"}},
+			"compile": {Extends: ".go", Stage: "build", Script: []string{"make compile"}},
+			"package": {Extends: ".go", Stage: "build", Image: &parser.Image{Name: "golang:1.23"}, Script: []string{"make package"}},
+		},
+	}
+
+	splitDiff := func(result *DiffResult) *ConfigDiff {
+		for i, diff := range result.Improvements {
+			if diff.Path == "jobs.build" {
+				return &result.Improvements[i]
+			}
+		}
+		return nil
+	}
+
+	result := Compare(oldConfig, newConfig)
+
+	if split := splitDiff(result); split == nil || !split.Behavioral {
+		t.Fatalf("Expected a behavioral split, got %+v", result.Improvements)
+	}
+	var paths []string
+	for _, diff := range result.Semantic {
+		if diff.Behavioral {
+			paths = append(paths, diff.Path)
+		}
+	}
+	if strings.Join(paths, ",") != "jobs.package.image" {
+		t.Errorf("Expected only package's image change to be behavioral, got %v", paths)
+	}
+
+	// Settings inherited from the same template don't count as changes
+	newConfig.Jobs["package"].Image = nil
+	result = Compare(oldConfig, newConfig)
+	if split := splitDiff(result); split == nil || split.Behavioral || len(result.Semantic) != 0 {
+		t.Errorf("Expected a split without behavioral changes, got %+v and %+v", result.Improvements, result.Semantic)
+	}
+}
+
+func TestCompare_RenamedJobs(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Default: &parser.JobConfig{BeforeScript: []string{"npm ci"}},
+		Jobs: map[string]*parser.JobConfig{
+			"unit":   {Stage: "test", Script: []string{"npm test"}},
+			"e2e":    {Stage: "test", Script: []string{"npm run e2e"}, Timeout: "1h"},
+			"lint":   {Stage: "test", Script: []string{"npm run lint"}},
+			"deploy": {Stage: "deploy", Script: []string{"./deploy.sh"}, Needs: []parser.Need{{Job: "unit"}}},
+		},
+	}
+	newConfig := &parser.GitLabConfig{
+		Default: &parser.JobConfig{BeforeScript: []string{"npm ci"}},
+		Jobs: map[string]*parser.JobConfig{
+			".node":     {Stage: "test"},
+			"test:unit": {Extends: ".node", Script: []string{"  npm test"}},
+			"test:e2e":  {Extends: ".node", Script: []string{"npm run e2e"}, Timeout: "2h"},
+			"test:lint": {Extends: ".node", Script: []string{"npm run lint"}, BeforeScript: []string{"yarn install"}},
+			"deploy":    {Stage: "deploy", Script: []string{"./deploy.sh"}, Needs: []parser.Need{{Job: "test:unit"}}},
+		},
+	}
+
+	result := Compare(oldConfig, newConfig)
+
+	renames := make(map[string]bool)
+	for _, diff := range result.Semantic {
+		if diff.Type == DiffTypeRenamed {
+			renames[diff.Description] = diff.Behavioral
+		}
+	}
+	if behavioral, ok := renames["Job 'unit' renamed to 'test:unit'"]; !ok || behavioral {
+		t.Errorf("Expected unit to be renamed without behavioral changes, got %v", renames)
+	}
+	if behavioral, ok := renames["Job 'e2e' renamed to 'test:e2e'"]; !ok || !behavioral {
+		t.Errorf("Expected e2e to be renamed with a behavioral change, got %v", renames)
+	}
+	if len(renames) != 2 {
+		t.Errorf("Expected a job with a different before_script not to be a rename, got %v", renames)
+	}
+
+	var changed []string
+	for _, diff := range result.Semantic {
+		switch diff.Type {
+		case DiffTypeModified:
+			changed = append(changed, diff.Path)
+		case DiffTypeAdded, DiffTypeRemoved:
+			if strings.Contains(diff.Path, "unit") || strings.Contains(diff.Path, "e2e") {
+				t.Errorf("Expected renamed jobs not to be reported as added or removed, got %+v", diff)
+			}
+		}
+	}
+	if strings.Join(changed, ",") != "jobs.test:e2e.timeout" {
+		t.Errorf("Expected only e2e's timeout change to be reported, got %v", changed)
+	}
+}
+
+func TestCompare_WorkflowNameAndAutoCancel(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Workflow: &parser.Workflow{Name: "Pipeline for $CI_COMMIT_REF_NAME"},
+		Jobs:     map[string]*parser.JobConfig{"build": {Script: []string{"make"}}},
+	}
+	newConfig := &parser.GitLabConfig{
+		Workflow: &parser.Workflow{
+			Name:       "Build $CI_COMMIT_SHORT_SHA",
+			AutoCancel: &parser.AutoCancel{OnNewCommit: "interruptible"},
+		},
+		Jobs: map[string]*parser.JobConfig{"build": {Script: []string{"make"}}},
+	}
+
+	result := Compare(oldConfig, newConfig)
+
+	behavioral := make(map[string]bool)
+	for _, diff := range result.Semantic {
+		behavioral[diff.Path] = diff.Behavioral
+	}
+	if isBehavioral, found := behavioral["workflow.name"]; !found || isBehavioral {
+		t.Errorf("Expected a non-behavioral pipeline name change, got %+v", result.Semantic)
+	}
+	if isBehavioral, found := behavioral["workflow.auto_cancel"]; !found || !isBehavioral {
+		t.Errorf("Expected a behavioral auto_cancel change, got %+v", result.Semantic)
+	}
+
+	if result := Compare(newConfig, newConfig); result.HasChanges {
+		t.Errorf("Expected no changes for identical workflows, got %+v", result.Semantic)
+	}
+}
+
+func TestCompare_WorkflowRules(t *testing.T) {
+	mainBranch := parser.Rule{If: `$CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH`}
+	mergeRequest := parser.Rule{If: `$CI_PIPELINE_SOURCE == "merge_request_event"`}
+	jobs := func() map[string]*parser.JobConfig {
+		return map[string]*parser.JobConfig{
+			"build": {Script: []string{"make"}},
+			"lint": {
+				Script: []string{"make lint"},
+				Rules:  []parser.Rule{{If: `$CI_PIPELINE_SOURCE == "merge_request_event"`}},
+			},
+		}
+	}
+	config := func(rules ...parser.Rule) *parser.GitLabConfig {
+		return &parser.GitLabConfig{Workflow: &parser.Workflow{Rules: rules}, Jobs: jobs()}
+	}
+
+	workflowDiff := func(result *DiffResult) *ConfigDiff {
+		for i, diff := range result.Semantic {
+			if diff.Path == "workflow.rules" {
+				return &result.Semantic[i]
+			}
+		}
+		return nil
+	}
+
+	t.Run("tightened rules are behavioral", func(t *testing.T) {
+		result := Compare(config(mainBranch, mergeRequest), config(mainBranch))
+
+		diff := workflowDiff(result)
+		if diff == nil || !diff.Behavioral {
+			t.Fatalf("Expected a behavioral workflow.rules diff, got %+v", result.Semantic)
+		}
+		if !strings.Contains(diff.Description, "jobs build, lint") {
+			t.Errorf("Expected the description to name the affected jobs, got %q", diff.Description)
+		}
+		if oldRules, ok := diff.OldValue.([]parser.Rule); !ok || len(oldRules) != 2 {
+			t.Errorf("Expected the old rule set as OldValue, got %+v", diff.OldValue)
+		}
+		if newRules, ok := diff.NewValue.([]parser.Rule); !ok || len(newRules) != 1 {
+			t.Errorf("Expected the new rule set as NewValue, got %+v", diff.NewValue)
+		}
+	})
+
+	t.Run("reordered rules are not behavioral", func(t *testing.T) {
+		result := Compare(config(mainBranch, mergeRequest), config(mergeRequest, mainBranch))
+
+		diff := workflowDiff(result)
+		if diff == nil || diff.Behavioral {
+			t.Errorf("Expected a non-behavioral workflow.rules diff, got %+v", result.Semantic)
+		}
+	})
+
+	t.Run("unchanged rules produce no diff", func(t *testing.T) {
+		if diff := workflowDiff(Compare(config(mainBranch), config(mainBranch))); diff != nil {
+			t.Errorf("Expected no workflow.rules diff, got %+v", diff)
+		}
+	})
+}
+
+func TestCompare_EmptyListsAndMaps(t *testing.T) {
+	oldConfig, err := parser.Parse([]byte(`
+build:
+  stage: build
+  script: [make]
+test:
+  stage: test
+  script: [make test]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse old config: %v", err)
+	}
+
+	// Written-out empty values that mean the same as leaving them out
+	newConfig, err := parser.Parse([]byte(`
+build:
+  stage: build
+  script: [make]
+  after_script: []
+  tags: []
+  variables: {}
+  rules:
+    - changes: []
+test:
+  stage: test
+  script: [make test]
+  artifacts:
+    paths: []
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse new config: %v", err)
+	}
+	oldConfig.Jobs["build"].Rules = []parser.Rule{{}}
+	oldConfig.Jobs["test"].Artifacts = &parser.Artifacts{}
+
+	if result := Compare(oldConfig, newConfig); result.HasChanges {
+		t.Errorf("Expected no changes, got semantic %+v, dependencies %+v, performance %+v", result.Semantic, result.Dependencies, result.Performance)
+	}
+
+	// Empty needs and dependencies change what the job waits for and downloads
+	newConfig, err = parser.Parse([]byte(`
+build:
+  stage: build
+  script: [make]
+test:
+  stage: test
+  script: [make test]
+  needs: []
+  dependencies: []
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse new config: %v", err)
+	}
+	oldConfig.Jobs["build"].Rules = nil
+	oldConfig.Jobs["test"].Artifacts = nil
+
+	paths := make(map[string]bool)
+	for _, diff := range Compare(oldConfig, newConfig).Dependencies {
+		paths[diff.Path] = true
+	}
+	if !paths["jobs.test.needs"] || !paths["jobs.test.dependencies"] {
+		t.Errorf("Expected needs and dependencies changes, got %v", paths)
+	}
+}
+
+func TestCompareWithOptions_IgnorePaths(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Stages:    []string{"build"},
+		Variables: map[string]interface{}{"NODE_VERSION": "16"},
+		Jobs: map[string]*parser.JobConfig{
+			"build": {Stage: "build", Script: []string{"make"}},
+		},
+	}
+	newConfig := &parser.GitLabConfig{
+		Stages:    []string{"build"},
+		Variables: map[string]interface{}{"NODE_VERSION": "20", "BUILD_ENV": "production"},
+		Jobs: map[string]*parser.JobConfig{
+			"build": {Stage: "build", Script: []string{"make all"}},
+		},
+	}
+
+	opts := DefaultDifferOptions()
+	opts.IgnorePaths = []string{"variables.*"}
+	result := CompareWithOptions(oldConfig, newConfig, opts)
+
+	if result.IgnoredDiffs != 2 {
+		t.Errorf("Expected 2 ignored diffs, got %d", result.IgnoredDiffs)
+	}
+	for _, diff := range append(append(result.Semantic, result.Performance...), result.Dependencies...) {
+		if strings.HasPrefix(diff.Path, "variables.") {
+			t.Errorf("Expected variable diffs to be ignored, got %+v", diff)
+		}
+	}
+	if !result.HasChanges || len(result.Semantic) != 1 || result.Semantic[0].Path != "jobs.build.script" {
+		t.Errorf("Expected only the script change to remain, got %+v", result.Semantic)
+	}
+	if !strings.HasSuffix(result.Summary, "(2 ignored)") {
+		t.Errorf("Expected the summary to mention the ignored diffs, got %q", result.Summary)
+	}
+
+	opts.IgnorePaths = []string{"variables.*", "jobs.build.*"}
+	result = CompareWithOptions(oldConfig, newConfig, opts)
+	if result.HasChanges || result.Summary != "No semantic differences found (3 ignored)" {
+		t.Errorf("Expected every diff to be ignored, got %+v", result)
+	}
+}
+
+func TestDiffFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, version := range []string{"old", "new"} {
+		os.MkdirAll(filepath.Join(dir, version), 0755)
+		os.WriteFile(filepath.Join(dir, version, ".gitlab-ci.yml"), []byte("include:\n  - local: jobs.yml\n"), 0644)
+	}
+	os.WriteFile(filepath.Join(dir, "old", "jobs.yml"), []byte("test:\n  script: [make test]\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "new", "jobs.yml"), []byte("test:\n  script: [make check]\n"), 0644)
+
+	result, err := DiffFiles(filepath.Join(dir, "old", ".gitlab-ci.yml"), filepath.Join(dir, "new", ".gitlab-ci.yml"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	found := false
+	for _, diff := range result.Semantic {
+		if diff.Path == "jobs.test.script" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the included job's script change, got %+v", result.Semantic)
+	}
+
+	if _, err := DiffFiles(filepath.Join(dir, "missing.yml"), filepath.Join(dir, "new", ".gitlab-ci.yml")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
@@ -6,6 +6,9 @@ package differ
 // - types.go: Type definitions (DiffType, ConfigDiff, DiffResult)
 // - comparison.go: Core comparison logic (Compare function and related comparisons)
 // - improvements.go: Improvement pattern detection functions
+// - matrix.go: Lining up concrete jobs with the jobs a parallel:matrix generates
+// - split.go: Lining up jobs split into or merged from several others
+// - unified.go: Unified-diff text rendering of a DiffResult
 // - utils.go: Helper functions and utilities
 //
 // All public functions are still available through this package.
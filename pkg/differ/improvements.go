@@ -3,45 +3,63 @@ package differ
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
 )
 
 // detectImprovementPatterns analyzes changes to identify refactoring improvement patterns
-func detectImprovementPatterns(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult) {
+func detectImprovementPatterns(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult, opts DifferOptions) {
 	// Track improvement tags we find
 	improvementTags := make(map[string]bool)
 
 	// 1. Detect consolidation to default block
-	detectDefaultConsolidation(oldConfig, newConfig, result, improvementTags)
+	if opts.DetectDefaultConsolidation {
+		detectDefaultConsolidation(oldConfig, newConfig, result, improvementTags, opts.MinConsolidationJobs)
+	}
 
 	// 2. Detect template extraction (extends usage)
-	detectTemplateExtraction(oldConfig, newConfig, result, improvementTags)
+	if opts.DetectTemplateExtraction {
+		detectTemplateExtraction(oldConfig, newConfig, result, improvementTags)
+	}
 
 	// 3. Detect variable optimization (job -> global)
-	detectVariableOptimization(oldConfig, newConfig, result, improvementTags)
+	if opts.DetectVariableOptimization {
+		detectVariableOptimization(oldConfig, newConfig, result, improvementTags, opts.MinVariablePromotionJobs)
+	}
 
 	// 4. Detect dependency optimization (dependencies -> needs)
-	detectDependencyOptimization(oldConfig, newConfig, result, improvementTags)
+	if opts.DetectDependencyOptimization {
+		detectDependencyOptimization(oldConfig, newConfig, result, improvementTags)
+	}
 
 	// 5. Detect cache optimization patterns
-	detectCacheOptimization(oldConfig, newConfig, result, improvementTags)
+	if opts.DetectCacheOptimization {
+		detectCacheOptimization(oldConfig, newConfig, result, improvementTags)
+	}
 
 	// 6. Detect matrix pattern usage
-	detectMatrixPatterns(oldConfig, newConfig, result, improvementTags)
+	if opts.DetectMatrixPatterns {
+		detectMatrixPatterns(oldConfig, newConfig, result, improvementTags, opts.MinMatrixJobs)
+	}
 
 	// 7. Detect duplication removal
-	detectDuplicationRemoval(oldConfig, newConfig, result, improvementTags)
+	if opts.DetectDuplicationRemoval {
+		detectDuplicationRemoval(oldConfig, newConfig, result, improvementTags)
+	}
 
-	// Convert map to slice for result
+	// Convert map to slice for result, keeping tags added while comparing jobs
+	for _, tag := range result.ImprovementTags {
+		delete(improvementTags, tag)
+	}
 	for tag := range improvementTags {
 		result.ImprovementTags = append(result.ImprovementTags, tag)
 	}
 }
 
 // detectDefaultConsolidation checks if duplicate setup was moved to default block
-func detectDefaultConsolidation(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult, tags map[string]bool) {
+func detectDefaultConsolidation(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult, tags map[string]bool, minJobs int) {
 	// Check if default block was added or enhanced
 	oldDefault := oldConfig.Default
 	newDefault := newConfig.Default
@@ -58,7 +76,7 @@ func detectDefaultConsolidation(oldConfig, newConfig *parser.GitLabConfig, resul
 			}
 		}
 
-		if commonFieldsRemoved >= 2 {
+		if commonFieldsRemoved >= minJobs {
 			result.Improvements = append(result.Improvements, ConfigDiff{
 				Type:        DiffTypeAdded,
 				Path:        "default",
@@ -79,7 +97,7 @@ func detectTemplateExtraction(oldConfig, newConfig *parser.GitLabConfig, result
 	templateJobs := 0
 
 	for jobName, job := range newConfig.Jobs {
-		if strings.HasPrefix(jobName, ".") {
+		if parser.IsTemplateJob(jobName) {
 			templateJobs++
 		}
 
@@ -109,7 +127,7 @@ func detectTemplateExtraction(oldConfig, newConfig *parser.GitLabConfig, result
 }
 
 // detectVariableOptimization checks for variables moved from job-level to global
-func detectVariableOptimization(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult, tags map[string]bool) {
+func detectVariableOptimization(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult, tags map[string]bool, minJobs int) {
 	// Check for variables that were added globally and removed from jobs
 	oldGlobalVars := make(map[string]interface{})
 	newGlobalVars := make(map[string]interface{})
@@ -136,7 +154,7 @@ func detectVariableOptimization(oldConfig, newConfig *parser.GitLabConfig, resul
 				}
 			}
 
-			if jobsWithVar >= 2 {
+			if jobsWithVar >= minJobs {
 				result.Improvements = append(result.Improvements, ConfigDiff{
 					Type:        DiffTypeAdded,
 					Path:        "variables." + varName,
@@ -162,7 +180,7 @@ func detectVariableOptimization(oldConfig, newConfig *parser.GitLabConfig, resul
 
 	for jobName, newJob := range newConfig.Jobs {
 		if len(newJob.Variables) > 0 {
-			if strings.HasPrefix(jobName, ".") {
+			if parser.IsTemplateJob(jobName) {
 				templateJobsWithVars++
 			} else {
 				newJobsWithVars++
@@ -276,7 +294,7 @@ func detectDependencyOptimization(oldConfig, newConfig *parser.GitLabConfig, res
 	jobsUsingTemplates := 0
 
 	for jobName, job := range newConfig.Jobs {
-		if strings.HasPrefix(jobName, ".") {
+		if parser.IsTemplateJob(jobName) {
 			templateJobCount++
 		} else if job.Extends != nil {
 			jobsUsingTemplates++
@@ -308,12 +326,7 @@ func detectDuplicationRemoval(oldConfig, newConfig *parser.GitLabConfig, result
 	newJobCount := len(newConfig.Jobs)
 
 	// Count template jobs (start with .)
-	templateJobs := 0
-	for jobName := range newConfig.Jobs {
-		if strings.HasPrefix(jobName, ".") {
-			templateJobs++
-		}
-	}
+	templateJobs := len(newConfig.TemplateJobs())
 
 	// If we have fewer actual jobs but more templates, likely consolidation
 	newActualJobs := newJobCount - templateJobs
@@ -348,7 +361,7 @@ func detectCacheOptimization(oldConfig, newConfig *parser.GitLabConfig, result *
 
 	for jobName, job := range newConfig.Jobs {
 		if job.Cache != nil {
-			if strings.HasPrefix(jobName, ".") {
+			if parser.IsTemplateJob(jobName) {
 				templateJobsWithCache++
 			} else {
 				newJobsWithCache++
@@ -410,26 +423,27 @@ func detectCacheOptimization(oldConfig, newConfig *parser.GitLabConfig, result *
 }
 
 // detectMatrixPatterns looks for matrix strategy usage
-func detectMatrixPatterns(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult, tags map[string]bool) {
+func detectMatrixPatterns(oldConfig, newConfig *parser.GitLabConfig, result *DiffResult, tags map[string]bool, minJobs int) {
 	matrixImprovements := 0
 
 	// Look for jobs that could benefit from matrix strategy
 	// This is a heuristic: if we have many similar jobs with slight variations
 	jobPatterns := make(map[string][]string)
 
-	for jobName, job := range newConfig.Jobs {
-		if strings.HasPrefix(jobName, ".") {
-			continue // Skip templates
+	for jobName, job := range newConfig.ConcreteJobs() {
+		if !isMatrixCandidate(job) {
+			continue
 		}
 
-		// Create a pattern key based on script and stage
-		patternKey := job.Stage + ":" + strings.Join(job.Script, "|")
+		// Jobs that differ at most in image and variables share a pattern
+		patternKey := newConfig.JobMatrixFingerprint(jobName)
 		jobPatterns[patternKey] = append(jobPatterns[patternKey], jobName)
 	}
 
 	// Check for patterns that suggest matrix opportunities
 	for _, jobs := range jobPatterns {
-		if len(jobs) >= 2 {
+		if len(jobs) >= minJobs && hasVaryingJobs(newConfig, jobs) {
+			sort.Strings(jobs)
 			// Multiple jobs with same pattern could use matrix
 			result.Improvements = append(result.Improvements, ConfigDiff{
 				Type:        DiffTypeModified,
@@ -443,7 +457,7 @@ func detectMatrixPatterns(oldConfig, newConfig *parser.GitLabConfig, result *Dif
 
 	// Check for actual matrix usage
 	for jobName, job := range newConfig.Jobs {
-		if job.Parallel > 1 || hasMatrixLikeVariables(job) {
+		if job.Parallel.Instances() > 1 || hasMatrixLikeVariables(job) {
 			result.Improvements = append(result.Improvements, ConfigDiff{
 				Type:        DiffTypeAdded,
 				Path:        fmt.Sprintf("jobs.%s.matrix", jobName),
@@ -460,3 +474,25 @@ func detectMatrixPatterns(oldConfig, newConfig *parser.GitLabConfig, result *Dif
 		tags["optimization"] = true
 	}
 }
+
+// isMatrixCandidate reports whether a job could be folded into a
+// parallel:matrix: it needs a script of its own, and trigger jobs, jobs
+// already running in parallel, and jobs built from templates are left alone
+func isMatrixCandidate(job *parser.JobConfig) bool {
+	if job == nil || job.Trigger != nil || job.Parallel != nil || len(job.GetExtends()) > 0 {
+		return false
+	}
+	return strings.TrimSpace(strings.Join(job.Script, "\n")) != ""
+}
+
+// hasVaryingJobs reports whether the jobs differ in image or variables. Jobs
+// that are identical are duplicates rather than matrix entries.
+func hasVaryingJobs(config *parser.GitLabConfig, jobNames []string) bool {
+	first := config.JobFingerprint(jobNames[0])
+	for _, jobName := range jobNames[1:] {
+		if config.JobFingerprint(jobName) != first {
+			return true
+		}
+	}
+	return false
+}
@@ -12,11 +12,11 @@ func TestImprovementPatternDetection(t *testing.T) {
 		Jobs: map[string]*parser.JobConfig{
 			"build": {
 				BeforeScript: []string{"npm ci"},
-				Image:        "node:16",
+				Image:        &parser.Image{Name: "node:16"},
 			},
 			"test": {
 				BeforeScript: []string{"npm ci"},
-				Image:        "node:16",
+				Image:        &parser.Image{Name: "node:16"},
 			},
 		},
 	}
@@ -24,7 +24,7 @@ func TestImprovementPatternDetection(t *testing.T) {
 	newConfig := &parser.GitLabConfig{
 		Default: &parser.JobConfig{
 			BeforeScript: []string{"npm ci"},
-			Image:        "node:16",
+			Image:        &parser.Image{Name: "node:16"},
 		},
 		Jobs: map[string]*parser.JobConfig{
 			"build": {},
@@ -61,7 +61,7 @@ func TestTemplateExtractionDetection(t *testing.T) {
 	oldConfig := &parser.GitLabConfig{
 		Jobs: map[string]*parser.JobConfig{
 			"build": {
-				Image: "docker:20.10.16",
+				Image: &parser.Image{Name: "docker:20.10.16"},
 			},
 		},
 	}
@@ -69,7 +69,7 @@ func TestTemplateExtractionDetection(t *testing.T) {
 	newConfig := &parser.GitLabConfig{
 		Jobs: map[string]*parser.JobConfig{
 			".docker_base": {
-				Image: "docker:20.10.16",
+				Image: &parser.Image{Name: "docker:20.10.16"},
 			},
 			"build": {
 				Extends: []interface{}{"docker_base"},
@@ -93,6 +93,224 @@ func TestTemplateExtractionDetection(t *testing.T) {
 	}
 }
 
+func TestCompareWithOptions(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"test:node16": {Script: []string{"npm test"}, Image: &parser.Image{Name: "node:16"}},
+			"test:node18": {Script: []string{"npm test"}, Image: &parser.Image{Name: "node:18"}},
+		},
+	}
+	newConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"test:node16": {Script: []string{"npm test"}, Image: &parser.Image{Name: "node:16"}},
+			"test:node18": {Script: []string{"npm test"}, Image: &parser.Image{Name: "node:20"}},
+		},
+	}
+
+	hasMatrixSuggestion := func(result *DiffResult) bool {
+		for _, improvement := range result.Improvements {
+			if contains(improvement.Description, "could be optimized using matrix strategy") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !hasMatrixSuggestion(Compare(oldConfig, newConfig)) {
+		t.Fatal("Expected the default options to suggest a matrix")
+	}
+
+	opts := DefaultDifferOptions()
+	opts.DetectMatrixPatterns = false
+	result := CompareWithOptions(oldConfig, newConfig, opts)
+	if hasMatrixSuggestion(result) {
+		t.Error("Expected no matrix suggestion with matrix detection disabled")
+	}
+	for _, tag := range result.ImprovementTags {
+		if tag == "matrix" {
+			t.Errorf("Expected no 'matrix' tag with matrix detection disabled, got: %v", result.ImprovementTags)
+		}
+	}
+	if len(result.Performance) == 0 {
+		t.Error("Expected the image change to be reported regardless of detector options")
+	}
+
+	opts = DefaultDifferOptions()
+	opts.MinMatrixJobs = 3
+	if hasMatrixSuggestion(CompareWithOptions(oldConfig, newConfig, opts)) {
+		t.Error("Expected no matrix suggestion for 2 jobs with a threshold of 3")
+	}
+
+	// Unset thresholds fall back to their defaults
+	if !hasMatrixSuggestion(CompareWithOptions(oldConfig, newConfig, DifferOptions{DetectMatrixPatterns: true})) {
+		t.Error("Expected a zero threshold to use the default of 2 jobs")
+	}
+}
+
+func TestCompareWithOptions_ConsolidationThreshold(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"build": {BeforeScript: []string{"npm ci"}, Image: &parser.Image{Name: "node:16"}},
+			"test":  {BeforeScript: []string{"npm ci"}, Image: &parser.Image{Name: "node:16"}},
+		},
+	}
+	newConfig := &parser.GitLabConfig{
+		Default: &parser.JobConfig{BeforeScript: []string{"npm ci"}, Image: &parser.Image{Name: "node:16"}},
+		Jobs: map[string]*parser.JobConfig{
+			"build": {},
+			"test":  {},
+		},
+	}
+
+	opts := DefaultDifferOptions()
+	opts.MinConsolidationJobs = 3
+	for _, improvement := range CompareWithOptions(oldConfig, newConfig, opts).Improvements {
+		if improvement.Path == "default" {
+			t.Errorf("Expected no default consolidation for 2 jobs with a threshold of 3, got %q", improvement.Description)
+		}
+	}
+}
+
+func TestMatrixPatternCandidates(t *testing.T) {
+	tests := []struct {
+		name       string
+		jobs       map[string]*parser.JobConfig
+		wantMatrix bool
+	}{
+		{
+			name: "jobs differing in variables",
+			jobs: map[string]*parser.JobConfig{
+				"test:py311": {Script: []string{"pytest"}, Variables: map[string]interface{}{"PYTHON": "3.11"}},
+				"test:py312": {Script: []string{"pytest"}, Variables: map[string]interface{}{"PYTHON": "3.12"}},
+			},
+			wantMatrix: true,
+		},
+		{
+			name: "jobs differing in image",
+			jobs: map[string]*parser.JobConfig{
+				"test:node18": {Script: []string{"npm test"}, Image: &parser.Image{Name: "node:18"}},
+				"test:node20": {Script: []string{"npm test"}, Image: &parser.Image{Name: "node:20"}},
+			},
+			wantMatrix: true,
+		},
+		{
+			name: "trigger jobs with empty scripts",
+			jobs: map[string]*parser.JobConfig{
+				"deploy:frontend": {Stage: "deploy", Trigger: "group/frontend"},
+				"deploy:backend":  {Stage: "deploy", Trigger: "group/backend"},
+			},
+		},
+		{
+			name: "jobs with blank scripts",
+			jobs: map[string]*parser.JobConfig{
+				"noop-a": {Script: []string{" "}, Variables: map[string]interface{}{"A": "1"}},
+				"noop-b": {Script: []string{""}, Variables: map[string]interface{}{"A": "2"}},
+			},
+		},
+		{
+			name: "identical jobs",
+			jobs: map[string]*parser.JobConfig{
+				"lint":       {Script: []string{"make lint"}},
+				"lint-again": {Script: []string{"make lint"}},
+			},
+		},
+		{
+			name: "jobs already using parallel",
+			jobs: map[string]*parser.JobConfig{
+				"test:a": {Script: []string{"make test"}, Parallel: &parser.Parallel{Count: 2}, Variables: map[string]interface{}{"SUITE": "a"}},
+				"test:b": {Script: []string{"make test"}, Parallel: &parser.Parallel{Count: 2}, Variables: map[string]interface{}{"SUITE": "b"}},
+			},
+		},
+		{
+			name: "jobs built from templates",
+			jobs: map[string]*parser.JobConfig{
+				".base":  {Script: []string{"make test"}},
+				"test:a": {Extends: ".base", Variables: map[string]interface{}{"SUITE": "a"}},
+				"test:b": {Extends: ".base", Variables: map[string]interface{}{"SUITE": "b"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &parser.GitLabConfig{Jobs: tt.jobs}
+			result := Compare(&parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{}}, config)
+
+			gotMatrix := false
+			for _, improvement := range result.Improvements {
+				if contains(improvement.Description, "could be optimized using matrix strategy") {
+					gotMatrix = true
+				}
+			}
+			if gotMatrix != tt.wantMatrix {
+				t.Errorf("Expected matrix suggestion %v, got %v: %+v", tt.wantMatrix, gotMatrix, result.Improvements)
+			}
+		})
+	}
+}
+
+func TestDefaultConsolidationOfJobKeywords(t *testing.T) {
+	interruptible := true
+	tests := []struct {
+		name string
+		set  func(job *parser.JobConfig)
+	}{
+		{"tags", func(job *parser.JobConfig) { job.Tags = []string{"docker"} }},
+		{"retry", func(job *parser.JobConfig) { job.Retry = &parser.Retry{Max: 2} }},
+		{"timeout", func(job *parser.JobConfig) { job.Timeout = "30m" }},
+		{"interruptible", func(job *parser.JobConfig) { job.Interruptible = &interruptible }},
+		{"after_script", func(job *parser.JobConfig) { job.AfterScript = []string{"./cleanup.sh"} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldConfig := &parser.GitLabConfig{Jobs: map[string]*parser.JobConfig{}}
+			newConfig := &parser.GitLabConfig{Default: &parser.JobConfig{}, Jobs: map[string]*parser.JobConfig{}}
+			tt.set(newConfig.Default)
+			for _, jobName := range []string{"build", "test"} {
+				oldJob := &parser.JobConfig{Script: []string{"make " + jobName}}
+				tt.set(oldJob)
+				oldConfig.Jobs[jobName] = oldJob
+				newConfig.Jobs[jobName] = &parser.JobConfig{Script: []string{"make " + jobName}}
+			}
+
+			result := Compare(oldConfig, newConfig)
+
+			consolidated := false
+			for _, improvement := range result.Improvements {
+				if improvement.Path == "default" {
+					consolidated = true
+				}
+			}
+			if !consolidated {
+				t.Errorf("Expected moving %s to default to count as consolidation, got %+v", tt.name, result.Improvements)
+			}
+			for _, diffs := range [][]ConfigDiff{result.Semantic, result.Performance} {
+				for _, diff := range diffs {
+					if diff.Path != "default" {
+						t.Errorf("Expected jobs to be unchanged once they inherit %s, got %s: %s", tt.name, diff.Path, diff.Description)
+					}
+				}
+			}
+
+			// Jobs opting out of the default no longer run with the keyword
+			for _, job := range newConfig.Jobs {
+				job.Inherit = &parser.Inherit{Default: false}
+			}
+			result = Compare(oldConfig, newConfig)
+			changed := false
+			for _, diff := range append(result.Semantic, result.Performance...) {
+				if diff.Path == "jobs.build."+tt.name {
+					changed = true
+				}
+			}
+			if !changed {
+				t.Errorf("Expected a %s change for jobs that don't inherit the default", tt.name)
+			}
+		})
+	}
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && (s[:len(substr)] == substr || s[len(s)-len(substr):] == substr || containsSubstring(s, substr)))
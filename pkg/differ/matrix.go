@@ -0,0 +1,182 @@
+package differ
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// matrixExpansion records that a parallel:matrix job of the new configuration
+// generates jobs equivalent to concrete jobs of the old one
+type matrixExpansion struct {
+	jobName string
+	oldJobs []string
+}
+
+// matchMatrixExpansions finds the matrix jobs new to newConfig whose
+// generated jobs each line up with a different job removed from oldConfig.
+// A generated job lines up when, with the matrix variables filled in, it runs
+// the same scripts and image with the same settings as the removed job.
+func matchMatrixExpansions(oldConfig, newConfig *parser.GitLabConfig) []matrixExpansion {
+	var removed []string
+	for jobName := range oldConfig.Jobs {
+		if _, exists := newConfig.Jobs[jobName]; !exists && !parser.IsTemplateJob(jobName) {
+			removed = append(removed, jobName)
+		}
+	}
+	sort.Strings(removed)
+
+	var added []string
+	for jobName := range newConfig.Jobs {
+		if _, exists := oldConfig.Jobs[jobName]; !exists && !parser.IsTemplateJob(jobName) {
+			added = append(added, jobName)
+		}
+	}
+	sort.Strings(added)
+
+	claimed := make(map[string]bool)
+	var expansions []matrixExpansion
+	for _, jobName := range added {
+		job := newConfig.Jobs[jobName]
+		instances := job.ExpandMatrix(jobName)
+		if len(instances) == 0 {
+			continue
+		}
+
+		effective := effectiveJob(newConfig, jobName, job)
+		var matched []string
+		taken := make(map[string]bool)
+		for _, instance := range instances {
+			want, wantVars := normalizedMatrixJob(effective, instance.Variables)
+			for _, oldName := range removed {
+				if claimed[oldName] || taken[oldName] {
+					continue
+				}
+				oldJob := oldConfig.Jobs[oldName]
+				got, gotVars := normalizedMatrixJob(effectiveJob(oldConfig, oldName, oldJob), nil)
+				if sameJobConfig(want, got) && matrixVariablesMatch(gotVars, wantVars, instance.Variables) {
+					matched = append(matched, oldName)
+					taken[oldName] = true
+					break
+				}
+			}
+		}
+
+		// Only a complete match replaces the add and remove diffs
+		if len(matched) != len(instances) {
+			continue
+		}
+		for _, oldName := range matched {
+			claimed[oldName] = true
+		}
+		sort.Strings(matched)
+		expansions = append(expansions, matrixExpansion{jobName: jobName, oldJobs: matched})
+	}
+	return expansions
+}
+
+// recordMatrixExpansions adds an improvement tagged matrix for each
+// expansion and returns the names of the jobs involved, which are left out of
+// the job-by-job comparison
+func recordMatrixExpansions(expansions []matrixExpansion, result *DiffResult) map[string]bool {
+	matrixJobs := make(map[string]bool)
+	for _, expansion := range expansions {
+		matrixJobs[expansion.jobName] = true
+		for _, oldName := range expansion.oldJobs {
+			matrixJobs[oldName] = true
+		}
+		result.Improvements = append(result.Improvements, ConfigDiff{
+			Type:        DiffTypeModified,
+			Path:        "jobs." + expansion.jobName + ".parallel.matrix",
+			Description: fmt.Sprintf("Jobs %s consolidated into matrix job '%s'", strings.Join(expansion.oldJobs, ", "), expansion.jobName),
+			OldValue:    expansion.oldJobs,
+			NewValue:    expansion.jobName,
+			Behavioral:  false,
+		})
+	}
+	if len(expansions) > 0 {
+		result.ImprovementTags = append(result.ImprovementTags, "matrix")
+	}
+	return matrixJobs
+}
+
+// normalizedMatrixJob returns a copy of the job with its own and the matrix
+// variables filled into its scripts and image, and the keywords that only
+// describe how the job is generated cleared, along with those variables
+func normalizedMatrixJob(job *parser.JobConfig, matrixVars map[string]string) (*parser.JobConfig, map[string]string) {
+	variables := make(map[string]string, len(job.Variables)+len(matrixVars))
+	for name, value := range job.Variables {
+		variables[name] = fmt.Sprint(value)
+	}
+	for name, value := range matrixVars {
+		variables[name] = value
+	}
+	expand := variableReplacer(variables)
+
+	normalized := *job
+	normalized.Script = expandLines(job.Script, expand)
+	normalized.BeforeScript = expandLines(job.BeforeScript, expand)
+	normalized.AfterScript = expandLines(job.AfterScript, expand)
+	if job.Image != nil {
+		image := *job.Image
+		image.Name = expand.Replace(image.Name)
+		normalized.Image = &image
+	}
+	normalized.Variables = nil
+	normalized.Parallel = nil
+	normalized.Extends = nil
+	normalized.Inherit = nil
+	return &normalized, variables
+}
+
+// matrixVariablesMatch reports whether a removed job's variables are the
+// generated job's, allowing the generated job the extra matrix variables
+func matrixVariablesMatch(oldVars, newVars, matrixVars map[string]string) bool {
+	for name, value := range oldVars {
+		if newVars[name] != value {
+			return false
+		}
+	}
+	for name := range newVars {
+		if _, exists := oldVars[name]; !exists {
+			if _, isMatrix := matrixVars[name]; !isMatrix {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// variableReplacer expands $NAME and ${NAME} references to the variables,
+// trying longer names first so $NODE doesn't match inside $NODE_VERSION
+func variableReplacer(variables map[string]string) *strings.Replacer {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	pairs := make([]string, 0, len(names)*4)
+	for _, name := range names {
+		pairs = append(pairs, "${"+name+"}", variables[name], "$"+name, variables[name])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func expandLines(lines []string, expand *strings.Replacer) []string {
+	if lines == nil {
+		return nil
+	}
+	expanded := make([]string, len(lines))
+	for i, line := range lines {
+		expanded[i] = strings.TrimSpace(expand.Replace(line))
+	}
+	return expanded
+}
@@ -0,0 +1,78 @@
+package differ
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// jobRename records that a removed job lives on under a new name
+type jobRename struct {
+	oldJob string
+	newJob string
+}
+
+// matchRenames pairs removed and added jobs, other than those in skip, that
+// share a fingerprint, so that they do the same work resolved through
+// extends and default. When several jobs share one, they're paired in name
+// order.
+func matchRenames(oldConfig, newConfig *parser.GitLabConfig, skip map[string]bool) []jobRename {
+	added := make(map[string][]string)
+	for _, jobName := range newConfig.ConcreteJobNames() {
+		if _, exists := oldConfig.Jobs[jobName]; exists || skip[jobName] {
+			continue
+		}
+		fingerprint := newConfig.JobFingerprint(jobName)
+		added[fingerprint] = append(added[fingerprint], jobName)
+	}
+
+	var renames []jobRename
+	for _, jobName := range oldConfig.ConcreteJobNames() {
+		if _, exists := newConfig.Jobs[jobName]; exists || skip[jobName] {
+			continue
+		}
+		fingerprint := oldConfig.JobFingerprint(jobName)
+		if candidates := added[fingerprint]; len(candidates) > 0 {
+			renames = append(renames, jobRename{oldJob: jobName, newJob: candidates[0]})
+			added[fingerprint] = candidates[1:]
+		}
+	}
+	sort.Slice(renames, func(i, j int) bool { return renames[i].newJob < renames[j].newJob })
+	return renames
+}
+
+// recordRenames adds a renamed semantic change for each match and marks both
+// jobs in skip, which leaves them out of the job-by-job comparison. Settings
+// outside the fingerprint that differ are reported as behavioral changes of
+// the new job, and make the rename behavioral too.
+func recordRenames(oldConfig, newConfig *parser.GitLabConfig, renames []jobRename, result *DiffResult, skip map[string]bool) {
+	for _, rename := range renames {
+		skip[rename.oldJob] = true
+		skip[rename.newJob] = true
+
+		oldJob := resolvedJob(oldConfig, rename.oldJob, oldConfig.Jobs[rename.oldJob])
+		newJob := resolvedJob(newConfig, rename.newJob, newConfig.Jobs[rename.newJob])
+		var changes []ConfigDiff
+		for _, keyword := range changedKeywords(oldJob, newJob) {
+			changes = append(changes, ConfigDiff{
+				Type:        DiffTypeModified,
+				Path:        "jobs." + rename.newJob + "." + keyword,
+				Description: fmt.Sprintf("%s of '%s' differs from '%s', which it was renamed from", keyword, rename.newJob, rename.oldJob),
+				OldValue:    keywordValue(oldJob, keyword),
+				NewValue:    keywordValue(newJob, keyword),
+				Behavioral:  true,
+			})
+		}
+
+		result.Semantic = append(result.Semantic, ConfigDiff{
+			Type:        DiffTypeRenamed,
+			Path:        "jobs." + rename.newJob,
+			Description: fmt.Sprintf("Job '%s' renamed to '%s'", rename.oldJob, rename.newJob),
+			OldValue:    rename.oldJob,
+			NewValue:    rename.newJob,
+			Behavioral:  len(changes) > 0,
+		})
+		result.Semantic = append(result.Semantic, changes...)
+	}
+}
@@ -0,0 +1,118 @@
+package differ
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// rulesBehaviorChanged reports whether a job's run decision differs between
+// the two configs in some representative pipeline context, taking each
+// config's workflow:rules into account. Reordered or rewritten rules with
+// identical outcomes are not behavioral changes. When either side can't be
+// evaluated, any structural difference counts as behavioral.
+func rulesBehaviorChanged(oldConfig, newConfig *parser.GitLabConfig, oldJob, newJob *parser.JobConfig) bool {
+	if semanticEqual(oldJob.Rules, newJob.Rules) {
+		return false
+	}
+
+	for _, ctx := range parser.RuleProbeContexts(oldJob.Rules, newJob.Rules) {
+		oldOutcome, err := jobRunOutcome(oldConfig, oldJob, ctx)
+		if err != nil {
+			return true
+		}
+		newOutcome, err := jobRunOutcome(newConfig, newJob, ctx)
+		if err != nil {
+			return true
+		}
+		if !semanticEqual(oldOutcome, newOutcome) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// workflowRulesBehaviorChanged reports whether the two configs' workflow
+// rules create pipelines, or inject variables, differently in some
+// representative context. It also returns the jobs present in both configs
+// whose run decision changes although their own rules didn't, so a workflow
+// change can be told apart from job rule changes.
+func workflowRulesBehaviorChanged(oldConfig, newConfig *parser.GitLabConfig) (bool, []string) {
+	var oldRules, newRules []parser.Rule
+	if oldConfig.Workflow != nil {
+		oldRules = oldConfig.Workflow.Rules
+	}
+	if newConfig.Workflow != nil {
+		newRules = newConfig.Workflow.Rules
+	}
+
+	contexts := parser.RuleProbeContexts(oldRules, newRules)
+	behavioral := false
+	for _, ctx := range contexts {
+		oldCreated, oldVars := oldConfig.EvaluateWorkflow(ctx)
+		newCreated, newVars := newConfig.EvaluateWorkflow(ctx)
+		if oldCreated != newCreated || (oldCreated && !semanticEqual(oldVars, newVars)) {
+			behavioral = true
+			break
+		}
+	}
+	if !behavioral {
+		return false, nil
+	}
+
+	var affected []string
+	for jobName, oldJob := range oldConfig.Jobs {
+		newJob, exists := newConfig.Jobs[jobName]
+		if !exists || oldJob == nil || newJob == nil || parser.IsTemplateJob(jobName) {
+			continue
+		}
+		if !semanticEqual(oldJob.Rules, newJob.Rules) {
+			continue
+		}
+		for _, ctx := range contexts {
+			oldOutcome, oldErr := jobRunOutcome(oldConfig, oldJob, ctx)
+			newOutcome, newErr := jobRunOutcome(newConfig, newJob, ctx)
+			if oldErr == nil && newErr == nil && !semanticEqual(oldOutcome, newOutcome) {
+				affected = append(affected, jobName)
+				break
+			}
+		}
+	}
+	sort.Strings(affected)
+
+	return true, affected
+}
+
+// jobRunOutcome evaluates whether and how a job runs in the given context
+func jobRunOutcome(config *parser.GitLabConfig, job *parser.JobConfig, ctx *parser.PipelineContext) (parser.RuleOutcome, error) {
+	created, workflowVars := config.EvaluateWorkflow(ctx)
+	if !created {
+		return parser.RuleOutcome{}, nil
+	}
+
+	var outcome parser.RuleOutcome
+	if len(job.Rules) > 0 {
+		var err error
+		outcome, err = parser.EvaluateRules(job.Rules, config.RuleVariables(ctx, workflowVars, job))
+		if err != nil {
+			return parser.RuleOutcome{}, err
+		}
+	} else {
+		if job.Only != nil || job.Except != nil {
+			return parser.RuleOutcome{}, fmt.Errorf("only/except cannot be compared with rules")
+		}
+		if job.When == "never" {
+			return parser.RuleOutcome{}, nil
+		}
+		outcome = parser.RuleOutcome{Runs: true, When: job.When}
+	}
+
+	// The simulation assumes earlier jobs succeed, so always and on_success
+	// make the same decision
+	if outcome.Runs && (outcome.When == "" || outcome.When == "always") {
+		outcome.When = "on_success"
+	}
+	return outcome, nil
+}
@@ -0,0 +1,262 @@
+package differ
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// jobSplit records that a removed job's script was divided among several
+// added jobs (split), or that several removed jobs' scripts were combined
+// into one added job (merge)
+type jobSplit struct {
+	kind    string
+	oldJobs []string
+	newJobs []string
+}
+
+// matchSplitsAndMerges lines up removed and added jobs, other than those in
+// skip, whose scripts were split or merged. A removed job is split when at
+// least two added jobs only run lines from its script and together run all
+// of them; a merge is the same the other way round. Lines are compared
+// trimmed, taking scripts from the templates jobs extend where unset.
+func matchSplitsAndMerges(oldConfig, newConfig *parser.GitLabConfig, skip map[string]bool) []jobSplit {
+	removed := changedJobScripts(oldConfig, newConfig, skip)
+	added := changedJobScripts(newConfig, oldConfig, skip)
+
+	claimed := make(map[string]bool)
+	var splits []jobSplit
+	match := func(kind string, wholes, parts map[string]map[string]bool) {
+		for _, whole := range sortedKeys(wholes) {
+			if claimed[whole] {
+				continue
+			}
+			var matched []string
+			covered := make(map[string]bool)
+			for _, part := range sortedKeys(parts) {
+				// A part with the whole script is a renamed job, not a piece
+				if claimed[part] || len(parts[part]) == len(wholes[whole]) || !lineSubset(parts[part], wholes[whole]) {
+					continue
+				}
+				matched = append(matched, part)
+				for line := range parts[part] {
+					covered[line] = true
+				}
+			}
+			if len(matched) < 2 || len(covered) != len(wholes[whole]) {
+				continue
+			}
+
+			claimed[whole] = true
+			for _, part := range matched {
+				claimed[part] = true
+			}
+			split := jobSplit{kind: kind, oldJobs: []string{whole}, newJobs: matched}
+			if kind == "merge" {
+				split.oldJobs, split.newJobs = matched, []string{whole}
+			}
+			splits = append(splits, split)
+		}
+	}
+	match("split", removed, added)
+	match("merge", added, removed)
+	return splits
+}
+
+// changedJobScripts returns the script lines of the jobs in config that
+// other doesn't have, leaving out templates, jobs in skip and jobs without a
+// script
+func changedJobScripts(config, other *parser.GitLabConfig, skip map[string]bool) map[string]map[string]bool {
+	scripts := make(map[string]map[string]bool)
+	for jobName, job := range config.ConcreteJobs() {
+		if _, exists := other.Jobs[jobName]; exists || skip[jobName] {
+			continue
+		}
+		lines := make(map[string]bool)
+		for _, line := range jobScript(config, jobName, job) {
+			if line = strings.TrimSpace(line); line != "" {
+				lines[line] = true
+			}
+		}
+		if len(lines) > 0 {
+			scripts[jobName] = lines
+		}
+	}
+	return scripts
+}
+
+// jobScript returns the job's script or, when unset, that of the nearest
+// template it extends
+func jobScript(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) []string {
+	if job.Script != nil {
+		return job.Script
+	}
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil && template.Script != nil {
+			return template.Script
+		}
+	}
+	return nil
+}
+
+// recordSplitsAndMerges adds an improvement tagged split or merge for each
+// match and marks the jobs involved in skip, which leaves them out of the
+// job-by-job comparison. Settings other than the script that differ between
+// the old and the new jobs are reported as behavioral semantic changes, and
+// make the improvement behavioral too.
+func recordSplitsAndMerges(oldConfig, newConfig *parser.GitLabConfig, splits []jobSplit, result *DiffResult, skip map[string]bool) {
+	tagged := make(map[string]bool)
+	for _, split := range splits {
+		for _, jobName := range append(append([]string{}, split.oldJobs...), split.newJobs...) {
+			skip[jobName] = true
+		}
+
+		changes := splitSettingChanges(oldConfig, newConfig, split)
+		result.Semantic = append(result.Semantic, changes...)
+		diff := ConfigDiff{
+			Type:       DiffTypeModified,
+			OldValue:   split.oldJobs,
+			NewValue:   split.newJobs,
+			Behavioral: len(changes) > 0,
+		}
+		if split.kind == "split" {
+			diff.Path = "jobs." + split.oldJobs[0]
+			diff.Description = fmt.Sprintf("Job '%s' split into %s", split.oldJobs[0], quoteJobNames(split.newJobs))
+		} else {
+			diff.Path = "jobs." + split.newJobs[0]
+			diff.Description = fmt.Sprintf("Jobs %s merged into '%s'", quoteJobNames(split.oldJobs), split.newJobs[0])
+		}
+		result.Improvements = append(result.Improvements, diff)
+
+		if !tagged[split.kind] {
+			tagged[split.kind] = true
+			result.ImprovementTags = append(result.ImprovementTags, split.kind)
+		}
+	}
+}
+
+// splitSettingChanges compares the settings of each old job of a split or
+// merge with each new one, resolved through extends and default, and returns
+// a diff for every keyword other than the script that differs
+func splitSettingChanges(oldConfig, newConfig *parser.GitLabConfig, split jobSplit) []ConfigDiff {
+	verb := "split"
+	if split.kind == "merge" {
+		verb = "merged"
+	}
+
+	var changes []ConfigDiff
+	for _, oldName := range split.oldJobs {
+		oldJob := resolvedJob(oldConfig, oldName, oldConfig.Jobs[oldName])
+		for _, newName := range split.newJobs {
+			newJob := resolvedJob(newConfig, newName, newConfig.Jobs[newName])
+			for _, keyword := range changedKeywords(oldJob, newJob) {
+				changes = append(changes, ConfigDiff{
+					Type:        DiffTypeModified,
+					Path:        "jobs." + newName + "." + keyword,
+					Description: fmt.Sprintf("%s of '%s' differs from '%s', which it was %s from", keyword, newName, oldName, verb),
+					OldValue:    keywordValue(oldJob, keyword),
+					NewValue:    keywordValue(newJob, keyword),
+					Behavioral:  true,
+				})
+			}
+		}
+	}
+	return changes
+}
+
+// resolvedJob returns the job with every keyword it doesn't set taken from
+// the templates it extends, nearest first, and from the default block as in
+// effectiveJob. Variables are merged, the job's own winning.
+func resolvedJob(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) *parser.JobConfig {
+	resolved := effectiveJob(config, jobName, job)
+	target := reflect.ValueOf(resolved).Elem()
+	variables := make(map[string]interface{})
+	for name, value := range job.Variables {
+		variables[name] = value
+	}
+
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		template := config.Jobs[chain[i]]
+		if template == nil {
+			continue
+		}
+		source := reflect.ValueOf(template).Elem()
+		for field := 0; field < target.NumField(); field++ {
+			if target.Field(field).IsZero() {
+				target.Field(field).Set(source.Field(field))
+			}
+		}
+		for name, value := range template.Variables {
+			if _, set := variables[name]; !set {
+				variables[name] = value
+			}
+		}
+	}
+	resolved.Variables = variables
+	resolved.Extends = nil
+	return resolved
+}
+
+// changedKeywords lists the keywords, by their YAML name, whose values differ
+// between two resolved jobs, leaving out the script and extends
+func changedKeywords(a, b *parser.JobConfig) []string {
+	var keywords []string
+	aValue, bValue := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for field := 0; field < aValue.NumField(); field++ {
+		keyword := yamlKeyword(aValue.Type().Field(field))
+		if keyword == "script" || keyword == "extends" {
+			continue
+		}
+		if !semanticEqual(aValue.Field(field).Interface(), bValue.Field(field).Interface()) {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+// keywordValue returns the value of a keyword of a job, by its YAML name
+func keywordValue(job *parser.JobConfig, keyword string) interface{} {
+	value := reflect.ValueOf(job).Elem()
+	for field := 0; field < value.NumField(); field++ {
+		if yamlKeyword(value.Type().Field(field)) == keyword {
+			return value.Field(field).Interface()
+		}
+	}
+	return nil
+}
+
+func yamlKeyword(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("yaml"), ",")[0]
+}
+
+// lineSubset reports whether every line of a is in b
+func lineSubset(a, b map[string]bool) bool {
+	for line := range a {
+		if !b[line] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func quoteJobNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "'" + name + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
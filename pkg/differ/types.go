@@ -26,4 +26,62 @@ type DiffResult struct {
 	HasChanges      bool         `json:"has_changes"`
 	Summary         string       `json:"summary"`
 	ImprovementTags []string     `json:"improvement_tags"` // Tags like "duplication", "consolidation", "templates"
+	IgnoredDiffs    int          `json:"ignored_diffs,omitempty"`
+}
+
+// DifferOptions controls which refactoring improvement patterns Compare
+// detects and how much evidence each needs. Use DefaultDifferOptions as a
+// starting point; thresholds left at zero take their default values.
+type DifferOptions struct {
+	DetectDefaultConsolidation   bool
+	DetectTemplateExtraction     bool
+	DetectVariableOptimization   bool
+	DetectDependencyOptimization bool
+	DetectCacheOptimization      bool
+	DetectMatrixPatterns         bool
+	DetectDuplicationRemoval     bool
+
+	// MinConsolidationJobs is how many jobs must lose configuration to the
+	// default block before it counts as consolidation
+	MinConsolidationJobs int
+	// MinVariablePromotionJobs is how many jobs must have shared a variable
+	// before moving it to the global scope counts as a promotion
+	MinVariablePromotionJobs int
+	// MinMatrixJobs is how many similar jobs it takes to suggest a matrix
+	MinMatrixJobs int
+
+	// IgnorePaths are glob patterns, such as "variables.*", matched against
+	// ConfigDiff.Path. Matching diffs are dropped from every category and
+	// only counted in DiffResult.IgnoredDiffs.
+	IgnorePaths []string
+}
+
+// DefaultDifferOptions enables every detector with its default thresholds
+func DefaultDifferOptions() DifferOptions {
+	return DifferOptions{
+		DetectDefaultConsolidation:   true,
+		DetectTemplateExtraction:     true,
+		DetectVariableOptimization:   true,
+		DetectDependencyOptimization: true,
+		DetectCacheOptimization:      true,
+		DetectMatrixPatterns:         true,
+		DetectDuplicationRemoval:     true,
+		MinConsolidationJobs:         2,
+		MinVariablePromotionJobs:     2,
+		MinMatrixJobs:                2,
+	}
+}
+
+func (o DifferOptions) withDefaults() DifferOptions {
+	defaults := DefaultDifferOptions()
+	if o.MinConsolidationJobs <= 0 {
+		o.MinConsolidationJobs = defaults.MinConsolidationJobs
+	}
+	if o.MinVariablePromotionJobs <= 0 {
+		o.MinVariablePromotionJobs = defaults.MinVariablePromotionJobs
+	}
+	if o.MinMatrixJobs <= 0 {
+		o.MinMatrixJobs = defaults.MinMatrixJobs
+	}
+	return o
 }
@@ -0,0 +1,291 @@
+package differ
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// unifiedContextLines is the number of unchanged lines shown around each change
+const unifiedContextLines = 3
+
+// ToUnifiedDiff renders the before/after YAML of every changed job or
+// top-level section as a unified diff hunk, grouped by path
+func (d *DiffResult) ToUnifiedDiff(oldConfig, newConfig *parser.GitLabConfig) string {
+	sections := make(map[string]bool)
+	for _, group := range [][]ConfigDiff{d.Semantic, d.Dependencies, d.Performance} {
+		for _, diff := range group {
+			if section := diffSection(diff.Path, oldConfig, newConfig); section != "" {
+				sections[section] = true
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(sections))
+	for section := range sections {
+		paths = append(paths, section)
+	}
+	sort.Strings(paths)
+
+	var buf strings.Builder
+	for _, path := range paths {
+		oldLines := sectionYAMLLines(path, oldConfig)
+		newLines := sectionYAMLLines(path, newConfig)
+
+		hunks := unifiedHunks(oldLines, newLines)
+		if len(hunks) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&buf, "--- a/%s\n", path)
+		fmt.Fprintf(&buf, "+++ b/%s\n", path)
+		for _, hunk := range hunks {
+			buf.WriteString(hunk)
+		}
+	}
+
+	return buf.String()
+}
+
+// UnifiedFileDiff renders the line changes between two versions of a file
+// as a unified diff, or "" when they're the same
+func UnifiedFileDiff(fileName string, oldData, newData []byte) string {
+	hunks := unifiedHunks(splitLines(oldData), splitLines(newData))
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- a/%s\n", fileName)
+	fmt.Fprintf(&buf, "+++ b/%s\n", fileName)
+	for _, hunk := range hunks {
+		buf.WriteString(hunk)
+	}
+	return buf.String()
+}
+
+func splitLines(data []byte) []string {
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffSection maps a diff path to the job or top-level section it belongs to
+func diffSection(path string, oldConfig, newConfig *parser.GitLabConfig) string {
+	for _, prefix := range []string{"jobs.", "dependency_graph."} {
+		if strings.HasPrefix(path, prefix) {
+			if jobName := matchJobName(strings.TrimPrefix(path, prefix), oldConfig, newConfig); jobName != "" {
+				return "jobs." + jobName
+			}
+			return ""
+		}
+	}
+
+	switch {
+	case path == "stages", path == "include", path == "default":
+		return path
+	case path == "variables" || strings.HasPrefix(path, "variables."):
+		return "variables"
+	}
+
+	return ""
+}
+
+// matchJobName finds the longest job name that prefixes the remaining path,
+// since job names may themselves contain dots
+func matchJobName(remainder string, oldConfig, newConfig *parser.GitLabConfig) string {
+	best := ""
+	for _, config := range []*parser.GitLabConfig{oldConfig, newConfig} {
+		if config == nil {
+			continue
+		}
+		for jobName := range config.Jobs {
+			if remainder == jobName || strings.HasPrefix(remainder, jobName+".") {
+				if len(jobName) > len(best) {
+					best = jobName
+				}
+			}
+		}
+	}
+	return best
+}
+
+// sectionYAMLLines marshals a section of the config to stable YAML lines
+func sectionYAMLLines(path string, config *parser.GitLabConfig) []string {
+	if config == nil {
+		return nil
+	}
+
+	var key string
+	var value interface{}
+
+	switch {
+	case strings.HasPrefix(path, "jobs."):
+		key = strings.TrimPrefix(path, "jobs.")
+		job, exists := config.Jobs[key]
+		if !exists || job == nil {
+			return nil
+		}
+		value = job
+	case path == "stages":
+		if len(config.Stages) == 0 {
+			return nil
+		}
+		key, value = path, config.Stages
+	case path == "variables":
+		if len(config.Variables) == 0 {
+			return nil
+		}
+		key, value = path, config.Variables
+	case path == "include":
+		if len(config.Include) == 0 {
+			return nil
+		}
+		key, value = path, config.Include
+	case path == "default":
+		if config.Default == nil {
+			return nil
+		}
+		key, value = path, config.Default
+	default:
+		return nil
+	}
+
+	// yaml.v3 sorts map keys, which keeps the output deterministic
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string]interface{}{key: value}); err != nil {
+		return []string{fmt.Sprintf("# failed to marshal %s: %v", path, err)}
+	}
+	encoder.Close()
+
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// diffOp is a single line-level edit: ' ' keep, '-' remove, '+' add
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// lineDiff computes a minimal line edit script using longest common subsequence
+func lineDiff(oldLines, newLines []string) []diffOp {
+	n, m := len(oldLines), len(newLines)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case oldLines[i] == newLines[j]:
+			ops = append(ops, diffOp{' ', oldLines[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', oldLines[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', newLines[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', oldLines[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', newLines[j]})
+	}
+
+	return ops
+}
+
+// unifiedHunks groups an edit script into @@ hunks with surrounding context
+func unifiedHunks(oldLines, newLines []string) []string {
+	ops := lineDiff(oldLines, newLines)
+
+	var hunks []string
+	for idx := 0; idx < len(ops); idx++ {
+		if ops[idx].kind == ' ' {
+			continue
+		}
+
+		// Extend the hunk while changes are within 2*context of each other
+		start, end := idx, idx
+		for next := idx + 1; next < len(ops) && next <= end+2*unifiedContextLines; next++ {
+			if ops[next].kind != ' ' {
+				end = next
+			}
+		}
+
+		hunks = append(hunks, formatHunk(ops, start, end))
+		idx = end
+	}
+
+	return hunks
+}
+
+func formatHunk(ops []diffOp, firstChange, lastChange int) string {
+	from := firstChange - unifiedContextLines
+	if from < 0 {
+		from = 0
+	}
+	to := lastChange + unifiedContextLines
+	if to >= len(ops) {
+		to = len(ops) - 1
+	}
+
+	// Line numbers of the hunk start in the old and new files (1-based)
+	oldStart, newStart := 1, 1
+	for _, op := range ops[:from] {
+		if op.kind != '+' {
+			oldStart++
+		}
+		if op.kind != '-' {
+			newStart++
+		}
+	}
+
+	var body strings.Builder
+	oldCount, newCount := 0, 0
+	for _, op := range ops[from : to+1] {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+		body.WriteByte(op.kind)
+		body.WriteString(op.line)
+		body.WriteByte('\n')
+	}
+
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@\n%s", oldStart, oldCount, newStart, newCount, body.String())
+}
@@ -0,0 +1,114 @@
+package differ
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func TestToUnifiedDiff(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Stages: []string{"build", "test"},
+		Variables: map[string]interface{}{
+			"NODE_VERSION": "16",
+		},
+		Jobs: map[string]*parser.JobConfig{
+			"build": {
+				Stage:  "build",
+				Script: []string{"npm ci", "npm run build"},
+			},
+			"test": {
+				Stage:  "test",
+				Script: []string{"npm test"},
+			},
+			"lint": {
+				Stage:  "test",
+				Script: []string{"npm run lint"},
+			},
+		},
+	}
+
+	newConfig := &parser.GitLabConfig{
+		Stages: []string{"build", "test"},
+		Variables: map[string]interface{}{
+			"NODE_VERSION": "18",
+		},
+		Jobs: map[string]*parser.JobConfig{
+			"build": {
+				Stage:  "build",
+				Script: []string{"npm ci", "npm run build"},
+			},
+			"test": {
+				Stage:  "test",
+				Script: []string{"npm test -- --coverage"},
+			},
+		},
+	}
+
+	result := Compare(oldConfig, newConfig)
+	output := result.ToUnifiedDiff(oldConfig, newConfig)
+
+	expectedSections := []string{
+		"--- a/jobs.lint\n+++ b/jobs.lint\n@@ -1,4 +0,0 @@\n-lint:\n",
+		"--- a/jobs.test\n+++ b/jobs.test\n",
+		"-    - npm test\n+    - npm test -- --coverage\n",
+		"--- a/variables\n+++ b/variables\n",
+		"-  NODE_VERSION: \"16\"\n+  NODE_VERSION: \"18\"\n",
+	}
+	for _, expected := range expectedSections {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected unified diff to contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	if strings.Contains(output, "jobs.build") {
+		t.Errorf("Unchanged job should not appear in diff:\n%s", output)
+	}
+
+	// Sections are ordered by path
+	if strings.Index(output, "jobs.lint") > strings.Index(output, "jobs.test") ||
+		strings.Index(output, "jobs.test") > strings.Index(output, "a/variables") {
+		t.Errorf("Expected sections sorted by path, got:\n%s", output)
+	}
+
+	// Output must be deterministic across runs
+	for i := 0; i < 5; i++ {
+		if again := Compare(oldConfig, newConfig).ToUnifiedDiff(oldConfig, newConfig); again != output {
+			t.Fatalf("Unified diff output is not deterministic:\n%s\nvs\n%s", output, again)
+		}
+	}
+}
+
+func TestToUnifiedDiff_NoChanges(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"test": {Script: []string{"make test"}},
+		},
+	}
+
+	if output := Compare(config, config).ToUnifiedDiff(config, config); output != "" {
+		t.Errorf("Expected empty diff, got:\n%s", output)
+	}
+}
+
+func TestToUnifiedDiff_DottedJobNames(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			".node.template": {Image: &parser.Image{Name: "node:16"}},
+		},
+	}
+	newConfig := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			".node.template": {Image: &parser.Image{Name: "node:18"}},
+		},
+	}
+
+	output := Compare(oldConfig, newConfig).ToUnifiedDiff(oldConfig, newConfig)
+	if !strings.Contains(output, "--- a/jobs..node.template\n") {
+		t.Errorf("Expected section for dotted job name, got:\n%s", output)
+	}
+	if !strings.Contains(output, "-  image: node:16\n+  image: node:18\n") {
+		t.Errorf("Expected image change hunk, got:\n%s", output)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
 )
 
+// equalStringSlices reports whether two lists hold the same strings in any
+// order. Like semanticEqual, it treats a missing list as an empty one.
 func equalStringSlices(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -30,7 +32,133 @@ func equalStringSlices(a, b []string) bool {
 	return true
 }
 
+// semanticEqual is reflect.DeepEqual, except that nil and empty slices and
+// maps are equal at any depth: for almost every keyword, `key: []` means the
+// same as leaving the key out, so a formatting change mustn't show up as a
+// diff. Where they differ, as for needs and dependencies, callers compare
+// whether the list is written separately.
+func semanticEqual(a, b interface{}) bool {
+	return equalValues(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func equalValues(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equalValues(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		iter := a.MapRange()
+		for iter.Next() {
+			other := b.MapIndex(iter.Key())
+			if !other.IsValid() || !equalValues(iter.Value(), other) {
+				return false
+			}
+		}
+		return true
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return equalValues(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !equalValues(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a.Equal(b)
+	}
+}
+
+// sameJobConfig compares two jobs with semanticEqual, except that needs and
+// dependencies must also agree on whether they're written at all
+func sameJobConfig(a, b *parser.JobConfig) bool {
+	return (a.Needs == nil) == (b.Needs == nil) &&
+		(a.Dependencies == nil) == (b.Dependencies == nil) &&
+		semanticEqual(a, b)
+}
+
+// sortDiffs orders diffs by path, then type, for stable output
+func sortDiffs(diffs []ConfigDiff) {
+	sort.SliceStable(diffs, func(i, j int) bool {
+		if diffs[i].Path != diffs[j].Path {
+			return diffs[i].Path < diffs[j].Path
+		}
+		return diffs[i].Type < diffs[j].Type
+	})
+}
+
+// sortResult applies a stable ordering to every diff list and the improvement tags
+func sortResult(result *DiffResult) {
+	sortDiffs(result.Semantic)
+	sortDiffs(result.Dependencies)
+	sortDiffs(result.Performance)
+	sortDiffs(result.Improvements)
+	sort.Strings(result.ImprovementTags)
+}
+
+// ignoreDiffs drops the diffs whose path matches one of the patterns from
+// every category and returns how many were dropped
+func ignoreDiffs(result *DiffResult, patterns []string) int {
+	if len(patterns) == 0 {
+		return 0
+	}
+	ignored := 0
+	filter := func(diffs []ConfigDiff) []ConfigDiff {
+		kept := diffs[:0]
+		for _, diff := range diffs {
+			if matchesAnyPath(diff.Path, patterns) {
+				ignored++
+				continue
+			}
+			kept = append(kept, diff)
+		}
+		return kept
+	}
+	result.Semantic = filter(result.Semantic)
+	result.Dependencies = filter(result.Dependencies)
+	result.Performance = filter(result.Performance)
+	result.Improvements = filter(result.Improvements)
+	return ignored
+}
+
+func matchesAnyPath(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if parser.MatchPattern(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
 func generateSummary(result *DiffResult) string {
+	summary := summarizeChanges(result)
+	if result.IgnoredDiffs > 0 {
+		summary += fmt.Sprintf(" (%d ignored)", result.IgnoredDiffs)
+	}
+	return summary
+}
+
+func summarizeChanges(result *DiffResult) string {
 	if !result.HasChanges {
 		return "No semantic differences found"
 	}
@@ -97,7 +225,7 @@ func hasMatrixLikeVariables(job *parser.JobConfig) bool {
 // Helper function to check if config uses template extends
 func hasTemplateExtends(config *parser.GitLabConfig) bool {
 	for jobName, job := range config.Jobs {
-		if strings.HasPrefix(jobName, ".") || job.Extends != nil {
+		if parser.IsTemplateJob(jobName) || job.Extends != nil {
 			return true
 		}
 	}
@@ -111,10 +239,15 @@ func hasSignificantDefaultChanges(oldDefault, newDefault *parser.JobConfig) bool
 	}
 
 	// Check if significant fields were added/changed
-	return !reflect.DeepEqual(oldDefault.Image, newDefault.Image) ||
+	return !semanticEqual(oldDefault.Image, newDefault.Image) ||
 		!equalStringSlices(oldDefault.BeforeScript, newDefault.BeforeScript) ||
-		!reflect.DeepEqual(oldDefault.Variables, newDefault.Variables) ||
-		!reflect.DeepEqual(oldDefault.Cache, newDefault.Cache)
+		!semanticEqual(oldDefault.AfterScript, newDefault.AfterScript) ||
+		!semanticEqual(oldDefault.Variables, newDefault.Variables) ||
+		!semanticEqual(oldDefault.Cache, newDefault.Cache) ||
+		!equalStringSlices(oldDefault.Tags, newDefault.Tags) ||
+		!semanticEqual(oldDefault.Retry, newDefault.Retry) ||
+		oldDefault.Timeout != newDefault.Timeout ||
+		!semanticEqual(oldDefault.Interruptible, newDefault.Interruptible)
 }
 
 func hasFieldsMovedToDefault(oldJob, newJob *parser.JobConfig, defaultJob *parser.JobConfig) bool {
@@ -125,7 +258,11 @@ func hasFieldsMovedToDefault(oldJob, newJob *parser.JobConfig, defaultJob *parse
 		fieldsMovedCount++
 	}
 
-	if oldJob.Image != "" && newJob.Image == "" && defaultJob.Image != "" {
+	if len(oldJob.AfterScript) > 0 && len(newJob.AfterScript) == 0 && len(defaultJob.AfterScript) > 0 {
+		fieldsMovedCount++
+	}
+
+	if oldJob.Image != nil && newJob.Image == nil && defaultJob.Image != nil {
 		fieldsMovedCount++
 	}
 
@@ -133,5 +270,67 @@ func hasFieldsMovedToDefault(oldJob, newJob *parser.JobConfig, defaultJob *parse
 		fieldsMovedCount++
 	}
 
+	if len(oldJob.Tags) > 0 && len(newJob.Tags) == 0 && len(defaultJob.Tags) > 0 {
+		fieldsMovedCount++
+	}
+
+	if oldJob.Retry != nil && newJob.Retry == nil && defaultJob.Retry != nil {
+		fieldsMovedCount++
+	}
+
+	if oldJob.Timeout != "" && newJob.Timeout == "" && defaultJob.Timeout != "" {
+		fieldsMovedCount++
+	}
+
+	if oldJob.Interruptible != nil && newJob.Interruptible == nil && defaultJob.Interruptible != nil {
+		fieldsMovedCount++
+	}
+
 	return fieldsMovedCount >= 1
 }
+
+// effectiveJob returns the job as it runs, with the keywords it doesn't set
+// itself taken from the templates it extends, nearest first, and then from
+// the default block where inherited
+func effectiveJob(config *parser.GitLabConfig, jobName string, job *parser.JobConfig) *parser.JobConfig {
+	effective := *job
+	chain := config.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := config.Jobs[chain[i]]; template != nil {
+			fillUnsetKeywords(&effective, template, func(string) bool { return true })
+		}
+	}
+	if config.Default != nil {
+		fillUnsetKeywords(&effective, config.Default, job.InheritsDefault)
+	}
+	return &effective
+}
+
+// fillUnsetKeywords copies the keywords a default block can set from src to
+// job where job leaves them unset and inherits allows it
+func fillUnsetKeywords(job, src *parser.JobConfig, inherits func(keyword string) bool) {
+	if job.Image == nil && inherits("image") {
+		job.Image = src.Image
+	}
+	if job.BeforeScript == nil && inherits("before_script") {
+		job.BeforeScript = src.BeforeScript
+	}
+	if job.AfterScript == nil && inherits("after_script") {
+		job.AfterScript = src.AfterScript
+	}
+	if job.Cache == nil && inherits("cache") {
+		job.Cache = src.Cache
+	}
+	if job.Tags == nil && inherits("tags") {
+		job.Tags = src.Tags
+	}
+	if job.Retry == nil && inherits("retry") {
+		job.Retry = src.Retry
+	}
+	if job.Timeout == "" && inherits("timeout") {
+		job.Timeout = src.Timeout
+	}
+	if job.Interruptible == nil && inherits("interruptible") {
+		job.Interruptible = src.Interruptible
+	}
+}
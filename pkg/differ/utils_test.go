@@ -2,6 +2,8 @@ package differ
 
 import (
 	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
 )
 
 func TestEqualStringSlices(t *testing.T) {
@@ -18,6 +20,7 @@ func TestEqualStringSlices(t *testing.T) {
 		{"Different content", []string{"a", "b", "c"}, []string{"a", "b", "d"}, false},
 		{"One nil", nil, []string{"a"}, false},
 		{"Both nil", nil, nil, true},
+		{"Nil and empty", nil, []string{}, true},
 	}
 
 	for _, tt := range tests {
@@ -30,6 +33,35 @@ func TestEqualStringSlices(t *testing.T) {
 	}
 }
 
+func TestSemanticEqual(t *testing.T) {
+	yes := true
+	tests := []struct {
+		name     string
+		a        interface{}
+		b        interface{}
+		expected bool
+	}{
+		{"Nil and empty slice", []string(nil), []string{}, true},
+		{"Nil and empty map", map[string]interface{}(nil), map[string]interface{}{}, true},
+		{"Nested nil and empty", &parser.Artifacts{}, &parser.Artifacts{Paths: []string{}}, true},
+		{"Nil and empty in a list", []parser.Rule{{}}, []parser.Rule{{Changes: []string{}}}, true},
+		{"Different values", &parser.Artifacts{Paths: []string{"a"}}, &parser.Artifacts{Paths: []string{"b"}}, false},
+		{"Order matters", []string{"a", "b"}, []string{"b", "a"}, false},
+		{"Nil and set pointer", (*bool)(nil), &yes, false},
+		{"Nil pointer and zero value", (*parser.Artifacts)(nil), &parser.Artifacts{}, false},
+		{"Nil interface and empty list", []interface{}{nil}, []interface{}{[]interface{}{}}, false},
+		{"Different types", 1, "1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := semanticEqual(tt.a, tt.b); result != tt.expected {
+				t.Errorf("semanticEqual(%v, %v) = %v, want %v", tt.a, tt.b, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestGenerateSummary(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,158 @@
+// Package fixer resolves analyzer issues that have a mechanical fix by
+// editing the configuration's YAML document. Each fixer is registered under
+// the name of the check whose issues it resolves.
+package fixer
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/differ"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixer edits the root mapping of a configuration to resolve an issue,
+// reporting whether it changed anything. It returns false when the part of
+// the document the issue is about isn't in this file.
+type Fixer func(root *yaml.Node, issue types.Issue) bool
+
+// fixers maps check names to the fixer for their issues
+var fixers = map[string]Fixer{
+	"artifact_expiration":   fixArtifactExpiration,
+	"duplicate_stages":      fixDuplicateStages,
+	"missing_interruptible": fixMissingInterruptible,
+}
+
+// defaultExpireIn is the artifacts:expire_in fixArtifactExpiration sets, and
+// GitLab.com's default for new projects
+const defaultExpireIn = "30 days"
+
+// CanFix reports whether issues of the named check have a fixer
+func CanFix(checkName string) bool {
+	_, ok := fixers[checkName]
+	return ok
+}
+
+// Apply fixes the issues it can in a configuration file's content. It
+// returns the fixed content and the issues it fixed. Both the content and the
+// result are in the canonical layout the YAML encoder writes, with comments
+// kept, so they can be compared line by line.
+func Apply(data []byte, issues []types.Issue) ([]byte, []types.Issue, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, nil, fmt.Errorf("parsing YAML: %w", err)
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return data, nil, nil
+	}
+
+	var fixed []types.Issue
+	for _, issue := range issues {
+		if fix, ok := fixers[issue.Check]; ok && fix(document.Content[0], issue) {
+			fixed = append(fixed, issue)
+		}
+	}
+
+	output, err := encode(&document)
+	if err != nil {
+		return nil, nil, err
+	}
+	return output, fixed, nil
+}
+
+// Preview returns the unified diff of the change that fixing issue would
+// make to a file, or "" when it can't be fixed in that file. The file isn't
+// written.
+func Preview(fileName string, data []byte, issue types.Issue) (string, error) {
+	before, _, err := Apply(data, nil)
+	if err != nil {
+		return "", err
+	}
+	after, fixed, err := Apply(data, []types.Issue{issue})
+	if err != nil || len(fixed) == 0 {
+		return "", err
+	}
+	return differ.UnifiedFileDiff(fileName, before, after), nil
+}
+
+func encode(document *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return nil, fmt.Errorf("writing YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("writing YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// fixArtifactExpiration sets expire_in on the artifacts of the issue's job
+func fixArtifactExpiration(root *yaml.Node, issue types.Issue) bool {
+	artifacts := mappingValue(mappingValue(root, issue.JobName), "artifacts")
+	if artifacts == nil || artifacts.Kind != yaml.MappingNode || mappingValue(artifacts, "expire_in") != nil {
+		return false
+	}
+	setMappingValue(artifacts, "expire_in", scalar(defaultExpireIn))
+	return true
+}
+
+// fixDuplicateStages removes the repeated entries of stages, keeping the
+// first of each
+func fixDuplicateStages(root *yaml.Node, issue types.Issue) bool {
+	stages := mappingValue(root, "stages")
+	if stages == nil || stages.Kind != yaml.SequenceNode {
+		return false
+	}
+
+	seen := make(map[string]bool)
+	kept := stages.Content[:0]
+	for _, stage := range stages.Content {
+		if stage.Kind == yaml.ScalarNode && seen[stage.Value] {
+			continue
+		}
+		seen[stage.Value] = true
+		kept = append(kept, stage)
+	}
+	changed := len(kept) != len(stages.Content)
+	stages.Content = kept
+	return changed
+}
+
+// fixMissingInterruptible sets interruptible: true under default, adding
+// the default block when there's none
+func fixMissingInterruptible(root *yaml.Node, issue types.Issue) bool {
+	defaults := mappingValue(root, "default")
+	if defaults == nil {
+		defaults = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		root.Content = append([]*yaml.Node{scalar("default"), defaults}, root.Content...)
+	}
+	if defaults.Kind != yaml.MappingNode || mappingValue(defaults, "interruptible") != nil {
+		return false
+	}
+	setMappingValue(defaults, "interruptible", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+	return true
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	node.Content = append(node.Content, scalar(key), value)
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,70 @@
+package fixer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+)
+
+const config = `# CI configuration
+stages: [build, test, build]
+build:
+  stage: build
+  script: [make]
+  artifacts:
+    paths: [dist/]
+test:
+  stage: test
+  script: [make test]
+`
+
+func TestApply(t *testing.T) {
+	issues := []types.Issue{
+		{Check: "duplicate_stages", Path: "stages"},
+		{Check: "artifact_expiration", Path: "jobs.build.artifacts.expire_in", JobName: "build"},
+		{Check: "missing_interruptible", Path: "default.interruptible"},
+		{Check: "image_tags", Path: "jobs.build.image", JobName: "build"},
+		{Check: "artifact_expiration", Path: "jobs.lint.artifacts.expire_in", JobName: "lint"},
+	}
+
+	output, fixed, err := Apply([]byte(config), issues)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(fixed) != 3 {
+		t.Errorf("Expected the 3 issues with a fixer and a job in the file to be fixed, got %+v", fixed)
+	}
+
+	text := string(output)
+	for _, want := range []string{"# CI configuration", "stages: [build, test]", "expire_in: 30 days", "default:\n  interruptible: true"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected the output to contain %q, got:\n%s", want, text)
+		}
+	}
+}
+
+func TestPreview(t *testing.T) {
+	issue := types.Issue{Check: "artifact_expiration", Path: "jobs.build.artifacts.expire_in", JobName: "build"}
+	patch, err := Preview(".gitlab-ci.yml", []byte(config), issue)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if !strings.HasPrefix(patch, "--- a/.gitlab-ci.yml\n+++ b/.gitlab-ci.yml\n") || !strings.Contains(patch, "+    expire_in: 30 days") {
+		t.Errorf("Expected a patch adding expire_in, got:\n%s", patch)
+	}
+	if strings.Count(patch, "\n+") != 2 || strings.Contains(patch, "\n-") {
+		t.Errorf("Expected the patch to only add expire_in, got:\n%s", patch)
+	}
+
+	issue.JobName = "test"
+	if patch, err := Preview(".gitlab-ci.yml", []byte(config), issue); err != nil || patch != "" {
+		t.Errorf("Expected no patch for a job without artifacts, got %q, %v", patch, err)
+	}
+}
+
+func TestCanFix(t *testing.T) {
+	if !CanFix("duplicate_stages") || CanFix("image_tags") {
+		t.Error("Expected only checks with a fixer to be fixable")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/httpretry"
 )
 
 // apiClient implements the Client interface using real GitLab API
@@ -15,6 +17,9 @@ type apiClient struct {
 	baseURL    string
 	token      string
 	httpClient *http.Client
+	// retry controls retries of transient failures and rate limiting; zero
+	// fields use httpretry.DefaultPolicy
+	retry httpretry.Policy
 }
 
 // NewAPIClientImpl creates a new API client for real GitLab instance
@@ -63,7 +68,7 @@ func (c *apiClient) doRequest(ctx context.Context, method, path string, body int
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	return c.httpClient.Do(req)
+	return c.retry.Do(c.httpClient, req)
 }
 
 // ValidateConfig validates a GitLab CI configuration
@@ -0,0 +1,126 @@
+// Package httpretry retries HTTP requests to the GitLab API on transient
+// failures and rate limiting
+package httpretry
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Policy controls how requests are retried. Zero fields take the values of
+// DefaultPolicy.
+type Policy struct {
+	// MaxAttempts is the total number of tries, including the first
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry; it doubles with each
+	// further retry up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultPolicy is used for any Policy field left unset
+var DefaultPolicy = Policy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// Do sends req with client, retrying network errors and 5xx responses with
+// exponential backoff, and 429 responses after the delay in their
+// Retry-After header. Retries stop early when the request's context is done
+// or its deadline would pass before the next attempt; the last response or
+// error is returned then.
+func (p Policy) Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	p = p.withDefaults()
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		try := req.Clone(ctx)
+		if req.Body != nil && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			try.Body = body
+		}
+
+		resp, err := client.Do(try)
+		if attempt >= p.MaxAttempts || ctx.Err() != nil || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		var delay time.Duration
+		switch {
+		case err != nil:
+			delay = p.backoff(attempt)
+		case resp.StatusCode == http.StatusTooManyRequests:
+			var ok bool
+			if delay, ok = retryAfter(resp.Header.Get("Retry-After")); !ok {
+				delay = p.backoff(attempt)
+			}
+		case resp.StatusCode >= 500:
+			delay = p.backoff(attempt)
+		default:
+			return resp, nil
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultPolicy.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultPolicy.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultPolicy.MaxDelay
+	}
+	return p
+}
+
+// backoff returns the wait after the given failed attempt
+func (p Policy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(when); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,132 @@
+package httpretry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var fastPolicy = Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// flakyServer fails with the given statuses before answering 200 with the
+// request body, counting the requests it receives
+func flakyServer(t *testing.T, statuses []int, header http.Header) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= len(statuses) {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statuses[requests-1])
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestPolicyDoRetriesServerErrors(t *testing.T) {
+	server, requests := flakyServer(t, []int{http.StatusBadGateway, http.StatusServiceUnavailable}, nil)
+
+	req, _ := http.NewRequest("POST", server.URL, bytes.NewReader([]byte("payload")))
+	resp, err := fastPolicy.Do(server.Client(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "payload" {
+		t.Errorf("Expected the body to be resent on the successful attempt, got %d %q", resp.StatusCode, body)
+	}
+	if *requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", *requests)
+	}
+}
+
+func TestPolicyDoGivesUpAfterMaxAttempts(t *testing.T) {
+	server, requests := flakyServer(t, []int{500, 500, 500, 500}, nil)
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := fastPolicy.Do(server.Client(), req)
+	if err != nil {
+		t.Fatalf("Expected the last response rather than an error, got: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 500 || *requests != 3 {
+		t.Errorf("Expected 3 attempts ending in a 500, got %d after %d requests", resp.StatusCode, *requests)
+	}
+}
+
+func TestPolicyDoDoesNotRetryClientErrors(t *testing.T) {
+	server, requests := flakyServer(t, []int{http.StatusNotFound}, nil)
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := fastPolicy.Do(server.Client(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || *requests != 1 {
+		t.Errorf("Expected a single 404, got %d after %d requests", resp.StatusCode, *requests)
+	}
+}
+
+func TestPolicyDoHonorsRetryAfter(t *testing.T) {
+	server, requests := flakyServer(t, []int{http.StatusTooManyRequests}, http.Header{"Retry-After": {"1"}})
+
+	// A deadline shorter than Retry-After returns the 429 without waiting
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	start := time.Now()
+	resp, err := fastPolicy.Do(server.Client(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || time.Since(start) > 150*time.Millisecond {
+		t.Errorf("Expected an immediate 429, got %d after %v", resp.StatusCode, time.Since(start))
+	}
+
+	// Without a deadline, the request is retried after Retry-After
+	*requests = 0
+	req, _ = http.NewRequest("GET", server.URL, nil)
+	start = time.Now()
+	resp, err = fastPolicy.Do(server.Client(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || time.Since(start) < time.Second {
+		t.Errorf("Expected a 200 after waiting 1s, got %d after %v", resp.StatusCode, time.Since(start))
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if delay, ok := retryAfter("3"); !ok || delay != 3*time.Second {
+		t.Errorf("Expected 3s, got %v %v", delay, ok)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if delay, ok := retryAfter(date); !ok || delay <= 0 || delay > time.Minute {
+		t.Errorf("Expected a delay up to a minute, got %v %v", delay, ok)
+	}
+	if _, ok := retryAfter("soon"); ok {
+		t.Error("Expected an invalid Retry-After to be rejected")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}.withDefaults()
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		if got := policy.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, expected %v", attempt, got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package parser
+
+import "strings"
+
+// RulesComplexity breaks down how hard a job's rules are to follow
+type RulesComplexity struct {
+	// Conditions counts each rule plus each && or || in its if expression
+	Conditions int `json:"conditions"`
+	// Variables counts the distinct variables the if expressions reference
+	Variables int `json:"variables"`
+	// FileChecks is 1, plus 1 when any rule uses changes and 1 when any
+	// rule uses exists, since those conditions depend on the repository
+	// rather than the pipeline
+	FileChecks int `json:"file_checks"`
+	// Score is the product of the other factors, 0 for a job without rules
+	Score int `json:"score"`
+}
+
+// ExplainRuleComplexity scores a job's rules as conditions × variables ×
+// file checks. Each factor is at least 1 for a job with rules, so adding a
+// condition, a variable or a file check always raises the score.
+func ExplainRuleComplexity(job *JobConfig) RulesComplexity {
+	if len(job.Rules) == 0 {
+		return RulesComplexity{}
+	}
+
+	complexity := RulesComplexity{FileChecks: 1}
+	variables := make(map[string]bool)
+	hasChanges, hasExists := false, false
+	for _, rule := range job.Rules {
+		complexity.Conditions += 1 + strings.Count(rule.If, "&&") + strings.Count(rule.If, "||")
+		for _, match := range referencedVariablePattern.FindAllStringSubmatch(rule.If, -1) {
+			variables[match[1]] = true
+		}
+		hasChanges = hasChanges || len(rule.Changes) > 0
+		hasExists = hasExists || len(rule.Exists) > 0
+	}
+	if hasChanges {
+		complexity.FileChecks++
+	}
+	if hasExists {
+		complexity.FileChecks++
+	}
+
+	complexity.Variables = len(variables)
+	complexity.Score = complexity.Conditions * max(complexity.Variables, 1) * complexity.FileChecks
+	return complexity
+}
+
+// RuleComplexity returns the complexity score of a job's rules, see
+// ExplainRuleComplexity
+func RuleComplexity(job *JobConfig) int {
+	return ExplainRuleComplexity(job).Score
+}
@@ -0,0 +1,33 @@
+package parser
+
+import "testing"
+
+func TestRuleComplexity(t *testing.T) {
+	tests := []struct {
+		name string
+		job  *JobConfig
+		want RulesComplexity
+	}{
+		{"no rules", &JobConfig{}, RulesComplexity{}},
+		{"bare when", &JobConfig{Rules: []Rule{{When: "manual"}}}, RulesComplexity{Conditions: 1, FileChecks: 1, Score: 1}},
+		{
+			"nested conditions",
+			&JobConfig{Rules: []Rule{
+				{If: `$CI_COMMIT_BRANCH == "main" && ($DEPLOY || ${CI_COMMIT_TAG})`, Changes: []string{"src/**/*"}},
+				{If: `$DEPLOY == "true"`, Exists: []string{"Dockerfile"}},
+			}},
+			RulesComplexity{Conditions: 4, Variables: 3, FileChecks: 3, Score: 36},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExplainRuleComplexity(tt.job); got != tt.want {
+				t.Errorf("ExplainRuleComplexity() = %+v, want %+v", got, tt.want)
+			}
+			if got := RuleComplexity(tt.job); got != tt.want.Score {
+				t.Errorf("RuleComplexity() = %d, want %d", got, tt.want.Score)
+			}
+		})
+	}
+}
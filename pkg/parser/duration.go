@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationUnits maps the unit names GitLab accepts in timeout, start_in,
+// expire_in and similar keywords to their length
+var durationUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "wk": 7 * 24 * time.Hour, "wks": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+	"mo": 30 * 24 * time.Hour, "mos": 30 * 24 * time.Hour, "month": 30 * 24 * time.Hour, "months": 30 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour, "yr": 365 * 24 * time.Hour, "yrs": 365 * 24 * time.Hour, "year": 365 * 24 * time.Hour, "years": 365 * 24 * time.Hour,
+}
+
+// durationPartPattern matches one amount and its unit, e.g. "1h", "30 mins"
+// or "1.5 hours"
+var durationPartPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-z]+)`)
+
+// ParseDuration parses a duration in GitLab's human-readable format, such as
+// "1h 30m", "90 minutes", "2 days and 4 hours" or a plain number of seconds
+func ParseDuration(value string) (time.Duration, error) {
+	text := strings.ToLower(strings.TrimSpace(value))
+	if text == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	if seconds, err := strconv.Atoi(text); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	var total time.Duration
+	for text != "" {
+		match := durationPartPattern.FindStringSubmatch(text)
+		if match == nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		unit, ok := durationUnits[match[2]]
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %q: unknown unit %q", value, match[2])
+		}
+		amount, _ := strconv.ParseFloat(match[1], 64)
+		total += time.Duration(amount * float64(unit))
+
+		text = strings.TrimLeft(text[len(match[0]):], " ,")
+		text = strings.TrimPrefix(text, "and ")
+	}
+	return total, nil
+}
@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"3600", time.Hour},
+		{"90m", 90 * time.Minute},
+		{"1h 30m", 90 * time.Minute},
+		{"1h30m", 90 * time.Minute},
+		{"2 hours", 2 * time.Hour},
+		{"1 day and 4 hours", 28 * time.Hour},
+		{"3 mins, 4 sec", 3*time.Minute + 4*time.Second},
+		{"1.5 hours", 90 * time.Minute},
+		{"1 week", 7 * 24 * time.Hour},
+		{" 10 Minutes ", 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.value)
+		if err != nil {
+			t.Errorf("ParseDuration(%q) failed: %v", tt.value, err)
+		} else if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "forever", "1 fortnight", "5 hrs 30", "h1"} {
+		if _, err := ParseDuration(value); err == nil {
+			t.Errorf("ParseDuration(%q) should fail", value)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// EvaluateExpression evaluates a GitLab CI `rules:if` expression against the
+// given variables. Undefined variables evaluate to null, matching GitLab's
+// behavior. Supported syntax covers variables ($VAR and ${VAR}), string
+// literals, null, regex literals, ==, !=, =~, !~, &&, || and parentheses.
+func EvaluateExpression(expression string, variables map[string]string) (bool, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return false, err
+	}
+	if len(tokens) == 0 {
+		return false, fmt.Errorf("empty expression")
+	}
+
+	p := &expressionParser{tokens: tokens, variables: variables}
+	result, err := p.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if p.pos < len(p.tokens) {
+		return false, fmt.Errorf("unexpected token %q in expression %q", p.tokens[p.pos].text, expression)
+	}
+
+	return result, nil
+}
+
+type expressionTokenKind int
+
+const (
+	tokenVariable expressionTokenKind = iota
+	tokenString
+	tokenRegex
+	tokenNull
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type expressionToken struct {
+	kind expressionTokenKind
+	text string
+}
+
+// tokenizeExpression splits an expression into tokens
+func tokenizeExpression(expression string) ([]expressionToken, error) {
+	var tokens []expressionToken
+	i := 0
+
+	for i < len(expression) {
+		ch := expression[i]
+
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '(':
+			tokens = append(tokens, expressionToken{kind: tokenLParen, text: "("})
+			i++
+		case ch == ')':
+			tokens = append(tokens, expressionToken{kind: tokenRParen, text: ")"})
+			i++
+		case ch == '$':
+			name, next, err := readVariableName(expression, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, expressionToken{kind: tokenVariable, text: name})
+			i = next
+		case ch == '"' || ch == '\'':
+			end := strings.IndexByte(expression[i+1:], ch)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string in expression %q", expression)
+			}
+			tokens = append(tokens, expressionToken{kind: tokenString, text: expression[i+1 : i+1+end]})
+			i += end + 2
+		case ch == '/':
+			pattern, next, err := readRegexLiteral(expression, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, expressionToken{kind: tokenRegex, text: pattern})
+			i = next
+		case strings.HasPrefix(expression[i:], "=="),
+			strings.HasPrefix(expression[i:], "!="),
+			strings.HasPrefix(expression[i:], "=~"),
+			strings.HasPrefix(expression[i:], "!~"),
+			strings.HasPrefix(expression[i:], "&&"),
+			strings.HasPrefix(expression[i:], "||"):
+			tokens = append(tokens, expressionToken{kind: tokenOperator, text: expression[i : i+2]})
+			i += 2
+		case strings.HasPrefix(expression[i:], "null"):
+			tokens = append(tokens, expressionToken{kind: tokenNull, text: "null"})
+			i += len("null")
+		default:
+			return nil, fmt.Errorf("unexpected character %q in expression %q", ch, expression)
+		}
+	}
+
+	return tokens, nil
+}
+
+// readVariableName reads a $VAR or ${VAR} reference starting at pos
+func readVariableName(expression string, pos int) (string, int, error) {
+	i := pos + 1
+	braced := i < len(expression) && expression[i] == '{'
+	if braced {
+		i++
+	}
+
+	start := i
+	for i < len(expression) && isVariableChar(expression[i]) {
+		i++
+	}
+	if i == start {
+		return "", 0, fmt.Errorf("invalid variable reference in expression %q", expression)
+	}
+	name := expression[start:i]
+
+	if braced {
+		if i >= len(expression) || expression[i] != '}' {
+			return "", 0, fmt.Errorf("unterminated variable reference in expression %q", expression)
+		}
+		i++
+	}
+
+	return name, i, nil
+}
+
+// readRegexLiteral reads a /pattern/flags literal starting at pos and returns
+// it in Go regexp syntax
+func readRegexLiteral(expression string, pos int) (string, int, error) {
+	i := pos + 1
+	var pattern strings.Builder
+
+	for i < len(expression) && expression[i] != '/' {
+		if expression[i] == '\\' && i+1 < len(expression) && expression[i+1] == '/' {
+			pattern.WriteByte('/')
+			i += 2
+			continue
+		}
+		pattern.WriteByte(expression[i])
+		i++
+	}
+	if i >= len(expression) {
+		return "", 0, fmt.Errorf("unterminated regex in expression %q", expression)
+	}
+	i++ // closing slash
+
+	flags := ""
+	for i < len(expression) && strings.IndexByte("imsx", expression[i]) >= 0 {
+		if expression[i] != 'x' {
+			flags += string(expression[i])
+		}
+		i++
+	}
+
+	if flags != "" {
+		return "(?" + flags + ")" + pattern.String(), i, nil
+	}
+	return pattern.String(), i, nil
+}
+
+func isVariableChar(ch byte) bool {
+	return ch == '_' || (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9')
+}
+
+// expressionValue is an evaluated operand; nil str means null
+type expressionValue struct {
+	str     *string
+	isRegex bool
+}
+
+type expressionParser struct {
+	tokens    []expressionToken
+	pos       int
+	variables map[string]string
+}
+
+func (p *expressionParser) peek() *expressionToken {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+func (p *expressionParser) parseOr() (bool, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		tok := p.peek()
+		if tok == nil || tok.kind != tokenOperator || tok.text != "||" {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return false, err
+		}
+		left = left || right
+	}
+}
+
+func (p *expressionParser) parseAnd() (bool, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		tok := p.peek()
+		if tok == nil || tok.kind != tokenOperator || tok.text != "&&" {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parsePrimary()
+		if err != nil {
+			return false, err
+		}
+		left = left && right
+	}
+}
+
+func (p *expressionParser) parsePrimary() (bool, error) {
+	tok := p.peek()
+	if tok == nil {
+		return false, fmt.Errorf("unexpected end of expression")
+	}
+
+	if tok.kind == tokenLParen {
+		p.pos++
+		result, err := p.parseOr()
+		if err != nil {
+			return false, err
+		}
+		closing := p.peek()
+		if closing == nil || closing.kind != tokenRParen {
+			return false, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return result, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return false, err
+	}
+
+	op := p.peek()
+	if op == nil || op.kind != tokenOperator || op.text == "&&" || op.text == "||" {
+		// A lone operand is truthy when it is defined and non-empty
+		return left.str != nil && *left.str != "", nil
+	}
+	p.pos++
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return false, err
+	}
+
+	switch op.text {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	case "=~":
+		return matchesPattern(left, right)
+	case "!~":
+		matched, err := matchesPattern(left, right)
+		return !matched, err
+	default:
+		return false, fmt.Errorf("unsupported operator %q", op.text)
+	}
+}
+
+func (p *expressionParser) parseOperand() (expressionValue, error) {
+	tok := p.peek()
+	if tok == nil {
+		return expressionValue{}, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+
+	switch tok.kind {
+	case tokenVariable:
+		if value, exists := p.variables[tok.text]; exists {
+			return expressionValue{str: &value}, nil
+		}
+		return expressionValue{}, nil
+	case tokenString:
+		value := tok.text
+		return expressionValue{str: &value}, nil
+	case tokenRegex:
+		value := tok.text
+		return expressionValue{str: &value, isRegex: true}, nil
+	case tokenNull:
+		return expressionValue{}, nil
+	default:
+		return expressionValue{}, fmt.Errorf("unexpected token %q", tok.text)
+	}
+}
+
+func valuesEqual(a, b expressionValue) bool {
+	if a.str == nil || b.str == nil {
+		return a.str == nil && b.str == nil
+	}
+	return *a.str == *b.str
+}
+
+// matchesPattern matches the left value against a regex literal or a
+// variable holding a /pattern/ string
+func matchesPattern(value, pattern expressionValue) (bool, error) {
+	if value.str == nil || pattern.str == nil {
+		return false, nil
+	}
+
+	expr := *pattern.str
+	if !pattern.isRegex {
+		if !strings.HasPrefix(expr, "/") {
+			return false, fmt.Errorf("right side of =~ must be a regex, got %q", expr)
+		}
+		converted, _, err := readRegexLiteral(expr, 0)
+		if err != nil {
+			return false, err
+		}
+		expr = converted
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return false, fmt.Errorf("invalid regex %q: %w", expr, err)
+	}
+	return re.MatchString(*value.str), nil
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestEvaluateExpression(t *testing.T) {
+	vars := map[string]string{
+		"CI_PIPELINE_SOURCE": "push",
+		"CI_COMMIT_BRANCH":   "feature/login",
+		"CI_COMMIT_TAG":      "",
+		"RELEASE_PATTERN":    "/^release-.*$/",
+		"CI_COMMIT_MESSAGE":  "Fix [skip tests] flaky",
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   bool
+	}{
+		{"equality match", `$CI_PIPELINE_SOURCE == "push"`, true},
+		{"equality mismatch", `$CI_PIPELINE_SOURCE == "merge_request_event"`, false},
+		{"single quoted string", `$CI_PIPELINE_SOURCE == 'push'`, true},
+		{"inequality", `$CI_PIPELINE_SOURCE != "schedule"`, true},
+		{"braced variable", `${CI_PIPELINE_SOURCE} == "push"`, true},
+		{"defined variable is truthy", `$CI_COMMIT_BRANCH`, true},
+		{"empty variable is falsy", `$CI_COMMIT_TAG`, false},
+		{"undefined variable is falsy", `$CI_MERGE_REQUEST_ID`, false},
+		{"undefined equals null", `$CI_MERGE_REQUEST_ID == null`, true},
+		{"empty is not null", `$CI_COMMIT_TAG == null`, false},
+		{"regex match", `$CI_COMMIT_BRANCH =~ /^feature\//`, true},
+		{"regex no match", `$CI_COMMIT_BRANCH =~ /^release/`, false},
+		{"regex case insensitive", `$CI_COMMIT_BRANCH =~ /^FEATURE/i`, true},
+		{"negated regex", `$CI_COMMIT_BRANCH !~ /^release/`, true},
+		{"regex from variable", `"release-1.0" =~ $RELEASE_PATTERN`, true},
+		{"regex against undefined", `$UNDEFINED =~ /.*/`, false},
+		{"regex with brackets", `$CI_COMMIT_MESSAGE =~ /\[skip tests\]/`, true},
+		{"and", `$CI_PIPELINE_SOURCE == "push" && $CI_COMMIT_BRANCH`, true},
+		{"and short", `$CI_PIPELINE_SOURCE == "push" && $CI_COMMIT_TAG`, false},
+		{"or", `$CI_COMMIT_TAG || $CI_PIPELINE_SOURCE == "push"`, true},
+		{"and binds tighter than or", `$CI_COMMIT_TAG && $CI_COMMIT_BRANCH || $CI_PIPELINE_SOURCE == "push"`, true},
+		{"parentheses", `($CI_COMMIT_TAG || $CI_COMMIT_BRANCH) && $CI_PIPELINE_SOURCE == "schedule"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EvaluateExpression(tt.expression, vars)
+			if err != nil {
+				t.Fatalf("Unexpected error evaluating %q: %v", tt.expression, err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q to evaluate to %v, got %v", tt.expression, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEvaluateExpressionErrors(t *testing.T) {
+	invalid := []string{
+		``,
+		`$CI_COMMIT_BRANCH == "unterminated`,
+		`($CI_COMMIT_BRANCH == "main"`,
+		`$CI_COMMIT_BRANCH =~ /unterminated`,
+		`$CI_COMMIT_BRANCH ==`,
+		`$CI_COMMIT_BRANCH = "main"`,
+	}
+
+	for _, expression := range invalid {
+		if _, err := EvaluateExpression(expression, map[string]string{}); err == nil {
+			t.Errorf("Expected error for expression %q", expression)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MatchGlob reports whether a repository path matches a rules:exists or
+// rules:changes pattern. As in GitLab, * and ? don't match "/", ** matches
+// any number of directories, and [...] classes and {a,b} alternatives are
+// supported. Patterns that can't be compiled match nothing.
+func MatchGlob(pattern, path string) bool {
+	re, err := regexp.Compile(globPattern(strings.TrimPrefix(pattern, "./")))
+	if err != nil {
+		return false
+	}
+	return re.MatchString(strings.TrimPrefix(path, "./"))
+}
+
+// globPattern translates a glob into an anchored regular expression
+func globPattern(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+
+	braces := 0
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '{':
+			braces++
+			b.WriteString("(?:")
+		case '}':
+			if braces == 0 {
+				b.WriteString(`\}`)
+				continue
+			}
+			braces--
+			b.WriteString(")")
+		case ',':
+			if braces > 0 {
+				b.WriteString("|")
+			} else {
+				b.WriteString(",")
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("$")
+	return b.String()
+}
+
+// anyFileMatches reports whether one of the files matches one of the
+// patterns, after expanding the variables in the patterns
+func anyFileMatches(files, patterns []string, vars map[string]string) bool {
+	for _, pattern := range patterns {
+		pattern = os.Expand(pattern, func(name string) string { return vars[name] })
+		for _, file := range files {
+			if MatchGlob(pattern, file) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RepositoryFiles lists the files under dir, relative to it and with "/"
+// separators, for PipelineContext.ExistingFiles. The .git directory is
+// skipped.
+func RepositoryFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if files == nil {
+		files = []string{}
+	}
+	return files, nil
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"Dockerfile", "Dockerfile", true},
+		{"Dockerfile", "docker/Dockerfile", false},
+		{"*.md", "README.md", true},
+		{"*.md", "docs/intro.md", false},
+		{"**/*.md", "docs/intro.md", true},
+		{"**/*.md", "README.md", true},
+		{"docs/**", "docs/a/b/c.txt", true},
+		{"src/**/test_*.py", "src/pkg/sub/test_api.py", true},
+		{"src/**/test_*.py", "src/test_api.py", true},
+		{"config/?.yml", "config/a.yml", true},
+		{"config/?.yml", "config/ab.yml", false},
+		{"{package,yarn}.json", "yarn.json", true},
+		{"*.{js,ts}", "index.ts", true},
+		{"*.{js,ts}", "index.go", false},
+		{"[ab].txt", "b.txt", true},
+		{"[!ab].txt", "b.txt", false},
+		{"./go.mod", "go.mod", true},
+		{"*", ".gitlab-ci.yml", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.path); got != tt.match {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.match)
+		}
+	}
+}
+
+func TestRepositoryFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"Dockerfile", "docs/guide/intro.md", ".git/HEAD"} {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := RepositoryFiles(dir)
+	if err != nil {
+		t.Fatalf("listing files: %v", err)
+	}
+	sort.Strings(files)
+	if expected := []string{"Dockerfile", "docs/guide/intro.md"}; !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+
+	if files, err := RepositoryFiles(t.TempDir()); err != nil || files == nil {
+		t.Errorf("expected an empty, non-nil list for an empty directory, got %v (%v)", files, err)
+	}
+}
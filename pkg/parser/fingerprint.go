@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jobFingerprint holds the fields that determine what a job does, in a form
+// that serializes identically for equivalent jobs
+type jobFingerprint struct {
+	BeforeScript []string          `json:"before_script,omitempty"`
+	Script       []string          `json:"script,omitempty"`
+	AfterScript  []string          `json:"after_script,omitempty"`
+	Image        *Image            `json:"image,omitempty"`
+	Stage        string            `json:"stage"`
+	Rules        []Rule            `json:"rules,omitempty"`
+	Variables    map[string]string `json:"variables,omitempty"`
+}
+
+// JobFingerprint returns a stable hash of the job's behaviorally significant
+// fields: before_script, script, after_script, image, stage, rules and
+// variables, as the job runs them, taken from the templates it extends and
+// from the default block where the job doesn't set them. Cosmetic
+// differences such as variable order, surrounding whitespace in script
+// lines, quoting of variable values, an explicit default stage or moving a
+// setting into a template don't change it. It returns "" for unknown jobs.
+func (c *GitLabConfig) JobFingerprint(jobName string) string {
+	return c.fingerprint(jobName, true)
+}
+
+// JobMatrixFingerprint is like JobFingerprint but ignores image and
+// variables, so jobs sharing it differ only in ways a single parallel:matrix
+// job can express
+func (c *GitLabConfig) JobMatrixFingerprint(jobName string) string {
+	return c.fingerprint(jobName, false)
+}
+
+func (c *GitLabConfig) fingerprint(jobName string, withEnvironment bool) string {
+	job := c.Jobs[jobName]
+	if job == nil {
+		return ""
+	}
+
+	// The job comes first, then the templates it extends, nearest first
+	sources := []*JobConfig{job}
+	chain := c.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := c.Jobs[chain[i]]; template != nil {
+			sources = append(sources, template)
+		}
+	}
+	// setting returns the first of the sources, or the default block when
+	// the job inherits keyword from it, for which isSet holds
+	setting := func(keyword string, isSet func(*JobConfig) bool) *JobConfig {
+		for _, source := range sources {
+			if isSet(source) {
+				return source
+			}
+		}
+		if c.Default != nil && job.InheritsDefault(keyword) && isSet(c.Default) {
+			return c.Default
+		}
+		return nil
+	}
+
+	fp := jobFingerprint{Stage: c.JobStage(jobName)}
+	if source := setting("before_script", func(j *JobConfig) bool { return j.BeforeScript != nil }); source != nil {
+		fp.BeforeScript = trimLines(source.BeforeScript)
+	}
+	if source := setting("script", func(j *JobConfig) bool { return j.Script != nil }); source != nil {
+		fp.Script = trimLines(source.Script)
+	}
+	if source := setting("after_script", func(j *JobConfig) bool { return j.AfterScript != nil }); source != nil {
+		fp.AfterScript = trimLines(source.AfterScript)
+	}
+	if source := setting("rules", func(j *JobConfig) bool { return j.Rules != nil }); source != nil {
+		fp.Rules = source.Rules
+	}
+	if withEnvironment {
+		if source := setting("image", func(j *JobConfig) bool { return j.Image.GetName() != "" }); source != nil {
+			fp.Image = source.Image
+		}
+		// Variables are merged along the chain, nearer definitions winning
+		for i := len(sources) - 1; i >= 0; i-- {
+			for name, value := range sources[i].Variables {
+				if fp.Variables == nil {
+					fp.Variables = make(map[string]string)
+				}
+				fp.Variables[name] = fmt.Sprint(value)
+			}
+		}
+	}
+
+	// encoding/json writes map keys in sorted order
+	data, err := json.Marshal(fp)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func trimLines(lines []string) []string {
+	trimmed := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed[i] = strings.TrimSpace(line)
+	}
+	return trimmed
+}
@@ -0,0 +1,96 @@
+package parser
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	config, err := Parse([]byte(`
+a:
+  stage: test
+  image: node:18
+  variables:
+    MODE: "1"
+    TARGET: web
+  script:
+    - npm ci
+    - npm test
+b:
+  variables:
+    TARGET: web
+    MODE: 1
+  image:
+    name: node:18
+  script:
+    - "  npm ci"
+    - npm test
+reordered:
+  image: node:18
+  variables: {MODE: "1", TARGET: web}
+  script: [npm test, npm ci]
+node20:
+  image: node:20
+  variables: {MODE: "2", TARGET: web}
+  script: [npm ci, npm test]
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	if config.JobFingerprint("a") != config.JobFingerprint("b") {
+		t.Error("expected jobs differing only cosmetically to share a fingerprint")
+	}
+	if config.JobFingerprint("a") == config.JobFingerprint("reordered") {
+		t.Error("expected script order to change the fingerprint")
+	}
+
+	if config.JobFingerprint("a") == config.JobFingerprint("node20") {
+		t.Error("expected a different image and variables to change the fingerprint")
+	}
+	if config.JobMatrixFingerprint("a") != config.JobMatrixFingerprint("node20") {
+		t.Error("expected jobs differing only in image and variables to share a matrix fingerprint")
+	}
+
+	if config.JobFingerprint("missing") != "" {
+		t.Error("expected an unknown job to have an empty fingerprint")
+	}
+}
+
+func TestFingerprintResolvesExtendsAndDefault(t *testing.T) {
+	config, err := Parse([]byte(`
+default:
+  image: node:18
+  before_script: [npm ci]
+
+.test:
+  stage: test
+  variables: {MODE: "1"}
+  script: [npm test]
+
+inline:
+  image: node:18
+  before_script: [npm ci]
+  variables: {MODE: "1"}
+  script: [npm test]
+extended:
+  extends: .test
+own_setup:
+  extends: .test
+  before_script: [yarn install]
+no_default:
+  extends: .test
+  inherit:
+    default: false
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	if config.JobFingerprint("inline") != config.JobFingerprint("extended") {
+		t.Error("expected settings from extends and default to count as the job's own")
+	}
+	if config.JobFingerprint("extended") == config.JobFingerprint("own_setup") {
+		t.Error("expected before_script to change the fingerprint")
+	}
+	if config.JobFingerprint("extended") == config.JobFingerprint("no_default") {
+		t.Error("expected settings the job doesn't inherit from default to be left out")
+	}
+}
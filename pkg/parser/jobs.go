@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// IsTemplateJob reports whether a job name denotes a hidden job, such as
+// .node-setup. GitLab never runs hidden jobs; they only serve as templates
+// for extends, !reference and YAML anchors.
+func IsTemplateJob(jobName string) bool {
+	return strings.HasPrefix(jobName, ".")
+}
+
+// ConcreteJobs returns the jobs GitLab runs: every job except templates and
+// jobs without a body
+func (c *GitLabConfig) ConcreteJobs() map[string]*JobConfig {
+	jobs := make(map[string]*JobConfig, len(c.Jobs))
+	for jobName, job := range c.Jobs {
+		if job != nil && !IsTemplateJob(jobName) {
+			jobs[jobName] = job
+		}
+	}
+	return jobs
+}
+
+// TemplateJobs returns the hidden jobs, which only serve as templates
+func (c *GitLabConfig) TemplateJobs() map[string]*JobConfig {
+	jobs := make(map[string]*JobConfig)
+	for jobName, job := range c.Jobs {
+		if job != nil && IsTemplateJob(jobName) {
+			jobs[jobName] = job
+		}
+	}
+	return jobs
+}
+
+// GetAllJobNames returns the names of all jobs, templates included, sorted
+func (c *GitLabConfig) GetAllJobNames() []string {
+	names := make([]string, 0, len(c.Jobs))
+	for jobName := range c.Jobs {
+		names = append(names, jobName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ConcreteJobNames returns the names of the jobs ConcreteJobs returns,
+// sorted, so checks report them in a stable order
+func (c *GitLabConfig) ConcreteJobNames() []string {
+	names := make([]string, 0, len(c.Jobs))
+	for jobName, job := range c.Jobs {
+		if job != nil && !IsTemplateJob(jobName) {
+			names = append(names, jobName)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
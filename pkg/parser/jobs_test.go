@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestJobClassification(t *testing.T) {
+	config := &GitLabConfig{
+		Jobs: map[string]*JobConfig{
+			"test":      {Script: []string{"make test"}},
+			"build":     {Script: []string{"make"}},
+			".base":     {Stage: "build"},
+			".defaults": {},
+			"empty":     nil,
+		},
+	}
+
+	names := func(jobs map[string]*JobConfig) []string {
+		var result []string
+		for jobName := range jobs {
+			result = append(result, jobName)
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	if got, want := names(config.ConcreteJobs()), []string{"build", "test"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ConcreteJobs() = %v, want %v", got, want)
+	}
+	if got, want := config.ConcreteJobNames(), []string{"build", "test"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ConcreteJobNames() = %v, want %v", got, want)
+	}
+	if got, want := names(config.TemplateJobs()), []string{".base", ".defaults"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TemplateJobs() = %v, want %v", got, want)
+	}
+	if got, want := config.GetAllJobNames(), []string{".base", ".defaults", "build", "empty", "test"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAllJobNames() = %v, want %v", got, want)
+	}
+
+	for jobName, want := range map[string]bool{".base": true, "build": false, "a.b": false} {
+		if got := IsTemplateJob(jobName); got != want {
+			t.Errorf("IsTemplateJob(%q) = %v, want %v", jobName, got, want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Parallel is a job's parallel setting, written either as a plain count of
+// instances or as a parallel:matrix of variable values
+type Parallel struct {
+	Count  int                      `yaml:"-" json:"-"`
+	Matrix []map[string]interface{} `yaml:"matrix,omitempty" json:"matrix,omitempty"`
+
+	// matrixKeys holds each matrix entry's variable names in declaration
+	// order, which GitLab uses to name the generated jobs
+	matrixKeys [][]string
+}
+
+// UnmarshalYAML accepts `parallel: 3` and the `parallel: {matrix: [...]}` form
+func (p *Parallel) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return value.Decode(&p.Count)
+	case yaml.MappingNode:
+		var raw struct {
+			Matrix []yaml.Node `yaml:"matrix"`
+		}
+		if err := value.Decode(&raw); err != nil {
+			return err
+		}
+		for _, entry := range raw.Matrix {
+			if entry.Kind != yaml.MappingNode {
+				return fmt.Errorf("line %d: parallel:matrix entries must be mappings", entry.Line)
+			}
+			variables := make(map[string]interface{}, len(entry.Content)/2)
+			keys := make([]string, 0, len(entry.Content)/2)
+			for i := 0; i+1 < len(entry.Content); i += 2 {
+				var values interface{}
+				if err := entry.Content[i+1].Decode(&values); err != nil {
+					return err
+				}
+				keys = append(keys, entry.Content[i].Value)
+				variables[entry.Content[i].Value] = values
+			}
+			p.Matrix = append(p.Matrix, variables)
+			p.matrixKeys = append(p.matrixKeys, keys)
+		}
+		return nil
+	default:
+		return fmt.Errorf("line %d: parallel must be a number or a mapping", value.Line)
+	}
+}
+
+// MarshalYAML writes the plain count when no matrix is set
+func (p *Parallel) MarshalYAML() (interface{}, error) {
+	if len(p.Matrix) == 0 {
+		return p.Count, nil
+	}
+	return map[string]interface{}{"matrix": p.Matrix}, nil
+}
+
+// MarshalJSON keeps the plain count form in JSON output when no matrix is set
+func (p *Parallel) MarshalJSON() ([]byte, error) {
+	if len(p.Matrix) == 0 {
+		return json.Marshal(p.Count)
+	}
+	return json.Marshal(map[string]interface{}{"matrix": p.Matrix})
+}
+
+// Instances returns how many jobs GitLab generates for the setting: the
+// number of matrix combinations, the count, or 1 when parallel isn't set
+func (p *Parallel) Instances() int {
+	if p == nil {
+		return 1
+	}
+	if len(p.Matrix) > 0 {
+		return len(p.expand())
+	}
+	if p.Count > 1 {
+		return p.Count
+	}
+	return 1
+}
+
+// Instances returns how many of the needed job's matrix jobs the selection
+// refers to, or 1 when it's nil
+func (p *NeedParallel) Instances() int {
+	if p == nil {
+		return 1
+	}
+	return (&Parallel{Matrix: p.Matrix}).Instances()
+}
+
+// MatrixJob is one of the jobs GitLab generates from a parallel:matrix
+type MatrixJob struct {
+	Name      string
+	Variables map[string]string
+}
+
+// ExpandMatrix returns the jobs GitLab generates from the job's
+// parallel:matrix, in order and named like GitLab names them, for example
+// `test: [16, linux]`. It returns nil when the job has no matrix.
+func (j *JobConfig) ExpandMatrix(jobName string) []MatrixJob {
+	if j == nil || j.Parallel == nil || len(j.Parallel.Matrix) == 0 {
+		return nil
+	}
+
+	combinations := j.Parallel.expand()
+	jobs := make([]MatrixJob, 0, len(combinations))
+	for _, combination := range combinations {
+		values := make([]string, len(combination))
+		variables := make(map[string]string, len(combination))
+		for i, variable := range combination {
+			values[i] = variable.value
+			variables[variable.name] = variable.value
+		}
+		jobs = append(jobs, MatrixJob{
+			Name:      fmt.Sprintf("%s: [%s]", jobName, strings.Join(values, ", ")),
+			Variables: variables,
+		})
+	}
+	return jobs
+}
+
+type matrixVariable struct {
+	name  string
+	value string
+}
+
+// expand returns every combination of the matrix's variable values, entry
+// by entry, with the variables of each in declaration order
+func (p *Parallel) expand() [][]matrixVariable {
+	var combinations [][]matrixVariable
+	for i, entry := range p.Matrix {
+		var keys []string
+		if i < len(p.matrixKeys) && len(p.matrixKeys[i]) == len(entry) {
+			keys = p.matrixKeys[i]
+		} else {
+			for key := range entry {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+		}
+
+		entryCombinations := [][]matrixVariable{{}}
+		for _, key := range keys {
+			var next [][]matrixVariable
+			for _, combination := range entryCombinations {
+				for _, value := range matrixValues(entry[key]) {
+					extended := append(append([]matrixVariable{}, combination...), matrixVariable{key, value})
+					next = append(next, extended)
+				}
+			}
+			entryCombinations = next
+		}
+		combinations = append(combinations, entryCombinations...)
+	}
+	return combinations
+}
+
+// matrixValues returns a matrix variable's values, which may be given as a
+// single value or a list
+func matrixValues(value interface{}) []string {
+	if list, ok := value.([]interface{}); ok {
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values
+	}
+	return []string{fmt.Sprint(value)}
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// expandMergeKeys replaces every `<<` merge key in the document with the keys
+// it merges in, so later decoding, including custom unmarshalers that walk a
+// mapping's nodes, only sees plain mappings. Keys written in the mapping
+// itself beat merged ones wherever they appear. Among merged mappings, the
+// earlier one wins, whether they are listed in one `<<: [*a, *b]` or in
+// several `<<` keys.
+func expandMergeKeys(node *yaml.Node) error {
+	return mergeExpander{expanded: make(map[*yaml.Node]bool)}.expand(node)
+}
+
+type mergeExpander struct {
+	// expanded tracks nodes already handled, since anchored nodes are
+	// reachable through every alias to them
+	expanded map[*yaml.Node]bool
+}
+
+func (e mergeExpander) expand(node *yaml.Node) error {
+	if node == nil || e.expanded[node] {
+		return nil
+	}
+	e.expanded[node] = true
+
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := e.expand(child); err != nil {
+				return err
+			}
+		}
+	case yaml.AliasNode:
+		return e.expand(node.Alias)
+	case yaml.MappingNode:
+		return e.expandMapping(node)
+	}
+	return nil
+}
+
+func (e mergeExpander) expandMapping(node *yaml.Node) error {
+	var explicit, sources []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if err := e.expand(value); err != nil {
+			return err
+		}
+		if !isMergeKey(key) {
+			explicit = append(explicit, key, value)
+			continue
+		}
+
+		value = resolveAlias(value)
+		switch value.Kind {
+		case yaml.MappingNode:
+			sources = append(sources, value)
+		case yaml.SequenceNode:
+			for _, item := range value.Content {
+				item = resolveAlias(item)
+				if item.Kind != yaml.MappingNode {
+					return fmt.Errorf("line %d: merge key sequences may only contain mappings", item.Line)
+				}
+				sources = append(sources, item)
+			}
+		default:
+			return fmt.Errorf("line %d: merge key value must be a mapping or a list of mappings", value.Line)
+		}
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(explicit)/2)
+	for i := 0; i < len(explicit); i += 2 {
+		seen[explicit[i].Value] = true
+	}
+	var merged []*yaml.Node
+	for _, source := range sources {
+		for i := 0; i+1 < len(source.Content); i += 2 {
+			key, value := source.Content[i], source.Content[i+1]
+			if seen[key.Value] {
+				continue
+			}
+			seen[key.Value] = true
+			merged = append(merged, key, shareNode(value))
+		}
+	}
+
+	// Merged keys go first, so the mapping's own keys keep their order
+	// after them
+	node.Content = append(merged, explicit...)
+	return nil
+}
+
+// isMergeKey reports whether a mapping key is the `<<` merge key, rather than
+// a quoted "<<" string
+func isMergeKey(key *yaml.Node) bool {
+	return key.Kind == yaml.ScalarNode && key.Value == "<<" && key.Tag != "!!str"
+}
+
+// shareNode returns a node to place a merged value under a second mapping.
+// Anchored values become aliases, since an anchor may only be defined once
+// when the document is marshaled again.
+func shareNode(value *yaml.Node) *yaml.Node {
+	if value.Anchor == "" {
+		return value
+	}
+	return &yaml.Node{Kind: yaml.AliasNode, Value: value.Anchor, Alias: value}
+}
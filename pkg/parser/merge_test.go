@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const mergeKeyConfig = `
+.defaults: &defaults
+  image: node:20.11.0
+  script: [echo default]
+  variables: &default_vars
+    NODE_ENV: test
+  retry: 2
+
+.artifacts: &junit
+  junit: report.xml
+
+override_before:
+  script: [npm test]
+  <<: *defaults
+
+override_after:
+  <<: *defaults
+  script: [npm test]
+  variables:
+    <<: *default_vars
+    CI: "true"
+
+listed:
+  <<: [*defaults, {retry: 1, stage: test}]
+  image: alpine:3.18
+
+repeated:
+  <<: {stage: build}
+  <<: *defaults
+
+matrix:
+  script: [make]
+  parallel:
+    matrix:
+      - <<: {GO: "1.22"}
+        OS: linux
+
+reports:
+  script: [make test]
+  artifacts:
+    reports:
+      <<: *junit
+      dotenv: build.env
+
+quoted:
+  "<<": literal
+  script: [make]
+`
+
+func TestParseMergeKeys(t *testing.T) {
+	for _, singlePass := range []bool{false, true} {
+		config, warnings, err := ParseWithWarnings([]byte(mergeKeyConfig), ParseOptions{DuplicateKeysAsErrors: true, SinglePass: singlePass})
+		if err != nil {
+			t.Fatalf("single pass %v: parsing config: %v", singlePass, err)
+		}
+		if len(warnings) != 0 {
+			t.Errorf("single pass %v: expected repeated merge keys not to be duplicates, got %v", singlePass, warnings)
+		}
+
+		for _, name := range []string{"override_before", "override_after"} {
+			job := config.Jobs[name]
+			if job == nil {
+				t.Fatalf("single pass %v: expected job %s", singlePass, name)
+			}
+			if !reflect.DeepEqual(job.Script, []string{"npm test"}) {
+				t.Errorf("single pass %v: expected the local script to beat the merged one in %s, got %v", singlePass, name, job.Script)
+			}
+			if job.Image == nil || job.Image.Name != "node:20.11.0" || job.Retry == nil || job.Retry.Max != 2 {
+				t.Errorf("single pass %v: expected %s to merge the defaults, got %+v", singlePass, name, job)
+			}
+		}
+		if vars := config.Jobs["override_after"].Variables; vars["NODE_ENV"] != "test" || vars["CI"] != "true" {
+			t.Errorf("single pass %v: expected nested merged variables, got %v", singlePass, vars)
+		}
+
+		// Earlier mappings in the list win over later ones
+		listed := config.Jobs["listed"]
+		if listed.Image.Name != "alpine:3.18" || listed.Retry.Max != 2 || listed.Stage != "test" {
+			t.Errorf("single pass %v: unexpected merge of a list of mappings: %+v", singlePass, listed)
+		}
+
+		repeated := config.Jobs["repeated"]
+		if repeated.Stage != "build" || !reflect.DeepEqual(repeated.Script, []string{"echo default"}) {
+			t.Errorf("single pass %v: expected both merge keys to apply, got %+v", singlePass, repeated)
+		}
+
+		matrix := config.Jobs["matrix"].Parallel
+		if matrix == nil || len(matrix.Matrix) != 1 || matrix.Matrix[0]["GO"] != "1.22" || matrix.Matrix[0]["OS"] != "linux" {
+			t.Errorf("single pass %v: expected the merged matrix variable, got %+v", singlePass, matrix)
+		}
+		if _, leaked := matrix.Matrix[0]["<<"]; leaked {
+			t.Errorf("single pass %v: merge key leaked into the matrix: %v", singlePass, matrix.Matrix[0])
+		}
+
+		reports := config.Jobs["reports"].Artifacts.Reports
+		if reports == nil || !reflect.DeepEqual(reports.JUnit, []string{"report.xml"}) || !reflect.DeepEqual(reports.Dotenv, []string{"build.env"}) {
+			t.Errorf("single pass %v: expected merged reports, got %+v", singlePass, reports)
+		}
+
+		if _, ok := config.RawData["quoted"].(map[string]interface{})["<<"]; !ok {
+			t.Errorf("single pass %v: expected a quoted \"<<\" to stay a plain key", singlePass)
+		}
+	}
+}
+
+func TestParseMergeKeyErrors(t *testing.T) {
+	_, err := Parse([]byte(`
+job:
+  <<: not-a-mapping
+  script: [make]
+`))
+	if err == nil || !strings.Contains(err.Error(), "merge key") {
+		t.Errorf("expected an error for a scalar merge value, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// PagesJobName is the job GitLab deploys to Pages without further
+	// configuration
+	PagesJobName = "pages"
+	// DefaultPagesPublishDir is the directory a Pages job publishes unless
+	// pages:publish or publish names another
+	DefaultPagesPublishDir = "public"
+)
+
+// Pages is a job's pages setting, written either as a boolean, which turns
+// any job into a Pages deployment (or, set to false, opts the pages job
+// out), or as `{publish, path_prefix, expire_in}`, which implies true
+type Pages struct {
+	Enabled    bool   `yaml:"-" json:"-"`
+	Publish    string `yaml:"publish,omitempty" json:"publish,omitempty"`
+	PathPrefix string `yaml:"path_prefix,omitempty" json:"path_prefix,omitempty"`
+	ExpireIn   string `yaml:"expire_in,omitempty" json:"expire_in,omitempty"`
+}
+
+// UnmarshalYAML accepts both `pages: true` and the object form
+func (p *Pages) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return value.Decode(&p.Enabled)
+	case yaml.MappingNode:
+		type plain Pages
+		p.Enabled = true
+		return value.Decode((*plain)(p))
+	default:
+		return fmt.Errorf("line %d: pages must be a boolean or a mapping", value.Line)
+	}
+}
+
+// MarshalYAML writes the boolean unless the object form sets something
+func (p *Pages) MarshalYAML() (interface{}, error) {
+	if !p.IsObject() {
+		return p.Enabled, nil
+	}
+	type plain Pages
+	return (*plain)(p), nil
+}
+
+// MarshalJSON writes the boolean unless the object form sets something
+func (p *Pages) MarshalJSON() ([]byte, error) {
+	if !p.IsObject() {
+		return json.Marshal(p.Enabled)
+	}
+	type plain Pages
+	return json.Marshal((*plain)(p))
+}
+
+// IsObject reports whether the setting uses the object form
+func (p *Pages) IsObject() bool {
+	return p != nil && (p.Publish != "" || p.PathPrefix != "" || p.ExpireIn != "")
+}
+
+// IsPagesJob reports whether a job deploys to GitLab Pages: the job named
+// pages, unless it sets `pages: false`, or any job setting pages, directly
+// or through extends
+func (c *GitLabConfig) IsPagesJob(jobName string) bool {
+	if c.Jobs[jobName] == nil || IsTemplateJob(jobName) {
+		return false
+	}
+	if pages := c.jobPages(jobName); pages != nil {
+		return pages.Enabled
+	}
+	return jobName == PagesJobName
+}
+
+// PagesPublishDir returns the directory a Pages job publishes: pages:publish,
+// else the older publish keyword, else public. Both are resolved through
+// extends.
+func (c *GitLabConfig) PagesPublishDir(jobName string) string {
+	if pages := c.jobPages(jobName); pages != nil && pages.Publish != "" {
+		return pages.Publish
+	}
+	for _, name := range c.extendsLineage(jobName) {
+		if job := c.Jobs[name]; job != nil && job.Publish != "" {
+			return job.Publish
+		}
+	}
+	return DefaultPagesPublishDir
+}
+
+// jobPages returns the pages setting of the job or, when unset, of the
+// nearest template it extends
+func (c *GitLabConfig) jobPages(jobName string) *Pages {
+	for _, name := range c.extendsLineage(jobName) {
+		if job := c.Jobs[name]; job != nil && job.Pages != nil {
+			return job.Pages
+		}
+	}
+	return nil
+}
+
+// extendsLineage returns the job followed by the templates it extends,
+// nearest first
+func (c *GitLabConfig) extendsLineage(jobName string) []string {
+	chain := c.ExtendsChain(jobName)
+	lineage := []string{jobName}
+	for i := len(chain) - 1; i >= 0; i-- {
+		lineage = append(lineage, chain[i])
+	}
+	return lineage
+}
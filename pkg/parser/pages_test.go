@@ -0,0 +1,77 @@
+package parser
+
+import "testing"
+
+func TestPagesJobs(t *testing.T) {
+	yamlContent := `
+pages:
+  script:
+    - mkdocs build --site-dir public
+  artifacts:
+    paths: [public]
+
+docs:
+  script:
+    - hugo --destination site
+  pages:
+    publish: site
+    path_prefix: "$CI_COMMIT_BRANCH"
+
+legacy:
+  script:
+    - make docs
+  publish: build/html
+  pages: true
+
+.pages-template:
+  pages:
+    publish: out
+
+from_template:
+  extends: .pages-template
+  script:
+    - make site
+
+opted_out:
+  script:
+    - make
+  pages: false
+
+build:
+  script:
+    - make
+`
+	config, err := Parse([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if pages := config.Jobs["docs"].Pages; pages == nil || !pages.Enabled || pages.Publish != "site" || pages.PathPrefix != "$CI_COMMIT_BRANCH" {
+		t.Errorf("Expected the object form of pages to be parsed, got %+v", pages)
+	}
+	if pages := config.Jobs["opted_out"].Pages; pages == nil || pages.Enabled {
+		t.Errorf("Expected pages: false to be parsed, got %+v", pages)
+	}
+
+	tests := []struct {
+		job        string
+		pages      bool
+		publishDir string
+	}{
+		{"pages", true, "public"},
+		{"docs", true, "site"},
+		{"legacy", true, "build/html"},
+		{"from_template", true, "out"},
+		{"opted_out", false, "public"},
+		{"build", false, "public"},
+		{".pages-template", false, "out"},
+	}
+	for _, tt := range tests {
+		if got := config.IsPagesJob(tt.job); got != tt.pages {
+			t.Errorf("IsPagesJob(%q) = %v, want %v", tt.job, got, tt.pages)
+		}
+		if got := config.PagesPublishDir(tt.job); got != tt.publishDir {
+			t.Errorf("PagesPublishDir(%q) = %q, want %q", tt.job, got, tt.publishDir)
+		}
+	}
+}
@@ -2,31 +2,119 @@ package parser
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+// ParseOptions controls how lenient parsing is
+type ParseOptions struct {
+	// DuplicateKeysAsErrors fails parsing on duplicate mapping keys. When
+	// false, duplicates are reported as warnings and the last definition wins.
+	DuplicateKeysAsErrors bool
+	// SinglePass decodes each top-level section straight from the YAML node
+	// tree instead of re-marshaling the document, which uses much less
+	// memory on very large configurations.
+	SinglePass bool
+}
+
+// Parse parses a GitLab CI configuration, failing on duplicate keys
 func Parse(data []byte) (*GitLabConfig, error) {
+	config, _, err := ParseWithWarnings(data, ParseOptions{DuplicateKeysAsErrors: true})
+	return config, err
+}
+
+// ParseWithWarnings parses a GitLab CI configuration and returns non-fatal
+// problems, such as duplicate keys, alongside it
+func ParseWithWarnings(data []byte, opts ParseOptions) (*GitLabConfig, []ParseWarning, error) {
 	// First parse with anchor/alias resolution
 	var node yaml.Node
 	if err := yaml.Unmarshal(data, &node); err != nil {
-		return nil, fmt.Errorf("parsing YAML structure: %w", err)
+		return nil, nil, fmt.Errorf("parsing YAML structure: %w", err)
+	}
+
+	// Duplicate keys must be found on the source node, since line numbers
+	// are lost once anchors are resolved
+	warnings := removeDuplicateKeys(&node)
+	if opts.DuplicateKeysAsErrors && len(warnings) > 0 {
+		return nil, warnings, fmt.Errorf("duplicate keys: %s", warnings[0])
+	}
+
+	// Merge keys are applied on the node tree, where the precedence of a
+	// mapping's own keys over merged ones doesn't depend on key order
+	if err := expandMergeKeys(&node); err != nil {
+		return nil, warnings, fmt.Errorf("resolving YAML merge keys: %w", err)
+	}
+	dropReferences(&node)
+
+	if opts.SinglePass {
+		if document := singlePassDocument(&node); document != nil {
+			config, err := decodeDocument(document)
+			if err != nil {
+				return nil, warnings, err
+			}
+			return config, warnings, nil
+		}
 	}
 
 	// Resolve anchors and aliases
 	resolvedData, err := yaml.Marshal(&node)
 	if err != nil {
-		return nil, fmt.Errorf("resolving YAML anchors: %w", err)
+		return nil, warnings, fmt.Errorf("resolving YAML anchors: %w", err)
 	}
 
 	// Parse the resolved YAML into our structure
 	var raw map[string]interface{}
 	if err := yaml.Unmarshal(resolvedData, &raw); err != nil {
+		return nil, warnings, fmt.Errorf("unmarshaling resolved YAML: %w", err)
+	}
+
+	config := buildConfig(raw, func(key string, out interface{}) error {
+		sectionBytes, _ := yaml.Marshal(raw[key])
+		return yaml.Unmarshal(sectionBytes, out)
+	})
+	return config, warnings, nil
+}
+
+// singlePassDocument returns the top-level mapping of a document that can be
+// decoded in a single pass, or nil when it isn't a mapping and has to take
+// the two-pass path
+func singlePassDocument(node *yaml.Node) *yaml.Node {
+	if node.Kind != yaml.DocumentNode || len(node.Content) != 1 {
+		return nil
+	}
+	document := node.Content[0]
+	if document.Kind != yaml.MappingNode {
+		return nil
+	}
+	return document
+}
+
+// decodeDocument builds a config from the top-level mapping, decoding each
+// section from its node. Decoding a node resolves anchors and aliases just
+// like re-marshaling the document does.
+func decodeDocument(document *yaml.Node) (*GitLabConfig, error) {
+	var raw map[string]interface{}
+	if err := document.Decode(&raw); err != nil {
 		return nil, fmt.Errorf("unmarshaling resolved YAML: %w", err)
 	}
 
+	sections := make(map[string]*yaml.Node, len(document.Content)/2)
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		sections[document.Content[i].Value] = document.Content[i+1]
+	}
+
+	return buildConfig(raw, func(key string, out interface{}) error {
+		return sections[key].Decode(out)
+	}), nil
+}
+
+// buildConfig fills a config from the decoded top-level mapping. decode
+// decodes the section under a top-level key into a typed value.
+func buildConfig(raw map[string]interface{}, decode func(key string, out interface{}) error) *GitLabConfig {
 	config := &GitLabConfig{
 		Jobs:    make(map[string]*JobConfig),
 		RawData: raw,
@@ -47,41 +135,52 @@ func Parse(data []byte) (*GitLabConfig, error) {
 				config.Variables = vars
 			}
 		case "image":
-			if img, ok := value.(string); ok {
-				config.Image = img
+			var image Image
+			if err := decode(key, &image); err == nil {
+				config.Image = &image
 			}
 		case "cache":
-			cacheBytes, _ := yaml.Marshal(value)
 			var cache Cache
-			if err := yaml.Unmarshal(cacheBytes, &cache); err == nil {
+			if err := decode(key, &cache); err == nil {
 				config.Cache = &cache
 			}
 		case "include":
 			parseInclude(value, config)
 		case "default":
-			jobBytes, _ := yaml.Marshal(value)
 			var defaultJob JobConfig
-			if err := yaml.Unmarshal(jobBytes, &defaultJob); err == nil {
+			if err := decode(key, &defaultJob); err == nil {
 				config.Default = &defaultJob
 			}
 		case "workflow":
-			workflowBytes, _ := yaml.Marshal(value)
 			var workflow Workflow
-			if err := yaml.Unmarshal(workflowBytes, &workflow); err == nil {
+			if err := decode(key, &workflow); err == nil {
 				config.Workflow = &workflow
 			}
 		default:
-			if !isReservedKeyword(key) && isJobDefinition(value) {
-				jobBytes, _ := yaml.Marshal(value)
+			// Hidden keys are kept as templates whatever keywords they
+			// set, so jobs can extend them
+			_, isMapping := value.(map[string]interface{})
+			isTemplate := strings.HasPrefix(key, ".") && isMapping
+			if !isReservedKeyword(key) && (isTemplate || isJobDefinition(value)) {
 				var job JobConfig
-				if err := yaml.Unmarshal(jobBytes, &job); err == nil {
+				if err := decode(key, &job); err == nil {
 					config.Jobs[key] = &job
 				}
 			}
 		}
 	}
 
-	return config, nil
+	return config
+}
+
+// ParseReader parses a GitLab CI configuration from r, such as stdin. Includes
+// are not resolved since there is no file to resolve them against.
+func ParseReader(r io.Reader) (*GitLabConfig, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return Parse(data)
 }
 
 // ParseFile parses a GitLab CI file and resolves its includes
@@ -100,6 +199,7 @@ func ParseFileWithResolver(filePath string, resolver *IncludeResolver) (*GitLabC
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse: %w", err)
 	}
+	config.FilePath = filePath
 
 	// Resolve includes relative to the file's directory
 	baseDir := filepath.Dir(filePath)
@@ -133,17 +233,11 @@ func parseInclude(value interface{}, config *GitLabConfig) {
 	}
 }
 
+// isReservedKeyword reports whether a top-level key is a GitLab keyword, which
+// GitLab never reads as a job whatever it contains. pages, despite its
+// special meaning, is an ordinary job name.
 func isReservedKeyword(key string) bool {
-	reserved := []string{
-		"stages", "variables", "include", "default", "before_script", "after_script",
-		"image", "services", "cache", "artifacts", "workflow",
-	}
-	for _, r := range reserved {
-		if key == r {
-			return true
-		}
-	}
-	return false
+	return globalKeywords[key]
 }
 
 func isJobDefinition(value interface{}) bool {
@@ -156,7 +250,8 @@ func isJobDefinition(value interface{}) bool {
 				key == "rules" || key == "when" || key == "artifacts" ||
 				key == "cache" || key == "variables" || key == "tags" ||
 				key == "allow_failure" || key == "retry" || key == "coverage" ||
-				key == "timeout" || key == "parallel" || key == "extends" {
+				key == "timeout" || key == "parallel" || key == "extends" ||
+				key == "trigger" {
 				return true
 			}
 		}
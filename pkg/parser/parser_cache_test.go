@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -26,7 +28,7 @@ test_job:
     - echo "test"`,
 			expectedImage: "golang:latest",
 			expectedCache: &Cache{
-				Key:   "test-key",
+				Key:   &CacheKey{Value: "test-key"},
 				Paths: []string{".cache/"},
 			},
 		},
@@ -49,8 +51,8 @@ build_job:
     - go build`,
 			expectedImage: "golang:1.21-alpine",
 			expectedCache: &Cache{
-				Key: map[string]interface{}{
-					"files": []interface{}{"go.mod", "go.sum"},
+				Key: &CacheKey{
+					Files: []string{"go.mod", "go.sum"},
 				},
 				Paths:  []string{".go/pkg/mod/"},
 				Policy: "pull-push",
@@ -77,8 +79,8 @@ test_job:
 			}
 
 			// Check image
-			if config.Image != tt.expectedImage {
-				t.Errorf("Expected image '%s', got '%s'", tt.expectedImage, config.Image)
+			if config.Image.GetName() != tt.expectedImage {
+				t.Errorf("Expected image '%s', got '%s'", tt.expectedImage, config.Image.GetName())
 			}
 
 			// Check cache
@@ -109,14 +111,8 @@ test_job:
 				}
 
 				// Check cache key (can be string or complex object)
-				if stringKey, ok := tt.expectedCache.Key.(string); ok {
-					if configKey, ok := config.Cache.Key.(string); ok {
-						if configKey != stringKey {
-							t.Errorf("Expected cache key '%s', got '%s'", stringKey, configKey)
-						}
-					} else {
-						t.Errorf("Expected string cache key '%s', got %T: %v", stringKey, config.Cache.Key, config.Cache.Key)
-					}
+				if !reflect.DeepEqual(config.Cache.Key, tt.expectedCache.Key) {
+					t.Errorf("Expected cache key %+v, got %+v", tt.expectedCache.Key, config.Cache.Key)
 				}
 			}
 		})
@@ -150,7 +146,7 @@ test_job:
 	}
 
 	expectedKey := "job-cache"
-	if keyStr, ok := job.Cache.Key.(string); !ok || keyStr != expectedKey {
+	if keyStr := job.Cache.KeyString(); keyStr != expectedKey {
 		t.Errorf("Expected job cache key '%s', got %v", expectedKey, job.Cache.Key)
 	}
 
@@ -161,3 +157,47 @@ test_job:
 		t.Errorf("Expected cache path '%s', got '%s'", expectedPaths[0], job.Cache.Paths[0])
 	}
 }
+
+func TestCacheKeyForms(t *testing.T) {
+	config, err := Parse([]byte(`
+plain:
+  script: [make]
+  cache:
+    key: $CI_COMMIT_REF_SLUG
+    paths: [.cache/]
+files:
+  script: [make]
+  cache:
+    key:
+      files: [go.mod, go.sum]
+      prefix: $CI_JOB_NAME
+    paths: [.go/]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	plain := config.Jobs["plain"].Cache
+	if plain.KeyString() != "$CI_COMMIT_REF_SLUG" || plain.KeyObject() != nil {
+		t.Errorf("Expected a plain string key, got %q / %+v", plain.KeyString(), plain.KeyObject())
+	}
+
+	files := config.Jobs["files"].Cache
+	object := files.KeyObject()
+	if files.KeyString() != "" || object == nil {
+		t.Fatalf("Expected an object key, got %q / %+v", files.KeyString(), object)
+	}
+	if len(object.Files) != 2 || object.Files[0] != "go.mod" || object.Files[1] != "go.sum" || object.Prefix != "$CI_JOB_NAME" {
+		t.Errorf("Unexpected key object %+v", object)
+	}
+
+	for name, cache := range map[string]*Cache{`"$CI_COMMIT_REF_SLUG"`: plain, `{"files":["go.mod","go.sum"],"prefix":"$CI_JOB_NAME"}`: files} {
+		data, err := json.Marshal(cache.Key)
+		if err != nil {
+			t.Fatalf("Failed to marshal key: %v", err)
+		}
+		if string(data) != name {
+			t.Errorf("Expected key to marshal as %s, got %s", name, data)
+		}
+	}
+}
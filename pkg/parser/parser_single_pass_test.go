@@ -0,0 +1,187 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSinglePassMatchesTwoPass(t *testing.T) {
+	var files []string
+	for _, pattern := range []string{"../../test/*/*.yml", "../../test/*/*/*.yml", "../../test/*/*/*/.gitlab-ci.yml"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatalf("globbing %s: %v", pattern, err)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		t.Fatal("expected test configurations to compare")
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("reading %s: %v", file, err)
+		}
+		twoPass, _, twoPassErr := ParseWithWarnings(data, ParseOptions{})
+		singlePass, _, singlePassErr := ParseWithWarnings(data, ParseOptions{SinglePass: true})
+		if (twoPassErr != nil) != (singlePassErr != nil) {
+			t.Errorf("%s: two-pass error %v, single-pass error %v", file, twoPassErr, singlePassErr)
+			continue
+		}
+		if twoPassErr != nil {
+			continue
+		}
+		// Re-marshaling sorts mapping keys, so only the single pass keeps
+		// the declaration order of matrix variables
+		for _, config := range []*GitLabConfig{twoPass, singlePass} {
+			for _, job := range config.Jobs {
+				if job.Parallel != nil {
+					job.Parallel.matrixKeys = nil
+				}
+			}
+		}
+		if !reflect.DeepEqual(twoPass, singlePass) {
+			t.Errorf("%s: single-pass parse differs from two-pass parse", file)
+		}
+	}
+}
+
+func TestParseSinglePassKeepsMatrixOrder(t *testing.T) {
+	config, _, err := ParseWithWarnings([]byte(`
+test:
+  script: [make test]
+  parallel:
+    matrix:
+      - REGION: [us-east-1]
+        PROVIDER: [aws]
+`), ParseOptions{SinglePass: true})
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	keys := config.Jobs["test"].Parallel.matrixKeys
+	if len(keys) != 1 || !reflect.DeepEqual(keys[0], []string{"REGION", "PROVIDER"}) {
+		t.Errorf("expected the declared variable order, got %v", keys)
+	}
+}
+
+func TestParseSinglePassAnchors(t *testing.T) {
+	config, _, err := ParseWithWarnings([]byte(`
+.defaults: &defaults
+  image: golang:1.22
+  tags: [docker]
+
+variables: &vars
+  GO111MODULE: "on"
+
+build:
+  <<: *defaults
+  variables: *vars
+  script: [make]
+`), ParseOptions{SinglePass: true})
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	build := config.Jobs["build"]
+	if build == nil || build.Image == nil || build.Image.Name != "golang:1.22" || len(build.Tags) != 1 {
+		t.Fatalf("expected build to merge the anchored defaults, got %+v", build)
+	}
+	if build.Variables["GO111MODULE"] != "on" {
+		t.Errorf("expected the aliased variables, got %v", build.Variables)
+	}
+}
+
+func TestParseSinglePassReferences(t *testing.T) {
+	data := []byte(`
+.env:
+  before_script: [source env.sh]
+
+build:
+  stage: build
+  before_script:
+    - !reference [.env, before_script]
+    - make deps
+  script: [make]
+`)
+	twoPass, _, err := ParseWithWarnings(data, ParseOptions{})
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+	singlePass, _, err := ParseWithWarnings(data, ParseOptions{SinglePass: true})
+	if err != nil {
+		t.Fatalf("parsing config in a single pass: %v", err)
+	}
+	if !reflect.DeepEqual(twoPass, singlePass) {
+		t.Errorf("expected both paths to parse !reference documents alike, got %+v and %+v", twoPass.Jobs, singlePass.Jobs)
+	}
+
+	build := singlePass.Jobs["build"]
+	if build == nil {
+		t.Fatal("expected the job using !reference to be parsed")
+	}
+	if build.Stage != "build" || !reflect.DeepEqual(build.BeforeScript, []string{"make deps"}) {
+		t.Errorf("expected the job without the referenced commands, got %+v", build)
+	}
+}
+
+// largeConfig generates a configuration with the given number of jobs,
+// sharing a template through anchors
+func largeConfig(jobs int) []byte {
+	var b strings.Builder
+	b.WriteString("stages: [build, test, deploy]\n\n")
+	b.WriteString(".base: &base\n  image: golang:1.22\n  tags: [docker]\n  cache:\n    key: go\n    paths: [.go/]\n\n")
+	for i := 0; i < jobs; i++ {
+		fmt.Fprintf(&b, "job_%d:\n  <<: *base\n  stage: test\n  variables:\n    SHARD: \"%d\"\n", i, i)
+		fmt.Fprintf(&b, "  script:\n    - go test ./pkg/%d/...\n    - echo done\n", i)
+		fmt.Fprintf(&b, "  rules:\n    - if: $CI_COMMIT_BRANCH == \"main\"\n  artifacts:\n    paths: [out/%d/]\n    expire_in: 1 week\n\n", i)
+	}
+	return []byte(b.String())
+}
+
+// BenchmarkParseLargeConfig compares the two-pass and single-pass parse
+// paths on a configuration of several tens of thousands of lines
+func BenchmarkParseLargeConfig(b *testing.B) {
+	data := largeConfig(3000)
+
+	for _, mode := range []struct {
+		name string
+		opts ParseOptions
+	}{
+		{"two-pass", ParseOptions{}},
+		{"single-pass", ParseOptions{SinglePass: true}},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := ParseWithWarnings(data, mode.opts); err != nil {
+					b.Fatalf("parsing config: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrorKeepsWarnings(t *testing.T) {
+	data := []byte(`
+variables:
+  MODE: fast
+  MODE: slow
+? [not, a, key]
+: value
+`)
+	for _, singlePass := range []bool{false, true} {
+		config, warnings, err := ParseWithWarnings(data, ParseOptions{SinglePass: singlePass})
+		if err == nil {
+			t.Fatalf("single pass %v: expected a decoding error, got config %+v", singlePass, config)
+		}
+		if len(warnings) != 1 || warnings[0].Line != 3 {
+			t.Errorf("single pass %v: expected the duplicate key warning alongside the error, got %+v", singlePass, warnings)
+		}
+	}
+}
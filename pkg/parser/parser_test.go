@@ -2,7 +2,11 @@ package parser
 
 import (
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestParse(t *testing.T) {
@@ -34,8 +38,8 @@ func TestParse(t *testing.T) {
 	if config.Default == nil {
 		t.Error("expected default config to be set")
 	} else {
-		if config.Default.Image != "node:16" {
-			t.Errorf("expected default image to be node:16, got %s", config.Default.Image)
+		if config.Default.Image.GetName() != "node:16" {
+			t.Errorf("expected default image to be node:16, got %s", config.Default.Image.GetName())
 		}
 	}
 
@@ -61,12 +65,8 @@ func TestParse(t *testing.T) {
 	} else {
 		if testUnitJob.Needs == nil {
 			t.Error("expected test:unit to have needs")
-		} else if needsSlice, ok := testUnitJob.Needs.([]interface{}); ok {
-			if len(needsSlice) != 1 || needsSlice[0] != "build" {
-				t.Errorf("expected test:unit to need 'build', got %v", needsSlice)
-			}
-		} else {
-			t.Errorf("unexpected needs type: %T", testUnitJob.Needs)
+		} else if len(testUnitJob.Needs) != 1 || testUnitJob.Needs[0].Job != "build" {
+			t.Errorf("expected test:unit to need 'build', got %+v", testUnitJob.Needs)
 		}
 	}
 
@@ -134,24 +134,58 @@ deploy:
 	}
 
 	testJob := config.Jobs["test"]
-	if needsSlice, ok := testJob.Needs.([]interface{}); ok {
-		if len(needsSlice) != 1 {
-			t.Errorf("expected test job to have 1 need, got %d", len(needsSlice))
-		}
-		if needMap, ok := needsSlice[0].(map[string]interface{}); ok {
-			if needMap["job"] != "build" || needMap["optional"] != true {
-				t.Errorf("unexpected needs for test job: %+v", needMap)
-			}
-		}
-	} else {
-		t.Errorf("unexpected needs type: %T", testJob.Needs)
+	if len(testJob.Needs) != 1 {
+		t.Fatalf("expected test job to have 1 need, got %d", len(testJob.Needs))
+	}
+	if need := testJob.Needs[0]; need.Job != "build" || !need.Optional {
+		t.Errorf("unexpected needs for test job: %+v", need)
 	}
 
 	deployJob := config.Jobs["deploy"]
-	if needsSlice, ok := deployJob.Needs.([]interface{}); ok {
-		if len(needsSlice) != 2 {
-			t.Errorf("expected deploy job to have 2 needs, got %d", len(needsSlice))
-		}
+	if names := deployJob.NeedNames(); len(names) != 2 || names[0] != "build" || names[1] != "test" {
+		t.Errorf("expected deploy job to need build and test, got %v", names)
+	}
+}
+
+func TestParseNeedsArtifacts(t *testing.T) {
+	yaml := `
+build:
+  script: [make]
+lint:
+  needs:
+    - job: build
+      artifacts: false
+    - build
+    - []
+  script: [lint]
+empty:
+  needs: []
+  script: [true]
+`
+	config, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+	if _, exists := config.Jobs["lint"]; exists {
+		t.Error("expected a needs entry that is neither a string nor a mapping to be rejected")
+	}
+
+	config, err = Parse([]byte(strings.Replace(yaml, "    - []\n", "", 1)))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+	needs := config.Jobs["lint"].Needs
+	if len(needs) != 2 {
+		t.Fatalf("expected 2 needs, got %+v", needs)
+	}
+	if needs[0].DownloadsArtifacts() {
+		t.Error("expected artifacts: false to disable artifact downloads")
+	}
+	if !needs[1].DownloadsArtifacts() {
+		t.Error("expected string needs to download artifacts")
+	}
+	if empty := config.Jobs["empty"].Needs; empty == nil || len(empty) != 0 {
+		t.Errorf("expected 'needs: []' to be preserved as empty needs, got %#v", empty)
 	}
 }
 
@@ -275,3 +309,313 @@ job_with_underscores:
 		}
 	}
 }
+
+func TestParseReader(t *testing.T) {
+	config, err := ParseReader(strings.NewReader(`
+build:
+  script: [make]
+`))
+	if err != nil {
+		t.Fatalf("ParseReader failed: %v", err)
+	}
+	if _, exists := config.Jobs["build"]; !exists {
+		t.Error("expected build job to be parsed")
+	}
+}
+
+func TestParseAllowFailure(t *testing.T) {
+	config, err := Parse([]byte(`
+blanket:
+  script: [make]
+  allow_failure: true
+strict:
+  script: [make]
+  allow_failure: false
+codes:
+  script: [make]
+  allow_failure:
+    exit_codes: [1, 137]
+code:
+  script: [make]
+  allow_failure:
+    exit_codes: 42
+unset:
+  script: [make]
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	if !config.Jobs["blanket"].AllowFailure.IsBlanket() {
+		t.Error("expected allow_failure: true to be a blanket allowance")
+	}
+	if af := config.Jobs["strict"].AllowFailure; af == nil || af.Allowed {
+		t.Errorf("expected allow_failure: false to be kept, got %+v", af)
+	}
+	if af := config.Jobs["codes"].AllowFailure; af.IsBlanket() || !af.Allowed || len(af.ExitCodes) != 2 {
+		t.Errorf("expected exit codes 1 and 137, got %+v", af)
+	}
+	if af := config.Jobs["code"].AllowFailure; len(af.ExitCodes) != 1 || af.ExitCodes[0] != 42 {
+		t.Errorf("expected a single exit code to be normalized to a list, got %+v", af)
+	}
+	if config.Jobs["unset"].AllowFailure.IsBlanket() {
+		t.Error("expected a job without allow_failure not to allow failure")
+	}
+}
+
+func TestParseRetry(t *testing.T) {
+	config, err := Parse([]byte(`
+count:
+  script: [make]
+  retry: 2
+reason:
+  script: [make]
+  retry:
+    max: 1
+    when: runner_system_failure
+reasons:
+  script: [make]
+  retry:
+    max: 2
+    when: [runner_system_failure, stuck_or_timeout_failure]
+    exit_codes: 137
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	if retry := config.Jobs["count"].Retry; retry == nil || retry.Max != 2 || len(retry.When) != 0 {
+		t.Errorf("expected a plain retry count of 2, got %+v", retry)
+	}
+	if retry := config.Jobs["reason"].Retry; retry.Max != 1 || len(retry.When) != 1 || retry.When[0] != "runner_system_failure" {
+		t.Errorf("expected a single when reason to be normalized to a list, got %+v", retry)
+	}
+	retry := config.Jobs["reasons"].Retry
+	if len(retry.When) != 2 || len(retry.ExitCodes) != 1 || retry.ExitCodes[0] != 137 {
+		t.Errorf("expected two reasons and exit code 137, got %+v", retry)
+	}
+}
+
+func TestParseParallel(t *testing.T) {
+	config, err := Parse([]byte(`
+shards:
+  script: [make test]
+  parallel: 3
+matrix:
+  script: [make test]
+  parallel:
+    matrix:
+      - PROVIDER: aws
+        REGION: [us-east-1, eu-west-1]
+      - PROVIDER: [gcp, azure]
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	if parallel := config.Jobs["shards"].Parallel; parallel == nil || parallel.Count != 3 || parallel.Instances() != 3 {
+		t.Errorf("expected a plain count of 3, got %+v", parallel)
+	}
+	if instances := config.Jobs["matrix"].Parallel.Instances(); instances != 4 {
+		t.Errorf("expected 4 matrix combinations, got %d", instances)
+	}
+	if instances := (&JobConfig{}).Parallel.Instances(); instances != 1 {
+		t.Errorf("expected a job without parallel to run once, got %d", instances)
+	}
+
+	jobs := config.Jobs["matrix"].ExpandMatrix("matrix")
+	names := make([]string, len(jobs))
+	for i, job := range jobs {
+		names[i] = job.Name
+	}
+	expected := []string{"matrix: [aws, us-east-1]", "matrix: [aws, eu-west-1]", "matrix: [gcp]", "matrix: [azure]"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected generated jobs %v, got %v", expected, names)
+	}
+	if jobs[1].Variables["PROVIDER"] != "aws" || jobs[1].Variables["REGION"] != "eu-west-1" {
+		t.Errorf("expected the matrix variables of the second job, got %v", jobs[1].Variables)
+	}
+	if config.Jobs["shards"].ExpandMatrix("shards") != nil {
+		t.Error("expected no generated jobs without a matrix")
+	}
+}
+
+func TestParseReports(t *testing.T) {
+	config, err := Parse([]byte(`
+test:
+  script: [make test]
+  artifacts:
+    reports:
+      junit: [unit.xml, integration.xml]
+      dotenv: build.env
+      coverage_report:
+        coverage_format: cobertura
+        path: coverage.xml
+      sast: gl-sast-report.json
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	reports := config.Jobs["test"].Artifacts.Reports
+	if len(reports.JUnit) != 2 || len(reports.Dotenv) != 1 || reports.Dotenv[0] != "build.env" {
+		t.Errorf("expected junit and dotenv paths, got %+v", reports)
+	}
+	if reports.CoverageReport == nil || reports.CoverageReport.CoverageFormat != "cobertura" {
+		t.Errorf("expected a cobertura coverage report, got %+v", reports.CoverageReport)
+	}
+	if !reports.Has("sast") || reports.Has("codequality") {
+		t.Errorf("expected only the sast report among other types, got %v", reports.Other)
+	}
+	if types := reports.Types(); !reflect.DeepEqual(types, []string{"coverage_report", "dotenv", "junit", "sast"}) {
+		t.Errorf("expected sorted report types, got %v", types)
+	}
+	expected := []string{"coverage.xml", "build.env", "unit.xml", "integration.xml", "gl-sast-report.json"}
+	if paths := reports.Paths(); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected report paths %v, got %v", expected, paths)
+	}
+
+	out, err := yaml.Marshal(reports)
+	if err != nil || !strings.Contains(string(out), "coverage_format: cobertura") || !strings.Contains(string(out), "sast:") {
+		t.Errorf("expected reports to marshal back to a mapping, got %s (%v)", out, err)
+	}
+}
+
+func TestParseSecrets(t *testing.T) {
+	config, err := Parse([]byte(`
+deploy:
+  script: [./deploy.sh]
+  id_tokens:
+    VAULT_ID_TOKEN:
+      aud: https://vault.example.com
+  secrets:
+    DATABASE_PASSWORD:
+      vault: production/db/password@ops
+      file: false
+      token: $VAULT_ID_TOKEN
+    API_KEY:
+      vault: api/key
+    TLS_CERT:
+      vault:
+        engine:
+          name: kv-v1
+          path: certs
+        path: production/tls
+        field: cert
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	secrets := config.Jobs["deploy"].Secrets
+	if len(secrets) != 3 {
+		t.Fatalf("expected 3 secrets, got %+v", secrets)
+	}
+
+	password := secrets["DATABASE_PASSWORD"]
+	want := VaultSecret{Engine: VaultEngine{Name: "kv-v2", Path: "ops"}, Path: "production/db", Field: "password"}
+	if password.Vault == nil || *password.Vault != want {
+		t.Errorf("expected %+v, got %+v", want, password.Vault)
+	}
+	if password.File == nil || *password.File || password.Token != "$VAULT_ID_TOKEN" {
+		t.Errorf("expected file: false and the token to be kept, got %+v", password)
+	}
+	if key := secrets["API_KEY"].Vault; key.Engine.Path != "secret" || key.Path != "api" || key.Field != "key" {
+		t.Errorf("expected the default secret engine path, got %+v", key)
+	}
+	if cert := secrets["TLS_CERT"].Vault; cert.Engine.Name != "kv-v1" || cert.Engine.Path != "certs" || cert.Field != "cert" {
+		t.Errorf("expected the object form to be decoded, got %+v", cert)
+	}
+
+	config, err = Parse([]byte("job:\n  script: [make]\n  secrets:\n    X:\n      vault: nofield\n"))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+	if job := config.Jobs["job"]; job == nil || job.Secrets["X"].Vault.Field != "" {
+		t.Errorf("expected a vault reference without a field to be kept with an empty field, got %+v", job)
+	}
+}
+
+func TestParseHooksAndGitVariables(t *testing.T) {
+	config, err := Parse([]byte(`
+variables:
+  GIT_DEPTH: "0"
+
+default:
+  hooks:
+    pre_get_sources_script:
+      - git config --global http.postBuffer 524288000
+
+.shallow:
+  variables:
+    GIT_DEPTH: 10
+
+build:
+  extends: .shallow
+  script: [make]
+
+deploy:
+  variables:
+    GIT_STRATEGY: None
+  inherit:
+    default: false
+  script: [./deploy.sh]
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	if hooks := config.JobHooks("build"); hooks == nil || len(hooks.PreGetSourcesScript) != 1 {
+		t.Errorf("expected build to inherit the default hooks, got %+v", hooks)
+	}
+	if hooks := config.JobHooks("deploy"); hooks != nil {
+		t.Errorf("expected deploy to opt out of the default hooks, got %+v", hooks)
+	}
+
+	if depth, ok := config.GitDepth("build"); !ok || depth != 10 {
+		t.Errorf("expected GIT_DEPTH 10 from the template, got %d (%v)", depth, ok)
+	}
+	if depth, ok := config.GitDepth("deploy"); !ok || depth != 0 {
+		t.Errorf("expected the global GIT_DEPTH 0, got %d (%v)", depth, ok)
+	}
+	if strategy := config.GitStrategy("deploy"); strategy != "none" {
+		t.Errorf("expected GIT_STRATEGY none, got %q", strategy)
+	}
+	if strategy := config.GitStrategy("build"); strategy != "" {
+		t.Errorf("expected no GIT_STRATEGY, got %q", strategy)
+	}
+}
+
+func TestParseServices(t *testing.T) {
+	config, err := Parse([]byte(`
+test:
+  services:
+    - redis:7.2
+    - name: postgres:16.2
+      alias: db
+      entrypoint: ["docker-entrypoint.sh"]
+      command: ["postgres", "-c", "fsync=off"]
+      variables:
+        POSTGRES_PASSWORD: test
+  script: [make test]
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	job := config.Jobs["test"]
+	if job == nil {
+		t.Fatal("expected the job with object-form services to be parsed")
+	}
+	if len(job.Services) != 2 {
+		t.Fatalf("expected 2 services, got %+v", job.Services)
+	}
+	if job.Services[0].Name != "redis:7.2" || job.Services[0].Alias != "" {
+		t.Errorf("unexpected short-form service: %+v", job.Services[0])
+	}
+	service := job.Services[1]
+	if service.Name != "postgres:16.2" || service.Alias != "db" || len(service.Command) != 3 ||
+		len(service.Entrypoint) != 1 || service.Variables["POSTGRES_PASSWORD"] != "test" {
+		t.Errorf("unexpected object-form service: %+v", service)
+	}
+}
@@ -244,3 +244,16 @@ func getJobNames(jobs map[string]*JobConfig) []string {
 	}
 	return names
 }
+
+func TestIsReservedKeyword(t *testing.T) {
+	for _, key := range []string{"image", "cache", "services", "variables", "workflow", "default", "spec"} {
+		if !isReservedKeyword(key) {
+			t.Errorf("expected %q to be reserved", key)
+		}
+	}
+	for _, key := range []string{"pages", "artifacts", "build", "hooks"} {
+		if isReservedKeyword(key) {
+			t.Errorf("expected %q to be usable as a job name", key)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MatchPattern reports whether str matches a simple glob pattern, where *
+// matches any run of characters and everything else matches itself, so job
+// names such as build.x or test[1] can be used as they are
+func MatchPattern(pattern, str string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == str
+	}
+
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$").MatchString(str)
+}
@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		str     string
+		match   bool
+	}{
+		{"exact", "exact", true},
+		{"exact", "not-exact", false},
+		{"prefix-*", "prefix-test", true},
+		{"prefix-*", "prefix-", true},
+		{"prefix-*", "other-test", false},
+		{"*-suffix", "test-suffix", true},
+		{"*-suffix", "-suffix", true},
+		{"*-suffix", "test-other", false},
+		{"*-middle-*", "test-middle-part", true},
+		{"*-middle-*", "-middle-", true},
+		{"*-middle-*", "test-other-part", false},
+		{"*", "anything", true},
+		{"*", "", true},
+		{"build.x", "build.x", true},
+		{"build.x", "buildax", false},
+		{"build.*", "build.linux", true},
+		{"build.*", "buildx", false},
+		{"test[1]", "test[1]", true},
+		{"test[1]-*", "test[1]-unit", true},
+		{"test[1]-*", "test1-unit", false},
+		{"variables.(A|B)*", "variables.A", false},
+	}
+
+	for _, tt := range tests {
+		if result := MatchPattern(tt.pattern, tt.str); result != tt.match {
+			t.Errorf("MatchPattern(%s, %s) = %v, want %v",
+				tt.pattern, tt.str, result, tt.match)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package parser
+
+import "gopkg.in/yaml.v3"
+
+// referenceTag is the tag of GitLab's !reference, which reuses a value from
+// another job or template
+const referenceTag = "!reference"
+
+// dropReferences removes the values tagged !reference from the document:
+// sequence items and mapping entries alike. Their targets are often in
+// included files, which aren't loaded yet, so jobs using them are parsed
+// without the referenced values rather than not at all.
+func dropReferences(node *yaml.Node) {
+	dropReferencesIn(node, make(map[*yaml.Node]bool))
+}
+
+func dropReferencesIn(node *yaml.Node, visited map[*yaml.Node]bool) {
+	if node == nil || visited[node] {
+		return
+	}
+	visited[node] = true
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			dropReferencesIn(child, visited)
+		}
+	case yaml.AliasNode:
+		dropReferencesIn(node.Alias, visited)
+	case yaml.SequenceNode:
+		kept := node.Content[:0]
+		for _, item := range node.Content {
+			if item.Tag == referenceTag {
+				continue
+			}
+			dropReferencesIn(item, visited)
+			kept = append(kept, item)
+		}
+		node.Content = kept
+	case yaml.MappingNode:
+		kept := node.Content[:0]
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Tag == referenceTag {
+				continue
+			}
+			dropReferencesIn(value, visited)
+			kept = append(kept, key, value)
+		}
+		node.Content = kept
+	}
+}
@@ -1,13 +1,17 @@
 package parser
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"time"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/httpretry"
 )
 
 // IncludeResolver handles resolution of different include types
@@ -16,6 +20,10 @@ type IncludeResolver struct {
 	cache        map[string][]byte
 	gitlabAPIURL string
 	gitlabToken  string
+	localFiles   []string
+	// parseCache holds parsed included files by the SHA-256 of their
+	// content; nil unless EnableParseCache was called
+	parseCache map[[sha256.Size]byte]*GitLabConfig
 }
 
 // NewIncludeResolver creates a new include resolver with optional GitLab API configuration
@@ -30,6 +38,51 @@ func NewIncludeResolver(gitlabAPIURL, gitlabToken string) *IncludeResolver {
 	}
 }
 
+// EnableParseCache makes the resolver parse each distinct included content
+// only once, which speeds up resolving the same templates repeatedly. Configs
+// merged from a cached include share their jobs and other nested values, so
+// callers that modify parsed configs in place shouldn't enable it.
+func (r *IncludeResolver) EnableParseCache() {
+	if r.parseCache == nil {
+		r.parseCache = make(map[[sha256.Size]byte]*GitLabConfig)
+	}
+}
+
+// parseIncluded parses included content, reusing an earlier parse of the
+// same content when the parse cache is enabled. Cached configs are copied so
+// merging into the result doesn't modify the cache.
+func (r *IncludeResolver) parseIncluded(data []byte) (*GitLabConfig, error) {
+	if r.parseCache == nil {
+		return Parse(data)
+	}
+
+	key := sha256.Sum256(data)
+	cached, found := r.parseCache[key]
+	if !found {
+		config, err := Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		r.parseCache[key] = config
+		cached = config
+	}
+
+	config := *cached
+	config.Jobs = make(map[string]*JobConfig, len(cached.Jobs))
+	for jobName, job := range cached.Jobs {
+		config.Jobs[jobName] = job
+	}
+	if cached.Variables != nil {
+		config.Variables = make(map[string]interface{}, len(cached.Variables))
+		for key, value := range cached.Variables {
+			config.Variables[key] = value
+		}
+	}
+	config.JobSources = nil
+	config.ShadowedJobs = nil
+	return &config, nil
+}
+
 // ResolveIncludes resolves and merges include files into the configuration
 func ResolveIncludes(config *GitLabConfig, baseDir string) error {
 	resolver := NewIncludeResolver("", "")
@@ -38,6 +91,20 @@ func ResolveIncludes(config *GitLabConfig, baseDir string) error {
 
 // ResolveIncludesWithResolver resolves includes using a custom resolver
 func ResolveIncludesWithResolver(config *GitLabConfig, baseDir string, resolver *IncludeResolver) error {
+	// The file's own jobs, variables and stages override those of its
+	// includes, so they're set aside and applied once the includes are merged
+	own := &GitLabConfig{Stages: config.Stages, Variables: make(map[string]interface{}, len(config.Variables))}
+	for key, value := range config.Variables {
+		own.Variables[key] = value
+	}
+	ownJobs := config.Jobs
+	ownSources := make(map[string]string, len(ownJobs))
+	for jobName := range ownJobs {
+		ownSources[jobName] = config.JobSource(jobName)
+	}
+	config.Jobs = make(map[string]*JobConfig, len(ownJobs))
+	config.Stages = nil
+
 	for _, include := range config.Include {
 		var data []byte
 		var err error
@@ -64,19 +131,88 @@ func ResolveIncludesWithResolver(config *GitLabConfig, baseDir string, resolver
 		}
 
 		if data != nil {
-			if err := resolver.mergeIncludedData(config, data, baseDir); err != nil {
+			if err := resolver.mergeIncludedData(config, data, baseDir, includeSource(include)); err != nil {
 				continue
 			}
 		}
 	}
+	mergeIncludedGlobals(config, own)
+	applyOwnJobs(config, ownJobs, ownSources)
 	return nil
 }
 
-// resolveLocalInclude reads a local file
+// applyOwnJobs merges a file's own jobs over the jobs of its includes. A job
+// the includes also define keeps the included keywords it doesn't set
+// itself, and the included definition is recorded in config.ShadowedJobs.
+func applyOwnJobs(config *GitLabConfig, ownJobs map[string]*JobConfig, ownSources map[string]string) {
+	for jobName, job := range ownJobs {
+		if included, exists := config.Jobs[jobName]; exists && included != nil && job != nil {
+			if config.ShadowedJobs == nil {
+				config.ShadowedJobs = make(map[string][]JobDefinition)
+			}
+			config.ShadowedJobs[jobName] = append(config.ShadowedJobs[jobName], JobDefinition{Source: config.JobSource(jobName), Job: included})
+			job = overrideJob(included, job)
+		}
+		config.Jobs[jobName] = job
+		setJobSource(config, jobName, ownSources[jobName])
+	}
+}
+
+// overrideJob returns base with every keyword override sets replaced by
+// override's value, the way GitLab merges a job over an included one.
+// Variables are merged per key, override's winning.
+func overrideJob(base, override *JobConfig) *JobConfig {
+	merged := *base
+	target := reflect.ValueOf(&merged).Elem()
+	source := reflect.ValueOf(override).Elem()
+	for field := 0; field < target.NumField(); field++ {
+		if !source.Field(field).IsZero() {
+			target.Field(field).Set(source.Field(field))
+		}
+	}
+	if len(base.Variables) > 0 && len(override.Variables) > 0 {
+		merged.Variables = make(map[string]interface{}, len(base.Variables)+len(override.Variables))
+		for key, value := range base.Variables {
+			merged.Variables[key] = value
+		}
+		for key, value := range override.Variables {
+			merged.Variables[key] = value
+		}
+	}
+	return &merged
+}
+
+// includeSource describes where an include is read from
+func includeSource(include Include) string {
+	switch {
+	case include.Local != "":
+		return include.Local
+	case include.Remote != "":
+		return include.Remote
+	case include.Template != "":
+		return "template:" + include.Template
+	case include.Project != "" && len(include.File) > 0:
+		source := include.Project + ":" + include.File[0]
+		if include.Ref != "" {
+			source += "@" + include.Ref
+		}
+		return source
+	}
+	return ""
+}
+
+// resolveLocalInclude reads a local file, recording its path even when it
+// doesn't exist yet so watchers notice when it's created
 func (r *IncludeResolver) resolveLocalInclude(path string) ([]byte, error) {
+	r.localFiles = append(r.localFiles, path)
 	return os.ReadFile(path)
 }
 
+// LocalFiles returns the local include paths this resolver has read
+func (r *IncludeResolver) LocalFiles() []string {
+	return append([]string(nil), r.localFiles...)
+}
+
 // resolveRemoteInclude fetches a remote file via HTTP/HTTPS
 func (r *IncludeResolver) resolveRemoteInclude(url string) ([]byte, error) {
 	// Check cache first
@@ -84,7 +220,12 @@ func (r *IncludeResolver) resolveRemoteInclude(url string) ([]byte, error) {
 		return cached, nil
 	}
 
-	resp, err := r.httpClient.Get(url)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for remote include: %w", err)
+	}
+
+	resp, err := httpretry.DefaultPolicy.Do(r.httpClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch remote include %s: %w", url, err)
 	}
@@ -152,7 +293,7 @@ func (r *IncludeResolver) resolveProjectInclude(project, file, ref string) ([]by
 		req.Header.Set("Authorization", "Bearer "+r.gitlabToken)
 	}
 
-	resp, err := r.httpClient.Do(req)
+	resp, err := httpretry.DefaultPolicy.Do(r.httpClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch project include %s/%s: %w", project, file, err)
 	}
@@ -172,49 +313,186 @@ func (r *IncludeResolver) resolveProjectInclude(project, file, ref string) ([]by
 	return data, nil
 }
 
-// mergeIncludedData merges included YAML data into the configuration
-func (r *IncludeResolver) mergeIncludedData(config *GitLabConfig, data []byte, baseDir string) error {
-	includedConfig, err := Parse(data)
+// mergeIncludedData merges included YAML data into the configuration,
+// recording source as the origin of its jobs
+func (r *IncludeResolver) mergeIncludedData(config *GitLabConfig, data []byte, baseDir, source string) error {
+	includedConfig, err := r.parseIncluded(data)
 	if err != nil {
 		return fmt.Errorf("failed to parse included data: %w", err)
 	}
 
-	// Merge included configuration into main config
-	// Jobs from includes are added (later includes can override earlier ones)
-	for jobName, job := range includedConfig.Jobs {
-		if config.Jobs == nil {
-			config.Jobs = make(map[string]*JobConfig)
+	// Includes of the included file are resolved first, so that its own jobs
+	// override theirs before it's merged
+	if len(includedConfig.Include) > 0 {
+		if err := ResolveIncludesWithResolver(includedConfig, baseDir, r); err != nil {
+			return err
 		}
-		config.Jobs[jobName] = job
 	}
 
-	// Merge variables (included variables are overridden by main file)
-	if includedConfig.Variables != nil && config.Variables == nil {
-		config.Variables = includedConfig.Variables
+	// Jobs from includes are added and, like the included variables,
+	// override those of earlier includes, and stage lists are combined. The
+	// including file's own jobs and globals are applied once all its
+	// includes are merged.
+	mergeConfig(config, includedConfig, false)
+	for jobName := range includedConfig.Jobs {
+		setJobSource(config, jobName, nestedSource(includedConfig.JobSource(jobName), source))
 	}
+	for jobName, definitions := range includedConfig.ShadowedJobs {
+		if config.ShadowedJobs == nil {
+			config.ShadowedJobs = make(map[string][]JobDefinition)
+		}
+		for _, definition := range definitions {
+			definition.Source = nestedSource(definition.Source, source)
+			config.ShadowedJobs[jobName] = append(config.ShadowedJobs[jobName], definition)
+		}
+	}
+
+	return nil
+}
 
-	// Stages are typically only defined in the main file, but merge if needed
-	if len(config.Stages) == 0 && len(includedConfig.Stages) > 0 {
-		config.Stages = includedConfig.Stages
+// nestedSource returns the source of a job merged from an include: its own
+// nested include, or the include itself when it defines the job
+func nestedSource(jobSource, includeSource string) string {
+	if jobSource == "" {
+		return includeSource
 	}
+	return jobSource
+}
 
-	// Default job config
-	if config.Default == nil && includedConfig.Default != nil {
-		config.Default = includedConfig.Default
+// mergeIncludedGlobals merges the variables and stages of src, a file that
+// takes precedence over the ones merged so far, into dst. Variables are
+// combined, with src's values winning per key. Stage lists are combined in
+// src's order: stages only dst defines are inserted after the nearest stage
+// preceding them in dst, so partial stage lists combine without duplicates.
+func mergeIncludedGlobals(dst, src *GitLabConfig) {
+	if len(src.Variables) > 0 {
+		if dst.Variables == nil {
+			dst.Variables = make(map[string]interface{}, len(src.Variables))
+		}
+		for key, value := range src.Variables {
+			dst.Variables[key] = value
+		}
+	}
+	if len(src.Stages) > 0 {
+		dst.Stages = mergeStages(src.Stages, dst.Stages)
 	}
+}
 
-	// Recursively process includes from the included file
-	if len(includedConfig.Include) > 0 {
-		if err := ResolveIncludesWithResolver(includedConfig, baseDir, r); err != nil {
-			return err
+// mergeStages inserts the stages of other missing from stages, keeping both
+// lists' order where they agree and stages' order where they don't
+func mergeStages(stages, other []string) []string {
+	merged := append([]string(nil), stages...)
+	insertAt := 0
+	for _, stage := range other {
+		if i := indexOf(merged, stage); i >= 0 {
+			insertAt = i + 1
+			continue
+		}
+		merged = append(merged, "")
+		copy(merged[insertAt+1:], merged[insertAt:])
+		merged[insertAt] = stage
+		insertAt++
+	}
+	return merged
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// MergeConfigs merges configurations in order into a new configuration, as
+// if each were included after the previous one. On conflicts the last
+// configuration wins: its jobs replace same-named jobs, its variables
+// override same-named variables, and its stages, default, workflow, image and
+// cache replace earlier ones. Includes are concatenated.
+func MergeConfigs(configs ...*GitLabConfig) *GitLabConfig {
+	merged := &GitLabConfig{
+		Jobs:    make(map[string]*JobConfig),
+		RawData: make(map[string]interface{}),
+	}
+	for _, config := range configs {
+		if config != nil {
+			mergeConfig(merged, config, true)
 		}
-		// Then merge any additional jobs found
-		for jobName, job := range includedConfig.Jobs {
-			if _, exists := config.Jobs[jobName]; !exists {
-				config.Jobs[jobName] = job
+	}
+	return merged
+}
+
+// mergeConfig merges src into dst. Jobs from src always replace same-named
+// jobs in dst. When srcWins is set, src's global settings replace dst's;
+// otherwise src is an include: its variables and stages are merged as by
+// mergeIncludedGlobals, its default only fills in a missing one, and
+// replaced jobs are recorded in dst.ShadowedJobs.
+func mergeConfig(dst, src *GitLabConfig, srcWins bool) {
+	if dst.Jobs == nil {
+		dst.Jobs = make(map[string]*JobConfig)
+	}
+	for jobName, job := range src.Jobs {
+		if existing, exists := dst.Jobs[jobName]; exists && !srcWins {
+			if dst.ShadowedJobs == nil {
+				dst.ShadowedJobs = make(map[string][]JobDefinition)
 			}
+			dst.ShadowedJobs[jobName] = append(dst.ShadowedJobs[jobName], JobDefinition{Source: dst.JobSource(jobName), Job: existing})
 		}
+		dst.Jobs[jobName] = job
+		setJobSource(dst, jobName, src.JobSource(jobName))
 	}
 
-	return nil
+	if !srcWins {
+		mergeIncludedGlobals(dst, src)
+		if dst.Default == nil && src.Default != nil {
+			dst.Default = src.Default
+		}
+		return
+	}
+
+	if len(src.Variables) > 0 {
+		if dst.Variables == nil {
+			dst.Variables = make(map[string]interface{})
+		}
+		for key, value := range src.Variables {
+			dst.Variables[key] = value
+		}
+	}
+	if len(src.Stages) > 0 {
+		dst.Stages = src.Stages
+	}
+	if src.Default != nil {
+		dst.Default = src.Default
+	}
+	if src.Workflow != nil {
+		dst.Workflow = src.Workflow
+	}
+	if src.Image != nil {
+		dst.Image = src.Image
+	}
+	if src.Cache != nil {
+		dst.Cache = src.Cache
+	}
+	dst.Include = append(dst.Include, src.Include...)
+
+	if dst.RawData == nil {
+		dst.RawData = make(map[string]interface{})
+	}
+	for key, value := range src.RawData {
+		dst.RawData[key] = value
+	}
+}
+
+// setJobSource records where a job came from, clearing any earlier source
+// when it was replaced by a job without one
+func setJobSource(config *GitLabConfig, jobName, source string) {
+	if source == "" {
+		delete(config.JobSources, jobName)
+		return
+	}
+	if config.JobSources == nil {
+		config.JobSources = make(map[string]string)
+	}
+	config.JobSources[jobName] = source
 }
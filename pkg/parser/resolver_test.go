@@ -1,8 +1,12 @@
 package parser
 
 import (
+	"crypto/sha256"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -241,7 +245,7 @@ included_job:
 `)
 
 	resolver := NewIncludeResolver("", "")
-	err := resolver.mergeIncludedData(config, includedData, "/tmp")
+	err := resolver.mergeIncludedData(config, includedData, "/tmp", "ci/included.yml")
 	if err != nil {
 		t.Fatalf("mergeIncludedData failed: %v", err)
 	}
@@ -256,14 +260,92 @@ included_job:
 		t.Error("expected base_job to be preserved")
 	}
 
-	// Check variables (base variables should be preserved, included variables should be added only if base variables is nil)
+	// Base variables are preserved and included ones added alongside them
 	if config.Variables["BASE_VAR"] != "base_value" {
 		t.Error("expected BASE_VAR to be preserved")
 	}
+	if config.Variables["INCLUDED_VAR"] != "included_value" {
+		t.Errorf("expected INCLUDED_VAR to be merged, got %v", config.Variables["INCLUDED_VAR"])
+	}
+	if !reflect.DeepEqual(config.Stages, []string{"build", "test"}) {
+		t.Errorf("expected stages [build test], got %v", config.Stages)
+	}
+}
 
-	// Since base config already has variables, included variables shouldn't override
-	if config.Variables["INCLUDED_VAR"] != nil {
-		t.Error("included variables should not override existing variables")
+func TestIncludeResolver_MergeIncludedGlobals(t *testing.T) {
+	files := map[string]string{
+		".gitlab-ci.yml": `
+include:
+  - local: ci/first.yml
+  - local: ci/second.yml
+variables:
+  MAIN: main
+build:
+  stage: build
+  script: [make]
+`,
+		"ci/first.yml": `
+include:
+  - local: ci/nested.yml
+stages: [build, test]
+variables:
+  SHARED: first
+  MAIN: first
+  FIRST_OVER_NESTED: first
+`,
+		"ci/second.yml": `
+stages: [build, test, deploy]
+variables:
+  SHARED: second
+`,
+		"ci/nested.yml": `
+stages: [prepare, build]
+variables:
+  FIRST_OVER_NESTED: nested
+  NESTED_ONLY: nested
+`,
+	}
+
+	parse := func(t *testing.T, files map[string]string) *GitLabConfig {
+		tempDir := t.TempDir()
+		for name, content := range files {
+			path := filepath.Join(tempDir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		config, err := ParseFile(filepath.Join(tempDir, ".gitlab-ci.yml"))
+		if err != nil {
+			t.Fatalf("ParseFile failed: %v", err)
+		}
+		return config
+	}
+
+	// Later includes override earlier ones, a file overrides its own
+	// includes, and the main file overrides everything
+	config := parse(t, files)
+	wantVariables := map[string]interface{}{
+		"MAIN":              "main",
+		"SHARED":            "second",
+		"FIRST_OVER_NESTED": "first",
+		"NESTED_ONLY":       "nested",
+	}
+	if !reflect.DeepEqual(config.Variables, wantVariables) {
+		t.Errorf("expected variables %v, got %v", wantVariables, config.Variables)
+	}
+
+	// Partial stage lists are combined in order without duplicates
+	if want := []string{"prepare", "build", "test", "deploy"}; !reflect.DeepEqual(config.Stages, want) {
+		t.Errorf("expected the combined included stages %v, got %v", want, config.Stages)
+	}
+
+	files[".gitlab-ci.yml"] += "stages: [build, review, release]\n"
+	config = parse(t, files)
+	if want := []string{"prepare", "build", "test", "deploy", "review", "release"}; !reflect.DeepEqual(config.Stages, want) {
+		t.Errorf("expected the main file's stages merged with the included ones %v, got %v", want, config.Stages)
 	}
 }
 
@@ -289,3 +371,324 @@ func findSubstring(s, substr string) int {
 	}
 	return -1
 }
+
+func TestMergeConfigsLastWins(t *testing.T) {
+	first, err := Parse([]byte(`
+stages: [build, test]
+variables:
+  NODE_VERSION: "18"
+  REGISTRY: registry.example.com
+build:
+  stage: build
+  script: [npm run build]
+test:
+  stage: test
+  script: [npm test]
+`))
+	if err != nil {
+		t.Fatalf("parsing first config: %v", err)
+	}
+	second, err := Parse([]byte(`
+variables:
+  NODE_VERSION: "20"
+test:
+  stage: test
+  script: [npm run test:ci]
+`))
+	if err != nil {
+		t.Fatalf("parsing second config: %v", err)
+	}
+
+	merged := MergeConfigs(first, second)
+
+	if len(merged.Stages) != 2 {
+		t.Errorf("expected stages from the first config to be kept, got %v", merged.Stages)
+	}
+	if merged.Variables["NODE_VERSION"] != "20" || merged.Variables["REGISTRY"] != "registry.example.com" {
+		t.Errorf("expected variables merged with the last config winning, got %v", merged.Variables)
+	}
+	if len(merged.Jobs) != 2 || merged.Jobs["test"].Script[0] != "npm run test:ci" {
+		t.Errorf("expected the last definition of test to win, got %+v", merged.Jobs["test"])
+	}
+	if first.Variables["NODE_VERSION"] != "18" {
+		t.Error("expected merging not to modify the input configs")
+	}
+}
+
+func TestParseFileExtendsIncludedTemplate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".gitlab-ci.yml": `
+include:
+  - local: ci/templates.yml
+
+job:
+  extends: .template
+  script: [make]
+
+broken:
+  extends: .missing
+  script: [make]
+`,
+		"ci/templates.yml": `
+include:
+  - local: ci/base.yml
+
+.template:
+  extends: .base
+  interruptible: true
+`,
+		"ci/base.yml": `
+.base:
+  image: alpine:3.19
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, err := ParseFile(filepath.Join(dir, ".gitlab-ci.yml"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	chain := config.ExtendsChain("job")
+	if !reflect.DeepEqual(chain, []string{".base", ".template"}) {
+		t.Errorf("expected job to extend .base through .template, got %v", chain)
+	}
+
+	unknown := config.UnknownExtends()
+	if !reflect.DeepEqual(unknown, map[string][]string{"broken": {".missing"}}) {
+		t.Errorf("expected only broken's extends to be unknown, got %v", unknown)
+	}
+}
+
+func TestParseFileJobSources(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".gitlab-ci.yml": `
+include:
+  - local: ci/build.yml
+  - project: group/ci-templates
+    file: /deploy.yml
+    ref: v1
+
+test:
+  script: [make test]
+`,
+		"ci/build.yml": `
+include:
+  - local: ci/base.yml
+
+build:
+  extends: .base
+  script: [make]
+`,
+		"ci/base.yml": `
+.base:
+  image: alpine:3.19
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, err := ParseFile(filepath.Join(dir, ".gitlab-ci.yml"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"test":  "",
+		"build": "ci/build.yml",
+		".base": "ci/base.yml",
+	}
+	for jobName, source := range expected {
+		if got := config.JobSource(jobName); got != source {
+			t.Errorf("expected %s to come from %q, got %q", jobName, source, got)
+		}
+	}
+
+	expectedFiles := map[string]string{
+		"test":  filepath.Join(dir, ".gitlab-ci.yml"),
+		"build": filepath.Join(dir, "ci", "build.yml"),
+		".base": filepath.Join(dir, "ci", "base.yml"),
+	}
+	for jobName, file := range expectedFiles {
+		if got := config.JobSourceFile(jobName); got != file {
+			t.Errorf("expected %s to be defined in %q, got %q", jobName, file, got)
+		}
+	}
+	config.JobSources["deploy"] = "group/ci-templates:/deploy.yml@v1"
+	if got := config.JobSourceFile("deploy"); got != "group/ci-templates:/deploy.yml@v1" {
+		t.Errorf("expected project includes to keep their source, got %q", got)
+	}
+
+	if got := includeSource(Include{Project: "group/ci-templates", File: []string{"/deploy.yml"}, Ref: "v1"}); got != "group/ci-templates:/deploy.yml@v1" {
+		t.Errorf("unexpected project include source %q", got)
+	}
+	if got := includeSource(Include{Template: "Security/SAST.gitlab-ci.yml"}); got != "template:Security/SAST.gitlab-ci.yml" {
+		t.Errorf("unexpected template include source %q", got)
+	}
+}
+
+func TestParseFileMainJobOverridesInclude(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".gitlab-ci.yml": `
+include:
+  - local: inc.yml
+
+build:
+  script: [echo MAIN]
+  variables:
+    TARGET: main
+`,
+		"inc.yml": `
+build:
+  stage: build
+  script: [echo INCLUDED]
+  variables:
+    TARGET: included
+    MODE: release
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, err := ParseFile(filepath.Join(dir, ".gitlab-ci.yml"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	// The main file's keywords win, and those it doesn't set are kept
+	build := config.Jobs["build"]
+	if want := []string{"echo MAIN"}; !reflect.DeepEqual(build.Script, want) {
+		t.Errorf("expected the main file's script %v, got %v", want, build.Script)
+	}
+	if build.Stage != "build" {
+		t.Errorf("expected the included stage to be kept, got %q", build.Stage)
+	}
+	wantVariables := map[string]interface{}{"TARGET": "main", "MODE": "release"}
+	if !reflect.DeepEqual(build.Variables, wantVariables) {
+		t.Errorf("expected variables %v, got %v", wantVariables, build.Variables)
+	}
+	if got := config.JobSource("build"); got != "" {
+		t.Errorf("expected build to come from the main file, got %q", got)
+	}
+
+	shadowed := config.ShadowedJobs["build"]
+	if len(shadowed) != 1 || shadowed[0].Source != "inc.yml" {
+		t.Fatalf("expected the included build to be shadowed, got %+v", shadowed)
+	}
+	if want := []string{"echo INCLUDED"}; !reflect.DeepEqual(shadowed[0].Job.Script, want) {
+		t.Errorf("expected the shadowed script %v, got %v", want, shadowed[0].Job.Script)
+	}
+}
+
+func TestIncludeResolverParseCache(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"templates.yml": `
+.base:
+  image: alpine:3.19
+lint:
+  script: [make lint]
+`,
+		"first.yml": `
+include:
+  - local: templates.yml
+build:
+  extends: .base
+  script: [make]
+`,
+		"second.yml": `
+include:
+  - local: templates.yml
+lint:
+  script: [make lint-strict]
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resolver := NewIncludeResolver("", "")
+	resolver.EnableParseCache()
+
+	first, err := ParseFileWithResolver(filepath.Join(dir, "first.yml"), resolver)
+	if err != nil {
+		t.Fatalf("parsing first.yml: %v", err)
+	}
+	second, err := ParseFileWithResolver(filepath.Join(dir, "second.yml"), resolver)
+	if err != nil {
+		t.Fatalf("parsing second.yml: %v", err)
+	}
+
+	if first.Jobs[".base"] == nil || first.Jobs[".base"] != second.Jobs[".base"] {
+		t.Error("expected both configs to reuse the cached parse of templates.yml")
+	}
+	if _, exists := second.Jobs["build"]; exists {
+		t.Error("expected merging into one config not to leak jobs into another")
+	}
+	if len(resolver.parseCache) != 1 {
+		t.Errorf("expected one cached include, got %d", len(resolver.parseCache))
+	}
+	if len(resolver.parseCache[sha256.Sum256([]byte(files["templates.yml"]))].Jobs) != 2 {
+		t.Error("expected the cached config to be left unmodified by merging")
+	}
+
+	// Without the cache every include is parsed afresh
+	uncached, err := ParseFile(filepath.Join(dir, "first.yml"))
+	if err != nil {
+		t.Fatalf("parsing first.yml: %v", err)
+	}
+	if uncached.Jobs[".base"] == first.Jobs[".base"] {
+		t.Error("expected an uncached parse not to share jobs with the cache")
+	}
+}
+
+// BenchmarkParseScenarioIncludes parses the refactoring scenarios that split
+// their configuration into local includes, with and without the parse cache
+func BenchmarkParseScenarioIncludes(b *testing.B) {
+	var files []string
+	for _, scenario := range []string{"scenario-2", "scenario-6", "scenario-7", "scenario-8"} {
+		files = append(files, filepath.Join("../../test/refactoring-scenarios", scenario, "after", ".gitlab-ci.yml"))
+	}
+
+	run := func(b *testing.B, resolver *IncludeResolver) {
+		for i := 0; i < b.N; i++ {
+			for _, file := range files {
+				if _, err := ParseFileWithResolver(file, resolver); err != nil {
+					b.Fatalf("parsing %s: %v", file, err)
+				}
+			}
+		}
+	}
+
+	b.Run("uncached", func(b *testing.B) {
+		run(b, NewIncludeResolver("", ""))
+	})
+	b.Run("cached", func(b *testing.B) {
+		resolver := NewIncludeResolver("", "")
+		resolver.EnableParseCache()
+		run(b, resolver)
+	})
+}
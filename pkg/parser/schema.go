@@ -0,0 +1,258 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaError is a structural problem that GitLab would reject when
+// validating the configuration
+type SchemaError struct {
+	Line    int    `json:"line"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e SchemaError) String() string {
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Path, e.Message)
+}
+
+var globalKeywords = map[string]bool{
+	"stages": true, "variables": true, "include": true, "default": true, "workflow": true,
+	"image": true, "services": true, "cache": true, "before_script": true, "after_script": true,
+	"spec": true,
+}
+
+var jobKeywords = map[string]bool{
+	"after_script": true, "allow_failure": true, "artifacts": true, "before_script": true,
+	"cache": true, "coverage": true, "dast_configuration": true, "dependencies": true,
+	"environment": true, "except": true, "extends": true, "hooks": true, "id_tokens": true,
+	"identity": true, "image": true, "inherit": true, "interruptible": true,
+	"manual_confirmation": true, "needs": true, "only": true, "pages": true, "parallel": true,
+	"publish": true, "release": true, "resource_group": true, "retry": true, "rules": true, "run": true,
+	"script": true, "secrets": true, "services": true, "stage": true, "start_in": true,
+	"tags": true, "timeout": true, "trigger": true, "variables": true, "when": true,
+}
+
+var defaultKeywords = map[string]bool{
+	"after_script": true, "artifacts": true, "before_script": true, "cache": true,
+	"hooks": true, "id_tokens": true, "image": true, "interruptible": true, "retry": true,
+	"services": true, "tags": true, "timeout": true,
+}
+
+var validWhenValues = map[string]bool{
+	"on_success": true, "on_failure": true, "always": true, "manual": true, "delayed": true, "never": true,
+}
+
+// IsGlobalKeyword reports whether key is a top-level keyword rather than a job
+func IsGlobalKeyword(key string) bool {
+	return globalKeywords[key]
+}
+
+// IsJobKeyword reports whether key is a keyword GitLab accepts in a job
+func IsJobKeyword(key string) bool {
+	return jobKeywords[key]
+}
+
+// JobKeywords returns all keywords GitLab accepts in a job, sorted
+func JobKeywords() []string {
+	keywords := make([]string, 0, len(jobKeywords))
+	for keyword := range jobKeywords {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	return keywords
+}
+
+// ValidateSchema checks the structure of a configuration: the type of each
+// known keyword and the allowed values of when. Unknown keywords in default
+// are not rejected but returned as warnings, since GitLab adds keywords over
+// time; unknown job keywords are left to the analyzer. Hidden keys (starting
+// with ".") are skipped as they may hold arbitrary anchor content.
+func ValidateSchema(data []byte) ([]SchemaError, []ParseWarning, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("parsing YAML structure: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil, nil
+	}
+	// Only the surviving definition of a duplicated key is validated
+	removeDuplicateKeys(&doc)
+
+	v := &schemaValidator{}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		v.errorf(root, "", "configuration must be a mapping")
+		return v.errors, v.warnings, nil
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i].Value, resolveAlias(root.Content[i+1])
+		switch {
+		case strings.HasPrefix(key, "."):
+			continue
+		case key == "stages":
+			v.expectScalarList(value, "stages")
+		case key == "variables":
+			v.expectKind(value, "variables", yaml.MappingNode)
+		case key == "default":
+			v.validateKeywords(value, "default", defaultKeywords)
+		case key == "workflow":
+			if v.expectKind(value, "workflow", yaml.MappingNode) {
+				if rules := mappingValue(value, "rules"); rules != nil {
+					v.validateRules(rules, "workflow.rules")
+				}
+			}
+		case globalKeywords[key]:
+			continue
+		default:
+			v.validateJob(key, value)
+		}
+	}
+
+	return v.errors, v.warnings, nil
+}
+
+type schemaValidator struct {
+	errors   []SchemaError
+	warnings []ParseWarning
+}
+
+func (v *schemaValidator) errorf(node *yaml.Node, path, format string, args ...interface{}) {
+	v.errors = append(v.errors, SchemaError{Line: node.Line, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) validateJob(name string, node *yaml.Node) {
+	path := "jobs." + name
+	if node.Kind != yaml.MappingNode {
+		v.errorf(node, path, "job %q must be a mapping", name)
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, resolveAlias(node.Content[i+1])
+		keyPath := path + "." + key
+		switch key {
+		case "script", "before_script", "after_script":
+			v.expectKind(value, keyPath, yaml.ScalarNode, yaml.SequenceNode)
+		case "stage", "resource_group", "coverage", "start_in", "publish":
+			v.expectKind(value, keyPath, yaml.ScalarNode)
+		case "needs", "dependencies", "tags":
+			v.expectKind(value, keyPath, yaml.SequenceNode)
+		case "variables":
+			v.expectKind(value, keyPath, yaml.MappingNode)
+		case "pages":
+			v.expectKind(value, keyPath, yaml.ScalarNode, yaml.MappingNode)
+		case "when":
+			v.validateWhen(value, keyPath)
+		case "rules":
+			v.validateRules(value, keyPath)
+		}
+	}
+}
+
+// validateKeywords warns about keys outside the allowed set
+func (v *schemaValidator) validateKeywords(node *yaml.Node, path string, allowed map[string]bool) {
+	if !v.expectKind(node, path, yaml.MappingNode) {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if key.Value == "<<" || allowed[key.Value] {
+			continue
+		}
+		v.warnings = append(v.warnings, ParseWarning{
+			Line:    key.Line,
+			Message: fmt.Sprintf("unknown key %q in %s", key.Value, path),
+		})
+	}
+}
+
+func (v *schemaValidator) validateRules(node *yaml.Node, path string) {
+	if !v.expectKind(node, path, yaml.SequenceNode) {
+		return
+	}
+	for i, rule := range node.Content {
+		rule = resolveAlias(rule)
+		rulePath := fmt.Sprintf("%s[%d]", path, i)
+		if !v.expectKind(rule, rulePath, yaml.MappingNode) {
+			continue
+		}
+		if when := mappingValue(rule, "when"); when != nil {
+			v.validateWhen(when, rulePath+".when")
+		}
+		// The evaluator doesn't cover every GitLab expression feature, so
+		// expressions it can't handle are only warned about
+		if condition := mappingValue(rule, "if"); condition != nil && v.expectKind(condition, rulePath+".if", yaml.ScalarNode) {
+			if _, err := EvaluateExpression(condition.Value, map[string]string{}); err != nil {
+				v.warnings = append(v.warnings, ParseWarning{
+					Line:    condition.Line,
+					Message: fmt.Sprintf("%s.if: cannot evaluate expression: %v", rulePath, err),
+				})
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) validateWhen(node *yaml.Node, path string) {
+	if v.expectKind(node, path, yaml.ScalarNode) && !validWhenValues[node.Value] {
+		v.errorf(node, path, "unknown when value %q", node.Value)
+	}
+}
+
+func (v *schemaValidator) expectScalarList(node *yaml.Node, path string) {
+	if !v.expectKind(node, path, yaml.SequenceNode) {
+		return
+	}
+	for i, item := range node.Content {
+		v.expectKind(resolveAlias(item), fmt.Sprintf("%s[%d]", path, i), yaml.ScalarNode)
+	}
+}
+
+// expectKind records an error unless the node has one of the given kinds
+func (v *schemaValidator) expectKind(node *yaml.Node, path string, kinds ...yaml.Kind) bool {
+	for _, kind := range kinds {
+		if node.Kind == kind {
+			return true
+		}
+	}
+
+	names := make([]string, len(kinds))
+	for i, kind := range kinds {
+		names[i] = kindName(kind)
+	}
+	v.errorf(node, path, "expected %s, got %s", strings.Join(names, " or "), kindName(node.Kind))
+	return false
+}
+
+func kindName(kind yaml.Kind) string {
+	switch kind {
+	case yaml.ScalarNode:
+		return "a string"
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.MappingNode:
+		return "a mapping"
+	default:
+		return "an unsupported value"
+	}
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return resolveAlias(node.Content[i+1])
+		}
+	}
+	return nil
+}
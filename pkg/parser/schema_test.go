@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name           string
+		yaml           string
+		expectedErrors []string
+		expectedWarns  []string
+	}{
+		{
+			name: "valid config",
+			yaml: `
+stages: [build]
+.template: &tpl
+  anything: goes
+build:
+  <<: *tpl
+  stage: build
+  script: make
+  rules:
+    - if: $CI_COMMIT_BRANCH == "main"
+      when: manual
+`,
+		},
+		{
+			name: "wrong keyword types",
+			yaml: `
+stages: build
+build:
+  script: {run: make}
+  needs: lint
+`,
+			expectedErrors: []string{"stages", "jobs.build.script", "jobs.build.needs"},
+		},
+		{
+			name: "invalid when and non-mapping job",
+			yaml: `
+build:
+  script: make
+  when: sometimes
+test: make test
+`,
+			expectedErrors: []string{"jobs.build.when", "jobs.test"},
+		},
+		{
+			name: "unknown keys",
+			yaml: `
+default:
+  stage: build
+build:
+  script: make
+  scripts: [make test]
+`,
+			expectedWarns: []string{`"stage" in default`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors, warnings, err := ValidateSchema([]byte(tt.yaml))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(errors) != len(tt.expectedErrors) {
+				t.Fatalf("Expected %d schema errors, got %d: %v", len(tt.expectedErrors), len(errors), errors)
+			}
+			for i, path := range tt.expectedErrors {
+				if errors[i].Path != path {
+					t.Errorf("Expected error at %s, got %s", path, errors[i])
+				}
+			}
+
+			if len(warnings) != len(tt.expectedWarns) {
+				t.Fatalf("Expected %d warnings, got %d: %v", len(tt.expectedWarns), len(warnings), warnings)
+			}
+			for i, fragment := range tt.expectedWarns {
+				if !strings.Contains(warnings[i].Message, fragment) {
+					t.Errorf("Expected warning containing %q, got %s", fragment, warnings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSchemaInvalidYAML(t *testing.T) {
+	if _, _, err := ValidateSchema([]byte("build: [unclosed")); err == nil {
+		t.Error("Expected error for invalid YAML")
+	}
+}
@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"path"
+	"strings"
+)
+
+// securityTemplateNames are GitLab's security scanning templates, as included
+// with `include: template`, without directory and extension
+var securityTemplateNames = map[string]bool{
+	"SAST":                true,
+	"SAST-IaC":            true,
+	"Dependency-Scanning": true,
+	"Container-Scanning":  true,
+	"Secret-Detection":    true,
+	"DAST":                true,
+	"DAST-API":            true,
+	"API-Fuzzing":         true,
+	"Coverage-Fuzzing":    true,
+	"License-Scanning":    true,
+}
+
+// securityTemplateJobs are the jobs GitLab's security templates define, by
+// name or, for analyzer-specific variants such as semgrep-sast or
+// gemnasium-dependency_scanning, by name suffix
+var securityTemplateJobs = []string{
+	"sast",
+	"dependency_scanning",
+	"container_scanning",
+	"secret_detection",
+	"dast",
+	"dast_api",
+	"apifuzzer_fuzz",
+	"license_scanning",
+}
+
+// IsSecurityTemplate reports whether an `include: template` names one of
+// GitLab's security scanning templates, such as Jobs/SAST.gitlab-ci.yml or
+// Security/DAST.latest.gitlab-ci.yml
+func IsSecurityTemplate(template string) bool {
+	if strings.HasPrefix(template, "Security/") {
+		return true
+	}
+	name := strings.TrimSuffix(strings.TrimSuffix(path.Base(template), ".yml"), ".yaml")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".gitlab-ci"), ".latest")
+	return securityTemplateNames[name]
+}
+
+// IsSecurityTemplateJob reports whether a job comes from one of GitLab's
+// security templates: it was merged from such a template or, when the
+// template couldn't be resolved, the configuration includes one and the job
+// is named like the template's jobs, typically to override them
+func (c *GitLabConfig) IsSecurityTemplateJob(jobName string) bool {
+	if source := c.JobSource(jobName); strings.HasPrefix(source, "template:") {
+		return IsSecurityTemplate(strings.TrimPrefix(source, "template:"))
+	}
+
+	includesTemplate := false
+	for _, include := range c.Include {
+		if include.Template != "" && IsSecurityTemplate(include.Template) {
+			includesTemplate = true
+			break
+		}
+	}
+	if !includesTemplate {
+		return false
+	}
+
+	return IsSecurityTemplateJobName(jobName)
+}
+
+// IsSecurityTemplateJobName reports whether a job is named like one of the
+// jobs GitLab's security templates define, whether or not the configuration
+// includes them
+func IsSecurityTemplateJobName(jobName string) bool {
+	for _, name := range securityTemplateJobs {
+		if jobName == name || strings.HasSuffix(jobName, "-"+name) {
+			return true
+		}
+	}
+	return false
+}
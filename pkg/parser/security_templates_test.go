@@ -0,0 +1,58 @@
+package parser
+
+import "testing"
+
+func TestIsSecurityTemplate(t *testing.T) {
+	tests := map[string]bool{
+		"Security/SAST.gitlab-ci.yml":                   true,
+		"Jobs/SAST.gitlab-ci.yml":                       true,
+		"Jobs/Dependency-Scanning.latest.gitlab-ci.yml": true,
+		"DAST.gitlab-ci.yml":                            true,
+		"Auto-DevOps.gitlab-ci.yml":                     false,
+		"Jobs/Build.gitlab-ci.yml":                      false,
+	}
+	for template, want := range tests {
+		if got := IsSecurityTemplate(template); got != want {
+			t.Errorf("IsSecurityTemplate(%q) = %v, want %v", template, got, want)
+		}
+	}
+}
+
+func TestIsSecurityTemplateJob(t *testing.T) {
+	config := &GitLabConfig{
+		Include: []Include{{Template: "Jobs/SAST.gitlab-ci.yml"}},
+		Jobs: map[string]*JobConfig{
+			"sast":          {Stage: "test"},
+			"semgrep-sast":  {},
+			"kics-iac-sast": {},
+			"sast-report":   {Script: []string{"./report.sh"}},
+			"build":         {Script: []string{"make"}},
+			"from-template": {},
+		},
+		JobSources: map[string]string{
+			"from-template": "template:Security/Secret-Detection.gitlab-ci.yml",
+			"build":         "template:Jobs/Build.gitlab-ci.yml",
+		},
+	}
+	for jobName, want := range map[string]bool{
+		"sast":          true,
+		"semgrep-sast":  true,
+		"kics-iac-sast": true,
+		"sast-report":   false,
+		"build":         false,
+		"from-template": true,
+	} {
+		if got := config.IsSecurityTemplateJob(jobName); got != want {
+			t.Errorf("IsSecurityTemplateJob(%q) = %v, want %v", jobName, got, want)
+		}
+	}
+
+	// Without a security template include, a job named sast is the user's own
+	config.Include = nil
+	if config.IsSecurityTemplateJob("sast") {
+		t.Error("Expected a sast job without a security template include not to count")
+	}
+	if !IsSecurityTemplateJobName("sast") || IsSecurityTemplateJobName("sast-report") {
+		t.Error("Expected IsSecurityTemplateJobName to match by name alone")
+	}
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"strings"
 )
 
@@ -21,15 +22,15 @@ func (c *GitLabConfig) SimulatePipeline(context *PipelineContext) map[string]boo
 	result := make(map[string]bool)
 
 	// First check if pipeline should be created at all
-	evaluator := NewWorkflowEvaluator(c, context)
-	if !evaluator.ShouldCreatePipeline() {
+	created, workflowVars := c.EvaluateWorkflow(context)
+	if !created {
 		// No jobs run if pipeline is not created
 		return result
 	}
 
 	// Evaluate each job's rules to see if it should run
 	for jobName, job := range c.Jobs {
-		result[jobName] = c.shouldJobRun(job, context)
+		result[jobName] = c.JobRuns(job, context, workflowVars)
 	}
 
 	return result
@@ -37,78 +38,157 @@ func (c *GitLabConfig) SimulatePipeline(context *PipelineContext) map[string]boo
 
 // shouldJobRun evaluates if a job should run in the given context
 func (c *GitLabConfig) shouldJobRun(job *JobConfig, context *PipelineContext) bool {
-	// If job has rules, evaluate them
+	return c.JobRuns(job, context, nil)
+}
+
+// JobRuns evaluates if a job should run in the given context, ignoring
+// workflow:rules. Variables injected by the matching workflow rule are
+// visible to the job's rules.
+func (c *GitLabConfig) JobRuns(job *JobConfig, context *PipelineContext, workflowVars map[string]string) bool {
+	return c.ExplainJob(job, context, workflowVars).Runs
+}
+
+// JobDecision is whether a job runs in a pipeline and why
+type JobDecision struct {
+	Runs bool
+	// When is the job's when once added to the pipeline: on_success,
+	// manual, delayed, always or on_failure. Whether it then starts depends
+	// on the jobs before it, which isn't simulated.
+	When   string
+	Reason string
+}
+
+// ExplainJob decides whether a job runs in the given context, like JobRuns,
+// and gives the reason: the rule that matched, the only/except decision, or
+// the absence of both
+func (c *GitLabConfig) ExplainJob(job *JobConfig, context *PipelineContext, workflowVars map[string]string) JobDecision {
 	if len(job.Rules) > 0 {
-		return c.evaluateJobRules(job, context)
+		vars := c.expressionVariables(context, workflowVars, job)
+		for i, rule := range job.Rules {
+			if !c.ruleMatches(&rule, vars, context) {
+				continue
+			}
+			reason := fmt.Sprintf("rules[%d] matched", i)
+			if description := describeRule(rule); description != "" {
+				reason += " (" + description + ")"
+			}
+			if rule.When == "never" {
+				return JobDecision{When: "never", Reason: reason + " with when: never"}
+			}
+			return JobDecision{Runs: true, When: whenOrDefault(rule.When), Reason: reason}
+		}
+		return JobDecision{Reason: "no rule matched"}
 	}
 
-	// If job has only/except, evaluate them (legacy)
+	// Legacy only/except
 	if job.Only != nil || job.Except != nil {
-		return c.evaluateOnlyExcept(job, context)
+		if c.evaluateOnlyExcept(job, context) {
+			return JobDecision{Runs: true, When: whenOrDefault(job.When), Reason: "only/except allow this pipeline"}
+		}
+		return JobDecision{Reason: "excluded by only/except"}
 	}
 
-	// Default behavior: job runs
-	return true
+	return JobDecision{Runs: true, When: whenOrDefault(job.When), Reason: "no rules, so it runs in every pipeline"}
 }
 
-// evaluateJobRules evaluates job rules to determine if job should run
-func (c *GitLabConfig) evaluateJobRules(job *JobConfig, context *PipelineContext) bool {
-	for _, rule := range job.Rules {
-		if c.ruleMatches(&rule, context) {
-			switch rule.When {
-			case "never":
-				return false
-			case "always", "":
-				return true
-			case "on_success", "on_failure", "manual", "delayed":
-				// These depend on previous job status, assume true for simulation
-				return true
-			}
-		}
+// describeRule summarizes the conditions of a rule
+func describeRule(rule Rule) string {
+	var parts []string
+	if rule.If != "" {
+		parts = append(parts, "if: "+rule.If)
+	}
+	if len(rule.Changes) > 0 {
+		parts = append(parts, "changes: "+strings.Join(rule.Changes, ", "))
 	}
+	if len(rule.Exists) > 0 {
+		parts = append(parts, "exists: "+strings.Join(rule.Exists, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
 
-	// No rule matched, default behavior is job doesn't run
-	return false
+func whenOrDefault(when string) string {
+	if when == "" {
+		return "on_success"
+	}
+	return when
 }
 
-// ruleMatches checks if a rule matches the current context (simplified)
-func (c *GitLabConfig) ruleMatches(rule *Rule, context *PipelineContext) bool {
-	// If no conditions, rule matches
-	if rule.If == "" && len(rule.Changes) == 0 && len(rule.Exists) == 0 {
-		return true
+// ruleMatches checks if a rule matches the given variables and, when the
+// context lists them, the changed and existing files
+func (c *GitLabConfig) ruleMatches(rule *Rule, vars map[string]string, context *PipelineContext) bool {
+	if rule.If != "" && !evaluateIf(rule.If, vars) {
+		return false
 	}
 
-	// Simple if condition evaluation
-	if rule.If != "" {
-		return c.evaluateSimpleIfCondition(rule.If, context)
+	// Without the files, changes and exists are assumed to match
+	if len(rule.Changes) > 0 && !context.changesMatch(rule.Changes, vars, true) {
+		return false
+	}
+	if len(rule.Exists) > 0 && !context.existsMatch(rule.Exists, vars, true) {
+		return false
 	}
 
-	// For changes/exists, we can't evaluate without file system, assume true
-	return len(rule.Changes) == 0 && len(rule.Exists) == 0
+	return true
 }
 
-// evaluateSimpleIfCondition provides basic evaluation of if conditions
-func (c *GitLabConfig) evaluateSimpleIfCondition(condition string, context *PipelineContext) bool {
-	// This is a simplified version - in practice GitLab has complex expression evaluation
-	condition = strings.TrimSpace(condition)
+// RuleOutcome is the decision made by the first matching rule of a job
+type RuleOutcome struct {
+	Runs         bool
+	When         string
+	AllowFailure bool
+	StartIn      string
+	Variables    map[string]string
+}
 
-	// Common patterns
-	if strings.Contains(condition, "$CI_PIPELINE_SOURCE == \"push\"") {
-		return context.Event == "push"
-	}
-	if strings.Contains(condition, "$CI_PIPELINE_SOURCE == \"merge_request_event\"") {
-		return context.Event == "merge_request_event"
-	}
-	if strings.Contains(condition, "$CI_COMMIT_BRANCH == \"main\"") ||
-		strings.Contains(condition, "$CI_COMMIT_BRANCH == \"master\"") {
-		return context.IsMainBranch
-	}
-	if strings.Contains(condition, "$CI_MERGE_REQUEST_ID") {
-		return context.IsMR
+// EvaluateRules evaluates job rules the way GitLab does, where the first
+// matching rule decides. Unlike the simulator it doesn't guess: an error is
+// returned when an if expression can't be parsed or when a matching rule
+// depends on changes/exists, since the outcome is unknown.
+func EvaluateRules(rules []Rule, vars map[string]string) (RuleOutcome, error) {
+	for _, rule := range rules {
+		if rule.If != "" {
+			matched, err := EvaluateExpression(rule.If, vars)
+			if err != nil {
+				return RuleOutcome{}, err
+			}
+			if !matched {
+				continue
+			}
+		}
+		if len(rule.Changes) > 0 || len(rule.Exists) > 0 {
+			return RuleOutcome{}, fmt.Errorf("rule depends on changes/exists and cannot be evaluated")
+		}
+
+		if rule.When == "never" {
+			return RuleOutcome{}, nil
+		}
+
+		outcome := RuleOutcome{
+			Runs:         true,
+			When:         rule.When,
+			AllowFailure: rule.AllowFailure != nil && *rule.AllowFailure,
+			StartIn:      rule.StartIn,
+		}
+		if outcome.When == "" {
+			outcome.When = "on_success"
+		}
+		if len(rule.Variables) > 0 {
+			outcome.Variables = make(map[string]string, len(rule.Variables))
+			for key, value := range rule.Variables {
+				outcome.Variables[key] = variableValueString(value)
+			}
+		}
+		return outcome, nil
 	}
 
-	// Default to true for unknown conditions
-	return true
+	// No rule matched, so the job doesn't run
+	return RuleOutcome{}, nil
+}
+
+// RuleVariables returns the variables visible to the job's rules:if
+// expressions in the given context
+func (c *GitLabConfig) RuleVariables(ctx *PipelineContext, workflowVars map[string]string, job *JobConfig) map[string]string {
+	return c.expressionVariables(ctx, workflowVars, job)
 }
 
 // evaluateOnlyExcept evaluates legacy only/except directives
@@ -163,8 +243,23 @@ func (c *GitLabConfig) matchesSingleCondition(condition string, context *Pipelin
 		return context.IsMR
 	case "pushes":
 		return context.Event == "push"
+	case "tags":
+		return context.Tag != ""
+	case "branches":
+		return context.Branch != "" && !context.IsMR
+	case "schedules":
+		return context.Event == "schedule"
+	case "web", "api", "chat", "external":
+		return context.Event == condition
+	case "triggers":
+		return context.Event == "trigger"
+	case "pipelines":
+		// Multi-project and child pipelines
+		return context.Event == "pipeline" || context.Event == "parent_pipeline"
+	case "external_pull_requests":
+		return context.Event == "external_pull_request_event"
 	default:
-		// Could be a branch name or pattern
-		return condition == context.Branch
+		// Could be a branch or tag name or pattern
+		return condition == context.Branch || (context.Tag != "" && condition == context.Tag)
 	}
 }
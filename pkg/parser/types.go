@@ -1,9 +1,20 @@
 package parser
 
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
 // GitLabConfig represents a parsed GitLab CI configuration
 type GitLabConfig struct {
 	Stages    []string               `yaml:"stages" json:"stages,omitempty"`
-	Image     string                 `yaml:"image" json:"image,omitempty"`
+	Image     *Image                 `yaml:"image" json:"image,omitempty"`
 	Variables map[string]interface{} `yaml:"variables" json:"variables,omitempty"`
 	Include   []Include              `yaml:"include" json:"include,omitempty"`
 	Default   *JobConfig             `yaml:"default" json:"default,omitempty"`
@@ -11,6 +22,21 @@ type GitLabConfig struct {
 	Workflow  *Workflow              `yaml:"workflow" json:"workflow,omitempty"`
 	Jobs      map[string]*JobConfig  `json:"jobs,omitempty"`
 	RawData   map[string]interface{} `json:"-"`
+	// JobSources maps jobs merged from includes to the include they came from
+	JobSources map[string]string `json:"-"`
+	// ShadowedJobs lists, per job name, the definitions a later include or
+	// the including file overrode, in the order they were merged
+	ShadowedJobs map[string][]JobDefinition `json:"-"`
+	// FilePath is the main file the configuration was parsed from, empty
+	// when it was parsed from data
+	FilePath string `json:"-"`
+}
+
+// JobDefinition is a job as defined in one source. An empty Source is the
+// main configuration file.
+type JobDefinition struct {
+	Source string
+	Job    *JobConfig
 }
 
 type Include struct {
@@ -27,55 +53,324 @@ type JobConfig struct {
 	Script        []string               `yaml:"script,omitempty" json:"script,omitempty"`
 	BeforeScript  []string               `yaml:"before_script,omitempty" json:"before_script,omitempty"`
 	AfterScript   []string               `yaml:"after_script,omitempty" json:"after_script,omitempty"`
-	Image         string                 `yaml:"image,omitempty" json:"image,omitempty"`
-	Services      []string               `yaml:"services,omitempty" json:"services,omitempty"`
+	Image         *Image                 `yaml:"image,omitempty" json:"image,omitempty"`
+	Services      []Service              `yaml:"services,omitempty" json:"services,omitempty"`
 	Variables     map[string]interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
 	Cache         *Cache                 `yaml:"cache,omitempty" json:"cache,omitempty"`
 	Artifacts     *Artifacts             `yaml:"artifacts,omitempty" json:"artifacts,omitempty"`
 	Dependencies  []string               `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
-	Needs         interface{}            `yaml:"needs,omitempty" json:"needs,omitempty"`
+	Needs         []Need                 `yaml:"needs,omitempty" json:"needs,omitempty"`
 	Tags          []string               `yaml:"tags,omitempty" json:"tags,omitempty"`
-	AllowFailure  bool                   `yaml:"allow_failure,omitempty" json:"allow_failure,omitempty"`
+	AllowFailure  *AllowFailure          `yaml:"allow_failure,omitempty" json:"allow_failure,omitempty"`
 	When          string                 `yaml:"when,omitempty" json:"when,omitempty"`
 	Only          interface{}            `yaml:"only,omitempty" json:"only,omitempty"`
 	Except        interface{}            `yaml:"except,omitempty" json:"except,omitempty"`
 	Rules         []Rule                 `yaml:"rules,omitempty" json:"rules,omitempty"`
 	Retry         *Retry                 `yaml:"retry,omitempty" json:"retry,omitempty"`
 	Timeout       string                 `yaml:"timeout,omitempty" json:"timeout,omitempty"`
-	Parallel      int                    `yaml:"parallel,omitempty" json:"parallel,omitempty"`
+	Interruptible *bool                  `yaml:"interruptible,omitempty" json:"interruptible,omitempty"`
+	Parallel      *Parallel              `yaml:"parallel,omitempty" json:"parallel,omitempty"`
 	ResourceGroup string                 `yaml:"resource_group,omitempty" json:"resource_group,omitempty"`
 	Environment   *Environment           `yaml:"environment,omitempty" json:"environment,omitempty"`
 	Coverage      string                 `yaml:"coverage,omitempty" json:"coverage,omitempty"`
 	Extends       interface{}            `yaml:"extends,omitempty" json:"extends,omitempty"`
+	Inherit       *Inherit               `yaml:"inherit,omitempty" json:"inherit,omitempty"`
+	IDTokens      map[string]IDToken     `yaml:"id_tokens,omitempty" json:"id_tokens,omitempty"`
+	Secrets       map[string]Secret      `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+	Trigger       interface{}            `yaml:"trigger,omitempty" json:"trigger,omitempty"` // Can be string or map
+	Hooks         *Hooks                 `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+	Pages         *Pages                 `yaml:"pages,omitempty" json:"pages,omitempty"`
+	Publish       string                 `yaml:"publish,omitempty" json:"publish,omitempty"`
+}
+
+// Hooks are commands the runner executes at fixed points of a job.
+// pre_get_sources_script runs before the repository is cloned or fetched.
+type Hooks struct {
+	PreGetSourcesScript []string `yaml:"pre_get_sources_script,omitempty" json:"pre_get_sources_script,omitempty"`
 }
 
 type Cache struct {
-	Key       interface{} `yaml:"key,omitempty" json:"key,omitempty"` // Can be string or CacheKey
-	Paths     []string    `yaml:"paths,omitempty" json:"paths,omitempty"`
-	Policy    string      `yaml:"policy,omitempty" json:"policy,omitempty"`
-	Untracked bool        `yaml:"untracked,omitempty" json:"untracked,omitempty"`
-	When      string      `yaml:"when,omitempty" json:"when,omitempty"`
+	Key       *CacheKey `yaml:"key,omitempty" json:"key,omitempty"`
+	Paths     []string  `yaml:"paths,omitempty" json:"paths,omitempty"`
+	Policy    string    `yaml:"policy,omitempty" json:"policy,omitempty"`
+	Untracked bool      `yaml:"untracked,omitempty" json:"untracked,omitempty"`
+	When      string    `yaml:"when,omitempty" json:"when,omitempty"`
 }
 
+// CacheKey is a cache's key, written either as a plain string (Value) or as
+// `{files, prefix}`, which derives the key from the content of up to two
+// files, optionally prefixed
 type CacheKey struct {
+	Value  string   `yaml:"-" json:"-"`
 	Files  []string `yaml:"files,omitempty" json:"files,omitempty"`
 	Prefix string   `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 }
 
+// UnmarshalYAML accepts both `key: node` and the `{files, prefix}` form
+func (k *CacheKey) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return value.Decode(&k.Value)
+	case yaml.MappingNode:
+		type plain CacheKey
+		return value.Decode((*plain)(k))
+	default:
+		return fmt.Errorf("line %d: cache:key must be a string or a mapping", value.Line)
+	}
+}
+
+// MarshalYAML writes the plain string unless files or a prefix are set
+func (k *CacheKey) MarshalYAML() (interface{}, error) {
+	if !k.IsObject() {
+		return k.Value, nil
+	}
+	type plain CacheKey
+	return (*plain)(k), nil
+}
+
+// MarshalJSON writes the plain string unless files or a prefix are set
+func (k *CacheKey) MarshalJSON() ([]byte, error) {
+	if !k.IsObject() {
+		return json.Marshal(k.Value)
+	}
+	type plain CacheKey
+	return json.Marshal((*plain)(k))
+}
+
+// IsObject reports whether the key uses the `{files, prefix}` form
+func (k *CacheKey) IsObject() bool {
+	return k != nil && (len(k.Files) > 0 || k.Prefix != "")
+}
+
+// KeyString returns the key when it's a plain string, and an empty string
+// when it's unset or uses the object form
+func (c *Cache) KeyString() string {
+	if c.Key == nil || c.Key.IsObject() {
+		return ""
+	}
+	return c.Key.Value
+}
+
+// KeyObject returns the key's `{files, prefix}` object form, or nil when the
+// key is unset or a plain string
+func (c *Cache) KeyObject() *CacheKey {
+	if !c.Key.IsObject() {
+		return nil
+	}
+	return c.Key
+}
+
 type Artifacts struct {
-	Paths     []string               `yaml:"paths,omitempty" json:"paths,omitempty"`
-	Name      string                 `yaml:"name,omitempty" json:"name,omitempty"`
-	Untracked bool                   `yaml:"untracked,omitempty" json:"untracked,omitempty"`
-	When      string                 `yaml:"when,omitempty" json:"when,omitempty"`
-	ExpireIn  string                 `yaml:"expire_in,omitempty" json:"expire_in,omitempty"`
-	Reports   map[string]interface{} `yaml:"reports,omitempty" json:"reports,omitempty"`
+	Paths     []string `yaml:"paths,omitempty" json:"paths,omitempty"`
+	Name      string   `yaml:"name,omitempty" json:"name,omitempty"`
+	Untracked bool     `yaml:"untracked,omitempty" json:"untracked,omitempty"`
+	When      string   `yaml:"when,omitempty" json:"when,omitempty"`
+	ExpireIn  string   `yaml:"expire_in,omitempty" json:"expire_in,omitempty"`
+	Reports   *Reports `yaml:"reports,omitempty" json:"reports,omitempty"`
+}
+
+// Reports is a job's artifacts:reports. Report files are uploaded for GitLab
+// to process and, apart from dotenv variables, aren't passed to later jobs.
+// Each report type is written as a single path or a list of paths, except
+// coverage_report, which names its format and path.
+type Reports struct {
+	JUnit          []string        `yaml:"-" json:"junit,omitempty"`
+	Dotenv         []string        `yaml:"-" json:"dotenv,omitempty"`
+	CoverageReport *CoverageReport `yaml:"-" json:"coverage_report,omitempty"`
+	// Other holds the paths of every other report type, keyed by type
+	Other map[string][]string `yaml:"-" json:"other,omitempty"`
+}
+
+// CoverageReport is artifacts:reports:coverage_report
+type CoverageReport struct {
+	CoverageFormat string `yaml:"coverage_format" json:"coverage_format"`
+	Path           string `yaml:"path" json:"path"`
+}
+
+// UnmarshalYAML reads each report type, accepting a single path or a list
+func (r *Reports) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: artifacts:reports must be a mapping", value.Line)
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		reportType, node := value.Content[i].Value, value.Content[i+1]
+		if reportType == "coverage_report" {
+			r.CoverageReport = &CoverageReport{}
+			if err := node.Decode(r.CoverageReport); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var paths []string
+		switch node.Kind {
+		case yaml.ScalarNode:
+			paths = []string{node.Value}
+		case yaml.SequenceNode:
+			if err := node.Decode(&paths); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("line %d: artifacts:reports:%s must be a path or a list of paths", node.Line, reportType)
+		}
+
+		switch reportType {
+		case "junit":
+			r.JUnit = paths
+		case "dotenv":
+			r.Dotenv = paths
+		default:
+			if r.Other == nil {
+				r.Other = make(map[string][]string)
+			}
+			r.Other[reportType] = paths
+		}
+	}
+	return nil
 }
 
+// MarshalYAML writes the reports back as a mapping of report type to paths
+func (r *Reports) MarshalYAML() (interface{}, error) {
+	reports := make(map[string]interface{}, len(r.Other)+3)
+	for reportType, paths := range r.Other {
+		reports[reportType] = paths
+	}
+	if len(r.JUnit) > 0 {
+		reports["junit"] = r.JUnit
+	}
+	if len(r.Dotenv) > 0 {
+		reports["dotenv"] = r.Dotenv
+	}
+	if r.CoverageReport != nil {
+		reports["coverage_report"] = r.CoverageReport
+	}
+	return reports, nil
+}
+
+// Has reports whether the report type is set
+func (r *Reports) Has(reportType string) bool {
+	if r == nil {
+		return false
+	}
+	switch reportType {
+	case "junit":
+		return len(r.JUnit) > 0
+	case "dotenv":
+		return len(r.Dotenv) > 0
+	case "coverage_report":
+		return r.CoverageReport != nil
+	}
+	_, exists := r.Other[reportType]
+	return exists
+}
+
+// Types returns the report types that are set, sorted
+func (r *Reports) Types() []string {
+	if r == nil {
+		return nil
+	}
+	var reportTypes []string
+	for reportType := range r.Other {
+		reportTypes = append(reportTypes, reportType)
+	}
+	for _, reportType := range []string{"junit", "dotenv", "coverage_report"} {
+		if r.Has(reportType) {
+			reportTypes = append(reportTypes, reportType)
+		}
+	}
+	sort.Strings(reportTypes)
+	return reportTypes
+}
+
+// Paths returns the files of every report type
+func (r *Reports) Paths() []string {
+	if r == nil {
+		return nil
+	}
+	var paths []string
+	for _, reportType := range r.Types() {
+		switch reportType {
+		case "junit":
+			paths = append(paths, r.JUnit...)
+		case "dotenv":
+			paths = append(paths, r.Dotenv...)
+		case "coverage_report":
+			if r.CoverageReport.Path != "" {
+				paths = append(paths, r.CoverageReport.Path)
+			}
+		default:
+			paths = append(paths, r.Other[reportType]...)
+		}
+	}
+	return paths
+}
+
+// Need is a single needs entry, written either as a job name or as an
+// object. Artifacts is nil unless set explicitly; GitLab downloads the
+// needed job's artifacts by default.
 type Need struct {
-	Job      string `yaml:"job,omitempty" json:"job,omitempty"`
-	Ref      string `yaml:"ref,omitempty" json:"ref,omitempty"`
-	Pipeline string `yaml:"pipeline,omitempty" json:"pipeline,omitempty"`
-	Optional bool   `yaml:"optional,omitempty" json:"optional,omitempty"`
+	Job       string        `yaml:"job,omitempty" json:"job,omitempty"`
+	Project   string        `yaml:"project,omitempty" json:"project,omitempty"`
+	Ref       string        `yaml:"ref,omitempty" json:"ref,omitempty"`
+	Pipeline  string        `yaml:"pipeline,omitempty" json:"pipeline,omitempty"`
+	Optional  bool          `yaml:"optional,omitempty" json:"optional,omitempty"`
+	Artifacts *bool         `yaml:"artifacts,omitempty" json:"artifacts,omitempty"`
+	Parallel  *NeedParallel `yaml:"parallel,omitempty" json:"parallel,omitempty"`
+}
+
+// NeedParallel selects specific parallel:matrix jobs of the needed job
+type NeedParallel struct {
+	Matrix []map[string]interface{} `yaml:"matrix,omitempty" json:"matrix,omitempty"`
+}
+
+// UnmarshalYAML accepts both `needs: [build]` and the object form
+func (n *Need) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return value.Decode(&n.Job)
+	case yaml.MappingNode:
+		type plain Need
+		return value.Decode((*plain)(n))
+	default:
+		return fmt.Errorf("line %d: needs entry must be a string or a mapping", value.Line)
+	}
+}
+
+// DownloadsArtifacts reports whether the needed job's artifacts are
+// downloaded, which is the case unless `artifacts: false` is set
+func (n Need) DownloadsArtifacts() bool {
+	return n.Artifacts == nil || *n.Artifacts
+}
+
+// IsExternal reports whether the need refers to a job in another project
+// or pipeline rather than one in this pipeline
+func (n Need) IsExternal() bool {
+	return n.Project != "" || n.Pipeline != ""
+}
+
+// NeedNames returns the names of the jobs of this pipeline a job needs, in
+// declaration order. Needs on other projects or pipelines are left out; see
+// ExternalNeeds.
+func (j *JobConfig) NeedNames() []string {
+	names := make([]string, 0, len(j.Needs))
+	for _, need := range j.Needs {
+		if need.Job != "" && !need.IsExternal() {
+			names = append(names, need.Job)
+		}
+	}
+	return names
+}
+
+// ExternalNeeds returns the needs on jobs in other projects or pipelines, in
+// declaration order
+func (j *JobConfig) ExternalNeeds() []Need {
+	var needs []Need
+	for _, need := range j.Needs {
+		if need.IsExternal() {
+			needs = append(needs, need)
+		}
+	}
+	return needs
 }
 
 type OnlyExcept struct {
@@ -92,26 +387,335 @@ type Rule struct {
 	Exists       []string               `yaml:"exists,omitempty" json:"exists,omitempty"`
 	Variables    map[string]interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
 	When         string                 `yaml:"when,omitempty" json:"when,omitempty"`
-	AllowFailure bool                   `yaml:"allow_failure,omitempty" json:"allow_failure,omitempty"`
+	AllowFailure *bool                  `yaml:"allow_failure,omitempty" json:"allow_failure,omitempty"`
 	StartIn      string                 `yaml:"start_in,omitempty" json:"start_in,omitempty"`
 }
 
+// Retry is a job's retry setting, written either as a plain count or as an
+// object that limits retries to failure reasons (when) or exit codes
 type Retry struct {
-	Max  int    `yaml:"max,omitempty" json:"max,omitempty"`
-	When string `yaml:"when,omitempty" json:"when,omitempty"`
+	Max       int      `yaml:"max,omitempty" json:"max,omitempty"`
+	When      []string `yaml:"when,omitempty" json:"when,omitempty"`
+	ExitCodes []int    `yaml:"exit_codes,omitempty" json:"exit_codes,omitempty"`
+}
+
+// RetryReasons are the failure reasons accepted by retry:when
+var RetryReasons = []string{
+	"always", "unknown_failure", "script_failure", "api_failure", "stuck_or_timeout_failure",
+	"runner_system_failure", "runner_unsupported", "stale_schedule", "job_execution_timeout",
+	"archived_failure", "unmet_prerequisites", "scheduler_failure", "data_integrity_failure",
+}
+
+// UnmarshalYAML accepts `retry: 2` and the object form, with when and
+// exit_codes each given as a single value or a list
+func (r *Retry) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return value.Decode(&r.Max)
+	case yaml.MappingNode:
+		var raw struct {
+			Max       int       `yaml:"max"`
+			When      yaml.Node `yaml:"when"`
+			ExitCodes yaml.Node `yaml:"exit_codes"`
+		}
+		if err := value.Decode(&raw); err != nil {
+			return err
+		}
+		r.Max = raw.Max
+		switch raw.When.Kind {
+		case yaml.ScalarNode:
+			r.When = []string{raw.When.Value}
+		case yaml.SequenceNode:
+			if err := raw.When.Decode(&r.When); err != nil {
+				return err
+			}
+		}
+		switch raw.ExitCodes.Kind {
+		case yaml.ScalarNode:
+			var code int
+			if err := raw.ExitCodes.Decode(&code); err != nil {
+				return err
+			}
+			r.ExitCodes = []int{code}
+		case yaml.SequenceNode:
+			return raw.ExitCodes.Decode(&r.ExitCodes)
+		}
+		return nil
+	default:
+		return fmt.Errorf("line %d: retry must be a number or a mapping", value.Line)
+	}
+}
+
+// MarshalYAML writes the plain count when neither when nor exit_codes is set
+func (r *Retry) MarshalYAML() (interface{}, error) {
+	if len(r.When) == 0 && len(r.ExitCodes) == 0 {
+		return r.Max, nil
+	}
+	type plain Retry
+	return (*plain)(r), nil
 }
 
 type Environment struct {
-	Name       string `yaml:"name,omitempty" json:"name,omitempty"`
-	URL        string `yaml:"url,omitempty" json:"url,omitempty"`
-	OnStop     string `yaml:"on_stop,omitempty" json:"on_stop,omitempty"`
-	Action     string `yaml:"action,omitempty" json:"action,omitempty"`
-	AutoStopIn string `yaml:"auto_stop_in,omitempty" json:"auto_stop_in,omitempty"`
-	Deployment string `yaml:"deployment,omitempty" json:"deployment,omitempty"`
+	Name           string `yaml:"name,omitempty" json:"name,omitempty"`
+	URL            string `yaml:"url,omitempty" json:"url,omitempty"`
+	OnStop         string `yaml:"on_stop,omitempty" json:"on_stop,omitempty"`
+	Action         string `yaml:"action,omitempty" json:"action,omitempty"`
+	AutoStopIn     string `yaml:"auto_stop_in,omitempty" json:"auto_stop_in,omitempty"`
+	Deployment     string `yaml:"deployment,omitempty" json:"deployment,omitempty"`
+	DeploymentTier string `yaml:"deployment_tier,omitempty" json:"deployment_tier,omitempty"`
+}
+
+// IsProduction reports whether the environment is a production deployment,
+// by its deployment_tier or, when unset, by its name
+func (e *Environment) IsProduction() bool {
+	if e == nil {
+		return false
+	}
+	if e.DeploymentTier != "" {
+		return e.DeploymentTier == "production"
+	}
+	name := strings.ToLower(e.Name)
+	return name == "production" || name == "prod" || strings.HasPrefix(name, "production/") || strings.HasPrefix(name, "prod/")
+}
+
+// UnmarshalYAML accepts both `environment: production` and the object form
+func (e *Environment) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return value.Decode(&e.Name)
+	case yaml.MappingNode:
+		type plain Environment
+		return value.Decode((*plain)(e))
+	default:
+		return fmt.Errorf("line %d: environment must be a string or a mapping", value.Line)
+	}
+}
+
+// IDToken is an OIDC ID token issued to a job. The aud claim is written
+// either as a single audience or as a list.
+type IDToken struct {
+	Aud []string `yaml:"aud,omitempty" json:"aud,omitempty"`
+}
+
+// UnmarshalYAML accepts both `aud: https://vault.example.com` and a list
+func (t *IDToken) UnmarshalYAML(value *yaml.Node) error {
+	var raw struct {
+		Aud yaml.Node `yaml:"aud"`
+	}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	switch raw.Aud.Kind {
+	case yaml.ScalarNode:
+		t.Aud = []string{raw.Aud.Value}
+	case yaml.SequenceNode:
+		return raw.Aud.Decode(&t.Aud)
+	}
+	return nil
+}
+
+// Secret is an external secret that GitLab fetches into a job variable
+type Secret struct {
+	Vault *VaultSecret `yaml:"vault,omitempty" json:"vault,omitempty"`
+	// File exposes the secret as a file path rather than its value; GitLab
+	// defaults to true
+	File *bool `yaml:"file,omitempty" json:"file,omitempty"`
+	// Token names the ID token used to authenticate, e.g. $VAULT_ID_TOKEN
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+}
+
+// VaultSecret locates a secret in HashiCorp Vault. It is written either as
+// `path/field@engine-path`, with the engine path defaulting to "secret", or
+// as an object with engine, path and field.
+type VaultSecret struct {
+	Engine VaultEngine `yaml:"engine" json:"engine"`
+	Path   string      `yaml:"path" json:"path"`
+	Field  string      `yaml:"field" json:"field"`
+}
+
+// VaultEngine is the Vault secrets engine a secret is read from
+type VaultEngine struct {
+	Name string `yaml:"name" json:"name"`
+	Path string `yaml:"path" json:"path"`
+}
+
+// UnmarshalYAML accepts both the `path/field@engine-path` shorthand and the
+// object form. A shorthand without a field leaves Field empty rather than
+// failing, so the job is still parsed and the reference can be reported.
+func (v *VaultSecret) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		type plain VaultSecret
+		return value.Decode((*plain)(v))
+	}
+
+	ref := value.Value
+	v.Engine = VaultEngine{Name: "kv-v2", Path: "secret"}
+	if at := strings.LastIndex(ref, "@"); at >= 0 {
+		ref, v.Engine.Path = ref[:at], ref[at+1:]
+	}
+	if slash := strings.LastIndex(ref, "/"); slash >= 0 {
+		v.Path, v.Field = ref[:slash], ref[slash+1:]
+	} else {
+		v.Path = ref
+	}
+	return nil
+}
+
+// AllowFailure is a job's allow_failure setting, written either as a bool or
+// as `exit_codes`, which lets the job fail only with the listed exit codes
+type AllowFailure struct {
+	Allowed   bool  `yaml:"-" json:"allowed"`
+	ExitCodes []int `yaml:"exit_codes,omitempty" json:"exit_codes,omitempty"`
+}
+
+// UnmarshalYAML accepts `allow_failure: true` and the exit_codes form, with
+// exit_codes given as a single code or a list
+func (a *AllowFailure) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return value.Decode(&a.Allowed)
+	case yaml.MappingNode:
+		var raw struct {
+			ExitCodes yaml.Node `yaml:"exit_codes"`
+		}
+		if err := value.Decode(&raw); err != nil {
+			return err
+		}
+		a.Allowed = true
+		switch raw.ExitCodes.Kind {
+		case yaml.ScalarNode:
+			var code int
+			if err := raw.ExitCodes.Decode(&code); err != nil {
+				return err
+			}
+			a.ExitCodes = []int{code}
+		case yaml.SequenceNode:
+			return raw.ExitCodes.Decode(&a.ExitCodes)
+		}
+		return nil
+	default:
+		return fmt.Errorf("line %d: allow_failure must be a bool or a mapping", value.Line)
+	}
+}
+
+// MarshalYAML writes the bool form unless exit codes are set
+func (a *AllowFailure) MarshalYAML() (interface{}, error) {
+	if len(a.ExitCodes) == 0 {
+		return a.Allowed, nil
+	}
+	type plain AllowFailure
+	return (*plain)(a), nil
+}
+
+// IsBlanket reports whether the job may fail with any exit code
+func (a *AllowFailure) IsBlanket() bool {
+	return a != nil && a.Allowed && len(a.ExitCodes) == 0
+}
+
+// Inherit controls which global defaults and variables a job receives. Each
+// field is either a bool or a list of keyword/variable names.
+type Inherit struct {
+	Default   interface{} `yaml:"default,omitempty" json:"default,omitempty"`
+	Variables interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
+}
+
+// Image is a job image, written either as a plain string or as an object
+// with name, entrypoint and pull_policy
+type Image struct {
+	Name       string   `yaml:"name,omitempty" json:"name,omitempty"`
+	Entrypoint []string `yaml:"entrypoint,omitempty" json:"entrypoint,omitempty"`
+	PullPolicy []string `yaml:"pull_policy,omitempty" json:"pull_policy,omitempty"`
+}
+
+// UnmarshalYAML accepts both `image: node:18` and the object form. A single
+// pull_policy string is normalized to a one-element list.
+func (i *Image) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return value.Decode(&i.Name)
+	case yaml.MappingNode:
+		var raw struct {
+			Name       string    `yaml:"name"`
+			Entrypoint []string  `yaml:"entrypoint"`
+			PullPolicy yaml.Node `yaml:"pull_policy"`
+		}
+		if err := value.Decode(&raw); err != nil {
+			return err
+		}
+		i.Name = raw.Name
+		i.Entrypoint = raw.Entrypoint
+		switch raw.PullPolicy.Kind {
+		case yaml.ScalarNode:
+			i.PullPolicy = []string{raw.PullPolicy.Value}
+		case yaml.SequenceNode:
+			return raw.PullPolicy.Decode(&i.PullPolicy)
+		}
+		return nil
+	default:
+		return fmt.Errorf("line %d: image must be a string or a mapping", value.Line)
+	}
+}
+
+// MarshalYAML writes the short string form when only a name is set
+func (i *Image) MarshalYAML() (interface{}, error) {
+	if len(i.Entrypoint) == 0 && len(i.PullPolicy) == 0 {
+		return i.Name, nil
+	}
+	type plain Image
+	return (*plain)(i), nil
+}
+
+// GetName returns the image name, or an empty string when no image is set
+func (i *Image) GetName() string {
+	if i == nil {
+		return ""
+	}
+	return i.Name
+}
+
+// Service is a service container started alongside a job, written either as
+// a plain image name or as an object
+type Service struct {
+	Name       string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	Alias      string                 `yaml:"alias,omitempty" json:"alias,omitempty"`
+	Entrypoint []string               `yaml:"entrypoint,omitempty" json:"entrypoint,omitempty"`
+	Command    []string               `yaml:"command,omitempty" json:"command,omitempty"`
+	Variables  map[string]interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
+}
+
+// UnmarshalYAML accepts both `- postgres:16` and the object form
+func (s *Service) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return value.Decode(&s.Name)
+	case yaml.MappingNode:
+		type plain Service
+		return value.Decode((*plain)(s))
+	default:
+		return fmt.Errorf("line %d: service must be a string or a mapping", value.Line)
+	}
+}
+
+// MarshalYAML writes the short string form when only a name is set
+func (s Service) MarshalYAML() (interface{}, error) {
+	if s.Alias == "" && len(s.Entrypoint) == 0 && len(s.Command) == 0 && len(s.Variables) == 0 {
+		return s.Name, nil
+	}
+	type plain Service
+	return plain(s), nil
 }
 
 type Workflow struct {
-	Rules []Rule `yaml:"rules,omitempty" json:"rules,omitempty"`
+	// Name is the pipeline name shown in the UI and may reference variables
+	Name       string      `yaml:"name,omitempty" json:"name,omitempty"`
+	Rules      []Rule      `yaml:"rules,omitempty" json:"rules,omitempty"`
+	AutoCancel *AutoCancel `yaml:"auto_cancel,omitempty" json:"auto_cancel,omitempty"`
+}
+
+// AutoCancel controls which running pipelines GitLab cancels automatically
+type AutoCancel struct {
+	OnNewCommit  string `yaml:"on_new_commit,omitempty" json:"on_new_commit,omitempty"`
+	OnJobFailure string `yaml:"on_job_failure,omitempty" json:"on_job_failure,omitempty"`
 }
 
 // GetExtends returns the extends field as a slice of strings, handling both string and []string cases
@@ -138,6 +742,274 @@ func (j *JobConfig) GetExtends() []string {
 	}
 }
 
+// DefaultStages are the stages GitLab uses when a configuration declares none
+var DefaultStages = []string{"build", "test", "deploy"}
+
+// AvailableStages returns the stages jobs may use: the declared stages, or
+// GitLab's defaults when none are declared, plus the implicit .pre and .post
+func (c *GitLabConfig) AvailableStages() map[string]bool {
+	declared := c.Stages
+	if len(declared) == 0 {
+		declared = DefaultStages
+	}
+
+	stages := map[string]bool{".pre": true, ".post": true}
+	for _, stage := range declared {
+		stages[stage] = true
+	}
+	return stages
+}
+
+// JobStage returns the stage a job runs in, taking the templates it extends
+// into account and falling back to GitLab's default "test" stage
+func (c *GitLabConfig) JobStage(jobName string) string {
+	if job := c.Jobs[jobName]; job != nil && job.Stage != "" {
+		return job.Stage
+	}
+	chain := c.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if template := c.Jobs[chain[i]]; template.Stage != "" {
+			return template.Stage
+		}
+	}
+	return "test"
+}
+
+// JobEnvironment returns the job's environment merged along its extends
+// chain, as GitLab merges mappings: each field comes from the job or from the
+// closest template that sets it. It returns nil when none sets one.
+func (c *GitLabConfig) JobEnvironment(jobName string) *Environment {
+	var merged *Environment
+	for _, name := range append(c.ExtendsChain(jobName), jobName) {
+		job := c.Jobs[name]
+		if job == nil || job.Environment == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &Environment{}
+		}
+		merged.merge(job.Environment)
+	}
+	return merged
+}
+
+// merge overrides e's fields with the ones other sets
+func (e *Environment) merge(other *Environment) {
+	for _, field := range []struct {
+		dst *string
+		src string
+	}{
+		{&e.Name, other.Name},
+		{&e.URL, other.URL},
+		{&e.OnStop, other.OnStop},
+		{&e.Action, other.Action},
+		{&e.AutoStopIn, other.AutoStopIn},
+		{&e.Deployment, other.Deployment},
+		{&e.DeploymentTier, other.DeploymentTier},
+	} {
+		if field.src != "" {
+			*field.dst = field.src
+		}
+	}
+}
+
+// UnknownExtends returns, per job, the extends targets that aren't defined.
+// Call it once includes are resolved, since templates often live in
+// included files.
+func (c *GitLabConfig) UnknownExtends() map[string][]string {
+	unknown := make(map[string][]string)
+	for jobName, job := range c.Jobs {
+		if job == nil {
+			continue
+		}
+		for _, parent := range job.GetExtends() {
+			if _, exists := c.Jobs[parent]; !exists {
+				unknown[jobName] = append(unknown[jobName], parent)
+			}
+		}
+	}
+	return unknown
+}
+
+// JobSource returns the include a job was defined in, as a local path, remote
+// URL, template:<name> or <project>:<file>@<ref>. Jobs from the main file, and
+// unknown jobs, have no source.
+func (c *GitLabConfig) JobSource(jobName string) string {
+	return c.JobSources[jobName]
+}
+
+// JobSourceFile returns the file to edit to change a job: the main file for
+// its own jobs, local includes resolved against the main file's directory,
+// and the JobSource of other includes. It's empty for jobs from the main file
+// of a configuration parsed from data.
+func (c *GitLabConfig) JobSourceFile(jobName string) string {
+	source := c.JobSource(jobName)
+	switch {
+	case source == "":
+		return c.FilePath
+	case c.FilePath == "" || strings.Contains(source, ":"):
+		// Remote URLs, templates and project files
+		return source
+	default:
+		return filepath.Join(filepath.Dir(c.FilePath), strings.TrimPrefix(source, "/"))
+	}
+}
+
+// ExtendsChain returns the templates a job extends, transitively, in the
+// order GitLab merges them (furthest ancestor first). Unknown templates are
+// skipped and cycles are broken.
+func (c *GitLabConfig) ExtendsChain(jobName string) []string {
+	var chain []string
+	visited := map[string]bool{jobName: true}
+
+	var walk func(name string)
+	walk = func(name string) {
+		job, exists := c.Jobs[name]
+		if !exists || job == nil {
+			return
+		}
+		for _, parent := range job.GetExtends() {
+			if visited[parent] {
+				continue
+			}
+			visited[parent] = true
+			walk(parent)
+			if _, exists := c.Jobs[parent]; exists {
+				chain = append(chain, parent)
+			}
+		}
+	}
+	walk(jobName)
+
+	return chain
+}
+
+// InheritsDefault reports whether the job receives the given keyword from the
+// `default:` block, honoring `inherit:default`
+func (j *JobConfig) InheritsDefault(keyword string) bool {
+	if j.Inherit == nil {
+		return true
+	}
+	return inheritAllows(j.Inherit.Default, keyword)
+}
+
+// InheritsVariable reports whether the job receives the given global
+// variable, honoring `inherit:variables`
+func (j *JobConfig) InheritsVariable(name string) bool {
+	if j.Inherit == nil {
+		return true
+	}
+	return inheritAllows(j.Inherit.Variables, name)
+}
+
+// InheritedVariables returns the global variables visible to the job
+func (c *GitLabConfig) InheritedVariables(job *JobConfig) map[string]interface{} {
+	if job == nil || job.Inherit == nil {
+		return c.Variables
+	}
+
+	inherited := make(map[string]interface{})
+	for key, value := range c.Variables {
+		if job.InheritsVariable(key) {
+			inherited[key] = value
+		}
+	}
+	return inherited
+}
+
+// JobVariable returns the value of a variable as the job sees it: set on the
+// job, on the nearest template it extends, or inherited from the global
+// variables
+func (c *GitLabConfig) JobVariable(jobName, name string) (string, bool) {
+	job := c.Jobs[jobName]
+	if job == nil {
+		return "", false
+	}
+	if value, exists := job.Variables[name]; exists {
+		return variableValueString(value), true
+	}
+	chain := c.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if value, exists := c.Jobs[chain[i]].Variables[name]; exists {
+			return variableValueString(value), true
+		}
+	}
+	if value, exists := c.InheritedVariables(job)[name]; exists {
+		return variableValueString(value), true
+	}
+	return "", false
+}
+
+// GitStrategy returns the job's GIT_STRATEGY (clone, fetch or none), or an
+// empty string when it's left to the project setting
+func (c *GitLabConfig) GitStrategy(jobName string) string {
+	strategy, _ := c.JobVariable(jobName, "GIT_STRATEGY")
+	return strings.ToLower(strings.TrimSpace(strategy))
+}
+
+// GitDepth returns the job's GIT_DEPTH. 0 means the full history is fetched.
+// ok is false when it's unset, left to the project setting, or not a number.
+func (c *GitLabConfig) GitDepth(jobName string) (depth int, ok bool) {
+	value, exists := c.JobVariable(jobName, "GIT_DEPTH")
+	if !exists {
+		return 0, false
+	}
+	depth, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || depth < 0 {
+		return 0, false
+	}
+	return depth, true
+}
+
+// JobHooks returns the job's hooks, its nearest template's, or the inherited
+// default hooks
+func (c *GitLabConfig) JobHooks(jobName string) *Hooks {
+	job := c.Jobs[jobName]
+	if job == nil {
+		return nil
+	}
+	if job.Hooks != nil {
+		return job.Hooks
+	}
+	chain := c.ExtendsChain(jobName)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if hooks := c.Jobs[chain[i]].Hooks; hooks != nil {
+			return hooks
+		}
+	}
+	if c.Default != nil && job.InheritsDefault("hooks") {
+		return c.Default.Hooks
+	}
+	return nil
+}
+
+// inheritAllows evaluates an inherit setting, which is either a bool or a
+// list of allowed names. Unset means everything is inherited.
+func inheritAllows(setting interface{}, name string) bool {
+	switch v := setting.(type) {
+	case nil:
+		return true
+	case bool:
+		return v
+	case []string:
+		for _, item := range v {
+			if item == name {
+				return true
+			}
+		}
+		return false
+	case []interface{}:
+		for _, item := range v {
+			if str, ok := item.(string); ok && str == name {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
 func (c *GitLabConfig) GetDependencyGraph() map[string][]string {
 	graph := make(map[string][]string)
 
@@ -148,24 +1020,7 @@ func (c *GitLabConfig) GetDependencyGraph() map[string][]string {
 			deps = append(deps, job.Dependencies...)
 		}
 
-		// Handle needs field which can be string array or object array
-		if job.Needs != nil {
-			switch needs := job.Needs.(type) {
-			case []interface{}:
-				for _, need := range needs {
-					switch n := need.(type) {
-					case string:
-						deps = append(deps, n)
-					case map[string]interface{}:
-						if jobName, ok := n["job"].(string); ok {
-							deps = append(deps, jobName)
-						}
-					}
-				}
-			case []string:
-				deps = append(deps, needs...)
-			}
-		}
+		deps = append(deps, job.NeedNames()...)
 
 		graph[jobName] = deps
 	}
@@ -2,7 +2,10 @@ package parser
 
 import (
 	"os"
+	"reflect"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestGetDependencyGraph(t *testing.T) {
@@ -43,3 +46,314 @@ func TestGetDependencyGraph(t *testing.T) {
 		t.Errorf("expected deploy:production to have 2 dependencies, got %v", deployProdDeps)
 	}
 }
+
+func TestExternalNeeds(t *testing.T) {
+	config, err := Parse([]byte(`
+build:
+  script: [make]
+test:
+  script: [make test]
+  needs:
+    - build
+    - project: group/library
+      job: build
+      ref: main
+    - pipeline: $PARENT_PIPELINE_ID
+      job: generate
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	graph := config.GetDependencyGraph()
+	if deps := graph["test"]; len(deps) != 1 || deps[0] != "build" {
+		t.Errorf("expected test to depend only on the local build job, got %v", deps)
+	}
+
+	external := config.Jobs["test"].ExternalNeeds()
+	if len(external) != 2 || external[0].Project != "group/library" || external[0].Ref != "main" || external[1].Pipeline != "$PARENT_PIPELINE_ID" {
+		t.Errorf("expected the project and pipeline needs, got %+v", external)
+	}
+}
+
+func TestParseImageForms(t *testing.T) {
+	yamlData := `
+image: alpine:3.19
+
+string_form:
+  image: node:18
+  script:
+    - npm test
+
+object_form:
+  image:
+    name: registry.example.com/tools:1.2
+    entrypoint: [""]
+    pull_policy: if-not-present
+  script:
+    - run
+
+policy_list:
+  image:
+    name: golang:1.22
+    pull_policy: [always, if-not-present]
+  script:
+    - go test ./...
+`
+	config, err := Parse([]byte(yamlData))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	if config.Image.GetName() != "alpine:3.19" {
+		t.Errorf("expected global image alpine:3.19, got %q", config.Image.GetName())
+	}
+
+	job := config.Jobs["string_form"]
+	if job == nil || job.Image.GetName() != "node:18" {
+		t.Fatalf("expected string_form image node:18, got %+v", job)
+	}
+
+	job = config.Jobs["object_form"]
+	if job == nil {
+		t.Fatal("expected object_form job to be parsed")
+	}
+	if job.Image.Name != "registry.example.com/tools:1.2" {
+		t.Errorf("expected object image name, got %q", job.Image.Name)
+	}
+	if len(job.Image.Entrypoint) != 1 || job.Image.Entrypoint[0] != "" {
+		t.Errorf("expected empty entrypoint override, got %v", job.Image.Entrypoint)
+	}
+	if len(job.Image.PullPolicy) != 1 || job.Image.PullPolicy[0] != "if-not-present" {
+		t.Errorf("expected single pull policy, got %v", job.Image.PullPolicy)
+	}
+
+	job = config.Jobs["policy_list"]
+	if job == nil || len(job.Image.PullPolicy) != 2 {
+		t.Fatalf("expected two pull policies, got %+v", job)
+	}
+}
+
+func TestImageMarshalYAML(t *testing.T) {
+	short, err := yaml.Marshal(&Image{Name: "node:18"})
+	if err != nil {
+		t.Fatalf("marshaling image: %v", err)
+	}
+	if string(short) != "node:18\n" {
+		t.Errorf("expected short string form, got %q", short)
+	}
+
+	full, err := yaml.Marshal(&Image{Name: "node:18", PullPolicy: []string{"always"}})
+	if err != nil {
+		t.Fatalf("marshaling image: %v", err)
+	}
+	var decoded Image
+	if err := yaml.Unmarshal(full, &decoded); err != nil {
+		t.Fatalf("round-tripping image: %v", err)
+	}
+	if decoded.Name != "node:18" || len(decoded.PullPolicy) != 1 {
+		t.Errorf("expected object form to round-trip, got %+v", decoded)
+	}
+
+	var nilImage *Image
+	if nilImage.GetName() != "" {
+		t.Error("expected nil image to have an empty name")
+	}
+}
+
+func TestParseInterruptibleAndAutoCancel(t *testing.T) {
+	yamlData := `
+default:
+  interruptible: true
+
+workflow:
+  name: "MR pipeline for $CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"
+  auto_cancel:
+    on_new_commit: interruptible
+    on_job_failure: all
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+
+deploy:
+  interruptible: false
+  script:
+    - ./deploy.sh
+
+test:
+  script:
+    - make test
+`
+	config, err := Parse([]byte(yamlData))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	if config.Default == nil || config.Default.Interruptible == nil || !*config.Default.Interruptible {
+		t.Error("expected default interruptible to be true")
+	}
+	if deploy := config.Jobs["deploy"]; deploy.Interruptible == nil || *deploy.Interruptible {
+		t.Error("expected deploy interruptible to be explicitly false")
+	}
+	if config.Jobs["test"].Interruptible != nil {
+		t.Error("expected unset interruptible to be nil")
+	}
+	if config.Workflow.AutoCancel == nil || config.Workflow.AutoCancel.OnNewCommit != "interruptible" {
+		t.Errorf("expected auto_cancel on_new_commit to be parsed, got %+v", config.Workflow.AutoCancel)
+	}
+	if config.Workflow.AutoCancel.OnJobFailure != "all" {
+		t.Errorf("expected auto_cancel on_job_failure to be parsed, got %+v", config.Workflow.AutoCancel)
+	}
+	if config.Workflow.Name != "MR pipeline for $CI_MERGE_REQUEST_SOURCE_BRANCH_NAME" {
+		t.Errorf("expected workflow name to be parsed, got %q", config.Workflow.Name)
+	}
+}
+
+func TestInherit(t *testing.T) {
+	yamlData := `
+variables:
+  FOO: foo
+  BAR: bar
+
+no_defaults:
+  inherit:
+    default: false
+    variables: [FOO]
+  script:
+    - echo
+
+some_defaults:
+  inherit:
+    default: [image, cache]
+  script:
+    - echo
+
+plain:
+  script:
+    - echo
+`
+	config, err := Parse([]byte(yamlData))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	noDefaults := config.Jobs["no_defaults"]
+	if noDefaults.Inherit == nil {
+		t.Fatal("expected inherit to be parsed")
+	}
+	if noDefaults.InheritsDefault("image") {
+		t.Error("expected inherit:default false to block default keywords")
+	}
+	if !noDefaults.InheritsVariable("FOO") || noDefaults.InheritsVariable("BAR") {
+		t.Error("expected only FOO to be inherited")
+	}
+	if vars := config.InheritedVariables(noDefaults); len(vars) != 1 || vars["FOO"] != "foo" {
+		t.Errorf("expected only FOO in inherited variables, got %v", vars)
+	}
+
+	someDefaults := config.Jobs["some_defaults"]
+	if !someDefaults.InheritsDefault("cache") || someDefaults.InheritsDefault("before_script") {
+		t.Error("expected only listed default keywords to be inherited")
+	}
+	if !someDefaults.InheritsVariable("BAR") {
+		t.Error("expected variables to be inherited when not restricted")
+	}
+
+	plain := config.Jobs["plain"]
+	if !plain.InheritsDefault("image") || len(config.InheritedVariables(plain)) != 2 {
+		t.Error("expected jobs without inherit to receive everything")
+	}
+}
+
+func TestExtendsChain(t *testing.T) {
+	config := &GitLabConfig{
+		Jobs: map[string]*JobConfig{
+			".base":  {},
+			".lint":  {Extends: ".base"},
+			".cache": {},
+			"job":    {Extends: []interface{}{".lint", ".cache", ".missing"}},
+			".loopA": {Extends: ".loopB"},
+			".loopB": {Extends: ".loopA"},
+		},
+	}
+
+	chain := config.ExtendsChain("job")
+	expected := []string{".base", ".lint", ".cache"}
+	if len(chain) != len(expected) {
+		t.Fatalf("expected chain %v, got %v", expected, chain)
+	}
+	for i := range expected {
+		if chain[i] != expected[i] {
+			t.Errorf("expected chain %v, got %v", expected, chain)
+			break
+		}
+	}
+
+	if loop := config.ExtendsChain(".loopA"); len(loop) != 1 || loop[0] != ".loopB" {
+		t.Errorf("expected cycle to be broken, got %v", loop)
+	}
+}
+
+func TestJobEnvironment(t *testing.T) {
+	config := &GitLabConfig{
+		Jobs: map[string]*JobConfig{
+			".deploy":  {Environment: &Environment{Name: "staging", URL: "https://staging.example.com"}},
+			".prod":    {Extends: ".deploy", Environment: &Environment{Name: "production"}},
+			"deploy":   {Extends: ".prod", Environment: &Environment{OnStop: "stop"}},
+			"plain":    {},
+			"override": {Extends: ".prod", Environment: &Environment{URL: "https://example.com"}},
+		},
+	}
+
+	expected := &Environment{Name: "production", URL: "https://staging.example.com", OnStop: "stop"}
+	if environment := config.JobEnvironment("deploy"); !reflect.DeepEqual(environment, expected) {
+		t.Errorf("expected %+v, got %+v", expected, environment)
+	}
+	if environment := config.JobEnvironment("override"); environment == nil || environment.URL != "https://example.com" {
+		t.Errorf("expected the job's URL to win, got %+v", environment)
+	}
+	if environment := config.JobEnvironment("plain"); environment != nil {
+		t.Errorf("expected no environment, got %+v", environment)
+	}
+	if config.Jobs[".deploy"].Environment.Name != "staging" {
+		t.Error("expected the templates to be left unchanged")
+	}
+}
+
+func TestParseTriggerOnlyJob(t *testing.T) {
+	config, err := Parse([]byte(`
+downstream:
+  trigger:
+    project: group/project
+    branch: main
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+	if job := config.Jobs["downstream"]; job == nil || job.Trigger == nil {
+		t.Errorf("expected trigger-only job to be parsed, got %+v", job)
+	}
+}
+
+func TestParseEnvironmentForms(t *testing.T) {
+	config, err := Parse([]byte(`
+deploy_staging:
+  script: [./deploy.sh]
+  environment: staging
+deploy_production:
+  script: [./deploy.sh]
+  environment:
+    name: production
+    url: https://example.com
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	if job := config.Jobs["deploy_staging"]; job == nil || job.Environment == nil || job.Environment.Name != "staging" {
+		t.Errorf("expected string-form environment to be parsed, got %+v", job)
+	}
+	if job := config.Jobs["deploy_production"]; job == nil || job.Environment == nil ||
+		job.Environment.Name != "production" || job.Environment.URL != "https://example.com" {
+		t.Errorf("expected object-form environment to be parsed, got %+v", job)
+	}
+}
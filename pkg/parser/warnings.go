@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseWarning is a non-fatal problem found while parsing
+type ParseWarning struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (w ParseWarning) String() string {
+	return fmt.Sprintf("line %d: %s", w.Line, w.Message)
+}
+
+// removeDuplicateKeys walks every mapping in the document, drops all but the
+// last definition of each repeated key (matching yaml's last-wins semantics)
+// and returns a warning per dropped definition. Repeated `<<` merge keys are
+// kept, since each merges in its own mappings.
+func removeDuplicateKeys(node *yaml.Node) []ParseWarning {
+	var warnings []ParseWarning
+
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			warnings = append(warnings, removeDuplicateKeys(child)...)
+		}
+	case yaml.MappingNode:
+		lastIndex := make(map[string]int)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i]; key.Kind == yaml.ScalarNode && !isMergeKey(key) {
+				lastIndex[key.Value] = i
+			}
+		}
+
+		kept := make([]*yaml.Node, 0, len(node.Content))
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if last, tracked := lastIndex[key.Value]; tracked && key.Kind == yaml.ScalarNode && !isMergeKey(key) && last != i {
+				warnings = append(warnings, ParseWarning{
+					Line: key.Line,
+					Message: fmt.Sprintf("duplicate key %q is overridden by the definition at line %d",
+						key.Value, node.Content[last].Line),
+				})
+				continue
+			}
+			warnings = append(warnings, removeDuplicateKeys(value)...)
+			kept = append(kept, key, value)
+		}
+		node.Content = kept
+	}
+
+	return warnings
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+const duplicateKeysYAML = `variables:
+  FOO: one
+
+build:
+  script:
+    - make one
+
+variables:
+  FOO: two
+
+build:
+  stage: build
+  script:
+    - make two
+  variables:
+    A: "1"
+    A: "2"
+`
+
+func TestParseWithWarnings_DuplicateKeys(t *testing.T) {
+	config, warnings, err := ParseWithWarnings([]byte(duplicateKeysYAML), ParseOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedLines := map[int]string{1: "variables", 4: "build", 16: "A"}
+	if len(warnings) != len(expectedLines) {
+		t.Fatalf("Expected %d warnings, got %d: %v", len(expectedLines), len(warnings), warnings)
+	}
+	for _, warning := range warnings {
+		key, exists := expectedLines[warning.Line]
+		if !exists || !strings.Contains(warning.Message, `"`+key+`"`) {
+			t.Errorf("Unexpected warning: %s", warning)
+		}
+	}
+
+	// The last definition wins
+	if config.Variables["FOO"] != "two" {
+		t.Errorf("Expected FOO=two, got %v", config.Variables["FOO"])
+	}
+	build := config.Jobs["build"]
+	if build == nil || build.Stage != "build" || build.Script[0] != "make two" {
+		t.Errorf("Expected the second build job to be kept, got %+v", build)
+	}
+	if build != nil && build.Variables["A"] != "2" {
+		t.Errorf("Expected nested duplicate to keep the last value, got %v", build.Variables["A"])
+	}
+}
+
+func TestParseWithWarnings_DuplicateKeysAsErrors(t *testing.T) {
+	_, warnings, err := ParseWithWarnings([]byte(duplicateKeysYAML), ParseOptions{DuplicateKeysAsErrors: true})
+	if err == nil {
+		t.Fatal("Expected an error for duplicate keys")
+	}
+	if !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected error to reference the source line, got %v", err)
+	}
+	if len(warnings) == 0 {
+		t.Error("Expected warnings to be returned with the error")
+	}
+
+	if _, err := Parse([]byte(duplicateKeysYAML)); err == nil {
+		t.Error("Expected Parse to reject duplicate keys")
+	}
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 )
@@ -8,10 +9,23 @@ import (
 // PipelineContext represents the context in which a pipeline is running
 type PipelineContext struct {
 	Branch       string            // Current branch name
-	Variables    map[string]string // GitLab predefined and custom variables
-	Event        string            // push, merge_request_event, schedule, api, etc.
+	Tag          string            // Tag name, for tag pipelines
+	Variables    map[string]string // Pipeline variables, which take precedence over YAML variables
+	Event        string            // CI_PIPELINE_SOURCE, one of PipelineSources
 	IsMR         bool              // Whether this is a merge request pipeline
 	IsMainBranch bool              // Whether this is the main/default branch
+
+	// Predefined overrides or adds GitLab predefined variables, such as
+	// CI_COMMIT_REF_NAME, on top of those derived from the fields above.
+	// See PredefinedVariables.
+	Predefined map[string]string
+
+	// ChangedFiles and ExistingFiles are the repository paths that
+	// rules:changes and rules:exists are matched against, see
+	// RepositoryFiles. While nil, those conditions can't be decided: job
+	// rules assume they match and workflow rules assume they don't.
+	ChangedFiles  []string
+	ExistingFiles []string
 }
 
 // WorkflowEvaluator evaluates workflow rules to determine if a pipeline should be created
@@ -30,160 +44,301 @@ func NewWorkflowEvaluator(config *GitLabConfig, context *PipelineContext) *Workf
 
 // ShouldCreatePipeline evaluates workflow rules to determine if a pipeline should be created
 func (w *WorkflowEvaluator) ShouldCreatePipeline() bool {
+	created, _ := w.config.EvaluateWorkflow(w.context)
+	return created
+}
+
+// EvaluateWorkflow evaluates workflow:rules in order and reports whether a
+// pipeline would be created in the given context. When the matching rule
+// defines variables, they are returned so they can be applied to every job.
+func (c *GitLabConfig) EvaluateWorkflow(ctx *PipelineContext) (created bool, injectedVars map[string]string) {
+	injectedVars = make(map[string]string)
+
 	// If no workflow is defined, default behavior is to create pipeline for all events
-	if w.config.Workflow == nil || len(w.config.Workflow.Rules) == 0 {
-		return true
+	if c.Workflow == nil || len(c.Workflow.Rules) == 0 {
+		return true, injectedVars
 	}
 
-	// Evaluate each rule in order
-	for _, rule := range w.config.Workflow.Rules {
-		result := w.evaluateRule(&rule)
+	vars := c.expressionVariables(ctx, nil, nil)
 
-		// Rules are evaluated in order, first match wins
-		if result != nil {
-			return *result
+	// Rules are evaluated in order, first match wins
+	for _, rule := range c.Workflow.Rules {
+		if !workflowRuleMatches(&rule, vars, ctx) {
+			continue
 		}
-	}
 
-	// If no rule matches, default to not creating pipeline
-	return false
-}
+		if rule.When == "never" {
+			return false, injectedVars
+		}
 
-// evaluateRule evaluates a single workflow rule and returns true/false or nil if rule doesn't match
-func (w *WorkflowEvaluator) evaluateRule(rule *Rule) *bool {
-	// Check if condition matches
-	if !w.ruleConditionMatches(rule) {
-		return nil // Rule doesn't apply
+		for key, value := range rule.Variables {
+			injectedVars[key] = variableValueString(value)
+		}
+		return true, injectedVars
 	}
 
-	// If condition matches, check the when clause
-	switch rule.When {
-	case "never":
-		result := false
-		return &result
-	case "always", "":
-		result := true
-		return &result
-	default:
-		// For any other when value, default to creating pipeline
-		result := true
-		return &result
-	}
+	// If no rule matches, default to not creating pipeline
+	return false, injectedVars
 }
 
-// ruleConditionMatches checks if a rule's conditions match the current context
-func (w *WorkflowEvaluator) ruleConditionMatches(rule *Rule) bool {
+// workflowRuleMatches checks if a workflow rule's conditions match
+func workflowRuleMatches(rule *Rule, vars map[string]string, ctx *PipelineContext) bool {
 	// If no conditions are specified, rule matches all contexts
 	if rule.If == "" && len(rule.Changes) == 0 && len(rule.Exists) == 0 {
 		return true
 	}
 
-	// Evaluate 'if' condition
-	if rule.If != "" {
-		if !w.evaluateIfCondition(rule.If) {
-			return false
-		}
+	if rule.If != "" && !evaluateIf(rule.If, vars) {
+		return false
 	}
 
-	// For changes and exists, we can't fully evaluate without file system access
-	// For now, we'll assume they don't match if specified (conservative approach)
-	if len(rule.Changes) > 0 || len(rule.Exists) > 0 {
+	// Without the files, changes and exists conservatively don't match
+	if len(rule.Changes) > 0 && !ctx.changesMatch(rule.Changes, vars, false) {
+		return false
+	}
+	if len(rule.Exists) > 0 && !ctx.existsMatch(rule.Exists, vars, false) {
 		return false
 	}
 
 	return true
 }
 
-// evaluateIfCondition evaluates a GitLab CI 'if' expression
-func (w *WorkflowEvaluator) evaluateIfCondition(condition string) bool {
-	// This is a simplified implementation of GitLab's if condition evaluation
-	// In a full implementation, this would need to parse complex expressions
-
-	// Handle common patterns
-	condition = strings.TrimSpace(condition)
+// changesMatch evaluates rules:changes. Like GitLab, changes only filter
+// branch push, merge request and external pull request pipelines and are
+// true for other pipelines. unknown is returned when ChangedFiles isn't set.
+func (ctx *PipelineContext) changesMatch(patterns []string, vars map[string]string, unknown bool) bool {
+	switch {
+	case ctx.Tag != "":
+		return true
+	case ctx.Event == "", ctx.Event == "push", ctx.Event == "merge_request_event", ctx.Event == "external_pull_request_event":
+	default:
+		return true
+	}
+	if ctx.ChangedFiles == nil {
+		return unknown
+	}
+	return anyFileMatches(ctx.ChangedFiles, patterns, vars)
+}
 
-	// Handle variable comparisons
-	if strings.Contains(condition, "$CI_PIPELINE_SOURCE") {
-		return w.evaluateSourceCondition(condition)
+// existsMatch evaluates rules:exists, returning unknown when ExistingFiles
+// isn't set
+func (ctx *PipelineContext) existsMatch(patterns []string, vars map[string]string, unknown bool) bool {
+	if ctx.ExistingFiles == nil {
+		return unknown
 	}
+	return anyFileMatches(ctx.ExistingFiles, patterns, vars)
+}
 
-	if strings.Contains(condition, "$CI_COMMIT_BRANCH") {
-		return w.evaluateBranchCondition(condition)
+// evaluateIf evaluates an if expression, treating expressions that cannot be
+// parsed as matching so unsupported syntax doesn't hide jobs
+func evaluateIf(condition string, vars map[string]string) bool {
+	result, err := EvaluateExpression(condition, vars)
+	if err != nil {
+		return true
 	}
+	return result
+}
 
-	if strings.Contains(condition, "$CI_MERGE_REQUEST_ID") {
-		return w.evaluateMRCondition(condition)
+// expressionVariables builds the variables visible to rules:if expressions.
+// Precedence from lowest to highest: predefined, global YAML, workflow
+// injected, job YAML, then context (pipeline-level) variables. Global YAML
+// variables are limited to those the job inherits.
+func (c *GitLabConfig) expressionVariables(ctx *PipelineContext, workflowVars map[string]string, job *JobConfig) map[string]string {
+	vars := ctx.PredefinedVariables()
+
+	for key, value := range c.InheritedVariables(job) {
+		vars[key] = variableValueString(value)
+	}
+	for key, value := range workflowVars {
+		vars[key] = value
+	}
+	if job != nil {
+		for key, value := range job.Variables {
+			vars[key] = variableValueString(value)
+		}
+	}
+	for key, value := range ctx.Variables {
+		vars[key] = value
 	}
 
-	// Default to true for unknown conditions (conservative approach)
-	return true
+	return vars
 }
 
-// evaluateSourceCondition evaluates conditions involving $CI_PIPELINE_SOURCE
-func (w *WorkflowEvaluator) evaluateSourceCondition(condition string) bool {
-	source := w.context.Event
+// Placeholder project and commit used for predefined variables that don't
+// depend on the pipeline context
+const (
+	simulatedServerHost  = "gitlab.example.com"
+	simulatedNamespace   = "group"
+	simulatedProjectName = "project"
+	simulatedProjectPath = simulatedNamespace + "/" + simulatedProjectName
+	simulatedCommitSHA   = "0123456789abcdef0123456789abcdef01234567"
+	simulatedCommitTitle = "Update CI configuration"
+	simulatedUser        = "developer"
+)
+
+// PredefinedVariables returns GitLab's predefined CI variables as a
+// pipeline in this context sees them: derived from the branch, tag and
+// event, with placeholders for the project and commit, and overridden by
+// Predefined
+func (ctx *PipelineContext) PredefinedVariables() map[string]string {
+	vars := map[string]string{
+		"CI":                        "true",
+		"GITLAB_CI":                 "true",
+		"CI_SERVER":                 "yes",
+		"CI_SERVER_HOST":            simulatedServerHost,
+		"CI_SERVER_URL":             "https://" + simulatedServerHost,
+		"CI_API_V4_URL":             "https://" + simulatedServerHost + "/api/v4",
+		"CI_PROJECT_ID":             "1",
+		"CI_PROJECT_NAMESPACE":      simulatedNamespace,
+		"CI_PROJECT_ROOT_NAMESPACE": simulatedNamespace,
+		"CI_PROJECT_NAME":           simulatedProjectName,
+		"CI_PROJECT_PATH":           simulatedProjectPath,
+		"CI_PROJECT_PATH_SLUG":      refSlug(simulatedProjectPath),
+		"CI_PROJECT_URL":            "https://" + simulatedServerHost + "/" + simulatedProjectPath,
+		"CI_PROJECT_VISIBILITY":     "private",
+		"CI_REGISTRY":               "registry." + simulatedServerHost,
+		"CI_REGISTRY_IMAGE":         "registry." + simulatedServerHost + "/" + simulatedProjectPath,
+		"CI_PIPELINE_ID":            "1",
+		"CI_PIPELINE_IID":           "1",
+		"CI_PIPELINE_URL":           "https://" + simulatedServerHost + "/" + simulatedProjectPath + "/-/pipelines/1",
+		"CI_COMMIT_SHA":             simulatedCommitSHA,
+		"CI_COMMIT_SHORT_SHA":       simulatedCommitSHA[:8],
+		"CI_COMMIT_TITLE":           simulatedCommitTitle,
+		"CI_COMMIT_MESSAGE":         simulatedCommitTitle,
+		"CI_COMMIT_AUTHOR":          simulatedUser + " <" + simulatedUser + "@example.com>",
+		"GITLAB_USER_LOGIN":         simulatedUser,
+		"GITLAB_USER_EMAIL":         simulatedUser + "@example.com",
+	}
+
+	source := ctx.Event
 	if source == "" {
 		source = "push" // Default
 	}
+	vars["CI_PIPELINE_SOURCE"] = source
+	if source == "trigger" {
+		vars["CI_PIPELINE_TRIGGERED"] = "true"
+	}
 
-	// Handle equality checks
-	if strings.Contains(condition, "==") {
-		// Extract the comparison value
-		re := regexp.MustCompile(`\$CI_PIPELINE_SOURCE\s*==\s*"([^"]+)"`)
-		matches := re.FindStringSubmatch(condition)
-		if len(matches) > 1 {
-			return source == matches[1]
-		}
+	defaultBranch := "main"
+	if ctx.IsMainBranch && ctx.Branch != "" {
+		defaultBranch = ctx.Branch
+	}
+	vars["CI_DEFAULT_BRANCH"] = defaultBranch
+
+	ref := ctx.Branch
+	if ctx.Tag != "" {
+		ref = ctx.Tag
+	}
+	if ref != "" {
+		vars["CI_COMMIT_REF_NAME"] = ref
+		vars["CI_COMMIT_REF_SLUG"] = refSlug(ref)
+		// Assume the default branch and tags are protected, as is typical
+		vars["CI_COMMIT_REF_PROTECTED"] = fmt.Sprint(ctx.Tag != "" || (!ctx.IsMR && ref == defaultBranch))
 	}
 
-	// Handle inequality checks
-	if strings.Contains(condition, "!=") {
-		re := regexp.MustCompile(`\$CI_PIPELINE_SOURCE\s*!=\s*"([^"]+)"`)
-		matches := re.FindStringSubmatch(condition)
-		if len(matches) > 1 {
-			return source != matches[1]
+	switch {
+	case source == "external_pull_request_event":
+		// GitHub pull requests run on their source branch
+		vars["CI_EXTERNAL_PULL_REQUEST_IID"] = "1"
+		vars["CI_EXTERNAL_PULL_REQUEST_SOURCE_REPOSITORY"] = simulatedProjectPath
+		vars["CI_EXTERNAL_PULL_REQUEST_TARGET_REPOSITORY"] = simulatedProjectPath
+		vars["CI_EXTERNAL_PULL_REQUEST_TARGET_BRANCH_NAME"] = defaultBranch
+		if ctx.Branch != "" {
+			vars["CI_EXTERNAL_PULL_REQUEST_SOURCE_BRANCH_NAME"] = ctx.Branch
+			vars["CI_COMMIT_BRANCH"] = ctx.Branch
+		}
+	case ctx.IsMR:
+		// Merge request pipelines don't set CI_COMMIT_BRANCH
+		vars["CI_MERGE_REQUEST_ID"] = "1"
+		vars["CI_MERGE_REQUEST_IID"] = "1"
+		vars["CI_MERGE_REQUEST_EVENT_TYPE"] = "detached"
+		vars["CI_MERGE_REQUEST_PROJECT_PATH"] = simulatedProjectPath
+		vars["CI_MERGE_REQUEST_SOURCE_PROJECT_PATH"] = simulatedProjectPath
+		vars["CI_MERGE_REQUEST_TITLE"] = simulatedCommitTitle
+		vars["CI_MERGE_REQUEST_REF_PATH"] = "refs/merge-requests/1/head"
+		vars["CI_MERGE_REQUEST_TARGET_BRANCH_NAME"] = defaultBranch
+		if ctx.Branch != "" {
+			vars["CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"] = ctx.Branch
 		}
+	case ctx.Tag != "":
+		// Tag pipelines don't set CI_COMMIT_BRANCH either
+		vars["CI_COMMIT_TAG"] = ctx.Tag
+	case ctx.Branch != "":
+		vars["CI_COMMIT_BRANCH"] = ctx.Branch
 	}
 
-	return true
+	for key, value := range ctx.Predefined {
+		vars[key] = value
+	}
+
+	return vars
 }
 
-// evaluateBranchCondition evaluates conditions involving $CI_COMMIT_BRANCH
-func (w *WorkflowEvaluator) evaluateBranchCondition(condition string) bool {
-	branch := w.context.Branch
-	if branch == "" {
-		return false
+// variableNamePattern matches the names GitLab accepts for CI variables
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// IsPredefinedVariable reports whether a variable name belongs to GitLab's
+// predefined CI variables
+func IsPredefinedVariable(name string) bool {
+	return name == "CI" || strings.HasPrefix(name, "CI_") || strings.HasPrefix(name, "GITLAB_")
+}
+
+// SetVariable applies a KEY=VALUE assignment, e.g. from the command line.
+// Predefined variables go to Predefined and override the derived value;
+// any other name is set as a pipeline variable.
+func (ctx *PipelineContext) SetVariable(assignment string) error {
+	key, value, found := strings.Cut(assignment, "=")
+	key = strings.TrimSpace(key)
+	if !found || !variableNamePattern.MatchString(key) {
+		return fmt.Errorf("invalid variable %q, expected KEY=VALUE", assignment)
 	}
 
-	// Handle equality checks
-	if strings.Contains(condition, "==") {
-		re := regexp.MustCompile(`\$CI_COMMIT_BRANCH\s*==\s*"([^"]+)"`)
-		matches := re.FindStringSubmatch(condition)
-		if len(matches) > 1 {
-			return branch == matches[1]
+	if IsPredefinedVariable(key) {
+		if ctx.Predefined == nil {
+			ctx.Predefined = make(map[string]string)
 		}
+		ctx.Predefined[key] = value
+	} else {
+		if ctx.Variables == nil {
+			ctx.Variables = make(map[string]string)
+		}
+		ctx.Variables[key] = value
 	}
+	return nil
+}
 
-	// Handle inequality checks
-	if strings.Contains(condition, "!=") {
-		re := regexp.MustCompile(`\$CI_COMMIT_BRANCH\s*!=\s*"([^"]+)"`)
-		matches := re.FindStringSubmatch(condition)
-		if len(matches) > 1 {
-			return branch != matches[1]
+// refSlug mirrors CI_COMMIT_REF_SLUG: the ref lowercased, with anything but
+// letters and digits replaced by "-", shortened to 63 bytes and without
+// leading or trailing "-"
+func refSlug(ref string) string {
+	slug := []byte(strings.ToLower(ref))
+	for i, c := range slug {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			slug[i] = '-'
 		}
 	}
-
-	return true
+	if len(slug) > 63 {
+		slug = slug[:63]
+	}
+	return strings.Trim(string(slug), "-")
 }
 
-// evaluateMRCondition evaluates conditions involving merge request variables
-func (w *WorkflowEvaluator) evaluateMRCondition(condition string) bool {
-	// Simple implementation: check if this is a merge request pipeline
-	if strings.Contains(condition, "$CI_MERGE_REQUEST_ID") {
-		return w.context.IsMR
+// variableValueString converts a YAML variable value to its string form,
+// unwrapping the expanded {value: ..., description: ...} syntax
+func variableValueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if inner, exists := v["value"]; exists {
+			return variableValueString(inner)
+		}
+		return ""
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
 	}
-
-	return true
 }
 
 // DefaultPipelineContext creates a default pipeline context for main branch push
@@ -207,3 +362,127 @@ func MergeRequestPipelineContext(sourceBranch string) *PipelineContext {
 		IsMainBranch: false,
 	}
 }
+
+// TagPipelineContext creates a pipeline context for a pushed tag
+func TagPipelineContext(tag string) *PipelineContext {
+	return &PipelineContext{
+		Tag:       tag,
+		Variables: map[string]string{},
+		Event:     "push",
+	}
+}
+
+// ScheduledPipelineContext creates a pipeline context for a scheduled
+// pipeline on the default branch
+func ScheduledPipelineContext() *PipelineContext {
+	return SourcePipelineContext("schedule")
+}
+
+// SourcePipelineContext creates a pipeline context for the given pipeline
+// source on the default branch, e.g. "web" for a pipeline run from the UI
+func SourcePipelineContext(source string) *PipelineContext {
+	return &PipelineContext{
+		Branch:       "main",
+		Variables:    map[string]string{},
+		Event:        source,
+		IsMainBranch: true,
+	}
+}
+
+// PipelineSources are the values GitLab sets CI_PIPELINE_SOURCE to
+var PipelineSources = []string{
+	"push", "merge_request_event", "external_pull_request_event", "schedule", "web", "api",
+	"trigger", "pipeline", "parent_pipeline", "chat", "webide", "external",
+	"ondemand_dast_scan", "ondemand_dast_validation", "security_orchestration_policy",
+}
+
+// IsPipelineSource reports whether source is one of PipelineSources
+func IsPipelineSource(source string) bool {
+	for _, known := range PipelineSources {
+		if source == known {
+			return true
+		}
+	}
+	return false
+}
+
+// PipelineScenario is a labelled pipeline context used to probe rules
+type PipelineScenario struct {
+	Label   string
+	Context *PipelineContext
+}
+
+// RepresentativeScenarios returns pipeline contexts covering the common
+// pipeline sources: branch pushes, merge requests, external pull requests,
+// tags and the non-push sources on the default branch
+func RepresentativeScenarios() []PipelineScenario {
+	scenarios := []PipelineScenario{
+		{"push to default branch", DefaultPipelineContext()},
+		{"push to feature branch", &PipelineContext{Branch: "feature", Event: "push", Variables: map[string]string{}}},
+		{"merge request", MergeRequestPipelineContext("feature")},
+		{"external pull request", &PipelineContext{Branch: "feature", Event: "external_pull_request_event", Variables: map[string]string{}}},
+		{"tag", TagPipelineContext("v1.0.0")},
+		{"schedule", ScheduledPipelineContext()},
+	}
+
+	for _, source := range []string{"web", "api", "trigger", "pipeline", "parent_pipeline"} {
+		scenarios = append(scenarios, PipelineScenario{Label: source, Context: SourcePipelineContext(source)})
+	}
+
+	return scenarios
+}
+
+var (
+	// comparedLiteralPattern finds `$VAR == "value"` style comparisons so the
+	// probe contexts can also exercise user-defined variables
+	comparedLiteralPattern = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)\}?\s*(?:==|!=)\s*(?:"([^"]*)"|'([^']*)')`)
+	// referencedVariablePattern finds every variable an expression uses
+	referencedVariablePattern = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// RuleProbeContexts returns the representative pipeline contexts plus
+// variants that set each variable compared against a literal in the rules,
+// and each other user-defined variable they test, to a non-empty value
+func RuleProbeContexts(ruleSets ...[]Rule) []*PipelineContext {
+	var contexts []*PipelineContext
+	for _, scenario := range RepresentativeScenarios() {
+		contexts = append(contexts, scenario.Context)
+	}
+
+	seen := make(map[string]bool)
+	probe := func(name, value string) {
+		if seen[name+"="+value] {
+			return
+		}
+		seen[name+"="+value] = true
+
+		for _, ctx := range []*PipelineContext{
+			DefaultPipelineContext(),
+			MergeRequestPipelineContext("feature"),
+			TagPipelineContext("v1.0.0"),
+			ScheduledPipelineContext(),
+		} {
+			ctx.Variables[name] = value
+			contexts = append(contexts, ctx)
+		}
+	}
+
+	for _, rules := range ruleSets {
+		for _, rule := range rules {
+			compared := make(map[string]bool)
+			for _, match := range comparedLiteralPattern.FindAllStringSubmatch(rule.If, -1) {
+				compared[match[1]] = true
+				probe(match[1], match[2]+match[3])
+			}
+			// Predefined variables already vary across the scenarios
+			for _, match := range referencedVariablePattern.FindAllStringSubmatch(rule.If, -1) {
+				name := match[1]
+				if !compared[name] && !strings.HasPrefix(name, "CI_") && !strings.HasPrefix(name, "GITLAB_") {
+					probe(name, "true")
+				}
+			}
+		}
+	}
+
+	return contexts
+}
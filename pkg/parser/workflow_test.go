@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -318,3 +319,436 @@ func TestOnlyExceptEvaluation(t *testing.T) {
 		t.Error("except-main-job should run in MR")
 	}
 }
+
+func TestEvaluateWorkflowVariables(t *testing.T) {
+	yamlContent := `
+variables:
+  DEPLOY_TARGET: none
+
+workflow:
+  rules:
+    - if: $CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH
+      variables:
+        DEPLOY_TARGET: production
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+      variables:
+        DEPLOY_TARGET: review
+    - when: never
+
+deploy-production:
+  script:
+    - echo "deploy"
+  rules:
+    - if: $DEPLOY_TARGET == "production"
+
+deploy-review:
+  script:
+    - echo "review"
+  rules:
+    - if: $DEPLOY_TARGET == "review"
+
+job-override:
+  variables:
+    DEPLOY_TARGET: custom
+  script:
+    - echo "custom"
+  rules:
+    - if: $DEPLOY_TARGET == "custom"
+`
+
+	config, err := Parse([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	created, vars := config.EvaluateWorkflow(DefaultPipelineContext())
+	if !created {
+		t.Fatal("Expected pipeline to be created on main branch")
+	}
+	if vars["DEPLOY_TARGET"] != "production" {
+		t.Errorf("Expected injected DEPLOY_TARGET=production, got %q", vars["DEPLOY_TARGET"])
+	}
+
+	mainJobs := config.SimulateMainBranchPipeline()
+	if !mainJobs["deploy-production"] {
+		t.Error("deploy-production should run with workflow-injected variables")
+	}
+	if mainJobs["deploy-review"] {
+		t.Error("deploy-review should not run on main branch")
+	}
+	if !mainJobs["job-override"] {
+		t.Error("job-level variables should take precedence over workflow variables")
+	}
+
+	mrJobs := config.SimulateMergeRequestPipeline("feature")
+	if mrJobs["deploy-production"] || !mrJobs["deploy-review"] {
+		t.Errorf("Expected only deploy-review in MR pipeline, got %v", mrJobs)
+	}
+
+	created, vars = config.EvaluateWorkflow(&PipelineContext{Branch: "feature", Event: "push"})
+	if created {
+		t.Error("Expected no pipeline for feature branch push")
+	}
+	if len(vars) != 0 {
+		t.Errorf("Expected no injected variables when pipeline is not created, got %v", vars)
+	}
+}
+
+func TestEvaluateWorkflowWithoutRules(t *testing.T) {
+	config := &GitLabConfig{}
+
+	created, vars := config.EvaluateWorkflow(DefaultPipelineContext())
+	if !created {
+		t.Error("Pipeline should be created when no workflow is defined")
+	}
+	if vars == nil || len(vars) != 0 {
+		t.Errorf("Expected empty injected variables, got %v", vars)
+	}
+}
+
+func TestJobRulesHonorInheritVariables(t *testing.T) {
+	yamlContent := `
+variables:
+  RUN_E2E: "true"
+  RUN_LINT: "true"
+
+e2e:
+  inherit:
+    variables: false
+  script:
+    - make e2e
+  rules:
+    - if: $RUN_E2E == "true"
+
+lint:
+  inherit:
+    variables: [RUN_LINT]
+  script:
+    - make lint
+  rules:
+    - if: $RUN_LINT == "true" && $RUN_E2E == null
+`
+
+	config, err := Parse([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	jobs := config.SimulateMainBranchPipeline()
+	if jobs["e2e"] {
+		t.Error("e2e should not see global variables with inherit:variables false")
+	}
+	if !jobs["lint"] {
+		t.Error("lint should only inherit RUN_LINT")
+	}
+}
+
+func TestEvaluateRules(t *testing.T) {
+	vars := map[string]string{"CI_COMMIT_BRANCH": "main", "CI_PIPELINE_SOURCE": "push"}
+	allowFailure := true
+
+	tests := []struct {
+		name     string
+		rules    []Rule
+		expected RuleOutcome
+		wantErr  bool
+	}{
+		{
+			name:     "first match wins with default when",
+			rules:    []Rule{{If: `$CI_COMMIT_BRANCH == "main"`}, {When: "manual"}},
+			expected: RuleOutcome{Runs: true, When: "on_success"},
+		},
+		{
+			name:     "never stops evaluation",
+			rules:    []Rule{{If: `$CI_PIPELINE_SOURCE == "push"`, When: "never"}, {When: "always"}},
+			expected: RuleOutcome{},
+		},
+		{
+			name:     "no match",
+			rules:    []Rule{{If: `$CI_COMMIT_TAG`}},
+			expected: RuleOutcome{},
+		},
+		{
+			name:     "matching rule attributes",
+			rules:    []Rule{{When: "delayed", StartIn: "5 minutes", AllowFailure: &allowFailure, Variables: map[string]interface{}{"MODE": "fast"}}},
+			expected: RuleOutcome{Runs: true, When: "delayed", StartIn: "5 minutes", AllowFailure: true, Variables: map[string]string{"MODE": "fast"}},
+		},
+		{
+			name:    "changes cannot be evaluated",
+			rules:   []Rule{{If: `$CI_COMMIT_BRANCH == "main"`, Changes: []string{"src/**"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome, err := EvaluateRules(tt.rules, vars)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(outcome, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, outcome)
+			}
+		})
+	}
+}
+
+func TestTagAndScheduledPipelineContexts(t *testing.T) {
+	tagVars := TagPipelineContext("v1.2.0").PredefinedVariables()
+	if tagVars["CI_COMMIT_TAG"] != "v1.2.0" || tagVars["CI_COMMIT_REF_NAME"] != "v1.2.0" {
+		t.Errorf("Expected tag variables to be set, got %v", tagVars)
+	}
+	if tagVars["CI_COMMIT_REF_SLUG"] != "v1-2-0" {
+		t.Errorf("Expected CI_COMMIT_REF_SLUG v1-2-0, got %q", tagVars["CI_COMMIT_REF_SLUG"])
+	}
+	if _, exists := tagVars["CI_COMMIT_BRANCH"]; exists {
+		t.Error("Tag pipelines should not set CI_COMMIT_BRANCH")
+	}
+	if tagVars["CI_PIPELINE_SOURCE"] != "push" || tagVars["CI_COMMIT_REF_PROTECTED"] != "true" {
+		t.Errorf("Expected a protected push pipeline, got %v", tagVars)
+	}
+
+	scheduleVars := ScheduledPipelineContext().PredefinedVariables()
+	if scheduleVars["CI_PIPELINE_SOURCE"] != "schedule" || scheduleVars["CI_COMMIT_BRANCH"] != "main" {
+		t.Errorf("Expected a scheduled pipeline on main, got %v", scheduleVars)
+	}
+	if _, exists := scheduleVars["CI_COMMIT_TAG"]; exists {
+		t.Error("Scheduled pipelines should not set CI_COMMIT_TAG")
+	}
+
+	mrVars := MergeRequestPipelineContext("Feature/Login").PredefinedVariables()
+	if mrVars["CI_COMMIT_REF_SLUG"] != "feature-login" || mrVars["CI_COMMIT_REF_PROTECTED"] != "false" {
+		t.Errorf("Expected an unprotected feature-login ref, got %v", mrVars)
+	}
+
+	config := &GitLabConfig{
+		Jobs: map[string]*JobConfig{
+			"release": {Rules: []Rule{{If: "$CI_COMMIT_TAG =~ /^v\\d+/"}}},
+			"nightly": {Rules: []Rule{{If: `$CI_PIPELINE_SOURCE == "schedule"`}}},
+			"publish": {Only: "tags"},
+		},
+	}
+
+	tagRuns := config.SimulatePipeline(TagPipelineContext("v1.2.0"))
+	if !tagRuns["release"] || !tagRuns["publish"] || tagRuns["nightly"] {
+		t.Errorf("Expected release and publish to run for tags, got %v", tagRuns)
+	}
+	scheduleRuns := config.SimulatePipeline(ScheduledPipelineContext())
+	if !scheduleRuns["nightly"] || scheduleRuns["release"] || scheduleRuns["publish"] {
+		t.Errorf("Expected only nightly to run on schedule, got %v", scheduleRuns)
+	}
+}
+
+func TestPipelineSourceContexts(t *testing.T) {
+	tests := []struct {
+		source string
+		only   string
+	}{
+		{"web", "web"},
+		{"api", "api"},
+		{"trigger", "triggers"},
+		{"pipeline", "pipelines"},
+		{"parent_pipeline", "pipelines"},
+		{"external_pull_request_event", "external_pull_requests"},
+		{"schedule", "schedules"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if !IsPipelineSource(tt.source) {
+				t.Errorf("Expected %s to be a known pipeline source", tt.source)
+			}
+			config := &GitLabConfig{
+				Jobs: map[string]*JobConfig{
+					"rules-job": {Rules: []Rule{{If: `$CI_PIPELINE_SOURCE == "` + tt.source + `"`}}},
+					"only-job":  {Only: []interface{}{tt.only}},
+				},
+			}
+			runs := config.SimulatePipeline(SourcePipelineContext(tt.source))
+			if !runs["rules-job"] || !runs["only-job"] {
+				t.Errorf("Expected both jobs to run for %s, got %v", tt.source, runs)
+			}
+			if runs := config.SimulatePipeline(DefaultPipelineContext()); runs["rules-job"] || runs["only-job"] {
+				t.Errorf("Expected neither job to run on push, got %v", runs)
+			}
+		})
+	}
+
+	ctx := &PipelineContext{Branch: "feature", Event: "external_pull_request_event", ChangedFiles: []string{"docs/index.md"}}
+	vars := ctx.PredefinedVariables()
+	if vars["CI_COMMIT_BRANCH"] != "feature" || vars["CI_EXTERNAL_PULL_REQUEST_SOURCE_BRANCH_NAME"] != "feature" {
+		t.Errorf("Expected external pull request variables for the source branch, got %v", vars)
+	}
+	if _, exists := vars["CI_MERGE_REQUEST_ID"]; exists {
+		t.Error("External pull request pipelines should not set CI_MERGE_REQUEST_ID")
+	}
+	// Like merge requests, external pull requests are filtered by changes
+	config := &GitLabConfig{Jobs: map[string]*JobConfig{"build": {Rules: []Rule{{Changes: []string{"src/**/*"}}}}}}
+	if config.SimulatePipeline(ctx)["build"] {
+		t.Error("Expected rules:changes to filter external pull request pipelines")
+	}
+	if !config.SimulatePipeline(SourcePipelineContext("web"))["build"] {
+		t.Error("Expected rules:changes to match web pipelines")
+	}
+
+	if IsPipelineSource("merge_request") {
+		t.Error("Expected merge_request not to be a pipeline source")
+	}
+}
+
+func TestSimulatePipelineWithFiles(t *testing.T) {
+	config := &GitLabConfig{
+		Variables: map[string]interface{}{"DOCKERFILE": "build/Dockerfile"},
+		Jobs: map[string]*JobConfig{
+			"docker": {Rules: []Rule{{Exists: []string{"$DOCKERFILE"}}}},
+			"docs":   {Rules: []Rule{{If: `$CI_COMMIT_BRANCH == "main"`, Changes: []string{"docs/**/*.md"}}}},
+			"python": {Rules: []Rule{{Exists: []string{"**/requirements*.txt"}}}},
+		},
+	}
+
+	// Without files, changes and exists are assumed to match
+	runs := config.SimulatePipeline(DefaultPipelineContext())
+	if !runs["docker"] || !runs["docs"] || !runs["python"] {
+		t.Errorf("Expected every job to run without file lists, got %v", runs)
+	}
+
+	ctx := DefaultPipelineContext()
+	ctx.ExistingFiles = []string{"build/Dockerfile", "src/main.go"}
+	ctx.ChangedFiles = []string{"src/main.go"}
+	runs = config.SimulatePipeline(ctx)
+	if !runs["docker"] || runs["docs"] || runs["python"] {
+		t.Errorf("Expected only docker to run, got %v", runs)
+	}
+
+	ctx.ChangedFiles = []string{"docs/guide/setup.md"}
+	ctx.ExistingFiles = []string{"services/api/requirements-dev.txt"}
+	runs = config.SimulatePipeline(ctx)
+	if runs["docker"] || !runs["docs"] || !runs["python"] {
+		t.Errorf("Expected docs and python to run, got %v", runs)
+	}
+
+	// changes don't filter tag or scheduled pipelines
+	schedule := ScheduledPipelineContext()
+	schedule.ChangedFiles = []string{}
+	if runs := config.SimulatePipeline(schedule); !runs["docs"] {
+		t.Errorf("Expected changes to be true in a scheduled pipeline, got %v", runs)
+	}
+
+	// Workflow rules only match on files when they are known
+	config.Workflow = &Workflow{Rules: []Rule{{Exists: []string{".gitlab-ci.yml"}}}}
+	if created, _ := config.EvaluateWorkflow(DefaultPipelineContext()); created {
+		t.Error("Expected the workflow not to match without file lists")
+	}
+	ctx.ExistingFiles = []string{".gitlab-ci.yml"}
+	if created, _ := config.EvaluateWorkflow(ctx); !created {
+		t.Error("Expected the workflow to match when .gitlab-ci.yml exists")
+	}
+}
+
+func TestRuleProbeContexts(t *testing.T) {
+	contexts := RuleProbeContexts([]Rule{
+		{If: `$DEPLOY_ENV == "staging" && $FORCE`},
+		{If: `$CI_COMMIT_BRANCH == "main"`},
+	})
+
+	probed := make(map[string]bool)
+	for _, ctx := range contexts {
+		for name, value := range ctx.Variables {
+			probed[name+"="+value] = true
+		}
+	}
+	for _, expected := range []string{"DEPLOY_ENV=staging", "FORCE=true", "CI_COMMIT_BRANCH=main"} {
+		if !probed[expected] {
+			t.Errorf("Expected a context with %s, got %v", expected, probed)
+		}
+	}
+	if probed["DEPLOY_ENV=true"] {
+		t.Error("Compared variables should only be probed with the compared value")
+	}
+	if len(contexts) != len(RepresentativeScenarios())+3*4 {
+		t.Errorf("Expected the scenarios plus 4 variants per probed value, got %d contexts", len(contexts))
+	}
+}
+
+func TestPredefinedVariablesInRegexRules(t *testing.T) {
+	config, err := Parse([]byte(`
+release:
+  script: [./release.sh]
+  rules:
+    - if: $CI_COMMIT_REF_NAME =~ /^release\/.*/ && $CI_DEFAULT_BRANCH == "main"
+
+visibility:
+  script: [./publish.sh]
+  rules:
+    - if: $CI_PROJECT_VISIBILITY == "public"
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	ctx := &PipelineContext{Branch: "release/1.4", Event: "push"}
+	if runs := config.SimulatePipeline(ctx); !runs["release"] || runs["visibility"] {
+		t.Errorf("Expected only release to run on a release branch, got %v", runs)
+	}
+	if runs := config.SimulatePipeline(DefaultPipelineContext()); runs["release"] {
+		t.Errorf("Expected release not to run on main, got %v", runs)
+	}
+
+	for _, assignment := range []string{"CI_PROJECT_VISIBILITY=public", "DEPLOY=true"} {
+		if err := ctx.SetVariable(assignment); err != nil {
+			t.Fatalf("SetVariable(%q) failed: %v", assignment, err)
+		}
+	}
+	if ctx.Predefined["CI_PROJECT_VISIBILITY"] != "public" || ctx.Variables["DEPLOY"] != "true" {
+		t.Errorf("Expected predefined and pipeline variables to be set apart, got %v and %v", ctx.Predefined, ctx.Variables)
+	}
+	if runs := config.SimulatePipeline(ctx); !runs["visibility"] {
+		t.Errorf("Expected the overridden predefined variable to be used, got %v", runs)
+	}
+
+	for _, assignment := range []string{"NOVALUE", "1BAD=x", "=x"} {
+		if err := ctx.SetVariable(assignment); err == nil {
+			t.Errorf("Expected SetVariable(%q) to fail", assignment)
+		}
+	}
+}
+
+func TestExplainJob(t *testing.T) {
+	config := &GitLabConfig{
+		Jobs: map[string]*JobConfig{
+			"rules-job": {
+				Rules: []Rule{
+					{If: `$CI_MERGE_REQUEST_ID`, When: "never"},
+					{If: `$CI_COMMIT_BRANCH == "main"`, When: "manual"},
+				},
+			},
+			"only-job":     {Only: []interface{}{"tags"}},
+			"no-rules-job": {},
+		},
+	}
+
+	tests := []struct {
+		job     string
+		context *PipelineContext
+		want    JobDecision
+	}{
+		{"rules-job", DefaultPipelineContext(), JobDecision{Runs: true, When: "manual", Reason: `rules[1] matched (if: $CI_COMMIT_BRANCH == "main")`}},
+		{"rules-job", MergeRequestPipelineContext("feature"), JobDecision{When: "never", Reason: "rules[0] matched (if: $CI_MERGE_REQUEST_ID) with when: never"}},
+		{"rules-job", &PipelineContext{Event: "push", Branch: "feature"}, JobDecision{Reason: "no rule matched"}},
+		{"only-job", DefaultPipelineContext(), JobDecision{Reason: "excluded by only/except"}},
+		{"no-rules-job", DefaultPipelineContext(), JobDecision{Runs: true, When: "on_success", Reason: "no rules, so it runs in every pipeline"}},
+	}
+
+	for _, tt := range tests {
+		got := config.ExplainJob(config.Jobs[tt.job], tt.context, nil)
+		if got != tt.want {
+			t.Errorf("ExplainJob(%s) = %+v, want %+v", tt.job, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package renderer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultPerPage is the page size requested from list endpoints, GitLab's
+// maximum
+const defaultPerPage = 100
+
+// LintResult is GitLab's verdict on a CI configuration from the project CI
+// lint API
+type LintResult struct {
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+	// MergedYAML is the configuration with every include resolved
+	MergedYAML string `json:"merged_yaml"`
+}
+
+// FetchMergedConfig returns the project's .gitlab-ci.yml at ref as merged by
+// GitLab, with every include resolved server-side. This reaches private
+// templates that local include resolution can't.
+func (c *GitLabClient) FetchMergedConfig(ctx context.Context, ref string) ([]byte, error) {
+	query := url.Values{}
+	query.Set("content_ref", ref)
+	query.Set("include_merged_yaml", "true")
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/ci/lint?%s", c.BaseURL, url.PathEscape(c.ProjectID), query.Encode())
+
+	resp, err := c.doRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+	}
+
+	var merged LintResult
+	if err := json.NewDecoder(resp.Body).Decode(&merged); err != nil {
+		return nil, err
+	}
+	if !merged.Valid {
+		return nil, fmt.Errorf("GitLab rejected the configuration at %s: %s", ref, strings.Join(merged.Errors, "; "))
+	}
+	if merged.MergedYAML == "" {
+		return nil, fmt.Errorf("GitLab returned no merged configuration for %s", ref)
+	}
+
+	return []byte(merged.MergedYAML), nil
+}
+
+// Lint validates content with the project's CI lint API, in the context of
+// the project at ref so that project-relative includes resolve, and returns
+// GitLab's errors, warnings and merged YAML
+func (c *GitLabClient) Lint(ctx context.Context, content string, ref string) (*LintResult, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"content":             content,
+		"ref":                 ref,
+		"include_merged_yaml": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/ci/lint", c.BaseURL, url.PathEscape(c.ProjectID))
+
+	resp, err := c.doRequest(ctx, "POST", endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+	}
+
+	var result LintResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// doRequest sends an authenticated API request, retrying transient failures
+// and rate limiting according to c.Retry. A non-nil body is sent as JSON.
+func (c *GitLabClient) doRequest(ctx context.Context, method, endpoint string, body []byte) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("PRIVATE-TOKEN", c.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.Retry.Do(c.Client, req)
+}
+
+// getAllPages fetches every page of a list endpoint and hands each page's body
+// to decodePage. It follows GitLab's X-Next-Page header, falling back to the
+// rel="next" entry of the Link header.
+func (c *GitLabClient) getAllPages(ctx context.Context, endpoint string, decodePage func(*json.Decoder) error) error {
+	perPage := c.PerPage
+	if perPage <= 0 {
+		perPage = defaultPerPage
+	}
+
+	pageURL, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	query := pageURL.Query()
+	query.Set("per_page", fmt.Sprint(perPage))
+	query.Set("page", "1")
+	pageURL.RawQuery = query.Encode()
+
+	visited := make(map[string]bool)
+	for pageURL != nil && !visited[pageURL.String()] {
+		visited[pageURL.String()] = true
+
+		resp, err := c.doRequest(ctx, "GET", pageURL.String(), nil)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+		}
+
+		err = decodePage(json.NewDecoder(resp.Body))
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		pageURL = nextPageURL(pageURL, resp.Header)
+	}
+
+	return nil
+}
+
+// nextPageURL returns the URL of the page after current, or nil on the last page
+func nextPageURL(current *url.URL, header http.Header) *url.URL {
+	if next := header.Get("X-Next-Page"); next != "" {
+		nextURL := *current
+		query := nextURL.Query()
+		query.Set("page", next)
+		nextURL.RawQuery = query.Encode()
+		return &nextURL
+	}
+
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		target, params, found := strings.Cut(strings.TrimSpace(link), ";")
+		if !found || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		nextURL, err := current.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err == nil {
+			return nextURL
+		}
+	}
+	return nil
+}
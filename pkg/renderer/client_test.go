@@ -0,0 +1,110 @@
+package renderer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGitLabClient_FetchMergedConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fproject/ci/lint" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("include_merged_yaml") != "true" {
+			t.Errorf("expected include_merged_yaml=true, got %q", r.URL.RawQuery)
+		}
+
+		switch r.URL.Query().Get("content_ref") {
+		case "main":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"valid":       true,
+				"errors":      []string{},
+				"merged_yaml": "build:\n  script: [make]\n",
+			})
+		case "broken":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"valid":  false,
+				"errors": []string{"jobs config should contain at least one visible job"},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewGitLabClient(server.URL, "test-token", "group/project")
+
+	data, err := client.FetchMergedConfig(ctx, "main")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.Contains(string(data), "script: [make]") {
+		t.Errorf("Expected the merged YAML, got %q", data)
+	}
+
+	if _, err := client.FetchMergedConfig(ctx, "broken"); err == nil || !strings.Contains(err.Error(), "visible job") {
+		t.Errorf("Expected GitLab's lint errors for an invalid config, got %v", err)
+	}
+
+	if _, err := client.FetchMergedConfig(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected a status error for a missing ref, got %v", err)
+	}
+
+	unauthorized := NewGitLabClient(server.URL, "wrong-token", "group/project")
+	if _, err := unauthorized.FetchMergedConfig(ctx, "main"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected a status error for a bad token, got %v", err)
+	}
+}
+
+func TestGitLabClient_Lint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v4/projects/123/ci/lint" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			Content           string `json:"content"`
+			Ref               string `json:"ref"`
+			IncludeMergedYAML bool   `json:"include_merged_yaml"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding request: %v", err)
+		}
+		if body.Ref != "develop" || !body.IncludeMergedYAML {
+			t.Errorf("unexpected request body: %+v", body)
+		}
+		json.NewEncoder(w).Encode(LintResult{
+			Valid:      !strings.Contains(body.Content, "stage: missing"),
+			Warnings:   []string{"jobs:build may allow multiple pipelines to run"},
+			MergedYAML: body.Content,
+		})
+	}))
+	defer server.Close()
+
+	client := NewGitLabClient(server.URL, "test-token", "123")
+	result, err := client.Lint(context.Background(), "build:\n  script: [make]\n", "develop")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !result.Valid || len(result.Warnings) != 1 || result.MergedYAML == "" {
+		t.Errorf("Unexpected lint result: %+v", result)
+	}
+
+	result, err = client.Lint(context.Background(), "build:\n  stage: missing\n", "develop")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Valid {
+		t.Error("Expected GitLab's verdict to be passed through")
+	}
+}
@@ -84,6 +84,7 @@ func (r *Renderer) compareExecutions(oldPipeline, newPipeline *PipelineExecution
 
 	comparison.Summary = summary
 	comparison.PerformanceGain = r.calculatePerformanceMetrics(oldPipeline, newPipeline)
+	comparison.StageMetrics, comparison.BottleneckStage = r.calculateStageMetrics(oldPipeline, newPipeline)
 
 	return comparison
 }
@@ -153,6 +154,55 @@ func (r *Renderer) calculatePerformanceMetrics(oldPipeline, newPipeline *Pipelin
 	}
 }
 
+// calculateStageMetrics aggregates job durations per stage, in pipeline
+// order, and marks the stage contributing most to the new pipeline's
+// wall-clock time as the bottleneck
+func (r *Renderer) calculateStageMetrics(oldPipeline, newPipeline *PipelineExecution) ([]StageMetric, string) {
+	var order []string
+	metrics := make(map[string]*StageMetric)
+	metricFor := func(stage string) *StageMetric {
+		if metric, exists := metrics[stage]; exists {
+			return metric
+		}
+		metric := &StageMetric{Stage: stage}
+		metrics[stage] = metric
+		order = append(order, stage)
+		return metric
+	}
+
+	for _, job := range newPipeline.Jobs {
+		metric := metricFor(job.Stage)
+		metric.NewJobs++
+		if job.Duration > metric.NewDuration {
+			metric.NewDuration = job.Duration
+		}
+	}
+	for _, job := range oldPipeline.Jobs {
+		metric := metricFor(job.Stage)
+		metric.OldJobs++
+		if job.Duration > metric.OldDuration {
+			metric.OldDuration = job.Duration
+		}
+	}
+
+	stageMetrics := make([]StageMetric, 0, len(order))
+	bottleneck := -1
+	for i, stage := range order {
+		metric := metrics[stage]
+		metric.Change = metric.NewDuration - metric.OldDuration
+		stageMetrics = append(stageMetrics, *metric)
+		if bottleneck < 0 || metric.NewDuration > stageMetrics[bottleneck].NewDuration {
+			bottleneck = i
+		}
+	}
+
+	if bottleneck < 0 || stageMetrics[bottleneck].NewDuration == 0 {
+		return stageMetrics, ""
+	}
+	stageMetrics[bottleneck].Bottleneck = true
+	return stageMetrics, stageMetrics[bottleneck].Stage
+}
+
 func (r *Renderer) calculateAverageJobDuration(jobs []JobExecution) float64 {
 	if len(jobs) == 0 {
 		return 0
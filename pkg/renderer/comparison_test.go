@@ -1,6 +1,7 @@
 package renderer
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
@@ -271,3 +272,38 @@ func TestRenderer_CalculatePerformanceMetrics(t *testing.T) {
 			expectedQueueReduction, metrics.StartupTimeReduction)
 	}
 }
+
+func TestRenderer_CalculateStageMetrics(t *testing.T) {
+	renderer := New(nil)
+
+	oldPipeline := &PipelineExecution{
+		Jobs: []JobExecution{
+			{Name: "build", Stage: "build", Duration: 60.0},
+			{Name: "unit", Stage: "test", Duration: 120.0},
+			{Name: "lint", Stage: "test", Duration: 30.0},
+		},
+	}
+	newPipeline := &PipelineExecution{
+		Jobs: []JobExecution{
+			{Name: "build", Stage: "build", Duration: 50.0},
+			{Name: "unit 1/2", Stage: "test", Duration: 70.0},
+			{Name: "unit 2/2", Stage: "test", Duration: 65.0},
+			{Name: "lint", Stage: "test", Duration: 30.0},
+			{Name: "deploy", Stage: "deploy", Duration: 40.0},
+		},
+	}
+
+	metrics, bottleneck := renderer.calculateStageMetrics(oldPipeline, newPipeline)
+
+	expected := []StageMetric{
+		{Stage: "build", OldDuration: 60, NewDuration: 50, Change: -10, OldJobs: 1, NewJobs: 1},
+		{Stage: "test", OldDuration: 120, NewDuration: 70, Change: -50, OldJobs: 2, NewJobs: 3, Bottleneck: true},
+		{Stage: "deploy", NewDuration: 40, Change: 40, NewJobs: 1},
+	}
+	if !reflect.DeepEqual(metrics, expected) {
+		t.Errorf("Expected stage metrics %+v, got %+v", expected, metrics)
+	}
+	if bottleneck != "test" {
+		t.Errorf("Expected bottleneck stage test, got %q", bottleneck)
+	}
+}
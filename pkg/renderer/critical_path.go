@@ -0,0 +1,66 @@
+package renderer
+
+import (
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// CriticalPath is the longest chain of jobs through a pipeline. Its duration
+// is the estimated wall-clock time of the pipeline given unlimited runners.
+type CriticalPath struct {
+	Jobs     []string `json:"jobs"`
+	Duration float64  `json:"duration"`
+}
+
+// PipelineCriticalPath estimates the critical path of the pipeline as
+// configured, where jobs without needs wait for all earlier stages
+func PipelineCriticalPath(config *parser.GitLabConfig) CriticalPath {
+	return criticalPath(config, false)
+}
+
+// NeedsCriticalPath estimates the critical path if every job that lists
+// dependencies but no needs switched to needing just those dependencies
+func NeedsCriticalPath(config *parser.GitLabConfig) CriticalPath {
+	return criticalPath(config, true)
+}
+
+// EstimateJobDuration returns the estimated duration of a job in seconds,
+// the same estimate used for simulated pipeline executions
+func EstimateJobDuration(config *parser.GitLabConfig, jobName string) float64 {
+	job := config.Jobs[jobName]
+	if job == nil {
+		return 0
+	}
+	return estimateJobDurationWithContext(job, config.Jobs)
+}
+
+func criticalPath(config *parser.GitLabConfig, dependenciesAsNeeds bool) CriticalPath {
+	order, waitsOn := orderJobs(config, dependenciesAsNeeds)
+
+	finish := make(map[string]float64, len(order))
+	previous := make(map[string]string, len(order))
+	var last string
+	for _, jobName := range order {
+		start := 0.0
+		for predecessor := range waitsOn[jobName] {
+			// Ties are broken by name so the reported path is deterministic
+			if finish[predecessor] > start || (finish[predecessor] == start && start > 0 && predecessor < previous[jobName]) {
+				start = finish[predecessor]
+				previous[jobName] = predecessor
+			}
+		}
+		finish[jobName] = start + EstimateJobDuration(config, jobName)
+		if last == "" || finish[jobName] > finish[last] {
+			last = jobName
+		}
+	}
+
+	if last == "" {
+		return CriticalPath{}
+	}
+
+	var jobs []string
+	for jobName := last; jobName != ""; jobName = previous[jobName] {
+		jobs = append([]string{jobName}, jobs...)
+	}
+	return CriticalPath{Jobs: jobs, Duration: finish[last]}
+}
@@ -0,0 +1,58 @@
+package renderer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func criticalPathConfig() *parser.GitLabConfig {
+	slowScript := make([]string, 20)
+	for i := range slowScript {
+		slowScript[i] = "make"
+	}
+	return &parser.GitLabConfig{
+		Stages: []string{"build", "test", "deploy"},
+		Jobs: map[string]*parser.JobConfig{
+			"build:fast": {Stage: "build", Script: []string{"make"}},
+			"build:slow": {Stage: "build", Script: slowScript},
+			"test":       {Stage: "test", Script: []string{"make test"}, Dependencies: []string{"build:fast"}},
+			"deploy":     {Stage: "deploy", Script: []string{"make deploy"}, Needs: []parser.Need{{Job: "test"}}},
+		},
+	}
+}
+
+func TestPipelineCriticalPath(t *testing.T) {
+	path := PipelineCriticalPath(criticalPathConfig())
+
+	// test waits for the whole build stage (70s), then test and deploy run
+	// back to back (32s each)
+	if path.Duration != 134 {
+		t.Errorf("Expected duration 134, got %v", path.Duration)
+	}
+	want := []string{"build:slow", "test", "deploy"}
+	if !reflect.DeepEqual(path.Jobs, want) {
+		t.Errorf("Expected path %v, got %v", want, path.Jobs)
+	}
+}
+
+func TestNeedsCriticalPath(t *testing.T) {
+	path := NeedsCriticalPath(criticalPathConfig())
+
+	// test only waits for build:fast, so build:slow alone is the longest chain
+	if path.Duration != 96 {
+		t.Errorf("Expected duration 96, got %v", path.Duration)
+	}
+	want := []string{"build:fast", "test", "deploy"}
+	if !reflect.DeepEqual(path.Jobs, want) {
+		t.Errorf("Expected path %v, got %v", want, path.Jobs)
+	}
+}
+
+func TestCriticalPath_Empty(t *testing.T) {
+	path := PipelineCriticalPath(&parser.GitLabConfig{})
+	if path.Duration != 0 || len(path.Jobs) != 0 {
+		t.Errorf("Expected empty critical path, got %+v", path)
+	}
+}
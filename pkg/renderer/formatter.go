@@ -20,13 +20,13 @@ func (r *Renderer) FormatComparison(comparison *PipelineComparison, format strin
 	case "table", "":
 		return r.formatComparisonTable(comparison), nil
 
-	case "dot", "mermaid":
+	case "dot", "mermaid", "plantuml":
 		// Visual formats require configuration data, which isn't available here
 		// These should be handled by RenderVisualComparison instead
 		return "", fmt.Errorf("visual format %s requires using RenderVisualComparison with configuration data", format)
 
 	default:
-		return "", fmt.Errorf("unsupported format: %s (supported: json, table, dot, mermaid)", format)
+		return "", fmt.Errorf("unsupported format: %s (supported: json, table, dot, mermaid, plantuml)", format)
 	}
 }
 
@@ -64,6 +64,21 @@ func (r *Renderer) formatComparisonTable(comparison *PipelineComparison) string
 	buf.WriteString(fmt.Sprintf("  Parallelism Improvement: %d jobs\n", perf.ParallelismImprovement))
 	buf.WriteString(fmt.Sprintf("  Startup Time Reduction: %.2fs\n", perf.StartupTimeReduction))
 
+	// Stage-level wall-clock
+	if len(comparison.StageMetrics) > 0 {
+		buf.WriteString("\nStage Durations:\n")
+		buf.WriteString("---------------\n")
+		buf.WriteString(fmt.Sprintf("  %-20s %10s %10s %10s\n", "Stage", "Old", "New", "Change"))
+		for _, metric := range comparison.StageMetrics {
+			marker := ""
+			if metric.Bottleneck {
+				marker = "  ← bottleneck"
+			}
+			buf.WriteString(fmt.Sprintf("  %-20s %9.2fs %9.2fs %+9.2fs%s\n",
+				metric.Stage, metric.OldDuration, metric.NewDuration, metric.Change, marker))
+		}
+	}
+
 	// Job-by-job comparison
 	buf.WriteString("\nJob Comparisons:\n")
 	buf.WriteString("---------------\n")
@@ -88,9 +103,35 @@ func (r *Renderer) formatComparisonTable(comparison *PipelineComparison) string
 		}
 	}
 
+	r.writeDurationBreakdown(&buf, comparison.NewExecution)
+
 	return buf.String()
 }
 
+// writeDurationBreakdown lists where the time of each simulated job goes, so
+// slow setup (worth caching dependencies) stands out from slow scripts
+// (worth splitting or parallelizing)
+func (r *Renderer) writeDurationBreakdown(buf *bytes.Buffer, execution *PipelineExecution) {
+	if execution == nil {
+		return
+	}
+	header := false
+	for _, job := range execution.Jobs {
+		if job.Breakdown == nil {
+			continue
+		}
+		if !header {
+			buf.WriteString("\nJob Duration Breakdown:\n")
+			buf.WriteString("----------------------\n")
+			buf.WriteString(fmt.Sprintf("  %-20s %9s %9s %9s %9s  %s\n", "Job", "Setup", "Script", "After", "Total", "Dominant"))
+			header = true
+		}
+		b := job.Breakdown
+		buf.WriteString(fmt.Sprintf("  %-20s %8.2fs %8.2fs %8.2fs %8.2fs  %s\n",
+			job.Name, b.Setup, b.Script, b.AfterScript, job.Duration, b.Dominant()))
+	}
+}
+
 func (r *Renderer) formatJobStatus(status CompareStatus) string {
 	switch status {
 	case StatusIdentical:
@@ -37,6 +37,16 @@ func TestRenderer_FormatComparison(t *testing.T) {
 				Changes: []string{"Job added to pipeline"},
 			},
 		},
+		StageMetrics: []StageMetric{
+			{Stage: "build", OldDuration: 60, NewDuration: 45, Change: -15, OldJobs: 1, NewJobs: 1, Bottleneck: true},
+			{Stage: "test", NewDuration: 30, Change: 30, NewJobs: 1},
+		},
+		BottleneckStage: "build",
+		NewExecution: &PipelineExecution{
+			Jobs: []JobExecution{
+				{Name: "build", Duration: 45, Breakdown: &DurationBreakdown{Setup: 35, Script: 10}},
+			},
+		},
 	}
 
 	// Test JSON format
@@ -68,6 +78,14 @@ func TestRenderer_FormatComparison(t *testing.T) {
 		t.Error("Expected table output to indicate improvement")
 	}
 
+	if !strings.Contains(tableOutput, "Stage Durations:") || !strings.Contains(tableOutput, "← bottleneck") {
+		t.Errorf("Expected table output to contain per-stage durations with the bottleneck, got:\n%s", tableOutput)
+	}
+
+	if !strings.Contains(tableOutput, "Job Duration Breakdown:") || !strings.Contains(tableOutput, "35.00s") || !strings.Contains(tableOutput, "setup\n") {
+		t.Errorf("Expected table output to contain the job duration breakdown, got:\n%s", tableOutput)
+	}
+
 	// Test invalid format
 	_, err = renderer.FormatComparison(comparison, "invalid")
 	if err == nil {
@@ -54,13 +54,7 @@ func (r *Renderer) RenderPipeline(ctx context.Context, pipelineID int) (*Pipelin
 
 func (r *Renderer) fetchPipeline(ctx context.Context, pipelineID int) (*PipelineExecution, error) {
 	url := fmt.Sprintf("%s/api/v4/projects/%s/pipelines/%d", r.client.BaseURL, r.client.ProjectID, pipelineID)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("PRIVATE-TOKEN", r.client.Token)
-	resp, err := r.client.Client.Do(req)
+	resp, err := r.client.doRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -80,24 +74,17 @@ func (r *Renderer) fetchPipeline(ctx context.Context, pipelineID int) (*Pipeline
 
 func (r *Renderer) fetchPipelineJobs(ctx context.Context, pipelineID int) ([]JobExecution, error) {
 	url := fmt.Sprintf("%s/api/v4/projects/%s/pipelines/%d/jobs", r.client.BaseURL, r.client.ProjectID, pipelineID)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("PRIVATE-TOKEN", r.client.Token)
-	resp, err := r.client.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
-	}
 
 	var jobs []JobExecution
-	if err := json.NewDecoder(resp.Body).Decode(&jobs); err != nil {
+	err := r.client.getAllPages(ctx, url, func(page *json.Decoder) error {
+		var pageJobs []JobExecution
+		if err := page.Decode(&pageJobs); err != nil {
+			return err
+		}
+		jobs = append(jobs, pageJobs...)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -111,8 +98,10 @@ func (r *Renderer) RenderVisualPipeline(config *parser.GitLabConfig, format stri
 		return r.visual.RenderPipelineGraph(config, FormatDOT)
 	case "mermaid":
 		return r.visual.RenderPipelineGraph(config, FormatMermaid)
+	case "plantuml":
+		return r.visual.RenderPipelineGraph(config, FormatPlantUML)
 	default:
-		return "", fmt.Errorf("unsupported visual format: %s (supported: dot, mermaid)", format)
+		return "", fmt.Errorf("unsupported visual format: %s (supported: dot, mermaid, plantuml)", format)
 	}
 }
 
@@ -123,7 +112,9 @@ func (r *Renderer) RenderVisualComparison(oldConfig, newConfig *parser.GitLabCon
 		return r.visual.RenderComparisonGraph(oldConfig, newConfig, comparison, FormatDOT)
 	case "mermaid":
 		return r.visual.RenderComparisonGraph(oldConfig, newConfig, comparison, FormatMermaid)
+	case "plantuml":
+		return r.visual.RenderComparisonGraph(oldConfig, newConfig, comparison, FormatPlantUML)
 	default:
-		return "", fmt.Errorf("unsupported visual format: %s (supported: dot, mermaid)", format)
+		return "", fmt.Errorf("unsupported visual format: %s (supported: dot, mermaid, plantuml)", format)
 	}
 }
@@ -7,6 +7,9 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/httpretry"
 )
 
 func TestRenderer_GitLabClientIntegration(t *testing.T) {
@@ -68,3 +71,73 @@ func TestRenderer_GitLabClientIntegration(t *testing.T) {
 		t.Errorf("Expected first job to be 'build', got %s", pipeline.Jobs[0].Name)
 	}
 }
+
+func TestRenderer_FetchPipelineJobsPaginated(t *testing.T) {
+	pages := map[string][]JobExecution{
+		"1": {{ID: 1, Name: "build"}, {ID: 2, Name: "lint"}},
+		"2": {{ID: 3, Name: "test"}},
+	}
+
+	for _, header := range []string{"X-Next-Page", "Link"} {
+		t.Run(header, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Query().Get("per_page"); got != "2" {
+					t.Errorf("Expected per_page=2, got %q", got)
+				}
+				page := r.URL.Query().Get("page")
+				if page == "1" {
+					if header == "Link" {
+						next := *r.URL
+						query := next.Query()
+						query.Set("page", "2")
+						next.RawQuery = query.Encode()
+						w.Header().Set("Link", `<http://`+r.Host+next.String()+`>; rel="next", <http://`+r.Host+r.URL.String()+`>; rel="first"`)
+					} else {
+						w.Header().Set("X-Next-Page", "2")
+					}
+				}
+				json.NewEncoder(w).Encode(pages[page])
+			}))
+			defer server.Close()
+
+			client := NewGitLabClient(server.URL, "test-token", "123")
+			client.PerPage = 2
+			jobs, err := New(client).fetchPipelineJobs(context.Background(), 7)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if len(jobs) != 3 {
+				t.Fatalf("Expected jobs from both pages, got %+v", jobs)
+			}
+			for i, name := range []string{"build", "lint", "test"} {
+				if jobs[i].Name != name {
+					t.Errorf("Expected job %d to be %s, got %s", i, name, jobs[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderer_FetchPipelineRetriesTransientErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(PipelineExecution{ID: 123, Status: "success"})
+	}))
+	defer server.Close()
+
+	client := NewGitLabClient(server.URL, "test-token", "123")
+	client.Retry = httpretry.Policy{BaseDelay: time.Millisecond}
+	pipeline, err := New(client).fetchPipeline(context.Background(), 123)
+	if err != nil {
+		t.Fatalf("Expected the retry to succeed, got: %v", err)
+	}
+	if pipeline.ID != 123 || requests != 2 {
+		t.Errorf("Expected pipeline 123 after 2 requests, got %d after %d", pipeline.ID, requests)
+	}
+}
@@ -2,8 +2,6 @@ package renderer
 
 import (
 	"fmt"
-	"sort"
-	"strings"
 	"time"
 
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
@@ -30,36 +28,29 @@ func (r *Renderer) simulatePipelineExecution(config *parser.GitLabConfig) *Pipel
 		UpdatedAt: time.Now(),
 	}
 
-	// Convert parsed jobs to job executions
-	for jobName, job := range config.Jobs {
-		if job == nil {
-			continue
-		}
-
-		// Skip template jobs (starting with .) as they don't run independently
-		if strings.HasPrefix(jobName, ".") {
-			continue
-		}
-
+	// Convert parsed jobs to job executions in the order they can start,
+	// following needs rather than only stage order. Template jobs (starting
+	// with .) are skipped as they don't run independently.
+	for _, jobName := range topologicalJobOrder(config) {
+		job := config.Jobs[jobName]
+		breakdown := estimateJobBreakdown(job, config.Jobs)
 		jobExec := JobExecution{
 			ID:             0, // Simulated
 			Name:           jobName,
 			Stage:          job.Stage,
 			Status:         "simulated",
 			Dependencies:   job.Dependencies,
-			Needs:          extractJobNames(job.Needs),
-			Duration:       estimateJobDurationWithContext(job, config.Jobs),
+			Needs:          job.NeedNames(),
+			Duration:       breakdown.total(),
 			QueuedDuration: 0,
+			Breakdown:      &breakdown,
 		}
 
 		pipeline.Jobs = append(pipeline.Jobs, jobExec)
 	}
 
-	// Sort jobs by stage order
-	sort.Slice(pipeline.Jobs, func(i, j int) bool {
-		return getStageOrder(pipeline.Jobs[i].Stage, config.Stages) <
-			getStageOrder(pipeline.Jobs[j].Stage, config.Stages)
-	})
+	// The simulated wall-clock duration is bounded by the critical path
+	pipeline.Duration = int(PipelineCriticalPath(config).Duration)
 
 	return pipeline
 }
@@ -80,36 +71,6 @@ func convertVariables(vars map[string]interface{}) map[string]string {
 	return result
 }
 
-func extractJobNames(needs interface{}) []string {
-	if needs == nil {
-		return []string{}
-	}
-
-	// Handle different need formats
-	switch v := needs.(type) {
-	case []string:
-		return v
-	case []interface{}:
-		names := make([]string, 0, len(v))
-		for _, item := range v {
-			if str, ok := item.(string); ok {
-				names = append(names, str)
-			} else if job, ok := item.(map[string]interface{}); ok {
-				if jobName, exists := job["job"]; exists {
-					if str, ok := jobName.(string); ok {
-						names = append(names, str)
-					}
-				}
-			}
-		}
-		return names
-	case string:
-		return []string{v}
-	default:
-		return []string{}
-	}
-}
-
 func estimateJobDuration(job *parser.JobConfig) float64 {
 	// Simple heuristic: base duration + script length factor
 	baseDuration := 30.0                           // 30 seconds base
@@ -127,40 +88,55 @@ func estimateJobDuration(job *parser.JobConfig) float64 {
 
 // estimateJobDurationWithContext considers template inheritance for more accurate estimation
 func estimateJobDurationWithContext(job *parser.JobConfig, allJobs map[string]*parser.JobConfig) float64 {
-	baseDuration := 30.0                           // 30 seconds base
+	breakdown := estimateJobBreakdown(job, allJobs)
+	return breakdown.total()
+}
+
+// estimateJobBreakdown splits a job's estimated duration into setup (image
+// pull, service startup and before_script), script and after_script time
+func estimateJobBreakdown(job *parser.JobConfig, allJobs map[string]*parser.JobConfig) DurationBreakdown {
+	setup := 30.0                                  // 30 seconds base for pulling the image and starting the job
 	scriptFactor := float64(len(job.Script)) * 2.0 // 2 seconds per script line
 
-	// Calculate before_script - either direct or from template
+	// Calculate before_script and after_script - either direct or from template
 	beforeScriptLines := len(job.BeforeScript)
+	afterScriptLines := len(job.AfterScript)
 
-	// If job uses extends, get before_script from template
+	// If job uses extends, get before_script and after_script from template
 	extendsTemplates := extractExtendsTemplates(job.Extends)
 	if len(extendsTemplates) > 0 {
 		for _, templateName := range extendsTemplates {
 			if template, exists := allJobs[templateName]; exists && template != nil {
 				beforeScriptLines += len(template.BeforeScript)
+				// The job's own after_script, or else the last template's, runs
+				if len(job.AfterScript) == 0 && len(template.AfterScript) > 0 {
+					afterScriptLines = len(template.AfterScript)
+				}
 			}
 		}
 	}
 
-	beforeScriptFactor := float64(beforeScriptLines) * 2.0
+	setup += float64(beforeScriptLines) * 2.0
 
 	if len(job.Services) > 0 {
-		baseDuration += 15.0 // Additional time for services
+		setup += 15.0 // Additional time for services
 	}
 
 	// Optimization bonus: if using templates, reduce overhead slightly due to better caching/reuse
-	optimizationBonus := 0.0
 	if len(extendsTemplates) > 0 {
-		optimizationBonus = 3.0 // Small improvement from template reuse
+		setup -= 3.0 // Small improvement from template reuse
 	}
 
-	duration := baseDuration + scriptFactor + beforeScriptFactor - optimizationBonus
-	if duration < 10.0 {
-		duration = 10.0 // Minimum duration
+	breakdown := DurationBreakdown{
+		Setup:       setup,
+		Script:      scriptFactor,
+		AfterScript: float64(afterScriptLines) * 2.0,
+	}
+	if total := breakdown.total(); total < 10.0 {
+		breakdown.Setup += 10.0 - total // Minimum duration
 	}
 
-	return duration
+	return breakdown
 }
 
 func extractExtendsTemplates(extends interface{}) []string {
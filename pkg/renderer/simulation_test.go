@@ -27,7 +27,7 @@ func TestRenderer_SimulatePipelineExecution(t *testing.T) {
 			"deploy": {
 				Stage:  "deploy",
 				Script: []string{"npm run deploy"},
-				Needs:  []interface{}{map[string]interface{}{"job": "test"}},
+				Needs:  []parser.Need{{Job: "test"}},
 			},
 		},
 	}
@@ -61,28 +61,10 @@ func TestRenderer_SimulatePipelineExecution(t *testing.T) {
 }
 
 func TestHelperFunctions(t *testing.T) {
-	// Test extractJobNames
-	needs := []interface{}{
-		map[string]interface{}{"job": "build"},
-		map[string]interface{}{"job": "test"},
-	}
-	names := extractJobNames(needs)
-	expected := []string{"build", "test"}
-
-	if len(names) != len(expected) {
-		t.Errorf("Expected %d names, got %d", len(expected), len(names))
-	}
-
-	for i, name := range names {
-		if name != expected[i] {
-			t.Errorf("Expected name %s at index %d, got %s", expected[i], i, name)
-		}
-	}
-
 	// Test estimateJobDuration
 	job := &parser.JobConfig{
 		Script:   []string{"echo hello", "npm test", "npm build"},
-		Services: []string{"postgres:13"},
+		Services: []parser.Service{{Name: "postgres:13"}},
 	}
 	duration := estimateJobDuration(job)
 	expectedDuration := 30.0 + (3.0 * 2.0) + 15.0 // base + scripts + services = 51.0
@@ -124,3 +106,57 @@ func abs(f float64) float64 {
 	}
 	return f
 }
+
+func TestSimulatedJobDurationBreakdown(t *testing.T) {
+	renderer := New(nil)
+
+	config := &parser.GitLabConfig{
+		Stages: []string{"test"},
+		Jobs: map[string]*parser.JobConfig{
+			".base": {
+				BeforeScript: []string{"npm ci", "npm run prepare"},
+				AfterScript:  []string{"rm -rf node_modules"},
+			},
+			"unit": {
+				Stage:    "test",
+				Extends:  ".base",
+				Services: []parser.Service{{Name: "postgres:16.2"}},
+				Script:   []string{"npm test"},
+			},
+			"e2e": {
+				Stage:       "test",
+				Extends:     ".base",
+				Script:      make([]string, 40),
+				AfterScript: []string{"collect-logs", "upload-logs"},
+			},
+		},
+	}
+
+	jobs := map[string]JobExecution{}
+	for _, job := range renderer.simulatePipelineExecution(config).Jobs {
+		jobs[job.Name] = job
+	}
+
+	unit := jobs["unit"]
+	if unit.Breakdown == nil {
+		t.Fatal("Expected a duration breakdown for simulated jobs")
+	}
+	// 30s image pull + 15s services + 2 before_script lines - 3s template bonus
+	if unit.Breakdown.Setup != 46 || unit.Breakdown.Script != 2 || unit.Breakdown.AfterScript != 2 {
+		t.Errorf("Unexpected breakdown for unit: %+v", *unit.Breakdown)
+	}
+	if unit.Duration != 50 || unit.Duration != EstimateJobDuration(config, "unit") {
+		t.Errorf("Expected the duration to be the sum of the breakdown, got %.2f", unit.Duration)
+	}
+	if unit.Breakdown.Dominant() != "setup" {
+		t.Errorf("Expected setup to dominate unit, got %s", unit.Breakdown.Dominant())
+	}
+
+	e2e := jobs["e2e"]
+	if e2e.Breakdown.AfterScript != 4 {
+		t.Errorf("Expected the job's own after_script to override the template's, got %+v", *e2e.Breakdown)
+	}
+	if e2e.Breakdown.Dominant() != "script" {
+		t.Errorf("Expected script to dominate e2e, got %s", e2e.Breakdown.Dominant())
+	}
+}
@@ -0,0 +1,238 @@
+package renderer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+// jobEdge is a directed relationship between two jobs: To waits on From
+type jobEdge struct {
+	From string
+	To   string
+	// Needs is set for needs relationships; otherwise the edge comes from
+	// dependencies alone
+	Needs bool
+}
+
+// jobStage returns the stage a job runs in, applying GitLab's default
+func jobStage(job *parser.JobConfig) string {
+	if job.Stage == "" {
+		return "test"
+	}
+	return job.Stage
+}
+
+// PipelineStages returns the stages in execution order: the declared stages
+// (or GitLab's defaults), wrapped by .pre and .post, with any undeclared
+// stages that jobs reference inserted before .post
+func PipelineStages(config *parser.GitLabConfig) []string {
+	declared := config.Stages
+	if len(declared) == 0 {
+		declared = parser.DefaultStages
+	}
+
+	stages := make([]string, 0, len(declared)+2)
+	seen := make(map[string]bool)
+	add := func(stage string) {
+		if !seen[stage] {
+			seen[stage] = true
+			stages = append(stages, stage)
+		}
+	}
+
+	add(".pre")
+	for _, stage := range declared {
+		if stage != ".post" {
+			add(stage)
+		}
+	}
+
+	var undeclared []string
+	for _, job := range config.ConcreteJobs() {
+		if stage := jobStage(job); !seen[stage] && stage != ".post" {
+			undeclared = append(undeclared, stage)
+		}
+	}
+	sort.Strings(undeclared)
+	for _, stage := range undeclared {
+		add(stage)
+	}
+
+	add(".post")
+	return stages
+}
+
+// jobEdges returns the needs and dependencies relationships between running
+// jobs, ordered by the dependent job's position in topologicalJobOrder.
+// A job listed under both needs and dependencies yields a single needs edge.
+func jobEdges(config *parser.GitLabConfig) []jobEdge {
+	jobs := config.ConcreteJobs()
+
+	var edges []jobEdge
+	for _, jobName := range topologicalJobOrder(config) {
+		job := jobs[jobName]
+		seen := make(map[string]bool)
+
+		needs := job.NeedNames()
+		sort.Strings(needs)
+		for _, need := range needs {
+			if _, exists := jobs[need]; exists && !seen[need] {
+				seen[need] = true
+				edges = append(edges, jobEdge{From: need, To: jobName, Needs: true})
+			}
+		}
+
+		deps := append([]string(nil), job.Dependencies...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if _, exists := jobs[dep]; exists && !seen[dep] {
+				seen[dep] = true
+				edges = append(edges, jobEdge{From: dep, To: jobName})
+			}
+		}
+	}
+
+	return edges
+}
+
+// externalEdge is a need on a job in another project or pipeline. Source
+// labels the external job, e.g. group/project:build@main.
+type externalEdge struct {
+	Source string
+	To     string
+}
+
+// externalEdges returns the needs of running jobs on jobs outside this
+// pipeline, ordered like jobEdges
+func externalEdges(config *parser.GitLabConfig) []externalEdge {
+	jobs := config.ConcreteJobs()
+
+	var edges []externalEdge
+	for _, jobName := range topologicalJobOrder(config) {
+		for _, need := range jobs[jobName].ExternalNeeds() {
+			edges = append(edges, externalEdge{Source: externalNeedLabel(need), To: jobName})
+		}
+	}
+	return edges
+}
+
+// externalNeedLabel names the job an external need refers to
+func externalNeedLabel(need parser.Need) string {
+	if need.Project != "" {
+		label := need.Project + ":" + need.Job
+		if need.Ref != "" {
+			label += "@" + need.Ref
+		}
+		return label
+	}
+	return "pipeline " + need.Pipeline + ":" + need.Job
+}
+
+// externalNodeIDs assigns each distinct external job a node ID, in order of
+// first appearance
+func externalNodeIDs(edges []externalEdge) ([]string, map[string]string) {
+	var sources []string
+	ids := make(map[string]string)
+	for _, edge := range edges {
+		if _, exists := ids[edge.Source]; !exists {
+			ids[edge.Source] = fmt.Sprintf("external_%d", len(sources))
+			sources = append(sources, edge.Source)
+		}
+	}
+	return sources, ids
+}
+
+// topologicalJobOrder orders running jobs by when they can start. A job with
+// needs waits only for the jobs it needs; a job without needs waits for every
+// job in earlier stages. Jobs that become ready together are ordered by stage
+// and then name. Jobs caught in a cycle are appended in the same order.
+func topologicalJobOrder(config *parser.GitLabConfig) []string {
+	order, _ := orderJobs(config, false)
+	return order
+}
+
+// orderJobs returns the topological job order along with the jobs each job
+// waits on. With dependenciesAsNeeds set, jobs that list dependencies but no
+// needs are treated as if they needed exactly those dependencies.
+func orderJobs(config *parser.GitLabConfig, dependenciesAsNeeds bool) ([]string, map[string]map[string]bool) {
+	jobs := config.ConcreteJobs()
+
+	stageIndex := make(map[string]int)
+	for i, stage := range PipelineStages(config) {
+		stageIndex[stage] = i
+	}
+
+	names := make([]string, 0, len(jobs))
+	for jobName := range jobs {
+		names = append(names, jobName)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		si, sj := stageIndex[jobStage(jobs[names[i]])], stageIndex[jobStage(jobs[names[j]])]
+		if si != sj {
+			return si < sj
+		}
+		return names[i] < names[j]
+	})
+
+	waitsOn := make(map[string]map[string]bool, len(names))
+	for _, jobName := range names {
+		job := jobs[jobName]
+		predecessors := make(map[string]bool)
+		if job.Needs != nil {
+			for _, need := range job.NeedNames() {
+				if _, exists := jobs[need]; exists && need != jobName {
+					predecessors[need] = true
+				}
+			}
+		} else if !dependenciesAsNeeds || len(job.Dependencies) == 0 {
+			stage := stageIndex[jobStage(job)]
+			for _, other := range names {
+				if stageIndex[jobStage(jobs[other])] < stage {
+					predecessors[other] = true
+				}
+			}
+		}
+		for _, dep := range job.Dependencies {
+			if _, exists := jobs[dep]; exists && dep != jobName {
+				predecessors[dep] = true
+			}
+		}
+		waitsOn[jobName] = predecessors
+	}
+
+	order := make([]string, 0, len(names))
+	placed := make(map[string]bool, len(names))
+	for len(order) < len(names) {
+		progressed := false
+		for _, jobName := range names {
+			if placed[jobName] || !allPlaced(waitsOn[jobName], placed) {
+				continue
+			}
+			placed[jobName] = true
+			order = append(order, jobName)
+			progressed = true
+			break
+		}
+		if !progressed {
+			for _, jobName := range names {
+				if !placed[jobName] {
+					placed[jobName] = true
+					order = append(order, jobName)
+				}
+			}
+		}
+	}
+
+	return order, waitsOn
+}
+
+func allPlaced(jobs map[string]bool, placed map[string]bool) bool {
+	for jobName := range jobs {
+		if !placed[jobName] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package renderer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+)
+
+func needsConfig() *parser.GitLabConfig {
+	return &parser.GitLabConfig{
+		Stages: []string{"build", "test", "deploy"},
+		Jobs: map[string]*parser.JobConfig{
+			"build:b": {Stage: "build"},
+			"build:a": {Stage: "build", Needs: []parser.Need{{Job: "build:b"}}},
+			"lint":    {Stage: "test", Needs: []parser.Need{}},
+			"test":    {Stage: "test"},
+			"deploy": {
+				Stage:        "deploy",
+				Needs:        []parser.Need{{Job: "build:a"}},
+				Dependencies: []string{"test"},
+			},
+			".template": {Stage: "build"},
+		},
+	}
+}
+
+func TestTopologicalJobOrder(t *testing.T) {
+	got := topologicalJobOrder(needsConfig())
+
+	// build:a waits on build:b in its own stage, lint has empty needs so
+	// it doesn't wait for the build stage, and deploy waits on both its
+	// needs and its dependencies
+	want := []string{"build:b", "build:a", "lint", "test", "deploy"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("topologicalJobOrder() = %v, want %v", got, want)
+	}
+}
+
+func TestTopologicalJobOrder_Cycle(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"a": {Stage: "build", Needs: []parser.Need{{Job: "b"}}},
+			"b": {Stage: "build", Needs: []parser.Need{{Job: "a"}}},
+			"c": {Stage: "build", Needs: []parser.Need{}},
+		},
+	}
+
+	got := topologicalJobOrder(config)
+	want := []string{"c", "a", "b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("topologicalJobOrder() = %v, want %v", got, want)
+	}
+}
+
+func TestPipelineStages(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Jobs: map[string]*parser.JobConfig{
+			"a": {Stage: "custom"},
+			"b": {},
+		},
+	}
+
+	got := PipelineStages(config)
+	want := []string{".pre", "build", "test", "deploy", "custom", ".post"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PipelineStages() = %v, want %v", got, want)
+	}
+}
+
+func TestJobEdges(t *testing.T) {
+	got := jobEdges(needsConfig())
+	want := []jobEdge{
+		{From: "build:b", To: "build:a", Needs: true},
+		{From: "build:a", To: "deploy", Needs: true},
+		{From: "test", To: "deploy"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("jobEdges() = %v, want %v", got, want)
+	}
+}
+
+func TestVisualRenderer_NeedsLayout(t *testing.T) {
+	vr := NewVisualRenderer()
+
+	dot, err := vr.RenderPipelineGraph(needsConfig(), FormatDOT)
+	if err != nil {
+		t.Fatalf("RenderPipelineGraph failed: %v", err)
+	}
+	if !strings.Contains(dot, `"build:b" -> "build:a" [style=bold, label="needs"];`) {
+		t.Errorf("Expected bold needs edge in DOT output, got:\n%s", dot)
+	}
+	if !strings.Contains(dot, `"test" -> "deploy";`) {
+		t.Errorf("Expected plain dependencies edge in DOT output, got:\n%s", dot)
+	}
+	if strings.Index(dot, `"build:b" [`) > strings.Index(dot, `"build:a" [`) {
+		t.Error("Expected build:b to be laid out before build:a, which needs it")
+	}
+
+	mermaid, err := vr.RenderPipelineGraph(needsConfig(), FormatMermaid)
+	if err != nil {
+		t.Fatalf("RenderPipelineGraph failed: %v", err)
+	}
+	if !strings.Contains(mermaid, "build_a ==> deploy") {
+		t.Errorf("Expected thick needs arrow in Mermaid output, got:\n%s", mermaid)
+	}
+	if !strings.Contains(mermaid, "test --> deploy") {
+		t.Errorf("Expected dependencies arrow in Mermaid output, got:\n%s", mermaid)
+	}
+
+	// Output must be stable across runs despite map iteration
+	for i := 0; i < 5; i++ {
+		again, _ := vr.RenderPipelineGraph(needsConfig(), FormatDOT)
+		if again != dot {
+			t.Fatal("Expected deterministic DOT output")
+		}
+	}
+}
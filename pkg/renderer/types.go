@@ -3,6 +3,8 @@ package renderer
 import (
 	"net/http"
 	"time"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/httpretry"
 )
 
 // PipelineExecution represents a GitLab pipeline execution
@@ -33,6 +35,36 @@ type JobExecution struct {
 	Artifacts      []ArtifactInfo `json:"artifacts"`
 	Dependencies   []string       `json:"dependencies"`
 	Needs          []string       `json:"needs"`
+	// Breakdown splits the duration of a simulated job into its phases;
+	// nil for jobs fetched from GitLab
+	Breakdown *DurationBreakdown `json:"breakdown,omitempty"`
+}
+
+// DurationBreakdown splits an estimated job duration, in seconds, into the
+// setup before the script runs (image pull, service startup and
+// before_script), the script itself and the after_script. The job's
+// Duration is their sum.
+type DurationBreakdown struct {
+	Setup       float64 `json:"setup"`
+	Script      float64 `json:"script"`
+	AfterScript float64 `json:"after_script"`
+}
+
+func (b DurationBreakdown) total() float64 {
+	return b.Setup + b.Script + b.AfterScript
+}
+
+// Dominant names the phase that takes the most time: "setup", "script" or
+// "after_script"
+func (b DurationBreakdown) Dominant() string {
+	switch {
+	case b.Setup >= b.Script && b.Setup >= b.AfterScript:
+		return "setup"
+	case b.Script >= b.AfterScript:
+		return "script"
+	default:
+		return "after_script"
+	}
 }
 
 // RunnerInfo represents information about the runner that executed a job
@@ -56,6 +88,20 @@ type PipelineComparison struct {
 	JobComparisons  []JobComparison    `json:"job_comparisons"`
 	Summary         ComparisonSummary  `json:"summary"`
 	PerformanceGain PerformanceMetrics `json:"performance_gain"`
+	StageMetrics    []StageMetric      `json:"stage_metrics"`
+	BottleneckStage string             `json:"bottleneck_stage,omitempty"`
+}
+
+// StageMetric compares a stage's wall-clock contribution, the duration of its
+// longest job since the stage's jobs run in parallel
+type StageMetric struct {
+	Stage       string  `json:"stage"`
+	OldDuration float64 `json:"old_duration"`
+	NewDuration float64 `json:"new_duration"`
+	Change      float64 `json:"change"`
+	OldJobs     int     `json:"old_jobs"`
+	NewJobs     int     `json:"new_jobs"`
+	Bottleneck  bool    `json:"bottleneck"`
 }
 
 // JobComparison represents a comparison between two job executions
@@ -108,4 +154,10 @@ type GitLabClient struct {
 	Token     string
 	ProjectID string
 	Client    *http.Client
+	// PerPage is the page size requested from list endpoints; zero uses
+	// defaultPerPage
+	PerPage int
+	// Retry controls retries of transient failures and rate limiting; zero
+	// fields use httpretry.DefaultPolicy
+	Retry httpretry.Policy
 }
@@ -3,7 +3,6 @@ package renderer
 import (
 	"bytes"
 	"fmt"
-	"sort"
 	"strings"
 
 	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
@@ -13,8 +12,9 @@ import (
 type VisualFormat string
 
 const (
-	FormatDOT     VisualFormat = "dot"
-	FormatMermaid VisualFormat = "mermaid"
+	FormatDOT      VisualFormat = "dot"
+	FormatMermaid  VisualFormat = "mermaid"
+	FormatPlantUML VisualFormat = "plantuml"
 )
 
 // VisualRenderer handles generation of visual pipeline representations
@@ -32,6 +32,8 @@ func (vr *VisualRenderer) RenderPipelineGraph(config *parser.GitLabConfig, forma
 		return vr.generateDOTGraph(config), nil
 	case FormatMermaid:
 		return vr.generateMermaidGraph(config), nil
+	case FormatPlantUML:
+		return vr.generatePlantUMLGraph(config), nil
 	default:
 		return "", fmt.Errorf("unsupported visual format: %s", format)
 	}
@@ -44,6 +46,8 @@ func (vr *VisualRenderer) RenderComparisonGraph(oldConfig, newConfig *parser.Git
 		return vr.generateComparisonDOTGraph(oldConfig, newConfig, comparison), nil
 	case FormatMermaid:
 		return vr.generateComparisonMermaidGraph(oldConfig, newConfig, comparison), nil
+	case FormatPlantUML:
+		return vr.generateComparisonPlantUMLGraph(oldConfig, newConfig, comparison), nil
 	default:
 		return "", fmt.Errorf("unsupported visual format: %s", format)
 	}
@@ -62,7 +66,7 @@ func (vr *VisualRenderer) generateDOTGraph(config *parser.GitLabConfig) string {
 	stageJobs := vr.groupJobsByStage(config)
 
 	// Create subgraphs for each stage
-	for i, stage := range config.Stages {
+	for i, stage := range PipelineStages(config) {
 		jobs := stageJobs[stage]
 		if len(jobs) == 0 {
 			continue
@@ -86,12 +90,19 @@ func (vr *VisualRenderer) generateDOTGraph(config *parser.GitLabConfig) string {
 		buf.WriteString("  }\n\n")
 	}
 
-	// Add dependencies
-	dependencyGraph := config.GetDependencyGraph()
-	for jobName, deps := range dependencyGraph {
-		for _, dep := range deps {
-			buf.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\";\n", dep, jobName))
-		}
+	// Add needs and dependencies edges
+	for _, edge := range jobEdges(config) {
+		buf.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\"%s;\n", edge.From, edge.To, vr.getDOTEdgeAttributes(edge)))
+	}
+
+	// Add jobs of other projects and pipelines as external nodes
+	external := externalEdges(config)
+	sources, _ := externalNodeIDs(external)
+	for _, source := range sources {
+		buf.WriteString(fmt.Sprintf("  \"%s\" [shape=box, style=\"dashed,rounded\"];\n", source))
+	}
+	for _, edge := range external {
+		buf.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [style=dashed, label=\"needs\"];\n", edge.Source, edge.To))
 	}
 
 	buf.WriteString("}\n")
@@ -108,7 +119,7 @@ func (vr *VisualRenderer) generateMermaidGraph(config *parser.GitLabConfig) stri
 	stageJobs := vr.groupJobsByStage(config)
 
 	// Create stage subgraphs
-	for i, stage := range config.Stages {
+	for i, stage := range PipelineStages(config) {
 		jobs := stageJobs[stage]
 		if len(jobs) == 0 {
 			continue
@@ -129,12 +140,20 @@ func (vr *VisualRenderer) generateMermaidGraph(config *parser.GitLabConfig) stri
 		buf.WriteString("  end\n\n")
 	}
 
-	// Add dependencies
-	dependencyGraph := config.GetDependencyGraph()
-	for jobName, deps := range dependencyGraph {
-		for _, dep := range deps {
-			buf.WriteString(fmt.Sprintf("  %s --> %s\n", vr.sanitizeMermaidID(dep), vr.sanitizeMermaidID(jobName)))
-		}
+	// Add needs and dependencies edges
+	for _, edge := range jobEdges(config) {
+		buf.WriteString(fmt.Sprintf("  %s %s %s\n",
+			vr.sanitizeMermaidID(edge.From), vr.getMermaidEdgeArrow(edge), vr.sanitizeMermaidID(edge.To)))
+	}
+
+	// Add jobs of other projects and pipelines as external nodes
+	external := externalEdges(config)
+	sources, ids := externalNodeIDs(external)
+	for _, source := range sources {
+		buf.WriteString(fmt.Sprintf("  %s[/\"%s\"/]\n", ids[source], source))
+	}
+	for _, edge := range external {
+		buf.WriteString(fmt.Sprintf("  %s -.-> %s\n", ids[edge.Source], vr.sanitizeMermaidID(edge.To)))
 	}
 
 	// Add styling
@@ -214,37 +233,132 @@ func (vr *VisualRenderer) generateComparisonMermaidGraph(oldConfig, newConfig *p
 	return buf.String()
 }
 
-// Helper methods
+// generatePlantUMLGraph creates a PlantUML component diagram with stages as
+// packages and needs/dependencies as arrows
+func (vr *VisualRenderer) generatePlantUMLGraph(config *parser.GitLabConfig) string {
+	var buf bytes.Buffer
 
-func (vr *VisualRenderer) groupJobsByStage(config *parser.GitLabConfig) map[string][]string {
-	stageJobs := make(map[string][]string)
+	buf.WriteString("@startuml\n")
+	buf.WriteString("skinparam rectangle {\n  RoundCorner 10\n}\n\n")
+	buf.WriteString(vr.generatePlantUMLSubgraph(config, "", "", nil))
+	buf.WriteString("@enduml\n")
 
-	for jobName, job := range config.Jobs {
-		if job == nil {
-			continue
+	return buf.String()
+}
+
+// generateComparisonPlantUMLGraph creates a PlantUML diagram showing
+// before/after comparison, coloring jobs by their comparison status
+func (vr *VisualRenderer) generateComparisonPlantUMLGraph(oldConfig, newConfig *parser.GitLabConfig, comparison *PipelineComparison) string {
+	var buf bytes.Buffer
+
+	statuses := make(map[string]CompareStatus, len(comparison.JobComparisons))
+	for _, jobComp := range comparison.JobComparisons {
+		statuses[jobComp.JobName] = jobComp.Status
+	}
+
+	buf.WriteString("@startuml\n")
+	buf.WriteString("left to right direction\n")
+	buf.WriteString("skinparam rectangle {\n  RoundCorner 10\n}\n\n")
+
+	buf.WriteString("package \"Before\" {\n")
+	buf.WriteString(vr.generatePlantUMLSubgraph(oldConfig, "old_", "  ", statuses))
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("package \"After\" {\n")
+	buf.WriteString(vr.generatePlantUMLSubgraph(newConfig, "new_", "  ", statuses))
+	buf.WriteString("}\n\n")
+
+	// Add comparison connections
+	for _, jobComp := range comparison.JobComparisons {
+		if jobComp.OldJob != nil && jobComp.NewJob != nil {
+			id := vr.sanitizeMermaidID(jobComp.JobName)
+			buf.WriteString(fmt.Sprintf("old_%s ..> new_%s : %s\n", id, id, jobComp.Status))
 		}
+	}
 
-		// Skip template jobs (starting with .)
-		if strings.HasPrefix(jobName, ".") {
+	buf.WriteString("@enduml\n")
+	return buf.String()
+}
+
+// generatePlantUMLSubgraph writes one package per stage and the edges
+// between jobs. When statuses is set, jobs are colored by comparison status
+// instead of by stage.
+func (vr *VisualRenderer) generatePlantUMLSubgraph(config *parser.GitLabConfig, prefix, indent string, statuses map[string]CompareStatus) string {
+	var buf bytes.Buffer
+
+	stageJobs := vr.groupJobsByStage(config)
+
+	for _, stage := range PipelineStages(config) {
+		jobs := stageJobs[stage]
+		if len(jobs) == 0 {
 			continue
 		}
 
-		stage := job.Stage
-		if stage == "" {
-			stage = "test" // Default stage
+		buf.WriteString(fmt.Sprintf("%spackage \"%s\" {\n", indent, stage))
+		for _, jobName := range jobs {
+			color := vr.getJobNodeColor(config.Jobs[jobName])
+			if statuses != nil {
+				color = vr.getPlantUMLStatusColor(statuses[jobName])
+			}
+			buf.WriteString(fmt.Sprintf("%s  rectangle \"%s\" as %s%s #%s\n",
+				indent, jobName, prefix, vr.sanitizeMermaidID(jobName), color))
 		}
+		buf.WriteString(fmt.Sprintf("%s}\n", indent))
+	}
 
-		stageJobs[stage] = append(stageJobs[stage], jobName)
+	for _, edge := range jobEdges(config) {
+		arrow, label := "..>", ""
+		if edge.Needs {
+			arrow, label = "-->", " : needs"
+		}
+		buf.WriteString(fmt.Sprintf("%s%s%s %s %s%s%s\n",
+			indent, prefix, vr.sanitizeMermaidID(edge.From), arrow, prefix, vr.sanitizeMermaidID(edge.To), label))
 	}
 
-	// Sort jobs within each stage for consistent output
-	for stage := range stageJobs {
-		sort.Strings(stageJobs[stage])
+	external := externalEdges(config)
+	sources, ids := externalNodeIDs(external)
+	for _, source := range sources {
+		buf.WriteString(fmt.Sprintf("%srectangle \"%s\" as %s%s #white\n", indent, source, prefix, ids[source]))
+	}
+	for _, edge := range external {
+		buf.WriteString(fmt.Sprintf("%s%s%s ..> %s%s : needs\n",
+			indent, prefix, ids[edge.Source], prefix, vr.sanitizeMermaidID(edge.To)))
+	}
+
+	return buf.String()
+}
+
+// Helper methods
+
+// groupJobsByStage groups running jobs by stage, ordering each stage's jobs
+// by when they can start according to the needs graph
+func (vr *VisualRenderer) groupJobsByStage(config *parser.GitLabConfig) map[string][]string {
+	stageJobs := make(map[string][]string)
+
+	for _, jobName := range topologicalJobOrder(config) {
+		stage := jobStage(config.Jobs[jobName])
+		stageJobs[stage] = append(stageJobs[stage], jobName)
 	}
 
 	return stageJobs
 }
 
+// getDOTEdgeAttributes draws needs edges bold so they stand out from
+// stage-ordered dependencies
+func (vr *VisualRenderer) getDOTEdgeAttributes(edge jobEdge) string {
+	if edge.Needs {
+		return " [style=bold, label=\"needs\"]"
+	}
+	return ""
+}
+
+func (vr *VisualRenderer) getMermaidEdgeArrow(edge jobEdge) string {
+	if edge.Needs {
+		return "==>"
+	}
+	return "-->"
+}
+
 func (vr *VisualRenderer) getJobNodeColor(job *parser.JobConfig) string {
 	stage := strings.ToLower(job.Stage)
 
@@ -292,7 +406,7 @@ func (vr *VisualRenderer) generateSubgraphContent(config *parser.GitLabConfig, p
 
 	stageJobs := vr.groupJobsByStage(config)
 
-	for _, stage := range config.Stages {
+	for _, stage := range PipelineStages(config) {
 		jobs := stageJobs[stage]
 		if len(jobs) == 0 {
 			continue
@@ -310,11 +424,9 @@ func (vr *VisualRenderer) generateSubgraphContent(config *parser.GitLabConfig, p
 	}
 
 	// Add dependencies within this subgraph
-	dependencyGraph := config.GetDependencyGraph()
-	for jobName, deps := range dependencyGraph {
-		for _, dep := range deps {
-			buf.WriteString(fmt.Sprintf("    \"%s%s\" -> \"%s%s\";\n", prefix, dep, prefix, jobName))
-		}
+	for _, edge := range jobEdges(config) {
+		buf.WriteString(fmt.Sprintf("    \"%s%s\" -> \"%s%s\"%s;\n",
+			prefix, edge.From, prefix, edge.To, vr.getDOTEdgeAttributes(edge)))
 	}
 
 	return buf.String()
@@ -325,7 +437,7 @@ func (vr *VisualRenderer) generateMermaidSubgraph(config *parser.GitLabConfig, p
 
 	stageJobs := vr.groupJobsByStage(config)
 
-	for _, stage := range config.Stages {
+	for _, stage := range PipelineStages(config) {
 		jobs := stageJobs[stage]
 		if len(jobs) == 0 {
 			continue
@@ -342,12 +454,9 @@ func (vr *VisualRenderer) generateMermaidSubgraph(config *parser.GitLabConfig, p
 	}
 
 	// Add dependencies within this subgraph
-	dependencyGraph := config.GetDependencyGraph()
-	for jobName, deps := range dependencyGraph {
-		for _, dep := range deps {
-			buf.WriteString(fmt.Sprintf("    %s%s --> %s%s\n",
-				prefix, vr.sanitizeMermaidID(dep), prefix, vr.sanitizeMermaidID(jobName)))
-		}
+	for _, edge := range jobEdges(config) {
+		buf.WriteString(fmt.Sprintf("    %s%s %s %s%s\n",
+			prefix, vr.sanitizeMermaidID(edge.From), vr.getMermaidEdgeArrow(edge), prefix, vr.sanitizeMermaidID(edge.To)))
 	}
 
 	return buf.String()
@@ -368,6 +477,25 @@ func (vr *VisualRenderer) getComparisonEdgeColor(status CompareStatus) string {
 	}
 }
 
+func (vr *VisualRenderer) getPlantUMLStatusColor(status CompareStatus) string {
+	switch status {
+	case StatusImproved:
+		return "palegreen"
+	case StatusDegraded:
+		return "lightcoral"
+	case StatusIdentical:
+		return "lightblue"
+	case StatusRestructured:
+		return "orange"
+	case StatusAdded:
+		return "c8e6c9"
+	case StatusRemoved:
+		return "ffcdd2"
+	default:
+		return "lightgrey"
+	}
+}
+
 func (vr *VisualRenderer) getComparisonMermaidStyle(status CompareStatus) string {
 	switch status {
 	case StatusImproved:
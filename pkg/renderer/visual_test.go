@@ -122,6 +122,52 @@ func TestVisualRenderer_RenderPipelineGraph_DOT(t *testing.T) {
 	}
 }
 
+func TestVisualRenderer_RenderPipelineGraph_ExternalNeeds(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Stages: []string{"build", "test"},
+		Jobs: map[string]*parser.JobConfig{
+			"build": {Stage: "build", Script: []string{"make"}},
+			"test": {
+				Stage:  "test",
+				Script: []string{"make test"},
+				Needs: []parser.Need{
+					{Job: "build"},
+					{Project: "group/library", Job: "build", Ref: "main"},
+				},
+			},
+		},
+	}
+
+	vr := NewVisualRenderer()
+
+	dot, err := vr.RenderPipelineGraph(config, FormatDOT)
+	if err != nil {
+		t.Fatalf("Failed to render DOT graph: %v", err)
+	}
+	for _, want := range []string{
+		`"group/library:build@main" [shape=box, style="dashed,rounded"];`,
+		`"group/library:build@main" -> "test" [style=dashed, label="needs"];`,
+		`"build" -> "test"`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Expected DOT graph to contain %q, got:\n%s", want, dot)
+		}
+	}
+	if strings.Count(dot, `"build" -> "test"`) != 1 {
+		t.Errorf("Expected the external build job not to be drawn as the local one, got:\n%s", dot)
+	}
+
+	mermaid, err := vr.RenderPipelineGraph(config, FormatMermaid)
+	if err != nil {
+		t.Fatalf("Failed to render Mermaid graph: %v", err)
+	}
+	for _, want := range []string{`external_0[/"group/library:build@main"/]`, "external_0 -.-> test"} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Expected Mermaid graph to contain %q, got:\n%s", want, mermaid)
+		}
+	}
+}
+
 func TestVisualRenderer_RenderComparisonGraph_Mermaid(t *testing.T) {
 	oldConfig := &parser.GitLabConfig{
 		Stages: []string{"build", "test"},
@@ -283,6 +329,94 @@ func TestVisualRenderer_RenderComparisonGraph_DOT(t *testing.T) {
 	}
 }
 
+func TestVisualRenderer_RenderPipelineGraph_PlantUML(t *testing.T) {
+	config := &parser.GitLabConfig{
+		Stages: []string{"build", "test"},
+		Jobs: map[string]*parser.JobConfig{
+			"build": {
+				Stage:  "build",
+				Script: []string{"make build"},
+			},
+			"test:unit": {
+				Stage:  "test",
+				Script: []string{"make test"},
+				Needs:  []parser.Need{{Job: "build"}},
+			},
+			"test:lint": {
+				Stage:        "test",
+				Script:       []string{"make lint"},
+				Dependencies: []string{"build"},
+			},
+		},
+	}
+
+	vr := NewVisualRenderer()
+	result, err := vr.RenderPipelineGraph(config, FormatPlantUML)
+	if err != nil {
+		t.Fatalf("RenderPipelineGraph failed: %v", err)
+	}
+
+	if !strings.HasPrefix(result, "@startuml\n") || !strings.HasSuffix(result, "@enduml\n") {
+		t.Errorf("Expected PlantUML document delimiters, got:\n%s", result)
+	}
+
+	expected := []string{
+		`package "build" {`,
+		`package "test" {`,
+		`rectangle "test:unit" as test_unit #lightpink`,
+		"build --> test_unit : needs",
+		"build ..> test_lint",
+	}
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected to find %q in PlantUML output, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestVisualRenderer_RenderComparisonGraph_PlantUML(t *testing.T) {
+	oldConfig := &parser.GitLabConfig{
+		Stages: []string{"test"},
+		Jobs: map[string]*parser.JobConfig{
+			"test":      {Stage: "test", Script: []string{"sleep 60 && test"}},
+			"slow_test": {Stage: "test", Script: []string{"sleep 60 && test"}},
+		},
+	}
+
+	newConfig := &parser.GitLabConfig{
+		Stages: []string{"test"},
+		Jobs: map[string]*parser.JobConfig{
+			"test": {Stage: "test", Script: []string{"test"}},
+		},
+	}
+
+	comparison := &PipelineComparison{
+		JobComparisons: []JobComparison{
+			{JobName: "test", Status: StatusImproved, OldJob: &JobExecution{}, NewJob: &JobExecution{}},
+			{JobName: "slow_test", Status: StatusRemoved, OldJob: &JobExecution{}},
+		},
+	}
+
+	vr := NewVisualRenderer()
+	result, err := vr.RenderComparisonGraph(oldConfig, newConfig, comparison, FormatPlantUML)
+	if err != nil {
+		t.Fatalf("RenderComparisonGraph failed: %v", err)
+	}
+
+	expected := []string{
+		`package "Before" {`,
+		`package "After" {`,
+		`rectangle "slow_test" as old_slow_test #ffcdd2`,
+		`rectangle "test" as new_test #palegreen`,
+		"old_test ..> new_test : improved",
+	}
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected to find %q in PlantUML comparison, got:\n%s", want, result)
+		}
+	}
+}
+
 func TestVisualRenderer_GroupJobsByStage(t *testing.T) {
 	config := &parser.GitLabConfig{
 		Stages: []string{"build", "test", "deploy"},
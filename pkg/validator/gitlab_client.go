@@ -7,6 +7,8 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/httpretry"
 )
 
 // GitLabClient provides API access to a GitLab instance
@@ -14,6 +16,9 @@ type GitLabClient struct {
 	baseURL    string
 	token      string
 	httpClient *http.Client
+	// retry controls retries of transient failures and rate limiting; zero
+	// fields use httpretry.DefaultPolicy
+	retry httpretry.Policy
 }
 
 // NewGitLabClient creates a new GitLab API client
@@ -199,7 +204,7 @@ func (c *GitLabClient) makeRequest(method, endpoint string, payload interface{},
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.token)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.retry.Do(c.httpClient, req)
 	if err != nil {
 		return fmt.Errorf("failed to make request: %w", err)
 	}
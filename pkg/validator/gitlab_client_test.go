@@ -0,0 +1,39 @@
+package validator
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/httpretry"
+)
+
+func TestGitLabClientRetriesTransientFailures(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "" {
+			t.Errorf("request %d was sent without its body", requests)
+		}
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id": 7, "name": "demo", "path": "demo"}`))
+	}))
+	defer server.Close()
+
+	client := NewGitLabClient(server.URL, "token")
+	client.retry = httpretry.Policy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	project, err := client.CreateProject("demo", "demo")
+	if err != nil {
+		t.Fatalf("expected the retried request to succeed, got %v", err)
+	}
+	if project.ID != 7 || requests != 2 {
+		t.Errorf("expected project 7 after 2 requests, got %+v after %d", project, requests)
+	}
+}
@@ -0,0 +1,116 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+)
+
+const (
+	// maxCommentLength stays well below GitLab's limit of 1,000,000
+	// characters per note, leaving room for text added around the report
+	maxCommentLength = 900000
+	// maxCellLength bounds a single table cell, since issue messages can
+	// quote whole scripts or variable values
+	maxCellLength = 300
+)
+
+// ToMarkdownComment renders the result as GitLab-flavored Markdown for a
+// merge request comment: the verdict, improvement tags, the pipeline
+// comparison and, in a collapsible block, the resolved and new issues. Long
+// values are shortened and rows dropped to stay within GitLab's comment
+// size limit.
+func (r *SimpleRefactoringResult) ToMarkdownComment() string {
+	var b strings.Builder
+
+	verdict := ":white_check_mark: Refactoring keeps pipeline behavior"
+	if !r.Success {
+		verdict = ":x: Refactoring needs attention"
+	}
+	fmt.Fprintf(&b, "### %s\n\n", verdict)
+	fmt.Fprintf(&b, "- **Behavior maintained:** %s\n", yesNo(r.BehaviorMaintained))
+	fmt.Fprintf(&b, "- **Performance improved:** %s\n", yesNo(r.PerformanceImproved))
+	fmt.Fprintf(&b, "- **Analyzer issues:** %d resolved, %d new\n", len(r.ResolvedIssues), len(r.IntroducedIssues))
+
+	if r.DiffResult != nil && len(r.DiffResult.ImprovementTags) > 0 {
+		badges := make([]string, len(r.DiffResult.ImprovementTags))
+		for i, tag := range r.DiffResult.ImprovementTags {
+			badges[i] = "`" + tag + "`"
+		}
+		fmt.Fprintf(&b, "- **Improvements:** %s\n", strings.Join(badges, " "))
+	}
+
+	if r.PipelineComparison != nil {
+		summary := r.PipelineComparison.Summary
+		b.WriteString("\n#### Pipeline comparison\n\n")
+		b.WriteString("| Jobs | Added | Removed | Faster | Slower | Unchanged | Time change |\n")
+		b.WriteString("|---:|---:|---:|---:|---:|---:|---:|\n")
+		fmt.Fprintf(&b, "| %d | %d | %d | %d | %d | %d | %+.1fs |\n",
+			summary.TotalJobs, summary.AddedJobs, summary.RemovedJobs,
+			summary.ImprovedJobs, summary.DegradedJobs, summary.IdenticalJobs, summary.TotalTimeChange)
+	}
+
+	if len(r.Issues) > 0 {
+		b.WriteString("\n#### Problems\n\n")
+		for _, problem := range r.Issues {
+			fmt.Fprintf(&b, "- %s\n", truncateText(problem, maxCellLength))
+		}
+	}
+
+	if len(r.ResolvedIssues) > 0 || len(r.IntroducedIssues) > 0 {
+		fmt.Fprintf(&b, "\n<details>\n<summary>Issues: %d resolved, %d new</summary>\n\n", len(r.ResolvedIssues), len(r.IntroducedIssues))
+		b.WriteString("| Status | Severity | Type | Path | Message |\n")
+		b.WriteString("|---|---|---|---|---|\n")
+
+		rows := issueRows(":new: New", r.IntroducedIssues)
+		rows = append(rows, issueRows(":heavy_check_mark: Resolved", r.ResolvedIssues)...)
+		// Leave room for the closing lines
+		budget := maxCommentLength - b.Len() - 200
+		for i, row := range rows {
+			if len(row) > budget {
+				fmt.Fprintf(&b, "\n_%d more issues omitted to fit GitLab's comment size limit._\n", len(rows)-i)
+				break
+			}
+			b.WriteString(row)
+			budget -= len(row)
+		}
+		b.WriteString("\n</details>\n")
+	}
+
+	return b.String()
+}
+
+// issueRows renders one table row per issue
+func issueRows(status string, issues []types.Issue) []string {
+	rows := make([]string, len(issues))
+	for i, issue := range issues {
+		rows[i] = fmt.Sprintf("| %s | %s | %s | `%s` | %s |\n",
+			status, issue.Severity, issue.Type,
+			strings.ReplaceAll(tableCell(issue.Path), "`", "'"), tableCell(issue.Message))
+	}
+	return rows
+}
+
+// tableCell shortens a value and escapes what would break a table row
+func tableCell(value string) string {
+	value = truncateText(value, maxCellLength)
+	value = strings.ReplaceAll(value, "|", "\\|")
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// truncateText shortens text longer than limit runes, marking the cut
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
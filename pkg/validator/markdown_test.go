@@ -0,0 +1,65 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/differ"
+	"github.com/wonderfulspam/gitlab-smith/pkg/renderer"
+)
+
+func TestToMarkdownComment(t *testing.T) {
+	result := &SimpleRefactoringResult{
+		Success:            true,
+		BehaviorMaintained: true,
+		DiffResult:         &differ.DiffResult{ImprovementTags: []string{"duplication", "templates"}},
+		PipelineComparison: &renderer.PipelineComparison{
+			Summary: renderer.ComparisonSummary{TotalJobs: 4, ImprovedJobs: 1, IdenticalJobs: 3, TotalTimeChange: -12.5},
+		},
+		ResolvedIssues: []types.Issue{{
+			Type:     types.IssueTypeMaintainability,
+			Severity: types.SeverityMedium,
+			Path:     "jobs",
+			Message:  "Duplicated scripts in jobs: a | b\n" + strings.Repeat("x", 2*maxCellLength),
+		}},
+	}
+
+	comment := result.ToMarkdownComment()
+	for _, want := range []string{
+		":white_check_mark:",
+		"**Improvements:** `duplication` `templates`",
+		"| 4 | 0 | 0 | 1 | 0 | 3 | -12.5s |",
+		"<summary>Issues: 1 resolved, 0 new</summary>",
+		"a \\| b xxx",
+		"</details>",
+	} {
+		if !strings.Contains(comment, want) {
+			t.Errorf("Expected %q in the comment, got:\n%s", want, comment)
+		}
+	}
+	if strings.Contains(comment, strings.Repeat("x", maxCellLength+1)) {
+		t.Errorf("Expected long messages to be truncated, got:\n%s", comment)
+	}
+}
+
+func TestToMarkdownCommentSizeLimit(t *testing.T) {
+	issue := types.Issue{
+		Type:     types.IssueTypeReliability,
+		Severity: types.SeverityHigh,
+		Path:     "jobs.build",
+		Message:  strings.Repeat("y", maxCellLength),
+	}
+	result := &SimpleRefactoringResult{}
+	for i := 0; i < 5000; i++ {
+		result.IntroducedIssues = append(result.IntroducedIssues, issue)
+	}
+
+	comment := result.ToMarkdownComment()
+	if len(comment) > maxCommentLength {
+		t.Errorf("Expected the comment to stay within %d bytes, got %d", maxCommentLength, len(comment))
+	}
+	if !strings.Contains(comment, "more issues omitted") || !strings.HasSuffix(comment, "</details>\n") {
+		t.Errorf("Expected omitted rows to be noted and the block closed, got tail %q", comment[len(comment)-200:])
+	}
+}
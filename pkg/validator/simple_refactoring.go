@@ -0,0 +1,312 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer"
+	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer/types"
+	"github.com/wonderfulspam/gitlab-smith/pkg/differ"
+	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
+	"github.com/wonderfulspam/gitlab-smith/pkg/renderer"
+)
+
+// SimpleRefactoringCase represents a simple before/after test case
+type SimpleRefactoringCase struct {
+	Name         string
+	Description  string
+	BeforeFile   string
+	AfterFile    string
+	Expectations SimpleRefactoringExpectations
+}
+
+// SimpleRefactoringExpectations defines success criteria for simple cases
+type SimpleRefactoringExpectations struct {
+	ShouldReduceIssues          bool     // Should analyzer find fewer issues
+	ShouldMaintainBehavior      bool     // Should behavior remain the same
+	ShouldImproveOrMaintainPerf bool     // Should performance improve or stay same
+	ExpectedImprovementAreas    []string // Areas that should show improvement
+	// MaxNewIssues should always be 0 - fully optimized configs should have no issues
+
+	// Specific expected issues in BEFORE configuration
+	ExpectedBeforeIssues []string // Specific issues that should be found in before config
+	// Specific expected improvements detected in AFTER configuration
+	ExpectedImprovements []string // Specific improvements that should be detected
+	// Expected improvement tags from differ
+	ExpectedImprovementTags []string // Tags like "duplication", "consolidation", "templates"
+	// Expected number of issues that should be resolved (exact)
+	ExpectedIssuesResolved int
+	// Specific expected issues remaining in AFTER configuration
+	ExpectedRemainingIssues []string // Specific issues that should remain in after config
+}
+
+// SimpleRefactoringResult contains validation results for simple cases
+type SimpleRefactoringResult struct {
+	Case                *SimpleRefactoringCase
+	Success             bool
+	Issues              []string
+	AnalysisImprovement int
+	BehaviorMaintained  bool
+	PerformanceImproved bool
+	DiffResult          *differ.DiffResult
+	PipelineComparison  *renderer.PipelineComparison
+	ResolvedIssues      []types.Issue // Issues found before but not after
+	IntroducedIssues    []types.Issue // Issues found after but not before
+}
+
+// simpleRefactoringReport is the JSON form of a SimpleRefactoringResult
+type simpleRefactoringReport struct {
+	Success             bool                        `json:"success"`
+	AnalysisImprovement int                         `json:"analysis_improvement"`
+	BehaviorMaintained  bool                        `json:"behavior_maintained"`
+	PerformanceImproved bool                        `json:"performance_improved"`
+	ImprovementTags     []string                    `json:"improvement_tags"`
+	PipelineComparison  *renderer.ComparisonSummary `json:"pipeline_comparison,omitempty"`
+	ResolvedIssues      []types.Issue               `json:"resolved_issues"`
+	IntroducedIssues    []types.Issue               `json:"introduced_issues"`
+	Failures            []string                    `json:"failures"`
+}
+
+// ToJSON renders the result for machine consumption, e.g. in CI
+func (r *SimpleRefactoringResult) ToJSON() ([]byte, error) {
+	report := simpleRefactoringReport{
+		Success:             r.Success,
+		AnalysisImprovement: r.AnalysisImprovement,
+		BehaviorMaintained:  r.BehaviorMaintained,
+		PerformanceImproved: r.PerformanceImproved,
+		ImprovementTags:     []string{},
+		ResolvedIssues:      r.ResolvedIssues,
+		IntroducedIssues:    r.IntroducedIssues,
+		Failures:            r.Issues,
+	}
+	if r.DiffResult != nil && r.DiffResult.ImprovementTags != nil {
+		report.ImprovementTags = r.DiffResult.ImprovementTags
+	}
+	if r.PipelineComparison != nil {
+		report.PipelineComparison = &r.PipelineComparison.Summary
+	}
+	if report.ResolvedIssues == nil {
+		report.ResolvedIssues = []types.Issue{}
+	}
+	if report.IntroducedIssues == nil {
+		report.IntroducedIssues = []types.Issue{}
+	}
+	if report.Failures == nil {
+		report.Failures = []string{}
+	}
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// ValidateSimpleRefactoring validates a simple before/after refactoring case
+func ValidateSimpleRefactoring(testCase *SimpleRefactoringCase) (*SimpleRefactoringResult, error) {
+	result := &SimpleRefactoringResult{
+		Case:   testCase,
+		Issues: []string{},
+	}
+
+	// Parse before configuration
+	beforeData, err := os.ReadFile(testCase.BeforeFile)
+	if err != nil {
+		return result, err
+	}
+	beforeConfig, err := parser.Parse(beforeData)
+	if err != nil {
+		return result, err
+	}
+
+	// Parse after configuration
+	afterData, err := os.ReadFile(testCase.AfterFile)
+	if err != nil {
+		return result, err
+	}
+	afterConfig, err := parser.Parse(afterData)
+	if err != nil {
+		return result, err
+	}
+
+	compareSimpleConfigs(result, beforeConfig, afterConfig)
+	return result, nil
+}
+
+// CompareDirectories validates a refactoring of a configuration split
+// across files. Each directory must contain a main CI file, whose local
+// includes are resolved relative to that directory.
+func CompareDirectories(beforeDir, afterDir string) (*SimpleRefactoringResult, error) {
+	testCase := &SimpleRefactoringCase{
+		Name: filepath.Base(afterDir),
+		Expectations: SimpleRefactoringExpectations{
+			ShouldMaintainBehavior:      true,
+			ShouldImproveOrMaintainPerf: true,
+		},
+	}
+	result := &SimpleRefactoringResult{
+		Case:   testCase,
+		Issues: []string{},
+	}
+
+	var err error
+	if testCase.BeforeFile, err = mainConfigFile(beforeDir); err != nil {
+		return result, err
+	}
+	if testCase.AfterFile, err = mainConfigFile(afterDir); err != nil {
+		return result, err
+	}
+
+	beforeConfig, err := parser.ParseFile(testCase.BeforeFile)
+	if err != nil {
+		return result, fmt.Errorf("failed to parse %s: %w", testCase.BeforeFile, err)
+	}
+	afterConfig, err := parser.ParseFile(testCase.AfterFile)
+	if err != nil {
+		return result, fmt.Errorf("failed to parse %s: %w", testCase.AfterFile, err)
+	}
+
+	compareSimpleConfigs(result, beforeConfig, afterConfig)
+	return result, nil
+}
+
+// compareSimpleConfigs diffs, analyzes and simulates both configurations and
+// checks the outcome against the case's expectations
+func compareSimpleConfigs(result *SimpleRefactoringResult, beforeConfig, afterConfig *parser.GitLabConfig) {
+	// Perform semantic diff
+	result.DiffResult = differ.Compare(beforeConfig, afterConfig)
+
+	// Analyze both configurations
+	beforeAnalysis := analyzer.Analyze(beforeConfig)
+	afterAnalysis := analyzer.Analyze(afterConfig)
+	result.AnalysisImprovement = beforeAnalysis.TotalIssues - afterAnalysis.TotalIssues
+	delta := analyzer.CompareAnalyses(beforeAnalysis, afterAnalysis)
+	result.ResolvedIssues = delta.Removed
+	result.IntroducedIssues = delta.Added
+
+	// Compare pipeline executions
+	renderer := renderer.New(nil)
+	pipelineComparison, err := renderer.CompareConfigurations(beforeConfig, afterConfig)
+	if err == nil {
+		result.PipelineComparison = pipelineComparison
+		result.PerformanceImproved = pipelineComparison.Summary.OverallImprovement
+	}
+
+	// Assess behavior maintenance (semantic equivalence)
+	result.BehaviorMaintained = assessBehaviorMaintenance(result.DiffResult)
+
+	// Validate expectations
+	result.Success = validateSimpleExpectations(result, result.Case.Expectations)
+}
+
+// assessBehaviorMaintenance checks if core behavior is maintained
+func assessBehaviorMaintenance(diffResult *differ.DiffResult) bool {
+	// Check for significant behavioral changes
+	significantChanges := 0
+	templateJobs := 0
+
+	for _, change := range diffResult.Semantic {
+		if isBehavioralChange(change) {
+			// Template jobs (starting with .) are refactoring improvements, not behavioral changes
+			if change.Type == differ.DiffTypeAdded && contains(change.Path, "jobs..") {
+				templateJobs++
+				continue
+			}
+
+			// A workflow rules change that doesn't name any affected jobs
+			// only affects jobs whose own rules changed, and those are
+			// counted through their own diffs
+			if change.Path == "workflow.rules" && !contains(change.Description, "although their own rules didn't change") {
+				continue
+			}
+
+			// Check if this is a refactoring-safe change
+			if isRefactoringSafeChange(change) {
+				continue // Safe refactoring changes don't count as significant
+			} else {
+				significantChanges++
+			}
+		}
+	}
+
+	// Allow template job additions and be more lenient with refactoring changes
+	// Templates are a sign of good refactoring, not behavioral problems
+	return significantChanges <= 3 || (templateJobs > 0 && significantChanges <= 5)
+}
+
+// isRefactoringSafeChange identifies changes that are safe refactoring moves
+func isRefactoringSafeChange(change differ.ConfigDiff) bool {
+	// Changes that are typically safe during refactoring
+	safePatterns := []string{
+		"script changed for",     // Often consolidation moves
+		"Job script changed for", // Usually setup consolidation
+		"Job removed:",           // Template-based consolidation
+		"Job added:",             // Template introduction
+	}
+
+	for _, pattern := range safePatterns {
+		if contains(change.Description, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isBehavioralChange determines if a change affects pipeline behavior
+func isBehavioralChange(change differ.ConfigDiff) bool {
+	// Use the Behavioral field from the differ
+	return change.Behavioral
+}
+
+// validateSimpleExpectations validates results against expectations
+func validateSimpleExpectations(result *SimpleRefactoringResult, expectations SimpleRefactoringExpectations) bool {
+	success := true
+
+	// Check issue reduction (only if expected to reduce issues and no explicit expected resolved count)
+	if expectations.ShouldReduceIssues && result.AnalysisImprovement <= 0 && expectations.ExpectedIssuesResolved > 0 {
+		result.Issues = append(result.Issues, "Expected to reduce analyzer issues but did not improve")
+		success = false
+	}
+
+	// Check for new issues - should never happen in optimized configs
+	if result.AnalysisImprovement < 0 {
+		result.Issues = append(result.Issues,
+			fmt.Sprintf("New issues introduced: %d (optimized configs should have 0)", -result.AnalysisImprovement))
+		success = false
+	}
+
+	// Check behavior maintenance
+	if expectations.ShouldMaintainBehavior && !result.BehaviorMaintained {
+		result.Issues = append(result.Issues, "Expected to maintain behavior but significant changes detected")
+		success = false
+	}
+
+	// Check for pipeline structure improvements (job count, parallelism)
+	if expectations.ShouldImproveOrMaintainPerf && result.PipelineComparison != nil {
+		// Focus on structural improvements rather than simulated timing
+		if result.PipelineComparison.Summary.AddedJobs > result.PipelineComparison.Summary.RemovedJobs+2 {
+			result.Issues = append(result.Issues, "Refactoring added too many jobs without clear benefit")
+			success = false
+		}
+		// Note: We don't validate duration changes as they're simulated estimates
+	}
+
+	return success
+}
+
+// contains checks if a string contains a substring (case-insensitive)
+func contains(s, substr string) bool {
+	return len(s) >= len(substr) &&
+		(s == substr ||
+			len(s) > len(substr) &&
+				(s[:len(substr)] == substr ||
+					s[len(s)-len(substr):] == substr ||
+					indexOf(s, substr) >= 0))
+}
+
+func indexOf(s, substr string) int {
+	for i := 0; i <= len(s)-len(substr); i++ {
+		if s[i:i+len(substr)] == substr {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,218 +1,10 @@
 package validator
 
 import (
-	"fmt"
-	"os"
 	"path/filepath"
 	"testing"
-
-	"github.com/wonderfulspam/gitlab-smith/pkg/analyzer"
-	"github.com/wonderfulspam/gitlab-smith/pkg/differ"
-	"github.com/wonderfulspam/gitlab-smith/pkg/parser"
-	"github.com/wonderfulspam/gitlab-smith/pkg/renderer"
 )
 
-// SimpleRefactoringCase represents a simple before/after test case
-type SimpleRefactoringCase struct {
-	Name         string
-	Description  string
-	BeforeFile   string
-	AfterFile    string
-	Expectations SimpleRefactoringExpectations
-}
-
-// SimpleRefactoringExpectations defines success criteria for simple cases
-type SimpleRefactoringExpectations struct {
-	ShouldReduceIssues          bool     // Should analyzer find fewer issues
-	ShouldMaintainBehavior      bool     // Should behavior remain the same
-	ShouldImproveOrMaintainPerf bool     // Should performance improve or stay same
-	ExpectedImprovementAreas    []string // Areas that should show improvement
-	// MaxNewIssues should always be 0 - fully optimized configs should have no issues
-
-	// Specific expected issues in BEFORE configuration
-	ExpectedBeforeIssues []string // Specific issues that should be found in before config
-	// Specific expected improvements detected in AFTER configuration
-	ExpectedImprovements []string // Specific improvements that should be detected
-	// Expected improvement tags from differ
-	ExpectedImprovementTags []string // Tags like "duplication", "consolidation", "templates"
-	// Expected number of issues that should be resolved (exact)
-	ExpectedIssuesResolved int
-	// Specific expected issues remaining in AFTER configuration
-	ExpectedRemainingIssues []string // Specific issues that should remain in after config
-}
-
-// SimpleRefactoringResult contains validation results for simple cases
-type SimpleRefactoringResult struct {
-	Case                *SimpleRefactoringCase
-	Success             bool
-	Issues              []string
-	AnalysisImprovement int
-	BehaviorMaintained  bool
-	PerformanceImproved bool
-	DiffResult          *differ.DiffResult
-	PipelineComparison  *renderer.PipelineComparison
-}
-
-// ValidateSimpleRefactoring validates a simple before/after refactoring case
-func ValidateSimpleRefactoring(testCase *SimpleRefactoringCase) (*SimpleRefactoringResult, error) {
-	result := &SimpleRefactoringResult{
-		Case:   testCase,
-		Issues: []string{},
-	}
-
-	// Parse before configuration
-	beforeData, err := os.ReadFile(testCase.BeforeFile)
-	if err != nil {
-		return result, err
-	}
-	beforeConfig, err := parser.Parse(beforeData)
-	if err != nil {
-		return result, err
-	}
-
-	// Parse after configuration
-	afterData, err := os.ReadFile(testCase.AfterFile)
-	if err != nil {
-		return result, err
-	}
-	afterConfig, err := parser.Parse(afterData)
-	if err != nil {
-		return result, err
-	}
-
-	// Perform semantic diff
-	result.DiffResult = differ.Compare(beforeConfig, afterConfig)
-
-	// Analyze both configurations
-	beforeAnalysis := analyzer.Analyze(beforeConfig)
-	afterAnalysis := analyzer.Analyze(afterConfig)
-	result.AnalysisImprovement = beforeAnalysis.TotalIssues - afterAnalysis.TotalIssues
-
-	// Compare pipeline executions
-	renderer := renderer.New(nil)
-	pipelineComparison, err := renderer.CompareConfigurations(beforeConfig, afterConfig)
-	if err == nil {
-		result.PipelineComparison = pipelineComparison
-		result.PerformanceImproved = pipelineComparison.Summary.OverallImprovement
-	}
-
-	// Assess behavior maintenance (semantic equivalence)
-	result.BehaviorMaintained = assessBehaviorMaintenance(result.DiffResult)
-
-	// Validate expectations
-	result.Success = validateSimpleExpectations(result, testCase.Expectations)
-
-	return result, nil
-}
-
-// assessBehaviorMaintenance checks if core behavior is maintained
-func assessBehaviorMaintenance(diffResult *differ.DiffResult) bool {
-	// Check for significant behavioral changes
-	significantChanges := 0
-	templateJobs := 0
-
-	for _, change := range diffResult.Semantic {
-		if isBehavioralChange(change) {
-			// Template jobs (starting with .) are refactoring improvements, not behavioral changes
-			if change.Type == differ.DiffTypeAdded && contains(change.Path, "jobs..") {
-				templateJobs++
-				continue
-			}
-
-			// Check if this is a refactoring-safe change
-			if isRefactoringSafeChange(change) {
-				continue // Safe refactoring changes don't count as significant
-			} else {
-				significantChanges++
-			}
-		}
-	}
-
-	// Allow template job additions and be more lenient with refactoring changes
-	// Templates are a sign of good refactoring, not behavioral problems
-	return significantChanges <= 3 || (templateJobs > 0 && significantChanges <= 5)
-}
-
-// isRefactoringSafeChange identifies changes that are safe refactoring moves
-func isRefactoringSafeChange(change differ.ConfigDiff) bool {
-	// Changes that are typically safe during refactoring
-	safePatterns := []string{
-		"script changed for",     // Often consolidation moves
-		"Job script changed for", // Usually setup consolidation
-		"Job removed:",           // Template-based consolidation
-		"Job added:",             // Template introduction
-	}
-
-	for _, pattern := range safePatterns {
-		if contains(change.Description, pattern) {
-			return true
-		}
-	}
-
-	return false
-}
-
-// isBehavioralChange determines if a change affects pipeline behavior
-func isBehavioralChange(change differ.ConfigDiff) bool {
-	// Use the Behavioral field from the differ
-	return change.Behavioral
-}
-
-// validateSimpleExpectations validates results against expectations
-func validateSimpleExpectations(result *SimpleRefactoringResult, expectations SimpleRefactoringExpectations) bool {
-	success := true
-
-	// Check issue reduction (only if expected to reduce issues and no explicit expected resolved count)
-	if expectations.ShouldReduceIssues && result.AnalysisImprovement <= 0 && expectations.ExpectedIssuesResolved > 0 {
-		result.Issues = append(result.Issues, "Expected to reduce analyzer issues but did not improve")
-		success = false
-	}
-
-	// Check for new issues - should never happen in optimized configs
-	if result.AnalysisImprovement < 0 {
-		result.Issues = append(result.Issues,
-			fmt.Sprintf("New issues introduced: %d (optimized configs should have 0)", -result.AnalysisImprovement))
-		success = false
-	}
-
-	// Check behavior maintenance
-	if expectations.ShouldMaintainBehavior && !result.BehaviorMaintained {
-		result.Issues = append(result.Issues, "Expected to maintain behavior but significant changes detected")
-		success = false
-	}
-
-	// Check for pipeline structure improvements (job count, parallelism)
-	if expectations.ShouldImproveOrMaintainPerf && result.PipelineComparison != nil {
-		// Focus on structural improvements rather than simulated timing
-		if result.PipelineComparison.Summary.AddedJobs > result.PipelineComparison.Summary.RemovedJobs+2 {
-			result.Issues = append(result.Issues, "Refactoring added too many jobs without clear benefit")
-			success = false
-		}
-		// Note: We don't validate duration changes as they're simulated estimates
-	}
-
-	return success
-}
-
-// contains checks if a string contains a substring (case-insensitive)
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) &&
-		(s == substr ||
-			len(s) > len(substr) &&
-				(s[:len(substr)] == substr ||
-					s[len(s)-len(substr):] == substr ||
-					indexOf(s, substr) >= 0))
-}
-
-func indexOf(s, substr string) int {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return i
-		}
-	}
-	return -1
-}
-
 // Test cases for simple refactoring scenarios
 func TestSimpleRefactoringCases(t *testing.T) {
 	basePath := "../../test/simple-refactoring-cases"
@@ -394,6 +186,30 @@ func TestSimpleRefactoringCases(t *testing.T) {
 				ExpectedRemainingIssues: []string{}, // All issues should be resolved
 			},
 		},
+		{
+			Name:        "matrix-expansion",
+			Description: "Folding per-version jobs into one parallel:matrix job",
+			BeforeFile:  filepath.Join(basePath, "matrix-expansion-before.yml"),
+			AfterFile:   filepath.Join(basePath, "matrix-expansion-after.yml"),
+			Expectations: SimpleRefactoringExpectations{
+				ShouldReduceIssues:          true,
+				ShouldMaintainBehavior:      true,
+				ShouldImproveOrMaintainPerf: true,
+				ExpectedImprovementAreas:    []string{"matrix"},
+				ExpectedBeforeIssues: []string{
+					"could use matrix strategy",
+					"Duplicate setup configuration",
+				},
+				ExpectedImprovements: []string{
+					"consolidated into matrix job 'test'",
+				},
+				ExpectedImprovementTags: []string{"matrix"},
+				ExpectedIssuesResolved:  3,
+				ExpectedRemainingIssues: []string{
+					"More than half of jobs don't use caching",
+				},
+			},
+		},
 		{
 			Name:        "complex-conditions",
 			Description: "Simplifying complex conditional logic using workflow rules",
@@ -494,3 +310,25 @@ func BenchmarkSimpleRefactoringValidation(b *testing.B) {
 		}
 	}
 }
+
+func TestCompareDirectories(t *testing.T) {
+	scenario := "../../test/refactoring-scenarios/scenario-1"
+
+	result, err := CompareDirectories(filepath.Join(scenario, "before"), filepath.Join(scenario, "after"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Case.BeforeFile != filepath.Join(scenario, "before", ".gitlab-ci.yml") {
+		t.Errorf("Expected the main file of the before directory, got %s", result.Case.BeforeFile)
+	}
+	if result.DiffResult == nil || result.PipelineComparison == nil {
+		t.Fatal("Expected a diff and a pipeline comparison")
+	}
+	if len(result.ResolvedIssues) == 0 {
+		t.Error("Expected the consolidation to resolve issues")
+	}
+
+	if _, err := CompareDirectories(scenario, filepath.Join(scenario, "after")); err == nil {
+		t.Error("Expected an error for a directory without a main CI file")
+	}
+}
@@ -256,20 +256,9 @@ func (rv *RefactoringValidator) performBehavioralValidation(beforeDir, afterDir
 
 // parseConfiguration parses a GitLab CI configuration from a directory
 func (rv *RefactoringValidator) parseConfiguration(configDir string) (*parser.GitLabConfig, error) {
-	// Look for main CI file
-	mainFiles := []string{".gitlab-ci.yml", ".gitlab-ci.yaml", "gitlab-ci.yml", "gitlab-ci.yaml"}
-
-	var mainFile string
-	for _, filename := range mainFiles {
-		path := filepath.Join(configDir, filename)
-		if _, err := os.Stat(path); err == nil {
-			mainFile = path
-			break
-		}
-	}
-
-	if mainFile == "" {
-		return nil, fmt.Errorf("no GitLab CI main file found in %s", configDir)
+	mainFile, err := mainConfigFile(configDir)
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse the configuration with includes
@@ -281,6 +270,20 @@ func (rv *RefactoringValidator) parseConfiguration(configDir string) (*parser.Gi
 	return config, nil
 }
 
+// mainConfigFile returns the path of the main CI file in configDir
+func mainConfigFile(configDir string) (string, error) {
+	mainFiles := []string{".gitlab-ci.yml", ".gitlab-ci.yaml", "gitlab-ci.yml", "gitlab-ci.yaml"}
+
+	for _, filename := range mainFiles {
+		path := filepath.Join(configDir, filename)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("no GitLab CI main file found in %s", configDir)
+}
+
 // configToYAML converts a GitLab config back to YAML (simplified implementation)
 func (rv *RefactoringValidator) configToYAML(config *parser.GitLabConfig) (string, error) {
 	// This is a simplified YAML generation - in a real implementation,
@@ -306,11 +309,7 @@ func (rv *RefactoringValidator) configToYAML(config *parser.GitLabConfig) (strin
 	}
 
 	// Add jobs (simplified - just basic structure)
-	for jobName, job := range config.Jobs {
-		if job == nil || strings.HasPrefix(jobName, ".") {
-			continue
-		}
-
+	for jobName, job := range config.ConcreteJobs() {
 		yamlLines = append(yamlLines, fmt.Sprintf("%s:", jobName))
 		if job.Stage != "" {
 			yamlLines = append(yamlLines, fmt.Sprintf("  stage: %s", job.Stage))